@@ -193,7 +193,9 @@ func RegisterV1Routes(router *gin.RouterGroup, handlers *HandlerContainer, authM
 			upload.GET("/image/:public_id", handlers.Upload.GetImageInfo)
 			upload.DELETE("/image/:public_id", handlers.Upload.DeleteImage)
 			upload.GET("/my-images", handlers.Upload.GetUserImages)
-			upload.GET("/presigned-url", handlers.Upload.GeneratePresignedURL)
+			upload.GET("/presigned-url", handlers.Upload.GeneratePresignedURL) // مهمل: يبقى للعملاء الحاليين
+			upload.POST("/presigned-url", handlers.Upload.GeneratePresignedURL)
+			upload.POST("/finalize", handlers.Upload.FinalizeUpload)
 			upload.POST("/file", handlers.Upload.UploadFile)
 			upload.GET("/file/:id", handlers.Upload.GetFile)
 			upload.DELETE("/file/:id", handlers.Upload.DeleteFile)
@@ -1,86 +1,147 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"flag"
 	"fmt"
 	"log"
+	"os"
+	"strconv"
+	"time"
 
-	_ "github.com/mattn/go-sqlite3" // استخدم هذا للـ SQLite
-	// _ "github.com/lib/pq"          // استخدم هذا لـ PostgreSQL
+	"github.com/nawthtech/nawthtech/backend/internal/config"
+	"github.com/nawthtech/nawthtech/backend/internal/db"
+	"github.com/nawthtech/nawthtech/backend/internal/db/d1"
+	"github.com/nawthtech/nawthtech/backend/internal/db/migrations"
+
+	_ "github.com/mattn/go-sqlite3"
 )
 
+const usage = `الاستخدام: migrate [flags] <command>
+
+الأوامر:
+  up            تطبيق كل الترحيلات المعلقة
+  down [n]      التراجع عن آخر n ترحيلات (الافتراضي 1، و all للكل)
+  status        عرض حالة الترحيلات
+  new <name>    إنشاء ملفي ترحيل جديدين في -dir
+
+الإعدادات الافتراضية من DB_DRIVER و DATABASE_URL.
+`
+
 func main() {
-	// 1. افتح الاتصال بقاعدة البيانات
-	db, err := sql.Open("sqlite3", "./mydatabase.db") // SQLite
-	// db, err := sql.Open("postgres", "user=postgres password=yourpass dbname=mydb sslmode=disable") // PostgreSQL
+	cfg := config.Load()
+
+	driver := flag.String("driver", cfg.Database.Driver, "database driver (sqlite3, postgres, d1)")
+	dsn := flag.String("dsn", cfg.Database.URL, "database connection string")
+	dir := flag.String("dir", "internal/db/migrations/sql", "migrations directory (for new)")
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	command, args := flag.Arg(0), flag.Args()[1:]
+
+	// new لا يحتاج اتصالاً بقاعدة البيانات
+	if command == "new" {
+		if len(args) == 0 {
+			log.Fatal("migration name is required: migrate new <name>")
+		}
+		up, down, err := migrations.Create(*dir, args[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("✅ Created %s\n✅ Created %s\n", up, down)
+		return
+	}
+
+	dialect, err := migrations.ParseDialect(*driver)
 	if err != nil {
 		log.Fatal(err)
 	}
-	defer db.Close()
-
-	// 2. جداول SQL
-	sqlStmt := `
-	CREATE TABLE IF NOT EXISTS users (
-		id TEXT PRIMARY KEY,
-		name TEXT NOT NULL,
-		email TEXT UNIQUE NOT NULL,
-		phone TEXT,
-		password TEXT NOT NULL,
-		status TEXT DEFAULT 'active',
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-	);
-
-	CREATE TABLE IF NOT EXISTS categories (
-		id TEXT PRIMARY KEY,
-		name TEXT NOT NULL,
-		slug TEXT UNIQUE NOT NULL,
-		image TEXT,
-		is_active BOOLEAN DEFAULT TRUE,
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-	);
-
-	CREATE TABLE IF NOT EXISTS services (
-		id TEXT PRIMARY KEY,
-		name TEXT NOT NULL,
-		description TEXT,
-		price REAL NOT NULL,
-		category_id TEXT,
-		is_active BOOLEAN DEFAULT TRUE,
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		FOREIGN KEY(category_id) REFERENCES categories(id)
-	);
-
-	CREATE TABLE IF NOT EXISTS orders (
-		id TEXT PRIMARY KEY,
-		user_id TEXT NOT NULL,
-		service_id TEXT NOT NULL,
-		status TEXT DEFAULT 'pending',
-		amount REAL NOT NULL,
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		FOREIGN KEY(user_id) REFERENCES users(id),
-		FOREIGN KEY(service_id) REFERENCES services(id)
-	);
-
-	CREATE TABLE IF NOT EXISTS payments (
-		id TEXT PRIMARY KEY,
-		order_id TEXT NOT NULL,
-		amount REAL NOT NULL,
-		status TEXT DEFAULT 'pending',
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		FOREIGN KEY(order_id) REFERENCES orders(id)
-	);
-	`
-
-	// 3. تنفيذ SQL
-	_, err = db.Exec(sqlStmt)
+
+	// DATABASE_URL الافتراضي قد يكون مسار SQLite، فنبني رابط Postgres من DB_*
+	// ورابط D1 من CLOUDFLARE_*
+	if *dsn == cfg.Database.URL {
+		switch {
+		case dialect == migrations.Postgres:
+			*dsn = db.PostgresDSN(cfg)
+		case *driver == d1.DriverName:
+			*dsn = db.D1DSN(cfg)
+		}
+	}
+
+	driverName, _ := db.SQLDriverName(*driver)
+	database, err := sql.Open(driverName, *dsn)
+	if err != nil {
+		log.Fatalf("failed to open database: %v", err)
+	}
+	defer database.Close()
+
+	migrator, err := migrations.New(database, dialect)
 	if err != nil {
-		log.Fatalf("%q: %s\n", err, sqlStmt)
+		log.Fatal(err)
 	}
 
-	fmt.Println("✅ جميع الجداول تم إنشاؤها بنجاح!")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	switch command {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		for _, m := range applied {
+			fmt.Printf("✅ Applied %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		if len(applied) == 0 {
+			fmt.Println("Database is up to date")
+		}
+
+	case "down":
+		steps := 1
+		if len(args) > 0 {
+			if args[0] == "all" {
+				steps = 0
+			} else if steps, err = strconv.Atoi(args[0]); err != nil || steps < 1 {
+				log.Fatalf("invalid step count: %s", args[0])
+			}
+		}
+		reverted, err := migrator.Down(ctx, steps)
+		for _, m := range reverted {
+			fmt.Printf("↩️  Reverted %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, s := range statuses {
+			state := "pending"
+			if s.Applied {
+				state = "applied " + s.AppliedAt.Local().Format("2006-01-02 15:04:05")
+			}
+			switch {
+			case s.Missing:
+				state += " (missing file)"
+			case s.ChecksumMismatch:
+				state += " (modified since applied)"
+			}
+			fmt.Printf("%04d_%-40s %s\n", s.Version, s.Name, state)
+		}
+
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
 }
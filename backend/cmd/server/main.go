@@ -14,6 +14,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/nawthtech/nawthtech/backend/internal/config"
 	"github.com/nawthtech/nawthtech/backend/internal/db"
+	"github.com/nawthtech/nawthtech/backend/internal/db/migrations"
 	"github.com/nawthtech/nawthtech/backend/internal/handlers"
 	"github.com/nawthtech/nawthtech/backend/internal/logger"
 	"github.com/nawthtech/nawthtech/backend/internal/middleware"
@@ -67,12 +68,23 @@ func Run() error {
 		logger.MetricAttr("connection_time_ms", 0, "ms"))
 
 	// إنشاء service container
-	serviceContainer, zapLogger := initServices(database, cfg)
+	serviceContainer, zapLogger, err := initServices(database, cfg)
+	if err != nil {
+		logger.Error(context.Background(), "❌ Failed to initialize services",
+			logger.ErrAttr(err),
+			logger.ComponentAttr("services"))
+		return err
+	}
 	defer closeServices(serviceContainer)
 
 	// تشغيل فحص الصحة الأولي
 	runInitialHealthCheck(serviceContainer)
 
+	// تشغيل المهام الدورية (تنظيف الرفع المهجور)
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	serviceContainer.StartBackgroundJobs(jobsCtx)
+
 	// تكوين تطبيق Gin
 	app := setupGinApp(cfg, database, serviceContainer)
 
@@ -190,7 +202,7 @@ func initDatabase(cfg *config.Config) (*sql.DB, error) {
 	logger.Info(context.Background(), "🔄 Running database migrations",
 		logger.ComponentAttr("database"))
 	
-	if err := db.RunMigrations(ctx, database); err != nil {
+	if err := db.RunMigrations(ctx, database.DB); err != nil {
 		logger.Warn(context.Background(), "⚠️ Migrations failed or already applied", 
 			logger.ErrAttr(err),
 			logger.ComponentAttr("database"))
@@ -207,6 +219,9 @@ func initDatabase(cfg *config.Config) (*sql.DB, error) {
 
 func checkTables(ctx context.Context, db *sql.DB) {
 	query := `SELECT name FROM sqlite_master WHERE type='table' ORDER BY name`
+	if migrations.DetectDialect(db) == migrations.Postgres {
+		query = `SELECT tablename FROM pg_tables WHERE schemaname = current_schema() ORDER BY tablename`
+	}
 	rows, err := db.QueryContext(ctx, query)
 	if err != nil {
 		logger.Warn(context.Background(), "⚠️ Failed to check tables", 
@@ -246,7 +261,7 @@ func closeDatabase(db *sql.DB) {
 	}
 }
 
-func initServices(db *sql.DB, cfg *config.Config) (*services.ServiceContainer, *zap.Logger) {
+func initServices(db *sql.DB, cfg *config.Config) (*services.ServiceContainer, *zap.Logger, error) {
 	logger.Info(context.Background(), "🛠️ Initializing services",
 		logger.ComponentAttr("services"))
 
@@ -256,12 +271,15 @@ func initServices(db *sql.DB, cfg *config.Config) (*services.ServiceContainer, *
 		zapLogger, _ = zap.NewDevelopment()
 	}
 
-	serviceContainer := services.NewServiceContainerWithConfig(db, cfg, zapLogger)
+	serviceContainer, err := services.NewServiceContainerWithConfig(db, cfg, zapLogger)
+	if err != nil {
+		return nil, nil, err
+	}
 
 	// اختبار الخدمات الأساسية
 	testBasicServices(serviceContainer)
 
-	return serviceContainer, zapLogger
+	return serviceContainer, zapLogger, nil
 }
 
 func testBasicServices(sc *services.ServiceContainer) {
@@ -5,10 +5,17 @@ import (
 	"os"
 
 	"github.com/gin-gonic/gin"
+	"github.com/nawthtech/nawthtech/backend/internal/ai"
 	"github.com/nawthtech/nawthtech/backend/internal/ai/video"
+	"github.com/nawthtech/nawthtech/backend/internal/config"
+	"github.com/nawthtech/nawthtech/backend/internal/db"
+	"github.com/nawthtech/nawthtech/backend/internal/middleware"
+	"github.com/nawthtech/nawthtech/backend/internal/repository"
 )
 
 func main() {
+	cfg := config.Load()
+
 	// إنشاء مزود فيديو هجين
 	provider := video.NewHybridVideoProvider()
 
@@ -20,16 +27,35 @@ func main() {
 	// مسارات API
 	api := r.Group("/api")
 
+	// حصص الفيديو لكل مستخدم موثق وحسب طبقته المخزنة، مشتركة مع الخادم الرئيسي عبر قاعدة البيانات
+	quota := middleware.AIQuotaOptions{
+		Types: map[string]string{"/api/video/generate": "video"},
+	}
+	var costManager *ai.CostManager
+	database, err := db.InitializeFromConfig(cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
+	}
+	if database != nil {
+		defer db.Close()
+		store := repository.NewStore(database.DB)
+		costManager = ai.NewCostManagerWithStore(store)
+		quota.Tier = store.Users.GetTier
+	} else {
+		log.Println("⚠️ Database not configured, video quotas use the free tier")
+		if costManager, err = ai.NewCostManager(); err != nil {
+			log.Fatalf("Failed to create cost manager: %v", err)
+		}
+	}
+
 	// مسار توليد الفيديو
-	api.POST("/video/generate", func(c *gin.Context) {
+	api.POST("/video/generate", middleware.AuthMiddleware(cfg), middleware.AIQuota(costManager, quota), func(c *gin.Context) {
 		var req struct {
 			Prompt     string `json:"prompt" binding:"required"`
 			Duration   int    `json:"duration" default:"5"`
 			Resolution string `json:"resolution" default:"512x512"`
 			Aspect     string `json:"aspect" default:"1:1"`
 			Style      string `json:"style" default:"realistic"`
-			UserID     string `json:"user_id"`
-			Tier       string `json:"tier" default:"free"`
 		}
 
 		if err := c.ShouldBindJSON(&req); err != nil {
@@ -37,6 +63,13 @@ func main() {
 			return
 		}
 
+		// المستخدم من التوكن والطبقة كما حددها AIQuota، لا من جسم الطلب
+		userID := c.GetString("userID")
+		tier := c.GetString("userTier")
+		if tier == "" {
+			tier = "free"
+		}
+
 		// إنشاء طلب الفيديو
 		videoReq := video.VideoRequest{
 			Prompt:     req.Prompt,
@@ -44,8 +77,8 @@ func main() {
 			Resolution: req.Resolution,
 			Aspect:     req.Aspect,
 			Style:      req.Style,
-			UserID:     req.UserID,
-			UserTier:   req.Tier,
+			UserID:     userID,
+			UserTier:   tier,
 		}
 
 		// التحقق من صحة الطلب
@@ -54,14 +87,6 @@ func main() {
 			return
 		}
 
-		// التحقق من إمكانية المستخدم لتوليد فيديو
-		if canGenerate, message := videoService.CanUserGenerateVideo(req.UserID, req.Tier); !canGenerate {
-			c.JSON(403, gin.H{
-				"error": message,
-			})
-			return
-		}
-
 		// إرسال طلب توليد الفيديو
 		job, err := videoService.SubmitVideoJob(videoReq)
 		if err != nil {
@@ -70,7 +95,7 @@ func main() {
 		}
 
 		// تسجيل استخدام المستخدم
-		videoService.RecordUserGeneration(req.UserID, req.Tier)
+		videoService.RecordUserGeneration(userID, tier)
 
 		c.JSON(202, gin.H{
 			"success": true,
@@ -0,0 +1,471 @@
+package ai
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/nawthtech/nawthtech/backend/internal/ai/types"
+	"github.com/nawthtech/nawthtech/backend/internal/models"
+	"github.com/nawthtech/nawthtech/backend/internal/repository"
+	"github.com/nawthtech/nawthtech/backend/internal/slack"
+)
+
+// ================================
+// الميزانيات في قاعدة البيانات
+// ================================
+
+// نطاقات الميزانيات
+const (
+	BudgetScopeGlobal = "global"
+	BudgetScopeUser   = "user"
+	BudgetScopeTier   = "tier"
+	BudgetScopeOrg    = "org"
+)
+
+// فترات الميزانيات وطرق فرضها: اللينة تنبّه فقط، والصارمة تمنع الاستخدام عند بلوغ الحد
+const (
+	BudgetDaily   = "daily"
+	BudgetMonthly = "monthly"
+
+	BudgetSoft = "soft"
+	BudgetHard = "hard"
+)
+
+// BudgetThresholds نسب الإنفاق التي يُرسل عندها تنبيه
+var BudgetThresholds = []int{50, 80, 100}
+
+// budgetQueryTimeout مهلة استعلامات الاستخدام، فالتسجيل يجري خارج سياق الطلب
+const budgetQueryTimeout = 5 * time.Second
+
+var (
+	// ErrBudgetsRequireDatabase الميزانيات متاحة فقط مع مدير تكاليف مدعوم بقاعدة البيانات
+	ErrBudgetsRequireDatabase = errors.New("budgets require a database-backed cost manager")
+	// ErrInvalidBudget ميزانية بنطاق أو فترة أو حد غير صالح
+	ErrInvalidBudget = errors.New("invalid budget")
+)
+
+// BudgetStatus إنفاق من تشملهم ميزانية في فترتها الحالية
+type BudgetStatus struct {
+	Budget    models.AIBudget `json:"budget"`
+	SubjectID string          `json:"subject_id,omitempty"` // المستخدم أو المؤسسة المحتسب عليها
+	PeriodKey string          `json:"period_key"`
+	Spent     float64         `json:"spent"`
+	Percent   float64         `json:"percent"`
+}
+
+// Blocks هل تمنع الميزانية استخدامًا جديدًا
+func (s BudgetStatus) Blocks() bool {
+	return s.Budget.Enforcement == BudgetHard && s.Spent >= s.Budget.Limit
+}
+
+// NewCostManagerWithStore مدير تكاليف يسجل الاستخدام في قاعدة البيانات ويحسب
+// الإنفاق منها عند كل فحص، فتتشارك نسخ الخادم المتعددة الحدود نفسها. التنبيهات
+// تُرسل إلى Slack وإشعارات التطبيق
+func NewCostManagerWithStore(store *repository.Store) *CostManager {
+	return &CostManager{
+		usage:         store.AIUsage,
+		notifications: store.Notifications,
+		sendAlert:     slack.DefaultSendAlert,
+		now:           time.Now,
+	}
+}
+
+// periodStart بداية الفترة الحالية ومفتاحها بتوقيت UTC
+func periodStart(period string, now time.Time) (time.Time, string) {
+	now = now.UTC()
+	if period == BudgetDaily {
+		return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC), now.Format("2006-01-02")
+	}
+	return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC), now.Format("2006-01")
+}
+
+// normalizeTier المستخدم بلا طبقة يعامل كمجاني
+func normalizeTier(tier string) string {
+	if tier == "" {
+		return "free"
+	}
+	return tier
+}
+
+// usageRecordID معرف فريد عبر نسخ الخادم
+func usageRecordID() string {
+	suffix := make([]byte, 4)
+	rand.Read(suffix)
+	return fmt.Sprintf("usage_%d_%s", time.Now().UnixNano(), hex.EncodeToString(suffix))
+}
+
+// recordUsageDB تسجيل الاستخدام في قاعدة البيانات ثم فحص الميزانيات وإرسال تنبيهات العتبات
+func (cm *CostManager) recordUsageDB(record *types.UsageRecord) error {
+	ctx, cancel := context.WithTimeout(context.Background(), budgetQueryTimeout)
+	defer cancel()
+
+	row := &models.AIUsageRecord{
+		ID:          record.ID,
+		UserID:      record.UserID,
+		OrgID:       record.OrgID,
+		Provider:    record.Provider,
+		RequestType: record.Type,
+		Cost:        record.Cost,
+		Quantity:    record.Quantity,
+		LatencyMs:   record.Latency,
+		Success:     record.Success,
+		CacheHit:    record.CacheHit,
+		SavedCost:   record.SavedCost,
+		Error:       record.Error,
+		Metadata:    record.Metadata,
+		CreatedAt:   record.Timestamp,
+	}
+	if row.ID == "" {
+		row.ID = usageRecordID()
+	}
+	if row.CreatedAt.IsZero() {
+		row.CreatedAt = cm.now()
+	}
+	if row.UserID != "" {
+		row.UserTier = normalizeTier(record.UserTier)
+	}
+	if err := cm.usage.Record(ctx, row); err != nil {
+		return err
+	}
+
+	// ما لا يكلف لا يغير حالة أي ميزانية
+	if row.Cost <= 0 {
+		return nil
+	}
+
+	statuses, err := cm.budgetStatuses(ctx, row.UserID, row.UserTier, row.OrgID)
+	if err != nil {
+		return err
+	}
+	cm.raiseAlerts(ctx, statuses)
+
+	for _, status := range statuses {
+		if status.Blocks() {
+			return fmt.Errorf("%s %s budget exceeded: %.2f/%.2f",
+				status.Budget.Period, status.Budget.Scope, status.Spent, status.Budget.Limit)
+		}
+	}
+	return nil
+}
+
+// canUseAIDB منع المستخدم إن بلغ أي ميزانية صارمة تشمله. خطأ قاعدة البيانات لا
+// يمنع الخدمة
+func (cm *CostManager) canUseAIDB(userID string) (bool, string) {
+	ctx, cancel := context.WithTimeout(context.Background(), budgetQueryTimeout)
+	defer cancel()
+
+	status, err := cm.blockingBudgetDB(ctx, userID, "")
+	if err != nil {
+		log.Printf("Warning: failed to check AI budgets: %v", err)
+		return true, ""
+	}
+	if status != nil {
+		return false, budgetReason(status.Budget)
+	}
+	return true, ""
+}
+
+// blockingBudgetDB أول ميزانية صارمة بلغها المستخدم أو nil. المؤسسة من آخر استخدام
+// له، وكذلك الطبقة إن لم تُمرَّر
+func (cm *CostManager) blockingBudgetDB(ctx context.Context, userID, tier string) (*BudgetStatus, error) {
+	var orgID string
+	if userID != "" {
+		lastTier, lastOrg, err := cm.usage.LatestSubject(ctx, userID)
+		if err != nil && !errors.Is(err, repository.ErrNotFound) {
+			log.Printf("Warning: failed to resolve AI usage subject: %v", err)
+		}
+		if tier == "" {
+			tier = lastTier
+		}
+		orgID = lastOrg
+		tier = normalizeTier(tier)
+	}
+
+	statuses, err := cm.budgetStatuses(ctx, userID, tier, orgID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range statuses {
+		if statuses[i].Blocks() {
+			return &statuses[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// budgetReason سبب المنع كما يُعرض للمستخدم
+func budgetReason(budget models.AIBudget) string {
+	period := "الشهري"
+	if budget.Period == BudgetDaily {
+		period = "اليومي"
+	}
+	switch budget.Scope {
+	case BudgetScopeGlobal:
+		return fmt.Sprintf("تم تجاوز الحد %s للتكاليف", period)
+	case BudgetScopeOrg:
+		return fmt.Sprintf("تم تجاوز حد التكلفة %s للمؤسسة", period)
+	default:
+		return fmt.Sprintf("تم تجاوز حد التكلفة %s للمستخدم", period)
+	}
+}
+
+// budgetStatuses إنفاق كل ميزانية تشمل المستخدم في فترتها الحالية. ميزانية الطبقة
+// تحد كل مستخدم على حدة، وتسقط عن المستخدم الذي له ميزانية خاصة بالفترة نفسها
+func (cm *CostManager) budgetStatuses(ctx context.Context, userID, tier, orgID string) ([]BudgetStatus, error) {
+	budgets, err := cm.usage.ApplicableBudgets(ctx, userID, tier, orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	ownPeriods := make(map[string]bool)
+	for _, budget := range budgets {
+		if budget.Scope == BudgetScopeUser {
+			ownPeriods[budget.Period] = true
+		}
+	}
+
+	now := cm.now()
+	spent := make(map[string]float64)
+	statuses := make([]BudgetStatus, 0, len(budgets))
+	for _, budget := range budgets {
+		if budget.Scope == BudgetScopeTier && ownPeriods[budget.Period] {
+			continue
+		}
+
+		var filter repository.AIUsageFilter
+		switch budget.Scope {
+		case BudgetScopeUser, BudgetScopeTier:
+			filter.UserID = userID
+		case BudgetScopeOrg:
+			filter.OrgID = orgID
+		}
+
+		since, periodKey := periodStart(budget.Period, now)
+		key := filter.UserID + "|" + filter.OrgID + "|" + periodKey
+		total, ok := spent[key]
+		if !ok {
+			if total, err = cm.usage.Spend(ctx, filter, since); err != nil {
+				return nil, err
+			}
+			spent[key] = total
+		}
+
+		status := BudgetStatus{
+			Budget:    budget,
+			SubjectID: filter.UserID + filter.OrgID,
+			PeriodKey: periodKey,
+			Spent:     total,
+		}
+		if budget.Limit > 0 {
+			status.Percent = total / budget.Limit * 100
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// raiseAlerts حجز العتبات التي بلغها الإنفاق وإرسال تنبيه بأعلاها. الحجز في قاعدة
+// البيانات يجعل كل عتبة تُرسل مرة واحدة في الفترة عبر كل النسخ
+func (cm *CostManager) raiseAlerts(ctx context.Context, statuses []BudgetStatus) {
+	for _, status := range statuses {
+		highest := 0
+		for _, threshold := range BudgetThresholds {
+			if status.Percent < float64(threshold) {
+				break
+			}
+			claimed, err := cm.usage.ClaimAlert(ctx, &models.AIBudgetAlert{
+				ID:        fmt.Sprintf("%s_%s_%s_%d", status.Budget.ID, status.SubjectID, status.PeriodKey, threshold),
+				BudgetID:  status.Budget.ID,
+				SubjectID: status.SubjectID,
+				PeriodKey: status.PeriodKey,
+				Threshold: threshold,
+				Spent:     status.Spent,
+				CreatedAt: cm.now(),
+			})
+			if err != nil {
+				log.Printf("Warning: %v", err)
+				continue
+			}
+			if claimed {
+				highest = threshold
+			}
+		}
+		if highest > 0 {
+			go cm.notifyBudget(status, highest)
+		}
+	}
+}
+
+// notifyBudget إيصال تنبيه العتبة: المستخدم يُبلَّغ داخل التطبيق بميزانيته، والفريق
+// يُبلَّغ في Slack بالميزانيات العامة وميزانيات المؤسسات، وببلوغ المستخدم حده كاملًا
+func (cm *CostManager) notifyBudget(status BudgetStatus, threshold int) {
+	budget := status.Budget
+	level := "warning"
+	if threshold >= 100 {
+		level = "error"
+	}
+
+	personal := budget.Scope == BudgetScopeUser || budget.Scope == BudgetScopeTier
+	if personal && cm.notifications != nil && status.SubjectID != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), budgetQueryTimeout)
+		defer cancel()
+
+		message := fmt.Sprintf("استهلكت %d%% من ميزانية الذكاء الاصطناعي (%.2f من %.2f دولار).",
+			threshold, status.Spent, budget.Limit)
+		if status.Blocks() {
+			message += " سيتوقف الاستخدام حتى بداية الفترة التالية."
+		}
+		err := cm.notifications.Create(ctx, &models.Notification{
+			ID:        fmt.Sprintf("notif_budget_%s_%s_%s_%d", budget.ID, status.SubjectID, status.PeriodKey, threshold),
+			UserID:    status.SubjectID,
+			Title:     "تنبيه ميزانية الذكاء الاصطناعي",
+			Message:   message,
+			Type:      level,
+			CreatedAt: cm.now(),
+		})
+		if err != nil {
+			log.Printf("Warning: failed to notify user of AI budget: %v", err)
+		}
+	}
+
+	if (personal && threshold < 100) || cm.sendAlert == nil {
+		return
+	}
+	subject := budget.Scope
+	if status.SubjectID != "" {
+		subject += " " + status.SubjectID
+	}
+	title := fmt.Sprintf("AI budget %d%% reached (%s, %s)", threshold, subject, budget.Period)
+	text := fmt.Sprintf("Spent $%.2f of $%.2f in %s (%s limit).",
+		status.Spent, budget.Limit, status.PeriodKey, budget.Enforcement)
+	if _, _, err := cm.sendAlert(level, title, text); err != nil && !errors.Is(err, slack.ErrClientNotInitialized) {
+		log.Printf("Warning: failed to send AI budget alert: %v", err)
+	}
+}
+
+// ================================
+// إدارة الميزانيات
+// ================================
+
+// SetBudget إنشاء ميزانية نطاق وفترة أو استبدالها
+func (cm *CostManager) SetBudget(ctx context.Context, budget models.AIBudget) error {
+	if cm.usage == nil {
+		return ErrBudgetsRequireDatabase
+	}
+
+	switch budget.Scope {
+	case BudgetScopeGlobal:
+		budget.ScopeID = ""
+	case BudgetScopeUser, BudgetScopeTier, BudgetScopeOrg:
+		if budget.ScopeID == "" {
+			return fmt.Errorf("%w: %s budget needs a scope id", ErrInvalidBudget, budget.Scope)
+		}
+	default:
+		return fmt.Errorf("%w: unknown scope %q", ErrInvalidBudget, budget.Scope)
+	}
+	if budget.Period != BudgetDaily && budget.Period != BudgetMonthly {
+		return fmt.Errorf("%w: unknown period %q", ErrInvalidBudget, budget.Period)
+	}
+	if budget.Limit <= 0 {
+		return fmt.Errorf("%w: limit must be positive", ErrInvalidBudget)
+	}
+	if budget.Enforcement == "" {
+		budget.Enforcement = BudgetSoft
+	}
+	if budget.Enforcement != BudgetSoft && budget.Enforcement != BudgetHard {
+		return fmt.Errorf("%w: unknown enforcement %q", ErrInvalidBudget, budget.Enforcement)
+	}
+	budget.IsActive = true
+
+	return cm.usage.SaveBudget(ctx, &budget)
+}
+
+// GetBudgets كل الميزانيات المعرفة
+func (cm *CostManager) GetBudgets(ctx context.Context) ([]models.AIBudget, error) {
+	if cm.usage == nil {
+		return nil, ErrBudgetsRequireDatabase
+	}
+	return cm.usage.Budgets(ctx)
+}
+
+// GetBudgetStatus إنفاق المستخدم مقابل كل ميزانية تشمله
+func (cm *CostManager) GetBudgetStatus(ctx context.Context, userID, tier, orgID string) ([]BudgetStatus, error) {
+	if cm.usage == nil {
+		return nil, ErrBudgetsRequireDatabase
+	}
+	if userID != "" {
+		tier = normalizeTier(tier)
+	}
+	return cm.budgetStatuses(ctx, userID, tier, orgID)
+}
+
+// setGlobalLimitsDB حفظ الحدود العامة كميزانيات صارمة مشتركة بين النسخ؛ الصفر يحذفها
+func (cm *CostManager) setGlobalLimitsDB(monthly, daily float64) {
+	ctx, cancel := context.WithTimeout(context.Background(), budgetQueryTimeout)
+	defer cancel()
+
+	for period, limit := range map[string]float64{BudgetMonthly: monthly, BudgetDaily: daily} {
+		var err error
+		if limit > 0 {
+			err = cm.SetBudget(ctx, models.AIBudget{Scope: BudgetScopeGlobal, Period: period, Limit: limit, Enforcement: BudgetHard})
+		} else {
+			err = cm.usage.DeleteBudget(ctx, repository.BudgetID(BudgetScopeGlobal, "", period))
+		}
+		if err != nil {
+			log.Printf("Warning: failed to save global AI limit: %v", err)
+		}
+	}
+}
+
+// usageStatisticsDB إحصائيات الاستخدام محسوبة من قاعدة البيانات
+func (cm *CostManager) usageStatisticsDB() map[string]interface{} {
+	ctx, cancel := context.WithTimeout(context.Background(), budgetQueryTimeout)
+	defer cancel()
+
+	stats := map[string]interface{}{"storage": "database"}
+	total, err := cm.usage.Summary(ctx, time.Time{})
+	if err != nil {
+		stats["error"] = err.Error()
+		return stats
+	}
+	stats["total_cost"] = total.Cost
+	stats["total_requests"] = total.Requests
+	stats["total_users"] = total.Users
+	stats["cache_hits"] = total.CacheHits
+	stats["saved_cost"] = total.SavedCost
+
+	now := cm.now()
+	for _, period := range []string{BudgetMonthly, BudgetDaily} {
+		since, _ := periodStart(period, now)
+		cost, err := cm.usage.Spend(ctx, repository.AIUsageFilter{}, since)
+		if err != nil {
+			continue
+		}
+		name := "month"
+		if period == BudgetDaily {
+			name = "day"
+		}
+		stats["current_"+name+"_cost"] = cost
+	}
+
+	if providers, err := cm.usage.ByProvider(ctx, time.Time{}); err == nil {
+		stats["providers"] = len(providers)
+		stats["provider_usage"] = providers
+	}
+	if statuses, err := cm.budgetStatuses(ctx, "", "", ""); err == nil {
+		for _, status := range statuses {
+			if status.Budget.Period == BudgetDaily {
+				stats["daily_usage_percentage"] = status.Percent
+			} else {
+				stats["monthly_usage_percentage"] = status.Percent
+			}
+		}
+		stats["budgets"] = statuses
+	}
+	return stats
+}
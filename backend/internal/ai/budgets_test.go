@@ -0,0 +1,124 @@
+package ai
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nawthtech/nawthtech/backend/internal/ai/types"
+	"github.com/nawthtech/nawthtech/backend/internal/db/migrations"
+	"github.com/nawthtech/nawthtech/backend/internal/models"
+	"github.com/nawthtech/nawthtech/backend/internal/repository"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestCostManagerBudgetsAcrossInstances(t *testing.T) {
+	database, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "ai.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close()
+	migrator, err := migrations.New(database, migrations.SQLite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Up(context.Background()); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	// نسختا خادم على قاعدة واحدة
+	alerts := make(chan string, 10)
+	now := time.Date(2026, 3, 15, 12, 0, 0, 0, time.UTC)
+	newInstance := func() *CostManager {
+		cm := NewCostManagerWithStore(repository.NewStore(database))
+		cm.now = func() time.Time { return now }
+		cm.sendAlert = func(level, title, message string) (string, string, error) {
+			alerts <- level + ": " + title
+			return "", "", nil
+		}
+		return cm
+	}
+	first, second := newInstance(), newInstance()
+
+	ctx := context.Background()
+	if err := first.SetBudget(ctx, models.AIBudget{Scope: BudgetScopeOrg, ScopeID: "org_1", Period: BudgetMonthly, Limit: 4}); err != nil {
+		t.Fatal(err)
+	}
+
+	record := func(cm *CostManager, cost float64) error {
+		return cm.RecordUsage(&types.UsageRecord{
+			UserID: "user_1", OrgID: "org_1", Provider: "gemini", Type: "text",
+			Cost: cost, Quantity: 100, Success: true, Timestamp: now,
+		})
+	}
+
+	// 60% من حد الطبقة المجانية (1$) و 15% من ميزانية المؤسسة
+	if err := record(first, 0.6); err != nil {
+		t.Fatalf("first record: %v", err)
+	}
+	if ok, reason := second.CanUseAI("user_1", "text"); !ok {
+		t.Fatalf("user blocked below the hard limit: %s", reason)
+	}
+
+	// تجاوز الحد من النسخة الثانية يمنع المستخدم في الأولى أيضًا
+	if err := record(second, 0.5); err == nil || !strings.Contains(err.Error(), "tier budget exceeded") {
+		t.Fatalf("expected tier budget error, got %v", err)
+	}
+	if ok, _ := first.CanUseAI("user_1", "text"); ok {
+		t.Error("user allowed past the hard tier limit")
+	}
+	if ok, _ := first.CanUseAI("user_2", "text"); !ok {
+		t.Error("another user blocked by user_1's spend")
+	}
+
+	// التنبيه الكامل يصل Slack مرة واحدة مهما سجلت النسخ بعده
+	select {
+	case alert := <-alerts:
+		if !strings.HasPrefix(alert, "error: AI budget 100% reached (tier user_1") {
+			t.Errorf("unexpected alert %q", alert)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no slack alert at 100%")
+	}
+	record(first, 0.1)
+	record(second, 0.1)
+
+	var claimed int
+	database.QueryRow("SELECT COUNT(*) FROM ai_budget_alerts WHERE subject_id = 'user_1'").Scan(&claimed)
+	if claimed != 3 {
+		t.Errorf("claimed %d tier alerts, want one per threshold", claimed)
+	}
+
+	// ميزانية المؤسسة اللينة: 1.3 من 4 لم تبلغ 50% فلا تنبيه ولا منع
+	statuses, err := first.GetBudgetStatus(ctx, "user_1", "free", "org_1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, status := range statuses {
+		if status.Budget.Scope == BudgetScopeOrg && (status.Blocks() || status.Spent < 1.29 || status.Spent > 1.31) {
+			t.Errorf("unexpected org status %+v", status)
+		}
+	}
+
+	// إشعار داخل التطبيق لكل عتبة جديدة للمستخدم
+	deadline := time.Now().Add(time.Second)
+	for {
+		count, _ := repository.NewStore(database).Notifications.Query().Eq("user_id", "user_1").Count(ctx, database)
+		if count == 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("got %d in-app notifications, want 2 (50%% then 100%%)", count)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	select {
+	case alert := <-alerts:
+		t.Errorf("duplicate alert %q", alert)
+	default:
+	}
+}
@@ -0,0 +1,304 @@
+package ai
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/nawthtech/nawthtech/backend/internal/ai/types"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// ================================
+// قاطع الدائرة لكل مزود
+// ================================
+
+// BreakerState حالة قاطع الدائرة
+type BreakerState string
+
+// حالات القاطع
+const (
+	BreakerClosed   BreakerState = "closed"    // الطلبات تمر طبيعيًا
+	BreakerOpen     BreakerState = "open"      // المزود مستبعد حتى انقضاء مهلة الفتح
+	BreakerHalfOpen BreakerState = "half_open" // عدد محدود من الطلبات التجريبية
+)
+
+var (
+	breakerStateGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "ai_provider_circuit_state",
+			Help: "Circuit breaker state per AI provider (0=closed, 1=half_open, 2=open)",
+		},
+		[]string{"provider"},
+	)
+
+	breakerTransitions = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "ai_provider_circuit_transitions_total",
+			Help: "Circuit breaker state transitions per AI provider",
+		},
+		[]string{"provider", "from", "to"},
+	)
+
+	healthProbesTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "ai_provider_health_probes_total",
+			Help: "Background health probes per AI provider",
+		},
+		[]string{"provider", "result"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(breakerStateGauge, breakerTransitions, healthProbesTotal)
+}
+
+// gaugeValue قيمة الحالة في مقياس ai_provider_circuit_state
+func (s BreakerState) gaugeValue() float64 {
+	switch s {
+	case BreakerHalfOpen:
+		return 1
+	case BreakerOpen:
+		return 2
+	default:
+		return 0
+	}
+}
+
+// BreakerOptions عتبات قاطع الدائرة
+type BreakerOptions struct {
+	// FailureThreshold عدد الإخفاقات المتتالية التي تفتح القاطع
+	FailureThreshold int
+	// OpenTimeout مدة بقاء القاطع مفتوحًا قبل السماح بطلبات تجريبية
+	OpenTimeout time.Duration
+	// HalfOpenRequests عدد الطلبات التجريبية المتزامنة، ونجاحها كلها يغلق القاطع
+	HalfOpenRequests int
+}
+
+// DefaultBreakerOptions العتبات الافتراضية
+func DefaultBreakerOptions() BreakerOptions {
+	return BreakerOptions{
+		FailureThreshold: 5,
+		OpenTimeout:      30 * time.Second,
+		HalfOpenRequests: 1,
+	}
+}
+
+// BreakerOptionsFromEnv العتبات من AI_BREAKER_FAILURE_THRESHOLD و AI_BREAKER_OPEN_TIMEOUT
+// و AI_BREAKER_HALF_OPEN_REQUESTS
+func BreakerOptionsFromEnv() BreakerOptions {
+	opts := DefaultBreakerOptions()
+	if n, err := strconv.Atoi(getEnvWithFallback("AI_BREAKER_FAILURE_THRESHOLD", "")); err == nil && n > 0 {
+		opts.FailureThreshold = n
+	}
+	opts.OpenTimeout = envDuration("AI_BREAKER_OPEN_TIMEOUT", opts.OpenTimeout)
+	if n, err := strconv.Atoi(getEnvWithFallback("AI_BREAKER_HALF_OPEN_REQUESTS", "")); err == nil && n > 0 {
+		opts.HalfOpenRequests = n
+	}
+	return opts
+}
+
+// BreakerSnapshot لقطة من حالة القاطع للعرض
+type BreakerSnapshot struct {
+	Provider            string       `json:"provider"`
+	State               BreakerState `json:"state"`
+	ConsecutiveFailures int          `json:"consecutive_failures"`
+	OpenedAt            *time.Time   `json:"opened_at,omitempty"`
+	LastError           string       `json:"last_error,omitempty"`
+	LastProbeAt         *time.Time   `json:"last_probe_at,omitempty"`
+	LastProbeOK         bool         `json:"last_probe_ok"`
+}
+
+// CircuitBreaker قاطع دائرة لمزود واحد
+type CircuitBreaker struct {
+	mu       sync.Mutex
+	provider string
+	opts     BreakerOptions
+	now      func() time.Time
+
+	state            BreakerState
+	failures         int
+	halfOpenInFlight int
+	halfOpenPassed   int
+	openedAt         time.Time
+	lastError        string
+	lastProbeAt      time.Time
+	lastProbeOK      bool
+}
+
+// NewCircuitBreaker إنشاء قاطع مغلق لمزود
+func NewCircuitBreaker(provider string, opts BreakerOptions) *CircuitBreaker {
+	if opts.FailureThreshold <= 0 {
+		opts.FailureThreshold = DefaultBreakerOptions().FailureThreshold
+	}
+	if opts.HalfOpenRequests <= 0 {
+		opts.HalfOpenRequests = 1
+	}
+	breakerStateGauge.WithLabelValues(provider).Set(BreakerClosed.gaugeValue())
+	return &CircuitBreaker{
+		provider: provider,
+		opts:     opts,
+		now:      time.Now,
+		state:    BreakerClosed,
+	}
+}
+
+// State الحالة الحالية (مع انتقال المفتوح إلى نصف مفتوح عند انقضاء المهلة)
+func (b *CircuitBreaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.expireOpen()
+	return b.state
+}
+
+// Allow هل يُرسل طلب إلى المزود الآن؛ كل سماح يجب أن يتبعه Record أو Release
+func (b *CircuitBreaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.expireOpen()
+	switch b.state {
+	case BreakerOpen:
+		return false
+	case BreakerHalfOpen:
+		if b.halfOpenInFlight >= b.opts.HalfOpenRequests {
+			return false
+		}
+		b.halfOpenInFlight++
+	}
+	return true
+}
+
+// Record تسجيل نتيجة طلب سُمح به. الأخطاء التي لا تدل على عطل المزود (طلب غير
+// صالح، عملية غير مدعومة، إلغاء من العميل) لا تُحتسب إخفاقًا
+func (b *CircuitBreaker) Record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	halfOpen := b.state == BreakerHalfOpen
+	if halfOpen && b.halfOpenInFlight > 0 {
+		b.halfOpenInFlight--
+	}
+
+	switch {
+	case err == nil:
+		b.failures = 0
+		if halfOpen {
+			b.halfOpenPassed++
+			if b.halfOpenPassed >= b.opts.HalfOpenRequests {
+				b.transition(BreakerClosed)
+			}
+		}
+	case !isProviderFault(err):
+		// لا يغيّر الحالة
+	default:
+		b.failures++
+		b.lastError = err.Error()
+		if halfOpen || b.failures >= b.opts.FailureThreshold {
+			b.transition(BreakerOpen)
+		}
+	}
+}
+
+// Release إعادة سماح لم يُستخدم (مثل طلب أُلغي قبل اكتماله) دون احتساب نتيجة
+func (b *CircuitBreaker) Release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == BreakerHalfOpen && b.halfOpenInFlight > 0 {
+		b.halfOpenInFlight--
+	}
+}
+
+// RecordProbe تسجيل نتيجة فحص الصحة الدوري: الفحص الناجح ينقل المفتوح إلى نصف
+// مفتوح مبكرًا، والفاشل يُحتسب إخفاقًا
+func (b *CircuitBreaker) RecordProbe(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.lastProbeAt = b.now()
+	b.lastProbeOK = err == nil
+	if err == nil {
+		if b.state == BreakerOpen {
+			b.transition(BreakerHalfOpen)
+		}
+		return
+	}
+
+	b.failures++
+	b.lastError = err.Error()
+	if b.state != BreakerOpen && (b.state == BreakerHalfOpen || b.failures >= b.opts.FailureThreshold) {
+		b.transition(BreakerOpen)
+	}
+}
+
+// Snapshot لقطة من الحالة
+func (b *CircuitBreaker) Snapshot() BreakerSnapshot {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.expireOpen()
+	snapshot := BreakerSnapshot{
+		Provider:            b.provider,
+		State:               b.state,
+		ConsecutiveFailures: b.failures,
+		LastError:           b.lastError,
+		LastProbeOK:         b.lastProbeOK,
+	}
+	if b.state != BreakerClosed {
+		openedAt := b.openedAt
+		snapshot.OpenedAt = &openedAt
+	}
+	if !b.lastProbeAt.IsZero() {
+		probeAt := b.lastProbeAt
+		snapshot.LastProbeAt = &probeAt
+	}
+	return snapshot
+}
+
+// expireOpen نقل القاطع المفتوح إلى نصف مفتوح بعد انقضاء OpenTimeout (يُستدعى مع القفل)
+func (b *CircuitBreaker) expireOpen() {
+	if b.state == BreakerOpen && b.now().Sub(b.openedAt) >= b.opts.OpenTimeout {
+		b.transition(BreakerHalfOpen)
+	}
+}
+
+// transition تغيير الحالة وتصفير عدادات نصف المفتوح (يُستدعى مع القفل)
+func (b *CircuitBreaker) transition(to BreakerState) {
+	from := b.state
+	if from == to {
+		return
+	}
+	b.state = to
+	b.halfOpenInFlight, b.halfOpenPassed = 0, 0
+	switch to {
+	case BreakerOpen:
+		b.openedAt = b.now()
+	case BreakerClosed:
+		b.failures = 0
+		b.lastError = ""
+	}
+
+	breakerStateGauge.WithLabelValues(b.provider).Set(to.gaugeValue())
+	breakerTransitions.WithLabelValues(b.provider, string(from), string(to)).Inc()
+}
+
+// isProviderFault هل يدل الخطأ على عطل في المزود نفسه
+func isProviderFault(err error) bool {
+	for _, neutral := range []*types.ProviderError{
+		types.ErrRequestCanceled,
+		types.ErrInvalidRequest,
+		types.ErrContentFiltered,
+		types.ErrTextNotSupported,
+		types.ErrImageNotSupported,
+		types.ErrVideoNotSupported,
+		types.ErrModelNotSupported,
+	} {
+		if errors.Is(err, neutral) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,90 @@
+package ai
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/nawthtech/nawthtech/backend/internal/ai/types"
+)
+
+func TestCircuitBreakerTransitions(t *testing.T) {
+	now := time.Now()
+	b := NewCircuitBreaker("test", BreakerOptions{FailureThreshold: 2, OpenTimeout: time.Minute, HalfOpenRequests: 1})
+	b.now = func() time.Time { return now }
+
+	unavailable := types.HTTPError("test", http.StatusServiceUnavailable, "")
+
+	// الأخطاء التي لا تدل على عطل المزود لا تفتح القاطع
+	for i := 0; i < 3; i++ {
+		b.Allow()
+		b.Record(types.HTTPError("test", http.StatusBadRequest, ""))
+	}
+	if got := b.State(); got != BreakerClosed {
+		t.Fatalf("state after invalid requests = %s, want closed", got)
+	}
+
+	for i := 0; i < 2; i++ {
+		b.Allow()
+		b.Record(unavailable)
+	}
+	if got := b.State(); got != BreakerOpen {
+		t.Fatalf("state after threshold = %s, want open", got)
+	}
+	if b.Allow() {
+		t.Fatal("open breaker allowed a request")
+	}
+
+	// بعد المهلة: طلب تجريبي واحد فقط
+	now = now.Add(time.Minute)
+	if !b.Allow() {
+		t.Fatal("half-open breaker rejected the trial request")
+	}
+	if b.Allow() {
+		t.Fatal("half-open breaker allowed more than HalfOpenRequests")
+	}
+	b.Record(unavailable)
+	if got := b.State(); got != BreakerOpen {
+		t.Fatalf("state after failed trial = %s, want open", got)
+	}
+
+	// فحص صحة ناجح ينقله إلى نصف مفتوح ثم نجاح الطلب التجريبي يغلقه
+	b.RecordProbe(nil)
+	if got := b.State(); got != BreakerHalfOpen {
+		t.Fatalf("state after healthy probe = %s, want half_open", got)
+	}
+	b.Allow()
+	b.Record(nil)
+	if got := b.Snapshot(); got.State != BreakerClosed || got.ConsecutiveFailures != 0 {
+		t.Fatalf("snapshot after successful trial = %+v, want closed with no failures", got)
+	}
+}
+
+func TestOpenBreakerExcludesProvider(t *testing.T) {
+	ollama := &scriptedProvider{name: "ollama"}
+	hf := &scriptedProvider{name: "huggingface"}
+	mp := newScriptedMultiProvider(FailoverOptions{MaxAttempts: 3}, map[ProviderType]*scriptedProvider{
+		ProviderOllama:      ollama,
+		ProviderHuggingFace: hf,
+	})
+	mp.strategy = &healthAwareStrategy{RoutingStrategy: &DefaultStrategy{}, mp: mp}
+	mp.breakerOpts = BreakerOptions{FailureThreshold: 1, OpenTimeout: time.Hour}
+
+	mp.breaker(ProviderOllama).RecordProbe(types.ErrProviderUnavailable)
+
+	if got := mp.strategy.SelectProvider("free", "text", "text"); got != ProviderHuggingFace {
+		t.Fatalf("SelectProvider = %s, want %s while ollama is open", got, ProviderHuggingFace)
+	}
+
+	resp, err := mp.GenerateText(context.Background(), types.TextRequest{Prompt: "hi", UserTier: "free"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Text != "from huggingface" || ollama.calls != 0 {
+		t.Fatalf("text = %q, ollama calls = %d; want huggingface only", resp.Text, ollama.calls)
+	}
+	if chain := resp.Metadata["provider_chain"].([]string); len(chain) != 1 || chain[0] != "huggingface" {
+		t.Fatalf("provider_chain = %v, want [huggingface]", chain)
+	}
+}
@@ -1,6 +1,7 @@
 package ai
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
@@ -16,20 +17,28 @@ type Client struct {
 	providers     map[string]types.ProviderInterface
 	multiProvider *MultiProvider
 	costManager   *CostManager
+	responseCache *ResponseCache
 }
 
-// NewClient إنشاء عميل AI جديد
+// NewClient إنشاء عميل AI جديد بمدير تكاليف يحفظ في ملف محلي
 func NewClient() (*Client, error) {
-	c := &Client{
-		providers: make(map[string]types.ProviderInterface),
-	}
-
-	// إنشاء مدير التكاليف
 	costManager, err := NewCostManager()
 	if err != nil {
 		log.Printf("Warning: Failed to initialize cost manager: %v", err)
 	}
-	c.costManager = costManager
+	return NewClientWithCostManager(costManager)
+}
+
+// NewClientWithCostManager إنشاء عميل AI بمدير تكاليف جاهز (مثل المدعوم بقاعدة
+// البيانات) يشاركه المزود المتعدد
+func NewClientWithCostManager(costManager *CostManager) (*Client, error) {
+	c := &Client{
+		providers:   make(map[string]types.ProviderInterface),
+		costManager: costManager,
+	}
+
+	// التخزين المؤقت للاستجابات المتطابقة (nil إذا عُطل)
+	c.responseCache = NewResponseCacheFromEnv()
 
 	// إنشاء مزود متعدد
 	mp, err := NewMultiProvider()
@@ -37,6 +46,9 @@ func NewClient() (*Client, error) {
 		log.Printf("Warning: Failed to create multi-provider: %v", err)
 		// استمرار بدون multi-provider
 	} else {
+		if costManager != nil {
+			mp.costManager = costManager
+		}
 		c.multiProvider = mp
 	}
 
@@ -65,6 +77,12 @@ func NewClient() (*Client, error) {
 		}
 	}
 
+	// مزود OpenAI بمفتاح API أو خادم متوافق عبر OPENAI_BASE_URL
+	if openAI := NewOpenAIProvider(); openAI.IsAvailable() {
+		c.providers["openai"] = openAI
+		log.Println("✅ OpenAI provider initialized")
+	}
+
 	if len(c.providers) == 0 {
 		log.Println("⚠️ No AI providers available")
 	} else {
@@ -74,8 +92,13 @@ func NewClient() (*Client, error) {
 	return c, nil
 }
 
-// GenerateText توليد نص
+// GenerateText توليد نص بدون سياق (للمستدعين القدامى)
 func (c *Client) GenerateText(prompt, provider string) (string, error) {
+	return c.GenerateTextContext(context.Background(), prompt, provider)
+}
+
+// GenerateTextContext توليد نص؛ إلغاء ctx يوقف الطلب الجاري لدى المزود
+func (c *Client) GenerateTextContext(ctx context.Context, prompt, provider string) (string, error) {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
@@ -90,7 +113,7 @@ func (c *Client) GenerateText(prompt, provider string) (string, error) {
 				Model:  "llama3.2:3b",
 			}
 
-			resp, err = c.multiProvider.GenerateText(req)
+			resp, err = c.multiProvider.GenerateText(ctx, req)
 		} else {
 			// استخدام أول مزود متاح
 			for _, p := range c.providers {
@@ -98,7 +121,7 @@ func (c *Client) GenerateText(prompt, provider string) (string, error) {
 					req := types.TextRequest{
 						Prompt: prompt,
 					}
-					resp, err = p.GenerateText(req)
+					resp, err = p.GenerateText(ctx, req)
 					break
 				}
 			}
@@ -113,7 +136,7 @@ func (c *Client) GenerateText(prompt, provider string) (string, error) {
 		req := types.TextRequest{
 			Prompt: prompt,
 		}
-		resp, err = p.GenerateText(req)
+		resp, err = p.GenerateText(ctx, req)
 	}
 
 	if err != nil {
@@ -140,8 +163,9 @@ func (c *Client) GenerateText(prompt, provider string) (string, error) {
 	return resp.Text, nil
 }
 
-// GenerateTextWithOptions توليد نص مع خيارات متقدمة
-func (c *Client) GenerateTextWithOptions(req types.TextRequest) (*types.TextResponse, error) {
+// GenerateTextWithOptions توليد نص مع خيارات متقدمة. الطلبات المتطابقة تُخدم من
+// التخزين المؤقت، والمتزامنة منها تنتظر استدعاءً واحداً للمزود
+func (c *Client) GenerateTextWithOptions(ctx context.Context, req types.TextRequest) (*types.TextResponse, error) {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
@@ -151,7 +175,15 @@ func (c *Client) GenerateTextWithOptions(req types.TextRequest) (*types.TextResp
 	if req.Model == "" || req.Model == "auto" {
 		// استخدام MultiProvider للاختيار التلقائي إذا كان متاحاً
 		if c.multiProvider != nil && c.multiProvider.IsAvailable() {
-			return c.multiProvider.GenerateText(req)
+			// الاستراتيجية تختار المزود حسب الطبقة، فتدخل الطبقة في المفتاح
+			key, _ := c.responseCache.TextKey("auto:"+req.UserTier, req)
+			resp, hit, err := cachedCall(ctx, c.responseCache, CacheOpText, key, func() (*types.TextResponse, error) {
+				return c.multiProvider.GenerateText(ctx, req)
+			})
+			if err == nil && hit {
+				c.recordCacheHit("auto", "text", req.UserID, req.UserTier, resp.Cost, int64(resp.Tokens))
+			}
+			return resp, err
 		}
 
 		// استخدام أول مزود نص متاح
@@ -173,34 +205,128 @@ func (c *Client) GenerateTextWithOptions(req types.TextRequest) (*types.TextResp
 		return nil, fmt.Errorf("no available text provider")
 	}
 
-	resp, err := provider.GenerateText(req)
+	key, _ := c.responseCache.TextKey(provider.GetName(), req)
+	resp, hit, err := cachedCall(ctx, c.responseCache, CacheOpText, key, func() (*types.TextResponse, error) {
+		resp, err := provider.GenerateText(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+
+		// تسجيل الاستخدام
+		if c.costManager != nil {
+			record := &types.UsageRecord{
+				Provider:  provider.GetName(),
+				Type:      "text",
+				Cost:      resp.Cost,
+				Quantity:  int64(resp.Tokens),
+				Success:   true,
+				Timestamp: resp.CreatedAt,
+			}
+			c.costManager.RecordUsage(record)
+		}
+		return resp, nil
+	})
 	if err != nil {
 		return nil, err
 	}
-
-	// تسجيل الاستخدام
-	if c.costManager != nil {
-		record := &types.UsageRecord{
-			Provider:  provider.GetName(),
-			Type:      "text",
-			Cost:      resp.Cost,
-			Quantity:  int64(resp.Tokens),
-			Success:   true,
-			Timestamp: resp.CreatedAt,
-		}
-		c.costManager.RecordUsage(record)
+	if hit {
+		c.recordCacheHit(provider.GetName(), "text", req.UserID, req.UserTier, resp.Cost, int64(resp.Tokens))
 	}
 
 	return resp, nil
 }
 
-// GenerateImage توليد صورة
+// GenerateTextStream توليد نص متدفق. provider فارغ أو "auto" يترك الاختيار للمزود
+// المتعدد (وهو يسجل الاستخدام)، وإلا فالمزود المحدد أو أول مزود نص متاح يدعم البث.
+// البث لا يمر بالتخزين المؤقت، وتكلفته تُسجل عند انتهائه ولو كان جزئيًا
+func (c *Client) GenerateTextStream(ctx context.Context, req types.TextRequest, provider string) (<-chan types.StreamChunk, error) {
+	c.mu.RLock()
+	multiProvider := c.multiProvider
+	var streamer types.StreamingProvider
+	var name string
+	if provider == "" || provider == "auto" {
+		if multiProvider == nil || !multiProvider.IsAvailable() {
+			for _, p := range c.providers {
+				if s, ok := p.(types.StreamingProvider); ok && p.SupportsStreaming() && p.IsAvailable() && p.GetType() == "text" {
+					streamer, name = s, p.GetName()
+					break
+				}
+			}
+			multiProvider = nil
+		}
+	} else {
+		p, exists := c.providers[provider]
+		if !exists {
+			c.mu.RUnlock()
+			return nil, fmt.Errorf("provider %s not found", provider)
+		}
+		s, ok := p.(types.StreamingProvider)
+		if !ok || !p.SupportsStreaming() {
+			c.mu.RUnlock()
+			return nil, types.ErrStreamNotSupported.WithProvider(p.GetName())
+		}
+		streamer, name = s, p.GetName()
+		multiProvider = nil
+	}
+	c.mu.RUnlock()
+
+	if multiProvider != nil {
+		return multiProvider.GenerateTextStream(ctx, req)
+	}
+	if streamer == nil {
+		return nil, types.ErrStreamNotSupported
+	}
+
+	stream, err := streamer.GenerateTextStream(ctx, req)
+	if err != nil || c.costManager == nil {
+		return stream, err
+	}
+
+	out := make(chan types.StreamChunk)
+	go func() {
+		defer close(out)
+
+		for chunk := range stream {
+			if chunk.Done {
+				record := &types.UsageRecord{
+					UserID:    req.UserID,
+					UserTier:  req.UserTier,
+					OrgID:     req.OrgID,
+					Provider:  name,
+					Type:      "text",
+					Cost:      chunk.Response.Cost,
+					Quantity:  int64(chunk.Response.Tokens),
+					Success:   chunk.Err == nil,
+					Timestamp: chunk.Response.CreatedAt,
+					Metadata: map[string]interface{}{
+						"stream":  true,
+						"partial": chunk.Err != nil,
+					},
+				}
+				if chunk.Err != nil {
+					record.Error = chunk.Err.Error()
+				}
+				c.costManager.RecordUsage(record)
+			}
+			out <- chunk
+		}
+	}()
+
+	return out, nil
+}
+
+// GenerateImage توليد صورة بدون سياق (للمستدعين القدامى)
 func (c *Client) GenerateImage(prompt, provider string) (string, error) {
+	return c.GenerateImageContext(context.Background(), prompt, provider)
+}
+
+// GenerateImageContext توليد صورة
+func (c *Client) GenerateImageContext(ctx context.Context, prompt, provider string) (string, error) {
 	req := types.ImageRequest{
 		Prompt: prompt,
 	}
 
-	resp, err := c.GenerateImageWithOptions(req)
+	resp, err := c.GenerateImageWithOptions(ctx, req)
 	if err != nil {
 		return "", err
 	}
@@ -209,14 +335,17 @@ func (c *Client) GenerateImage(prompt, provider string) (string, error) {
 }
 
 // GenerateImageWithOptions توليد صورة مع خيارات متقدمة
-func (c *Client) GenerateImageWithOptions(req types.ImageRequest) (*types.ImageResponse, error) {
+func (c *Client) GenerateImageWithOptions(ctx context.Context, req types.ImageRequest) (*types.ImageResponse, error) {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
 	// البحث عن مزود صور
 	for _, p := range c.providers {
 		if p.IsAvailable() {
-			resp, err := p.GenerateImage(req)
+			resp, err := p.GenerateImage(ctx, req)
+			if types.IsCanceled(err) {
+				return nil, err
+			}
 			if err == nil {
 				// تسجيل الاستخدام
 				if c.costManager != nil {
@@ -238,14 +367,19 @@ func (c *Client) GenerateImageWithOptions(req types.ImageRequest) (*types.ImageR
 	return nil, fmt.Errorf("no available image provider")
 }
 
-// GenerateVideo توليد فيديو
+// GenerateVideo توليد فيديو بدون سياق (للمستدعين القدامى)
 func (c *Client) GenerateVideo(prompt, provider string) (string, error) {
+	return c.GenerateVideoContext(context.Background(), prompt, provider)
+}
+
+// GenerateVideoContext توليد فيديو
+func (c *Client) GenerateVideoContext(ctx context.Context, prompt, provider string) (string, error) {
 	req := types.VideoRequest{
 		Prompt:   prompt,
 		Duration: 30, // 30 ثانية افتراضياً
 	}
 
-	resp, err := c.GenerateVideoWithOptions(req)
+	resp, err := c.GenerateVideoWithOptions(ctx, req)
 	if err != nil {
 		return "", err
 	}
@@ -254,14 +388,17 @@ func (c *Client) GenerateVideo(prompt, provider string) (string, error) {
 }
 
 // GenerateVideoWithOptions توليد فيديو مع خيارات متقدمة
-func (c *Client) GenerateVideoWithOptions(req types.VideoRequest) (*types.VideoResponse, error) {
+func (c *Client) GenerateVideoWithOptions(ctx context.Context, req types.VideoRequest) (*types.VideoResponse, error) {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
 	// البحث عن مزود فيديو
 	for _, p := range c.providers {
 		if p.IsAvailable() {
-			resp, err := p.GenerateVideo(req)
+			resp, err := p.GenerateVideo(ctx, req)
+			if types.IsCanceled(err) {
+				return nil, err
+			}
 			if err == nil {
 				// تسجيل الاستخدام
 				if c.costManager != nil {
@@ -283,24 +420,32 @@ func (c *Client) GenerateVideoWithOptions(req types.VideoRequest) (*types.VideoR
 	return nil, fmt.Errorf("no available video provider")
 }
 
-// AnalyzeText تحليل نص
+// AnalyzeText تحليل نص بدون سياق (للمستدعين القدامى)
 func (c *Client) AnalyzeText(text, provider string) (*types.AnalysisResponse, error) {
+	return c.AnalyzeTextContext(context.Background(), text, provider)
+}
+
+// AnalyzeTextContext تحليل نص
+func (c *Client) AnalyzeTextContext(ctx context.Context, text, provider string) (*types.AnalysisResponse, error) {
 	req := types.AnalysisRequest{
 		Text: text,
 	}
 
-	return c.AnalyzeTextWithOptions(req)
+	return c.AnalyzeTextWithOptions(ctx, req)
 }
 
 // AnalyzeTextWithOptions تحليل نص مع خيارات متقدمة
-func (c *Client) AnalyzeTextWithOptions(req types.AnalysisRequest) (*types.AnalysisResponse, error) {
+func (c *Client) AnalyzeTextWithOptions(ctx context.Context, req types.AnalysisRequest) (*types.AnalysisResponse, error) {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
 	// البحث عن مزود يدعم تحليل النصوص
 	for _, p := range c.providers {
 		if p.IsAvailable() {
-			resp, err := p.AnalyzeText(req)
+			resp, err := p.AnalyzeText(ctx, req)
+			if types.IsCanceled(err) {
+				return nil, err
+			}
 			if err == nil {
 				// تسجيل الاستخدام
 				if c.costManager != nil {
@@ -322,15 +467,20 @@ func (c *Client) AnalyzeTextWithOptions(req types.AnalysisRequest) (*types.Analy
 	return nil, fmt.Errorf("no available text analysis provider")
 }
 
-// TranslateText ترجمة نص
+// TranslateText ترجمة نص بدون سياق (للمستدعين القدامى)
 func (c *Client) TranslateText(text, fromLang, toLang, provider string) (string, error) {
+	return c.TranslateTextContext(context.Background(), text, fromLang, toLang, provider)
+}
+
+// TranslateTextContext ترجمة نص
+func (c *Client) TranslateTextContext(ctx context.Context, text, fromLang, toLang, provider string) (string, error) {
 	req := types.TranslationRequest{
 		Text:     text,
 		FromLang: fromLang,
 		ToLang:   toLang,
 	}
 
-	resp, err := c.TranslateTextWithOptions(req)
+	resp, err := c.TranslateTextWithOptions(ctx, req)
 	if err != nil {
 		return "", err
 	}
@@ -338,15 +488,34 @@ func (c *Client) TranslateText(text, fromLang, toLang, provider string) (string,
 	return resp.TranslatedText, nil
 }
 
-// TranslateTextWithOptions ترجمة نص مع خيارات متقدمة
-func (c *Client) TranslateTextWithOptions(req types.TranslationRequest) (*types.TranslationResponse, error) {
+// TranslateTextWithOptions ترجمة نص مع خيارات متقدمة (مع التخزين المؤقت للترجمات المتطابقة)
+func (c *Client) TranslateTextWithOptions(ctx context.Context, req types.TranslationRequest) (*types.TranslationResponse, error) {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
+	// العميل يختار المزود، فيكون المفتاح على "auto" والنموذج المطلوب
+	key, _ := c.responseCache.TranslationKey("auto", req)
+	resp, hit, err := cachedCall(ctx, c.responseCache, CacheOpTranslation, key, func() (*types.TranslationResponse, error) {
+		return c.translate(ctx, req)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if hit {
+		c.recordCacheHit("auto", "translation", req.UserID, req.UserTier, resp.Cost, 1)
+	}
+	return resp, nil
+}
+
+// translate ترجمة عبر أول مزود ينجح
+func (c *Client) translate(ctx context.Context, req types.TranslationRequest) (*types.TranslationResponse, error) {
 	// البحث عن مزود يدعم الترجمة
 	for _, p := range c.providers {
 		if p.IsAvailable() {
-			resp, err := p.TranslateText(req)
+			resp, err := p.TranslateText(ctx, req)
+			if types.IsCanceled(err) {
+				return nil, err
+			}
 			if err == nil {
 				// تسجيل الاستخدام
 				if c.costManager != nil {
@@ -368,25 +537,33 @@ func (c *Client) TranslateTextWithOptions(req types.TranslationRequest) (*types.
 	return nil, fmt.Errorf("no available translation provider")
 }
 
-// AnalyzeImage تحليل صورة
+// AnalyzeImage تحليل صورة بدون سياق (للمستدعين القدامى)
 func (c *Client) AnalyzeImage(imageData []byte, prompt, provider string) (*types.AnalysisResponse, error) {
+	return c.AnalyzeImageContext(context.Background(), imageData, prompt, provider)
+}
+
+// AnalyzeImageContext تحليل صورة
+func (c *Client) AnalyzeImageContext(ctx context.Context, imageData []byte, prompt, provider string) (*types.AnalysisResponse, error) {
 	req := types.AnalysisRequest{
 		ImageData: imageData,
 		Prompt:    prompt,
 	}
 
-	return c.AnalyzeImageWithOptions(req)
+	return c.AnalyzeImageWithOptions(ctx, req)
 }
 
 // AnalyzeImageWithOptions تحليل صورة مع خيارات متقدمة
-func (c *Client) AnalyzeImageWithOptions(req types.AnalysisRequest) (*types.AnalysisResponse, error) {
+func (c *Client) AnalyzeImageWithOptions(ctx context.Context, req types.AnalysisRequest) (*types.AnalysisResponse, error) {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
 	// البحث عن مزود يدعم تحليل الصور
 	for _, p := range c.providers {
 		if p.IsAvailable() {
-			resp, err := p.AnalyzeImage(req)
+			resp, err := p.AnalyzeImage(ctx, req)
+			if types.IsCanceled(err) {
+				return nil, err
+			}
 			if err == nil {
 				// تسجيل الاستخدام
 				if c.costManager != nil {
@@ -408,15 +585,20 @@ func (c *Client) AnalyzeImageWithOptions(req types.AnalysisRequest) (*types.Anal
 	return nil, fmt.Errorf("no available image analysis provider")
 }
 
-// GetVideoStatus الحصول على حالة فيديو
+// GetVideoStatus الحصول على حالة فيديو بدون سياق (للمستدعين القدامى)
 func (c *Client) GetVideoStatus(operationID string) (*types.VideoResponse, error) {
+	return c.GetVideoStatusContext(context.Background(), operationID)
+}
+
+// GetVideoStatusContext الحصول على حالة فيديو
+func (c *Client) GetVideoStatusContext(ctx context.Context, operationID string) (*types.VideoResponse, error) {
 	// البحث عن أي مزود فيديو يدعم GetVideoStatus
 	for _, p := range c.providers {
 		if p.IsAvailable() && p.GetType() == "video" {
 			if videoProvider, ok := p.(interface {
-				GetVideoStatus(string) (*types.VideoResponse, error)
+				GetVideoStatus(context.Context, string) (*types.VideoResponse, error)
 			}); ok {
-				return videoProvider.GetVideoStatus(operationID)
+				return videoProvider.GetVideoStatus(ctx, operationID)
 			}
 		}
 	}
@@ -508,14 +690,43 @@ func (c *Client) GetProviderStats(providerName string) (*types.ProviderStats, er
 // GetUsageStatistics الحصول على إحصائيات الاستخدام
 func (c *Client) GetUsageStatistics() map[string]interface{} {
 	if c.costManager != nil {
-		return c.costManager.GetUsageStatistics()
+		stats := c.costManager.GetUsageStatistics()
+		stats["response_cache"] = c.responseCache.Stats()
+		return stats
 	}
 
 	return map[string]interface{}{
-		"total_cost": 0.0,
-		"providers":  len(c.providers),
-		"message":    "cost manager not available",
+		"total_cost":     0.0,
+		"providers":      len(c.providers),
+		"message":        "cost manager not available",
+		"response_cache": c.responseCache.Stats(),
+	}
+}
+
+// CostManager مدير التكاليف المشترك مع المزود المتعدد (nil إن تعذر إنشاؤه)
+func (c *Client) CostManager() *CostManager {
+	return c.costManager
+}
+
+// MultiProvider المزود المتعدد الذي يوجّه الطلبات ويحتسب تكلفتها (nil إن تعذر إنشاؤه)
+func (c *Client) MultiProvider() *MultiProvider {
+	return c.multiProvider
+}
+
+// GetProviderHealth حالة قواطع الدائرة لمزودي المزود المتعدد (فارغة بدونه)
+func (c *Client) GetProviderHealth() []BreakerSnapshot {
+	if c.multiProvider == nil {
+		return []BreakerSnapshot{}
 	}
+	return c.multiProvider.GetBreakerStates()
+}
+
+// SetResponseCache استبدال التخزين المؤقت للاستجابات (nil يعطله)
+func (c *Client) SetResponseCache(rc *ResponseCache) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.responseCache = rc
 }
 
 // Close إغلاق العميل وتحرير الموارد
@@ -525,6 +736,10 @@ func (c *Client) Close() error {
 
 	log.Println("Closing AI client...")
 
+	if c.multiProvider != nil {
+		c.multiProvider.Close()
+	}
+
 	// إغلاق جميع المزودين
 	for name, provider := range c.providers {
 		if closer, ok := provider.(interface{ Close() error }); ok {
@@ -539,6 +754,25 @@ func (c *Client) Close() error {
 
 // Helper functions
 
+// recordCacheHit تسجيل استجابة خُدمت من التخزين المؤقت: بلا تكلفة، مع التكلفة الموفرة
+func (c *Client) recordCacheHit(provider, usageType, userID, userTier string, savedCost float64, quantity int64) {
+	c.responseCache.addSaved(savedCost)
+	if c.costManager == nil {
+		return
+	}
+	c.costManager.RecordUsage(&types.UsageRecord{
+		UserID:    userID,
+		UserTier:  userTier,
+		Provider:  provider,
+		Type:      usageType,
+		Quantity:  quantity,
+		Success:   true,
+		CacheHit:  true,
+		SavedCost: savedCost,
+		Timestamp: time.Now(),
+	})
+}
+
 func (c *Client) getProviderByModel(model string) (types.ProviderInterface, error) {
 	// بحث مبسط عن المزود المناسب للنموذج
 	for _, provider := range c.providers {
@@ -580,3 +814,5 @@ func (c *Client) RemoveProvider(name string) {
 func (c *Client) GetVideoStatusWrapper(operationID string) (*types.VideoResponse, error) {
 	return c.GetVideoStatus(operationID)
 }
+
+var _ types.AIClientInterface = (*Client)(nil)
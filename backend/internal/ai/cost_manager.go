@@ -1,22 +1,32 @@
 package ai
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/nawthtech/nawthtech/backend/internal/ai/types"
+	"github.com/nawthtech/nawthtech/backend/internal/repository"
 	"os"
 	"path/filepath"
 	"sync"
 	"time"
 )
 
-// CostManager مدير التكاليف والإحصائيات
+// CostManager مدير التكاليف والإحصائيات. يحفظ في cost_data.json لنسخة واحدة،
+// أو في قاعدة البيانات مع الميزانيات عند إنشائه بـ NewCostManagerWithStore
 type CostManager struct {
 	mu           sync.RWMutex
 	dataPath     string
 	monthlyLimit float64
 	dailyLimit   float64
 	Usage        UsageStats
+
+	// usage سجلات الاستخدام والميزانيات في قاعدة البيانات (nil = الملف)
+	usage         *repository.AIUsageRepository
+	notifications *repository.NotificationRepository
+	// sendAlert إرسال تنبيه Slack بتوقيع SlackClient.SendAlert
+	sendAlert func(alertType, title, message string) (string, string, error)
+	now       func() time.Time
 }
 
 // UsageStats إحصائيات الاستخدام
@@ -26,6 +36,8 @@ type UsageStats struct {
 	DailyCost     map[string]float64         `json:"daily_cost"`
 	UserUsage     map[string]*UserUsageStats `json:"user_usage"`
 	ProviderUsage map[string]*ProviderStats  `json:"provider_usage"`
+	CacheHits     int64                      `json:"cache_hits"`
+	SavedCost     float64                    `json:"saved_cost"`
 	LastReset     time.Time                  `json:"last_reset"`
 }
 
@@ -47,6 +59,8 @@ type ProviderStats struct {
 	TotalCost     float64   `json:"total_cost"`
 	AvgLatency    float64   `json:"avg_latency"`
 	SuccessRate   float64   `json:"success_rate"`
+	CacheHits     int64     `json:"cache_hits"`
+	SavedCost     float64   `json:"saved_cost"`
 	LastUsed      time.Time `json:"last_used"`
 }
 
@@ -57,6 +71,7 @@ type Quota struct {
 	Limit       int64     `json:"limit"`        // الحد الأقصى
 	ResetPeriod string    `json:"reset_period"` // daily, weekly, monthly
 	LastReset   time.Time `json:"last_reset"`
+	Reserved    int64     `json:"reserved"` // محجوز لاستدعاءات جارية
 }
 
 // NewCostManager إنشاء CostManager جديد
@@ -70,6 +85,7 @@ func NewCostManager() (*CostManager, error) {
 		dataPath:     dataPath,
 		monthlyLimit: 0.0, // 0 = لا يوجد حد (مجاني)
 		dailyLimit:   0.0,
+		now:          time.Now,
 	}
 
 	// تهيئة البيانات
@@ -248,6 +264,13 @@ func (cm *CostManager) getDefaultQuotas(tier string) map[string]*Quota {
 			ResetPeriod: "monthly",
 			LastReset:   now,
 		}
+		quotas["video"] = &Quota{
+			Type:        "video",
+			Used:        0,
+			Limit:       30,
+			ResetPeriod: "monthly",
+			LastReset:   now,
+		}
 	default: // free
 		quotas["text"] = &Quota{
 			Type:        "text",
@@ -263,6 +286,13 @@ func (cm *CostManager) getDefaultQuotas(tier string) map[string]*Quota {
 			ResetPeriod: "daily",
 			LastReset:   now,
 		}
+		quotas["video"] = &Quota{
+			Type:        "video",
+			Used:        0,
+			Limit:       10,
+			ResetPeriod: "monthly",
+			LastReset:   now,
+		}
 	}
 
 	return quotas
@@ -270,6 +300,10 @@ func (cm *CostManager) getDefaultQuotas(tier string) map[string]*Quota {
 
 // RecordUsage تسجيل استخدام
 func (cm *CostManager) RecordUsage(record *types.UsageRecord) error {
+	if cm.usage != nil {
+		return cm.recordUsageDB(record)
+	}
+
 	cm.mu.Lock()
 	defer cm.mu.Unlock()
 
@@ -284,26 +318,13 @@ func (cm *CostManager) RecordUsage(record *types.UsageRecord) error {
 
 	// تحديث إحصائيات المستخدم
 	if record.UserID != "" {
-		if _, exists := cm.Usage.UserUsage[record.UserID]; !exists {
-			cm.Usage.UserUsage[record.UserID] = &UserUsageStats{
-				UserID:      record.UserID,
-				Tier:        record.UserTier,
-				MonthlyCost: make(map[string]float64),
-				DailyCost:   make(map[string]float64),
-				Quotas:      cm.getDefaultQuotas(record.UserTier),
-			}
-		}
-
-		userStats := cm.Usage.UserUsage[record.UserID]
+		userStats := cm.userStats(record.UserID, record.UserTier)
 		userStats.TotalCost += record.Cost
 		userStats.MonthlyCost[monthKey] += record.Cost
 		userStats.DailyCost[dayKey] += record.Cost
 		userStats.LastActive = now
 
-		// تحديث الحصص
-		if quota, exists := userStats.Quotas[record.Type]; exists {
-			quota.Used += record.Quantity
-		}
+		// الحصص تُحتسب عند اعتماد الحجز في CommitQuota لا هنا
 	}
 
 	// تحديث إحصائيات المزود
@@ -314,6 +335,18 @@ func (cm *CostManager) RecordUsage(record *types.UsageRecord) error {
 	}
 
 	providerStats := cm.Usage.ProviderUsage[record.Provider]
+
+	// إصابة التخزين المؤقت لم تصل للمزود: تحتسب التكلفة الموفرة فقط دون
+	// المساس بزمن الاستجابة ونسبة النجاح
+	if record.CacheHit {
+		cm.Usage.CacheHits++
+		cm.Usage.SavedCost += record.SavedCost
+		providerStats.CacheHits++
+		providerStats.SavedCost += record.SavedCost
+		go cm.save()
+		return nil
+	}
+
 	providerStats.TotalRequests++
 	providerStats.TotalCost += record.Cost
 	providerStats.LastUsed = now
@@ -353,6 +386,10 @@ func (cm *CostManager) RecordUsage(record *types.UsageRecord) error {
 
 // CanUseAI التحقق من إمكانية استخدام AI
 func (cm *CostManager) CanUseAI(userID, requestType string) (bool, string) {
+	if cm.usage != nil {
+		return cm.canUseAIDB(userID)
+	}
+
 	cm.mu.RLock()
 	defer cm.mu.RUnlock()
 
@@ -361,54 +398,83 @@ func (cm *CostManager) CanUseAI(userID, requestType string) (bool, string) {
 		return true, ""
 	}
 
+	if reason, _, blocked := cm.fileBudgetBlock(userID); blocked {
+		return false, reason
+	}
+
+	// التحقق من حصص النوع المحدد
+	if userStats, exists := cm.Usage.UserUsage[userID]; exists && userID != "" {
+		if quota, exists := userStats.Quotas[requestType]; exists {
+			if quota.Used+quota.Reserved >= quota.Limit {
+				return false, fmt.Sprintf("تم تجاوز حصة %s لهذا المستخدم", requestType)
+			}
+		}
+	}
+
+	return true, ""
+}
+
+// fileBudgetBlock حدود التكلفة العامة وحد المستخدم الشهري حسب طبقته، مع موعد انتهاء
+// الفترة التي بُلغ حدها. يُستدعى مع قفل القراءة
+func (cm *CostManager) fileBudgetBlock(userID string) (string, time.Time, bool) {
 	now := time.Now()
 	monthKey := now.Format("2006-01")
 	dayKey := now.Format("2006-01-02")
+	monthStart, _ := periodStart(BudgetMonthly, now)
+	dayStart, _ := periodStart(BudgetDaily, now)
 
 	// التحقق من الحدود العامة
 	if cm.monthlyLimit > 0 && cm.Usage.MonthlyCost[monthKey] >= cm.monthlyLimit {
-		return false, "تم تجاوز الحد الشهري للتكاليف"
+		return "تم تجاوز الحد الشهري للتكاليف", periodEnd(BudgetMonthly, monthStart), true
 	}
 
 	if cm.dailyLimit > 0 && cm.Usage.DailyCost[dayKey] >= cm.dailyLimit {
-		return false, "تم تجاوز الحد اليومي للتكاليف"
+		return "تم تجاوز الحد اليومي للتكاليف", periodEnd(BudgetDaily, dayStart), true
 	}
 
-	// التحقق من حصص المستخدم
-	if userID != "" {
-		if userStats, exists := cm.Usage.UserUsage[userID]; exists {
-			// التحقق من التكلفة الشهرية للمستخدم
-			if monthlyCost, ok := userStats.MonthlyCost[monthKey]; ok {
-				// حساب الحد الشهري حسب الطبقة
-				var userMonthlyLimit float64
-				switch userStats.Tier {
-				case "premium":
-					userMonthlyLimit = 100.0 // 100 دولار
-				case "basic":
-					userMonthlyLimit = 10.0 // 10 دولار
-				default:
-					userMonthlyLimit = 1.0 // 1 دولار
-				}
-
-				if monthlyCost >= userMonthlyLimit {
-					return false, "تم تجاوز حد التكلفة الشهرية للمستخدم"
-				}
+	// التحقق من التكلفة الشهرية للمستخدم
+	if userStats, exists := cm.Usage.UserUsage[userID]; exists && userID != "" {
+		if monthlyCost, ok := userStats.MonthlyCost[monthKey]; ok {
+			// حساب الحد الشهري حسب الطبقة
+			var userMonthlyLimit float64
+			switch userStats.Tier {
+			case "premium":
+				userMonthlyLimit = 100.0 // 100 دولار
+			case "basic":
+				userMonthlyLimit = 10.0 // 10 دولار
+			default:
+				userMonthlyLimit = 1.0 // 1 دولار
 			}
 
-			// التحقق من حصص النوع المحدد
-			if quota, exists := userStats.Quotas[requestType]; exists {
-				if quota.Used >= quota.Limit {
-					return false, fmt.Sprintf("تم تجاوز حصة %s لهذا المستخدم", requestType)
-				}
+			if monthlyCost >= userMonthlyLimit {
+				return "تم تجاوز حد التكلفة الشهرية للمستخدم", periodEnd(BudgetMonthly, monthStart), true
 			}
 		}
 	}
 
-	return true, ""
+	return "", time.Time{}, false
+}
+
+// userStats إحصائيات المستخدم، تُنشأ بحصص طبقته عند أول استخدام. يُستدعى مع قفل الكتابة
+func (cm *CostManager) userStats(userID, tier string) *UserUsageStats {
+	if _, exists := cm.Usage.UserUsage[userID]; !exists {
+		cm.Usage.UserUsage[userID] = &UserUsageStats{
+			UserID:      userID,
+			Tier:        tier,
+			MonthlyCost: make(map[string]float64),
+			DailyCost:   make(map[string]float64),
+			Quotas:      cm.getDefaultQuotas(tier),
+		}
+	}
+	return cm.Usage.UserUsage[userID]
 }
 
 // GetUsageStatistics الحصول على إحصائيات الاستخدام
 func (cm *CostManager) GetUsageStatistics() map[string]interface{} {
+	if cm.usage != nil {
+		return cm.usageStatisticsDB()
+	}
+
 	cm.mu.RLock()
 	defer cm.mu.RUnlock()
 
@@ -418,6 +484,8 @@ func (cm *CostManager) GetUsageStatistics() map[string]interface{} {
 	stats["daily_cost"] = cm.Usage.DailyCost
 	stats["total_users"] = len(cm.Usage.UserUsage)
 	stats["providers"] = len(cm.Usage.ProviderUsage)
+	stats["cache_hits"] = cm.Usage.CacheHits
+	stats["saved_cost"] = cm.Usage.SavedCost
 	stats["last_reset"] = cm.Usage.LastReset
 
 	// حساب التكلفة الشهرية الحالية
@@ -449,11 +517,21 @@ func (cm *CostManager) SetLimits(monthly, daily float64) {
 	cm.monthlyLimit = monthly
 	cm.dailyLimit = daily
 
+	if cm.usage != nil {
+		cm.setGlobalLimitsDB(monthly, daily)
+		return
+	}
 	go cm.save()
 }
 
 // ResetUsage إعادة تعيين جميع الإحصائيات
 func (cm *CostManager) ResetUsage() error {
+	if cm.usage != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), budgetQueryTimeout)
+		defer cancel()
+		return cm.usage.Purge(ctx, cm.now())
+	}
+
 	cm.mu.Lock()
 	defer cm.mu.Unlock()
 
@@ -462,6 +540,8 @@ func (cm *CostManager) ResetUsage() error {
 	cm.Usage.DailyCost = make(map[string]float64)
 	cm.Usage.UserUsage = make(map[string]*UserUsageStats)
 	cm.Usage.ProviderUsage = make(map[string]*ProviderStats)
+	cm.Usage.CacheHits = 0
+	cm.Usage.SavedCost = 0
 	cm.Usage.LastReset = time.Now()
 
 	return cm.save()
@@ -0,0 +1,265 @@
+package ai
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/nawthtech/nawthtech/backend/internal/ai/types"
+)
+
+// ================================
+// التحويل التلقائي بين المزودين
+// ================================
+
+// FailoverOptions حدود التحويل التلقائي على سلسلة المزودين الاحتياطية
+type FailoverOptions struct {
+	// PerProviderTimeout مهلة كل محاولة على حدة (صفر = مهلة سياق الطلب فقط)
+	PerProviderTimeout time.Duration
+	// MaxAttempts أقصى عدد من المزودين يُستدعى للطلب الواحد
+	MaxAttempts int
+}
+
+// DefaultFailoverOptions الإعدادات الافتراضية للتحويل التلقائي
+func DefaultFailoverOptions() FailoverOptions {
+	return FailoverOptions{
+		PerProviderTimeout: 60 * time.Second,
+		MaxAttempts:        3,
+	}
+}
+
+// FailoverOptionsFromEnv إعدادات التحويل من AI_PROVIDER_TIMEOUT و AI_FAILOVER_MAX_ATTEMPTS
+func FailoverOptionsFromEnv() FailoverOptions {
+	opts := DefaultFailoverOptions()
+	opts.PerProviderTimeout = envDuration("AI_PROVIDER_TIMEOUT", opts.PerProviderTimeout)
+	if n, err := strconv.Atoi(getEnvWithFallback("AI_FAILOVER_MAX_ATTEMPTS", "")); err == nil && n > 0 {
+		opts.MaxAttempts = n
+	}
+	return opts
+}
+
+// failoverCandidate مزود مرشح ضمن السلسلة
+type failoverCandidate struct {
+	providerType ProviderType
+	provider     types.ProviderInterface
+}
+
+// routeDecision قرار التوجيه لعملية: الإستراتيجية والمزود الأساسي وتفاصيل الطلب
+type routeDecision struct {
+	strategy RoutingStrategy
+	primary  ProviderType
+	request  RoutingRequest
+}
+
+// failoverResult نتيجة السير على السلسلة: المزود الأخير والمحاولات بالترتيب
+type failoverResult struct {
+	provider     types.ProviderInterface
+	providerType ProviderType
+	attempts     []types.ProviderAttempt
+}
+
+// SetFailoverOptions تعيين حدود التحويل التلقائي
+func (mp *MultiProvider) SetFailoverOptions(opts FailoverOptions) {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+
+	mp.failover = opts
+}
+
+// candidates المزود الأساسي ثم سلسلته الاحتياطية دون تكرار، مما هو مُسجَّل فعلًا
+func (mp *MultiProvider) candidates(decision routeDecision) ([]failoverCandidate, FailoverOptions) {
+	chain := append([]ProviderType{decision.primary},
+		decision.strategy.GetFallbackChain(decision.primary, decision.request.ProviderType)...)
+
+	mp.mu.RLock()
+	defer mp.mu.RUnlock()
+
+	seen := make(map[ProviderType]bool, len(chain))
+	result := make([]failoverCandidate, 0, len(chain))
+	for _, pt := range chain {
+		if seen[pt] {
+			continue
+		}
+		seen[pt] = true
+		if provider, exists := mp.providers[pt]; exists {
+			result = append(result, failoverCandidate{providerType: pt, provider: provider})
+		}
+	}
+
+	opts := mp.failover
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = len(result)
+	}
+	return result, opts
+}
+
+// recordFallback احتساب انتقال إلى مزود احتياطي
+func (mp *MultiProvider) recordFallback(from, to ProviderType) {
+	mp.mu.Lock()
+	mp.stats.FallbackCount[to]++
+	mp.mu.Unlock()
+
+	log.Printf("🔄 Fallback from %s to %s", from, to)
+}
+
+// withFailover تنفيذ العملية على المزود الأساسي ثم على السلسلة الاحتياطية ما دام
+// الخطأ قابلًا لإعادة المحاولة (types.IsRetryable). تنتهي السلسلة عند النجاح، أو
+// خطأ نهائي، أو إلغاء سياق الطلب، أو نفاد ميزانية المحاولات. المزود ذو القاطع
+// المفتوح يُتخطى دون أن يُحتسب من الميزانية
+func withFailover[T any](ctx context.Context, mp *MultiProvider, decision routeDecision,
+	call func(context.Context, types.ProviderInterface) (*T, error)) (*T, failoverResult, error) {
+	return withFailoverWhere(ctx, mp, decision, nil, call)
+}
+
+// withFailoverWhere مثل withFailover مع تخطي المزودين الذين لا يقبلهم accept (nil يقبل
+// الجميع) قبل استشارة قواطعهم، للعمليات التي لا يدعمها كل مزود
+func withFailoverWhere[T any](ctx context.Context, mp *MultiProvider, decision routeDecision,
+	accept func(types.ProviderInterface) bool,
+	call func(context.Context, types.ProviderInterface) (*T, error)) (*T, failoverResult, error) {
+	var result failoverResult
+	if err := ctx.Err(); err != nil {
+		return nil, result, types.ContextError(ctx, mp.GetName(), err)
+	}
+
+	candidates, opts := mp.candidates(decision)
+	lastErr := error(types.ErrNoProviderAvailable)
+	for _, candidate := range candidates {
+		if len(result.attempts) >= opts.MaxAttempts {
+			break
+		}
+		if accept != nil && !accept(candidate.provider) {
+			continue
+		}
+		breaker := mp.breaker(candidate.providerType)
+		if !breaker.Allow() {
+			continue
+		}
+		if !candidate.provider.IsAvailable() {
+			breaker.Release()
+			continue
+		}
+		if len(result.attempts) > 0 {
+			mp.recordFallback(result.providerType, candidate.providerType)
+		}
+		result.provider, result.providerType = candidate.provider, candidate.providerType
+
+		attemptCtx, cancel := ctx, context.CancelFunc(func() {})
+		if opts.PerProviderTimeout > 0 {
+			attemptCtx, cancel = context.WithTimeout(ctx, opts.PerProviderTimeout)
+		}
+		start := time.Now()
+		resp, err := call(attemptCtx, candidate.provider)
+		if err != nil && attemptCtx.Err() != nil {
+			// انتهاء مهلة المحاولة وحدها يُعامل كمهلة من المزود فيُنتقل إلى التالي
+			err = types.ContextError(attemptCtx, candidate.provider.GetName(), err)
+		}
+		cancel()
+		if ctx.Err() != nil {
+			breaker.Release()
+		} else {
+			breaker.Record(err)
+		}
+
+		elapsed := time.Since(start)
+		mp.observeLatency(candidate.providerType, elapsed)
+
+		attempt := types.ProviderAttempt{
+			Provider: candidate.provider.GetName(),
+			Success:  err == nil,
+			Latency:  float64(elapsed.Milliseconds()),
+		}
+		mp.updateRequestStats(candidate.providerType, err == nil, responseCost(resp))
+		if err == nil {
+			result.attempts = append(result.attempts, attempt)
+			return resp, result, nil
+		}
+		attempt.Error = err.Error()
+		result.attempts = append(result.attempts, attempt)
+		lastErr = err
+
+		if ctx.Err() != nil {
+			return nil, result, types.ContextError(ctx, candidate.provider.GetName(), err)
+		}
+		if !types.IsRetryable(err) {
+			return nil, result, err
+		}
+	}
+
+	return nil, result, lastErr
+}
+
+// metadata سلسلة المزودين التي جُرّبت فعلًا وتفاصيل كل محاولة
+func (r failoverResult) metadata() map[string]interface{} {
+	chain := make([]string, len(r.attempts))
+	for i, attempt := range r.attempts {
+		chain[i] = attempt.Provider
+	}
+	return map[string]interface{}{
+		"provider_chain": chain,
+		"attempts":       r.attempts,
+	}
+}
+
+// annotate دمج بيانات السلسلة في بيانات الرد أو السجل دون حذف ما فيها
+func (r failoverResult) annotate(metadata map[string]interface{}) map[string]interface{} {
+	if metadata == nil {
+		metadata = make(map[string]interface{})
+	}
+	for key, value := range r.metadata() {
+		metadata[key] = value
+	}
+	return metadata
+}
+
+// responseCost التكلفة الفعلية التي أعادها المزود في الرد
+func responseCost(resp interface{}) float64 {
+	switch r := resp.(type) {
+	case *types.TextResponse:
+		if r != nil {
+			return r.Cost
+		}
+	case *types.ImageResponse:
+		if r != nil {
+			return r.Cost
+		}
+	case *types.VideoResponse:
+		if r != nil {
+			return r.Cost
+		}
+	case *types.AnalysisResponse:
+		if r != nil {
+			return r.Cost
+		}
+	case *types.TranslationResponse:
+		if r != nil {
+			return r.Cost
+		}
+	case *types.EmbeddingResponse:
+		if r != nil {
+			return r.Cost
+		}
+	}
+	return 0
+}
+
+// recordUsage تسجيل استخدام العملية مرة واحدة باسم المزود الأخير مع السلسلة كاملة؛
+// التكلفة والكمية يحددهما المستدعي من الرد
+func (mp *MultiProvider) recordUsage(result failoverResult, record *types.UsageRecord, start time.Time, err error) {
+	if mp.costManager == nil {
+		return
+	}
+
+	record.Provider = mp.GetName()
+	if result.provider != nil {
+		record.Provider = result.provider.GetName()
+	}
+	record.Latency = float64(time.Since(start).Milliseconds())
+	record.Success = err == nil
+	record.Timestamp = time.Now()
+	record.Metadata = result.annotate(record.Metadata)
+	if err != nil {
+		record.Error = err.Error()
+	}
+	mp.costManager.RecordUsage(record)
+}
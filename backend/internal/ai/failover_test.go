@@ -0,0 +1,150 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/nawthtech/nawthtech/backend/internal/ai/types"
+)
+
+// scriptedProvider مزود نصوص يعيد خطأً محددًا أو ينتظر انتهاء السياق
+type scriptedProvider struct {
+	types.ProviderInterface
+	name  string
+	err   error
+	block bool
+	calls int
+}
+
+func (p *scriptedProvider) GenerateText(ctx context.Context, req types.TextRequest) (*types.TextResponse, error) {
+	p.calls++
+	if p.block {
+		<-ctx.Done()
+		return nil, types.ContextError(ctx, p.name, ctx.Err())
+	}
+	if p.err != nil {
+		return nil, p.err
+	}
+	return &types.TextResponse{Text: "from " + p.name}, nil
+}
+
+func (p *scriptedProvider) GetName() string   { return p.name }
+func (p *scriptedProvider) GetCost() float64  { return 0 }
+func (p *scriptedProvider) IsAvailable() bool { return true }
+
+func newScriptedMultiProvider(opts FailoverOptions, providers map[ProviderType]*scriptedProvider) *MultiProvider {
+	mp := &MultiProvider{
+		providers: make(map[ProviderType]types.ProviderInterface),
+		strategy:  &DefaultStrategy{},
+		failover:  opts,
+		stats: &MultiProviderStats{
+			ProviderStats: make(map[ProviderType]*types.ProviderStats),
+			FallbackCount: make(map[ProviderType]int64),
+		},
+	}
+	for pt, p := range providers {
+		mp.providers[pt] = p
+	}
+	return mp
+}
+
+func TestMultiProviderFailover(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      FailoverOptions
+		ollama    *scriptedProvider
+		wantChain []string
+		wantText  string
+		wantErr   *types.ProviderError
+	}{
+		{
+			name:      "retryable error falls through to next provider",
+			opts:      FailoverOptions{MaxAttempts: 3},
+			ollama:    &scriptedProvider{name: "ollama", err: types.HTTPError("ollama", http.StatusServiceUnavailable, "")},
+			wantChain: []string{"ollama", "huggingface"},
+			wantText:  "from huggingface",
+		},
+		{
+			name:      "per-provider timeout moves on",
+			opts:      FailoverOptions{MaxAttempts: 3, PerProviderTimeout: 20 * time.Millisecond},
+			ollama:    &scriptedProvider{name: "ollama", block: true},
+			wantChain: []string{"ollama", "huggingface"},
+			wantText:  "from huggingface",
+		},
+		{
+			name:      "invalid request is not retried",
+			opts:      FailoverOptions{MaxAttempts: 3},
+			ollama:    &scriptedProvider{name: "ollama", err: types.HTTPError("ollama", http.StatusBadRequest, "")},
+			wantChain: []string{"ollama"},
+			wantErr:   types.ErrInvalidRequest,
+		},
+		{
+			name:      "attempt budget caps the chain",
+			opts:      FailoverOptions{MaxAttempts: 1},
+			ollama:    &scriptedProvider{name: "ollama", err: types.HTTPError("ollama", http.StatusTooManyRequests, "")},
+			wantChain: []string{"ollama"},
+			wantErr:   types.ErrRateLimitExceeded,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mp := newScriptedMultiProvider(tt.opts, map[ProviderType]*scriptedProvider{
+				ProviderOllama:      tt.ollama,
+				ProviderHuggingFace: {name: "huggingface"},
+			})
+
+			decision := routeDecision{strategy: mp.strategy, primary: ProviderOllama, request: RoutingRequest{ProviderType: "text"}}
+			resp, result, err := withFailover(context.Background(), mp, decision,
+				func(ctx context.Context, p types.ProviderInterface) (*types.TextResponse, error) {
+					return p.GenerateText(ctx, types.TextRequest{Prompt: "hi"})
+				})
+
+			chain := result.metadata()["provider_chain"].([]string)
+			if len(chain) != len(tt.wantChain) {
+				t.Fatalf("chain = %v, want %v", chain, tt.wantChain)
+			}
+			for i := range chain {
+				if chain[i] != tt.wantChain[i] {
+					t.Fatalf("chain = %v, want %v", chain, tt.wantChain)
+				}
+			}
+
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if resp.Text != tt.wantText {
+				t.Errorf("text = %q, want %q", resp.Text, tt.wantText)
+			}
+		})
+	}
+}
+
+func TestMultiProviderFailoverStopsOnCancel(t *testing.T) {
+	ollama := &scriptedProvider{name: "ollama", block: true}
+	hf := &scriptedProvider{name: "huggingface"}
+	mp := newScriptedMultiProvider(FailoverOptions{MaxAttempts: 3}, map[ProviderType]*scriptedProvider{
+		ProviderOllama:      ollama,
+		ProviderHuggingFace: hf,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	_, err := mp.GenerateText(ctx, types.TextRequest{Prompt: "hi"})
+	if !errors.Is(err, types.ErrRequestTimeout) {
+		t.Fatalf("err = %v, want request timeout", err)
+	}
+	if hf.calls != 0 {
+		t.Errorf("fallback called %d times after the request deadline", hf.calls)
+	}
+}
@@ -1,6 +1,7 @@
 package ai
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -48,7 +49,7 @@ func NewGeminiProvider() *GeminiProvider {
 }
 
 // GenerateText توليد نص باستخدام Gemini
-func (p *GeminiProvider) GenerateText(req types.TextRequest) (*types.TextResponse, error) {
+func (p *GeminiProvider) GenerateText(ctx context.Context, req types.TextRequest) (*types.TextResponse, error) {
 	startTime := time.Now()
 
 	p.mu.Lock()
@@ -73,41 +74,7 @@ func (p *GeminiProvider) GenerateText(req types.TextRequest) (*types.TextRespons
 
 	url := fmt.Sprintf("%s/models/%s:generateContent?key=%s", p.baseURL, model, p.apiKey)
 
-	payload := map[string]interface{}{
-		"contents": []map[string]interface{}{
-			{
-				"parts": []map[string]interface{}{
-					{
-						"text": req.Prompt,
-					},
-				},
-			},
-		},
-		"generationConfig": map[string]interface{}{
-			"temperature":     req.Temperature,
-			"maxOutputTokens": req.MaxTokens,
-			"topP":            0.95,
-			"topK":            40,
-		},
-		"safetySettings": []map[string]interface{}{
-			{
-				"category":  "HARM_CATEGORY_HARASSMENT",
-				"threshold": "BLOCK_MEDIUM_AND_ABOVE",
-			},
-			{
-				"category":  "HARM_CATEGORY_HATE_SPEECH",
-				"threshold": "BLOCK_MEDIUM_AND_ABOVE",
-			},
-			{
-				"category":  "HARM_CATEGORY_SEXUALLY_EXPLICIT",
-				"threshold": "BLOCK_MEDIUM_AND_ABOVE",
-			},
-			{
-				"category":  "HARM_CATEGORY_DANGEROUS_CONTENT",
-				"threshold": "BLOCK_MEDIUM_AND_ABOVE",
-			},
-		},
-	}
+	payload := geminiTextPayload(req)
 
 	jsonData, err := json.Marshal(payload)
 	if err != nil {
@@ -117,7 +84,7 @@ func (p *GeminiProvider) GenerateText(req types.TextRequest) (*types.TextRespons
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	httpReq, err := http.NewRequest("POST", url, strings.NewReader(string(jsonData)))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, strings.NewReader(string(jsonData)))
 	if err != nil {
 		p.mu.Lock()
 		p.stats.Failed++
@@ -132,7 +99,7 @@ func (p *GeminiProvider) GenerateText(req types.TextRequest) (*types.TextRespons
 		p.mu.Lock()
 		p.stats.Failed++
 		p.mu.Unlock()
-		return nil, fmt.Errorf("request failed: %w", err)
+		return nil, types.RequestError(ctx, p.GetName(), fmt.Errorf("request failed: %w", err))
 	}
 	defer resp.Body.Close()
 
@@ -141,7 +108,7 @@ func (p *GeminiProvider) GenerateText(req types.TextRequest) (*types.TextRespons
 		p.mu.Lock()
 		p.stats.Failed++
 		p.mu.Unlock()
-		return nil, fmt.Errorf("Gemini API error: %s - %s", resp.Status, string(body))
+		return nil, types.HTTPError(p.GetName(), resp.StatusCode, string(body))
 	}
 
 	body, err := io.ReadAll(resp.Body)
@@ -191,32 +158,160 @@ func (p *GeminiProvider) GenerateText(req types.TextRequest) (*types.TextRespons
 
 	fullText := strings.Join(textParts, "\n")
 
-	// تقدير التكلفة (Gemini flash مجاني تقريباً)
-	cost := 0.0
-	if model != "gemini-2.5-flash-exp" {
-		// تكلفة تقديرية للنماذج المدفوعة
-		totalTokens := result.UsageMetadata.TotalTokenCount
-		cost = float64(totalTokens) * 0.0000025 // $0.0025 per 1K tokens للـ pro
+	// الرموز الفعلية من usageMetadata والتكلفة من جدول الأسعار
+	response := &types.TextResponse{
+		Text:             strings.TrimSpace(fullText),
+		PromptTokens:     result.UsageMetadata.PromptTokenCount,
+		CompletionTokens: result.UsageMetadata.CandidatesTokenCount,
+		ModelUsed:        model,
+		FinishReason:     result.Candidates[0].FinishReason,
+		CreatedAt:        time.Now(),
 	}
+	meterText(model, req.Prompt, response)
 
 	p.mu.Lock()
 	p.stats.Successful++
-	p.stats.TotalCost += cost
+	p.stats.TotalCost += response.Cost
 	p.stats.LastUsed = time.Now()
 	p.mu.Unlock()
 
-	return &types.TextResponse{
-		Text:         strings.TrimSpace(fullText),
-		Tokens:       result.UsageMetadata.TotalTokenCount,
-		Cost:         cost,
-		ModelUsed:    model,
-		FinishReason: result.Candidates[0].FinishReason,
-		CreatedAt:    time.Now(),
-	}, nil
+	return response, nil
+}
+
+// GenerateTextStream توليد نص متدفق عبر streamGenerateContent بصيغة SSE؛ كل حدث
+// يحمل usageMetadata التراكمي فيُؤخذ آخره
+func (p *GeminiProvider) GenerateTextStream(ctx context.Context, req types.TextRequest) (<-chan types.StreamChunk, error) {
+	if p.apiKey == "" {
+		return nil, fmt.Errorf("GEMINI_API_KEY environment variable is required")
+	}
+
+	model := req.Model
+	if model == "" {
+		model = "gemini-2.5-flash-exp" // نموذج مجاني
+	}
+
+	url := fmt.Sprintf("%s/models/%s:streamGenerateContent?alt=sse&key=%s", p.baseURL, model, p.apiKey)
+
+	jsonData, err := json.Marshal(geminiTextPayload(req))
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, strings.NewReader(string(jsonData)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	startTime := time.Now()
+	p.mu.Lock()
+	p.stats.Requests++
+	p.mu.Unlock()
+
+	body, err := openStream(ctx, p.client, httpReq, p.GetName())
+	if err != nil {
+		p.mu.Lock()
+		p.stats.Failed++
+		p.mu.Unlock()
+		p.updateStats(time.Since(startTime), false)
+		return nil, err
+	}
+
+	return textStream(ctx, p.GetName(), model, req.Prompt, func(emit func(string) error, usage *streamUsage) error {
+		defer body.Close()
+
+		err := scanSSE(body, func(data []byte) error {
+			var event struct {
+				Candidates []struct {
+					Content struct {
+						Parts []struct {
+							Text string `json:"text"`
+						} `json:"parts"`
+					} `json:"content"`
+					FinishReason string `json:"finishReason"`
+				} `json:"candidates"`
+				UsageMetadata struct {
+					PromptTokenCount     int `json:"promptTokenCount"`
+					CandidatesTokenCount int `json:"candidatesTokenCount"`
+				} `json:"usageMetadata"`
+			}
+			if err := json.Unmarshal(data, &event); err != nil {
+				return fmt.Errorf("failed to parse stream event: %w", err)
+			}
+
+			if event.UsageMetadata.PromptTokenCount > 0 {
+				usage.promptTokens = event.UsageMetadata.PromptTokenCount
+				usage.completionTokens = event.UsageMetadata.CandidatesTokenCount
+			}
+			if len(event.Candidates) == 0 {
+				return nil
+			}
+			candidate := event.Candidates[0]
+			if candidate.FinishReason != "" {
+				usage.finishReason = candidate.FinishReason
+			}
+			for _, part := range candidate.Content.Parts {
+				if err := emit(part.Text); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+
+		p.mu.Lock()
+		if err == nil {
+			p.stats.Successful++
+			p.stats.LastUsed = time.Now()
+		} else {
+			p.stats.Failed++
+		}
+		p.mu.Unlock()
+		p.updateStats(time.Since(startTime), err == nil)
+		return err
+	}), nil
+}
+
+// geminiTextPayload جسم طلب توليد النص، مشترك بين generateContent و streamGenerateContent
+func geminiTextPayload(req types.TextRequest) map[string]interface{} {
+	return map[string]interface{}{
+		"contents": []map[string]interface{}{
+			{
+				"parts": []map[string]interface{}{
+					{
+						"text": req.Prompt,
+					},
+				},
+			},
+		},
+		"generationConfig": map[string]interface{}{
+			"temperature":     req.Temperature,
+			"maxOutputTokens": req.MaxTokens,
+			"topP":            0.95,
+			"topK":            40,
+		},
+		"safetySettings": []map[string]interface{}{
+			{
+				"category":  "HARM_CATEGORY_HARASSMENT",
+				"threshold": "BLOCK_MEDIUM_AND_ABOVE",
+			},
+			{
+				"category":  "HARM_CATEGORY_HATE_SPEECH",
+				"threshold": "BLOCK_MEDIUM_AND_ABOVE",
+			},
+			{
+				"category":  "HARM_CATEGORY_SEXUALLY_EXPLICIT",
+				"threshold": "BLOCK_MEDIUM_AND_ABOVE",
+			},
+			{
+				"category":  "HARM_CATEGORY_DANGEROUS_CONTENT",
+				"threshold": "BLOCK_MEDIUM_AND_ABOVE",
+			},
+		},
+	}
 }
 
 // GenerateImage توليد صور باستخدام Gemini - غير مدعوم مباشرة
-func (p *GeminiProvider) GenerateImage(req types.ImageRequest) (*types.ImageResponse, error) {
+func (p *GeminiProvider) GenerateImage(ctx context.Context, req types.ImageRequest) (*types.ImageResponse, error) {
 	startTime := time.Now()
 
 	p.mu.Lock()
@@ -243,7 +338,7 @@ func (p *GeminiProvider) GenerateImage(req types.ImageRequest) (*types.ImageResp
 		Model:  "gemini-2.5-flash-exp",
 	}
 
-	resp, err := p.GenerateText(textReq)
+	resp, err := p.GenerateText(ctx, textReq)
 	if err != nil {
 		p.mu.Lock()
 		p.stats.Failed++
@@ -269,12 +364,12 @@ func (p *GeminiProvider) GenerateImage(req types.ImageRequest) (*types.ImageResp
 }
 
 // GenerateVideo توليد فيديو - غير مدعوم في Gemini
-func (p *GeminiProvider) GenerateVideo(req types.VideoRequest) (*types.VideoResponse, error) {
-	return nil, fmt.Errorf("video generation not supported by Gemini")
+func (p *GeminiProvider) GenerateVideo(ctx context.Context, req types.VideoRequest) (*types.VideoResponse, error) {
+	return nil, types.ErrVideoNotSupported.WithProvider(p.GetName())
 }
 
 // AnalyzeText تحليل نص
-func (p *GeminiProvider) AnalyzeText(req types.AnalysisRequest) (*types.AnalysisResponse, error) {
+func (p *GeminiProvider) AnalyzeText(ctx context.Context, req types.AnalysisRequest) (*types.AnalysisResponse, error) {
 	startTime := time.Now()
 
 	p.mu.Lock()
@@ -311,7 +406,7 @@ func (p *GeminiProvider) AnalyzeText(req types.AnalysisRequest) (*types.Analysis
 		Temperature: 0.3, // أقل درجة حرارة لتحليل أكثر دقة
 	}
 
-	resp, err := p.GenerateText(textReq)
+	resp, err := p.GenerateText(ctx, textReq)
 	if err != nil {
 		p.mu.Lock()
 		p.stats.Failed++
@@ -332,7 +427,7 @@ func (p *GeminiProvider) AnalyzeText(req types.AnalysisRequest) (*types.Analysis
 }
 
 // AnalyzeImage تحليل صور باستخدام Gemini Vision
-func (p *GeminiProvider) AnalyzeImage(req types.AnalysisRequest) (*types.AnalysisResponse, error) {
+func (p *GeminiProvider) AnalyzeImage(ctx context.Context, req types.AnalysisRequest) (*types.AnalysisResponse, error) {
 	startTime := time.Now()
 
 	p.mu.Lock()
@@ -394,7 +489,7 @@ func (p *GeminiProvider) AnalyzeImage(req types.AnalysisRequest) (*types.Analysi
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	httpReq, err := http.NewRequest("POST", url, strings.NewReader(string(jsonData)))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, strings.NewReader(string(jsonData)))
 	if err != nil {
 		p.mu.Lock()
 		p.stats.Failed++
@@ -409,7 +504,7 @@ func (p *GeminiProvider) AnalyzeImage(req types.AnalysisRequest) (*types.Analysi
 		p.mu.Lock()
 		p.stats.Failed++
 		p.mu.Unlock()
-		return nil, fmt.Errorf("request failed: %w", err)
+		return nil, types.RequestError(ctx, p.GetName(), fmt.Errorf("request failed: %w", err))
 	}
 	defer resp.Body.Close()
 
@@ -418,7 +513,7 @@ func (p *GeminiProvider) AnalyzeImage(req types.AnalysisRequest) (*types.Analysi
 		p.mu.Lock()
 		p.stats.Failed++
 		p.mu.Unlock()
-		return nil, fmt.Errorf("Gemini Vision API error: %s - %s", resp.Status, string(body))
+		return nil, types.HTTPError(p.GetName(), resp.StatusCode, string(body))
 	}
 
 	body, err := io.ReadAll(resp.Body)
@@ -482,7 +577,7 @@ func (p *GeminiProvider) AnalyzeImage(req types.AnalysisRequest) (*types.Analysi
 }
 
 // TranslateText ترجمة نص
-func (p *GeminiProvider) TranslateText(req types.TranslationRequest) (*types.TranslationResponse, error) {
+func (p *GeminiProvider) TranslateText(ctx context.Context, req types.TranslationRequest) (*types.TranslationResponse, error) {
 	startTime := time.Now()
 
 	p.mu.Lock()
@@ -513,7 +608,7 @@ func (p *GeminiProvider) TranslateText(req types.TranslationRequest) (*types.Tra
 		Model:  model,
 	}
 
-	resp, err := p.GenerateText(textReq)
+	resp, err := p.GenerateText(ctx, textReq)
 	if err != nil {
 		p.mu.Lock()
 		p.stats.Failed++
@@ -539,6 +634,19 @@ func (p *GeminiProvider) IsAvailable() bool {
 	return p.stats.IsAvailable
 }
 
+// HealthCheck فحص الصحة: سرد نموذج واحد، وهو مجاني ولا يستهلك الحصة
+func (p *GeminiProvider) HealthCheck(ctx context.Context) error {
+	if p.apiKey == "" {
+		return types.ErrInvalidAPIKey.WithProvider(p.GetName())
+	}
+	url := fmt.Sprintf("%s/models?pageSize=1&key=%s", p.baseURL, p.apiKey)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	return probeHTTP(ctx, p.client, p.GetName(), req)
+}
+
 // GetName اسم المزود
 func (p *GeminiProvider) GetName() string {
 	return p.stats.Name
@@ -591,7 +699,7 @@ func (p *GeminiProvider) updateStats(latency time.Duration, success bool) {
 
 // SupportsStreaming يدعم التدفق
 func (p *GeminiProvider) SupportsStreaming() bool {
-	return false // Gemini لا يدعم التدفق في API المجاني
+	return true
 }
 
 // SupportsEmbedding يدعم التضمين
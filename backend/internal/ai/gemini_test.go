@@ -0,0 +1,88 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nawthtech/nawthtech/backend/internal/ai/types"
+)
+
+func TestGeminiGenerateTextStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/models/gemini-test:streamGenerateContent" || r.URL.Query().Get("alt") != "sse" ||
+			r.URL.Query().Get("key") != "test-key" {
+			http.NotFound(w, r)
+			return
+		}
+		var body struct {
+			Contents []struct {
+				Parts []struct {
+					Text string `json:"text"`
+				} `json:"parts"`
+			} `json:"contents"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, `data: {"candidates":[{"content":{"parts":[{"text":"Hello"}],"role":"model"}}],"usageMetadata":{"promptTokenCount":5,"candidatesTokenCount":1}}`+"\n\n")
+		w.(http.Flusher).Flush()
+		if len(body.Contents) > 0 && len(body.Contents[0].Parts) > 0 && body.Contents[0].Parts[0].Text == "hang" {
+			<-r.Context().Done()
+			return
+		}
+		fmt.Fprint(w, `data: {"candidates":[{"content":{"parts":[{"text":" there"}],"role":"model"}}],"usageMetadata":{"promptTokenCount":5,"candidatesTokenCount":2}}`+"\n\n")
+		fmt.Fprint(w, `data: {"candidates":[{"content":{"parts":[{"text":""}],"role":"model"},"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":5,"candidatesTokenCount":3,"totalTokenCount":8}}`+"\n\n")
+	}))
+	defer server.Close()
+	t.Setenv("GEMINI_API_KEY", "test-key")
+	provider := NewGeminiProvider()
+	provider.baseURL = server.URL
+
+	// بث كامل: الاستخدام من usageMetadata في آخر حدث
+	stream, err := provider.GenerateTextStream(context.Background(), types.TextRequest{Prompt: "hi", Model: "gemini-test"})
+	if err != nil {
+		t.Fatalf("GenerateTextStream: %v", err)
+	}
+	var text string
+	var last types.StreamChunk
+	for chunk := range stream {
+		text += chunk.Delta
+		last = chunk
+	}
+	if text != "Hello there" || !last.Done || last.Err != nil {
+		t.Fatalf("got %q, final %+v", text, last)
+	}
+	if last.Response.PromptTokens != 5 || last.Response.CompletionTokens != 3 || last.Response.FinishReason != "STOP" {
+		t.Errorf("usage = %+v, want 5+3 STOP", last.Response)
+	}
+
+	// إلغاء العميل بعد أول جزء: الجزء الأخير يحمل الإلغاء وما وُلّد قبله
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err = provider.GenerateTextStream(ctx, types.TextRequest{Prompt: "hang", Model: "gemini-test"})
+	if err != nil {
+		t.Fatalf("GenerateTextStream: %v", err)
+	}
+	if first := <-stream; first.Delta != "Hello" {
+		t.Fatalf("first chunk %+v", first)
+	}
+	cancel()
+	for chunk := range stream {
+		last = chunk
+	}
+	if !last.Done || !errors.Is(last.Err, types.ErrRequestCanceled) {
+		t.Fatalf("expected canceled final chunk, got %+v", last)
+	}
+	if last.Response.Text != "Hello" || last.Response.CompletionTokens != 1 || last.Response.FinishReason != "canceled" {
+		t.Errorf("partial response = %+v, want the delivered text metered", last.Response)
+	}
+
+	// رفض المزود قبل البث يعود خطأً مُنمّطاً دون قناة
+	if _, err := provider.GenerateTextStream(context.Background(), types.TextRequest{Prompt: "hi", Model: "missing"}); err == nil {
+		t.Error("expected an error for a rejected stream request")
+	}
+}
@@ -2,6 +2,7 @@ package ai
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/nawthtech/nawthtech/backend/internal/ai/types"
@@ -77,7 +78,7 @@ func NewHuggingFaceProvider() *HuggingFaceProvider {
 }
 
 // GenerateText توليد نص
-func (p *HuggingFaceProvider) GenerateText(req types.TextRequest) (*types.TextResponse, error) {
+func (p *HuggingFaceProvider) GenerateText(ctx context.Context, req types.TextRequest) (*types.TextResponse, error) {
 	if p.apiToken == "" {
 		return nil, fmt.Errorf("HUGGINGFACE_TOKEN environment variable is required")
 	}
@@ -114,7 +115,7 @@ func (p *HuggingFaceProvider) GenerateText(req types.TextRequest) (*types.TextRe
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	httpReq, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -124,21 +125,17 @@ func (p *HuggingFaceProvider) GenerateText(req types.TextRequest) (*types.TextRe
 
 	resp, err := p.client.Do(httpReq)
 	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
+		return nil, types.RequestError(ctx, p.GetName(), fmt.Errorf("request failed: %w", err))
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode == http.StatusNotFound {
-		return nil, fmt.Errorf("model %s not found", model)
-	}
-
 	if resp.StatusCode == http.StatusServiceUnavailable {
-		return nil, fmt.Errorf("model is loading, please try again in a few moments")
+		return nil, types.HTTPError(p.GetName(), resp.StatusCode, "model is loading, please try again in a few moments")
 	}
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("Hugging Face API error: %s - %s", resp.Status, string(body))
+		return nil, types.HTTPError(p.GetName(), resp.StatusCode, string(body))
 	}
 
 	body, err := io.ReadAll(resp.Body)
@@ -152,17 +149,102 @@ func (p *HuggingFaceProvider) GenerateText(req types.TextRequest) (*types.TextRe
 		return nil, err
 	}
 
-	// تقدير عدد الرموز
-	tokens := int(float64(len(strings.Fields(generatedText))) * 1.3)
-
-	return &types.TextResponse{
+	// واجهة الاستدلال لا تعيد أعداد الرموز، فتُقدَّر بمُرمِّز عائلة النموذج
+	response := &types.TextResponse{
 		Text:         strings.TrimSpace(generatedText),
-		Tokens:       int(tokens),
-		Cost:         0.0, // Hugging Face مجاني
 		ModelUsed:    model,
 		FinishReason: "length",
 		CreatedAt:    time.Now(),
-	}, nil
+	}
+	meterText(model, req.Prompt, response)
+	return response, nil
+}
+
+// GenerateTextStream توليد نص متدفق بـ "stream": true؛ واجهة الاستدلال تبث رمزًا
+// في كل حدث SSE وتعلن عدد الرموز المولدة في الحدث الأخير
+func (p *HuggingFaceProvider) GenerateTextStream(ctx context.Context, req types.TextRequest) (<-chan types.StreamChunk, error) {
+	if p.apiToken == "" {
+		return nil, fmt.Errorf("HUGGINGFACE_TOKEN environment variable is required")
+	}
+
+	if !p.rateLimit.Allow() {
+		return nil, types.ErrRateLimitExceeded.WithProvider(p.GetName())
+	}
+
+	model := req.Model
+	if model == "" {
+		model = "google/flan-t5-xl"
+	}
+
+	url := fmt.Sprintf("%s/%s", p.baseURL, model)
+
+	payload := map[string]interface{}{
+		"inputs": req.Prompt,
+		"stream": true,
+		"parameters": map[string]interface{}{
+			"max_new_tokens":   500,
+			"temperature":      0.7,
+			"top_p":            0.9,
+			"do_sample":        true,
+			"return_full_text": false,
+		},
+		"options": map[string]interface{}{
+			"use_cache":      false,
+			"wait_for_model": true,
+		},
+	}
+
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	httpReq.Header.Set("Authorization", "Bearer "+p.apiToken)
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept", "text/event-stream")
+
+	body, err := openStream(ctx, p.client, httpReq, p.GetName())
+	if err != nil {
+		return nil, err
+	}
+
+	return textStream(ctx, p.GetName(), model, req.Prompt, func(emit func(string) error, usage *streamUsage) error {
+		defer body.Close()
+
+		return scanSSE(body, func(data []byte) error {
+			var event struct {
+				Token struct {
+					Text    string `json:"text"`
+					Special bool   `json:"special"`
+				} `json:"token"`
+				Details *struct {
+					FinishReason    string `json:"finish_reason"`
+					GeneratedTokens int    `json:"generated_tokens"`
+				} `json:"details"`
+				Error string `json:"error"`
+			}
+			if err := json.Unmarshal(data, &event); err != nil {
+				return fmt.Errorf("failed to parse stream event: %w", err)
+			}
+			if event.Error != "" {
+				return fmt.Errorf("Hugging Face stream error: %s", event.Error)
+			}
+
+			if event.Details != nil {
+				usage.finishReason = event.Details.FinishReason
+				usage.completionTokens = event.Details.GeneratedTokens
+			}
+			if event.Token.Special {
+				return nil
+			}
+			return emit(event.Token.Text)
+		})
+	}), nil
 }
 
 // parseResponse تحليل استجابة Hugging Face
@@ -205,7 +287,7 @@ func (p *HuggingFaceProvider) parseResponse(body []byte) (string, error) {
 }
 
 // GenerateImage توليد صورة
-func (p *HuggingFaceProvider) GenerateImage(req types.ImageRequest) (*types.ImageResponse, error) {
+func (p *HuggingFaceProvider) GenerateImage(ctx context.Context, req types.ImageRequest) (*types.ImageResponse, error) {
 	if p.apiToken == "" {
 		return nil, fmt.Errorf("HUGGINGFACE_TOKEN environment variable is required")
 	}
@@ -231,7 +313,7 @@ func (p *HuggingFaceProvider) GenerateImage(req types.ImageRequest) (*types.Imag
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	httpReq, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -241,13 +323,13 @@ func (p *HuggingFaceProvider) GenerateImage(req types.ImageRequest) (*types.Imag
 
 	resp, err := p.client.Do(httpReq)
 	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
+		return nil, types.RequestError(ctx, p.GetName(), fmt.Errorf("request failed: %w", err))
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("image generation failed: %s - %s", resp.Status, string(body))
+		return nil, types.HTTPError(p.GetName(), resp.StatusCode, string(body))
 	}
 
 	imageData, err := io.ReadAll(resp.Body)
@@ -268,12 +350,12 @@ func (p *HuggingFaceProvider) GenerateImage(req types.ImageRequest) (*types.Imag
 }
 
 // GenerateVideo توليد فيديو - غير مدعوم في Hugging Face
-func (p *HuggingFaceProvider) GenerateVideo(req types.VideoRequest) (*types.VideoResponse, error) {
-	return nil, fmt.Errorf("video generation not supported by Hugging Face")
+func (p *HuggingFaceProvider) GenerateVideo(ctx context.Context, req types.VideoRequest) (*types.VideoResponse, error) {
+	return nil, types.ErrVideoNotSupported.WithProvider(p.GetName())
 }
 
 // AnalyzeText تحليل نص
-func (p *HuggingFaceProvider) AnalyzeText(req types.AnalysisRequest) (*types.AnalysisResponse, error) {
+func (p *HuggingFaceProvider) AnalyzeText(ctx context.Context, req types.AnalysisRequest) (*types.AnalysisResponse, error) {
 	if p.apiToken == "" {
 		return nil, fmt.Errorf("HUGGINGFACE_TOKEN environment variable is required")
 	}
@@ -292,7 +374,7 @@ func (p *HuggingFaceProvider) AnalyzeText(req types.AnalysisRequest) (*types.Ana
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	httpReq, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -302,13 +384,13 @@ func (p *HuggingFaceProvider) AnalyzeText(req types.AnalysisRequest) (*types.Ana
 
 	resp, err := p.client.Do(httpReq)
 	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
+		return nil, types.RequestError(ctx, p.GetName(), fmt.Errorf("request failed: %w", err))
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("analysis failed: %s - %s", resp.Status, string(body))
+		return nil, types.HTTPError(p.GetName(), resp.StatusCode, string(body))
 	}
 
 	body, err := io.ReadAll(resp.Body)
@@ -344,12 +426,12 @@ func (p *HuggingFaceProvider) AnalyzeText(req types.AnalysisRequest) (*types.Ana
 }
 
 // AnalyzeImage تحليل صورة
-func (p *HuggingFaceProvider) AnalyzeImage(req types.AnalysisRequest) (*types.AnalysisResponse, error) {
-	return nil, fmt.Errorf("image analysis not supported by Hugging Face")
+func (p *HuggingFaceProvider) AnalyzeImage(ctx context.Context, req types.AnalysisRequest) (*types.AnalysisResponse, error) {
+	return nil, types.ErrImageNotSupported.WithProvider(p.GetName())
 }
 
 // TranslateText ترجمة نص
-func (p *HuggingFaceProvider) TranslateText(req types.TranslationRequest) (*types.TranslationResponse, error) {
+func (p *HuggingFaceProvider) TranslateText(ctx context.Context, req types.TranslationRequest) (*types.TranslationResponse, error) {
 	if p.apiToken == "" {
 		return nil, fmt.Errorf("HUGGINGFACE_TOKEN environment variable is required")
 	}
@@ -368,7 +450,7 @@ func (p *HuggingFaceProvider) TranslateText(req types.TranslationRequest) (*type
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	httpReq, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -378,7 +460,7 @@ func (p *HuggingFaceProvider) TranslateText(req types.TranslationRequest) (*type
 
 	resp, err := p.client.Do(httpReq)
 	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
+		return nil, types.RequestError(ctx, p.GetName(), fmt.Errorf("request failed: %w", err))
 	}
 	defer resp.Body.Close()
 
@@ -387,6 +469,10 @@ func (p *HuggingFaceProvider) TranslateText(req types.TranslationRequest) (*type
 		return nil, fmt.Errorf("failed to read response: %w", err)
 	}
 
+	if resp.StatusCode != http.StatusOK {
+		return nil, types.HTTPError(p.GetName(), resp.StatusCode, string(body))
+	}
+
 	var result []map[string]interface{}
 	if err := json.Unmarshal(body, &result); err != nil {
 		return nil, fmt.Errorf("failed to parse translation response: %w", err)
@@ -412,14 +498,14 @@ func (p *HuggingFaceProvider) TranslateText(req types.TranslationRequest) (*type
 }
 
 // Transcribe تحويل صوت إلى نص
-func (p *HuggingFaceProvider) Transcribe(audioData []byte) (string, error) {
+func (p *HuggingFaceProvider) Transcribe(ctx context.Context, audioData []byte) (string, error) {
 	if p.apiToken == "" {
 		return "", fmt.Errorf("HUGGINGFACE_TOKEN environment variable is required")
 	}
 
 	url := p.baseURL + "/openai/whisper-large-v3"
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(audioData))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(audioData))
 	if err != nil {
 		return "", fmt.Errorf("failed to create request: %w", err)
 	}
@@ -429,7 +515,7 @@ func (p *HuggingFaceProvider) Transcribe(audioData []byte) (string, error) {
 
 	resp, err := p.client.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("request failed: %w", err)
+		return "", types.RequestError(ctx, p.GetName(), fmt.Errorf("request failed: %w", err))
 	}
 	defer resp.Body.Close()
 
@@ -450,7 +536,7 @@ func (p *HuggingFaceProvider) Transcribe(audioData []byte) (string, error) {
 }
 
 // Summarize تلخيص نص
-func (p *HuggingFaceProvider) Summarize(text string, maxLength int) (string, error) {
+func (p *HuggingFaceProvider) Summarize(ctx context.Context, text string, maxLength int) (string, error) {
 	if p.apiToken == "" {
 		return "", fmt.Errorf("HUGGINGFACE_TOKEN environment variable is required")
 	}
@@ -471,7 +557,7 @@ func (p *HuggingFaceProvider) Summarize(text string, maxLength int) (string, err
 		return "", fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return "", fmt.Errorf("failed to create request: %w", err)
 	}
@@ -481,7 +567,7 @@ func (p *HuggingFaceProvider) Summarize(text string, maxLength int) (string, err
 
 	resp, err := p.client.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("request failed: %w", err)
+		return "", types.RequestError(ctx, p.GetName(), fmt.Errorf("request failed: %w", err))
 	}
 	defer resp.Body.Close()
 
@@ -521,17 +607,17 @@ func (p *HuggingFaceProvider) GetAvailableModels() []string {
 }
 
 // GetModelInfo الحصول على معلومات النموذج
-func (p *HuggingFaceProvider) GetModelInfo(model string) (map[string]interface{}, error) {
+func (p *HuggingFaceProvider) GetModelInfo(ctx context.Context, model string) (map[string]interface{}, error) {
 	url := fmt.Sprintf("https://huggingface.co/api/models/%s", model)
 
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	resp, err := p.client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
+		return nil, types.RequestError(ctx, p.GetName(), fmt.Errorf("request failed: %w", err))
 	}
 	defer resp.Body.Close()
 
@@ -554,6 +640,19 @@ func (p *HuggingFaceProvider) IsAvailable() bool {
 	return err == nil && resp.StatusCode == http.StatusOK
 }
 
+// HealthCheck فحص الصحة: التحقق من الرمز دون استدعاء نموذج
+func (p *HuggingFaceProvider) HealthCheck(ctx context.Context) error {
+	if p.apiToken == "" {
+		return types.ErrInvalidAPIKey.WithProvider(p.GetName())
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", "https://huggingface.co/api/whoami", nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+p.apiToken)
+	return probeHTTP(ctx, p.client, p.GetName(), req)
+}
+
 // GetName اسم المزود
 func (p *HuggingFaceProvider) GetName() string {
 	return "Hugging Face"
@@ -587,7 +686,7 @@ func (p *HuggingFaceProvider) GetType() string {
 
 // SupportsStreaming يدعم التدفق
 func (p *HuggingFaceProvider) SupportsStreaming() bool {
-	return false
+	return true
 }
 
 // SupportsEmbedding يدعم التضمين
@@ -0,0 +1,99 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nawthtech/nawthtech/backend/internal/ai/types"
+)
+
+func TestHuggingFaceGenerateTextStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/test/model" || r.Header.Get("Authorization") != "Bearer test-token" {
+			http.NotFound(w, r)
+			return
+		}
+		var body struct {
+			Inputs string `json:"inputs"`
+			Stream bool   `json:"stream"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		if !body.Stream {
+			http.Error(w, "stream not requested", http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, `data: {"token":{"id":1,"text":"Hello","special":false},"generated_text":null,"details":null}`+"\n\n")
+		w.(http.Flusher).Flush()
+		switch body.Inputs {
+		case "hang":
+			<-r.Context().Done()
+			return
+		case "fail":
+			fmt.Fprint(w, `data: {"error":"model overloaded"}`+"\n\n")
+			return
+		}
+		fmt.Fprint(w, `data: {"token":{"id":2,"text":" there","special":false},"generated_text":null,"details":null}`+"\n\n")
+		fmt.Fprint(w, `data: {"token":{"id":3,"text":"</s>","special":true},"generated_text":"Hello there","details":{"finish_reason":"eos_token","generated_tokens":3}}`+"\n\n")
+	}))
+	defer server.Close()
+	t.Setenv("HUGGINGFACE_TOKEN", "test-token")
+	provider := NewHuggingFaceProvider()
+	provider.baseURL = server.URL
+
+	// بث كامل: الرموز الخاصة لا تظهر في النص، والعدد من details في الحدث الأخير
+	stream, err := provider.GenerateTextStream(context.Background(), types.TextRequest{Prompt: "hi", Model: "test/model"})
+	if err != nil {
+		t.Fatalf("GenerateTextStream: %v", err)
+	}
+	var text string
+	var last types.StreamChunk
+	for chunk := range stream {
+		text += chunk.Delta
+		last = chunk
+	}
+	if text != "Hello there" || !last.Done || last.Err != nil {
+		t.Fatalf("got %q, final %+v", text, last)
+	}
+	if last.Response.CompletionTokens != 3 || last.Response.FinishReason != "eos_token" {
+		t.Errorf("usage = %+v, want 3 tokens and eos_token", last.Response)
+	}
+
+	// خطأ داخل البث ينهيه بخطأ ويبقي ما وصل قبله
+	stream, err = provider.GenerateTextStream(context.Background(), types.TextRequest{Prompt: "fail", Model: "test/model"})
+	if err != nil {
+		t.Fatalf("GenerateTextStream: %v", err)
+	}
+	for chunk := range stream {
+		last = chunk
+	}
+	if last.Err == nil || last.Response.Text != "Hello" || last.Response.FinishReason != "error" {
+		t.Errorf("expected stream error after partial text, got %+v (err %v)", last.Response, last.Err)
+	}
+
+	// إلغاء العميل بعد أول رمز: الجزء الأخير يحمل الإلغاء وما وُلّد قبله
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err = provider.GenerateTextStream(ctx, types.TextRequest{Prompt: "hang", Model: "test/model"})
+	if err != nil {
+		t.Fatalf("GenerateTextStream: %v", err)
+	}
+	if first := <-stream; first.Delta != "Hello" {
+		t.Fatalf("first chunk %+v", first)
+	}
+	cancel()
+	for chunk := range stream {
+		last = chunk
+	}
+	if !last.Done || !errors.Is(last.Err, types.ErrRequestCanceled) {
+		t.Fatalf("expected canceled final chunk, got %+v", last)
+	}
+	if last.Response.Text != "Hello" || last.Response.CompletionTokens == 0 || last.Response.FinishReason != "canceled" {
+		t.Errorf("partial response = %+v, want the delivered text metered", last.Response)
+	}
+}
@@ -1,9 +1,11 @@
 package ai
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
+	"strings"
 	"sync"
 	"time"
 
@@ -18,6 +20,7 @@ const (
 	ProviderGemini      ProviderType = "gemini"
 	ProviderOllama      ProviderType = "ollama"
 	ProviderHuggingFace ProviderType = "huggingface"
+	ProviderOpenAI      ProviderType = "openai"
 )
 
 // MultiProviderStats إحصائيات المزود المتعدد
@@ -45,8 +48,18 @@ type MultiProvider struct {
 	imageProviders map[string]types.ProviderInterface
 	videoProviders map[string]types.ProviderInterface
 	strategy       RoutingStrategy
+	strategies     map[string]RoutingStrategy // إستراتيجية خاصة بعملية بعينها
+	latencies      map[ProviderType]*latencyWindow
 	costManager    *CostManager
+	failover       FailoverOptions
 	stats          *MultiProviderStats
+
+	// قواطع الدائرة لكل مزود وفحوص الصحة الدورية
+	breakersMu  sync.Mutex
+	breakers    map[ProviderType]*CircuitBreaker
+	breakerOpts BreakerOptions
+	stopProbes  context.CancelFunc
+	probesDone  chan struct{}
 }
 
 // NewMultiProvider إنشاء مزود متعدد جديد
@@ -56,13 +69,18 @@ func NewMultiProvider() (*MultiProvider, error) {
 		textProviders:  make(map[string]types.ProviderInterface),
 		imageProviders: make(map[string]types.ProviderInterface),
 		videoProviders: make(map[string]types.ProviderInterface),
-		strategy:       &DefaultStrategy{},
+		strategies:     make(map[string]RoutingStrategy),
+		latencies:      make(map[ProviderType]*latencyWindow),
+		failover:       FailoverOptionsFromEnv(),
+		breakers:       make(map[ProviderType]*CircuitBreaker),
+		breakerOpts:    BreakerOptionsFromEnv(),
 		stats: &MultiProviderStats{
 			ProviderStats: make(map[ProviderType]*types.ProviderStats),
 			LastRotation:  make(map[string]time.Time),
 			FallbackCount: make(map[ProviderType]int64),
 		},
 	}
+	mp.strategy = &healthAwareStrategy{RoutingStrategy: &DefaultStrategy{}, mp: mp}
 
 	// تهيئة مدير التكاليف
 	cm, err := NewCostManager()
@@ -79,6 +97,12 @@ func NewMultiProvider() (*MultiProvider, error) {
 	// تهيئة الإحصائيات
 	mp.updateProviderStats()
 
+	// إستراتيجيات التوجيه من الإعداد
+	mp.configureRouting()
+
+	// فحوص الصحة الدورية تغذي قواطع الدائرة (AI_HEALTH_PROBE_INTERVAL=0 يعطلها)
+	mp.StartHealthProbes(envDuration("AI_HEALTH_PROBE_INTERVAL", 30*time.Second))
+
 	log.Printf("🤖 MultiProvider initialized with %d total providers", len(mp.providers))
 
 	return mp, nil
@@ -117,6 +141,17 @@ func (mp *MultiProvider) initProviders() error {
 		}
 	}
 
+	// 4. OpenAI Provider (أو خادم متوافق عبر OPENAI_BASE_URL)
+	if openAI := NewOpenAIProvider(); openAI.IsAvailable() {
+		mp.providers[ProviderOpenAI] = openAI
+		mp.textProviders["openai"] = openAI
+		// الخوادم المتوافقة نادرًا ما تولد صورًا، إلا إن حُدد نموذج لها
+		if !openAI.IsCompatibleServer() || getEnvWithFallback("OPENAI_IMAGE_MODEL", "") != "" {
+			mp.imageProviders["openai"] = openAI
+		}
+		log.Println("✅ OpenAI provider initialized")
+	}
+
 	if len(mp.providers) == 0 {
 		return fmt.Errorf("no AI providers available")
 	}
@@ -189,266 +224,415 @@ func (mp *MultiProvider) updateRequestStats(providerType ProviderType, success b
 }
 
 // GenerateText توليد نص
-func (mp *MultiProvider) GenerateText(req types.TextRequest) (*types.TextResponse, error) {
+func (mp *MultiProvider) GenerateText(ctx context.Context, req types.TextRequest) (*types.TextResponse, error) {
 	startTime := time.Now()
 
-	// تحديد المزود المناسب
-	providerType := mp.strategy.SelectProvider(req.UserTier, "text", "text")
-
-	// البحث عن المزود
-	provider, err := mp.getProvider(providerType, "text")
-	if err != nil {
-		return nil, err
+	// تحديد المزود المناسب ثم السير على سلسلته الاحتياطية
+	decision := mp.route(RoutingRequest{
+		Operation:       "text",
+		UserID:          req.UserID,
+		UserTier:        req.UserTier,
+		PromptType:      "text",
+		ProviderType:    "text",
+		Language:        requestLanguage(req.Metadata, req.Prompt),
+		EstimatedTokens: types.CalculateTokens(req.Prompt) + req.MaxTokens,
+		Provider:        requestProvider(req.Metadata),
+	})
+	resp, result, err := withFailover(ctx, mp, decision,
+		func(ctx context.Context, p types.ProviderInterface) (*types.TextResponse, error) {
+			return p.GenerateText(ctx, req)
+		})
+	if resp != nil {
+		resp.Metadata = result.annotate(resp.Metadata)
+	}
+
+	// تسجيل الاستخدام بالرموز الفعلية؛ الطلب الفاشل يُسجَّل برموز مدخلاته المقدّرة
+	record := &types.UsageRecord{
+		UserID:   req.UserID,
+		UserTier: req.UserTier,
+		OrgID:    req.OrgID,
+		Type:     "text",
+		Quantity: int64(types.CountTokens(req.Model, req.Prompt)),
+		Metadata: map[string]interface{}{
+			"model": req.Model,
+		},
 	}
-
-	// توليد النص
-	resp, err := provider.GenerateText(req)
-
-	// تحديث الإحصائيات
-	latency := float64(time.Since(startTime).Milliseconds())
-	mp.updateRequestStats(providerType, err == nil, provider.GetCost())
-
-	// تسجيل الاستخدام
-	if mp.costManager != nil {
-		record := &types.UsageRecord{
-			UserID:    req.UserID,
-			UserTier:  req.UserTier,
-			Provider:  provider.GetName(),
-			Type:      "text",
-			Cost:      provider.GetCost(),
-			Quantity:  int64(len(req.Prompt) / 4), // تقدير تقريبي
-			Latency:   latency,
-			Success:   err == nil,
-			Timestamp: time.Now(),
-			Metadata: map[string]interface{}{
-				"model": req.Model,
-			},
-		}
-		mp.costManager.RecordUsage(record)
+	if resp != nil {
+		record.Cost = resp.Cost
+		record.Quantity = int64(resp.Tokens)
+		record.Metadata["model"] = resp.ModelUsed
+		record.Metadata["prompt_tokens"] = resp.PromptTokens
+		record.Metadata["completion_tokens"] = resp.CompletionTokens
+		record.Metadata["tokens_estimated"] = resp.TokensEstimated
 	}
+	mp.recordUsage(result, record, startTime, err)
 
 	return resp, err
 }
 
-// GenerateImage توليد صورة
-func (mp *MultiProvider) GenerateImage(req types.ImageRequest) (*types.ImageResponse, error) {
+// GenerateTextStream توليد نص متدفق من أول مزود في السلسلة يدعم البث. التحويل
+// الاحتياطي يقتصر على فتح البث، فلا يُبدَّل المزود بعد بدء وصول النص؛ الاستخدام
+// وقاطع الدائرة يُسجَّلان عند انتهاء البث بما وُلّد فعلًا
+func (mp *MultiProvider) GenerateTextStream(ctx context.Context, req types.TextRequest) (<-chan types.StreamChunk, error) {
 	startTime := time.Now()
 
-	// تحديد المزود المناسب
-	providerType := mp.strategy.SelectProvider(req.UserTier, "image", "image")
+	decision := mp.route(RoutingRequest{
+		Operation:       "text",
+		UserID:          req.UserID,
+		UserTier:        req.UserTier,
+		PromptType:      "text",
+		ProviderType:    "text",
+		Language:        requestLanguage(req.Metadata, req.Prompt),
+		EstimatedTokens: types.CalculateTokens(req.Prompt) + req.MaxTokens,
+		Provider:        requestProvider(req.Metadata),
+	})
+	record := &types.UsageRecord{
+		UserID:   req.UserID,
+		UserTier: req.UserTier,
+		OrgID:    req.OrgID,
+		Type:     "text",
+		Quantity: int64(types.CountTokens(req.Model, req.Prompt)),
+		Metadata: map[string]interface{}{
+			"model":  req.Model,
+			"stream": true,
+		},
+	}
 
-	// البحث عن المزود
-	provider, err := mp.getProvider(providerType, "image")
+	stream, result, err := mp.openTextStream(ctx, decision, req)
 	if err != nil {
+		mp.recordUsage(result, record, startTime, err)
 		return nil, err
 	}
 
-	// توليد الصورة
-	resp, err := provider.GenerateImage(req)
-
-	// تحديث الإحصائيات
-	latency := float64(time.Since(startTime).Milliseconds())
-	mp.updateRequestStats(providerType, err == nil, provider.GetCost())
-
-	// تسجيل الاستخدام
-	if mp.costManager != nil {
-		record := &types.UsageRecord{
-			UserID:    req.UserID,
-			UserTier:  req.UserTier,
-			Provider:  provider.GetName(),
-			Type:      "image",
-			Cost:      provider.GetCost(),
-			Quantity:  1,
-			Latency:   latency,
-			Success:   err == nil,
-			Timestamp: time.Now(),
+	out := make(chan types.StreamChunk)
+	go func() {
+		defer close(out)
+
+		for chunk := range stream {
+			if chunk.Done {
+				resp := chunk.Response
+				resp.Metadata = result.annotate(resp.Metadata)
+
+				breaker := mp.breaker(result.providerType)
+				if types.IsCanceled(chunk.Err) {
+					breaker.Release()
+				} else {
+					breaker.Record(chunk.Err)
+				}
+				mp.updateRequestStats(result.providerType, chunk.Err == nil, resp.Cost)
+
+				// التوليد الجزئي يُحتسب بما وصل منه
+				record.Cost = resp.Cost
+				record.Quantity = int64(resp.Tokens)
+				record.Metadata["model"] = resp.ModelUsed
+				record.Metadata["prompt_tokens"] = resp.PromptTokens
+				record.Metadata["completion_tokens"] = resp.CompletionTokens
+				record.Metadata["tokens_estimated"] = resp.TokensEstimated
+				record.Metadata["partial"] = chunk.Err != nil
+				mp.recordUsage(result, record, startTime, chunk.Err)
+			}
+			out <- chunk
 		}
-		mp.costManager.RecordUsage(record)
-	}
+	}()
 
-	return resp, err
+	return out, nil
 }
 
-// GenerateVideo توليد فيديو
-func (mp *MultiProvider) GenerateVideo(req types.VideoRequest) (*types.VideoResponse, error) {
-	startTime := time.Now()
+// openTextStream فتح البث على المزود الأساسي ثم السلسلة الاحتياطية كما في
+// withFailover، متخطيًا من لا يدعم البث ودون مهلة لكل محاولة تقطع البث المفتوح
+func (mp *MultiProvider) openTextStream(ctx context.Context, decision routeDecision, req types.TextRequest) (<-chan types.StreamChunk, failoverResult, error) {
+	var result failoverResult
+	if err := ctx.Err(); err != nil {
+		return nil, result, types.ContextError(ctx, mp.GetName(), err)
+	}
 
-	// تحديد المزود المناسب
-	providerType := mp.strategy.SelectProvider(req.UserTier, "video", "video")
+	candidates, opts := mp.candidates(decision)
+	lastErr := error(types.ErrStreamNotSupported.WithProvider(mp.GetName()))
+	for _, candidate := range candidates {
+		if len(result.attempts) >= opts.MaxAttempts {
+			break
+		}
+		streamer, ok := candidate.provider.(types.StreamingProvider)
+		if !ok || !candidate.provider.SupportsStreaming() {
+			continue
+		}
+		breaker := mp.breaker(candidate.providerType)
+		if !breaker.Allow() {
+			continue
+		}
+		if !candidate.provider.IsAvailable() {
+			breaker.Release()
+			continue
+		}
+		if len(result.attempts) > 0 {
+			mp.recordFallback(result.providerType, candidate.providerType)
+		}
+		result.provider, result.providerType = candidate.provider, candidate.providerType
 
-	// البحث عن المزود
-	provider, err := mp.getProvider(providerType, "video")
-	if err != nil {
-		return nil, err
-	}
+		start := time.Now()
+		stream, err := streamer.GenerateTextStream(ctx, req)
+		elapsed := time.Since(start)
+		mp.observeLatency(candidate.providerType, elapsed)
 
-	// توليد الفيديو
-	resp, err := provider.GenerateVideo(req)
-
-	// تحديث الإحصائيات
-	latency := float64(time.Since(startTime).Milliseconds())
-	mp.updateRequestStats(providerType, err == nil, provider.GetCost())
-
-	// تسجيل الاستخدام
-	if mp.costManager != nil {
-		record := &types.UsageRecord{
-			UserID:    req.UserID,
-			UserTier:  req.UserTier,
-			Provider:  provider.GetName(),
-			Type:      "video",
-			Cost:      provider.GetCost(),
-			Quantity:  1,
-			Latency:   latency,
-			Success:   err == nil,
-			Timestamp: time.Now(),
-			Metadata: map[string]interface{}{
-				"duration": req.Duration,
-			},
+		attempt := types.ProviderAttempt{
+			Provider: candidate.provider.GetName(),
+			Success:  err == nil,
+			Latency:  float64(elapsed.Milliseconds()),
+		}
+		if err == nil {
+			result.attempts = append(result.attempts, attempt)
+			return stream, result, nil
+		}
+
+		if ctx.Err() != nil {
+			breaker.Release()
+		} else {
+			breaker.Record(err)
+		}
+		mp.updateRequestStats(candidate.providerType, false, 0)
+		attempt.Error = err.Error()
+		result.attempts = append(result.attempts, attempt)
+		lastErr = err
+
+		if ctx.Err() != nil {
+			return nil, result, types.ContextError(ctx, candidate.provider.GetName(), err)
+		}
+		if !types.IsRetryable(err) {
+			return nil, result, err
 		}
-		mp.costManager.RecordUsage(record)
 	}
 
-	return resp, err
+	return nil, result, lastErr
 }
 
-// AnalyzeText تحليل نص
-func (mp *MultiProvider) AnalyzeText(req types.AnalysisRequest) (*types.AnalysisResponse, error) {
+// Embed تضمين النصوص بأول مزود في سلسلة النص يدعم التضمين، مع التحويل الاحتياطي
+// وتسجيل الاستخدام برموز المدخلات
+func (mp *MultiProvider) Embed(ctx context.Context, req types.EmbeddingRequest) (*types.EmbeddingResponse, error) {
 	startTime := time.Now()
 
-	// تحديد المزود المناسب
-	providerType := mp.strategy.SelectProvider(req.UserTier, "analysis", "text")
-
-	// البحث عن المزود
-	provider, err := mp.getProvider(providerType, "text")
-	if err != nil {
-		return nil, err
+	var tokens int
+	for _, text := range req.Input {
+		tokens += types.CountTokens(req.Model, text)
+	}
+	decision := mp.route(RoutingRequest{
+		Operation:       "text",
+		UserID:          req.UserID,
+		UserTier:        req.UserTier,
+		PromptType:      "embedding",
+		ProviderType:    "text",
+		Language:        requestLanguage(req.Metadata, strings.Join(req.Input, " ")),
+		EstimatedTokens: tokens,
+		Provider:        requestProvider(req.Metadata),
+	})
+	resp, result, err := withFailoverWhere(ctx, mp, decision, supportsEmbedding,
+		func(ctx context.Context, p types.ProviderInterface) (*types.EmbeddingResponse, error) {
+			embedder := p.(types.Embedder)
+			resp := &types.EmbeddingResponse{
+				Embeddings: make([][]float64, 0, len(req.Input)),
+				Tokens:     tokens,
+				ModelUsed:  req.Model,
+				CreatedAt:  time.Now(),
+			}
+			for _, text := range req.Input {
+				vector, err := embedder.Embed(ctx, text, req.Model)
+				if err != nil {
+					return nil, err
+				}
+				resp.Embeddings = append(resp.Embeddings, vector)
+			}
+			resp.Cost = Prices().Cost(req.Model, tokens, 0)
+			return resp, nil
+		})
+	if resp != nil {
+		resp.Metadata = result.annotate(resp.Metadata)
+	}
+
+	record := &types.UsageRecord{
+		UserID:   req.UserID,
+		UserTier: req.UserTier,
+		OrgID:    req.OrgID,
+		Type:     "embedding",
+		Quantity: int64(tokens),
+		Metadata: map[string]interface{}{
+			"model":  req.Model,
+			"inputs": len(req.Input),
+		},
 	}
-
-	// تحليل النص
-	resp, err := provider.AnalyzeText(req)
-
-	// تحديث الإحصائيات
-	latency := float64(time.Since(startTime).Milliseconds())
-	mp.updateRequestStats(providerType, err == nil, provider.GetCost())
-
-	// تسجيل الاستخدام
-	if mp.costManager != nil {
-		record := &types.UsageRecord{
-			UserID:    req.UserID,
-			UserTier:  req.UserTier,
-			Provider:  provider.GetName(),
-			Type:      "analysis",
-			Cost:      provider.GetCost(),
-			Quantity:  1,
-			Latency:   latency,
-			Success:   err == nil,
-			Timestamp: time.Now(),
-		}
-		mp.costManager.RecordUsage(record)
+	if resp != nil {
+		record.Cost = resp.Cost
 	}
+	mp.recordUsage(result, record, startTime, err)
 
 	return resp, err
 }
 
-// AnalyzeImage تحليل صورة
-func (mp *MultiProvider) AnalyzeImage(req types.AnalysisRequest) (*types.AnalysisResponse, error) {
+// supportsEmbedding هل يُجرَّب المزود في سلسلة التضمين
+func supportsEmbedding(p types.ProviderInterface) bool {
+	_, ok := p.(types.Embedder)
+	return ok && p.SupportsEmbedding()
+}
+
+// GenerateImage توليد صورة
+func (mp *MultiProvider) GenerateImage(ctx context.Context, req types.ImageRequest) (*types.ImageResponse, error) {
 	startTime := time.Now()
 
-	// تحديد المزود المناسب
-	providerType := mp.strategy.SelectProvider(req.UserTier, "analysis", "image")
+	decision := mp.route(RoutingRequest{
+		Operation:    "image",
+		UserID:       req.UserID,
+		UserTier:     req.UserTier,
+		PromptType:   "image",
+		ProviderType: "image",
+	})
+	resp, result, err := withFailover(ctx, mp, decision,
+		func(ctx context.Context, p types.ProviderInterface) (*types.ImageResponse, error) {
+			return p.GenerateImage(ctx, req)
+		})
+	if resp != nil {
+		resp.Metadata = result.annotate(resp.Metadata)
+	}
+
+	mp.recordUsage(result, &types.UsageRecord{
+		UserID:   req.UserID,
+		UserTier: req.UserTier,
+		OrgID:    req.OrgID,
+		Type:     "image",
+		Cost:     responseCost(resp),
+		Quantity: 1,
+	}, startTime, err)
 
-	// البحث عن المزود
-	provider, err := mp.getProvider(providerType, "image")
-	if err != nil {
-		return nil, err
-	}
+	return resp, err
+}
 
-	// تحليل الصورة
-	resp, err := provider.AnalyzeImage(req)
-
-	// تحديث الإحصائيات
-	latency := float64(time.Since(startTime).Milliseconds())
-	mp.updateRequestStats(providerType, err == nil, provider.GetCost())
-
-	// تسجيل الاستخدام
-	if mp.costManager != nil {
-		record := &types.UsageRecord{
-			UserID:    req.UserID,
-			UserTier:  req.UserTier,
-			Provider:  provider.GetName(),
-			Type:      "image_analysis",
-			Cost:      provider.GetCost(),
-			Quantity:  1,
-			Latency:   latency,
-			Success:   err == nil,
-			Timestamp: time.Now(),
-		}
-		mp.costManager.RecordUsage(record)
-	}
+// GenerateVideo توليد فيديو
+func (mp *MultiProvider) GenerateVideo(ctx context.Context, req types.VideoRequest) (*types.VideoResponse, error) {
+	startTime := time.Now()
+
+	decision := mp.route(RoutingRequest{
+		Operation:    "video",
+		UserID:       req.UserID,
+		UserTier:     req.UserTier,
+		PromptType:   "video",
+		ProviderType: "video",
+	})
+	resp, result, err := withFailover(ctx, mp, decision,
+		func(ctx context.Context, p types.ProviderInterface) (*types.VideoResponse, error) {
+			return p.GenerateVideo(ctx, req)
+		})
+	if resp != nil {
+		resp.Metadata = result.annotate(resp.Metadata)
+	}
+
+	mp.recordUsage(result, &types.UsageRecord{
+		UserID:   req.UserID,
+		UserTier: req.UserTier,
+		OrgID:    req.OrgID,
+		Type:     "video",
+		Cost:     responseCost(resp),
+		Quantity: 1,
+		Metadata: map[string]interface{}{
+			"duration": req.Duration,
+		},
+	}, startTime, err)
 
 	return resp, err
 }
 
-// TranslateText ترجمة نص
-func (mp *MultiProvider) TranslateText(req types.TranslationRequest) (*types.TranslationResponse, error) {
+// AnalyzeText تحليل نص
+func (mp *MultiProvider) AnalyzeText(ctx context.Context, req types.AnalysisRequest) (*types.AnalysisResponse, error) {
 	startTime := time.Now()
 
-	// تحديد المزود المناسب
-	providerType := mp.strategy.SelectProvider(req.UserTier, "translation", "text")
+	decision := mp.route(RoutingRequest{
+		Operation:       "analysis",
+		UserID:          req.UserID,
+		UserTier:        req.UserTier,
+		PromptType:      "analysis",
+		ProviderType:    "text",
+		Language:        requestLanguage(req.Metadata, req.Text),
+		EstimatedTokens: types.CalculateTokens(req.Text + req.Prompt),
+	})
+	resp, result, err := withFailover(ctx, mp, decision,
+		func(ctx context.Context, p types.ProviderInterface) (*types.AnalysisResponse, error) {
+			return p.AnalyzeText(ctx, req)
+		})
+	if resp != nil {
+		resp.Metadata = result.annotate(resp.Metadata)
+	}
+
+	mp.recordUsage(result, &types.UsageRecord{
+		UserID:   req.UserID,
+		UserTier: req.UserTier,
+		OrgID:    req.OrgID,
+		Type:     "analysis",
+		Cost:     responseCost(resp),
+		Quantity: 1,
+	}, startTime, err)
 
-	// البحث عن المزود
-	provider, err := mp.getProvider(providerType, "text")
-	if err != nil {
-		return nil, err
-	}
+	return resp, err
+}
 
-	// ترجمة النص
-	resp, err := provider.TranslateText(req)
-
-	// تحديث الإحصائيات
-	latency := float64(time.Since(startTime).Milliseconds())
-	mp.updateRequestStats(providerType, err == nil, provider.GetCost())
-
-	// تسجيل الاستخدام
-	if mp.costManager != nil {
-		record := &types.UsageRecord{
-			UserID:    req.UserID,
-			UserTier:  req.UserTier,
-			Provider:  provider.GetName(),
-			Type:      "translation",
-			Cost:      provider.GetCost(),
-			Quantity:  1,
-			Latency:   latency,
-			Success:   err == nil,
-			Timestamp: time.Now(),
-		}
-		mp.costManager.RecordUsage(record)
-	}
+// AnalyzeImage تحليل صورة
+func (mp *MultiProvider) AnalyzeImage(ctx context.Context, req types.AnalysisRequest) (*types.AnalysisResponse, error) {
+	startTime := time.Now()
+
+	decision := mp.route(RoutingRequest{
+		Operation:    "image_analysis",
+		UserID:       req.UserID,
+		UserTier:     req.UserTier,
+		PromptType:   "analysis",
+		ProviderType: "image",
+		Language:     requestLanguage(req.Metadata, req.Prompt),
+	})
+	resp, result, err := withFailover(ctx, mp, decision,
+		func(ctx context.Context, p types.ProviderInterface) (*types.AnalysisResponse, error) {
+			return p.AnalyzeImage(ctx, req)
+		})
+	if resp != nil {
+		resp.Metadata = result.annotate(resp.Metadata)
+	}
+
+	mp.recordUsage(result, &types.UsageRecord{
+		UserID:   req.UserID,
+		UserTier: req.UserTier,
+		OrgID:    req.OrgID,
+		Type:     "image_analysis",
+		Cost:     responseCost(resp),
+		Quantity: 1,
+	}, startTime, err)
 
 	return resp, err
 }
 
-// getProvider الحصول على مزود من النوع المحدد
-func (mp *MultiProvider) getProvider(providerType ProviderType, requestedType string) (types.ProviderInterface, error) {
-	mp.mu.RLock()
-	defer mp.mu.RUnlock()
+// TranslateText ترجمة نص
+func (mp *MultiProvider) TranslateText(ctx context.Context, req types.TranslationRequest) (*types.TranslationResponse, error) {
+	startTime := time.Now()
 
-	// محاولة الحصول على المزود المحدد
-	provider, exists := mp.providers[providerType]
-	if !exists || !provider.IsAvailable() {
-		// استخدام التسلسل الاحتياطي
-		fallbackChain := mp.strategy.GetFallbackChain(providerType, requestedType)
-		for _, fbType := range fallbackChain {
-			if fbProvider, fbExists := mp.providers[fbType]; fbExists && fbProvider.IsAvailable() {
-				mp.stats.FallbackCount[fbType]++
-				log.Printf("🔄 Fallback from %s to %s", providerType, fbType)
-				return fbProvider, nil
-			}
-		}
-		return nil, fmt.Errorf("no available %s provider", requestedType)
-	}
+	decision := mp.route(RoutingRequest{
+		Operation:       "translation",
+		UserID:          req.UserID,
+		UserTier:        req.UserTier,
+		PromptType:      "translation",
+		ProviderType:    "text",
+		Language:        req.ToLang,
+		EstimatedTokens: types.CalculateTokens(req.Text) * 2,
+	})
+	resp, result, err := withFailover(ctx, mp, decision,
+		func(ctx context.Context, p types.ProviderInterface) (*types.TranslationResponse, error) {
+			return p.TranslateText(ctx, req)
+		})
+	if resp != nil {
+		resp.Metadata = result.annotate(resp.Metadata)
+	}
+
+	mp.recordUsage(result, &types.UsageRecord{
+		UserID:   req.UserID,
+		UserTier: req.UserTier,
+		OrgID:    req.OrgID,
+		Type:     "translation",
+		Cost:     responseCost(resp),
+		Quantity: 1,
+	}, startTime, err)
 
-	return provider, nil
+	return resp, err
 }
 
 // GetTextProvider الحصول على مزود نصوص محدد
@@ -518,12 +702,80 @@ func (mp *MultiProvider) GetAvailableProviders() map[string][]string {
 	return result
 }
 
-// SetRoutingStrategy تعيين إستراتيجية التوجيه
+// SetRoutingStrategy تعيين إستراتيجية التوجيه؛ المزودون ذوو القواطع المفتوحة يُستبعدون منها تلقائيًا
 func (mp *MultiProvider) SetRoutingStrategy(strategy RoutingStrategy) {
 	mp.mu.Lock()
 	defer mp.mu.Unlock()
 
-	mp.strategy = strategy
+	mp.strategy = &healthAwareStrategy{RoutingStrategy: strategy, mp: mp}
+}
+
+// SetOperationStrategy تعيين إستراتيجية لعملية واحدة (انظر RoutingOperations)؛ nil يعيدها للعامة
+func (mp *MultiProvider) SetOperationStrategy(operation string, strategy RoutingStrategy) {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+
+	if mp.strategies == nil {
+		mp.strategies = make(map[string]RoutingStrategy)
+	}
+	if strategy == nil {
+		delete(mp.strategies, operation)
+		return
+	}
+	mp.strategies[operation] = &healthAwareStrategy{RoutingStrategy: strategy, mp: mp}
+}
+
+// route الإستراتيجية المعتمدة للعملية والمزود الأساسي الذي تختاره
+func (mp *MultiProvider) route(req RoutingRequest) routeDecision {
+	mp.mu.RLock()
+	strategy, exists := mp.strategies[req.Operation]
+	if !exists {
+		strategy = mp.strategy
+	}
+	_, pinned := mp.providers[req.Provider]
+	mp.mu.RUnlock()
+
+	if pinned {
+		return routeDecision{strategy: strategy, primary: req.Provider, request: req}
+	}
+	return routeDecision{strategy: strategy, primary: selectFor(strategy, req), request: req}
+}
+
+// Provider المزود المسجل من النوع المحدد
+func (mp *MultiProvider) Provider(providerType ProviderType) (types.ProviderInterface, bool) {
+	mp.mu.RLock()
+	defer mp.mu.RUnlock()
+
+	provider, exists := mp.providers[providerType]
+	return provider, exists
+}
+
+// LatencyP95 المئين 95 لزمن استجابة المزود من آخر المحاولات المرصودة
+func (mp *MultiProvider) LatencyP95(providerType ProviderType) (time.Duration, bool) {
+	mp.mu.RLock()
+	defer mp.mu.RUnlock()
+
+	window, exists := mp.latencies[providerType]
+	if !exists || len(window.samples) == 0 {
+		return 0, false
+	}
+	return window.p95(), true
+}
+
+// observeLatency رصد زمن محاولة لدى المزود
+func (mp *MultiProvider) observeLatency(providerType ProviderType, d time.Duration) {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+
+	if mp.latencies == nil {
+		mp.latencies = make(map[ProviderType]*latencyWindow)
+	}
+	window, exists := mp.latencies[providerType]
+	if !exists {
+		window = &latencyWindow{}
+		mp.latencies[providerType] = window
+	}
+	window.observe(d)
 }
 
 // GetStats الحصول على إحصائيات المزود المتعدد
@@ -689,9 +941,10 @@ func (s *DefaultStrategy) SelectProvider(userTier, promptType, providerType stri
 
 func (s *DefaultStrategy) GetFallbackChain(primary ProviderType, providerType string) []ProviderType {
 	chains := map[ProviderType][]ProviderType{
-		ProviderGemini:      {ProviderHuggingFace, ProviderOllama},
-		ProviderHuggingFace: {ProviderOllama, ProviderGemini},
-		ProviderOllama:      {ProviderHuggingFace, ProviderGemini},
+		ProviderGemini:      {ProviderHuggingFace, ProviderOllama, ProviderOpenAI},
+		ProviderHuggingFace: {ProviderOllama, ProviderGemini, ProviderOpenAI},
+		ProviderOllama:      {ProviderHuggingFace, ProviderGemini, ProviderOpenAI},
+		ProviderOpenAI:      {ProviderGemini, ProviderHuggingFace, ProviderOllama},
 	}
 
 	if chain, exists := chains[primary]; exists {
@@ -699,7 +952,7 @@ func (s *DefaultStrategy) GetFallbackChain(primary ProviderType, providerType st
 	}
 
 	// سلسلة احتياطية افتراضية
-	return []ProviderType{ProviderOllama, ProviderHuggingFace, ProviderGemini}
+	return []ProviderType{ProviderOllama, ProviderHuggingFace, ProviderGemini, ProviderOpenAI}
 }
 
 // Helper functions
@@ -713,7 +966,7 @@ func getEnvWithFallback(key, fallback string) string {
 
 func getProviderType(providerType ProviderType) string {
 	switch providerType {
-	case ProviderGemini, ProviderOllama, ProviderHuggingFace:
+	case ProviderGemini, ProviderOllama, ProviderHuggingFace, ProviderOpenAI:
 		return "text"
 	default:
 		return "mixed"
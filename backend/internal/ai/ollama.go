@@ -73,7 +73,7 @@ func (p *OllamaProvider) loadModels() error {
 }
 
 // GenerateText توليد نص
-func (p *OllamaProvider) GenerateText(req types.TextRequest) (*types.TextResponse, error) {
+func (p *OllamaProvider) GenerateText(ctx context.Context, req types.TextRequest) (*types.TextResponse, error) {
 	url := p.baseURL + "/api/generate"
 
 	// تعيين القيم الافتراضية
@@ -107,52 +107,66 @@ func (p *OllamaProvider) GenerateText(req types.TextRequest) (*types.TextRespons
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	resp, err := p.httpClient.Post(url, "application/json", bytes.NewBuffer(jsonData))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
-		return nil, fmt.Errorf("Ollama request failed: %w", err)
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, types.RequestError(ctx, p.GetName(), fmt.Errorf("Ollama request failed: %w", err))
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("Ollama API error: %s - %s", resp.Status, string(body))
+		return nil, types.HTTPError(p.GetName(), resp.StatusCode, string(body))
 	}
 
 	var result struct {
-		Response string `json:"response"`
-		Done     bool   `json:"done"`
-		Model    string `json:"model"`
+		Response        string `json:"response"`
+		Done            bool   `json:"done"`
+		DoneReason      string `json:"done_reason"`
+		Model           string `json:"model"`
+		PromptEvalCount int    `json:"prompt_eval_count"`
+		EvalCount       int    `json:"eval_count"`
 	}
 
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
-	// تقدير عدد الرموز (تقريبي)
-	tokens := int(float64(len(strings.Fields(result.Response))) * 1.3)
+	finishReason := result.DoneReason
+	if finishReason == "" {
+		finishReason = "length"
+	}
 
-	return &types.TextResponse{
-		Text:         strings.TrimSpace(result.Response),
-		Tokens:       int(tokens),
-		Cost:         0.0, // Ollama مجاني
-		ModelUsed:    result.Model,
-		FinishReason: "length",
-		CreatedAt:    time.Now(),
-	}, nil
+	// الرموز الفعلية من prompt_eval_count و eval_count (النماذج المحلية مجانية في جدول الأسعار)
+	response := &types.TextResponse{
+		Text:             strings.TrimSpace(result.Response),
+		PromptTokens:     result.PromptEvalCount,
+		CompletionTokens: result.EvalCount,
+		ModelUsed:        result.Model,
+		FinishReason:     finishReason,
+		CreatedAt:        time.Now(),
+	}
+	meterText(model, req.Prompt, response)
+	return response, nil
 }
 
 // GenerateImage توليد صورة - غير مدعوم في Ollama
-func (p *OllamaProvider) GenerateImage(req types.ImageRequest) (*types.ImageResponse, error) {
-	return nil, fmt.Errorf("image generation not supported by Ollama")
+func (p *OllamaProvider) GenerateImage(ctx context.Context, req types.ImageRequest) (*types.ImageResponse, error) {
+	return nil, types.ErrImageNotSupported.WithProvider(p.GetName())
 }
 
 // GenerateVideo توليد فيديو - غير مدعوم في Ollama
-func (p *OllamaProvider) GenerateVideo(req types.VideoRequest) (*types.VideoResponse, error) {
-	return nil, fmt.Errorf("video generation not supported by Ollama")
+func (p *OllamaProvider) GenerateVideo(ctx context.Context, req types.VideoRequest) (*types.VideoResponse, error) {
+	return nil, types.ErrVideoNotSupported.WithProvider(p.GetName())
 }
 
 // AnalyzeImage تحليل صورة
-func (p *OllamaProvider) AnalyzeImage(req types.AnalysisRequest) (*types.AnalysisResponse, error) {
+func (p *OllamaProvider) AnalyzeImage(ctx context.Context, req types.AnalysisRequest) (*types.AnalysisResponse, error) {
 	// استخدام نموذج رؤية لتحليل الصورة
 	// هذا يتطلب نموذج multimodal مثل llama3.2-vision
 	if req.Model == "" {
@@ -167,7 +181,7 @@ func (p *OllamaProvider) AnalyzeImage(req types.AnalysisRequest) (*types.Analysi
 		Model:  req.Model,
 	}
 
-	resp, err := p.GenerateText(textReq)
+	resp, err := p.GenerateText(ctx, textReq)
 	if err != nil {
 		return nil, err
 	}
@@ -182,7 +196,7 @@ func (p *OllamaProvider) AnalyzeImage(req types.AnalysisRequest) (*types.Analysi
 }
 
 // AnalyzeText تحليل نص
-func (p *OllamaProvider) AnalyzeText(req types.AnalysisRequest) (*types.AnalysisResponse, error) {
+func (p *OllamaProvider) AnalyzeText(ctx context.Context, req types.AnalysisRequest) (*types.AnalysisResponse, error) {
 	prompt := fmt.Sprintf("Analyze this text: %s\n\nProvide analysis:", req.Text)
 
 	if req.Prompt != "" {
@@ -194,7 +208,7 @@ func (p *OllamaProvider) AnalyzeText(req types.AnalysisRequest) (*types.Analysis
 		Model:  req.Model,
 	}
 
-	resp, err := p.GenerateText(textReq)
+	resp, err := p.GenerateText(ctx, textReq)
 	if err != nil {
 		return nil, err
 	}
@@ -209,7 +223,7 @@ func (p *OllamaProvider) AnalyzeText(req types.AnalysisRequest) (*types.Analysis
 }
 
 // TranslateText ترجمة نص
-func (p *OllamaProvider) TranslateText(req types.TranslationRequest) (*types.TranslationResponse, error) {
+func (p *OllamaProvider) TranslateText(ctx context.Context, req types.TranslationRequest) (*types.TranslationResponse, error) {
 	prompt := fmt.Sprintf("Translate the following text from %s to %s:\n\n%s",
 		req.FromLang, req.ToLang, req.Text)
 
@@ -222,7 +236,7 @@ func (p *OllamaProvider) TranslateText(req types.TranslationRequest) (*types.Tra
 		Model:  req.Model,
 	}
 
-	resp, err := p.GenerateText(textReq)
+	resp, err := p.GenerateText(ctx, textReq)
 	if err != nil {
 		return nil, err
 	}
@@ -235,98 +249,117 @@ func (p *OllamaProvider) TranslateText(req types.TranslationRequest) (*types.Tra
 	}, nil
 }
 
-// GenerateStream توليد نص بشكل متدفق
-func (p *OllamaProvider) GenerateStream(ctx context.Context, req types.TextRequest) (<-chan string, <-chan error) {
-	textChan := make(chan string)
-	errChan := make(chan error, 1)
+// GenerateTextStream توليد نص متدفق من /api/generate؛ الجزء الأخير من Ollama يحمل
+// prompt_eval_count و eval_count
+func (p *OllamaProvider) GenerateTextStream(ctx context.Context, req types.TextRequest) (<-chan types.StreamChunk, error) {
+	url := p.baseURL + "/api/generate"
 
-	go func() {
-		defer close(textChan)
-		defer close(errChan)
+	// تعيين القيم الافتراضية
+	model := req.Model
+	if model == "" {
+		model = "llama3.2:3b"
+	}
 
-		url := p.baseURL + "/api/generate"
+	temperature := req.Temperature
+	if temperature == 0 {
+		temperature = 0.7
+	}
 
-		// تعيين القيم الافتراضية
-		model := req.Model
-		if model == "" {
-			model = "llama3.2:3b"
-		}
+	maxTokens := req.MaxTokens
+	if maxTokens == 0 {
+		maxTokens = 2000
+	}
 
-		temperature := req.Temperature
-		if temperature == 0 {
-			temperature = 0.7
-		}
+	request := map[string]interface{}{
+		"model":  model,
+		"prompt": req.Prompt,
+		"stream": true,
+		"options": map[string]interface{}{
+			"temperature": temperature,
+			"num_predict": maxTokens,
+		},
+	}
 
-		maxTokens := req.MaxTokens
-		if maxTokens == 0 {
-			maxTokens = 2000
-		}
+	jsonData, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
 
-		request := map[string]interface{}{
-			"model":  model,
-			"prompt": req.Prompt,
-			"stream": true,
-			"options": map[string]interface{}{
-				"temperature": temperature,
-				"num_predict": maxTokens,
-			},
-		}
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
 
-		jsonData, err := json.Marshal(request)
-		if err != nil {
-			errChan <- err
-			return
-		}
+	body, err := openStream(ctx, p.httpClient, httpReq, p.GetName())
+	if err != nil {
+		return nil, err
+	}
 
-		httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
-		if err != nil {
-			errChan <- err
-			return
-		}
-		httpReq.Header.Set("Content-Type", "application/json")
+	return textStream(ctx, p.GetName(), model, req.Prompt, func(emit func(string) error, usage *streamUsage) error {
+		defer body.Close()
 
-		resp, err := p.httpClient.Do(httpReq)
-		if err != nil {
-			errChan <- err
-			return
-		}
-		defer resp.Body.Close()
+		decoder := json.NewDecoder(body)
+		for {
+			var chunk struct {
+				Response        string `json:"response"`
+				Done            bool   `json:"done"`
+				DoneReason      string `json:"done_reason"`
+				Model           string `json:"model"`
+				PromptEvalCount int    `json:"prompt_eval_count"`
+				EvalCount       int    `json:"eval_count"`
+			}
 
-		if resp.StatusCode != http.StatusOK {
-			body, _ := io.ReadAll(resp.Body)
-			errChan <- fmt.Errorf("Ollama API error: %s - %s", resp.Status, string(body))
-			return
-		}
+			if err := decoder.Decode(&chunk); err != nil {
+				if err == io.EOF {
+					return nil
+				}
+				return fmt.Errorf("failed to decode stream: %w", err)
+			}
 
-		decoder := json.NewDecoder(resp.Body)
-		var fullText strings.Builder
+			if err := emit(chunk.Response); err != nil {
+				return err
+			}
 
-		for {
-			select {
-			case <-ctx.Done():
-				return
-			default:
-				var chunk struct {
-					Response string `json:"response"`
-					Done     bool   `json:"done"`
+			if chunk.Done {
+				if chunk.Model != "" {
+					usage.model = chunk.Model
 				}
+				usage.finishReason = chunk.DoneReason
+				usage.promptTokens = chunk.PromptEvalCount
+				usage.completionTokens = chunk.EvalCount
+				return nil
+			}
+		}
+	}), nil
+}
 
-				if err := decoder.Decode(&chunk); err != nil {
-					if err == io.EOF {
-						return
-					}
-					errChan <- err
-					return
-				}
+// GenerateStream توليد نص بشكل متدفق (نص فقط فوق GenerateTextStream)
+func (p *OllamaProvider) GenerateStream(ctx context.Context, req types.TextRequest) (<-chan string, <-chan error) {
+	textChan := make(chan string)
+	errChan := make(chan error, 1)
 
-				if chunk.Response != "" {
-					fullText.WriteString(chunk.Response)
-					textChan <- chunk.Response
-				}
+	go func() {
+		defer close(textChan)
+		defer close(errChan)
+
+		stream, err := p.GenerateTextStream(ctx, req)
+		if err != nil {
+			errChan <- err
+			return
+		}
 
-				if chunk.Done {
-					return
+		// القراءة حتى إغلاق البث حتى لو توقف المستهلك عن القراءة
+		for chunk := range stream {
+			if chunk.Done {
+				if chunk.Err != nil && ctx.Err() == nil {
+					errChan <- chunk.Err
 				}
+				continue
+			}
+			select {
+			case textChan <- chunk.Delta:
+			case <-ctx.Done():
 			}
 		}
 	}()
@@ -335,7 +368,7 @@ func (p *OllamaProvider) GenerateStream(ctx context.Context, req types.TextReque
 }
 
 // Embed توليد embeddings
-func (p *OllamaProvider) Embed(text string, model string) ([]float64, error) {
+func (p *OllamaProvider) Embed(ctx context.Context, text string, model string) ([]float64, error) {
 	if model == "" {
 		model = "nomic-embed-text"
 	}
@@ -352,25 +385,41 @@ func (p *OllamaProvider) Embed(text string, model string) ([]float64, error) {
 		return nil, err
 	}
 
-	resp, err := p.httpClient.Post(url, "application/json", bytes.NewBuffer(jsonData))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return nil, err
 	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, types.RequestError(ctx, p.GetName(), err)
+	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, types.HTTPError(p.GetName(), resp.StatusCode, string(body))
+	}
+
+	// /api/embed يعيد embeddings لكل مدخل؛ embedding من واجهة /api/embeddings القديمة
 	var result struct {
-		Embedding []float64 `json:"embedding"`
+		Embeddings [][]float64 `json:"embeddings"`
+		Embedding  []float64   `json:"embedding"`
 	}
 
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to decode embedding: %w", err)
 	}
 
+	if len(result.Embeddings) > 0 {
+		return result.Embeddings[0], nil
+	}
 	return result.Embedding, nil
 }
 
 // PullModel سحب نموذج جديد
-func (p *OllamaProvider) PullModel(model string) error {
+func (p *OllamaProvider) PullModel(ctx context.Context, model string) error {
 	url := p.baseURL + "/api/pull"
 
 	request := map[string]interface{}{
@@ -383,15 +432,21 @@ func (p *OllamaProvider) PullModel(model string) error {
 		return err
 	}
 
-	resp, err := p.httpClient.Post(url, "application/json", bytes.NewBuffer(jsonData))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return err
 	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.httpClient.Do(httpReq)
+	if err != nil {
+		return types.RequestError(ctx, p.GetName(), err)
+	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("failed to pull model: %s - %s", resp.Status, string(body))
+		return types.HTTPError(p.GetName(), resp.StatusCode, string(body))
 	}
 
 	// إضافة النموذج إلى القائمة
@@ -411,6 +466,15 @@ func (p *OllamaProvider) IsAvailable() bool {
 	return err == nil && resp.StatusCode == http.StatusOK
 }
 
+// HealthCheck فحص الصحة: قائمة النماذج المحلية
+func (p *OllamaProvider) HealthCheck(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", p.baseURL+"/api/tags", nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	return probeHTTP(ctx, p.httpClient, p.GetName(), req)
+}
+
 // GetName اسم المزود
 func (p *OllamaProvider) GetName() string {
 	return "Ollama"
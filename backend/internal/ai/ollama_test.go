@@ -0,0 +1,128 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/nawthtech/nawthtech/backend/internal/ai/types"
+)
+
+func TestOllamaGenerateTextHonoursContext(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/generate" {
+			select {
+			case <-release:
+			case <-r.Context().Done():
+			}
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+	defer close(release)
+	t.Setenv("OLLAMA_HOST", server.URL)
+
+	provider := NewOllamaProvider()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := provider.GenerateText(ctx, types.TextRequest{Prompt: "hi"})
+	if !errors.Is(err, types.ErrRequestTimeout) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected typed timeout, got %v", err)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	_, err = provider.GenerateText(ctx, types.TextRequest{Prompt: "hi"})
+	if !errors.Is(err, types.ErrRequestCanceled) || !types.IsCanceled(err) {
+		t.Fatalf("expected typed cancellation, got %v", err)
+	}
+}
+
+func TestOllamaGenerateTextReportsEvalCounts(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"model":"llama3.2:3b","response":"Hello there","done":true,"done_reason":"stop","prompt_eval_count":26,"eval_count":298}`))
+	}))
+	defer server.Close()
+	t.Setenv("OLLAMA_HOST", server.URL)
+
+	resp, err := NewOllamaProvider().GenerateText(context.Background(), types.TextRequest{Prompt: "hi"})
+	if err != nil {
+		t.Fatalf("GenerateText: %v", err)
+	}
+	if resp.PromptTokens != 26 || resp.CompletionTokens != 298 || resp.Tokens != 324 || resp.TokensEstimated {
+		t.Errorf("tokens = %d+%d=%d (estimated %v), want 26+298=324 from the response",
+			resp.PromptTokens, resp.CompletionTokens, resp.Tokens, resp.TokensEstimated)
+	}
+	if resp.FinishReason != "stop" || resp.Cost != 0 {
+		t.Errorf("finish reason = %q, cost = %v; want stop and free", resp.FinishReason, resp.Cost)
+	}
+}
+
+func TestOllamaGenerateTextStreamCountsPartialOutput(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/generate" {
+			w.Write([]byte(`{}`))
+			return
+		}
+		var body struct {
+			Prompt string `json:"prompt"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+
+		w.Write([]byte(`{"model":"llama3.2:3b","response":"Hello","done":false}` + "\n"))
+		w.(http.Flusher).Flush()
+		if body.Prompt == "hang" {
+			<-r.Context().Done()
+			return
+		}
+		w.Write([]byte(`{"model":"llama3.2:3b","response":" there","done":false}` + "\n"))
+		w.Write([]byte(`{"model":"llama3.2:3b","response":"","done":true,"done_reason":"stop","prompt_eval_count":26,"eval_count":2}` + "\n"))
+	}))
+	defer server.Close()
+	t.Setenv("OLLAMA_HOST", server.URL)
+	provider := NewOllamaProvider()
+
+	// بث كامل: أجزاء النص ثم الاستخدام الذي أعلنه Ollama
+	stream, err := provider.GenerateTextStream(context.Background(), types.TextRequest{Prompt: "hi"})
+	if err != nil {
+		t.Fatalf("GenerateTextStream: %v", err)
+	}
+	var text string
+	var last types.StreamChunk
+	for chunk := range stream {
+		text += chunk.Delta
+		last = chunk
+	}
+	if text != "Hello there" || !last.Done || last.Err != nil {
+		t.Fatalf("got %q, final %+v", text, last)
+	}
+	if last.Response.PromptTokens != 26 || last.Response.CompletionTokens != 2 || last.Response.FinishReason != "stop" {
+		t.Errorf("usage = %+v, want 26+2 stop", last.Response)
+	}
+
+	// إلغاء العميل بعد أول جزء: الجزء الأخير يحمل الإلغاء وما وُلّد قبله
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err = provider.GenerateTextStream(ctx, types.TextRequest{Prompt: "hang"})
+	if err != nil {
+		t.Fatalf("GenerateTextStream: %v", err)
+	}
+	if first := <-stream; first.Delta != "Hello" {
+		t.Fatalf("first chunk %+v", first)
+	}
+	cancel()
+	for chunk := range stream {
+		last = chunk
+	}
+	if !last.Done || !errors.Is(last.Err, types.ErrRequestCanceled) {
+		t.Fatalf("expected canceled final chunk, got %+v", last)
+	}
+	if last.Response.Text != "Hello" || last.Response.CompletionTokens == 0 || last.Response.FinishReason != "canceled" {
+		t.Errorf("partial response = %+v, want the delivered text metered", last.Response)
+	}
+}
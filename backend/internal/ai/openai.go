@@ -0,0 +1,525 @@
+package ai
+
+import (
+	"cmp"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nawthtech/nawthtech/backend/internal/ai/types"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// openAIDefaultBaseURL عنوان واجهة OpenAI الرسمية
+const openAIDefaultBaseURL = "https://api.openai.com/v1"
+
+// OpenAIProvider مزود OpenAI، ويعمل أيضًا مع الخوادم المتوافقة (vLLM، llama.cpp)
+// عبر OPENAI_BASE_URL؛ الخادم المحلي لا يحتاج مفتاحًا
+type OpenAIProvider struct {
+	client         *openai.Client
+	apiKey         string
+	baseURL        string
+	model          string
+	imageModel     string
+	visionModel    string
+	embeddingModel string
+	mu             sync.RWMutex
+	stats          *types.ProviderStats
+}
+
+// NewOpenAIProvider إنشاء مزود OpenAI من OPENAI_API_KEY و OPENAI_MODEL و OPENAI_BASE_URL
+func NewOpenAIProvider() *OpenAIProvider {
+	apiKey := getEnvWithFallback("OPENAI_API_KEY", "")
+	baseURL := strings.TrimRight(getEnvWithFallback("OPENAI_BASE_URL", openAIDefaultBaseURL), "/")
+	model := getEnvWithFallback("OPENAI_MODEL", "gpt-4-turbo-preview")
+
+	config := openai.DefaultConfig(apiKey)
+	config.BaseURL = baseURL
+	config.HTTPClient = &http.Client{Timeout: 120 * time.Second}
+
+	return &OpenAIProvider{
+		client:         openai.NewClientWithConfig(config),
+		apiKey:         apiKey,
+		baseURL:        baseURL,
+		model:          model,
+		imageModel:     getEnvWithFallback("OPENAI_IMAGE_MODEL", openai.CreateImageModelDallE3),
+		visionModel:    getEnvWithFallback("OPENAI_VISION_MODEL", model),
+		embeddingModel: getEnvWithFallback("OPENAI_EMBEDDING_MODEL", string(openai.SmallEmbedding3)),
+		stats: &types.ProviderStats{
+			Name: "OpenAI",
+			Type: "text",
+		},
+	}
+}
+
+// GenerateText توليد نص عبر chat/completions برسالة مستخدم واحدة
+func (p *OpenAIProvider) GenerateText(ctx context.Context, req types.TextRequest) (*types.TextResponse, error) {
+	startTime := time.Now()
+	p.begin()
+
+	model := p.textModel(req.Model)
+	resp, err := p.client.CreateChatCompletion(ctx, p.chatRequest(model, req))
+	if err != nil {
+		err = p.wrapError(ctx, err)
+		p.finish(startTime, 0, err)
+		return nil, err
+	}
+	if len(resp.Choices) == 0 {
+		err = fmt.Errorf("no choices returned from OpenAI")
+		p.finish(startTime, 0, err)
+		return nil, err
+	}
+
+	response := &types.TextResponse{
+		Text:             strings.TrimSpace(resp.Choices[0].Message.Content),
+		PromptTokens:     resp.Usage.PromptTokens,
+		CompletionTokens: resp.Usage.CompletionTokens,
+		ModelUsed:        cmp.Or(resp.Model, model),
+		FinishReason:     string(resp.Choices[0].FinishReason),
+		CreatedAt:        time.Now(),
+	}
+	meterText(model, req.Prompt, response)
+
+	p.finish(startTime, response.Cost, nil)
+	return response, nil
+}
+
+// GenerateTextStream توليد نص متدفق؛ include_usage يجعل آخر جزء يحمل الرموز الفعلية
+func (p *OpenAIProvider) GenerateTextStream(ctx context.Context, req types.TextRequest) (<-chan types.StreamChunk, error) {
+	startTime := time.Now()
+	p.begin()
+
+	model := p.textModel(req.Model)
+	chatReq := p.chatRequest(model, req)
+	chatReq.Stream = true
+	chatReq.StreamOptions = &openai.StreamOptions{IncludeUsage: true}
+
+	stream, err := p.client.CreateChatCompletionStream(ctx, chatReq)
+	if err != nil {
+		err = p.wrapError(ctx, err)
+		p.finish(startTime, 0, err)
+		return nil, err
+	}
+
+	return textStream(ctx, p.GetName(), model, req.Prompt, func(emit func(string) error, usage *streamUsage) error {
+		defer stream.Close()
+
+		var err error
+		for {
+			var event openai.ChatCompletionStreamResponse
+			event, err = stream.Recv()
+			if errors.Is(err, io.EOF) {
+				err = nil
+				break
+			}
+			if err != nil {
+				err = p.wrapError(ctx, err)
+				break
+			}
+
+			if event.Model != "" {
+				usage.model = event.Model
+			}
+			if event.Usage != nil {
+				usage.promptTokens = event.Usage.PromptTokens
+				usage.completionTokens = event.Usage.CompletionTokens
+			}
+			if len(event.Choices) == 0 {
+				continue
+			}
+			if reason := event.Choices[0].FinishReason; reason != "" && reason != openai.FinishReasonNull {
+				usage.finishReason = string(reason)
+			}
+			if err = emit(event.Choices[0].Delta.Content); err != nil {
+				break
+			}
+		}
+
+		p.finish(startTime, 0, err)
+		return err
+	}), nil
+}
+
+// chatRequest طلب المحادثة المشترك بين التوليد العادي والمتدفق
+func (p *OpenAIProvider) chatRequest(model string, req types.TextRequest) openai.ChatCompletionRequest {
+	return openai.ChatCompletionRequest{
+		Model: model,
+		Messages: []openai.ChatCompletionMessage{
+			{Role: openai.ChatMessageRoleUser, Content: req.Prompt},
+		},
+		MaxTokens:        req.MaxTokens,
+		Temperature:      float32(req.Temperature),
+		TopP:             float32(req.TopP),
+		FrequencyPenalty: float32(req.FrequencyPenalty),
+		PresencePenalty:  float32(req.PresencePenalty),
+		Stop:             req.Stop,
+		User:             cmp.Or(req.User, req.UserID),
+	}
+}
+
+// GenerateImage توليد صورة بنماذج DALL·E؛ b64_json يعيد بيانات الصورة بدل الرابط
+func (p *OpenAIProvider) GenerateImage(ctx context.Context, req types.ImageRequest) (*types.ImageResponse, error) {
+	startTime := time.Now()
+	p.begin()
+
+	size := req.Size
+	if size == "" {
+		size = openai.CreateImageSize1024x1024
+	}
+	format := openai.CreateImageResponseFormatURL
+	if req.ResponseFormat == openai.CreateImageResponseFormatB64JSON {
+		format = openai.CreateImageResponseFormatB64JSON
+	}
+
+	resp, err := p.client.CreateImage(ctx, openai.ImageRequest{
+		Prompt:         req.Prompt,
+		Model:          p.imageModel,
+		N:              1,
+		Quality:        req.Quality,
+		Size:           size,
+		Style:          req.Style,
+		ResponseFormat: format,
+		User:           cmp.Or(req.User, req.UserID),
+	})
+	if err != nil {
+		err = p.wrapError(ctx, err)
+		p.finish(startTime, 0, err)
+		return nil, err
+	}
+	if len(resp.Data) == 0 {
+		err = fmt.Errorf("no images returned from OpenAI")
+		p.finish(startTime, 0, err)
+		return nil, err
+	}
+
+	image := resp.Data[0]
+	response := &types.ImageResponse{
+		URL:       image.URL,
+		Size:      size,
+		Format:    "png",
+		Cost:      openAIImageCost(p.imageModel, size, req.Quality),
+		ModelUsed: p.imageModel,
+		CreatedAt: time.Now(),
+	}
+	fmt.Sscanf(size, "%dx%d", &response.Width, &response.Height)
+	if image.B64JSON != "" {
+		response.ImageData, err = base64.StdEncoding.DecodeString(image.B64JSON)
+		if err != nil {
+			err = fmt.Errorf("failed to decode image: %w", err)
+			p.finish(startTime, 0, err)
+			return nil, err
+		}
+	}
+	if image.RevisedPrompt != "" {
+		response.Metadata = map[string]interface{}{"revised_prompt": image.RevisedPrompt}
+	}
+
+	p.finish(startTime, response.Cost, nil)
+	return response, nil
+}
+
+// openAIImageCost سعر الصورة الواحدة المعلن لنماذج DALL·E؛ غيرها يُحتسب مجانًا
+func openAIImageCost(model, size, quality string) float64 {
+	square := size == openai.CreateImageSize1024x1024
+	switch model {
+	case openai.CreateImageModelDallE3:
+		switch {
+		case quality == openai.CreateImageQualityHD && square:
+			return 0.08
+		case quality == openai.CreateImageQualityHD:
+			return 0.12
+		case square:
+			return 0.04
+		default:
+			return 0.08
+		}
+	case openai.CreateImageModelDallE2:
+		switch size {
+		case openai.CreateImageSize256x256:
+			return 0.016
+		case openai.CreateImageSize512x512:
+			return 0.018
+		default:
+			return 0.02
+		}
+	}
+	return 0
+}
+
+// GenerateVideo توليد فيديو - غير مدعوم في OpenAI
+func (p *OpenAIProvider) GenerateVideo(ctx context.Context, req types.VideoRequest) (*types.VideoResponse, error) {
+	return nil, types.ErrVideoNotSupported.WithProvider(p.GetName())
+}
+
+// AnalyzeText تحليل نص
+func (p *OpenAIProvider) AnalyzeText(ctx context.Context, req types.AnalysisRequest) (*types.AnalysisResponse, error) {
+	prompt := fmt.Sprintf("Analyze this text and provide insights: %s", req.Text)
+	if req.Prompt != "" {
+		prompt = fmt.Sprintf("%s\n\nText to analyze: %s", req.Prompt, req.Text)
+	}
+
+	resp, err := p.GenerateText(ctx, types.TextRequest{
+		Prompt:      prompt,
+		Model:       req.Model,
+		Temperature: 0.3, // أقل درجة حرارة لتحليل أكثر دقة
+		User:        req.User,
+		UserID:      req.UserID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to analyze text: %w", err)
+	}
+
+	return &types.AnalysisResponse{
+		Result:     resp.Text,
+		Confidence: 0.9,
+		Cost:       resp.Cost,
+		Model:      resp.ModelUsed,
+		CreatedAt:  time.Now(),
+	}, nil
+}
+
+// AnalyzeImage تحليل صورة بنموذج رؤية، تُرسل الصورة مضمنة كرابط data:
+func (p *OpenAIProvider) AnalyzeImage(ctx context.Context, req types.AnalysisRequest) (*types.AnalysisResponse, error) {
+	if len(req.ImageData) == 0 {
+		return nil, fmt.Errorf("image data is required for image analysis")
+	}
+
+	startTime := time.Now()
+	p.begin()
+
+	prompt := req.Prompt
+	if prompt == "" {
+		prompt = "Describe this image in detail."
+	}
+	model := cmp.Or(req.Model, p.visionModel)
+	imageURL := fmt.Sprintf("data:%s;base64,%s", http.DetectContentType(req.ImageData),
+		base64.StdEncoding.EncodeToString(req.ImageData))
+
+	resp, err := p.client.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
+		Model: model,
+		Messages: []openai.ChatCompletionMessage{{
+			Role: openai.ChatMessageRoleUser,
+			MultiContent: []openai.ChatMessagePart{
+				{Type: openai.ChatMessagePartTypeText, Text: prompt},
+				{Type: openai.ChatMessagePartTypeImageURL, ImageURL: &openai.ChatMessageImageURL{URL: imageURL}},
+			},
+		}},
+		User: cmp.Or(req.User, req.UserID),
+	})
+	if err != nil {
+		err = p.wrapError(ctx, err)
+		p.finish(startTime, 0, err)
+		return nil, err
+	}
+	if len(resp.Choices) == 0 {
+		err = fmt.Errorf("no choices returned from OpenAI vision")
+		p.finish(startTime, 0, err)
+		return nil, err
+	}
+
+	// رموز الصورة لا تُقدَّر محليًا، فالتكلفة من usage وحده
+	cost := Prices().Cost(model, resp.Usage.PromptTokens, resp.Usage.CompletionTokens)
+	p.finish(startTime, cost, nil)
+
+	return &types.AnalysisResponse{
+		Result:     strings.TrimSpace(resp.Choices[0].Message.Content),
+		Confidence: 0.85,
+		Cost:       cost,
+		Model:      cmp.Or(resp.Model, model),
+		CreatedAt:  time.Now(),
+	}, nil
+}
+
+// TranslateText ترجمة نص
+func (p *OpenAIProvider) TranslateText(ctx context.Context, req types.TranslationRequest) (*types.TranslationResponse, error) {
+	prompt := fmt.Sprintf("Translate the following text from %s to %s. Reply with the translation only:\n\n%s",
+		req.FromLang, req.ToLang, req.Text)
+
+	resp, err := p.GenerateText(ctx, types.TextRequest{
+		Prompt:      prompt,
+		Model:       req.Model,
+		Temperature: 0.2,
+		User:        req.User,
+		UserID:      req.UserID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to translate text: %w", err)
+	}
+
+	return &types.TranslationResponse{
+		TranslatedText: resp.Text,
+		Cost:           resp.Cost,
+		Model:          resp.ModelUsed,
+		CreatedAt:      time.Now(),
+	}, nil
+}
+
+// Embed تضمين نص عبر /embeddings
+func (p *OpenAIProvider) Embed(ctx context.Context, text string, model string) ([]float64, error) {
+	startTime := time.Now()
+	p.begin()
+
+	model = cmp.Or(model, p.embeddingModel)
+	resp, err := p.client.CreateEmbeddings(ctx, openai.EmbeddingRequestStrings{
+		Input: []string{text},
+		Model: openai.EmbeddingModel(model),
+	})
+	if err != nil {
+		err = p.wrapError(ctx, err)
+		p.finish(startTime, 0, err)
+		return nil, err
+	}
+	if len(resp.Data) == 0 {
+		err = fmt.Errorf("no embeddings returned from OpenAI")
+		p.finish(startTime, 0, err)
+		return nil, err
+	}
+
+	vector := make([]float64, len(resp.Data[0].Embedding))
+	for i, v := range resp.Data[0].Embedding {
+		vector[i] = float64(v)
+	}
+
+	p.finish(startTime, Prices().Cost(model, resp.Usage.PromptTokens, 0), nil)
+	return vector, nil
+}
+
+// HealthCheck فحص الصحة: سرد النماذج، وهو مجاني ولا يستهلك الرصيد
+func (p *OpenAIProvider) HealthCheck(ctx context.Context) error {
+	if !p.IsAvailable() {
+		return types.ErrInvalidAPIKey.WithProvider(p.GetName())
+	}
+	if _, err := p.client.ListModels(ctx); err != nil {
+		return p.wrapError(ctx, err)
+	}
+	return nil
+}
+
+// ListModels عرض النماذج المتاحة
+func (p *OpenAIProvider) ListModels(ctx context.Context) ([]string, error) {
+	list, err := p.client.ListModels(ctx)
+	if err != nil {
+		return nil, p.wrapError(ctx, err)
+	}
+
+	models := make([]string, 0, len(list.Models))
+	for _, model := range list.Models {
+		models = append(models, model.ID)
+	}
+	return models, nil
+}
+
+// wrapError تحويل أخطاء go-openai إلى أخطاء المزود المُنمّطة
+func (p *OpenAIProvider) wrapError(ctx context.Context, err error) error {
+	var apiErr *openai.APIError
+	if errors.As(err, &apiErr) && apiErr.HTTPStatusCode > 0 {
+		return types.HTTPError(p.GetName(), apiErr.HTTPStatusCode, apiErr.Message)
+	}
+	var reqErr *openai.RequestError
+	if errors.As(err, &reqErr) && reqErr.HTTPStatusCode > 0 {
+		return types.HTTPError(p.GetName(), reqErr.HTTPStatusCode, string(reqErr.Body))
+	}
+	return types.RequestError(ctx, p.GetName(), err)
+}
+
+// textModel النموذج المطلوب أو الافتراضي
+func (p *OpenAIProvider) textModel(model string) string {
+	return cmp.Or(model, p.model)
+}
+
+// begin احتساب طلب جديد
+func (p *OpenAIProvider) begin() {
+	p.mu.Lock()
+	p.stats.Requests++
+	p.mu.Unlock()
+}
+
+// finish تسجيل نتيجة الطلب وتكلفته وزمنه
+func (p *OpenAIProvider) finish(startTime time.Time, cost float64, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err != nil {
+		p.stats.Failed++
+	} else {
+		p.stats.Successful++
+		p.stats.TotalCost += cost
+		p.stats.LastUsed = time.Now()
+	}
+
+	latency := float64(time.Since(startTime).Milliseconds())
+	if p.stats.AvgLatency == 0 {
+		p.stats.AvgLatency = latency
+	} else {
+		// متوسط متحرك بسيط
+		p.stats.AvgLatency = (p.stats.AvgLatency*float64(p.stats.Requests-1) + latency) / float64(p.stats.Requests)
+	}
+	p.stats.SuccessRate = float64(p.stats.Successful) / float64(p.stats.Requests) * 100
+}
+
+// IsAvailable متاح بمفتاح API، أو بعنوان خادم متوافق لا يحتاج مفتاحًا
+func (p *OpenAIProvider) IsAvailable() bool {
+	return p.apiKey != "" || p.IsCompatibleServer()
+}
+
+// IsCompatibleServer هل يعمل المزود مع خادم متوافق بدل واجهة OpenAI الرسمية
+func (p *OpenAIProvider) IsCompatibleServer() bool {
+	return p.baseURL != openAIDefaultBaseURL
+}
+
+// GetName اسم المزود
+func (p *OpenAIProvider) GetName() string {
+	return p.stats.Name
+}
+
+// GetCost التكلفة
+func (p *OpenAIProvider) GetCost() float64 {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.stats.TotalCost
+}
+
+// GetType نوع المزود
+func (p *OpenAIProvider) GetType() string {
+	return p.stats.Type
+}
+
+// GetStats الحصول على إحصائيات
+func (p *OpenAIProvider) GetStats() *types.ProviderStats {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	stats := *p.stats // نسخة
+	stats.IsAvailable = p.IsAvailable()
+	return &stats
+}
+
+// SupportsStreaming يدعم التدفق
+func (p *OpenAIProvider) SupportsStreaming() bool {
+	return true
+}
+
+// SupportsEmbedding يدعم التضمين
+func (p *OpenAIProvider) SupportsEmbedding() bool {
+	return true
+}
+
+// GetMaxTokens الحد الأقصى للرموز
+func (p *OpenAIProvider) GetMaxTokens() int {
+	return 16384 // حد مخرجات gpt-4o
+}
+
+// GetSupportedLanguages اللغات المدعومة
+func (p *OpenAIProvider) GetSupportedLanguages() []string {
+	return []string{
+		"ar", "en", "es", "fr", "de", "zh", "ja", "ko", "ru", "pt",
+		"it", "nl", "pl", "sv", "da", "fi", "no", "he", "hi", "tr",
+		"fa", "ur", "bn", "id",
+	}
+}
@@ -0,0 +1,89 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nawthtech/nawthtech/backend/internal/ai/types"
+)
+
+func TestOpenAIProviderAgainstCompatibleServer(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Model  string `json:"model"`
+			Stream bool   `json:"stream"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+
+		switch {
+		case r.URL.Path == "/v1/chat/completions" && body.Model == "missing":
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":{"message":"model not found","type":"invalid_request_error"}}`))
+		case r.URL.Path == "/v1/chat/completions" && body.Stream:
+			w.Header().Set("Content-Type", "text/event-stream")
+			for _, event := range []string{
+				`{"model":"llama3","choices":[{"index":0,"delta":{"role":"assistant","content":"Hel"}}]}`,
+				`{"model":"llama3","choices":[{"index":0,"delta":{"content":"lo"},"finish_reason":"stop"}]}`,
+				`{"model":"llama3","choices":[],"usage":{"prompt_tokens":7,"completion_tokens":2,"total_tokens":9}}`,
+			} {
+				fmt.Fprintf(w, "data: %s\n\n", event)
+			}
+			fmt.Fprint(w, "data: [DONE]\n\n")
+		case r.URL.Path == "/v1/chat/completions":
+			w.Write([]byte(`{"model":"llama3","choices":[{"index":0,"message":{"role":"assistant","content":"Hello"},"finish_reason":"stop"}],"usage":{"prompt_tokens":7,"completion_tokens":2,"total_tokens":9}}`))
+		case r.URL.Path == "/v1/embeddings":
+			w.Write([]byte(`{"object":"list","data":[{"object":"embedding","index":0,"embedding":[0.5,-0.25]}],"model":"nomic","usage":{"prompt_tokens":3,"total_tokens":3}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	t.Setenv("OPENAI_API_KEY", "")
+	t.Setenv("OPENAI_BASE_URL", server.URL+"/v1/")
+	t.Setenv("OPENAI_MODEL", "llama3")
+
+	provider := NewOpenAIProvider()
+	if !provider.IsAvailable() || !provider.IsCompatibleServer() {
+		t.Fatal("compatible server without an API key should be available")
+	}
+
+	resp, err := provider.GenerateText(context.Background(), types.TextRequest{Prompt: "hi"})
+	if err != nil {
+		t.Fatalf("GenerateText: %v", err)
+	}
+	if resp.Text != "Hello" || resp.PromptTokens != 7 || resp.CompletionTokens != 2 || resp.TokensEstimated {
+		t.Errorf("text response %+v", resp)
+	}
+
+	stream, err := provider.GenerateTextStream(context.Background(), types.TextRequest{Prompt: "hi"})
+	if err != nil {
+		t.Fatalf("GenerateTextStream: %v", err)
+	}
+	var text string
+	var final types.StreamChunk
+	for chunk := range stream {
+		if chunk.Done {
+			final = chunk
+			continue
+		}
+		text += chunk.Delta
+	}
+	if text != "Hello" || final.Err != nil || final.Response.CompletionTokens != 2 || final.Response.FinishReason != "stop" {
+		t.Errorf("streamed %q, final %+v (err %v)", text, final.Response, final.Err)
+	}
+
+	vector, err := provider.Embed(context.Background(), "hi", "")
+	if err != nil || len(vector) != 2 || vector[1] != -0.25 {
+		t.Errorf("Embed = %v, %v", vector, err)
+	}
+
+	_, err = provider.GenerateText(context.Background(), types.TextRequest{Prompt: "hi", Model: "missing"})
+	if !errors.Is(err, types.ErrModelNotSupported) {
+		t.Errorf("expected typed model error, got %v", err)
+	}
+}
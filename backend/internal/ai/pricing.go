@@ -0,0 +1,135 @@
+package ai
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/nawthtech/nawthtech/backend/internal/ai/types"
+)
+
+// ================================
+// جدول أسعار النماذج
+// ================================
+
+// ModelPrice سعر النموذج بالدولار لكل 1000 رمز
+type ModelPrice struct {
+	InputPer1K  float64 `json:"input_per_1k"`
+	OutputPer1K float64 `json:"output_per_1k"`
+}
+
+// PriceTable أسعار النماذج بمفتاح هو بادئة اسم النموذج؛ أطول بادئة مطابقة تُعتمد
+type PriceTable map[string]ModelPrice
+
+// DefaultPriceTable الأسعار المعلنة للنماذج المستخدمة؛ النماذج المحلية والتجريبية مجانية
+func DefaultPriceTable() PriceTable {
+	return PriceTable{
+		"gemini-2.5-pro":        {InputPer1K: 0.00125, OutputPer1K: 0.01},
+		"gemini-2.5-flash":      {InputPer1K: 0.0003, OutputPer1K: 0.0025},
+		"gemini-2.5-flash-lite": {InputPer1K: 0.0001, OutputPer1K: 0.0004},
+		"gemini-2.0-flash":      {InputPer1K: 0.0001, OutputPer1K: 0.0004},
+		"gemini-2.0-flash-lite": {InputPer1K: 0.000075, OutputPer1K: 0.0003},
+		"gemini-1.5-pro":        {InputPer1K: 0.00125, OutputPer1K: 0.005},
+		"gemini-1.5-flash":      {InputPer1K: 0.000075, OutputPer1K: 0.0003},
+
+		"gpt-4o":                 {InputPer1K: 0.0025, OutputPer1K: 0.01},
+		"gpt-4o-mini":            {InputPer1K: 0.00015, OutputPer1K: 0.0006},
+		"gpt-4.1":                {InputPer1K: 0.002, OutputPer1K: 0.008},
+		"gpt-4.1-mini":           {InputPer1K: 0.0004, OutputPer1K: 0.0016},
+		"gpt-4.1-nano":           {InputPer1K: 0.0001, OutputPer1K: 0.0004},
+		"gpt-4-turbo":            {InputPer1K: 0.01, OutputPer1K: 0.03},
+		"gpt-3.5-turbo":          {InputPer1K: 0.0005, OutputPer1K: 0.0015},
+		"text-embedding-3-small": {InputPer1K: 0.00002},
+		"text-embedding-3-large": {InputPer1K: 0.00013},
+		"text-embedding-ada-002": {InputPer1K: 0.0001},
+	}
+}
+
+// PriceTableFromFile جدول الأسعار الافتراضي بعد دمج أسعار ملف JSON فوقه
+func PriceTableFromFile(path string) (PriceTable, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read price table: %w", err)
+	}
+
+	var overrides PriceTable
+	if err := json.Unmarshal(data, &overrides); err != nil {
+		return nil, fmt.Errorf("failed to parse price table: %w", err)
+	}
+
+	table := DefaultPriceTable()
+	for model, price := range overrides {
+		table[strings.ToLower(model)] = price
+	}
+	return table, nil
+}
+
+var (
+	pricesOnce sync.Once
+	prices     PriceTable
+)
+
+// Prices جدول الأسعار المعتمد: AI_PRICE_TABLE (ملف JSON) إن وُجد وإلا الافتراضي
+func Prices() PriceTable {
+	pricesOnce.Do(func() {
+		prices = DefaultPriceTable()
+		if path := getEnvWithFallback("AI_PRICE_TABLE", ""); path != "" {
+			table, err := PriceTableFromFile(path)
+			if err != nil {
+				log.Printf("Warning: %v; using default prices", err)
+				return
+			}
+			prices = table
+		}
+	})
+	return prices
+}
+
+// Lookup سعر النموذج بأطول بادئة مطابقة؛ النماذج التجريبية (-exp) وغير المدرجة مجانية
+func (t PriceTable) Lookup(model string) (ModelPrice, bool) {
+	model = strings.ToLower(model)
+	if strings.Contains(model, "-exp") {
+		return ModelPrice{}, false
+	}
+
+	keys := make([]string, 0, len(t))
+	for key := range t {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return len(keys[i]) > len(keys[j]) })
+
+	for _, key := range keys {
+		if strings.HasPrefix(model, key) {
+			return t[key], true
+		}
+	}
+	return ModelPrice{}, false
+}
+
+// Cost تكلفة استدعاء بعدد رموز المدخلات والمخرجات
+func (t PriceTable) Cost(model string, promptTokens, completionTokens int) float64 {
+	price, _ := t.Lookup(model)
+	return float64(promptTokens)/1000*price.InputPer1K + float64(completionTokens)/1000*price.OutputPer1K
+}
+
+// meterText استكمال عدّ الرموز وتسعير الرد: ما أعاده المزود من أعداد يُعتمد، وما
+// لم يُعِده يُقدَّر بمُرمِّز عائلة النموذج ويُعلَّم TokensEstimated
+func meterText(model, prompt string, resp *types.TextResponse) {
+	if resp.ModelUsed != "" {
+		model = resp.ModelUsed
+	}
+	if resp.PromptTokens == 0 {
+		resp.PromptTokens = types.CountTokens(model, prompt)
+		resp.TokensEstimated = true
+	}
+	if resp.CompletionTokens == 0 && resp.Text != "" {
+		resp.CompletionTokens = types.CountTokens(model, resp.Text)
+		resp.TokensEstimated = true
+	}
+	resp.Tokens = resp.PromptTokens + resp.CompletionTokens
+	resp.Cost = Prices().Cost(model, resp.PromptTokens, resp.CompletionTokens)
+}
@@ -0,0 +1,61 @@
+package ai
+
+import (
+	"math"
+	"testing"
+
+	"github.com/nawthtech/nawthtech/backend/internal/ai/types"
+)
+
+func TestPriceTableLookup(t *testing.T) {
+	table := DefaultPriceTable()
+
+	tests := []struct {
+		model string
+		want  ModelPrice
+		found bool
+	}{
+		{model: "gemini-2.5-flash-lite-preview", want: table["gemini-2.5-flash-lite"], found: true},
+		{model: "gemini-2.5-flash", want: table["gemini-2.5-flash"], found: true},
+		{model: "gemini-2.5-flash-exp", found: false},
+		{model: "llama3.2:3b", found: false},
+	}
+	for _, tt := range tests {
+		got, found := table.Lookup(tt.model)
+		if got != tt.want || found != tt.found {
+			t.Errorf("Lookup(%q) = %+v, %v; want %+v, %v", tt.model, got, found, tt.want, tt.found)
+		}
+	}
+
+	cost := table.Cost("gemini-2.5-pro", 2000, 1000)
+	if want := 2*0.00125 + 0.01; math.Abs(cost-want) > 1e-12 {
+		t.Errorf("Cost = %v, want %v", cost, want)
+	}
+}
+
+func TestMeterTextEstimatesOnlyMissingCounts(t *testing.T) {
+	resp := &types.TextResponse{Text: "مرحبا بكم في نوث", PromptTokens: 12}
+	meterText("llama3.2:3b", "ignored because the provider reported it", resp)
+
+	if resp.PromptTokens != 12 {
+		t.Errorf("prompt tokens = %d, want provider-reported 12", resp.PromptTokens)
+	}
+	if resp.CompletionTokens == 0 || !resp.TokensEstimated {
+		t.Errorf("completion tokens = %d (estimated %v), want a local estimate", resp.CompletionTokens, resp.TokensEstimated)
+	}
+	if resp.Tokens != resp.PromptTokens+resp.CompletionTokens {
+		t.Errorf("tokens = %d, want prompt+completion", resp.Tokens)
+	}
+}
+
+func TestCountTokensByFamily(t *testing.T) {
+	english := "The quick brown fox jumps over the lazy dog."
+	if got := types.CountTokens("gemini-2.0-flash", english); got < 9 || got > 14 {
+		t.Errorf("english tokens = %d, want roughly one per word", got)
+	}
+
+	arabic := "الثعلب البني السريع يقفز فوق الكلب الكسول"
+	if types.CountTokens("mistral-7b", arabic) <= types.CountTokens("gemini-2.0-flash", arabic) {
+		t.Error("mistral should spend more tokens on Arabic than gemini")
+	}
+}
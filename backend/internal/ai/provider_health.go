@@ -0,0 +1,215 @@
+package ai
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/nawthtech/nawthtech/backend/internal/ai/types"
+)
+
+// ================================
+// صحة المزودين والتوجيه المعتمد عليها
+// ================================
+
+// maxProbeTimeout الحد الأعلى لمهلة فحص الصحة الواحد
+const maxProbeTimeout = 10 * time.Second
+
+// probeHTTP تنفيذ طلب فحص صحة والتحقق من نجاح الرد
+func probeHTTP(ctx context.Context, client *http.Client, provider string, req *http.Request) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return types.RequestError(ctx, provider, err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode != http.StatusOK {
+		return types.HTTPError(provider, resp.StatusCode, string(body))
+	}
+	return nil
+}
+
+// breaker قاطع الدائرة الخاص بالمزود، يُنشأ عند أول استخدام
+func (mp *MultiProvider) breaker(providerType ProviderType) *CircuitBreaker {
+	mp.breakersMu.Lock()
+	defer mp.breakersMu.Unlock()
+
+	if mp.breakers == nil {
+		mp.breakers = make(map[ProviderType]*CircuitBreaker)
+	}
+	b, exists := mp.breakers[providerType]
+	if !exists {
+		b = NewCircuitBreaker(string(providerType), mp.breakerOpts)
+		mp.breakers[providerType] = b
+	}
+	return b
+}
+
+// isOpen هل المزود مستبعد حاليًا بقاطع مفتوح (المزود بلا قاطع بعدُ لم يفشل قط)
+func (mp *MultiProvider) isOpen(providerType ProviderType) bool {
+	mp.breakersMu.Lock()
+	b := mp.breakers[providerType]
+	mp.breakersMu.Unlock()
+
+	return b != nil && b.State() == BreakerOpen
+}
+
+// GetBreakerStates حالة قواطع المزودين المسجلين مرتبة بالاسم
+func (mp *MultiProvider) GetBreakerStates() []BreakerSnapshot {
+	mp.mu.RLock()
+	providerTypes := make([]ProviderType, 0, len(mp.providers))
+	for pt := range mp.providers {
+		providerTypes = append(providerTypes, pt)
+	}
+	mp.mu.RUnlock()
+
+	sort.Slice(providerTypes, func(i, j int) bool { return providerTypes[i] < providerTypes[j] })
+	states := make([]BreakerSnapshot, 0, len(providerTypes))
+	for _, pt := range providerTypes {
+		states = append(states, mp.breaker(pt).Snapshot())
+	}
+	return states
+}
+
+// StartHealthProbes فحص صحة جميع المزودين كل interval حتى Close
+func (mp *MultiProvider) StartHealthProbes(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	mp.mu.Lock()
+	if mp.stopProbes != nil {
+		mp.mu.Unlock()
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	mp.stopProbes, mp.probesDone = cancel, done
+	mp.mu.Unlock()
+
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			mp.probeAll(ctx, interval)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// probeAll فحص كل مزود مسجل وتغذية قاطعه بالنتيجة
+func (mp *MultiProvider) probeAll(ctx context.Context, interval time.Duration) {
+	mp.mu.RLock()
+	providers := make(map[ProviderType]types.ProviderInterface, len(mp.providers))
+	for pt, p := range mp.providers {
+		providers[pt] = p
+	}
+	mp.mu.RUnlock()
+
+	timeout := interval
+	if timeout > maxProbeTimeout {
+		timeout = maxProbeTimeout
+	}
+
+	for pt, provider := range providers {
+		if ctx.Err() != nil {
+			return
+		}
+		probeCtx, cancel := context.WithTimeout(ctx, timeout)
+		err := probeProvider(probeCtx, provider)
+		cancel()
+		if ctx.Err() != nil {
+			return
+		}
+
+		mp.breaker(pt).RecordProbe(err)
+		result := "ok"
+		if err != nil {
+			result = "fail"
+			log.Printf("⚠️ Health probe failed for %s: %v", pt, err)
+		}
+		healthProbesTotal.WithLabelValues(string(pt), result).Inc()
+
+		mp.mu.Lock()
+		if stats, exists := mp.stats.ProviderStats[pt]; exists {
+			stats.IsAvailable = err == nil
+		}
+		mp.mu.Unlock()
+	}
+}
+
+// probeProvider أرخص استدعاء متاح للمزود: HealthCheck إن وُجد وإلا IsAvailable
+func probeProvider(ctx context.Context, provider types.ProviderInterface) error {
+	if checker, ok := provider.(types.HealthChecker); ok {
+		return checker.HealthCheck(ctx)
+	}
+	if !provider.IsAvailable() {
+		return types.ErrProviderUnavailable.WithProvider(provider.GetName())
+	}
+	return nil
+}
+
+// Close إيقاف فحوص الصحة الدورية
+func (mp *MultiProvider) Close() error {
+	mp.mu.Lock()
+	stop, done := mp.stopProbes, mp.probesDone
+	mp.stopProbes, mp.probesDone = nil, nil
+	mp.mu.Unlock()
+
+	if stop != nil {
+		stop()
+		<-done
+	}
+	return nil
+}
+
+// healthAwareStrategy تغليف لإستراتيجية التوجيه يستبعد المزودين ذوي القواطع المفتوحة
+type healthAwareStrategy struct {
+	RoutingStrategy
+	mp *MultiProvider
+}
+
+// SelectProvider اختيار الإستراتيجية الأصلية، أو أول بديل غير مستبعد إن كان قاطعه مفتوحًا
+func (s *healthAwareStrategy) SelectProvider(userTier, promptType, providerType string) ProviderType {
+	return s.healthy(s.RoutingStrategy.SelectProvider(userTier, promptType, providerType), providerType)
+}
+
+// SelectProviderFor كـ SelectProvider مع تفاصيل الطلب للإستراتيجيات التي تدعمها
+func (s *healthAwareStrategy) SelectProviderFor(req RoutingRequest) ProviderType {
+	return s.healthy(selectFor(s.RoutingStrategy, req), req.ProviderType)
+}
+
+// healthy المزود المختار ما لم يكن قاطعه مفتوحًا، وإلا أول بديل سليم
+func (s *healthAwareStrategy) healthy(primary ProviderType, providerType string) ProviderType {
+	if !s.mp.isOpen(primary) {
+		return primary
+	}
+	for _, fallback := range s.RoutingStrategy.GetFallbackChain(primary, providerType) {
+		if !s.mp.isOpen(fallback) {
+			return fallback
+		}
+	}
+	return primary
+}
+
+// GetFallbackChain السلسلة الأصلية دون المزودين ذوي القواطع المفتوحة
+func (s *healthAwareStrategy) GetFallbackChain(primary ProviderType, providerType string) []ProviderType {
+	chain := s.RoutingStrategy.GetFallbackChain(primary, providerType)
+	healthy := make([]ProviderType, 0, len(chain))
+	for _, pt := range chain {
+		if !s.mp.isOpen(pt) {
+			healthy = append(healthy, pt)
+		}
+	}
+	return healthy
+}
@@ -0,0 +1,177 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// ================================
+// حجز الحصص
+// ================================
+
+// ErrQuotaExceeded رُفض الحجز لنفاد الحصة أو بلوغ ميزانية صارمة
+var ErrQuotaExceeded = errors.New("ai quota exceeded")
+
+// QuotaError سبب رفض الحجز والمتبقي وموعد إتاحة الاستخدام مجددًا
+type QuotaError struct {
+	Type      string
+	Reason    string
+	Limit     int64
+	Remaining int64
+	ResetAt   time.Time
+}
+
+func (e *QuotaError) Error() string {
+	return fmt.Sprintf("%v: %s", ErrQuotaExceeded, e.Reason)
+}
+
+func (e *QuotaError) Unwrap() error {
+	return ErrQuotaExceeded
+}
+
+// QuotaReservation كمية محجوزة من حصة مستخدم قبل الاستدعاء، تُعتمد بالكمية الفعلية
+// بعد نجاحه أو تُلغى بعد فشله. Limit صفر يعني أن النوع بلا حصة لطبقة المستخدم
+type QuotaReservation struct {
+	UserID    string
+	Type      string
+	Amount    int64
+	Limit     int64
+	Remaining int64
+	ResetAt   time.Time
+
+	periodKey string
+	settled   bool
+}
+
+// periodEnd نهاية الفترة التي تبدأ في start
+func periodEnd(period string, start time.Time) time.Time {
+	if period == BudgetDaily {
+		return start.AddDate(0, 0, 1)
+	}
+	return start.AddDate(0, 1, 0)
+}
+
+// ReserveQuota حجز amount من حصة النوع بحدود getDefaultQuotas لطبقة المستخدم، بعد
+// التحقق من ميزانيات التكلفة الصارمة. الرفض يعيد *QuotaError
+func (cm *CostManager) ReserveQuota(ctx context.Context, userID, tier, requestType string, amount int64) (*QuotaReservation, error) {
+	tier = normalizeTier(tier)
+	if amount <= 0 {
+		amount = 1
+	}
+	reservation := &QuotaReservation{UserID: userID, Type: requestType, Amount: amount}
+
+	if reason, resetAt, blocked := cm.budgetBlock(ctx, userID, tier); blocked {
+		return nil, &QuotaError{Type: requestType, Reason: reason, ResetAt: resetAt}
+	}
+
+	quota, limited := cm.getDefaultQuotas(tier)[requestType]
+	if !limited || userID == "" {
+		return reservation, nil
+	}
+	start, periodKey := periodStart(quota.ResetPeriod, cm.now())
+	reservation.Limit = quota.Limit
+	reservation.ResetAt = periodEnd(quota.ResetPeriod, start)
+	reservation.periodKey = periodKey
+
+	var ok bool
+	var used, reserved int64
+	if cm.usage != nil {
+		var err error
+		ok, used, reserved, err = cm.usage.ReserveQuota(ctx, userID, requestType, periodKey, amount, quota.Limit)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		ok, used, reserved = cm.reserveFileQuota(userID, tier, requestType, amount)
+	}
+
+	reservation.Remaining = max(quota.Limit-used-reserved, 0)
+	if !ok {
+		return nil, &QuotaError{
+			Type:      requestType,
+			Reason:    fmt.Sprintf("تم تجاوز حصة %s لهذا المستخدم", requestType),
+			Limit:     quota.Limit,
+			Remaining: reservation.Remaining,
+			ResetAt:   reservation.ResetAt,
+		}
+	}
+	return reservation, nil
+}
+
+// CommitQuota اعتماد الحجز بالكمية الفعلية (صفر أو أقل = الكمية المحجوزة)
+func (cm *CostManager) CommitQuota(ctx context.Context, r *QuotaReservation, actual int64) error {
+	if actual <= 0 && r != nil {
+		actual = r.Amount
+	}
+	return cm.settleQuota(ctx, r, actual)
+}
+
+// ReleaseQuota إلغاء الحجز دون احتساب شيء
+func (cm *CostManager) ReleaseQuota(ctx context.Context, r *QuotaReservation) error {
+	return cm.settleQuota(ctx, r, 0)
+}
+
+func (cm *CostManager) settleQuota(ctx context.Context, r *QuotaReservation, used int64) error {
+	if r == nil || r.settled || r.Limit == 0 {
+		return nil
+	}
+	r.settled = true
+
+	if cm.usage != nil {
+		return cm.usage.SettleQuota(ctx, r.UserID, r.Type, r.periodKey, r.Amount, used)
+	}
+
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	if stats, ok := cm.Usage.UserUsage[r.UserID]; ok {
+		if quota, ok := stats.Quotas[r.Type]; ok {
+			quota.Reserved = max(quota.Reserved-r.Amount, 0)
+			quota.Used += used
+		}
+	}
+	go cm.save()
+	return nil
+}
+
+// reserveFileQuota الحجز في الذاكرة لمدير التكاليف المعتمد على الملف
+func (cm *CostManager) reserveFileQuota(userID, tier, requestType string, amount int64) (ok bool, used, reserved int64) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	stats := cm.userStats(userID, tier)
+	quota, exists := stats.Quotas[requestType]
+	if !exists {
+		// حصة نوع أضيف بعد حفظ ملف الاستخدام
+		quota = cm.getDefaultQuotas(tier)[requestType]
+		stats.Quotas[requestType] = quota
+	}
+	if quota.Used+quota.Reserved+amount > quota.Limit {
+		return false, quota.Used, quota.Reserved
+	}
+	quota.Reserved += amount
+	return true, quota.Used, quota.Reserved
+}
+
+// budgetBlock هل بلغ المستخدم ميزانية تكلفة صارمة، وسبب ذلك وموعد انتهاء فترتها.
+// خطأ قاعدة البيانات لا يمنع الخدمة
+func (cm *CostManager) budgetBlock(ctx context.Context, userID, tier string) (string, time.Time, bool) {
+	if cm.usage == nil {
+		cm.mu.RLock()
+		defer cm.mu.RUnlock()
+		return cm.fileBudgetBlock(userID)
+	}
+
+	status, err := cm.blockingBudgetDB(ctx, userID, tier)
+	if err != nil {
+		log.Printf("Warning: failed to check AI budgets: %v", err)
+		return "", time.Time{}, false
+	}
+	if status == nil {
+		return "", time.Time{}, false
+	}
+	start, _ := periodStart(status.Budget.Period, cm.now())
+	return budgetReason(status.Budget), periodEnd(status.Budget.Period, start), true
+}
@@ -0,0 +1,281 @@
+package ai
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/nawthtech/nawthtech/backend/internal/ai/types"
+	"github.com/nawthtech/nawthtech/backend/internal/cache"
+	"golang.org/x/sync/singleflight"
+)
+
+// ================================
+// تخزين استجابات AI المؤقت
+// ================================
+
+// عمليات التخزين المؤقت، ولكل منها مدة صلاحية مستقلة
+const (
+	CacheOpText        = "text"
+	CacheOpTranslation = "translation"
+)
+
+// الإعدادات الافتراضية (تتغير عبر AI_CACHE_* في البيئة)
+const (
+	defaultTextCacheTTL        = time.Hour
+	defaultTranslationCacheTTL = 24 * time.Hour
+	// defaultCacheMaxTemperature يطابق الحرارة الافتراضية للمزودين، فلا يتجاوز
+	// التخزين إلا من يطلب صراحة نتائج أكثر عشوائية
+	defaultCacheMaxTemperature = 0.7
+	aiCacheRedisPrefix         = "nawthtech:ai:"
+)
+
+// ResponseCache تخزين مؤقت معنون بالمحتوى لاستجابات التوليد والترجمة:
+// المفتاح مشتق من المزود والنموذج والنص بعد توحيده والمعاملات، والطلبات
+// المتطابقة المتزامنة تنتظر استدعاءً واحداً للمزود
+type ResponseCache struct {
+	store          cache.Cache
+	ttls           map[string]time.Duration
+	maxTemperature float64
+	group          singleflight.Group
+
+	hits      atomic.Int64
+	misses    atomic.Int64
+	coalesced atomic.Int64
+	saved     atomic.Uint64 // float64 bits للتكلفة الموفرة
+}
+
+// ResponseCacheOptions إعدادات ResponseCache
+type ResponseCacheOptions struct {
+	// TTLs مدة الصلاحية لكل عملية؛ العملية بلا مدة لا تخزن
+	TTLs map[string]time.Duration
+	// MaxTemperature أعلى حرارة تعتبر حتمية بما يكفي للتخزين (صفر = غير محددة، تعامل كالافتراضية)
+	MaxTemperature float64
+}
+
+// NewResponseCache إنشاء تخزين مؤقت للاستجابات فوق store
+func NewResponseCache(store cache.Cache, opts ResponseCacheOptions) *ResponseCache {
+	if opts.TTLs == nil {
+		opts.TTLs = map[string]time.Duration{
+			CacheOpText:        defaultTextCacheTTL,
+			CacheOpTranslation: defaultTranslationCacheTTL,
+		}
+	}
+	if opts.MaxTemperature <= 0 {
+		opts.MaxTemperature = defaultCacheMaxTemperature
+	}
+	return &ResponseCache{store: store, ttls: opts.TTLs, maxTemperature: opts.MaxTemperature}
+}
+
+// NewResponseCacheFromEnv إنشاء التخزين من البيئة، أو nil إذا كان AI_CACHE_ENABLED=false
+func NewResponseCacheFromEnv() *ResponseCache {
+	if enabled, err := strconv.ParseBool(getEnvWithFallback("AI_CACHE_ENABLED", "true")); err == nil && !enabled {
+		return nil
+	}
+
+	var store cache.Cache = cache.NewMemory(cache.DefaultMaxEntries, 0)
+	if getEnvWithFallback("CACHE_TYPE", "") == cache.BackendRedis {
+		redisStore, err := cache.NewRedis(getEnvWithFallback("REDIS_URL", "redis://localhost:6379"), cache.RedisOptions{Prefix: aiCacheRedisPrefix})
+		if err != nil {
+			log.Printf("Warning: AI response cache falling back to memory: %v", err)
+		} else {
+			store = redisStore
+		}
+	}
+
+	opts := ResponseCacheOptions{
+		TTLs: map[string]time.Duration{
+			CacheOpText:        envDuration("AI_CACHE_TEXT_TTL", defaultTextCacheTTL),
+			CacheOpTranslation: envDuration("AI_CACHE_TRANSLATION_TTL", defaultTranslationCacheTTL),
+		},
+	}
+	if value, err := strconv.ParseFloat(getEnvWithFallback("AI_CACHE_MAX_TEMPERATURE", ""), 64); err == nil {
+		opts.MaxTemperature = value
+	}
+	return NewResponseCache(store, opts)
+}
+
+// TextKey مفتاح طلب توليد نص، و false إذا كان الطلب غير قابل للتخزين
+// (بث، حرارة أعلى من الحد، أو Metadata["cache"] = false)
+func (rc *ResponseCache) TextKey(provider string, req types.TextRequest) (string, bool) {
+	if rc == nil || req.Stream || optedOut(req.Metadata) {
+		return "", false
+	}
+	temperature := req.Temperature
+	if temperature == 0 {
+		temperature = defaultCacheMaxTemperature
+	}
+	if temperature > rc.maxTemperature {
+		return "", false
+	}
+
+	return cacheKey(CacheOpText, provider, req.Model, normalizePrompt(req.Prompt), map[string]interface{}{
+		"temperature":       req.Temperature,
+		"max_tokens":        req.MaxTokens,
+		"top_p":             req.TopP,
+		"frequency_penalty": req.FrequencyPenalty,
+		"presence_penalty":  req.PresencePenalty,
+		"stop":              req.Stop,
+	}), true
+}
+
+// TranslationKey مفتاح طلب ترجمة
+func (rc *ResponseCache) TranslationKey(provider string, req types.TranslationRequest) (string, bool) {
+	if rc == nil || optedOut(req.Metadata) {
+		return "", false
+	}
+	return cacheKey(CacheOpTranslation, provider, req.Model, normalizePrompt(req.Text), map[string]interface{}{
+		"from": strings.ToLower(req.FromLang),
+		"to":   strings.ToLower(req.ToLang),
+	}), true
+}
+
+// Stats إحصائيات التخزين
+func (rc *ResponseCache) Stats() map[string]interface{} {
+	if rc == nil {
+		return map[string]interface{}{"enabled": false}
+	}
+	hits, misses := rc.hits.Load(), rc.misses.Load()
+	hitRate := 0.0
+	if hits+misses > 0 {
+		hitRate = float64(hits) / float64(hits+misses)
+	}
+	return map[string]interface{}{
+		"enabled":    true,
+		"hits":       hits,
+		"misses":     misses,
+		"coalesced":  rc.coalesced.Load(),
+		"hit_rate":   hitRate,
+		"saved_cost": rc.savedCost(),
+		"store":      rc.store.Stats(),
+	}
+}
+
+// cachedCall قراءة الاستجابة من التخزين، أو استدعاء load مرة واحدة لكل المتزامنين
+// على نفس المفتاح. القيمة المعادة نسخة مستقلة لكل مستدعٍ، و hit صحيحة إذا لم
+// يستدعِ هذا المستدعي المزود بنفسه. كل منتظر يتوقف عند إلغاء سياقه هو
+func cachedCall[T any](ctx context.Context, rc *ResponseCache, op, key string, load func() (*T, error)) (*T, bool, error) {
+	ttl := time.Duration(0)
+	if rc != nil {
+		ttl = rc.ttls[op]
+	}
+	if rc == nil || key == "" || ttl <= 0 {
+		resp, err := load()
+		return resp, false, err
+	}
+
+	var cached T
+	if err := cache.GetInto(rc.store, key, &cached); err == nil {
+		rc.hits.Add(1)
+		return &cached, true, nil
+	}
+
+	leader := false
+	ch := rc.group.DoChan(key, func() (interface{}, error) {
+		leader = true
+		resp, err := load()
+		if err != nil {
+			return nil, err
+		}
+		if resp != nil {
+			if err := rc.store.Set(key, *resp, ttl); err != nil {
+				log.Printf("Warning: failed to cache AI response: %v", err)
+			}
+		}
+		return resp, nil
+	})
+
+	var result singleflight.Result
+	select {
+	case result = <-ch:
+	case <-ctx.Done():
+		return nil, false, types.ContextError(ctx, "", ctx.Err())
+	}
+	if result.Err != nil {
+		// إلغاء طلب المستدعي الأول لا يلغي المنتظرين: من بقي سياقه صالحاً يستدعي المزود بنفسه
+		if !leader && types.IsCanceled(result.Err) && ctx.Err() == nil {
+			resp, err := load()
+			return resp, false, err
+		}
+		return nil, false, result.Err
+	}
+
+	resp, _ := result.Val.(*T)
+	if resp == nil {
+		return nil, false, nil
+	}
+	if leader {
+		rc.misses.Add(1)
+		return resp, false, nil
+	}
+
+	rc.hits.Add(1)
+	rc.coalesced.Add(1)
+	shared := *resp
+	return &shared, true, nil
+}
+
+// addSaved إضافة تكلفة موفرة
+func (rc *ResponseCache) addSaved(cost float64) {
+	if rc == nil || cost <= 0 {
+		return
+	}
+	for {
+		old := rc.saved.Load()
+		next := math.Float64bits(math.Float64frombits(old) + cost)
+		if rc.saved.CompareAndSwap(old, next) {
+			return
+		}
+	}
+}
+
+func (rc *ResponseCache) savedCost() float64 {
+	return math.Float64frombits(rc.saved.Load())
+}
+
+// cacheKey مفتاح معنون بالمحتوى
+func cacheKey(op, provider, model, prompt string, params map[string]interface{}) string {
+	data, _ := json.Marshal(struct {
+		Op       string                 `json:"op"`
+		Provider string                 `json:"provider"`
+		Model    string                 `json:"model"`
+		Prompt   string                 `json:"prompt"`
+		Params   map[string]interface{} `json:"params"`
+	}{op, provider, model, prompt, params})
+	sum := sha256.Sum256(data)
+	return "ai:" + op + ":" + hex.EncodeToString(sum[:])
+}
+
+// normalizePrompt توحيد النص: نهايات الأسطر، والمسافات المتكررة داخل السطر،
+// والمسافات في الأطراف (حالة الأحرف تبقى كما هي لأنها قد تغير المعنى)
+func normalizePrompt(prompt string) string {
+	lines := strings.Split(strings.ReplaceAll(prompt, "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.Join(strings.Fields(line), " ")
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// optedOut هل طلب المستدعي تجاوز التخزين عبر Metadata["cache"] = false
+func optedOut(metadata map[string]interface{}) bool {
+	value, ok := metadata["cache"]
+	if !ok {
+		return false
+	}
+	enabled, isBool := value.(bool)
+	return isBool && !enabled
+}
+
+func envDuration(key string, fallback time.Duration) time.Duration {
+	if d, err := time.ParseDuration(getEnvWithFallback(key, "")); err == nil {
+		return d
+	}
+	return fallback
+}
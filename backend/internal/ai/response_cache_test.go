@@ -0,0 +1,113 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nawthtech/nawthtech/backend/internal/ai/types"
+	"github.com/nawthtech/nawthtech/backend/internal/cache"
+)
+
+func TestResponseCacheKeys(t *testing.T) {
+	rc := NewResponseCache(cache.NewMemory(0, 0), ResponseCacheOptions{})
+
+	a, ok := rc.TextKey("gemini", types.TextRequest{Prompt: "  Write a slogan\r\nfor   coffee ", Temperature: 0.2})
+	if !ok {
+		t.Fatal("expected deterministic request to be cacheable")
+	}
+	b, _ := rc.TextKey("gemini", types.TextRequest{Prompt: "Write a slogan\nfor coffee", Temperature: 0.2, UserID: "u1"})
+	if a != b {
+		t.Error("expected normalised prompts to share a key")
+	}
+
+	if c, _ := rc.TextKey("ollama", types.TextRequest{Prompt: "Write a slogan\nfor coffee", Temperature: 0.2}); c == a {
+		t.Error("expected provider to be part of the key")
+	}
+	if d, _ := rc.TextKey("gemini", types.TextRequest{Prompt: "Write a slogan\nfor coffee", Temperature: 0.2, MaxTokens: 50}); d == a {
+		t.Error("expected parameters to be part of the key")
+	}
+
+	if _, ok := rc.TextKey("gemini", types.TextRequest{Prompt: "x", Temperature: 1.2}); ok {
+		t.Error("high temperature requests must not be cached")
+	}
+	if _, ok := rc.TextKey("gemini", types.TextRequest{Prompt: "x", Metadata: map[string]interface{}{"cache": false}}); ok {
+		t.Error("explicit opt-out must not be cached")
+	}
+}
+
+func TestCachedCallCoalescesAndCaches(t *testing.T) {
+	rc := NewResponseCache(cache.NewMemory(0, 0), ResponseCacheOptions{TTLs: map[string]time.Duration{CacheOpText: time.Minute}})
+
+	var calls atomic.Int32
+	release := make(chan struct{})
+	load := func() (*types.TextResponse, error) {
+		calls.Add(1)
+		<-release
+		return &types.TextResponse{Text: "hello", Cost: 0.02}, nil
+	}
+
+	var wg sync.WaitGroup
+	var hits atomic.Int32
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, hit, err := cachedCall(context.Background(), rc, CacheOpText, "k", load)
+			if err != nil || resp.Text != "hello" {
+				t.Errorf("unexpected result: %+v, %v", resp, err)
+			}
+			if hit {
+				hits.Add(1)
+			}
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if calls.Load() != 1 || hits.Load() != 4 {
+		t.Errorf("expected one provider call and four shared results, got calls=%d hits=%d", calls.Load(), hits.Load())
+	}
+
+	resp, hit, _ := cachedCall(context.Background(), rc, CacheOpText, "k", load)
+	if !hit || resp.Text != "hello" || calls.Load() != 1 {
+		t.Errorf("expected stored response, hit=%v calls=%d", hit, calls.Load())
+	}
+
+	// الأخطاء لا تخزن
+	failing := func() (*types.TextResponse, error) { return nil, errors.New("provider down") }
+	if _, _, err := cachedCall(context.Background(), rc, CacheOpText, "other", failing); err == nil {
+		t.Error("expected provider error")
+	}
+	if ok, _ := rc.store.Exists("other"); ok {
+		t.Error("failed responses must not be cached")
+	}
+}
+
+func TestCachedCallWaiterStopsOnOwnContext(t *testing.T) {
+	rc := NewResponseCache(cache.NewMemory(0, 0), ResponseCacheOptions{TTLs: map[string]time.Duration{CacheOpText: time.Minute}})
+
+	release := make(chan struct{})
+	defer close(release)
+	started := make(chan struct{})
+	go cachedCall(context.Background(), rc, CacheOpText, "slow", func() (*types.TextResponse, error) {
+		close(started)
+		<-release
+		return &types.TextResponse{Text: "late"}, nil
+	})
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, _, err := cachedCall(ctx, rc, CacheOpText, "slow", func() (*types.TextResponse, error) {
+		t.Error("waiter must not call the provider")
+		return nil, nil
+	})
+	if !errors.Is(err, types.ErrRequestTimeout) {
+		t.Errorf("expected waiter timeout, got %v", err)
+	}
+}
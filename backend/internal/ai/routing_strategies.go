@@ -0,0 +1,454 @@
+package ai
+
+import (
+	"fmt"
+	"hash/fnv"
+	"log"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/nawthtech/nawthtech/backend/internal/ai/models"
+	"github.com/nawthtech/nawthtech/backend/internal/ai/types"
+)
+
+// ================================
+// إستراتيجيات التوجيه
+// ================================
+
+// أسماء الإستراتيجيات في الإعداد
+const (
+	StrategyDefault  = "default"
+	StrategyCost     = "cost"
+	StrategyLatency  = "latency"
+	StrategyLanguage = "language"
+	StrategyWeighted = "weighted"
+)
+
+// RoutingOperations العمليات التي يمكن تخصيص إستراتيجية لكل منها
+var RoutingOperations = []string{"text", "image", "video", "analysis", "image_analysis", "translation"}
+
+// RoutingRequest تفاصيل الطلب المتاحة لقرار التوجيه
+type RoutingRequest struct {
+	Operation       string // text, image, video, analysis, image_analysis, translation
+	UserID          string
+	UserTier        string
+	PromptType      string
+	ProviderType    string // text, image, video
+	Language        string // لغة المحتوى المطلوب إن عُرفت
+	EstimatedTokens int
+	Provider        ProviderType // مزود طلبه المستدعي صراحةً؛ يتقدم على الإستراتيجية إن كان مسجلًا
+}
+
+// RequestAwareStrategy إستراتيجية تختار بحسب تفاصيل الطلب لا طبقة المستخدم وحدها
+type RequestAwareStrategy interface {
+	RoutingStrategy
+	SelectProviderFor(req RoutingRequest) ProviderType
+}
+
+// ProviderSignals ما تعرفه الإستراتيجيات عن المزودين المسجلين
+type ProviderSignals interface {
+	Provider(providerType ProviderType) (types.ProviderInterface, bool)
+	LatencyP95(providerType ProviderType) (time.Duration, bool)
+}
+
+// selectFor اختيار المزود بتفاصيل الطلب إن دعمتها الإستراتيجية
+func selectFor(strategy RoutingStrategy, req RoutingRequest) ProviderType {
+	if aware, ok := strategy.(RequestAwareStrategy); ok {
+		return aware.SelectProviderFor(req)
+	}
+	return strategy.SelectProvider(req.UserTier, req.PromptType, req.ProviderType)
+}
+
+// ================================
+// الترتيب بمقياس
+// ================================
+
+// rankedStrategy ترتيب المزودين المسجلين بمقياس (الأصغر أفضل)، مع الحفاظ على
+// ترتيب الإستراتيجية الأساسية عند التعادل. المزود الذي لا يصلح للطلب يُؤخَّر إلى آخر السلسلة
+type rankedStrategy struct {
+	base    RoutingStrategy
+	signals ProviderSignals
+	score   func(providerType ProviderType, provider types.ProviderInterface, req RoutingRequest) (score float64, eligible bool)
+}
+
+func (s *rankedStrategy) rank(req RoutingRequest) []ProviderType {
+	primary := s.base.SelectProvider(req.UserTier, req.PromptType, req.ProviderType)
+	order := append([]ProviderType{primary}, s.base.GetFallbackChain(primary, req.ProviderType)...)
+
+	type ranked struct {
+		providerType ProviderType
+		score        float64
+		eligible     bool
+	}
+	seen := make(map[ProviderType]bool, len(order))
+	candidates := make([]ranked, 0, len(order))
+	for _, pt := range order {
+		if seen[pt] {
+			continue
+		}
+		seen[pt] = true
+		provider, exists := s.signals.Provider(pt)
+		if !exists {
+			continue
+		}
+		score, eligible := s.score(pt, provider, req)
+		candidates = append(candidates, ranked{providerType: pt, score: score, eligible: eligible})
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].eligible != candidates[j].eligible {
+			return candidates[i].eligible
+		}
+		return candidates[i].score < candidates[j].score
+	})
+
+	result := make([]ProviderType, len(candidates))
+	for i, c := range candidates {
+		result[i] = c.providerType
+	}
+	return result
+}
+
+// SelectProviderFor أفضل مزود مسجل بالمقياس
+func (s *rankedStrategy) SelectProviderFor(req RoutingRequest) ProviderType {
+	if ranked := s.rank(req); len(ranked) > 0 {
+		return ranked[0]
+	}
+	return s.base.SelectProvider(req.UserTier, req.PromptType, req.ProviderType)
+}
+
+// SelectProvider الاختيار دون تفاصيل الطلب
+func (s *rankedStrategy) SelectProvider(userTier, promptType, providerType string) ProviderType {
+	return s.SelectProviderFor(RoutingRequest{UserTier: userTier, PromptType: promptType, ProviderType: providerType})
+}
+
+// GetFallbackChain بقية المزودين بترتيب المقياس
+func (s *rankedStrategy) GetFallbackChain(primary ProviderType, providerType string) []ProviderType {
+	ranked := s.rank(RoutingRequest{ProviderType: providerType})
+	chain := make([]ProviderType, 0, len(ranked))
+	for _, pt := range ranked {
+		if pt != primary {
+			chain = append(chain, pt)
+		}
+	}
+	return chain
+}
+
+// ================================
+// التكلفة
+// ================================
+
+// CatalogueEntry نموذج في كتالوج التكلفة منسوب إلى مزود
+type CatalogueEntry struct {
+	Provider  ProviderType
+	Model     string
+	CostPer1K float64
+	Languages []string
+}
+
+// CostCatalogue كتالوج تكلفة النماذج المتاحة عبر المزودين
+type CostCatalogue []CatalogueEntry
+
+// CatalogueFromTextModels بناء كتالوج من قائمة نماذج نصية مثل models.FreeTextModels:
+// النماذج المحلية عبر Ollama، ونماذج Google عبر Gemini، والباقي عبر Hugging Face
+func CatalogueFromTextModels(textModels []models.TextModel) CostCatalogue {
+	catalogue := make(CostCatalogue, 0, len(textModels))
+	for _, m := range textModels {
+		if !m.IsAvailable {
+			continue
+		}
+		provider := ProviderHuggingFace
+		switch {
+		case m.IsLocal:
+			provider = ProviderOllama
+		case strings.Contains(strings.ToLower(m.Provider), "google"):
+			provider = ProviderGemini
+		}
+		catalogue = append(catalogue, CatalogueEntry{
+			Provider:  provider,
+			Model:     m.ID,
+			CostPer1K: m.CostPer1K,
+			Languages: m.Languages,
+		})
+	}
+	return catalogue
+}
+
+// EstimateCost أرخص تكلفة تقديرية للطلب لدى المزود، مع تفضيل نماذجه التي تدعم اللغة
+func (c CostCatalogue) EstimateCost(provider ProviderType, language string, tokens int) (float64, bool) {
+	best, found := math.Inf(1), false
+	for _, entry := range c {
+		if entry.Provider != provider {
+			continue
+		}
+		if language != "" && !containsLanguage(entry.Languages, language) {
+			continue
+		}
+		if cost := entry.CostPer1K * float64(tokens) / 1000; cost < best {
+			best, found = cost, true
+		}
+	}
+	if !found && language != "" {
+		return c.EstimateCost(provider, "", tokens)
+	}
+	return best, found
+}
+
+// NewCostStrategy اختيار أرخص مزود بتقدير الكتالوج؛ المزود خارج الكتالوج يُؤخَّر
+func NewCostStrategy(base RoutingStrategy, signals ProviderSignals, catalogue CostCatalogue) RoutingStrategy {
+	return &rankedStrategy{
+		base:    base,
+		signals: signals,
+		score: func(pt ProviderType, _ types.ProviderInterface, req RoutingRequest) (float64, bool) {
+			tokens := req.EstimatedTokens
+			if tokens <= 0 {
+				tokens = 1000
+			}
+			return catalogue.EstimateCost(pt, req.Language, tokens)
+		},
+	}
+}
+
+// ================================
+// زمن الاستجابة
+// ================================
+
+// latencySamples عدد آخر الأزمنة المحفوظة لكل مزود لحساب المئين
+const latencySamples = 128
+
+// latencyWindow نافذة دوارة لآخر أزمنة الاستجابة
+type latencyWindow struct {
+	samples []time.Duration
+	next    int
+}
+
+func (w *latencyWindow) observe(d time.Duration) {
+	if len(w.samples) < latencySamples {
+		w.samples = append(w.samples, d)
+		return
+	}
+	w.samples[w.next] = d
+	w.next = (w.next + 1) % latencySamples
+}
+
+// p95 المئين 95 من الأزمنة المحفوظة
+func (w *latencyWindow) p95() time.Duration {
+	sorted := append([]time.Duration(nil), w.samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted[(len(sorted)*95-1)/100]
+}
+
+// NewLatencyStrategy اختيار المزود الأسرع بالمئين 95 المرصود؛ المزود بلا رصد بعدُ
+// يُقدَّم ليُجمع له رصد
+func NewLatencyStrategy(base RoutingStrategy, signals ProviderSignals) RoutingStrategy {
+	return &rankedStrategy{
+		base:    base,
+		signals: signals,
+		score: func(pt ProviderType, _ types.ProviderInterface, _ RoutingRequest) (float64, bool) {
+			p95, observed := signals.LatencyP95(pt)
+			if !observed {
+				return 0, true
+			}
+			return float64(p95), true
+		},
+	}
+}
+
+// ================================
+// اللغة
+// ================================
+
+// NewLanguageStrategy تفضيل المزودين الذين يدعمون لغة الطلب (مثل ar) بحسب
+// GetSupportedLanguages؛ الطلب بلا لغة معروفة يتبع ترتيب الإستراتيجية الأساسية
+func NewLanguageStrategy(base RoutingStrategy, signals ProviderSignals) RoutingStrategy {
+	return &rankedStrategy{
+		base:    base,
+		signals: signals,
+		score: func(_ ProviderType, provider types.ProviderInterface, req RoutingRequest) (float64, bool) {
+			if req.Language == "" {
+				return 0, true
+			}
+			return 0, containsLanguage(provider.GetSupportedLanguages(), req.Language)
+		},
+	}
+}
+
+func containsLanguage(languages []string, language string) bool {
+	for _, lang := range languages {
+		if strings.EqualFold(lang, language) {
+			return true
+		}
+	}
+	return false
+}
+
+// detectLanguage لغة النص إن غلبت عليه الحروف العربية، وإلا فارغة (غير محددة)
+func detectLanguage(text string) string {
+	letters, arabic := 0, 0
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		if unicode.Is(unicode.Arabic, r) {
+			arabic++
+		}
+	}
+	if letters > 0 && arabic*2 >= letters {
+		return "ar"
+	}
+	return ""
+}
+
+// requestLanguage لغة الطلب من metadata["language"] وإلا من النص نفسه
+func requestLanguage(metadata map[string]interface{}, text string) string {
+	if lang, ok := metadata["language"].(string); ok && lang != "" {
+		return lang
+	}
+	return detectLanguage(text)
+}
+
+// requestProvider المزود المطلوب صراحةً في بيانات الطلب (metadata["provider"])
+func requestProvider(metadata map[string]interface{}) ProviderType {
+	provider, _ := metadata["provider"].(string)
+	return ProviderType(provider)
+}
+
+// ================================
+// التوزيع الموزون (A/B)
+// ================================
+
+// weightedStrategy توزيع الطلبات على المزودين بأوزان؛ المستخدم نفسه يبقى على المزود
+// نفسه (تجزئة UserID) ليستقر الاختبار، والطلب المجهول يُوزع عشوائيًا
+type weightedStrategy struct {
+	base    RoutingStrategy
+	signals ProviderSignals
+	weights map[ProviderType]int
+	random  func(n int) int
+}
+
+// NewWeightedStrategy إنشاء إستراتيجية التوزيع الموزون
+func NewWeightedStrategy(base RoutingStrategy, signals ProviderSignals, weights map[ProviderType]int) RoutingStrategy {
+	return &weightedStrategy{base: base, signals: signals, weights: weights, random: rand.Intn}
+}
+
+// ParseWeights قراءة الأوزان بصيغة "gemini=80,ollama=20"
+func ParseWeights(spec string) (map[ProviderType]int, error) {
+	weights := make(map[ProviderType]int)
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, value, found := strings.Cut(part, "=")
+		weight, err := strconv.Atoi(strings.TrimSpace(value))
+		if !found || err != nil || weight < 0 {
+			return nil, fmt.Errorf("invalid routing weight %q", part)
+		}
+		weights[ProviderType(strings.TrimSpace(name))] = weight
+	}
+	return weights, nil
+}
+
+// SelectProviderFor اختيار مزود بحسب الأوزان من بين المسجلين
+func (s *weightedStrategy) SelectProviderFor(req RoutingRequest) ProviderType {
+	providerTypes := make([]ProviderType, 0, len(s.weights))
+	total := 0
+	for pt, weight := range s.weights {
+		if _, exists := s.signals.Provider(pt); exists && weight > 0 {
+			providerTypes = append(providerTypes, pt)
+			total += weight
+		}
+	}
+	if total == 0 {
+		return s.base.SelectProvider(req.UserTier, req.PromptType, req.ProviderType)
+	}
+	sort.Slice(providerTypes, func(i, j int) bool { return providerTypes[i] < providerTypes[j] })
+
+	var point int
+	if req.UserID != "" {
+		h := fnv.New32a()
+		h.Write([]byte(req.UserID))
+		point = int(h.Sum32() % uint32(total))
+	} else {
+		point = s.random(total)
+	}
+
+	for _, pt := range providerTypes {
+		point -= s.weights[pt]
+		if point < 0 {
+			return pt
+		}
+	}
+	return providerTypes[len(providerTypes)-1]
+}
+
+// SelectProvider الاختيار دون تفاصيل الطلب
+func (s *weightedStrategy) SelectProvider(userTier, promptType, providerType string) ProviderType {
+	return s.SelectProviderFor(RoutingRequest{UserTier: userTier, PromptType: promptType, ProviderType: providerType})
+}
+
+// GetFallbackChain سلسلة الإستراتيجية الأساسية
+func (s *weightedStrategy) GetFallbackChain(primary ProviderType, providerType string) []ProviderType {
+	return s.base.GetFallbackChain(primary, providerType)
+}
+
+// ================================
+// الإعداد
+// ================================
+
+// NewRoutingStrategy إنشاء إستراتيجية بالاسم فوق DefaultStrategy
+func NewRoutingStrategy(name string, signals ProviderSignals) (RoutingStrategy, error) {
+	base := &DefaultStrategy{}
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", StrategyDefault:
+		return base, nil
+	case StrategyCost:
+		return NewCostStrategy(base, signals, CatalogueFromTextModels(models.FreeTextModels)), nil
+	case StrategyLatency:
+		return NewLatencyStrategy(base, signals), nil
+	case StrategyLanguage:
+		return NewLanguageStrategy(base, signals), nil
+	case StrategyWeighted:
+		weights, err := ParseWeights(getEnvWithFallback("AI_ROUTING_WEIGHTS", ""))
+		if err != nil {
+			return nil, err
+		}
+		if len(weights) == 0 {
+			return nil, fmt.Errorf("weighted routing requires AI_ROUTING_WEIGHTS")
+		}
+		return NewWeightedStrategy(base, signals, weights), nil
+	default:
+		return nil, fmt.Errorf("unknown routing strategy %q", name)
+	}
+}
+
+// configureRouting الإستراتيجية العامة من AI_ROUTING_STRATEGY وإستراتيجية كل عملية من
+// AI_ROUTING_STRATEGY_<OPERATION> (مثل AI_ROUTING_STRATEGY_TRANSLATION=language)
+func (mp *MultiProvider) configureRouting() {
+	if name := getEnvWithFallback("AI_ROUTING_STRATEGY", ""); name != "" {
+		if strategy, err := NewRoutingStrategy(name, mp); err != nil {
+			log.Printf("Warning: %v; using default routing", err)
+		} else {
+			mp.SetRoutingStrategy(strategy)
+		}
+	}
+
+	for _, operation := range RoutingOperations {
+		name := getEnvWithFallback("AI_ROUTING_STRATEGY_"+strings.ToUpper(operation), "")
+		if name == "" {
+			continue
+		}
+		strategy, err := NewRoutingStrategy(name, mp)
+		if err != nil {
+			log.Printf("Warning: %v for %s; using default routing", err, operation)
+			continue
+		}
+		mp.SetOperationStrategy(operation, strategy)
+	}
+}
@@ -0,0 +1,95 @@
+package ai
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// languageProvider مزود يعلن لغات محددة
+type languageProvider struct {
+	scriptedProvider
+	languages []string
+}
+
+func (p *languageProvider) GetSupportedLanguages() []string { return p.languages }
+
+func newRoutingMultiProvider() *MultiProvider {
+	mp := newScriptedMultiProvider(FailoverOptions{}, nil)
+	mp.providers[ProviderOllama] = &languageProvider{scriptedProvider{name: "ollama"}, []string{"en"}}
+	mp.providers[ProviderHuggingFace] = &languageProvider{scriptedProvider{name: "huggingface"}, []string{"en", "ar"}}
+	mp.providers[ProviderGemini] = &languageProvider{scriptedProvider{name: "gemini"}, []string{"en", "ar"}}
+	return mp
+}
+
+func TestRoutingStrategies(t *testing.T) {
+	mp := newRoutingMultiProvider()
+	base := &DefaultStrategy{}
+
+	t.Run("cost picks the cheapest catalogue entry", func(t *testing.T) {
+		strategy := NewCostStrategy(base, mp, CostCatalogue{
+			{Provider: ProviderOllama, CostPer1K: 0.002},
+			{Provider: ProviderHuggingFace, CostPer1K: 0.001},
+			{Provider: ProviderGemini, CostPer1K: 0.0005, Languages: []string{"ar"}},
+		})
+		got := selectFor(strategy, RoutingRequest{ProviderType: "text", EstimatedTokens: 500})
+		if got != ProviderGemini {
+			t.Errorf("cost = %s, want %s", got, ProviderGemini)
+		}
+		chain := strategy.GetFallbackChain(ProviderGemini, "text")
+		if len(chain) != 2 || chain[0] != ProviderHuggingFace || chain[1] != ProviderOllama {
+			t.Errorf("cost fallback chain = %v, want [huggingface ollama]", chain)
+		}
+	})
+
+	t.Run("latency prefers lowest p95", func(t *testing.T) {
+		lat := newRoutingMultiProvider()
+		for i := 0; i < 20; i++ {
+			lat.observeLatency(ProviderOllama, 900*time.Millisecond)
+			lat.observeLatency(ProviderHuggingFace, 200*time.Millisecond)
+			lat.observeLatency(ProviderGemini, 400*time.Millisecond)
+		}
+		got := selectFor(NewLatencyStrategy(base, lat), RoutingRequest{ProviderType: "text"})
+		if got != ProviderHuggingFace {
+			t.Errorf("latency = %s, want %s", got, ProviderHuggingFace)
+		}
+	})
+
+	t.Run("language prefers Arabic-capable providers", func(t *testing.T) {
+		strategy := NewLanguageStrategy(base, mp)
+		req := RoutingRequest{UserTier: "free", ProviderType: "text", Language: detectLanguage("اكتب مقالًا عن التسويق")}
+		if got := selectFor(strategy, req); got != ProviderHuggingFace {
+			t.Errorf("language(ar) = %s, want %s", got, ProviderHuggingFace)
+		}
+		req.Language = detectLanguage("write an article")
+		if got := selectFor(strategy, req); got != ProviderOllama {
+			t.Errorf("language(unknown) = %s, want tier default %s", got, ProviderOllama)
+		}
+	})
+
+	t.Run("weighted split is sticky per user", func(t *testing.T) {
+		weights, err := ParseWeights("gemini=50, ollama=50")
+		if err != nil {
+			t.Fatalf("ParseWeights: %v", err)
+		}
+		strategy := NewWeightedStrategy(base, mp, weights)
+		counts := map[ProviderType]int{}
+		for i := 0; i < 200; i++ {
+			req := RoutingRequest{ProviderType: "text", UserID: fmt.Sprintf("user-%d", i)}
+			first := selectFor(strategy, req)
+			if again := selectFor(strategy, req); again != first {
+				t.Fatalf("user %s routed to %s then %s", req.UserID, first, again)
+			}
+			counts[first]++
+		}
+		if counts[ProviderGemini] == 0 || counts[ProviderOllama] == 0 || counts[ProviderHuggingFace] != 0 {
+			t.Errorf("weighted split = %v, want gemini and ollama only", counts)
+		}
+	})
+}
+
+func TestParseWeightsRejectsMalformed(t *testing.T) {
+	if _, err := ParseWeights("gemini=abc"); err == nil {
+		t.Error("expected error for non-numeric weight")
+	}
+}
@@ -70,7 +70,7 @@ Provide data-driven insights with specific examples and actionable recommendatio
 		UserTier:    extractUserTierFromContext(ctx),
 	}
 
-	return s.textProvider.GenerateText(req)
+	return s.textProvider.GenerateText(ctx, req)
 }
 
 func (s *AnalysisService) AnalyzeSentiment(ctx context.Context, text string, sourceType string) (*types.AnalysisResponse, error) {
@@ -99,9 +99,9 @@ Provide the analysis in a structured format with confidence scores.`, sourceType
 
 	// نحتاج إلى معرفة إذا كان المزود يدعم AnalyzeText مباشرة
 	if provider, ok := s.textProvider.(interface {
-		AnalyzeText(req types.AnalysisRequest) (*types.AnalysisResponse, error)
+		AnalyzeText(ctx context.Context, req types.AnalysisRequest) (*types.AnalysisResponse, error)
 	}); ok {
-		return provider.AnalyzeText(req)
+		return provider.AnalyzeText(ctx, req)
 	}
 
 	// خيار احتياطي: استخدام GenerateText
@@ -113,7 +113,7 @@ Provide the analysis in a structured format with confidence scores.`, sourceType
 		UserTier:    extractUserTierFromContext(ctx),
 	}
 
-	resp, err := s.textProvider.GenerateText(textReq)
+	resp, err := s.textProvider.GenerateText(ctx, textReq)
 	if err != nil {
 		return nil, err
 	}
@@ -185,7 +185,7 @@ Provide actionable intelligence.`, competitor, yourCompany, analysisType)
 		UserTier:    extractUserTierFromContext(ctx),
 	}
 
-	return s.textProvider.GenerateText(req)
+	return s.textProvider.GenerateText(ctx, req)
 }
 
 func (s *AnalysisService) AnalyzeFinancialData(ctx context.Context, financialMetrics map[string]interface{}, timeframe string) (*types.TextResponse, error) {
@@ -251,7 +251,7 @@ Provide data-driven insights with specific recommendations.`, timeframe, metrics
 		UserTier:    extractUserTierFromContext(ctx),
 	}
 
-	return s.textProvider.GenerateText(req)
+	return s.textProvider.GenerateText(ctx, req)
 }
 
 func (s *AnalysisService) AnalyzeCustomerFeedback(ctx context.Context, feedback []string, product string) (*types.AnalysisResponse, error) {
@@ -312,9 +312,9 @@ Provide structured analysis with specific action items.`, product, feedbackStr)
 	}
 
 	if provider, ok := s.textProvider.(interface {
-		AnalyzeText(req types.AnalysisRequest) (*types.AnalysisResponse, error)
+		AnalyzeText(ctx context.Context, req types.AnalysisRequest) (*types.AnalysisResponse, error)
 	}); ok {
-		return provider.AnalyzeText(req)
+		return provider.AnalyzeText(ctx, req)
 	}
 
 	textReq := types.TextRequest{
@@ -325,7 +325,7 @@ Provide structured analysis with specific action items.`, product, feedbackStr)
 		UserTier:    extractUserTierFromContext(ctx),
 	}
 
-	resp, err := s.textProvider.GenerateText(textReq)
+	resp, err := s.textProvider.GenerateText(ctx, textReq)
 	if err != nil {
 		return nil, err
 	}
@@ -396,7 +396,7 @@ Make it actionable with specific recommendations.`, organization, industry)
 		UserTier:    extractUserTierFromContext(ctx),
 	}
 
-	return s.textProvider.GenerateText(req)
+	return s.textProvider.GenerateText(ctx, req)
 }
 
 func (s *AnalysisService) AnalyzeRisk(ctx context.Context, project string, riskAreas []string) (*types.TextResponse, error) {
@@ -464,7 +464,7 @@ Provide a practical, actionable risk management framework.`, project, riskAreasS
 		UserTier:    extractUserTierFromContext(ctx),
 	}
 
-	return s.textProvider.GenerateText(req)
+	return s.textProvider.GenerateText(ctx, req)
 }
 
 func (s *AnalysisService) AnalyzePerformanceMetrics(ctx context.Context, metrics map[string]interface{}, benchmarks map[string]interface{}) (*types.TextResponse, error) {
@@ -540,7 +540,7 @@ Provide data-driven insights with specific recommendations.`, metricsStr, benchm
 		UserTier:    extractUserTierFromContext(ctx),
 	}
 
-	return s.textProvider.GenerateText(req)
+	return s.textProvider.GenerateText(ctx, req)
 }
 
 func (s *AnalysisService) GetServiceStats(ctx context.Context) map[string]interface{} {
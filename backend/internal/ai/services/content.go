@@ -49,7 +49,7 @@ Make it engaging and shareable.`, topic, tone, wordCount)
 		UserTier:    extractUserTierFromContext(ctx),
 	}
 
-	return s.textProvider.GenerateText(req)
+	return s.textProvider.GenerateText(ctx, req)
 }
 
 func (s *ContentService) GenerateSocialMediaPost(ctx context.Context, platform string, message string, hashtags []string) (*types.TextResponse, error) {
@@ -103,7 +103,7 @@ Make it engaging and platform-optimized.`, platform, message, hashtagsStr, platf
 		UserTier:    extractUserTierFromContext(ctx),
 	}
 
-	return s.textProvider.GenerateText(req)
+	return s.textProvider.GenerateText(ctx, req)
 }
 
 func (s *ContentService) GenerateProductDescription(ctx context.Context, product string, features []string, targetCustomer string) (*types.TextResponse, error) {
@@ -142,7 +142,7 @@ Make it sell the benefits, not just the features.`, product, featuresStr, target
 		UserTier:    extractUserTierFromContext(ctx),
 	}
 
-	return s.textProvider.GenerateText(req)
+	return s.textProvider.GenerateText(ctx, req)
 }
 
 func (s *ContentService) GenerateEmailNewsletter(ctx context.Context, topic string, audience string, length string) (*types.TextResponse, error) {
@@ -190,7 +190,7 @@ Make it valuable and click-worthy.`, topic, audience, wordCount)
 		UserTier:    extractUserTierFromContext(ctx),
 	}
 
-	return s.textProvider.GenerateText(req)
+	return s.textProvider.GenerateText(ctx, req)
 }
 
 func (s *ContentService) GenerateAdCopy(ctx context.Context, product string, platform string, goal string) (*types.TextResponse, error) {
@@ -229,7 +229,7 @@ Make it conversion-optimized.`, product, platform, goal)
 		UserTier:    extractUserTierFromContext(ctx),
 	}
 
-	return s.textProvider.GenerateText(req)
+	return s.textProvider.GenerateText(ctx, req)
 }
 
 func (s *ContentService) GenerateVideoScript(ctx context.Context, videoType string, topic string, duration int) (*types.TextResponse, error) {
@@ -269,7 +269,7 @@ Make it visual and engaging.`, videoType, topic, duration)
 		UserTier:    extractUserTierFromContext(ctx),
 	}
 
-	return s.textProvider.GenerateText(req)
+	return s.textProvider.GenerateText(ctx, req)
 }
 
 func (s *ContentService) GenerateWhitepaperOutline(ctx context.Context, topic string, targetAudience string) (*types.TextResponse, error) {
@@ -337,7 +337,7 @@ Make it thorough and research-oriented.`, topic, targetAudience)
 		UserTier:    extractUserTierFromContext(ctx),
 	}
 
-	return s.textProvider.GenerateText(req)
+	return s.textProvider.GenerateText(ctx, req)
 }
 
 func (s *ContentService) GeneratePressRelease(ctx context.Context, company string, announcement string, keyPoints []string) (*types.TextResponse, error) {
@@ -382,7 +382,7 @@ Make it journalistic and newsworthy.`, company, announcement, keyPointsStr)
 		UserTier:    extractUserTierFromContext(ctx),
 	}
 
-	return s.textProvider.GenerateText(req)
+	return s.textProvider.GenerateText(ctx, req)
 }
 
 func (s *ContentService) GenerateCaseStudy(ctx context.Context, client string, challenge string, solution string, results []string) (*types.TextResponse, error) {
@@ -430,7 +430,7 @@ Make it compelling and results-focused.`, client, challenge, solution, resultsSt
 		UserTier:    extractUserTierFromContext(ctx),
 	}
 
-	return s.textProvider.GenerateText(req)
+	return s.textProvider.GenerateText(ctx, req)
 }
 
 func (s *ContentService) GenerateLandingPageCopy(ctx context.Context, product string, targetCustomer string, uniqueSellingPoints []string) (*types.TextResponse, error) {
@@ -475,7 +475,7 @@ Make it convert visitors into leads/customers.`, product, targetCustomer, uspStr
 		UserTier:    extractUserTierFromContext(ctx),
 	}
 
-	return s.textProvider.GenerateText(req)
+	return s.textProvider.GenerateText(ctx, req)
 }
 
 func (s *ContentService) GetServiceStats(ctx context.Context) map[string]interface{} {
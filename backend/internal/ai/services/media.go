@@ -4,9 +4,8 @@ import (
 	"context"
 	"fmt"
 	"image"
-	_ "image/jpeg"
-	_ "image/png"
 	"io"
+	"sort"
 	"strings"
 
 	"github.com/nawthtech/nawthtech/backend/internal/ai/types"
@@ -42,7 +41,7 @@ func (s *MediaService) AnalyzeImage(ctx context.Context, imageData []byte, promp
 		UserID:    userID,
 	}
 
-	return s.imageProvider.AnalyzeImage(req)
+	return s.imageProvider.AnalyzeImage(ctx, req)
 }
 
 // GetImageInfo الحصول على معلومات الصورة
@@ -67,31 +66,58 @@ func (s *MediaService) GetImageInfo(ctx context.Context, imageData []byte) (*typ
 	}, nil
 }
 
-// ResizeImage تغيير حجم الصورة
+// ResizeImage تغيير حجم الصورة؛ إذا كان أحد البعدين صفراً يحسب من النسبة
 func (s *MediaService) ResizeImage(ctx context.Context, imageData []byte, width, height int) ([]byte, error) {
-	// تطبيق بسيط لتغيير حجم الصورة
-	// في تطبيق حقيقي، يمكن استخدام مكتبة مثل "github.com/disintegration/imaging"
+	if width <= 0 && height <= 0 {
+		return nil, fmt.Errorf("invalid dimensions: %dx%d", width, height)
+	}
 
-	// هنا نعيد نفس البيانات كتطبيق بسيط
-	return imageData, nil
+	result, err := s.ProcessImage(ctx, imageData, ImageProcessOptions{
+		Width:  width,
+		Height: height,
+		Fit:    FitFill,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.Data, nil
 }
 
-// CompressImage ضغط الصورة
+// CompressImage ضغط الصورة بإعادة ترميزها بالجودة المطلوبة (1-100) بنفس التنسيق
 func (s *MediaService) CompressImage(ctx context.Context, imageData []byte, quality int) ([]byte, error) {
-	// تطبيق بسيط لضغط الصورة
-	// في تطبيق حقيقي، يمكن استخدام مكتبة ضغط الصور
+	if quality < 1 || quality > 100 {
+		return nil, fmt.Errorf("invalid quality: %d", quality)
+	}
 
-	// هنا نعيد نفس البيانات كتطبيق بسيط
-	return imageData, nil
+	result, err := s.ProcessImage(ctx, imageData, ImageProcessOptions{Quality: quality})
+	if err != nil {
+		return nil, err
+	}
+
+	// إعادة الترميز قد تكبر الملف (مثلاً PNG محسن مسبقاً)، فيعاد الأصل بعد تنظيفه
+	stripped, err := s.StripMetadata(ctx, imageData)
+	if err == nil && len(stripped) <= len(result.Data) {
+		return stripped, nil
+	}
+	return result.Data, nil
 }
 
-// ConvertImageFormat تحويل تنسيق الصورة
+// ConvertImageFormat تحويل تنسيق الصورة (jpeg, png, webp, gif, bmp)
 func (s *MediaService) ConvertImageFormat(ctx context.Context, imageData []byte, format string) ([]byte, error) {
-	// تطبيق بسيط لتحويل التنسيق
-	// في تطبيق حقيقي، يمكن استخدام مكتبة للتحويل
+	if !s.isSupportedFormat(format) {
+		return nil, fmt.Errorf("unsupported image format: %s", format)
+	}
 
-	// هنا نعيد نفس البيانات كتطبيق بسيط
-	return imageData, nil
+	result, err := s.ProcessImage(ctx, imageData, ImageProcessOptions{Format: format})
+	if err != nil {
+		return nil, err
+	}
+	return result.Data, nil
+}
+
+// StripMetadata إزالة بيانات EXIF (ومنها الموقع GPS) والتعليقات من الصورة
+func (s *MediaService) StripMetadata(ctx context.Context, imageData []byte) ([]byte, error) {
+	return stripImageMetadata(imageData)
 }
 
 // GenerateImageFromText توليد صورة من نص
@@ -112,7 +138,7 @@ func (s *MediaService) GenerateImageFromText(ctx context.Context, prompt string,
 		UserID:  userID,
 	}
 
-	resp, err := s.imageProvider.GenerateImage(req)
+	resp, err := s.imageProvider.GenerateImage(ctx, req)
 	if err != nil {
 		return nil, err
 	}
@@ -143,13 +169,23 @@ func (s *MediaService) RemoveBackground(ctx context.Context, imageData []byte) (
 	return imageData, nil
 }
 
-// ApplyFilter تطبيق فلتر على الصورة
+// ApplyFilter تطبيق فلتر مسمى على الصورة (انظر GetSupportedFilters)
 func (s *MediaService) ApplyFilter(ctx context.Context, imageData []byte, filterType string) ([]byte, error) {
-	// تطبيق بسيط للفلتر
-	// في تطبيق حقيقي، يمكن استخدام مكتبة معالجة الصور
+	result, err := s.ProcessImage(ctx, imageData, ImageProcessOptions{Filter: filterType})
+	if err != nil {
+		return nil, err
+	}
+	return result.Data, nil
+}
 
-	// هنا نعيد نفس البيانات كتطبيق بسيط
-	return imageData, nil
+// GetSupportedFilters الحصول على أسماء الفلاتر المدعومة
+func (s *MediaService) GetSupportedFilters() []string {
+	filters := make([]string, 0, len(imageFilters))
+	for name := range imageFilters {
+		filters = append(filters, name)
+	}
+	sort.Strings(filters)
+	return filters
 }
 
 // ExtractTextFromImage استخراج نص من صورة (OCR)
@@ -190,12 +226,8 @@ func (s *MediaService) ValidateImage(imageData []byte) error {
 	}
 
 	// التحقق من دعم التنسيق
-	supported := s.GetSupportedImageFormats()
-	format = strings.ToLower(format)
-	for _, supportedFormat := range supported {
-		if format == supportedFormat {
-			return nil
-		}
+	if s.isSupportedFormat(format) {
+		return nil
 	}
 
 	return fmt.Errorf("unsupported image format: %s", format)
@@ -228,12 +260,16 @@ func (s *MediaService) GetImageStats(ctx context.Context) map[string]interface{}
 
 // BatchProcessImages معالجة دفعة من الصور
 func (s *MediaService) BatchProcessImages(ctx context.Context, images [][]byte, processFunc func([]byte) ([]byte, error)) ([][]byte, error) {
-	var results [][]byte
+	results := make([][]byte, 0, len(images))
+
+	for i, imageData := range images {
+		if err := ctx.Err(); err != nil {
+			return results, err
+		}
 
-	for _, imageData := range images {
 		processed, err := processFunc(imageData)
 		if err != nil {
-			return results, fmt.Errorf("failed to process image: %w", err)
+			return results, fmt.Errorf("failed to process image %d: %w", i, err)
 		}
 		results = append(results, processed)
 	}
@@ -241,34 +277,32 @@ func (s *MediaService) BatchProcessImages(ctx context.Context, images [][]byte,
 	return results, nil
 }
 
-// CreateThumbnail إنشاء صورة مصغرة
-func (s *MediaService) CreateThumbnail(ctx context.Context, imageData []byte, maxWidth, maxHeight int) ([]byte, error) {
-	// الحصول على معلومات الصورة
-	info, err := s.GetImageInfo(ctx, imageData)
-	if err != nil {
-		return nil, err
-	}
-
-	// حساب الأبعاد الجديدة مع الحفاظ على النسبة
-	width := info.Width
-	height := info.Height
-
-	if width > maxWidth || height > maxHeight {
-		ratio := float64(width) / float64(height)
-
-		if width > maxWidth {
-			width = maxWidth
-			height = int(float64(width) / ratio)
+// Processor دالة معالجة بخيارات ثابتة للاستخدام مع BatchProcessImages
+func (s *MediaService) Processor(ctx context.Context, opts ImageProcessOptions) func([]byte) ([]byte, error) {
+	return func(imageData []byte) ([]byte, error) {
+		result, err := s.ProcessImage(ctx, imageData, opts)
+		if err != nil {
+			return nil, err
 		}
+		return result.Data, nil
+	}
+}
 
-		if height > maxHeight {
-			height = maxHeight
-			width = int(float64(height) * ratio)
-		}
+// CreateThumbnail إنشاء صورة مصغرة ضمن الأبعاد القصوى مع الحفاظ على النسبة
+func (s *MediaService) CreateThumbnail(ctx context.Context, imageData []byte, maxWidth, maxHeight int) ([]byte, error) {
+	if maxWidth <= 0 || maxHeight <= 0 {
+		return nil, fmt.Errorf("invalid thumbnail size: %dx%d", maxWidth, maxHeight)
 	}
 
-	// تغيير حجم الصورة
-	return s.ResizeImage(ctx, imageData, width, height)
+	result, err := s.ProcessImage(ctx, imageData, ImageProcessOptions{
+		Width:  maxWidth,
+		Height: maxHeight,
+		Fit:    FitInside,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.Data, nil
 }
 
 // ReadImageFromReader قراءة صورة من قارئ
@@ -284,6 +318,17 @@ func (s *MediaService) WriteImageToWriter(ctx context.Context, imageData []byte,
 
 // ==================== دالة مساعدة خاصة ====================
 
+// isSupportedFormat التحقق من دعم التنسيق
+func (s *MediaService) isSupportedFormat(format string) bool {
+	format = normalizeImageFormat(format)
+	for _, supportedFormat := range s.GetSupportedImageFormats() {
+		if format == supportedFormat {
+			return true
+		}
+	}
+	return false
+}
+
 // extractUserIDFromContext استخراج معرف المستخدم من السياق
 func (s *MediaService) extractUserIDFromContext(ctx context.Context) string {
 	// في تطبيق حقيقي، يمكن استخراج معرف المستخدم من السياق
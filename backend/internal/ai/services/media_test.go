@@ -0,0 +1,278 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+// testImage صورة ملونة: النصف الأيسر أحمر والأيمن أزرق
+func testImage(w, h int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := color.NRGBA{R: 220, A: 255}
+			if x >= w/2 {
+				c = color.NRGBA{B: 220, A: 255}
+			}
+			img.SetNRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("png encode: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// jpegWithEXIF ترميز JPEG وإدراج مقطع APP1 يحمل وسم الاتجاه ووسم GPS
+func jpegWithEXIF(t *testing.T, img image.Image, orientation uint16) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 95}); err != nil {
+		t.Fatalf("jpeg encode: %v", err)
+	}
+
+	tiff := []byte("II*\x00\x08\x00\x00\x00")
+	tiff = binary.LittleEndian.AppendUint16(tiff, 2)
+	// Orientation (SHORT)
+	tiff = binary.LittleEndian.AppendUint16(tiff, 0x0112)
+	tiff = binary.LittleEndian.AppendUint16(tiff, 3)
+	tiff = binary.LittleEndian.AppendUint32(tiff, 1)
+	tiff = binary.LittleEndian.AppendUint16(tiff, orientation)
+	tiff = append(tiff, 0, 0)
+	// GPSInfo IFD pointer (LONG)
+	tiff = binary.LittleEndian.AppendUint16(tiff, 0x8825)
+	tiff = binary.LittleEndian.AppendUint16(tiff, 4)
+	tiff = binary.LittleEndian.AppendUint32(tiff, 1)
+	tiff = binary.LittleEndian.AppendUint32(tiff, 0)
+	tiff = append(tiff, 0, 0, 0, 0)
+
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	segment := []byte{0xFF, 0xE1}
+	segment = binary.BigEndian.AppendUint16(segment, uint16(len(payload)+2))
+	segment = append(segment, payload...)
+
+	data := buf.Bytes()
+	out := append([]byte{}, data[:2]...)
+	out = append(out, segment...)
+	return append(out, data[2:]...)
+}
+
+func decodeConfig(t *testing.T, data []byte) (image.Config, string) {
+	t.Helper()
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("decode config: %v", err)
+	}
+	return cfg, format
+}
+
+func TestResizeImage(t *testing.T) {
+	s := NewMediaService(nil, nil)
+	ctx := context.Background()
+	src := encodePNG(t, testImage(400, 200))
+
+	out, err := s.ResizeImage(ctx, src, 100, 50)
+	if err != nil {
+		t.Fatalf("ResizeImage: %v", err)
+	}
+	if cfg, format := decodeConfig(t, out); cfg.Width != 100 || cfg.Height != 50 || format != "png" {
+		t.Errorf("expected 100x50 png, got %dx%d %s", cfg.Width, cfg.Height, format)
+	}
+
+	// البعد الصفري يحسب من النسبة
+	out, err = s.ResizeImage(ctx, src, 80, 0)
+	if err != nil {
+		t.Fatalf("ResizeImage: %v", err)
+	}
+	if cfg, _ := decodeConfig(t, out); cfg.Width != 80 || cfg.Height != 40 {
+		t.Errorf("expected 80x40, got %dx%d", cfg.Width, cfg.Height)
+	}
+}
+
+func TestCreateThumbnailKeepsAspectRatio(t *testing.T) {
+	s := NewMediaService(nil, nil)
+	out, err := s.CreateThumbnail(context.Background(), encodePNG(t, testImage(300, 100)), 60, 60)
+	if err != nil {
+		t.Fatalf("CreateThumbnail: %v", err)
+	}
+	if cfg, _ := decodeConfig(t, out); cfg.Width != 60 || cfg.Height != 20 {
+		t.Errorf("expected 60x20, got %dx%d", cfg.Width, cfg.Height)
+	}
+}
+
+func TestProcessImageFitCover(t *testing.T) {
+	s := NewMediaService(nil, nil)
+	result, err := s.ProcessImage(context.Background(), encodePNG(t, testImage(400, 200)), ImageProcessOptions{
+		Width: 100, Height: 100, Fit: FitCover, Format: "webp", Quality: 80,
+	})
+	if err != nil {
+		t.Fatalf("ProcessImage: %v", err)
+	}
+	cfg, format := decodeConfig(t, result.Data)
+	if format != "webp" || cfg.Width != 100 || cfg.Height != 100 {
+		t.Errorf("expected 100x100 webp, got %dx%d %s", cfg.Width, cfg.Height, format)
+	}
+	if result.ContentType() != "image/webp" {
+		t.Errorf("unexpected content type %s", result.ContentType())
+	}
+}
+
+func TestConvertAndCompress(t *testing.T) {
+	s := NewMediaService(nil, nil)
+	ctx := context.Background()
+	src := encodePNG(t, testImage(64, 64))
+
+	for _, format := range []string{"jpeg", "jpg", "png", "webp", "gif", "bmp"} {
+		out, err := s.ConvertImageFormat(ctx, src, format)
+		if err != nil {
+			t.Fatalf("ConvertImageFormat(%s): %v", format, err)
+		}
+		if _, got := decodeConfig(t, out); got != normalizeImageFormat(format) {
+			t.Errorf("expected %s, got %s", format, got)
+		}
+	}
+	if _, err := s.ConvertImageFormat(ctx, src, "tiff"); err == nil {
+		t.Error("expected unsupported format error")
+	}
+
+	photo := jpegWithEXIF(t, testImage(256, 256), 1)
+	low, err := s.CompressImage(ctx, photo, 20)
+	if err != nil {
+		t.Fatalf("CompressImage: %v", err)
+	}
+	if len(low) >= len(photo) {
+		t.Errorf("expected compressed output smaller than %d, got %d", len(photo), len(low))
+	}
+}
+
+func TestEXIFOrientationCorrected(t *testing.T) {
+	s := NewMediaService(nil, nil)
+
+	// الاتجاه 6: الصورة مخزنة أفقية وتعرض بعد تدويرها 90 درجة
+	data := jpegWithEXIF(t, testImage(80, 40), 6)
+	if got := jpegOrientation(data); got != 6 {
+		t.Fatalf("expected orientation 6, got %d", got)
+	}
+
+	result, err := s.ProcessImage(context.Background(), data, ImageProcessOptions{Format: "png"})
+	if err != nil {
+		t.Fatalf("ProcessImage: %v", err)
+	}
+	if result.Width != 40 || result.Height != 80 {
+		t.Fatalf("expected 40x80 after rotation, got %dx%d", result.Width, result.Height)
+	}
+
+	// بعد التدوير باتجاه عقارب الساعة يصبح الأحمر في الأعلى
+	img, _, _ := image.Decode(bytes.NewReader(result.Data))
+	top := color.NRGBAModel.Convert(img.At(20, 5)).(color.NRGBA)
+	bottom := color.NRGBAModel.Convert(img.At(20, 75)).(color.NRGBA)
+	if top.R < 150 || bottom.B < 150 {
+		t.Errorf("unexpected colors after rotation: top=%v bottom=%v", top, bottom)
+	}
+}
+
+func TestStripMetadata(t *testing.T) {
+	s := NewMediaService(nil, nil)
+	ctx := context.Background()
+
+	data := jpegWithEXIF(t, testImage(32, 32), 1)
+	stripped, err := s.StripMetadata(ctx, data)
+	if err != nil {
+		t.Fatalf("StripMetadata: %v", err)
+	}
+	if bytes.Contains(stripped, []byte("Exif\x00\x00")) {
+		t.Error("EXIF segment still present")
+	}
+	if len(stripped) >= len(data) {
+		t.Error("expected stripped jpeg to be smaller")
+	}
+	decodeConfig(t, stripped)
+
+	// صورة مدورة تطبق اتجاهها قبل حذف الوسم
+	rotated, err := s.StripMetadata(ctx, jpegWithEXIF(t, testImage(32, 16), 8))
+	if err != nil {
+		t.Fatalf("StripMetadata: %v", err)
+	}
+	if cfg, _ := decodeConfig(t, rotated); cfg.Width != 16 || cfg.Height != 32 || jpegOrientation(rotated) != 1 {
+		t.Errorf("expected baked 16x32 rotation, got %dx%d", cfg.Width, cfg.Height)
+	}
+
+	// PNG: حذف الأجزاء النصية مع بقاء الصورة صالحة
+	src := encodePNG(t, testImage(8, 8))
+	text := []byte("tEXtComment\x00gps 24.7,46.6")
+	chunk := binary.BigEndian.AppendUint32(nil, uint32(len(text)-4))
+	chunk = append(chunk, text...)
+	chunk = append(chunk, 0, 0, 0, 0)
+	withText := append(append(append([]byte{}, src[:33]...), chunk...), src[33:]...)
+
+	cleaned, err := s.StripMetadata(ctx, withText)
+	if err != nil {
+		t.Fatalf("StripMetadata png: %v", err)
+	}
+	if !bytes.Equal(cleaned, src) {
+		t.Error("expected tEXt chunk to be removed")
+	}
+}
+
+func TestApplyFilter(t *testing.T) {
+	s := NewMediaService(nil, nil)
+	ctx := context.Background()
+	src := encodePNG(t, testImage(16, 16))
+
+	for _, name := range s.GetSupportedFilters() {
+		if _, err := s.ApplyFilter(ctx, src, name); err != nil {
+			t.Errorf("filter %s: %v", name, err)
+		}
+	}
+
+	out, err := s.ApplyFilter(ctx, src, "grayscale")
+	if err != nil {
+		t.Fatalf("ApplyFilter: %v", err)
+	}
+	img, _, _ := image.Decode(bytes.NewReader(out))
+	c := color.NRGBAModel.Convert(img.At(2, 2)).(color.NRGBA)
+	if c.R != c.G || c.G != c.B {
+		t.Errorf("expected gray pixel, got %v", c)
+	}
+
+	if _, err := s.ApplyFilter(ctx, src, "unknown"); err == nil {
+		t.Error("expected error for unknown filter")
+	}
+}
+
+func TestBatchProcessImages(t *testing.T) {
+	s := NewMediaService(nil, nil)
+	images := [][]byte{encodePNG(t, testImage(100, 100)), encodePNG(t, testImage(50, 200))}
+
+	ctx := context.Background()
+	results, err := s.BatchProcessImages(ctx, images, s.Processor(ctx, ImageProcessOptions{
+		Width: 20, Height: 20, Fit: FitContain, Format: "jpeg",
+	}))
+	if err != nil {
+		t.Fatalf("BatchProcessImages: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(results))
+	}
+	if cfg, format := decodeConfig(t, results[1]); format != "jpeg" || cfg.Width != 5 || cfg.Height != 20 {
+		t.Errorf("expected 5x20 jpeg, got %dx%d %s", cfg.Width, cfg.Height, format)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := s.BatchProcessImages(cancelled, images, s.Processor(cancelled, ImageProcessOptions{})); err == nil {
+		t.Error("expected cancelled batch to fail")
+	}
+}
@@ -44,7 +44,7 @@ Make it detailed and actionable.`, product, targetAudience, budget)
 		UserTier:    extractUserTierFromContext(ctx),
 	}
 
-	return s.textProvider.GenerateText(req)
+	return s.textProvider.GenerateText(ctx, req)
 }
 
 func (s *StrategyService) GenerateBusinessPlan(ctx context.Context, businessIdea string, industry string) (*types.TextResponse, error) {
@@ -73,7 +73,7 @@ Make it professional and investor-ready.`, businessIdea, industry)
 		UserTier:    extractUserTierFromContext(ctx),
 	}
 
-	return s.textProvider.GenerateText(req)
+	return s.textProvider.GenerateText(ctx, req)
 }
 
 func (s *StrategyService) GenerateContentStrategy(ctx context.Context, brand string, platforms []string, goals []string) (*types.TextResponse, error) {
@@ -105,7 +105,7 @@ Make it practical and platform-optimized.`, brand, platformsStr, goalsStr)
 		UserTier:    extractUserTierFromContext(ctx),
 	}
 
-	return s.textProvider.GenerateText(req)
+	return s.textProvider.GenerateText(ctx, req)
 }
 
 func (s *StrategyService) GenerateSWOTAnalysis(ctx context.Context, company string, industry string) (*types.TextResponse, error) {
@@ -145,7 +145,7 @@ Make it insightful and actionable.`, company, industry)
 		UserTier:    extractUserTierFromContext(ctx),
 	}
 
-	return s.textProvider.GenerateText(req)
+	return s.textProvider.GenerateText(ctx, req)
 }
 
 func (s *StrategyService) GenerateCompetitiveAnalysis(ctx context.Context, company string, competitors []string) (*types.TextResponse, error) {
@@ -180,7 +180,7 @@ Make it data-driven and strategic.`, company, competitorsStr)
 		UserTier:    extractUserTierFromContext(ctx),
 	}
 
-	return s.textProvider.GenerateText(req)
+	return s.textProvider.GenerateText(ctx, req)
 }
 
 func (s *StrategyService) GenerateProductLaunchPlan(ctx context.Context, product string, targetMarket string, launchDate string) (*types.TextResponse, error) {
@@ -222,7 +222,7 @@ Make it detailed with specific timelines and responsibilities.`, product, target
 		UserTier:    extractUserTierFromContext(ctx),
 	}
 
-	return s.textProvider.GenerateText(req)
+	return s.textProvider.GenerateText(ctx, req)
 }
 
 func (s *StrategyService) GenerateBrandPositioning(ctx context.Context, brand string, targetCustomer string, competitors []string) (*types.TextResponse, error) {
@@ -255,7 +255,7 @@ Make it distinctive and memorable.`, brand, targetCustomer, competitorsStr)
 		UserTier:    extractUserTierFromContext(ctx),
 	}
 
-	return s.textProvider.GenerateText(req)
+	return s.textProvider.GenerateText(ctx, req)
 }
 
 func (s *StrategyService) GenerateCrisisManagementPlan(ctx context.Context, organization string, potentialCrises []string) (*types.TextResponse, error) {
@@ -287,7 +287,7 @@ Make it comprehensive and ready-to-use.`, organization, crisesStr)
 		UserTier:    extractUserTierFromContext(ctx),
 	}
 
-	return s.textProvider.GenerateText(req)
+	return s.textProvider.GenerateText(ctx, req)
 }
 
 func (s *StrategyService) GenerateDigitalTransformationStrategy(ctx context.Context, company string, currentState string, goals []string) (*types.TextResponse, error) {
@@ -320,7 +320,7 @@ Make it practical with clear milestones.`, company, currentState, goalsStr)
 		UserTier:    extractUserTierFromContext(ctx),
 	}
 
-	return s.textProvider.GenerateText(req)
+	return s.textProvider.GenerateText(ctx, req)
 }
 
 func (s *StrategyService) GenerateOKRs(ctx context.Context, department string, timeframe string, companyGoals []string) (*types.TextResponse, error) {
@@ -363,7 +363,7 @@ Make the OKRs SMART (Specific, Measurable, Achievable, Relevant, Time-bound).`,
 		UserTier:    extractUserTierFromContext(ctx),
 	}
 
-	return s.textProvider.GenerateText(req)
+	return s.textProvider.GenerateText(ctx, req)
 }
 
 func (s *StrategyService) GetServiceStats(ctx context.Context) map[string]interface{} {
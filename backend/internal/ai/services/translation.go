@@ -27,7 +27,7 @@ func (s *TranslationService) Translate(ctx context.Context, text string, sourceL
 		UserTier: extractUserTierFromContext(ctx),
 	}
 
-	return s.textProvider.TranslateText(req)
+	return s.textProvider.TranslateText(ctx, req)
 }
 
 func (s *TranslationService) BatchTranslate(ctx context.Context, texts []string, sourceLang string, targetLang string) ([]*types.TranslationResponse, error) {
@@ -46,7 +46,7 @@ func (s *TranslationService) BatchTranslate(ctx context.Context, texts []string,
 			UserTier: extractUserTierFromContext(ctx),
 		}
 
-		resp, err := s.textProvider.TranslateText(req)
+		resp, err := s.textProvider.TranslateText(ctx, req)
 		if err != nil {
 			return responses, fmt.Errorf("failed to translate text '%s': %w", text, err)
 		}
@@ -78,7 +78,7 @@ func (s *TranslationService) DetectLanguage(ctx context.Context, text string) (s
 	}
 
 	// نستخدم GenerateText لأن TranslateText يتطلب لغة المصدر
-	resp, err := s.textProvider.GenerateText(textReq)
+	resp, err := s.textProvider.GenerateText(ctx, textReq)
 	if err != nil {
 		return "", 0.0, fmt.Errorf("failed to detect language: %w", err)
 	}
@@ -112,7 +112,7 @@ func (s *TranslationService) TranslateDocument(ctx context.Context, document str
 			UserTier: extractUserTierFromContext(ctx),
 		}
 
-		resp, err := s.textProvider.TranslateText(req)
+		resp, err := s.textProvider.TranslateText(ctx, req)
 		if err != nil {
 			return nil, err
 		}
@@ -151,7 +151,7 @@ func (s *TranslationService) translateMarkdown(ctx context.Context, markdown str
 					UserTier: extractUserTierFromContext(ctx),
 				}
 
-				if resp, err := s.textProvider.TranslateText(req); err == nil {
+				if resp, err := s.textProvider.TranslateText(ctx, req); err == nil {
 					translatedLine := applyTranslationToMarkdown(line, content, resp.TranslatedText)
 					translatedLines = append(translatedLines, translatedLine)
 					continue
@@ -168,7 +168,7 @@ func (s *TranslationService) translateMarkdown(ctx context.Context, markdown str
 			UserTier: extractUserTierFromContext(ctx),
 		}
 
-		if resp, err := s.textProvider.TranslateText(req); err == nil {
+		if resp, err := s.textProvider.TranslateText(ctx, req); err == nil {
 			translatedLines = append(translatedLines, resp.TranslatedText)
 		} else {
 			translatedLines = append(translatedLines, line)
@@ -216,7 +216,7 @@ func (s *TranslationService) translateHTML(ctx context.Context, html string, sou
 		UserTier: extractUserTierFromContext(ctx),
 	}
 
-	resp, err := s.textProvider.TranslateText(req)
+	resp, err := s.textProvider.TranslateText(ctx, req)
 	if err != nil {
 		return html
 	}
@@ -235,7 +235,7 @@ func (s *TranslationService) translateJSON(ctx context.Context, jsonStr string,
 		UserTier: extractUserTierFromContext(ctx),
 	}
 
-	resp, err := s.textProvider.TranslateText(req)
+	resp, err := s.textProvider.TranslateText(ctx, req)
 	if err != nil {
 		return jsonStr
 	}
@@ -269,7 +269,7 @@ func (s *TranslationService) TranslateWithGlossary(ctx context.Context, text str
 		UserTier: extractUserTierFromContext(ctx),
 	}
 
-	resp, err := s.textProvider.TranslateText(req)
+	resp, err := s.textProvider.TranslateText(ctx, req)
 	if err != nil {
 		return nil, err
 	}
@@ -303,7 +303,7 @@ Return a score from 0-100.`, sourceLang, targetLang, original, translated)
 		UserTier:  extractUserTierFromContext(ctx),
 	}
 
-	resp, err := s.textProvider.GenerateText(textReq)
+	resp, err := s.textProvider.GenerateText(ctx, textReq)
 	if err != nil {
 		return 0.0, fmt.Errorf("failed to evaluate translation quality: %w", err)
 	}
@@ -0,0 +1,116 @@
+package ai
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/nawthtech/nawthtech/backend/internal/ai/types"
+)
+
+// ================================
+// توليد النص المتدفق
+// ================================
+
+// maxStreamLine أطول سطر بث يُقبل من المزود
+const maxStreamLine = 1 << 20
+
+// streamUsage ما يعلنه المزود عن البث عند انتهائه؛ الرموز الناقصة تُقدَّر بـ meterText
+type streamUsage struct {
+	model            string
+	finishReason     string
+	promptTokens     int
+	completionTokens int
+}
+
+// openStream إرسال طلب البث وإعادة جسمه المفتوح، أو خطأ مُنمّط إن رفضه المزود
+func openStream(ctx context.Context, client *http.Client, req *http.Request, provider string) (io.ReadCloser, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, types.RequestError(ctx, provider, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return nil, types.HTTPError(provider, resp.StatusCode, string(body))
+	}
+	return resp.Body, nil
+}
+
+// textStream تشغيل read في goroutine وتحويل ما يبثه إلى أجزاء. read يمرر كل نص جديد
+// إلى emit ويملأ usage بما يعرفه، ثم يُرسل جزء Done بالنص المجمّع ورموزه وتكلفته،
+// فيُحتسب التوليد الجزئي أيضًا عند الخطأ أو إلغاء السياق
+func textStream(ctx context.Context, provider, model, prompt string,
+	read func(emit func(string) error, usage *streamUsage) error) <-chan types.StreamChunk {
+	out := make(chan types.StreamChunk)
+
+	go func() {
+		defer close(out)
+
+		var text strings.Builder
+		usage := streamUsage{model: model}
+		emit := func(delta string) error {
+			if delta == "" {
+				return nil
+			}
+			text.WriteString(delta)
+			select {
+			case out <- types.StreamChunk{Delta: delta}:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		err := read(emit, &usage)
+		if err != nil {
+			err = types.ContextError(ctx, provider, err)
+		}
+		finishReason := usage.finishReason
+		switch {
+		case err != nil && types.IsCanceled(err):
+			finishReason = "canceled"
+		case err != nil:
+			finishReason = "error"
+		case finishReason == "":
+			finishReason = "stop"
+		}
+
+		response := &types.TextResponse{
+			Text:             text.String(),
+			PromptTokens:     usage.promptTokens,
+			CompletionTokens: usage.completionTokens,
+			ModelUsed:        usage.model,
+			FinishReason:     finishReason,
+			CreatedAt:        time.Now(),
+		}
+		meterText(model, prompt, response)
+		out <- types.StreamChunk{Done: true, Response: response, Err: err}
+	}()
+
+	return out
+}
+
+// scanSSE قراءة أحداث Server-Sent Events وتمرير حقل data لكل حدث؛ [DONE] يُتجاهل
+func scanSSE(body io.Reader, handle func(data []byte) error) error {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxStreamLine)
+	for scanner.Scan() {
+		data, ok := bytes.CutPrefix(scanner.Bytes(), []byte("data:"))
+		if !ok {
+			continue
+		}
+		data = bytes.TrimSpace(data)
+		if len(data) == 0 || string(data) == "[DONE]" {
+			continue
+		}
+		if err := handle(data); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
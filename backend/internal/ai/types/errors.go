@@ -1,6 +1,11 @@
 package types
 
-import "fmt"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+)
 
 // أخطاء مخصصة
 var (
@@ -11,6 +16,7 @@ var (
 	ErrRateLimitExceeded   = &ProviderError{Code: "RATE_LIMIT_EXCEEDED", Message: "Rate limit exceeded"}
 	ErrInsufficientQuota   = &ProviderError{Code: "INSUFFICIENT_QUOTA", Message: "Insufficient quota"}
 	ErrRequestTimeout      = &ProviderError{Code: "REQUEST_TIMEOUT", Message: "Request timeout"}
+	ErrRequestCanceled     = &ProviderError{Code: "REQUEST_CANCELED", Message: "Request was canceled"}
 	ErrInvalidRequest      = &ProviderError{Code: "INVALID_REQUEST", Message: "Invalid request parameters"}
 	ErrServiceUnavailable  = &ProviderError{Code: "SERVICE_UNAVAILABLE", Message: "Service temporarily unavailable"}
 	ErrInternalServerError = &ProviderError{Code: "INTERNAL_SERVER_ERROR", Message: "Internal server error"}
@@ -18,6 +24,7 @@ var (
 	ErrImageNotSupported   = &ProviderError{Code: "IMAGE_NOT_SUPPORTED", Message: "Image generation not supported"}
 	ErrTextNotSupported    = &ProviderError{Code: "TEXT_NOT_SUPPORTED", Message: "Text generation not supported"}
 	ErrModelNotSupported   = &ProviderError{Code: "MODEL_NOT_SUPPORTED", Message: "Model not supported by this provider"}
+	ErrStreamNotSupported  = &ProviderError{Code: "STREAM_NOT_SUPPORTED", Message: "Streaming not supported by this provider"}
 	ErrContentFiltered     = &ProviderError{Code: "CONTENT_FILTERED", Message: "Content was filtered"}
 )
 
@@ -28,6 +35,8 @@ type ProviderError struct {
 	Provider   string `json:"provider,omitempty"`
 	Details    string `json:"details,omitempty"`
 	StatusCode int    `json:"status_code,omitempty"`
+	// Err السبب الأصلي (مثل context.Canceled) لـ errors.Is و errors.As
+	Err error `json:"-"`
 }
 
 func (e *ProviderError) Error() string {
@@ -37,6 +46,116 @@ func (e *ProviderError) Error() string {
 	return fmt.Sprintf("%s: %s", e.Code, e.Message)
 }
 
+// Unwrap السبب الأصلي
+func (e *ProviderError) Unwrap() error {
+	return e.Err
+}
+
+// Is مطابقة بالرمز، حتى تطابق نسخة تحمل اسم المزود الخطأ العام المقابل
+func (e *ProviderError) Is(target error) bool {
+	t, ok := target.(*ProviderError)
+	return ok && t.Code == e.Code
+}
+
+// WithProvider نسخة من الخطأ تحمل اسم المزود
+func (e *ProviderError) WithProvider(provider string) *ProviderError {
+	clone := *e
+	clone.Provider = provider
+	return &clone
+}
+
+// ContextError تحويل أخطاء الإلغاء وانتهاء المهلة إلى ErrRequestCanceled أو
+// ErrRequestTimeout مع اسم المزود؛ الأخطاء الأخرى تُعاد كما هي
+func ContextError(ctx context.Context, provider string, err error) error {
+	if err == nil || errors.Is(err, ErrRequestCanceled) || errors.Is(err, ErrRequestTimeout) {
+		return err
+	}
+	cause := contextCause(ctx, err)
+	if cause == nil {
+		return err
+	}
+
+	base := ErrRequestTimeout
+	if errors.Is(cause, context.Canceled) {
+		base = ErrRequestCanceled
+	}
+	return &ProviderError{Code: base.Code, Message: base.Message, Provider: provider, Details: err.Error(), Err: cause}
+}
+
+// RequestError خطأ إرسال الطلب إلى المزود: الإلغاء وانتهاء المهلة كما في
+// ContextError، وغيرهما (اتصال مرفوض، DNS...) ErrProviderUnavailable
+func RequestError(ctx context.Context, provider string, err error) error {
+	if err == nil {
+		return nil
+	}
+	if contextCause(ctx, err) != nil {
+		return ContextError(ctx, provider, err)
+	}
+	unavailable := ErrProviderUnavailable.WithProvider(provider)
+	unavailable.Details, unavailable.Err = err.Error(), err
+	return unavailable
+}
+
+// HTTPError خطأ مزود من رد HTTP غير ناجح، برمز مشتق من الحالة
+func HTTPError(provider string, status int, body string) error {
+	base := ErrInternalServerError
+	switch {
+	case status == 429:
+		base = ErrRateLimitExceeded
+	case status == 401 || status == 403:
+		base = ErrInvalidAPIKey
+	case status == 402:
+		base = ErrInsufficientQuota
+	case status == 404:
+		base = ErrModelNotSupported
+	case status == 408 || status == 504:
+		base = ErrRequestTimeout
+	case status == 502 || status == 503:
+		base = ErrServiceUnavailable
+	case status >= 400 && status < 500:
+		base = ErrInvalidRequest
+	}
+	err := base.WithProvider(provider)
+	err.Details, err.StatusCode = body, status
+	return err
+}
+
+// contextCause سبب الخطأ إن كان إلغاءً أو انتهاء مهلة، وإلا nil
+func contextCause(ctx context.Context, err error) error {
+	if cause := ctx.Err(); cause != nil {
+		return cause
+	}
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.Canceled):
+		return context.Canceled
+	case errors.Is(err, context.DeadlineExceeded):
+		return context.DeadlineExceeded
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return context.DeadlineExceeded
+	}
+	return nil
+}
+
+// IsCanceled هل فشل الطلب لإلغاء سياقه أو انتهاء مهلته (لا لمهلة من المزود نفسه)
+func IsCanceled(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// IsRetryable هل يُجرَّب مزود آخر بعد هذا الخطأ: أخطاء المزود المُنمّطة كلها
+// عدا الطلب غير الصالح والمحتوى المحجوب والإلغاء؛ الأخطاء غير المُنمّطة لا تُعاد
+func IsRetryable(err error) bool {
+	var perr *ProviderError
+	if !errors.As(err, &perr) {
+		return false
+	}
+	switch perr.Code {
+	case ErrInvalidRequest.Code, ErrContentFiltered.Code, ErrRequestCanceled.Code:
+		return false
+	}
+	return true
+}
+
 // ValidationError خطأ في التحقق
 type ValidationError struct {
 	Field   string `json:"field"`
@@ -68,6 +187,7 @@ var ErrorMap = map[string]*ProviderError{
 	"RATE_LIMIT_EXCEEDED":   ErrRateLimitExceeded,
 	"INSUFFICIENT_QUOTA":    ErrInsufficientQuota,
 	"REQUEST_TIMEOUT":       ErrRequestTimeout,
+	"REQUEST_CANCELED":      ErrRequestCanceled,
 	"INVALID_REQUEST":       ErrInvalidRequest,
 	"SERVICE_UNAVAILABLE":   ErrServiceUnavailable,
 	"INTERNAL_SERVER_ERROR": ErrInternalServerError,
@@ -75,5 +195,6 @@ var ErrorMap = map[string]*ProviderError{
 	"IMAGE_NOT_SUPPORTED":   ErrImageNotSupported,
 	"TEXT_NOT_SUPPORTED":    ErrTextNotSupported,
 	"MODEL_NOT_SUPPORTED":   ErrModelNotSupported,
+	"STREAM_NOT_SUPPORTED":  ErrStreamNotSupported,
 	"CONTENT_FILTERED":      ErrContentFiltered,
 }
@@ -0,0 +1,109 @@
+package types
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+// ================================
+// عدّ الرموز محليًا
+// ================================
+
+// TokenizerFamily معاملات تقدير الرموز لعائلة نماذج: متوسط الأحرف لكل رمز بحسب نوع الكتابة.
+// تُستخدم عندما لا يعيد المزود عدد الرموز الفعلي
+type TokenizerFamily struct {
+	Name string
+	// LatinCharsPerToken للحروف اللاتينية والأرقام
+	LatinCharsPerToken float64
+	// ArabicCharsPerToken للحروف العربية، وهي أغلى في معظم المفردات
+	ArabicCharsPerToken float64
+	// CJKCharsPerToken للصينية واليابانية والكورية
+	CJKCharsPerToken float64
+	// OtherCharsPerToken لبقية أنظمة الكتابة
+	OtherCharsPerToken float64
+}
+
+// عائلات المُرمِّزات المعروفة، مقاسة على نصوص نموذجية بمُرمِّز كل عائلة
+var (
+	TokenizerGemini  = TokenizerFamily{Name: "gemini", LatinCharsPerToken: 4.2, ArabicCharsPerToken: 3.0, CJKCharsPerToken: 1.4, OtherCharsPerToken: 2.5}
+	TokenizerLlama   = TokenizerFamily{Name: "llama", LatinCharsPerToken: 4.0, ArabicCharsPerToken: 2.2, CJKCharsPerToken: 1.1, OtherCharsPerToken: 2.0}
+	TokenizerMistral = TokenizerFamily{Name: "mistral", LatinCharsPerToken: 3.6, ArabicCharsPerToken: 1.6, CJKCharsPerToken: 0.9, OtherCharsPerToken: 1.6}
+	TokenizerQwen    = TokenizerFamily{Name: "qwen", LatinCharsPerToken: 4.0, ArabicCharsPerToken: 2.4, CJKCharsPerToken: 1.5, OtherCharsPerToken: 2.0}
+	TokenizerPhi     = TokenizerFamily{Name: "phi", LatinCharsPerToken: 3.8, ArabicCharsPerToken: 1.5, CJKCharsPerToken: 0.9, OtherCharsPerToken: 1.5}
+	TokenizerDefault = TokenizerFamily{Name: "default", LatinCharsPerToken: 4.0, ArabicCharsPerToken: 2.0, CJKCharsPerToken: 1.0, OtherCharsPerToken: 2.0}
+)
+
+// TokenizerFor عائلة المُرمِّز من اسم النموذج (مثل gemini-2.0-flash أو llama3.2:3b)
+func TokenizerFor(model string) TokenizerFamily {
+	model = strings.ToLower(model)
+	switch {
+	case strings.Contains(model, "gemini"), strings.Contains(model, "gemma"):
+		return TokenizerGemini
+	case strings.Contains(model, "llama"):
+		return TokenizerLlama
+	case strings.Contains(model, "mistral"), strings.Contains(model, "mixtral"):
+		return TokenizerMistral
+	case strings.Contains(model, "qwen"):
+		return TokenizerQwen
+	case strings.Contains(model, "phi"):
+		return TokenizerPhi
+	default:
+		return TokenizerDefault
+	}
+}
+
+// CountTokens تقدير عدد رموز النص بمُرمِّز عائلة النموذج. كل كلمة (تتابع حروف من
+// نظام كتابة واحد) تُقدَّر على حدة ولا تقل عن رمز، وكل علامة ترقيم رمز مستقل
+func CountTokens(model, text string) int {
+	return TokenizerFor(model).Count(text)
+}
+
+// Count تقدير عدد رموز النص بهذه العائلة
+func (f TokenizerFamily) Count(text string) int {
+	tokens := 0
+	runLength, runRate := 0, 0.0
+
+	flush := func() {
+		if runLength > 0 {
+			tokens += int(math.Max(1, math.Ceil(float64(runLength)/runRate)))
+		}
+		runLength = 0
+	}
+
+	for _, r := range text {
+		switch {
+		case unicode.IsSpace(r):
+			// المسافة تندمج عادة مع الكلمة التالية
+			flush()
+		case unicode.IsLetter(r) || unicode.IsNumber(r) || unicode.Is(unicode.Mn, r):
+			rate := f.rateFor(r)
+			if runLength > 0 && rate != runRate && !unicode.Is(unicode.Mn, r) {
+				flush()
+			}
+			if runLength == 0 {
+				runRate = rate
+			}
+			runLength++
+		default:
+			flush()
+			tokens++
+		}
+	}
+	flush()
+	return tokens
+}
+
+// rateFor متوسط الأحرف لكل رمز لنظام كتابة الحرف
+func (f TokenizerFamily) rateFor(r rune) float64 {
+	switch {
+	case r < unicode.MaxASCII || unicode.Is(unicode.Latin, r):
+		return f.LatinCharsPerToken
+	case unicode.Is(unicode.Arabic, r):
+		return f.ArabicCharsPerToken
+	case unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul):
+		return f.CJKCharsPerToken
+	default:
+		return f.OtherCharsPerToken
+	}
+}
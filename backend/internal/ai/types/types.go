@@ -1,18 +1,22 @@
 package types
 
-import "time"
+import (
+	"context"
+	"time"
+)
 
 // ============ الواجهات الأساسية ============
 
-// ProviderInterface واجهة أساسية لجميع مزودي AI
+// ProviderInterface واجهة أساسية لجميع مزودي AI. العمليات تتوقف عند إلغاء
+// السياق أو انتهاء مهلته وتعيد ErrRequestCanceled أو ErrRequestTimeout
 type ProviderInterface interface {
 	// العمليات الأساسية
-	GenerateText(req TextRequest) (*TextResponse, error)
-	GenerateImage(req ImageRequest) (*ImageResponse, error)
-	GenerateVideo(req VideoRequest) (*VideoResponse, error)
-	AnalyzeText(req AnalysisRequest) (*AnalysisResponse, error)
-	AnalyzeImage(req AnalysisRequest) (*AnalysisResponse, error)
-	TranslateText(req TranslationRequest) (*TranslationResponse, error)
+	GenerateText(ctx context.Context, req TextRequest) (*TextResponse, error)
+	GenerateImage(ctx context.Context, req ImageRequest) (*ImageResponse, error)
+	GenerateVideo(ctx context.Context, req VideoRequest) (*VideoResponse, error)
+	AnalyzeText(ctx context.Context, req AnalysisRequest) (*AnalysisResponse, error)
+	AnalyzeImage(ctx context.Context, req AnalysisRequest) (*AnalysisResponse, error)
+	TranslateText(ctx context.Context, req TranslationRequest) (*TranslationResponse, error)
 
 	// معلومات المزود
 	GetName() string
@@ -28,23 +32,39 @@ type ProviderInterface interface {
 
 // ============ الواجهات المتخصصة ============
 
+// HealthChecker مزود يوفر فحص صحة رخيصًا لا يستهلك رصيد التوليد
+type HealthChecker interface {
+	HealthCheck(ctx context.Context) error
+}
+
+// StreamingProvider مزود يبث توليد النص أجزاءً. خطأ فتح البث يُعاد مباشرة؛ بعد ذلك
+// تنتهي القناة دائمًا بجزء Done واحد ثم تُغلق، وعلى المستهلك قراءتها حتى الإغلاق
+type StreamingProvider interface {
+	GenerateTextStream(ctx context.Context, req TextRequest) (<-chan StreamChunk, error)
+}
+
+// Embedder مزود يحول النص إلى متجه تضمين
+type Embedder interface {
+	Embed(ctx context.Context, text string, model string) ([]float64, error)
+}
+
 type TextProvider interface {
-	GenerateText(req TextRequest) (*TextResponse, error)
-	AnalyzeText(req AnalysisRequest) (*AnalysisResponse, error)
-	TranslateText(req TranslationRequest) (*TranslationResponse, error)
+	GenerateText(ctx context.Context, req TextRequest) (*TextResponse, error)
+	AnalyzeText(ctx context.Context, req AnalysisRequest) (*AnalysisResponse, error)
+	TranslateText(ctx context.Context, req TranslationRequest) (*TranslationResponse, error)
 	GetName() string
 	SupportsStreaming() bool
 	GetMaxTokens() int
 }
 
 type ImageProvider interface {
-	GenerateImage(req ImageRequest) (*ImageResponse, error)
-	AnalyzeImage(req AnalysisRequest) (*AnalysisResponse, error)
+	GenerateImage(ctx context.Context, req ImageRequest) (*ImageResponse, error)
+	AnalyzeImage(ctx context.Context, req AnalysisRequest) (*AnalysisResponse, error)
 	GetName() string
 }
 
 type VideoProvider interface {
-	GenerateVideo(req VideoRequest) (*VideoResponse, error)
+	GenerateVideo(ctx context.Context, req VideoRequest) (*VideoResponse, error)
 	GetName() string
 }
 
@@ -80,26 +100,34 @@ type CacheManagerInterface interface {
 	GetStats() CacheStats
 }
 
-// AIClientInterface واجهة عميل AI
+// AIClientInterface واجهة عميل AI. دوال Context والدوال ذات الخيارات تمرر
+// السياق إلى المزود؛ الدوال المختصرة بدونه تستخدم context.Background()
 type AIClientInterface interface {
 	// العمليات الأساسية
 	GenerateText(prompt, provider string) (string, error)
 	GenerateImage(prompt, provider string) (string, error)
 	GenerateVideo(prompt, provider string) (string, error)
+	GenerateTextContext(ctx context.Context, prompt, provider string) (string, error)
+	GenerateImageContext(ctx context.Context, prompt, provider string) (string, error)
+	GenerateVideoContext(ctx context.Context, prompt, provider string) (string, error)
 
 	// العمليات المتقدمة
-	GenerateTextWithOptions(req TextRequest) (*TextResponse, error)
-	GenerateImageWithOptions(req ImageRequest) (*ImageResponse, error)
-	GenerateVideoWithOptions(req VideoRequest) (*VideoResponse, error)
+	GenerateTextWithOptions(ctx context.Context, req TextRequest) (*TextResponse, error)
+	GenerateImageWithOptions(ctx context.Context, req ImageRequest) (*ImageResponse, error)
+	GenerateVideoWithOptions(ctx context.Context, req VideoRequest) (*VideoResponse, error)
 	AnalyzeText(text, provider string) (*AnalysisResponse, error)
-	AnalyzeTextWithOptions(req AnalysisRequest) (*AnalysisResponse, error)
+	AnalyzeTextContext(ctx context.Context, text, provider string) (*AnalysisResponse, error)
+	AnalyzeTextWithOptions(ctx context.Context, req AnalysisRequest) (*AnalysisResponse, error)
 	TranslateText(text, fromLang, toLang, provider string) (string, error)
-	TranslateTextWithOptions(req TranslationRequest) (*TranslationResponse, error)
+	TranslateTextContext(ctx context.Context, text, fromLang, toLang, provider string) (string, error)
+	TranslateTextWithOptions(ctx context.Context, req TranslationRequest) (*TranslationResponse, error)
 	AnalyzeImage(imageData []byte, prompt, provider string) (*AnalysisResponse, error)
-	AnalyzeImageWithOptions(req AnalysisRequest) (*AnalysisResponse, error)
+	AnalyzeImageContext(ctx context.Context, imageData []byte, prompt, provider string) (*AnalysisResponse, error)
+	AnalyzeImageWithOptions(ctx context.Context, req AnalysisRequest) (*AnalysisResponse, error)
 
 	// معلومات النظام
 	GetVideoStatus(operationID string) (*VideoResponse, error)
+	GetVideoStatusContext(ctx context.Context, operationID string) (*VideoResponse, error)
 	GetAvailableProviders() map[string][]string
 	IsProviderAvailable(providerType, providerName string) bool
 	GetProviderStats(providerName string) (*ProviderStats, error)
@@ -129,18 +157,53 @@ type TextRequest struct {
 	User             string                 `json:"user,omitempty"`
 	UserID           string                 `json:"user_id,omitempty"`
 	UserTier         string                 `json:"user_tier,omitempty"`
+	OrgID            string                 `json:"org_id,omitempty"`
 	Metadata         map[string]interface{} `json:"metadata,omitempty"`
 }
 
 // TextResponse استجابة توليد نص
 type TextResponse struct {
-	Text         string    `json:"text"`
-	Tokens       int       `json:"tokens"`
-	Cost         float64   `json:"cost"`
-	ModelUsed    string    `json:"model_used"`
-	FinishReason string    `json:"finish_reason,omitempty"`
-	CreatedAt    time.Time `json:"created_at"`
-	RawResponse  string    `json:"raw_response,omitempty"`
+	Text             string    `json:"text"`
+	Tokens           int       `json:"tokens"`                      // إجمالي رموز المدخلات والمخرجات
+	PromptTokens     int       `json:"prompt_tokens,omitempty"`     // كما أعادها المزود أو مقدّرة محليًا
+	CompletionTokens int       `json:"completion_tokens,omitempty"` // كما أعادها المزود أو مقدّرة محليًا
+	TokensEstimated  bool      `json:"tokens_estimated,omitempty"`  // لم يُعِد المزود أعداد الرموز
+	Cost             float64   `json:"cost"`
+	ModelUsed        string    `json:"model_used"`
+	FinishReason     string    `json:"finish_reason,omitempty"`
+	CreatedAt        time.Time `json:"created_at"`
+	RawResponse      string    `json:"raw_response,omitempty"`
+	// Metadata بيانات إضافية مثل سلسلة المزودين التي جُرّبت
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// StreamChunk جزء من توليد نص متدفق: Delta للنص الجديد، أو الجزء الأخير Done الذي
+// يحمل في Response ما وُلّد كاملًا مع رموزه وتكلفته حتى لو توقف البث بخطأ أو إلغاء
+type StreamChunk struct {
+	Delta    string        `json:"delta,omitempty"`
+	Done     bool          `json:"done,omitempty"`
+	Response *TextResponse `json:"response,omitempty"`
+	Err      error         `json:"-"`
+}
+
+// EmbeddingRequest طلب تضمين نصوص
+type EmbeddingRequest struct {
+	Input    []string               `json:"input"`
+	Model    string                 `json:"model,omitempty"`
+	UserID   string                 `json:"user_id,omitempty"`
+	UserTier string                 `json:"user_tier,omitempty"`
+	OrgID    string                 `json:"org_id,omitempty"`
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// EmbeddingResponse متجهات التضمين بترتيب المدخلات
+type EmbeddingResponse struct {
+	Embeddings [][]float64            `json:"embeddings"`
+	Tokens     int                    `json:"tokens"` // رموز المدخلات مقدّرة محليًا
+	Cost       float64                `json:"cost"`
+	ModelUsed  string                 `json:"model_used"`
+	CreatedAt  time.Time              `json:"created_at"`
+	Metadata   map[string]interface{} `json:"metadata,omitempty"`
 }
 
 // ImageRequest طلب توليد صورة
@@ -154,21 +217,23 @@ type ImageRequest struct {
 	User           string                 `json:"user,omitempty"`
 	UserID         string                 `json:"user_id,omitempty"`
 	UserTier       string                 `json:"user_tier,omitempty"`
+	OrgID          string                 `json:"org_id,omitempty"`
 	Metadata       map[string]interface{} `json:"metadata,omitempty"`
 }
 
 // ImageResponse استجابة توليد صورة
 type ImageResponse struct {
-	URL       string    `json:"url,omitempty"`
-	ImageData []byte    `json:"image_data,omitempty"`
-	Size      string    `json:"size"`
-	Format    string    `json:"format"`
-	Cost      float64   `json:"cost"`
-	ModelUsed string    `json:"model_used"`
-	CreatedAt time.Time `json:"created_at"`
-	Seed      int64     `json:"seed,omitempty"`
-	Width     int       `json:"width,omitempty"`
-	Height    int       `json:"height,omitempty"`
+	URL       string                 `json:"url,omitempty"`
+	ImageData []byte                 `json:"image_data,omitempty"`
+	Size      string                 `json:"size"`
+	Format    string                 `json:"format"`
+	Cost      float64                `json:"cost"`
+	ModelUsed string                 `json:"model_used"`
+	CreatedAt time.Time              `json:"created_at"`
+	Seed      int64                  `json:"seed,omitempty"`
+	Width     int                    `json:"width,omitempty"`
+	Height    int                    `json:"height,omitempty"`
+	Metadata  map[string]interface{} `json:"metadata,omitempty"`
 }
 
 // VideoRequest طلب توليد فيديو
@@ -181,6 +246,7 @@ type VideoRequest struct {
 	User       string                 `json:"user,omitempty"`
 	UserID     string                 `json:"user_id,omitempty"`
 	UserTier   string                 `json:"user_tier,omitempty"`
+	OrgID      string                 `json:"org_id,omitempty"`
 	Metadata   map[string]interface{} `json:"metadata,omitempty"`
 }
 
@@ -209,19 +275,21 @@ type AnalysisRequest struct {
 	User      string                 `json:"user,omitempty"`
 	UserID    string                 `json:"user_id,omitempty"`
 	UserTier  string                 `json:"user_tier,omitempty"`
+	OrgID     string                 `json:"org_id,omitempty"`
 	Metadata  map[string]interface{} `json:"metadata,omitempty"`
 }
 
 // AnalysisResponse استجابة تحليل
 type AnalysisResponse struct {
-	Result         string    `json:"result"`
-	Confidence     float64   `json:"confidence,omitempty"`
-	Cost           float64   `json:"cost"`
-	Model          string    `json:"model"`
-	CreatedAt      time.Time `json:"created_at"`
-	Categories     []string  `json:"categories,omitempty"`
-	Sentiment      string    `json:"sentiment,omitempty"`
-	SentimentScore float64   `json:"sentiment_score,omitempty"`
+	Result         string                 `json:"result"`
+	Confidence     float64                `json:"confidence,omitempty"`
+	Cost           float64                `json:"cost"`
+	Model          string                 `json:"model"`
+	CreatedAt      time.Time              `json:"created_at"`
+	Categories     []string               `json:"categories,omitempty"`
+	Sentiment      string                 `json:"sentiment,omitempty"`
+	SentimentScore float64                `json:"sentiment_score,omitempty"`
+	Metadata       map[string]interface{} `json:"metadata,omitempty"`
 }
 
 // TranslationRequest طلب ترجمة
@@ -233,17 +301,19 @@ type TranslationRequest struct {
 	User     string                 `json:"user,omitempty"`
 	UserID   string                 `json:"user_id,omitempty"`
 	UserTier string                 `json:"user_tier,omitempty"`
+	OrgID    string                 `json:"org_id,omitempty"`
 	Metadata map[string]interface{} `json:"metadata,omitempty"`
 }
 
 // TranslationResponse استجابة ترجمة
 type TranslationResponse struct {
-	TranslatedText         string    `json:"translated_text"`
-	Cost                   float64   `json:"cost"`
-	Model                  string    `json:"model"`
-	CreatedAt              time.Time `json:"created_at"`
-	Accuracy               float64   `json:"accuracy,omitempty"`
-	DetectedSourceLanguage string    `json:"detected_source_language,omitempty"`
+	TranslatedText         string                 `json:"translated_text"`
+	Cost                   float64                `json:"cost"`
+	Model                  string                 `json:"model"`
+	CreatedAt              time.Time              `json:"created_at"`
+	Accuracy               float64                `json:"accuracy,omitempty"`
+	DetectedSourceLanguage string                 `json:"detected_source_language,omitempty"`
+	Metadata               map[string]interface{} `json:"metadata,omitempty"`
 }
 
 // ============ الإحصائيات والتتبع ============
@@ -269,6 +339,7 @@ type UsageRecord struct {
 	ID        string                 `json:"id"`
 	UserID    string                 `json:"user_id"`
 	UserTier  string                 `json:"user_tier"`
+	OrgID     string                 `json:"org_id,omitempty"`
 	Provider  string                 `json:"provider"`
 	Type      string                 `json:"type"` // text, image, video, etc.
 	Cost      float64                `json:"cost"`
@@ -278,6 +349,16 @@ type UsageRecord struct {
 	Timestamp time.Time              `json:"timestamp"`
 	Metadata  map[string]interface{} `json:"metadata,omitempty"`
 	Error     string                 `json:"error,omitempty"`
+	CacheHit  bool                   `json:"cache_hit,omitempty"`  // خُدم من التخزين المؤقت دون استدعاء المزود
+	SavedCost float64                `json:"saved_cost,omitempty"` // تكلفة الاستدعاء الذي تم تجنبه
+}
+
+// ProviderAttempt محاولة واحدة ضمن سلسلة التحويل التلقائي بين المزودين
+type ProviderAttempt struct {
+	Provider string  `json:"provider"`
+	Success  bool    `json:"success"`
+	Error    string  `json:"error,omitempty"`
+	Latency  float64 `json:"latency"` // in milliseconds
 }
 
 // UsageStats إحصائيات الاستخدام
@@ -18,10 +18,9 @@ func FormatCost(cost float64) string {
 	return fmt.Sprintf("$%.4f", cost)
 }
 
-// CalculateTokens حساب عدد الرموز تقريبياً
+// CalculateTokens تقدير عدد الرموز بالمُرمِّز الافتراضي (انظر CountTokens لنموذج بعينه)
 func CalculateTokens(text string) int {
-	// تقدير تقريبي: 1 رمز = 4 أحرف للغة الإنجليزية
-	return len(text) / 4
+	return CountTokens("", text)
 }
 
 // ParseSize تحليل حجم الصورة
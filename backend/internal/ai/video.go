@@ -1,6 +1,7 @@
 package ai
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/nawthtech/nawthtech/backend/internal/ai/types"
@@ -70,23 +71,23 @@ func NewVideoProvider(apiType string) (*VideoProvider, error) {
 }
 
 // GenerateVideo توليد فيديو باستخدام API المختار
-func (p *VideoProvider) GenerateVideo(req types.VideoRequest) (*types.VideoResponse, error) {
+func (p *VideoProvider) GenerateVideo(ctx context.Context, req types.VideoRequest) (*types.VideoResponse, error) {
 	switch p.apiType {
 	case "gemini":
-		return p.generateWithGemini(req)
+		return p.generateWithGemini(ctx, req)
 	case "luma":
-		return p.generateWithLuma(req)
+		return p.generateWithLuma(ctx, req)
 	case "runway":
-		return p.generateWithRunway(req)
+		return p.generateWithRunway(ctx, req)
 	case "pika":
-		return p.generateWithPika(req)
+		return p.generateWithPika(ctx, req)
 	default:
 		return nil, fmt.Errorf("unsupported API type: %s", p.apiType)
 	}
 }
 
 // generateWithGemini توليد فيديو باستخدام Gemini Veo
-func (p *VideoProvider) generateWithGemini(req types.VideoRequest) (*types.VideoResponse, error) {
+func (p *VideoProvider) generateWithGemini(ctx context.Context, req types.VideoRequest) (*types.VideoResponse, error) {
 	url := fmt.Sprintf("%s/models/veo-2.0-generate-001:generateVideo?key=%s", p.baseURL, p.apiKey)
 
 	requestBody := map[string]interface{}{
@@ -104,15 +105,21 @@ func (p *VideoProvider) generateWithGemini(req types.VideoRequest) (*types.Video
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	resp, err := p.httpClient.Post(url, "application/json", strings.NewReader(string(jsonData)))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, strings.NewReader(string(jsonData)))
 	if err != nil {
-		return nil, fmt.Errorf("Gemini API request failed: %w", err)
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, types.RequestError(ctx, p.GetName(), fmt.Errorf("Gemini API request failed: %w", err))
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("Gemini API error: %s - %s", resp.Status, string(body))
+		return nil, types.HTTPError(p.GetName(), resp.StatusCode, string(body))
 	}
 
 	var result struct {
@@ -138,7 +145,7 @@ func (p *VideoProvider) generateWithGemini(req types.VideoRequest) (*types.Video
 }
 
 // generateWithLuma توليد فيديو باستخدام Luma AI
-func (p *VideoProvider) generateWithLuma(req types.VideoRequest) (*types.VideoResponse, error) {
+func (p *VideoProvider) generateWithLuma(ctx context.Context, req types.VideoRequest) (*types.VideoResponse, error) {
 	url := p.baseURL + "/generations"
 
 	requestBody := map[string]interface{}{
@@ -154,7 +161,7 @@ func (p *VideoProvider) generateWithLuma(req types.VideoRequest) (*types.VideoRe
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	httpReq, err := http.NewRequest("POST", url, strings.NewReader(string(jsonData)))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, strings.NewReader(string(jsonData)))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -164,13 +171,13 @@ func (p *VideoProvider) generateWithLuma(req types.VideoRequest) (*types.VideoRe
 
 	resp, err := p.httpClient.Do(httpReq)
 	if err != nil {
-		return nil, fmt.Errorf("Luma API request failed: %w", err)
+		return nil, types.RequestError(ctx, p.GetName(), fmt.Errorf("Luma API request failed: %w", err))
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("Luma API error: %s - %s", resp.Status, string(body))
+		return nil, types.HTTPError(p.GetName(), resp.StatusCode, string(body))
 	}
 
 	var result struct {
@@ -196,7 +203,7 @@ func (p *VideoProvider) generateWithLuma(req types.VideoRequest) (*types.VideoRe
 }
 
 // generateWithRunway توليد فيديو باستخدام Runway ML
-func (p *VideoProvider) generateWithRunway(req types.VideoRequest) (*types.VideoResponse, error) {
+func (p *VideoProvider) generateWithRunway(ctx context.Context, req types.VideoRequest) (*types.VideoResponse, error) {
 	url := p.baseURL + "/generations"
 
 	requestBody := map[string]interface{}{
@@ -209,7 +216,7 @@ func (p *VideoProvider) generateWithRunway(req types.VideoRequest) (*types.Video
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	httpReq, err := http.NewRequest("POST", url, strings.NewReader(string(jsonData)))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, strings.NewReader(string(jsonData)))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -219,13 +226,13 @@ func (p *VideoProvider) generateWithRunway(req types.VideoRequest) (*types.Video
 
 	resp, err := p.httpClient.Do(httpReq)
 	if err != nil {
-		return nil, fmt.Errorf("Runway API request failed: %w", err)
+		return nil, types.RequestError(ctx, p.GetName(), fmt.Errorf("Runway API request failed: %w", err))
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("Runway API error: %s - %s", resp.Status, string(body))
+		return nil, types.HTTPError(p.GetName(), resp.StatusCode, string(body))
 	}
 
 	var result struct {
@@ -253,7 +260,7 @@ func (p *VideoProvider) generateWithRunway(req types.VideoRequest) (*types.Video
 }
 
 // generateWithPika توليد فيديو باستخدام Pika Labs
-func (p *VideoProvider) generateWithPika(req types.VideoRequest) (*types.VideoResponse, error) {
+func (p *VideoProvider) generateWithPika(ctx context.Context, req types.VideoRequest) (*types.VideoResponse, error) {
 	url := p.baseURL + "/generate"
 
 	requestBody := map[string]interface{}{
@@ -266,7 +273,7 @@ func (p *VideoProvider) generateWithPika(req types.VideoRequest) (*types.VideoRe
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	httpReq, err := http.NewRequest("POST", url, strings.NewReader(string(jsonData)))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, strings.NewReader(string(jsonData)))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -276,13 +283,13 @@ func (p *VideoProvider) generateWithPika(req types.VideoRequest) (*types.VideoRe
 
 	resp, err := p.httpClient.Do(httpReq)
 	if err != nil {
-		return nil, fmt.Errorf("Pika API request failed: %w", err)
+		return nil, types.RequestError(ctx, p.GetName(), fmt.Errorf("Pika API request failed: %w", err))
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("Pika API error: %s - %s", resp.Status, string(body))
+		return nil, types.HTTPError(p.GetName(), resp.StatusCode, string(body))
 	}
 
 	var result struct {
@@ -310,43 +317,48 @@ func (p *VideoProvider) generateWithPika(req types.VideoRequest) (*types.VideoRe
 }
 
 // AnalyzeImage تحليل صورة - غير مدعوم في معظم مزودي الفيديو
-func (p *VideoProvider) AnalyzeImage(req types.AnalysisRequest) (*types.AnalysisResponse, error) {
-	return nil, fmt.Errorf("image analysis not supported by video provider %s", p.apiType)
+func (p *VideoProvider) AnalyzeImage(ctx context.Context, req types.AnalysisRequest) (*types.AnalysisResponse, error) {
+	return nil, types.ErrImageNotSupported.WithProvider(p.GetName())
 }
 
 // AnalyzeText تحليل نص - غير مدعوم في معظم مزودي الفيديو
-func (p *VideoProvider) AnalyzeText(req types.AnalysisRequest) (*types.AnalysisResponse, error) {
-	return nil, fmt.Errorf("text analysis not supported by video provider %s", p.apiType)
+func (p *VideoProvider) AnalyzeText(ctx context.Context, req types.AnalysisRequest) (*types.AnalysisResponse, error) {
+	return nil, types.ErrTextNotSupported.WithProvider(p.GetName())
 }
 
 // TranslateText ترجمة نص - غير مدعوم في معظم مزودي الفيديو
-func (p *VideoProvider) TranslateText(req types.TranslationRequest) (*types.TranslationResponse, error) {
-	return nil, fmt.Errorf("text translation not supported by video provider %s", p.apiType)
+func (p *VideoProvider) TranslateText(ctx context.Context, req types.TranslationRequest) (*types.TranslationResponse, error) {
+	return nil, types.ErrTextNotSupported.WithProvider(p.GetName())
 }
 
 // GetVideoStatus الحصول على حالة فيديو
-func (p *VideoProvider) GetVideoStatus(operationID string) (*types.VideoResponse, error) {
+func (p *VideoProvider) GetVideoStatus(ctx context.Context, operationID string) (*types.VideoResponse, error) {
 	switch p.apiType {
 	case "gemini":
-		return p.getGeminiStatus(operationID)
+		return p.getGeminiStatus(ctx, operationID)
 	case "luma":
-		return p.getLumaStatus(operationID)
+		return p.getLumaStatus(ctx, operationID)
 	case "runway":
-		return p.getRunwayStatus(operationID)
+		return p.getRunwayStatus(ctx, operationID)
 	case "pika":
-		return p.getPikaStatus(operationID)
+		return p.getPikaStatus(ctx, operationID)
 	default:
 		return nil, fmt.Errorf("unsupported API type: %s", p.apiType)
 	}
 }
 
 // getGeminiStatus الحصول على حالة فيديو Gemini
-func (p *VideoProvider) getGeminiStatus(operationID string) (*types.VideoResponse, error) {
+func (p *VideoProvider) getGeminiStatus(ctx context.Context, operationID string) (*types.VideoResponse, error) {
 	url := fmt.Sprintf("%s/operations/%s?key=%s", p.baseURL, operationID, p.apiKey)
 
-	resp, err := p.httpClient.Get(url)
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := p.httpClient.Do(httpReq)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get Gemini status: %w", err)
+		return nil, types.RequestError(ctx, p.GetName(), fmt.Errorf("failed to get Gemini status: %w", err))
 	}
 	defer resp.Body.Close()
 
@@ -383,10 +395,10 @@ func (p *VideoProvider) getGeminiStatus(operationID string) (*types.VideoRespons
 }
 
 // getLumaStatus الحصول على حالة فيديو Luma
-func (p *VideoProvider) getLumaStatus(operationID string) (*types.VideoResponse, error) {
+func (p *VideoProvider) getLumaStatus(ctx context.Context, operationID string) (*types.VideoResponse, error) {
 	url := fmt.Sprintf("%s/generations/%s", p.baseURL, operationID)
 
-	httpReq, err := http.NewRequest("GET", url, nil)
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -395,7 +407,7 @@ func (p *VideoProvider) getLumaStatus(operationID string) (*types.VideoResponse,
 
 	resp, err := p.httpClient.Do(httpReq)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get Luma status: %w", err)
+		return nil, types.RequestError(ctx, p.GetName(), fmt.Errorf("failed to get Luma status: %w", err))
 	}
 	defer resp.Body.Close()
 
@@ -417,13 +429,13 @@ func (p *VideoProvider) getLumaStatus(operationID string) (*types.VideoResponse,
 }
 
 // GenerateText توليد نص - غير مدعوم في مزود الفيديو
-func (p *VideoProvider) GenerateText(req types.TextRequest) (*types.TextResponse, error) {
-	return nil, fmt.Errorf("text generation not supported by video provider %s", p.apiType)
+func (p *VideoProvider) GenerateText(ctx context.Context, req types.TextRequest) (*types.TextResponse, error) {
+	return nil, types.ErrTextNotSupported.WithProvider(p.GetName())
 }
 
 // GenerateImage توليد صورة - غير مدعوم في مزود الفيديو
-func (p *VideoProvider) GenerateImage(req types.ImageRequest) (*types.ImageResponse, error) {
-	return nil, fmt.Errorf("image generation not supported by video provider %s", p.apiType)
+func (p *VideoProvider) GenerateImage(ctx context.Context, req types.ImageRequest) (*types.ImageResponse, error) {
+	return nil, types.ErrImageNotSupported.WithProvider(p.GetName())
 }
 
 // IsAvailable التحقق من التوفر
@@ -492,10 +504,10 @@ func (p *VideoProvider) GetStats() *types.ProviderStats {
 }
 
 // getRunwayStatus الحصول على حالة فيديو Runway
-func (p *VideoProvider) getRunwayStatus(operationID string) (*types.VideoResponse, error) {
+func (p *VideoProvider) getRunwayStatus(ctx context.Context, operationID string) (*types.VideoResponse, error) {
 	url := fmt.Sprintf("%s/generations/%s", p.baseURL, operationID)
 
-	httpReq, err := http.NewRequest("GET", url, nil)
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -504,7 +516,7 @@ func (p *VideoProvider) getRunwayStatus(operationID string) (*types.VideoRespons
 
 	resp, err := p.httpClient.Do(httpReq)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get Runway status: %w", err)
+		return nil, types.RequestError(ctx, p.GetName(), fmt.Errorf("failed to get Runway status: %w", err))
 	}
 	defer resp.Body.Close()
 
@@ -528,10 +540,10 @@ func (p *VideoProvider) getRunwayStatus(operationID string) (*types.VideoRespons
 }
 
 // getPikaStatus الحصول على حالة فيديو Pika
-func (p *VideoProvider) getPikaStatus(operationID string) (*types.VideoResponse, error) {
+func (p *VideoProvider) getPikaStatus(ctx context.Context, operationID string) (*types.VideoResponse, error) {
 	url := fmt.Sprintf("%s/generations/%s", p.baseURL, operationID)
 
-	httpReq, err := http.NewRequest("GET", url, nil)
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -540,7 +552,7 @@ func (p *VideoProvider) getPikaStatus(operationID string) (*types.VideoResponse,
 
 	resp, err := p.httpClient.Do(httpReq)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get Pika status: %w", err)
+		return nil, types.RequestError(ctx, p.GetName(), fmt.Errorf("failed to get Pika status: %w", err))
 	}
 	defer resp.Body.Close()
 
@@ -17,6 +17,9 @@ func NewCostManager() *CostManager {
 	}
 }
 
+// CanGenerateVideo التحقق من حصة الفيديو حسب الطبقة.
+//
+// Deprecated: الحصص تُفرض على مسارات الفيديو بـ middleware.AIQuota.
 func (c *CostManager) CanGenerateVideo(userID, tier string) bool {
 	quota, exists := c.userQuotas[tier]
 	if !exists {
@@ -406,7 +406,9 @@ func (s *VideoService) GetUserUsage(userID, tier string) *VideoUsage {
 	}
 }
 
-// CanUserGenerateVideo التحقق من إمكانية المستخدم لتوليد فيديو
+// CanUserGenerateVideo التحقق من إمكانية المستخدم لتوليد فيديو.
+//
+// Deprecated: الحصص تُفرض على مسارات الفيديو بـ middleware.AIQuota.
 func (s *VideoService) CanUserGenerateVideo(userID, tier string) (bool, string) {
 	usage := s.GetUserUsage(userID, tier)
 	return usage.CanGenerateVideo()
@@ -0,0 +1,126 @@
+// Package cache يوفر التخزين المؤقت بواجهة موحدة: ذاكرة محلية بحد أقصى (LRU) ومدة صلاحية،
+// أو Redis مشترك بين النسخ، مع إحصائيات الإصابة والإخفاق والطرد
+package cache
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/nawthtech/nawthtech/backend/internal/config"
+)
+
+// ================================
+// الأنواع والأخطاء
+// ================================
+
+const (
+	BackendMemory = "memory"
+	BackendRedis  = "redis"
+)
+
+// DefaultMaxEntries الحد الافتراضي لعدد المفاتيح في الذاكرة المحلية
+const DefaultMaxEntries = 10000
+
+// ErrMiss المفتاح غير موجود أو انتهت صلاحيته
+var ErrMiss = errors.New("key not found")
+
+// Cache واجهة التخزين المؤقت (تطابق services.CacheService)
+type Cache interface {
+	Get(key string) (interface{}, error)
+	Set(key string, value interface{}, expiration time.Duration) error
+	Delete(key string) error
+	Exists(key string) (bool, error)
+	Flush() error
+	Stats() Stats
+}
+
+// Stats إحصائيات التخزين المؤقت
+type Stats struct {
+	Backend     string  `json:"backend"`
+	Hits        int64   `json:"hits"`
+	Misses      int64   `json:"misses"`
+	Evictions   int64   `json:"evictions"`
+	Expirations int64   `json:"expirations"`
+	Keys        int64   `json:"keys"`
+	MaxEntries  int     `json:"max_entries,omitempty"`
+	HitRate     float64 `json:"hit_rate"`
+}
+
+// computeHitRate نسبة الإصابة من إجمالي القراءات
+func (s *Stats) computeHitRate() {
+	if total := s.Hits + s.Misses; total > 0 {
+		s.HitRate = float64(s.Hits) / float64(total)
+	}
+}
+
+// ================================
+// الإنشاء من الإعدادات
+// ================================
+
+// New إنشاء التخزين المؤقت حسب config.Cache.Type (memory أو redis)
+func New(cfg *config.Config) (Cache, error) {
+	if cfg == nil {
+		return NewMemory(DefaultMaxEntries, 0), nil
+	}
+
+	switch cfg.Cache.Type {
+	case BackendRedis:
+		if cfg.Cache.Redis == "" {
+			return nil, errors.New("redis cache configured but REDIS_URL is empty")
+		}
+		return NewRedis(cfg.Cache.Redis, RedisOptions{DefaultTTL: cfg.Cache.TTL})
+	case BackendMemory, "":
+		return NewMemory(cfg.Cache.MaxEntries, cfg.Cache.TTL), nil
+	default:
+		return nil, fmt.Errorf("unsupported cache type: %s", cfg.Cache.Type)
+	}
+}
+
+// ================================
+// دوال مساعدة
+// ================================
+
+// GetInto قراءة قيمة مخزنة إلى dst (مؤشر)، بغض النظر عن الـ backend:
+// الذاكرة تعيد القيمة الأصلية، و Redis يعيد JSON خاماً
+func GetInto(c Cache, key string, dst interface{}) error {
+	value, err := c.Get(key)
+	if err != nil {
+		return err
+	}
+	return decodeInto(value, dst)
+}
+
+// decodeInto نسخ القيمة مباشرة إن توافق النوع، وإلا عبر JSON
+func decodeInto(value, dst interface{}) error {
+	target := reflect.ValueOf(dst)
+	if target.Kind() != reflect.Ptr || target.IsNil() {
+		return errors.New("cache: destination must be a non-nil pointer")
+	}
+
+	switch raw := value.(type) {
+	case json.RawMessage:
+		return json.Unmarshal(raw, dst)
+	case nil:
+		target.Elem().Set(reflect.Zero(target.Elem().Type()))
+		return nil
+	}
+
+	v := reflect.ValueOf(value)
+	if v.Type().AssignableTo(target.Elem().Type()) {
+		target.Elem().Set(v)
+		return nil
+	}
+	if v.Kind() == reflect.Ptr && !v.IsNil() && v.Elem().Type().AssignableTo(target.Elem().Type()) {
+		target.Elem().Set(v.Elem())
+		return nil
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("cache: failed to encode value: %w", err)
+	}
+	return json.Unmarshal(data, dst)
+}
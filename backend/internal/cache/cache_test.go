@@ -0,0 +1,238 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+type cachedUser struct {
+	ID    string   `json:"id"`
+	Roles []string `json:"roles"`
+}
+
+// fakeClock ساعة يمكن تقديمها يدوياً لاختبار الانتهاء دون انتظار
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) Now() time.Time          { return c.t }
+func (c *fakeClock) Advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func TestMemoryTTL(t *testing.T) {
+	clock := &fakeClock{t: time.Now()}
+	m := NewMemory(10, time.Minute)
+	m.now = clock.Now
+
+	m.Set("short", "v", 10*time.Second)
+	m.Set("default", "v", 0)
+
+	clock.Advance(30 * time.Second)
+	if _, err := m.Get("short"); !errors.Is(err, ErrMiss) {
+		t.Errorf("expected short key to expire, got %v", err)
+	}
+	if _, err := m.Get("default"); err != nil {
+		t.Errorf("expected default TTL key to live, got %v", err)
+	}
+
+	clock.Advance(time.Minute)
+	if ok, _ := m.Exists("default"); ok {
+		t.Error("expected default TTL key to expire")
+	}
+
+	stats := m.Stats()
+	if stats.Hits != 1 || stats.Misses != 1 || stats.Expirations != 2 || stats.Keys != 0 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestMemoryLRUEviction(t *testing.T) {
+	m := NewMemory(3, 0)
+	for i := 0; i < 3; i++ {
+		m.Set(fmt.Sprintf("k%d", i), i, 0)
+	}
+
+	// k0 يصبح الأحدث استخداماً، فيطرد k1 عند الإضافة
+	if _, err := m.Get("k0"); err != nil {
+		t.Fatalf("Get k0: %v", err)
+	}
+	m.Set("k3", 3, 0)
+
+	if _, err := m.Get("k1"); !errors.Is(err, ErrMiss) {
+		t.Error("expected k1 to be evicted")
+	}
+	for _, key := range []string{"k0", "k2", "k3"} {
+		if ok, _ := m.Exists(key); !ok {
+			t.Errorf("expected %s to remain", key)
+		}
+	}
+
+	stats := m.Stats()
+	if stats.Evictions != 1 || stats.Keys != 3 || stats.MaxEntries != 3 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestMemoryEvictsExpiredBeforeLive(t *testing.T) {
+	clock := &fakeClock{t: time.Now()}
+	m := NewMemory(2, 0)
+	m.now = clock.Now
+
+	m.Set("old", 1, time.Second)
+	m.Set("live", 2, 0)
+	clock.Advance(2 * time.Second)
+
+	if removed := m.DeleteExpired(); removed != 1 {
+		t.Errorf("expected 1 expired key removed, got %d", removed)
+	}
+	m.Set("new", 3, 0)
+	if ok, _ := m.Exists("live"); !ok {
+		t.Error("live key should not be evicted while there is room")
+	}
+	if stats := m.Stats(); stats.Evictions != 0 {
+		t.Errorf("expected no evictions, got %+v", stats)
+	}
+}
+
+func TestRedisAgainstStandIn(t *testing.T) {
+	mr := miniredis.RunT(t)
+
+	r, err := NewRedis("redis://"+mr.Addr()+"/0", RedisOptions{DefaultTTL: time.Minute})
+	if err != nil {
+		t.Fatalf("NewRedis: %v", err)
+	}
+	defer r.Close()
+
+	user := cachedUser{ID: "u1", Roles: []string{"admin"}}
+	if err := r.Set("user:u1", user, 10*time.Second); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if !mr.Exists(DefaultRedisPrefix + "user:u1") {
+		t.Fatal("expected key to be stored under prefix")
+	}
+
+	var got cachedUser
+	if err := GetInto(r, "user:u1", &got); err != nil {
+		t.Fatalf("GetInto: %v", err)
+	}
+	if got.ID != "u1" || len(got.Roles) != 1 {
+		t.Errorf("unexpected value: %+v", got)
+	}
+
+	// المدة الافتراضية تطبق عند تمرير صفر
+	r.Set("default", "v", 0)
+	if ttl := mr.TTL(DefaultRedisPrefix + "default"); ttl != time.Minute {
+		t.Errorf("expected default TTL, got %v", ttl)
+	}
+
+	mr.FastForward(11 * time.Second)
+	if _, err := r.Get("user:u1"); !errors.Is(err, ErrMiss) {
+		t.Errorf("expected expired key to miss, got %v", err)
+	}
+
+	// Flush لا يمس المفاتيح خارج البادئة
+	mr.Set("other:key", "x")
+	if err := r.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	if ok, _ := r.Exists("default"); ok {
+		t.Error("expected prefixed keys to be flushed")
+	}
+	if !mr.Exists("other:key") {
+		t.Error("flush removed a key outside the prefix")
+	}
+
+	stats := r.Stats()
+	if stats.Backend != BackendRedis || stats.Hits != 1 || stats.Misses != 1 || stats.HitRate != 0.5 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestGetIntoMemory(t *testing.T) {
+	m := NewMemory(0, 0)
+	m.Set("ptr", &cachedUser{ID: "u2"}, 0)
+	m.Set("map", map[string]interface{}{"id": "u3"}, 0)
+
+	var u cachedUser
+	if err := GetInto(m, "ptr", &u); err != nil || u.ID != "u2" {
+		t.Errorf("pointer value: %+v, %v", u, err)
+	}
+	if err := GetInto(m, "map", &u); err != nil || u.ID != "u3" {
+		t.Errorf("converted value: %+v, %v", u, err)
+	}
+	if err := GetInto(m, "missing", &u); !errors.Is(err, ErrMiss) {
+		t.Errorf("expected miss, got %v", err)
+	}
+}
+
+func TestReadThroughSingleFlight(t *testing.T) {
+	rt := NewReadThrough(NewMemory(0, 0), time.Minute)
+
+	var loads atomic.Int32
+	release := make(chan struct{})
+	load := func(ctx context.Context) (interface{}, error) {
+		loads.Add(1)
+		<-release
+		return cachedUser{ID: "u1"}, nil
+	}
+
+	var wg sync.WaitGroup
+	results := make([]cachedUser, 10)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if err := rt.Fetch(context.Background(), "user:u1", nil, &results[i], load); err != nil {
+				t.Errorf("Fetch: %v", err)
+			}
+		}(i)
+	}
+	// مهلة قصيرة حتى تنضم كل الطلبات للتحميل الجاري
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if n := loads.Load(); n != 1 {
+		t.Errorf("expected a single load, got %d", n)
+	}
+	for i, u := range results {
+		if u.ID != "u1" {
+			t.Errorf("result %d: unexpected value %+v", i, u)
+		}
+	}
+}
+
+func TestReadThroughTagInvalidation(t *testing.T) {
+	rt := NewReadThrough(NewMemory(0, 0), time.Minute)
+	ctx := context.Background()
+
+	version := 0
+	load := func(ctx context.Context) (interface{}, error) {
+		version++
+		return version, nil
+	}
+
+	var got int
+	rt.Fetch(ctx, "list", []string{"services"}, &got, load)
+	rt.Fetch(ctx, "other", []string{"categories"}, &got, load)
+	if err := rt.Fetch(ctx, "list", []string{"services"}, &got, load); err != nil || got != 1 {
+		t.Fatalf("expected cached value 1, got %d (%v)", got, err)
+	}
+
+	if err := rt.Invalidate("services"); err != nil {
+		t.Fatalf("Invalidate: %v", err)
+	}
+	if ok, _ := rt.Lookup("list", &got); ok {
+		t.Error("expected invalidated entry to miss")
+	}
+	if ok, _ := rt.Lookup("other", &got); !ok || got != 2 {
+		t.Errorf("entry with another tag should survive, got %d", got)
+	}
+	if err := rt.Fetch(ctx, "list", []string{"services"}, &got, load); err != nil || got != 3 {
+		t.Errorf("expected reload after invalidation, got %d (%v)", got, err)
+	}
+}
@@ -0,0 +1,202 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// ================================
+// التخزين المؤقت في الذاكرة (LRU + TTL)
+// ================================
+
+// Memory تخزين مؤقت محلي بحد أقصى للمفاتيح: الأقدم استخداماً يُطرد أولاً،
+// والمفاتيح المنتهية تُحذف عند الوصول إليها أو عند الحاجة لمساحة
+type Memory struct {
+	mu         sync.Mutex
+	items      map[string]*list.Element
+	order      *list.List // الأحدث استخداماً في المقدمة
+	maxEntries int
+	defaultTTL time.Duration
+	now        func() time.Time
+
+	hits        int64
+	misses      int64
+	evictions   int64
+	expirations int64
+}
+
+// memoryEntry عنصر مخزن مع وقت انتهائه (صفر = بلا انتهاء)
+type memoryEntry struct {
+	key       string
+	value     interface{}
+	expiresAt time.Time
+}
+
+// NewMemory إنشاء تخزين مؤقت محلي. maxEntries <= 0 يعني DefaultMaxEntries،
+// و defaultTTL يطبق عند Set بمدة صفرية (صفر = بلا انتهاء)
+func NewMemory(maxEntries int, defaultTTL time.Duration) *Memory {
+	if maxEntries <= 0 {
+		maxEntries = DefaultMaxEntries
+	}
+	return &Memory{
+		items:      make(map[string]*list.Element),
+		order:      list.New(),
+		maxEntries: maxEntries,
+		defaultTTL: defaultTTL,
+		now:        time.Now,
+	}
+}
+
+// Get قراءة قيمة وتحديث ترتيب استخدامها
+func (m *Memory) Get(key string) (interface{}, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	elem, ok := m.items[key]
+	if !ok {
+		m.misses++
+		return nil, ErrMiss
+	}
+
+	entry := elem.Value.(*memoryEntry)
+	if m.expired(entry) {
+		m.removeElement(elem)
+		m.expirations++
+		m.misses++
+		return nil, ErrMiss
+	}
+
+	m.order.MoveToFront(elem)
+	m.hits++
+	return entry.value, nil
+}
+
+// Set تخزين قيمة بمدة صلاحية، مع طرد الأقدم استخداماً عند تجاوز الحد
+func (m *Memory) Set(key string, value interface{}, expiration time.Duration) error {
+	if key == "" {
+		return nil
+	}
+	if expiration <= 0 {
+		expiration = m.defaultTTL
+	}
+
+	var expiresAt time.Time
+	if expiration > 0 {
+		expiresAt = m.now().Add(expiration)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if elem, ok := m.items[key]; ok {
+		entry := elem.Value.(*memoryEntry)
+		entry.value = value
+		entry.expiresAt = expiresAt
+		m.order.MoveToFront(elem)
+		return nil
+	}
+
+	m.items[key] = m.order.PushFront(&memoryEntry{key: key, value: value, expiresAt: expiresAt})
+	for len(m.items) > m.maxEntries {
+		m.evictOldest()
+	}
+	return nil
+}
+
+// Delete حذف مفتاح
+func (m *Memory) Delete(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if elem, ok := m.items[key]; ok {
+		m.removeElement(elem)
+	}
+	return nil
+}
+
+// Exists هل المفتاح موجود وصالح (دون احتسابه إصابة أو تحديث ترتيبه)
+func (m *Memory) Exists(key string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	elem, ok := m.items[key]
+	if !ok {
+		return false, nil
+	}
+	if m.expired(elem.Value.(*memoryEntry)) {
+		m.removeElement(elem)
+		m.expirations++
+		return false, nil
+	}
+	return true, nil
+}
+
+// Flush حذف كل المفاتيح (الإحصائيات تبقى)
+func (m *Memory) Flush() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.items = make(map[string]*list.Element)
+	m.order.Init()
+	return nil
+}
+
+// DeleteExpired حذف كل المفاتيح المنتهية، ويعيد عددها
+func (m *Memory) DeleteExpired() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	removed := 0
+	for elem := m.order.Back(); elem != nil; {
+		prev := elem.Prev()
+		if m.expired(elem.Value.(*memoryEntry)) {
+			m.removeElement(elem)
+			m.expirations++
+			removed++
+		}
+		elem = prev
+	}
+	return removed
+}
+
+// Stats إحصائيات الاستخدام
+func (m *Memory) Stats() Stats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stats := Stats{
+		Backend:     BackendMemory,
+		Hits:        m.hits,
+		Misses:      m.misses,
+		Evictions:   m.evictions,
+		Expirations: m.expirations,
+		Keys:        int64(len(m.items)),
+		MaxEntries:  m.maxEntries,
+	}
+	stats.computeHitRate()
+	return stats
+}
+
+// evictOldest طرد مفتاح منتهٍ إن وجد في آخر القائمة، وإلا الأقدم استخداماً
+func (m *Memory) evictOldest() {
+	oldest := m.order.Back()
+	if oldest == nil {
+		return
+	}
+	if m.expired(oldest.Value.(*memoryEntry)) {
+		m.expirations++
+	} else {
+		m.evictions++
+	}
+	m.removeElement(oldest)
+}
+
+func (m *Memory) expired(entry *memoryEntry) bool {
+	return !entry.expiresAt.IsZero() && !m.now().Before(entry.expiresAt)
+}
+
+func (m *Memory) removeElement(elem *list.Element) {
+	m.order.Remove(elem)
+	delete(m.items, elem.Value.(*memoryEntry).key)
+}
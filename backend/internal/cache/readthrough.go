@@ -0,0 +1,143 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// ================================
+// القراءة عبر التخزين المؤقت مع الوسوم
+// ================================
+
+// tagKeyPrefix بادئة مفاتيح إصدارات الوسوم
+const tagKeyPrefix = "tag:"
+
+// Versions إصدارات الوسوم وقت قراءة البيانات من المصدر
+type Versions map[string]int64
+
+// entry القيمة المخزنة مع إصدارات وسومها؛ تُرمَّز JSON دائماً حتى لا يعدّل
+// المستدعون نسخة مشتركة في الذاكرة
+type entry struct {
+	Versions Versions        `json:"v,omitempty"`
+	Value    json.RawMessage `json:"d"`
+}
+
+// ReadThrough طبقة قراءة عبر التخزين المؤقت: الإبطال يتم برفع إصدار الوسم،
+// فتصبح كل القيم المخزنة بإصدار أقدم منتهية دون الحاجة لتتبع مفاتيحها.
+// الطلبات المتزامنة لنفس المفتاح تنتظر تحميلاً واحداً (single-flight)
+type ReadThrough struct {
+	store Cache
+	ttl   time.Duration
+	group singleflight.Group
+}
+
+// NewReadThrough إنشاء طبقة قراءة فوق تخزين مؤقت بمدة صلاحية افتراضية
+func NewReadThrough(store Cache, ttl time.Duration) *ReadThrough {
+	return &ReadThrough{store: store, ttl: ttl}
+}
+
+// Fetch قراءة المفتاح إلى dst، أو تحميله مرة واحدة عبر load وتخزينه مع وسومه
+func (r *ReadThrough) Fetch(ctx context.Context, key string, tags []string, dst interface{}, load func(context.Context) (interface{}, error)) error {
+	if ok, err := r.Lookup(key, dst); err == nil && ok {
+		return nil
+	}
+
+	data, err, _ := r.group.Do(key, func() (interface{}, error) {
+		// الإصدارات تُقرأ قبل التحميل: إبطال أثناء التحميل يجعل النتيجة منتهية فوراً
+		versions := r.TagVersions(tags...)
+
+		// المستدعي الأول قد يلغي طلبه، والبقية ينتظرون نفس التحميل
+		value, err := load(context.WithoutCancel(ctx))
+		if err != nil {
+			return nil, err
+		}
+
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return nil, fmt.Errorf("cache: failed to encode value: %w", err)
+		}
+		_ = r.store.Set(key, entry{Versions: versions, Value: raw}, r.ttl)
+		return raw, nil
+	})
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data.([]byte), dst)
+}
+
+// Lookup قراءة قيمة مخزنة إذا كانت وسومها ما زالت صالحة
+func (r *ReadThrough) Lookup(key string, dst interface{}) (bool, error) {
+	var e entry
+	if err := GetInto(r.store, key, &e); err != nil {
+		if errors.Is(err, ErrMiss) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	for tag, version := range e.Versions {
+		if r.tagVersion(tag) != version {
+			_ = r.store.Delete(key)
+			return false, nil
+		}
+	}
+
+	if err := json.Unmarshal(e.Value, dst); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Store تخزين قيمة بإصدارات وسوم قُرئت قبل إنتاجها (انظر TagVersions)
+func (r *ReadThrough) Store(key string, versions Versions, value interface{}, ttl time.Duration) error {
+	if ttl <= 0 {
+		ttl = r.ttl
+	}
+
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("cache: failed to encode value: %w", err)
+	}
+	return r.store.Set(key, entry{Versions: versions, Value: raw}, ttl)
+}
+
+// TagVersions الإصدارات الحالية للوسوم
+func (r *ReadThrough) TagVersions(tags ...string) Versions {
+	if len(tags) == 0 {
+		return nil
+	}
+	versions := make(Versions, len(tags))
+	for _, tag := range tags {
+		versions[tag] = r.tagVersion(tag)
+	}
+	return versions
+}
+
+// Invalidate رفع إصدار الوسوم فتنتهي كل القيم المرتبطة بها
+func (r *ReadThrough) Invalidate(tags ...string) error {
+	var errs []error
+	for _, tag := range tags {
+		if err := r.store.Set(tagKeyPrefix+tag, time.Now().UnixNano(), 0); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// tagVersion إصدار الوسم؛ الوسم المفقود (أو المطرود) يُنشأ بإصدار جديد،
+// فلا تعود قيم قديمة صالحة بعد فقدانه
+func (r *ReadThrough) tagVersion(tag string) int64 {
+	var version int64
+	if err := GetInto(r.store, tagKeyPrefix+tag, &version); err == nil {
+		return version
+	}
+
+	version = time.Now().UnixNano()
+	_ = r.store.Set(tagKeyPrefix+tag, version, 0)
+	return version
+}
@@ -0,0 +1,209 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ================================
+// التخزين المؤقت عبر Redis
+// ================================
+
+// DefaultRedisPrefix بادئة المفاتيح لعزلها عن بقية بيانات Redis
+const DefaultRedisPrefix = "nawthtech:cache:"
+
+// redisTimeout مهلة كل عملية على Redis
+const redisTimeout = 2 * time.Second
+
+// RedisOptions إعدادات عميل Redis
+type RedisOptions struct {
+	// Prefix بادئة المفاتيح (الافتراضي DefaultRedisPrefix)
+	Prefix string
+	// DefaultTTL المدة عند Set بمدة صفرية (صفر = بلا انتهاء)
+	DefaultTTL time.Duration
+}
+
+// Redis تخزين مؤقت مشترك. القيم تخزن بصيغة JSON، و Get يعيد json.RawMessage
+// (استخدم GetInto للحصول على النوع الأصلي)
+type Redis struct {
+	client     *redis.Client
+	prefix     string
+	defaultTTL time.Duration
+
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+// NewRedis الاتصال بـ Redis عبر رابط بصيغة redis://[:password@]host:port/db
+func NewRedis(url string, opts RedisOptions) (*Redis, error) {
+	redisOpts, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redis url: %w", err)
+	}
+	if opts.Prefix == "" {
+		opts.Prefix = DefaultRedisPrefix
+	}
+
+	return &Redis{
+		client:     redis.NewClient(redisOpts),
+		prefix:     opts.Prefix,
+		defaultTTL: opts.DefaultTTL,
+	}, nil
+}
+
+// Get قراءة قيمة كـ JSON خام
+func (r *Redis) Get(key string) (interface{}, error) {
+	if key == "" {
+		r.misses.Add(1)
+		return nil, ErrMiss
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	data, err := r.client.Get(ctx, r.prefix+key).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			r.misses.Add(1)
+			return nil, ErrMiss
+		}
+		return nil, fmt.Errorf("redis get failed: %w", err)
+	}
+
+	r.hits.Add(1)
+	return json.RawMessage(data), nil
+}
+
+// Set تخزين القيمة بصيغة JSON مع مدة الصلاحية
+func (r *Redis) Set(key string, value interface{}, expiration time.Duration) error {
+	if key == "" {
+		return nil
+	}
+	if expiration <= 0 {
+		expiration = r.defaultTTL
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to encode cache value: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	if err := r.client.Set(ctx, r.prefix+key, data, expiration).Err(); err != nil {
+		return fmt.Errorf("redis set failed: %w", err)
+	}
+	return nil
+}
+
+// Delete حذف مفتاح
+func (r *Redis) Delete(key string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	if err := r.client.Del(ctx, r.prefix+key).Err(); err != nil {
+		return fmt.Errorf("redis delete failed: %w", err)
+	}
+	return nil
+}
+
+// Exists هل المفتاح موجود
+func (r *Redis) Exists(key string) (bool, error) {
+	if key == "" {
+		return false, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	n, err := r.client.Exists(ctx, r.prefix+key).Result()
+	if err != nil {
+		return false, fmt.Errorf("redis exists failed: %w", err)
+	}
+	return n > 0, nil
+}
+
+// Flush حذف مفاتيح هذا التخزين فقط (حسب البادئة) دون المساس ببقية قاعدة Redis
+func (r *Redis) Flush() error {
+	ctx := context.Background()
+	iter := r.client.Scan(ctx, 0, r.prefix+"*", 500).Iterator()
+
+	batch := make([]string, 0, 500)
+	for iter.Next(ctx) {
+		batch = append(batch, iter.Val())
+		if len(batch) == cap(batch) {
+			if err := r.client.Del(ctx, batch...).Err(); err != nil {
+				return fmt.Errorf("redis flush failed: %w", err)
+			}
+			batch = batch[:0]
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return fmt.Errorf("redis flush failed: %w", err)
+	}
+	if len(batch) > 0 {
+		if err := r.client.Del(ctx, batch...).Err(); err != nil {
+			return fmt.Errorf("redis flush failed: %w", err)
+		}
+	}
+	return nil
+}
+
+// Ping التحقق من الاتصال بـ Redis
+func (r *Redis) Ping(ctx context.Context) error {
+	return r.client.Ping(ctx).Err()
+}
+
+// Close إغلاق الاتصالات
+func (r *Redis) Close() error {
+	return r.client.Close()
+}
+
+// Stats الإصابة والإخفاق من هذا العميل، والطرد والانتهاء وعدد المفاتيح من الخادم
+func (r *Redis) Stats() Stats {
+	stats := Stats{
+		Backend: BackendRedis,
+		Hits:    r.hits.Load(),
+		Misses:  r.misses.Load(),
+	}
+	stats.computeHitRate()
+
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	if info, err := r.client.Info(ctx, "stats").Result(); err == nil {
+		fields := parseRedisInfo(info)
+		stats.Evictions, _ = strconv.ParseInt(fields["evicted_keys"], 10, 64)
+		stats.Expirations, _ = strconv.ParseInt(fields["expired_keys"], 10, 64)
+	}
+	if keys, err := r.client.DBSize(ctx).Result(); err == nil {
+		stats.Keys = keys
+	}
+	return stats
+}
+
+// parseRedisInfo تحليل مخرجات INFO بصيغة key:value لكل سطر
+func parseRedisInfo(info string) map[string]string {
+	fields := make(map[string]string)
+	scanner := bufio.NewScanner(strings.NewReader(info))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if key, value, ok := strings.Cut(line, ":"); ok {
+			fields[key] = value
+		}
+	}
+	return fields
+}
@@ -76,14 +76,29 @@ type Config struct {
 	
 	// الرفع (Upload)
 	Upload struct {
-		MaxSize        int64    `mapstructure:"max_size"`
-		Path           string   `mapstructure:"path"`
-		AllowedTypes   []string `mapstructure:"allowed_types"`
-		CloudinaryURL  string   `mapstructure:"cloudinary_url"`
-		S3Bucket       string   `mapstructure:"s3_bucket"`
-		S3Region       string   `mapstructure:"s3_region"`
-		S3AccessKey    string   `mapstructure:"s3_access_key"`
-		S3SecretKey    string   `mapstructure:"s3_secret_key"`
+		MaxSize          int64    `mapstructure:"max_size"`
+		MaxResumableSize int64    `mapstructure:"max_resumable_size"`
+		Path             string   `mapstructure:"path"`
+		AllowedTypes     []string `mapstructure:"allowed_types"`
+		Storage          string   `mapstructure:"storage"` // local, s3, cloudinary
+		PublicURL        string   `mapstructure:"public_url"`
+		CloudinaryURL    string   `mapstructure:"cloudinary_url"`
+		S3Bucket         string   `mapstructure:"s3_bucket"`
+		S3Region         string   `mapstructure:"s3_region"`
+		S3AccessKey      string   `mapstructure:"s3_access_key"`
+		S3SecretKey      string   `mapstructure:"s3_secret_key"`
+		S3Endpoint       string   `mapstructure:"s3_endpoint"`
+		S3PathStyle      bool     `mapstructure:"s3_path_style"`
+		S3PublicURL      string   `mapstructure:"s3_public_url"` // رابط CDN عام لكائنات S3
+		ImageSigningKey  string   `mapstructure:"image_signing_key"`
+		ClamAVAddress    string   `mapstructure:"clamav_address"`
+		SafetyCheck      bool     `mapstructure:"safety_check"`
+		// Quotas حصة التخزين بالبايت لكل طبقة مستخدم (0 = بلا حد)
+		Quotas        map[string]int64 `mapstructure:"quotas"`
+		RestoreWindow time.Duration    `mapstructure:"restore_window"`
+		OrphanGrace   time.Duration    `mapstructure:"orphan_grace"`
+		// ImageURLTTL مدة صلاحية روابط الصور الموقعة (0 = دائمة)
+		ImageURLTTL time.Duration `mapstructure:"image_url_ttl"`
 	} `mapstructure:"upload"`
 	
 	// التخزين المؤقت (Cache)
@@ -92,6 +107,8 @@ type Config struct {
 		Type    string `mapstructure:"type"` // memory, redis
 		Redis   string `mapstructure:"redis"`
 		TTL     time.Duration `mapstructure:"ttl"`
+		// MaxEntries الحد الأقصى لمفاتيح الذاكرة المحلية قبل طرد الأقدم استخداماً
+		MaxEntries int `mapstructure:"max_entries"`
 	} `mapstructure:"cache"`
 	
 	// الأمان
@@ -157,8 +174,9 @@ type Config struct {
 	// AI Services
 	AI struct {
 		OpenAI struct {
-			APIKey string `mapstructure:"api_key"`
-			Model  string `mapstructure:"model"`
+			APIKey  string `mapstructure:"api_key"`
+			Model   string `mapstructure:"model"`
+			BaseURL string `mapstructure:"base_url"` // خادم متوافق مع OpenAI (vLLM، llama.cpp)
 		} `mapstructure:"openai"`
 		Gemini struct {
 			APIKey string `mapstructure:"api_key"`
@@ -236,18 +254,37 @@ func Load() *Config {
 	// ==================== الرفع ====================
 	config.Upload.MaxSize = getEnvInt64("UPLOAD_MAX_SIZE", 10*1024*1024) // 10MB
 	config.Upload.Path = getEnv("UPLOAD_PATH", "./uploads")
-	config.Upload.AllowedTypes = strings.Split(getEnv("UPLOAD_ALLOWED_TYPES", "image/jpeg,image/png,image/gif,image/webp,application/pdf"), ",")
+	config.Upload.MaxResumableSize = getEnvInt64("UPLOAD_MAX_RESUMABLE_SIZE", 2*1024*1024*1024) // 2GB للرفع المجزأ
+	config.Upload.AllowedTypes = strings.Split(getEnv("UPLOAD_ALLOWED_TYPES", "image/jpeg,image/png,image/gif,image/webp,application/pdf,video/mp4,video/webm,video/quicktime"), ",")
+	config.Upload.Storage = getEnv("UPLOAD_STORAGE_BACKEND", "")
+	config.Upload.PublicURL = getEnv("UPLOAD_PUBLIC_URL", config.APIURL)
 	config.Upload.CloudinaryURL = getEnv("CLOUDINARY_URL", "")
 	config.Upload.S3Bucket = getEnv("S3_BUCKET", "")
 	config.Upload.S3Region = getEnv("S3_REGION", "us-east-1")
 	config.Upload.S3AccessKey = getEnv("S3_ACCESS_KEY", "")
 	config.Upload.S3SecretKey = getEnv("S3_SECRET_KEY", "")
+	config.Upload.S3Endpoint = getEnv("S3_ENDPOINT", "") // مثل MinIO أو Cloudflare R2
+	config.Upload.S3PathStyle = getEnvBool("S3_PATH_STYLE", config.Upload.S3Endpoint != "")
+	config.Upload.S3PublicURL = getEnv("S3_PUBLIC_URL", "") // فارغ: روابط الـ bucket المباشرة
+	config.Upload.ImageSigningKey = getEnv("IMAGE_SIGNING_KEY", "") // الافتراضي: ENCRYPTION_KEY
+	config.Upload.ImageURLTTL = getEnvDuration("IMAGE_URL_TTL", 0)
+	config.Upload.ClamAVAddress = getEnv("CLAMAV_ADDRESS", "")       // مثل tcp://localhost:3310
+	config.Upload.SafetyCheck = getEnvBool("UPLOAD_SAFETY_CHECK", false)
+	config.Upload.Quotas = map[string]int64{
+		"free":       getEnvInt64("STORAGE_QUOTA_FREE", 1024*1024*1024),         // 1GB
+		"basic":      getEnvInt64("STORAGE_QUOTA_BASIC", 10*1024*1024*1024),     // 10GB
+		"premium":    getEnvInt64("STORAGE_QUOTA_PREMIUM", 100*1024*1024*1024),  // 100GB
+		"enterprise": getEnvInt64("STORAGE_QUOTA_ENTERPRISE", 0),                // بلا حد
+	}
+	config.Upload.RestoreWindow = getEnvDuration("UPLOAD_RESTORE_WINDOW", 30*24*time.Hour)
+	config.Upload.OrphanGrace = getEnvDuration("UPLOAD_ORPHAN_GRACE", 7*24*time.Hour)
 	
 	// ==================== التخزين المؤقت ====================
 	config.Cache.Enabled = getEnvBool("CACHE_ENABLED", true)
 	config.Cache.Type = getEnv("CACHE_TYPE", "memory")
 	config.Cache.Redis = getEnv("REDIS_URL", "redis://localhost:6379")
 	config.Cache.TTL = getEnvDuration("CACHE_TTL", 5*time.Minute)
+	config.Cache.MaxEntries = getEnvInt("CACHE_MAX_ENTRIES", 10000)
 	
 	// ==================== الأمان ====================
 	config.Security.RateLimit = getEnvInt("RATE_LIMIT", 100)
@@ -296,6 +333,7 @@ func Load() *Config {
 	// ==================== AI Services ====================
 	config.AI.OpenAI.APIKey = getEnv("OPENAI_API_KEY", "")
 	config.AI.OpenAI.Model = getEnv("OPENAI_MODEL", "gpt-4-turbo-preview")
+	config.AI.OpenAI.BaseURL = getEnv("OPENAI_BASE_URL", "https://api.openai.com/v1")
 	config.AI.Gemini.APIKey = getEnv("GEMINI_API_KEY", "")
 	
 	return config
@@ -413,6 +451,21 @@ func (c *Config) GetAppName() string {
 	return c.AppName
 }
 
+// GetStorageDriver يحصل على نوع التخزين المستخدم للملفات
+// إذا لم يُحدد صراحةً يتم اختياره حسب الإعدادات المتوفرة
+func (c *Config) GetStorageDriver() string {
+	if c.Upload.Storage != "" {
+		return c.Upload.Storage
+	}
+	if c.Upload.S3Bucket != "" && c.Upload.S3AccessKey != "" && c.Upload.S3SecretKey != "" {
+		return "s3"
+	}
+	if c.Upload.CloudinaryURL != "" {
+		return "cloudinary"
+	}
+	return "local"
+}
+
 // GetFrontendURL يحصل على رابط الواجهة الأمامية
 func (c *Config) GetFrontendURL() string {
 	return c.FrontendURL
@@ -0,0 +1,424 @@
+package d1
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// timeFormat صيغة تخزين الأوقات، نفس صيغة go-sqlite3 حتى تتوافق البيانات بين SQLite و D1
+const timeFormat = "2006-01-02 15:04:05.999999999-07:00"
+
+// timeLayouts الصيغ التي تُحول نصوصها إلى time.Time عند القراءة
+var timeLayouts = []string{
+	"2006-01-02 15:04:05.999999999-07:00",
+	"2006-01-02T15:04:05.999999999-07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02T15:04:05Z07:00",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+}
+
+// ================================
+// الاتصال
+// ================================
+
+type conn struct {
+	client *client
+	tx     *tx
+}
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	return &stmt{conn: c, query: query}, nil
+}
+
+func (c *conn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	return c.Prepare(query)
+}
+
+func (c *conn) Close() error {
+	return nil
+}
+
+func (c *conn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *conn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if c.tx != nil {
+		return nil, errors.New("d1: transaction already in progress")
+	}
+	c.tx = &tx{conn: c}
+	return c.tx, nil
+}
+
+func (c *conn) Ping(ctx context.Context) error {
+	_, err := c.client.query(ctx, []statement{{SQL: "SELECT 1", Params: []interface{}{}}})
+	return err
+}
+
+func (c *conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	statements, err := buildStatements(query, args)
+	if err != nil {
+		return nil, err
+	}
+
+	// داخل المعاملة تُؤجل الكتابة إلى Commit
+	if c.tx != nil {
+		result := &pendingResult{}
+		c.tx.pending = append(c.tx.pending, pendingExec{statements: statements, result: result})
+		return result, nil
+	}
+
+	results, err := c.client.query(ctx, statements)
+	if err != nil {
+		return nil, err
+	}
+	return newResult(results), nil
+}
+
+func (c *conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	params, err := bindParams(args)
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := c.client.query(ctx, []statement{{SQL: query, Params: params}})
+	if err != nil {
+		return nil, err
+	}
+	if len(results) == 0 {
+		return &rows{}, nil
+	}
+	last := results[len(results)-1]
+	return &rows{columns: last.Results.Columns, values: last.Results.Rows}, nil
+}
+
+var (
+	_ driver.ExecerContext      = (*conn)(nil)
+	_ driver.QueryerContext     = (*conn)(nil)
+	_ driver.ConnBeginTx        = (*conn)(nil)
+	_ driver.ConnPrepareContext = (*conn)(nil)
+	_ driver.Pinger             = (*conn)(nil)
+)
+
+// ================================
+// التعليمات المجهزة
+// ================================
+
+type stmt struct {
+	conn  *conn
+	query string
+}
+
+func (s *stmt) Close() error  { return nil }
+func (s *stmt) NumInput() int { return -1 }
+
+func (s *stmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.conn.ExecContext(context.Background(), s.query, namedValues(args))
+}
+
+func (s *stmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.conn.QueryContext(context.Background(), s.query, namedValues(args))
+}
+
+func (s *stmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	return s.conn.ExecContext(ctx, s.query, args)
+}
+
+func (s *stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	return s.conn.QueryContext(ctx, s.query, args)
+}
+
+func namedValues(args []driver.Value) []driver.NamedValue {
+	named := make([]driver.NamedValue, len(args))
+	for i, arg := range args {
+		named[i] = driver.NamedValue{Ordinal: i + 1, Value: arg}
+	}
+	return named
+}
+
+// ================================
+// المعاملات
+// ================================
+
+type pendingExec struct {
+	statements []statement
+	result     *pendingResult
+}
+
+// tx يجمع تعليمات Exec ويرسلها دفعة واحدة عند Commit
+type tx struct {
+	conn    *conn
+	pending []pendingExec
+}
+
+func (t *tx) Commit() error {
+	defer func() { t.conn.tx = nil }()
+	if len(t.pending) == 0 {
+		return nil
+	}
+
+	var batch []statement
+	for _, exec := range t.pending {
+		batch = append(batch, exec.statements...)
+	}
+	results, err := t.conn.client.query(context.Background(), batch)
+	if err != nil {
+		return err
+	}
+
+	offset := 0
+	for _, exec := range t.pending {
+		end := offset + len(exec.statements)
+		if end > len(results) {
+			end = len(results)
+		}
+		exec.result.resolve(newResult(results[offset:end]))
+		offset = end
+	}
+	return nil
+}
+
+func (t *tx) Rollback() error {
+	t.conn.tx = nil
+	t.pending = nil
+	return nil
+}
+
+// ================================
+// النتائج والصفوف
+// ================================
+
+type result struct {
+	lastInsertID int64
+	rowsAffected int64
+}
+
+func newResult(results []queryResult) *result {
+	r := &result{}
+	for _, qr := range results {
+		r.rowsAffected += qr.Meta.Changes
+		if qr.Meta.LastRowID != 0 {
+			r.lastInsertID = qr.Meta.LastRowID
+		}
+	}
+	return r
+}
+
+func (r *result) LastInsertId() (int64, error) { return r.lastInsertID, nil }
+func (r *result) RowsAffected() (int64, error) { return r.rowsAffected, nil }
+
+// pendingResult نتيجة Exec داخل معاملة، تتوفر بعد Commit
+type pendingResult struct {
+	result *result
+}
+
+func (r *pendingResult) resolve(res *result) { r.result = res }
+
+func (r *pendingResult) LastInsertId() (int64, error) {
+	if r.result == nil {
+		return 0, ErrResultNotReady
+	}
+	return r.result.LastInsertId()
+}
+
+func (r *pendingResult) RowsAffected() (int64, error) {
+	if r.result == nil {
+		return 0, ErrResultNotReady
+	}
+	return r.result.RowsAffected()
+}
+
+type rows struct {
+	columns []string
+	values  [][]json.RawMessage
+	next    int
+}
+
+func (r *rows) Columns() []string { return r.columns }
+func (r *rows) Close() error      { return nil }
+
+func (r *rows) Next(dest []driver.Value) error {
+	if r.next >= len(r.values) {
+		return io.EOF
+	}
+	row := r.values[r.next]
+	r.next++
+	for i := range dest {
+		if i >= len(row) {
+			dest[i] = nil
+			continue
+		}
+		value, err := decodeValue(row[i])
+		if err != nil {
+			return fmt.Errorf("d1: failed to decode column %s: %w", r.columns[i], err)
+		}
+		dest[i] = value
+	}
+	return nil
+}
+
+// ================================
+// تحويل القيم
+// ================================
+
+// bindParams تحويل العناصر إلى قيم JSON بصيغ SQLite
+func bindParams(args []driver.NamedValue) ([]interface{}, error) {
+	params := make([]interface{}, len(args))
+	for i, arg := range args {
+		if arg.Name != "" {
+			return nil, fmt.Errorf("d1: named parameters are not supported (%s)", arg.Name)
+		}
+		switch v := arg.Value.(type) {
+		case nil, int64, float64, string:
+			params[i] = v
+		case bool:
+			if v {
+				params[i] = 1
+			} else {
+				params[i] = 0
+			}
+		case []byte:
+			params[i] = string(v)
+		case time.Time:
+			params[i] = v.Format(timeFormat)
+		default:
+			return nil, fmt.Errorf("d1: unsupported parameter type %T", v)
+		}
+	}
+	return params, nil
+}
+
+// buildStatements نص بلا عناصر قد يحوي عدة تعليمات تُرسل كدفعة ذرية
+func buildStatements(query string, args []driver.NamedValue) ([]statement, error) {
+	params, err := bindParams(args)
+	if err != nil {
+		return nil, err
+	}
+	if len(args) > 0 {
+		return []statement{{SQL: query, Params: params}}, nil
+	}
+
+	parts := SplitStatements(query)
+	if len(parts) == 0 {
+		return nil, errors.New("d1: empty query")
+	}
+	statements := make([]statement, len(parts))
+	for i, part := range parts {
+		statements[i] = statement{SQL: part, Params: []interface{}{}}
+	}
+	return statements, nil
+}
+
+// decodeValue تحويل قيمة JSON إلى قيمة driver.Value
+func decodeValue(raw json.RawMessage) (driver.Value, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+
+	switch raw[0] {
+	case '"':
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return nil, err
+		}
+		if t, ok := parseTime(s); ok {
+			return t, nil
+		}
+		return s, nil
+	case 't', 'f':
+		var b bool
+		err := json.Unmarshal(raw, &b)
+		return b, err
+	case '[', '{':
+		return []byte(raw), nil
+	}
+
+	var n json.Number
+	if err := json.Unmarshal(raw, &n); err != nil {
+		return nil, err
+	}
+	if i, err := n.Int64(); err == nil {
+		return i, nil
+	}
+	return n.Float64()
+}
+
+// parseTime تحويل النصوص بصيغ الأوقات المعروفة (كما يفعل go-sqlite3 لأعمدة الأوقات)
+func parseTime(s string) (time.Time, bool) {
+	if len(s) < 19 || s[4] != '-' || s[7] != '-' || s[13] != ':' {
+		return time.Time{}, false
+	}
+	for _, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.UTC); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// SplitStatements تقسيم نص SQL إلى تعليمات على ; مع تجاهل النصوص والتعليقات
+func SplitStatements(query string) []string {
+	var statements []string
+	var current strings.Builder
+	var quote byte
+	inLineComment, inBlockComment := false, false
+
+	flush := func() {
+		if s := strings.TrimSpace(current.String()); s != "" && !onlyComments(s) {
+			statements = append(statements, s)
+		}
+		current.Reset()
+	}
+
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case inLineComment:
+			if c == '\n' {
+				inLineComment = false
+			}
+		case inBlockComment:
+			if c == '*' && i+1 < len(query) && query[i+1] == '/' {
+				inBlockComment = false
+				current.WriteByte(c)
+				i++
+				c = '/'
+			}
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '-' && i+1 < len(query) && query[i+1] == '-':
+			inLineComment = true
+		case c == '/' && i+1 < len(query) && query[i+1] == '*':
+			inBlockComment = true
+		case c == ';':
+			flush()
+			continue
+		}
+		current.WriteByte(c)
+	}
+	flush()
+	return statements
+}
+
+// onlyComments هل النص تعليقات فقط
+func onlyComments(s string) bool {
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "--") {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,332 @@
+// Package d1 مشغل database/sql لقواعد بيانات Cloudflare D1 عبر واجهة HTTP.
+//
+// كل استعلام يُرسل إلى نقطة /raw في واجهة D1 مع عناصره كقيم JSON. واجهة HTTP لا
+// تدعم المعاملات التفاعلية، لذلك تُجمع تعليمات Exec داخل المعاملة وتُرسل دفعة
+// واحدة عند Commit (والدفعة تُنفذ ذرياً في D1)، ولا تتوفر نتائجها قبل ذلك.
+//
+// صيغة DSN:
+//
+//	d1://<account_id>/<database>?token=<api_token>[&endpoint=<base_url>]
+//
+// database معرف القاعدة (UUID) أو اسمها، ويُحل الاسم إلى المعرف عند أول اتصال.
+package d1
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DriverName اسم المشغل المسجل في database/sql
+const DriverName = "d1"
+
+// DefaultEndpoint عنوان واجهة Cloudflare API
+const DefaultEndpoint = "https://api.cloudflare.com/client/v4"
+
+func init() {
+	sql.Register(DriverName, &Driver{})
+}
+
+var (
+	ErrInvalidDSN     = errors.New("d1: invalid DSN")
+	ErrUnauthorized   = errors.New("d1: unauthorized")
+	ErrNotFound       = errors.New("d1: database not found")
+	ErrRateLimited    = errors.New("d1: rate limited")
+	ErrConstraint     = errors.New("d1: constraint violation")
+	ErrResultNotReady = errors.New("d1: result is not available until the transaction commits")
+)
+
+// Error خطأ مرجع من واجهة D1
+type Error struct {
+	StatusCode int
+	Code       int
+	Message    string
+}
+
+func (e *Error) Error() string {
+	if e.Code != 0 {
+		return fmt.Sprintf("d1: %s (code %d, status %d)", e.Message, e.Code, e.StatusCode)
+	}
+	return fmt.Sprintf("d1: %s (status %d)", e.Message, e.StatusCode)
+}
+
+// Is ربط الخطأ بالأخطاء المعرفة حتى يعمل errors.Is
+func (e *Error) Is(target error) bool {
+	switch target {
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrConstraint:
+		return strings.Contains(e.Message, "constraint failed") || strings.Contains(e.Message, "SQLITE_CONSTRAINT")
+	}
+	return false
+}
+
+// Config إعدادات الاتصال بقاعدة D1
+type Config struct {
+	AccountID  string
+	Database   string // المعرف أو الاسم
+	APIToken   string
+	Endpoint   string
+	HTTPClient *http.Client
+	MaxRetries int // إعادة المحاولة عند 429 فقط، لأن الطلب لم يُنفذ
+}
+
+// DSN بناء رابط الاتصال من الإعدادات
+func (c Config) DSN() string {
+	u := url.URL{Scheme: "d1", Host: c.AccountID, Path: "/" + c.Database}
+	query := url.Values{}
+	if c.APIToken != "" {
+		query.Set("token", c.APIToken)
+	}
+	if c.Endpoint != "" && c.Endpoint != DefaultEndpoint {
+		query.Set("endpoint", c.Endpoint)
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// ParseDSN تحليل رابط الاتصال
+func ParseDSN(dsn string) (Config, error) {
+	u, err := url.Parse(dsn)
+	if err != nil || u.Scheme != "d1" || u.Host == "" || strings.Trim(u.Path, "/") == "" {
+		return Config{}, fmt.Errorf("%w: expected d1://<account_id>/<database>?token=...", ErrInvalidDSN)
+	}
+	query := u.Query()
+	cfg := Config{
+		AccountID: u.Host,
+		Database:  strings.Trim(u.Path, "/"),
+		APIToken:  query.Get("token"),
+		Endpoint:  query.Get("endpoint"),
+	}
+	if cfg.APIToken == "" {
+		return Config{}, fmt.Errorf("%w: token is required", ErrInvalidDSN)
+	}
+	return cfg, nil
+}
+
+// ================================
+// المشغل والموصل
+// ================================
+
+// Driver مشغل D1
+type Driver struct{}
+
+// Open فتح اتصال من DSN
+func (d *Driver) Open(dsn string) (driver.Conn, error) {
+	connector, err := d.OpenConnector(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return connector.Connect(context.Background())
+}
+
+// OpenConnector إنشاء موصل من DSN
+func (d *Driver) OpenConnector(dsn string) (driver.Connector, error) {
+	cfg, err := ParseDSN(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return NewConnector(cfg), nil
+}
+
+// Connector موصل D1 يشارك عميل HTTP ومعرف القاعدة بين الاتصالات
+type Connector struct {
+	client *client
+}
+
+// NewConnector إنشاء موصل للاستخدام مع sql.OpenDB
+func NewConnector(cfg Config) *Connector {
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = DefaultEndpoint
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{Timeout: 30 * time.Second}
+	}
+	if cfg.MaxRetries == 0 {
+		cfg.MaxRetries = 3
+	}
+	return &Connector{client: &client{config: cfg}}
+}
+
+// Connect إنشاء اتصال (لا يفتح اتصالاً فعلياً، فكل استعلام طلب HTTP مستقل)
+func (c *Connector) Connect(ctx context.Context) (driver.Conn, error) {
+	if _, err := c.client.databaseID(ctx); err != nil {
+		return nil, err
+	}
+	return &conn{client: c.client}, nil
+}
+
+// Driver المشغل المرتبط
+func (c *Connector) Driver() driver.Driver {
+	return &Driver{}
+}
+
+// ================================
+// عميل HTTP
+// ================================
+
+// statement تعليمة واحدة مع عناصرها
+type statement struct {
+	SQL    string        `json:"sql"`
+	Params []interface{} `json:"params"`
+}
+
+// queryResult نتيجة تعليمة من نقطة /raw
+type queryResult struct {
+	Results struct {
+		Columns []string            `json:"columns"`
+		Rows    [][]json.RawMessage `json:"rows"`
+	} `json:"results"`
+	Success bool `json:"success"`
+	Meta    struct {
+		Changes     int64   `json:"changes"`
+		LastRowID   int64   `json:"last_row_id"`
+		Duration    float64 `json:"duration"`
+		RowsRead    int64   `json:"rows_read"`
+		RowsWritten int64   `json:"rows_written"`
+	} `json:"meta"`
+}
+
+// envelope غلاف استجابات Cloudflare API
+type envelope struct {
+	Success bool            `json:"success"`
+	Result  json.RawMessage `json:"result"`
+	Errors  []struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+type client struct {
+	config Config
+
+	mu         sync.Mutex
+	resolvedID string
+}
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// databaseID معرف القاعدة، مع حل الاسم عبر واجهة القوائم مرة واحدة
+func (c *client) databaseID(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.resolvedID != "" {
+		return c.resolvedID, nil
+	}
+	if uuidPattern.MatchString(c.config.Database) {
+		c.resolvedID = c.config.Database
+		return c.resolvedID, nil
+	}
+
+	path := fmt.Sprintf("/accounts/%s/d1/database?name=%s", url.PathEscape(c.config.AccountID), url.QueryEscape(c.config.Database))
+	raw, err := c.do(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve D1 database %q: %w", c.config.Database, err)
+	}
+	var databases []struct {
+		UUID string `json:"uuid"`
+		Name string `json:"name"`
+	}
+	if err := json.Unmarshal(raw, &databases); err != nil {
+		return "", fmt.Errorf("failed to decode D1 database list: %w", err)
+	}
+	for _, database := range databases {
+		if database.Name == c.config.Database {
+			c.resolvedID = database.UUID
+			return c.resolvedID, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %s", ErrNotFound, c.config.Database)
+}
+
+// query تنفيذ تعليمة واحدة أو دفعة تعليمات ذرية
+func (c *client) query(ctx context.Context, statements []statement) ([]queryResult, error) {
+	id, err := c.databaseID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var body interface{} = statements[0]
+	if len(statements) > 1 {
+		body = map[string]interface{}{"batch": statements}
+	}
+
+	path := fmt.Sprintf("/accounts/%s/d1/database/%s/raw", url.PathEscape(c.config.AccountID), url.PathEscape(id))
+	raw, err := c.do(ctx, http.MethodPost, path, body)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []queryResult
+	if err := json.Unmarshal(raw, &results); err != nil {
+		return nil, fmt.Errorf("failed to decode D1 response: %w", err)
+	}
+	return results, nil
+}
+
+func (c *client) do(ctx context.Context, method, path string, body interface{}) (json.RawMessage, error) {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return nil, fmt.Errorf("failed to encode D1 request: %w", err)
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, method, strings.TrimRight(c.config.Endpoint, "/")+path, bytes.NewReader(payload))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create D1 request: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+c.config.APIToken)
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := c.config.HTTPClient.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("d1 request failed: %w", err)
+		}
+		data, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read D1 response: %w", err)
+		}
+
+		if resp.StatusCode == http.StatusTooManyRequests && attempt < c.config.MaxRetries {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(time.Duration(attempt+1) * 200 * time.Millisecond):
+			}
+			continue
+		}
+
+		var env envelope
+		if err := json.Unmarshal(data, &env); err != nil {
+			return nil, &Error{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(data))}
+		}
+		if !env.Success || resp.StatusCode >= 400 {
+			apiErr := &Error{StatusCode: resp.StatusCode, Message: http.StatusText(resp.StatusCode)}
+			if len(env.Errors) > 0 {
+				apiErr.Code, apiErr.Message = env.Errors[0].Code, env.Errors[0].Message
+			}
+			return nil, apiErr
+		}
+		return env.Result, nil
+	}
+}
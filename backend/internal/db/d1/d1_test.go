@@ -0,0 +1,179 @@
+package d1_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/nawthtech/nawthtech/backend/internal/db/d1"
+	"github.com/nawthtech/nawthtech/backend/internal/db/d1/d1test"
+)
+
+func openDB(t *testing.T, server *d1test.Server) *sql.DB {
+	t.Helper()
+	database, err := sql.Open(d1.DriverName, server.DSN())
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+	return database
+}
+
+func TestExecQueryAndTypes(t *testing.T) {
+	server := d1test.NewServer(t)
+	database := openDB(t, server)
+	ctx := context.Background()
+
+	// نص متعدد التعليمات يُرسل كدفعة واحدة
+	_, err := database.ExecContext(ctx, `
+		-- جدول للاختبار; مع فاصلة منقوطة في التعليق
+		CREATE TABLE items (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT UNIQUE, note TEXT, price REAL, active BOOLEAN, created_at DATETIME);
+		CREATE INDEX idx_items_name ON items(name);`)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	created := time.Date(2025, 3, 1, 10, 30, 0, 0, time.UTC)
+	result, err := database.ExecContext(ctx,
+		"INSERT INTO items (name, note, price, active, created_at) VALUES (?, ?, ?, ?, ?)",
+		"first; item", nil, 9.5, true, created)
+	if err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	if id, _ := result.LastInsertId(); id != 1 {
+		t.Errorf("expected last insert id 1, got %d", id)
+	}
+	if n, _ := result.RowsAffected(); n != 1 {
+		t.Errorf("expected 1 row affected, got %d", n)
+	}
+
+	var (
+		name      string
+		note      sql.NullString
+		price     float64
+		active    bool
+		createdAt time.Time
+	)
+	err = database.QueryRowContext(ctx, "SELECT name, note, price, active, created_at FROM items WHERE id = ?", 1).
+		Scan(&name, &note, &price, &active, &createdAt)
+	if err != nil {
+		t.Fatalf("select: %v", err)
+	}
+	if name != "first; item" || note.Valid || price != 9.5 || !active || !createdAt.Equal(created) {
+		t.Errorf("unexpected row: %q %v %v %v %v", name, note, price, active, createdAt)
+	}
+}
+
+func TestErrorMapping(t *testing.T) {
+	server := d1test.NewServer(t)
+	database := openDB(t, server)
+	ctx := context.Background()
+
+	database.ExecContext(ctx, "CREATE TABLE users (email TEXT UNIQUE)")
+	database.ExecContext(ctx, "INSERT INTO users (email) VALUES (?)", "a@example.com")
+
+	_, err := database.ExecContext(ctx, "INSERT INTO users (email) VALUES (?)", "a@example.com")
+	var apiErr *d1.Error
+	if !errors.Is(err, d1.ErrConstraint) || !errors.As(err, &apiErr) || apiErr.Code != 7500 {
+		t.Errorf("expected constraint error, got %v", err)
+	}
+
+	// 429 يعاد تلقائياً لأن الطلب لم يُنفذ
+	server.FailNext(http.StatusTooManyRequests, 971, "Too many requests")
+	if _, err := database.ExecContext(ctx, "INSERT INTO users (email) VALUES (?)", "b@example.com"); err != nil {
+		t.Errorf("expected rate limited request to be retried, got %v", err)
+	}
+
+	cfg := server.Config()
+	cfg.APIToken = "wrong"
+	bad := sql.OpenDB(d1.NewConnector(cfg))
+	defer bad.Close()
+	if err := bad.PingContext(ctx); !errors.Is(err, d1.ErrUnauthorized) {
+		t.Errorf("expected unauthorized error, got %v", err)
+	}
+}
+
+func TestTransactionsAreBatched(t *testing.T) {
+	server := d1test.NewServer(t)
+	database := openDB(t, server)
+	ctx := context.Background()
+
+	database.ExecContext(ctx, "CREATE TABLE counters (name TEXT PRIMARY KEY, value INTEGER)")
+
+	before := server.Requests()
+	tx, _ := database.BeginTx(ctx, nil)
+	tx.ExecContext(ctx, "INSERT INTO counters (name, value) VALUES (?, ?)", "a", 1)
+	result, _ := tx.ExecContext(ctx, "INSERT INTO counters (name, value) VALUES (?, ?)", "b", 2)
+	if _, err := result.RowsAffected(); !errors.Is(err, d1.ErrResultNotReady) {
+		t.Errorf("expected result to be pending before commit, got %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("commit: %v", err)
+	}
+	if n, _ := result.RowsAffected(); n != 1 {
+		t.Errorf("expected result after commit, got %d", n)
+	}
+	if got := server.Requests() - before; got != 1 {
+		t.Errorf("expected one batched request, got %d", got)
+	}
+
+	// فشل أي تعليمة يلغي الدفعة كاملة
+	tx, _ = database.BeginTx(ctx, nil)
+	tx.ExecContext(ctx, "INSERT INTO counters (name, value) VALUES (?, ?)", "c", 3)
+	tx.ExecContext(ctx, "INSERT INTO counters (name, value) VALUES (?, ?)", "a", 4)
+	if err := tx.Commit(); !errors.Is(err, d1.ErrConstraint) {
+		t.Errorf("expected constraint error on commit, got %v", err)
+	}
+
+	// الإلغاء لا يرسل شيئاً
+	tx, _ = database.BeginTx(ctx, nil)
+	tx.ExecContext(ctx, "INSERT INTO counters (name, value) VALUES (?, ?)", "d", 5)
+	tx.Rollback()
+
+	var count int
+	database.QueryRowContext(ctx, "SELECT COUNT(*) FROM counters").Scan(&count)
+	if count != 2 {
+		t.Errorf("expected only the committed rows, got %d", count)
+	}
+}
+
+func TestDatabaseNameResolution(t *testing.T) {
+	server := d1test.NewServer(t)
+	ctx := context.Background()
+
+	cfg := server.Config()
+	cfg.Database = d1test.DatabaseName
+	database := sql.OpenDB(d1.NewConnector(cfg))
+	defer database.Close()
+	if err := database.PingContext(ctx); err != nil {
+		t.Fatalf("expected name to resolve, got %v", err)
+	}
+
+	cfg.Database = "missing"
+	missing := sql.OpenDB(d1.NewConnector(cfg))
+	defer missing.Close()
+	if err := missing.PingContext(ctx); !errors.Is(err, d1.ErrNotFound) {
+		t.Errorf("expected not found error, got %v", err)
+	}
+}
+
+func TestParseDSN(t *testing.T) {
+	cfg := d1.Config{AccountID: "acc", Database: "nawthtech-db", APIToken: "tok"}
+	parsed, err := d1.ParseDSN(cfg.DSN())
+	if err != nil || parsed.AccountID != "acc" || parsed.Database != "nawthtech-db" || parsed.APIToken != "tok" {
+		t.Errorf("unexpected round trip: %+v, %v", parsed, err)
+	}
+	if _, err := d1.ParseDSN("d1://acc/db"); !errors.Is(err, d1.ErrInvalidDSN) {
+		t.Errorf("expected token to be required, got %v", err)
+	}
+}
+
+func TestSplitStatements(t *testing.T) {
+	got := d1.SplitStatements("INSERT INTO t VALUES ('a;b'); /* x; y */ SELECT 1;\n-- done;\n")
+	if len(got) != 2 || got[0] != "INSERT INTO t VALUES ('a;b')" {
+		t.Errorf("unexpected statements: %q", got)
+	}
+}
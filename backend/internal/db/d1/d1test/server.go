@@ -0,0 +1,249 @@
+// Package d1test خادم HTTP محلي يحاكي واجهة Cloudflare D1 فوق SQLite للاختبارات.
+package d1test
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nawthtech/nawthtech/backend/internal/db/d1"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const (
+	AccountID    = "test-account"
+	DatabaseID   = "6f1c2c3e-0d5b-4f8a-9a51-5c3c0e4d7b21"
+	DatabaseName = "nawthtech-test"
+	Token        = "test-token"
+)
+
+// Server بديل محلي لواجهة D1
+type Server struct {
+	*httptest.Server
+	db       *sql.DB
+	requests atomic.Int64
+
+	mu       sync.Mutex
+	failures []failure
+}
+
+type failure struct {
+	status  int
+	code    int
+	message string
+}
+
+type statement struct {
+	SQL    string        `json:"sql"`
+	Params []interface{} `json:"params"`
+}
+
+// NewServer تشغيل الخادم على قاعدة SQLite مؤقتة تُحذف بانتهاء الاختبار
+func NewServer(t testing.TB) *Server {
+	t.Helper()
+
+	database, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "d1.db"))
+	if err != nil {
+		t.Fatalf("failed to open stand-in database: %v", err)
+	}
+	database.SetMaxOpenConns(1)
+
+	s := &Server{db: database}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	t.Cleanup(func() {
+		s.Close()
+		database.Close()
+	})
+	return s
+}
+
+// Config إعدادات المشغل للاتصال بالخادم
+func (s *Server) Config() d1.Config {
+	return d1.Config{AccountID: AccountID, Database: DatabaseID, APIToken: Token, Endpoint: s.URL}
+}
+
+// DSN رابط الاتصال بالخادم
+func (s *Server) DSN() string {
+	return s.Config().DSN()
+}
+
+// Requests عدد الطلبات المستلمة
+func (s *Server) Requests() int64 {
+	return s.requests.Load()
+}
+
+// FailNext جعل الطلب التالي يفشل بالحالة والرسالة المحددة
+func (s *Server) FailNext(status, code int, message string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = append(s.failures, failure{status: status, code: code, message: message})
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	s.requests.Add(1)
+
+	if r.Header.Get("Authorization") != "Bearer "+Token {
+		writeError(w, http.StatusUnauthorized, 10000, "Authentication error")
+		return
+	}
+
+	s.mu.Lock()
+	if len(s.failures) > 0 {
+		f := s.failures[0]
+		s.failures = s.failures[1:]
+		s.mu.Unlock()
+		writeError(w, f.status, f.code, f.message)
+		return
+	}
+	s.mu.Unlock()
+
+	base := "/accounts/" + AccountID + "/d1/database"
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == base:
+		var list []map[string]string
+		if name := r.URL.Query().Get("name"); name == "" || name == DatabaseName {
+			list = append(list, map[string]string{"uuid": DatabaseID, "name": DatabaseName})
+		}
+		writeResult(w, list)
+
+	case r.Method == http.MethodPost && r.URL.Path == base+"/"+DatabaseID+"/raw":
+		var body struct {
+			statement
+			Batch []statement `json:"batch"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeError(w, http.StatusBadRequest, 7400, "invalid request body")
+			return
+		}
+		statements := body.Batch
+		if len(statements) == 0 {
+			statements = []statement{body.statement}
+		}
+
+		results, err := s.execute(statements)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, 7500, err.Error()+": SQLITE_ERROR")
+			return
+		}
+		writeResult(w, results)
+
+	default:
+		writeError(w, http.StatusNotFound, 7404, "Could not route to "+r.URL.Path)
+	}
+}
+
+// execute تنفيذ التعليمات في معاملة واحدة كما تفعل دفعات D1
+func (s *Server) execute(statements []statement) ([]map[string]interface{}, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	results := make([]map[string]interface{}, 0, len(statements))
+	for _, stmt := range statements {
+		start := time.Now()
+		// أرقام JSON تصل float64، و LIMIT وأمثالها تتطلب أعداداً صحيحة
+		for i, p := range stmt.Params {
+			if f, ok := p.(float64); ok && f == float64(int64(f)) {
+				stmt.Params[i] = int64(f)
+			}
+		}
+		rows, err := tx.Query(stmt.SQL, stmt.Params...)
+		if err != nil {
+			return nil, err
+		}
+		columns, _ := rows.Columns()
+		values := [][]interface{}{}
+		for rows.Next() {
+			row := make([]interface{}, len(columns))
+			pointers := make([]interface{}, len(columns))
+			for i := range row {
+				pointers[i] = &row[i]
+			}
+			if err := rows.Scan(pointers...); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			for i, v := range row {
+				row[i] = rawValue(v)
+			}
+			values = append(values, row)
+		}
+		if err := rows.Err(); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if err := rows.Close(); err != nil {
+			return nil, err
+		}
+
+		var changes, lastRowID int64
+		if !isRead(stmt.SQL) {
+			if err := tx.QueryRow("SELECT changes(), last_insert_rowid()").Scan(&changes, &lastRowID); err != nil {
+				return nil, err
+			}
+		}
+
+		results = append(results, map[string]interface{}{
+			"results": map[string]interface{}{"columns": columns, "rows": values},
+			"success": true,
+			"meta": map[string]interface{}{
+				"changes":     changes,
+				"last_row_id": lastRowID,
+				"duration":    float64(time.Since(start).Microseconds()) / 1000,
+			},
+		})
+	}
+	return results, tx.Commit()
+}
+
+// rawValue القيمة كما تعيدها D1: بلا تحويل أعمدة الأوقات والقيم المنطقية
+func rawValue(v interface{}) interface{} {
+	switch value := v.(type) {
+	case []byte:
+		return string(value)
+	case time.Time:
+		return value.Format("2006-01-02 15:04:05.999999999-07:00")
+	case bool:
+		if value {
+			return 1
+		}
+		return 0
+	}
+	return v
+}
+
+func isRead(query string) bool {
+	q := strings.ToUpper(strings.TrimSpace(query))
+	return strings.HasPrefix(q, "SELECT") || strings.HasPrefix(q, "PRAGMA") || strings.HasPrefix(q, "WITH")
+}
+
+func writeResult(w http.ResponseWriter, result interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"result":   result,
+		"success":  true,
+		"errors":   []interface{}{},
+		"messages": []interface{}{},
+	})
+}
+
+func writeError(w http.ResponseWriter, status, code int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"result":   nil,
+		"success":  false,
+		"errors":   []map[string]interface{}{{"code": code, "message": message}},
+		"messages": []interface{}{},
+	})
+}
@@ -6,14 +6,18 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"strings"
 	"time"
 
 	"github.com/nawthtech/nawthtech/backend/internal/config"
+	"github.com/nawthtech/nawthtech/backend/internal/db/d1"
+	"github.com/nawthtech/nawthtech/backend/internal/db/migrations"
 )
 
 // DB هو نوع مغلف لـ sql.DB
 type DB struct {
 	*sql.DB
+	dialect Dialect
 }
 
 // Config يحتوي على إعدادات قاعدة البيانات
@@ -38,14 +42,20 @@ var (
 	}
 )
 
-// InitializeSQL تهيئة اتصال قاعدة البيانات SQL
+// InitializeSQL تهيئة اتصال قاعدة البيانات SQL (driver بقيم DB_DRIVER: sqlite3 أو postgres)
 func InitializeSQL(cfg *config.Config, driver, dsn string) (*DB, error) {
 	if dsn == "" {
 		return nil, fmt.Errorf("DSN cannot be empty")
 	}
 
+	dialect, err := ParseDialect(driver)
+	if err != nil {
+		return nil, err
+	}
+	driverName, _ := SQLDriverName(driver)
+
 	// فتح الاتصال بقاعدة البيانات
-	sqlDB, err := sql.Open(driver, dsn)
+	sqlDB, err := sql.Open(driverName, dsn)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database connection: %w", err)
 	}
@@ -65,9 +75,9 @@ func InitializeSQL(cfg *config.Config, driver, dsn string) (*DB, error) {
 	sqlDB.SetConnMaxLifetime(defaultConfig.Timeout)
 
 	// إنشاء المغلف
-	db = &DB{DB: sqlDB}
+	db = &DB{DB: sqlDB, dialect: dialect}
 
-	log.Printf("✅ Database connected successfully (driver: %s)", driver)
+	log.Printf("✅ Database connected successfully (driver: %s)", dialect)
 	return db, nil
 }
 
@@ -79,7 +89,17 @@ func InitializeFromConfig(cfg *config.Config) (*DB, error) {
 		return nil, nil
 	}
 
-	return InitializeSQL(cfg, "postgres", dsn)
+	driver := cfg.Database.Driver
+	if driver == "" {
+		driver = string(SQLite)
+	}
+	if dialect, err := ParseDialect(driver); err == nil && dialect == Postgres {
+		dsn = PostgresDSN(cfg)
+	} else if strings.EqualFold(driver, d1.DriverName) {
+		dsn = D1DSN(cfg)
+	}
+
+	return InitializeSQL(cfg, driver, dsn)
 }
 
 // GetDB ترجع نسخة من اتصال قاعدة البيانات
@@ -204,86 +224,31 @@ func HealthCheck(ctx context.Context) (bool, error) {
 	return true, nil
 }
 
-// RunMigrations تشغيل عمليات الترحيل
-func RunMigrations(ctx context.Context) error {
-	if db == nil {
-		return fmt.Errorf("database not initialized")
-	}
-
-	// جدول المستخدمين
-	usersTable := `
-	CREATE TABLE IF NOT EXISTS users (
-		id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
-		email VARCHAR(255) UNIQUE NOT NULL,
-		name VARCHAR(255),
-		password_hash VARCHAR(255),
-		role VARCHAR(50) DEFAULT 'user',
-		status VARCHAR(50) DEFAULT 'active',
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		last_login TIMESTAMP
-	)
-	`
-
-	// جدول الجلسات
-	sessionsTable := `
-	CREATE TABLE IF NOT EXISTS sessions (
-		id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
-		user_id UUID REFERENCES users(id) ON DELETE CASCADE,
-		token TEXT NOT NULL,
-		expires_at TIMESTAMP NOT NULL,
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		INDEX idx_token (token),
-		INDEX idx_user_id (user_id)
-	)
-	`
-
-	// جدول السجلات
-	auditLogTable := `
-	CREATE TABLE IF NOT EXISTS audit_logs (
-		id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
-		user_id UUID REFERENCES users(id) ON DELETE SET NULL,
-		action VARCHAR(255) NOT NULL,
-		resource VARCHAR(255),
-		details JSONB,
-		ip_address VARCHAR(45),
-		user_agent TEXT,
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-	)
-	`
-
-	migrations := []string{usersTable, sessionsTable, auditLogTable}
-
-	for _, migration := range migrations {
-		if _, err := db.ExecContext(ctx, migration); err != nil {
-			return fmt.Errorf("failed to run migration: %w", err)
+// RunMigrations تطبيق ترحيلات المخطط المعلقة على database (أو الاتصال العام إذا كان nil)
+func RunMigrations(ctx context.Context, database *sql.DB) error {
+	if database == nil {
+		if db == nil {
+			return fmt.Errorf("database not initialized")
 		}
+		database = db.DB
 	}
 
-	log.Println("✅ Database migrations completed successfully")
-	return nil
-}
-
-// CreateIndexes إنشاء الفهارس
-func CreateIndexes(ctx context.Context) error {
-	if db == nil {
-		return fmt.Errorf("database not initialized")
+	dialect := migrations.DetectDialect(database)
+	if db != nil && db.DB == database && db.dialect != "" {
+		dialect = db.dialect
 	}
 
-	indexes := []string{
-		"CREATE INDEX IF NOT EXISTS idx_users_email ON users(email)",
-		"CREATE INDEX IF NOT EXISTS idx_users_status ON users(status)",
-		"CREATE INDEX IF NOT EXISTS idx_sessions_expires ON sessions(expires_at)",
-		"CREATE INDEX IF NOT EXISTS idx_audit_logs_created ON audit_logs(created_at)",
+	migrator, err := migrations.New(database, dialect)
+	if err != nil {
+		return fmt.Errorf("failed to load migrations: %w", err)
 	}
 
-	for _, index := range indexes {
-		if _, err := db.ExecContext(ctx, index); err != nil {
-			return fmt.Errorf("failed to create index: %w", err)
-		}
+	applied, err := migrator.Up(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to run migrations: %w", err)
 	}
 
-	log.Println("✅ Database indexes created successfully")
+	log.Printf("✅ Database migrations completed successfully (%d applied)", len(applied))
 	return nil
 }
 
@@ -303,9 +268,9 @@ func (d *DB) GetDatabaseStats(ctx context.Context) (map[string]interface{}, erro
 	var dbInfo map[string]interface{}
 
 	// محاولة الحصول على معلومات قاعدة البيانات
-	if driver := d.GetDriver(); driver == "sqlite3" {
+	if driver := d.GetDriver(); driver == string(SQLite) || driver == string(Postgres) {
 		var version string
-		err := d.DB.QueryRowContext(ctx, "SELECT sqlite_version()").Scan(&version)
+		err := d.DB.QueryRowContext(ctx, VersionQuery(d.Dialect())).Scan(&version)
 		if err == nil {
 			dbInfo = map[string]interface{}{
 				"driver":             driver,
@@ -341,9 +306,23 @@ func (d *DB) GetDriver() string {
 		return "unknown"
 	}
 
-	// يمكن تحسين هذا المنطق بناءً على تكوينك الفعلي
-	// افتراضياً نرجع sqlite3 أو يمكن تعديله حسب التكوين
-	return "sqlite3"
+	return string(d.Dialect())
+}
+
+// Dialect لهجة الاتصال (من الإعدادات، أو من نوع المشغل)
+func (d *DB) Dialect() Dialect {
+	if d.dialect == "" {
+		return migrations.DetectDialect(d.DB)
+	}
+	return d.dialect
+}
+
+// VersionQuery استعلام رقم إصدار الخادم حسب اللهجة
+func VersionQuery(dialect Dialect) string {
+	if dialect == Postgres {
+		return "SHOW server_version"
+	}
+	return "SELECT sqlite_version()"
 }
 
 // TestQuery تجربة استعلام اختبار
@@ -479,10 +458,11 @@ func (d *DB) GetHealthMetrics(ctx context.Context, metricName string, hours int)
 
 	query := `SELECT metric_name, metric_value, metadata, created_at
 			  FROM performance_metrics 
-			  WHERE metric_name = ? AND created_at >= datetime('now', ?)
+			  WHERE metric_name = ? AND created_at >= ?
 			  ORDER BY created_at ASC`
 
-	rows, err := d.DB.QueryContext(ctx, query, metricName, fmt.Sprintf("-%d hours", hours))
+	since := time.Now().Add(-time.Duration(hours) * time.Hour)
+	rows, err := d.DB.QueryContext(ctx, query, metricName, since)
 	if err != nil {
 		// إذا كان الجدول غير موجود، نرجع مصفوفة فارغة
 		return []map[string]interface{}{}, nil
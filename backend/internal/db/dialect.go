@@ -0,0 +1,191 @@
+package db
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/nawthtech/nawthtech/backend/internal/config"
+	"github.com/nawthtech/nawthtech/backend/internal/db/d1"
+	"github.com/nawthtech/nawthtech/backend/internal/db/migrations"
+)
+
+// ================================
+// طبقة اللهجات
+// ================================
+
+// الخدمات تكتب استعلاماتها بصيغة SQLite (عناصر ? و INSERT OR ...)، وطبقة
+// اللهجة تحولها عند التنفيذ إلى صيغة Postgres حتى يبقى كود الخدمات واحداً.
+
+// Dialect لهجة SQL لقاعدة البيانات
+type Dialect = migrations.Dialect
+
+const (
+	SQLite   = migrations.SQLite
+	Postgres = migrations.Postgres
+)
+
+// ParseDialect تحويل قيمة DB_DRIVER إلى لهجة
+func ParseDialect(driver string) (Dialect, error) {
+	return migrations.ParseDialect(driver)
+}
+
+// SQLDriverName اسم مشغل database/sql المقابل لقيمة DB_DRIVER
+func SQLDriverName(driver string) (string, error) {
+	dialect, err := ParseDialect(driver)
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case dialect == Postgres:
+		return PostgresDriverName, nil
+	case strings.EqualFold(driver, d1.DriverName):
+		return d1.DriverName, nil
+	}
+	return "sqlite3", nil
+}
+
+// D1DSN رابط الاتصال بقاعدة Cloudflare D1: DATABASE_URL إن كان بصيغة d1://،
+// وإلا من CLOUDFLARE_ACCOUNT_ID و CLOUDFLARE_D1_DATABASE و CLOUDFLARE_API_TOKEN
+func D1DSN(cfg *config.Config) string {
+	if strings.HasPrefix(cfg.Database.URL, "d1://") {
+		return cfg.Database.URL
+	}
+	return d1.Config{
+		AccountID: cfg.Cloudflare.AccountID,
+		Database:  cfg.Cloudflare.D1Database,
+		APIToken:  cfg.Cloudflare.APIToken,
+	}.DSN()
+}
+
+var (
+	insertOrPattern = regexp.MustCompile(`(?is)^(\s*)INSERT\s+OR\s+(IGNORE|REPLACE)\s+INTO\s+([A-Za-z_][A-Za-z0-9_.]*)\s*\(([^)]*)\)(.*)$`)
+	datetimeNow     = regexp.MustCompile(`(?i)datetime\(\s*'now'\s*\)`)
+	likeKeyword     = regexp.MustCompile(`(?i)\bLIKE\b`)
+	ddlStatement    = regexp.MustCompile(`(?is)^\s*(CREATE|ALTER)\s+TABLE\b`)
+	returningClause = regexp.MustCompile(`(?is)\bRETURNING\b`)
+
+	// أنواع SQLite ومقابلاتها في Postgres (لتعليمات CREATE/ALTER TABLE فقط)
+	postgresTypes = []struct {
+		pattern *regexp.Regexp
+		replace string
+	}{
+		{regexp.MustCompile(`(?i)\bINTEGER\s+PRIMARY\s+KEY\s+AUTOINCREMENT\b`), "BIGSERIAL PRIMARY KEY"},
+		{regexp.MustCompile(`(?i)\bDATETIME\b`), "TIMESTAMP"},
+		{regexp.MustCompile(`(?i)\bREAL\b`), "DOUBLE PRECISION"},
+		{regexp.MustCompile(`(?i)\bBLOB\b`), "BYTEA"},
+		{regexp.MustCompile(`(?i)\bINTEGER\b`), "BIGINT"},
+	}
+)
+
+// Translate تحويل استعلام مكتوب بصيغة SQLite إلى اللهجة المطلوبة
+func Translate(dialect Dialect, query string) string {
+	if dialect != Postgres {
+		return query
+	}
+
+	query = translateUpsert(query)
+	query = datetimeNow.ReplaceAllString(query, "CURRENT_TIMESTAMP")
+	if ddlStatement.MatchString(query) {
+		for _, t := range postgresTypes {
+			query = replaceOutsideQuotes(query, t.pattern, t.replace)
+		}
+	} else {
+		// LIKE في SQLite غير حساس لحالة الأحرف
+		query = replaceOutsideQuotes(query, likeKeyword, "ILIKE")
+	}
+	return Rebind(dialect, query)
+}
+
+// Rebind تحويل عناصر ? إلى $1, $2, ... في Postgres مع تجاهل النصوص المقتبسة
+func Rebind(dialect Dialect, query string) string {
+	if dialect != Postgres || !strings.Contains(query, "?") {
+		return query
+	}
+
+	var b strings.Builder
+	b.Grow(len(query) + 8)
+	n := 0
+	var quote rune
+	for _, r := range query {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"':
+			quote = r
+		case r == '?':
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// translateUpsert تحويل INSERT OR IGNORE/REPLACE إلى ON CONFLICT.
+// في REPLACE يُعتبر أول عمود في القائمة هو المفتاح الفريد.
+func translateUpsert(query string) string {
+	match := insertOrPattern.FindStringSubmatch(query)
+	if match == nil {
+		return query
+	}
+	indent, mode, table, columnList, rest := match[1], strings.ToUpper(match[2]), match[3], match[4], match[5]
+
+	// ON CONFLICT يأتي قبل RETURNING إن وجد
+	body, returning := strings.TrimRight(rest, "; \t\n"), ""
+	if loc := returningClause.FindStringIndex(body); loc != nil {
+		body, returning = strings.TrimRight(body[:loc[0]], " \t\n"), " "+body[loc[0]:]
+	}
+
+	var conflict string
+	columns := strings.Split(columnList, ",")
+	for i := range columns {
+		columns[i] = strings.TrimSpace(columns[i])
+	}
+	if mode == "IGNORE" || len(columns) < 2 {
+		conflict = " ON CONFLICT DO NOTHING"
+	} else {
+		updates := make([]string, 0, len(columns)-1)
+		for _, column := range columns[1:] {
+			updates = append(updates, fmt.Sprintf("%s = EXCLUDED.%s", column, column))
+		}
+		conflict = fmt.Sprintf(" ON CONFLICT (%s) DO UPDATE SET %s", columns[0], strings.Join(updates, ", "))
+	}
+
+	return fmt.Sprintf("%sINSERT INTO %s (%s)%s%s%s", indent, table, columnList, body, conflict, returning)
+}
+
+// replaceOutsideQuotes استبدال النمط خارج النصوص المقتبسة فقط
+func replaceOutsideQuotes(query string, pattern *regexp.Regexp, replacement string) string {
+	var b strings.Builder
+	start := 0
+	var quote byte
+	flush := func(end int) {
+		b.WriteString(pattern.ReplaceAllString(query[start:end], replacement))
+	}
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+				b.WriteString(query[start : i+1])
+				start = i + 1
+			}
+		case c == '\'' || c == '"':
+			flush(i)
+			start = i
+			quote = c
+		}
+	}
+	if quote != 0 {
+		b.WriteString(query[start:])
+	} else {
+		flush(len(query))
+	}
+	return b.String()
+}
@@ -0,0 +1,479 @@
+// Package migrations محرك ترحيل مخطط قاعدة البيانات بإصدارات مرقمة.
+//
+// كل ترحيل ملفان في sql/ باسم NNNN_name.up.sql و NNNN_name.down.sql،
+// ويمكن تخصيص لهجة بملف NNNN_name.up.postgres.sql (أو .sqlite3.sql) يحل محل
+// الملف العام لتلك اللهجة. الترحيلات المطبقة تُسجل في schema_migrations مع
+// بصمة SHA-256 لملف up، وتغيير ملف مطبق يوقف الترحيل حتى يُعالج يدوياً.
+package migrations
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed sql/*.sql
+var embedded embed.FS
+
+// Files الترحيلات المضمنة في الملف التنفيذي
+func Files() fs.FS {
+	sub, _ := fs.Sub(embedded, "sql")
+	return sub
+}
+
+// Dialect لهجة SQL المستهدفة
+type Dialect string
+
+const (
+	SQLite   Dialect = "sqlite3"
+	Postgres Dialect = "postgres"
+)
+
+// TableName جدول سجل الترحيلات المطبقة
+const TableName = "schema_migrations"
+
+// postgresLockID مفتاح القفل الاستشاري حتى لا يرحّل خادمان في نفس الوقت
+const postgresLockID = 7283190
+
+var (
+	ErrChecksumMismatch  = errors.New("applied migration has been modified")
+	ErrUnknownMigration  = errors.New("applied migration is missing from source")
+	ErrNoDownMigration   = errors.New("migration has no down script")
+	ErrUnsupportedDriver = errors.New("unsupported database driver")
+)
+
+// fileNamePattern NNNN_name.(up|down)[.dialect].sql
+var fileNamePattern = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)(?:\.(sqlite3|postgres))?\.sql$`)
+
+// Migration ترحيل واحد بعد اختيار ملفات اللهجة
+type Migration struct {
+	Version  int64
+	Name     string
+	Up       string
+	Down     string
+	Checksum string
+}
+
+// Status حالة ترحيل مقارنة بقاعدة البيانات
+type Status struct {
+	Version          int64      `json:"version"`
+	Name             string     `json:"name"`
+	Applied          bool       `json:"applied"`
+	AppliedAt        *time.Time `json:"applied_at,omitempty"`
+	ChecksumMismatch bool       `json:"checksum_mismatch,omitempty"`
+	Missing          bool       `json:"missing,omitempty"` // مطبق لكن ملفه غير موجود
+}
+
+// appliedRecord صف من schema_migrations
+type appliedRecord struct {
+	Version   int64
+	Name      string
+	Checksum  string
+	AppliedAt time.Time
+}
+
+// Migrator ينفذ الترحيلات على قاعدة بيانات بلهجة محددة
+type Migrator struct {
+	db         *sql.DB
+	dialect    Dialect
+	migrations []Migration
+}
+
+// ParseDialect تحويل اسم المشغل في الإعدادات إلى لهجة
+func ParseDialect(driver string) (Dialect, error) {
+	switch strings.ToLower(driver) {
+	case "sqlite3", "sqlite", "d1":
+		return SQLite, nil
+	case "postgres", "postgresql", "pgx":
+		return Postgres, nil
+	default:
+		return "", fmt.Errorf("%w: %s", ErrUnsupportedDriver, driver)
+	}
+}
+
+// DetectDialect استنتاج اللهجة من نوع مشغل الاتصال (SQLite افتراضياً)
+func DetectDialect(db *sql.DB) Dialect {
+	name := strings.ToLower(reflect.TypeOf(db.Driver()).String())
+	if strings.Contains(name, "pq.") || strings.Contains(name, "pgx") || strings.Contains(name, "postgres") {
+		return Postgres
+	}
+	return SQLite
+}
+
+// New إنشاء Migrator على الترحيلات المضمنة
+func New(db *sql.DB, dialect Dialect) (*Migrator, error) {
+	return NewWithSource(db, dialect, Files())
+}
+
+// NewWithSource إنشاء Migrator على مصدر ترحيلات محدد
+func NewWithSource(db *sql.DB, dialect Dialect, source fs.FS) (*Migrator, error) {
+	migrations, err := Load(source, dialect)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, dialect: dialect, migrations: migrations}, nil
+}
+
+// Load قراءة الترحيلات من المصدر واختيار ملفات اللهجة، مرتبة حسب الإصدار
+func Load(source fs.FS, dialect Dialect) ([]Migration, error) {
+	entries, err := fs.ReadDir(source, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	type scripts struct {
+		name                   string
+		up, down               string
+		upDialect, downDialect bool
+	}
+	byVersion := make(map[int64]*scripts)
+
+	for _, entry := range entries {
+		match := fileNamePattern.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+		fileDialect := Dialect(match[4])
+		if fileDialect != "" && fileDialect != dialect {
+			continue
+		}
+
+		version, _ := strconv.ParseInt(match[1], 10, 64)
+		s, ok := byVersion[version]
+		if !ok {
+			s = &scripts{name: match[2]}
+			byVersion[version] = s
+		}
+		if s.name != match[2] {
+			return nil, fmt.Errorf("migration %d has conflicting names: %s and %s", version, s.name, match[2])
+		}
+
+		data, err := fs.ReadFile(source, entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", entry.Name(), err)
+		}
+
+		// ملف اللهجة يحل محل العام بغض النظر عن ترتيب القراءة
+		specific := fileDialect != ""
+		switch match[3] {
+		case "up":
+			if specific || !s.upDialect {
+				s.up, s.upDialect = string(data), specific
+			}
+		case "down":
+			if specific || !s.downDialect {
+				s.down, s.downDialect = string(data), specific
+			}
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for version, s := range byVersion {
+		if strings.TrimSpace(s.up) == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up script", version, s.name)
+		}
+		migrations = append(migrations, Migration{
+			Version:  version,
+			Name:     s.name,
+			Up:       s.up,
+			Down:     s.down,
+			Checksum: checksum(s.up),
+		})
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Migrations الترحيلات المعروفة
+func (m *Migrator) Migrations() []Migration {
+	return m.migrations
+}
+
+// Up تطبيق كل الترحيلات المعلقة بالترتيب، كل منها في معاملة مستقلة
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var applied []Migration
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		records, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if err := m.verify(records); err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			if _, done := records[migration.Version]; done {
+				continue
+			}
+			if err := m.apply(ctx, conn, migration); err != nil {
+				return err
+			}
+			applied = append(applied, migration)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down التراجع عن آخر steps ترحيلات مطبقة (الأحدث أولاً)
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var reverted []Migration
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		records, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		versions := make([]int64, 0, len(records))
+		for version := range records {
+			versions = append(versions, version)
+		}
+		sort.Slice(versions, func(i, j int) bool { return versions[i] > versions[j] })
+		if steps > 0 && steps < len(versions) {
+			versions = versions[:steps]
+		}
+
+		for _, version := range versions {
+			migration, ok := m.find(version)
+			if !ok {
+				return fmt.Errorf("%w: %d_%s", ErrUnknownMigration, version, records[version].Name)
+			}
+			if migration.Checksum != records[version].Checksum {
+				return fmt.Errorf("%w: %d_%s", ErrChecksumMismatch, version, migration.Name)
+			}
+			if strings.TrimSpace(migration.Down) == "" {
+				return fmt.Errorf("%w: %d_%s", ErrNoDownMigration, version, migration.Name)
+			}
+			if err := m.revert(ctx, conn, migration); err != nil {
+				return err
+			}
+			reverted = append(reverted, migration)
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// Status حالة كل ترحيل، بما فيها المطبقة التي لم تعد ملفاتها موجودة
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer conn.Close()
+
+	if err := m.ensureTable(ctx, conn); err != nil {
+		return nil, err
+	}
+	records, err := m.applied(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		status := Status{Version: migration.Version, Name: migration.Name}
+		if record, ok := records[migration.Version]; ok {
+			appliedAt := record.AppliedAt
+			status.Applied = true
+			status.AppliedAt = &appliedAt
+			status.ChecksumMismatch = record.Checksum != migration.Checksum
+			delete(records, migration.Version)
+		}
+		statuses = append(statuses, status)
+	}
+	for _, record := range records {
+		appliedAt := record.AppliedAt
+		statuses = append(statuses, Status{
+			Version:   record.Version,
+			Name:      record.Name,
+			Applied:   true,
+			AppliedAt: &appliedAt,
+			Missing:   true,
+		})
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses, nil
+}
+
+// Create إنشاء ملفي ترحيل جديدين في dir بالإصدار التالي، ويعيد مساريهما
+func Create(dir, name string) (string, string, error) {
+	name = strings.Trim(regexp.MustCompile(`[^a-z0-9]+`).ReplaceAllString(strings.ToLower(name), "_"), "_")
+	if name == "" {
+		return "", "", errors.New("migration name is required")
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read migrations directory: %w", err)
+	}
+	var next int64 = 1
+	for _, entry := range entries {
+		if match := fileNamePattern.FindStringSubmatch(entry.Name()); match != nil {
+			if version, _ := strconv.ParseInt(match[1], 10, 64); version >= next {
+				next = version + 1
+			}
+		}
+	}
+
+	base := fmt.Sprintf("%04d_%s", next, name)
+	up := filepath.Join(dir, base+".up.sql")
+	down := filepath.Join(dir, base+".down.sql")
+	header := "-- " + base + "\n"
+	if err := os.WriteFile(up, []byte(header+"\n"), 0644); err != nil {
+		return "", "", fmt.Errorf("failed to create migration: %w", err)
+	}
+	if err := os.WriteFile(down, []byte(header+"\n"), 0644); err != nil {
+		os.Remove(up)
+		return "", "", fmt.Errorf("failed to create migration: %w", err)
+	}
+	return up, down, nil
+}
+
+// ================================
+// التنفيذ
+// ================================
+
+// withLock تنفيذ fn على اتصال واحد، مع قفل استشاري في Postgres
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer conn.Close()
+
+	if m.dialect == Postgres {
+		if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", postgresLockID); err != nil {
+			return fmt.Errorf("failed to acquire migration lock: %w", err)
+		}
+		defer conn.ExecContext(context.WithoutCancel(ctx), "SELECT pg_advisory_unlock($1)", postgresLockID)
+	}
+
+	if err := m.ensureTable(ctx, conn); err != nil {
+		return err
+	}
+	return fn(conn)
+}
+
+func (m *Migrator) ensureTable(ctx context.Context, conn *sql.Conn) error {
+	query := `CREATE TABLE IF NOT EXISTS ` + TableName + ` (
+		version BIGINT PRIMARY KEY,
+		name TEXT NOT NULL,
+		checksum TEXT NOT NULL,
+		applied_at TIMESTAMP NOT NULL
+	)`
+	if _, err := conn.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("failed to create %s: %w", TableName, err)
+	}
+	return nil
+}
+
+func (m *Migrator) applied(ctx context.Context, conn *sql.Conn) (map[int64]appliedRecord, error) {
+	rows, err := conn.QueryContext(ctx, `SELECT version, name, checksum, applied_at FROM `+TableName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", TableName, err)
+	}
+	defer rows.Close()
+
+	records := make(map[int64]appliedRecord)
+	for rows.Next() {
+		var record appliedRecord
+		if err := rows.Scan(&record.Version, &record.Name, &record.Checksum, &record.AppliedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan %s: %w", TableName, err)
+		}
+		records[record.Version] = record
+	}
+	return records, rows.Err()
+}
+
+// verify رفض المتابعة إذا عُدل ملف ترحيل مطبق
+func (m *Migrator) verify(records map[int64]appliedRecord) error {
+	var changed []string
+	for _, migration := range m.migrations {
+		if record, ok := records[migration.Version]; ok && record.Checksum != migration.Checksum {
+			changed = append(changed, fmt.Sprintf("%d_%s", migration.Version, migration.Name))
+		}
+	}
+	if len(changed) > 0 {
+		return fmt.Errorf("%w: %s", ErrChecksumMismatch, strings.Join(changed, ", "))
+	}
+	return nil
+}
+
+func (m *Migrator) apply(ctx context.Context, conn *sql.Conn, migration Migration) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin migration %d: %w", migration.Version, err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, migration.Up); err != nil {
+		return fmt.Errorf("failed to apply migration %d_%s: %w", migration.Version, migration.Name, err)
+	}
+	insert := m.bind(`INSERT INTO ` + TableName + ` (version, name, checksum, applied_at) VALUES (?, ?, ?, ?)`)
+	if _, err := tx.ExecContext(ctx, insert, migration.Version, migration.Name, migration.Checksum, time.Now().UTC()); err != nil {
+		return fmt.Errorf("failed to record migration %d: %w", migration.Version, err)
+	}
+	return tx.Commit()
+}
+
+func (m *Migrator) revert(ctx context.Context, conn *sql.Conn, migration Migration) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin rollback %d: %w", migration.Version, err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, migration.Down); err != nil {
+		return fmt.Errorf("failed to revert migration %d_%s: %w", migration.Version, migration.Name, err)
+	}
+	if _, err := tx.ExecContext(ctx, m.bind(`DELETE FROM `+TableName+` WHERE version = ?`), migration.Version); err != nil {
+		return fmt.Errorf("failed to unrecord migration %d: %w", migration.Version, err)
+	}
+	return tx.Commit()
+}
+
+func (m *Migrator) find(version int64) (Migration, bool) {
+	for _, migration := range m.migrations {
+		if migration.Version == version {
+			return migration, true
+		}
+	}
+	return Migration{}, false
+}
+
+// bind تحويل العناصر النائبة ? إلى $n في Postgres
+func (m *Migrator) bind(query string) string {
+	if m.dialect != Postgres {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func checksum(script string) string {
+	sum := sha256.Sum256([]byte(script))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,157 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestEmbeddedMigrationsUpDown(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+
+	m, err := New(db, SQLite)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	applied, err := m.Up(ctx)
+	if err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+	if len(applied) != len(m.Migrations()) {
+		t.Fatalf("expected %d migrations applied, got %d", len(m.Migrations()), len(applied))
+	}
+	if again, err := m.Up(ctx); err != nil || len(again) != 0 {
+		t.Fatalf("second Up should be a no-op: %v, %d", err, len(again))
+	}
+
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM scheduled_checks").Scan(&count); err != nil || count != 4 {
+		t.Errorf("expected seeded health checks, got %d (%v)", count, err)
+	}
+
+	if _, err := m.Down(ctx, 0); err != nil {
+		t.Fatalf("Down: %v", err)
+	}
+	if err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'users'").Scan(&count); err != nil || count != 0 {
+		t.Errorf("expected users table to be dropped, got %d (%v)", count, err)
+	}
+}
+
+// TestUpgradeLegacySchema قاعدة أنشأها CreateTablesSQL قبل نظام الترحيل تحصل على الأعمدة الجديدة
+func TestUpgradeLegacySchema(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+
+	for _, stmt := range []string{
+		`CREATE TABLE users (id TEXT PRIMARY KEY, email TEXT UNIQUE NOT NULL, username TEXT UNIQUE NOT NULL,
+			password_hash TEXT NOT NULL, first_name TEXT NOT NULL, last_name TEXT NOT NULL, phone TEXT, avatar TEXT,
+			role TEXT DEFAULT 'user', status TEXT DEFAULT 'active', email_verified BOOLEAN DEFAULT FALSE,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP, updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP)`,
+		`CREATE TABLE files (id TEXT PRIMARY KEY, user_id TEXT NOT NULL, name TEXT NOT NULL, url TEXT NOT NULL,
+			size INTEGER, type TEXT, created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP)`,
+		`INSERT INTO users (id, email, username, password_hash, first_name, last_name) VALUES ('u1', 'a@example.com', 'a', 'x', 'A', 'B')`,
+		`INSERT INTO files (id, user_id, name, url, size, type) VALUES ('f1', 'u1', 'a.txt', '/a.txt', 12, 'text/plain')`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("legacy schema: %v", err)
+		}
+	}
+
+	m, err := New(db, SQLite)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+
+	var tier, scanStatus string
+	if err := db.QueryRow("SELECT tier FROM users WHERE id = 'u1'").Scan(&tier); err != nil || tier != "free" {
+		t.Errorf("expected users.tier = free, got %q (%v)", tier, err)
+	}
+	if err := db.QueryRow("SELECT scan_status FROM files WHERE id = 'f1'").Scan(&scanStatus); err != nil || scanStatus != "pending" {
+		t.Errorf("expected files.scan_status = pending, got %q (%v)", scanStatus, err)
+	}
+	if _, err := db.Exec("SELECT deleted_at, orphaned_at, referenced_at FROM files"); err != nil {
+		t.Errorf("expected file lifecycle columns: %v", err)
+	}
+	var used, count int64
+	if err := db.QueryRow("SELECT used_bytes, file_count FROM storage_usage WHERE user_id = 'u1'").Scan(&used, &count); err != nil || used != 12 || count != 1 {
+		t.Errorf("expected backfilled storage usage 12/1, got %d/%d (%v)", used, count, err)
+	}
+}
+
+func TestDialectOverridesAndChecksums(t *testing.T) {
+	source := fstest.MapFS{
+		"0001_items.up.sql":          {Data: []byte("CREATE TABLE items (id INTEGER);")},
+		"0001_items.up.postgres.sql": {Data: []byte("CREATE TABLE items (id BIGINT);")},
+		"0001_items.down.sql":        {Data: []byte("DROP TABLE items;")},
+		"0002_tags.up.sql":           {Data: []byte("CREATE TABLE tags (name TEXT);")},
+		"README.md":                  {Data: []byte("ignored")},
+	}
+
+	pg, err := Load(source, Postgres)
+	if err != nil || len(pg) != 2 || pg[0].Up != "CREATE TABLE items (id BIGINT);" {
+		t.Fatalf("expected postgres override, got %+v (%v)", pg, err)
+	}
+
+	db := openTestDB(t)
+	ctx := context.Background()
+	m, _ := NewWithSource(db, SQLite, source)
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+
+	// 0002 بلا down لا يمكن التراجع عنه
+	if _, err := m.Down(ctx, 1); !errors.Is(err, ErrNoDownMigration) {
+		t.Errorf("expected ErrNoDownMigration, got %v", err)
+	}
+
+	// تعديل ملف مطبق يوقف الترحيل ويظهر في الحالة
+	source["0001_items.up.sql"] = &fstest.MapFile{Data: []byte("CREATE TABLE items (id TEXT);")}
+	m, _ = NewWithSource(db, SQLite, source)
+	if _, err := m.Up(ctx); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("expected ErrChecksumMismatch, got %v", err)
+	}
+	statuses, err := m.Status(ctx)
+	if err != nil || len(statuses) != 2 || !statuses[0].ChecksumMismatch || !statuses[1].Applied {
+		t.Errorf("unexpected status: %+v (%v)", statuses, err)
+	}
+}
+
+func TestCreateNextVersion(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "0007_existing.up.sql"), []byte(""), 0644)
+
+	up, down, err := Create(dir, "Add Orders Index")
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if filepath.Base(up) != "0008_add_orders_index.up.sql" || filepath.Base(down) != "0008_add_orders_index.down.sql" {
+		t.Errorf("unexpected file names: %s, %s", up, down)
+	}
+}
+
+func TestPostgresPlaceholders(t *testing.T) {
+	m := &Migrator{dialect: Postgres}
+	if got := m.bind("INSERT INTO t VALUES (?, ?)"); got != "INSERT INTO t VALUES ($1, $2)" {
+		t.Errorf("unexpected query: %s", got)
+	}
+}
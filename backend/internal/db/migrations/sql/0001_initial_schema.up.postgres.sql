@@ -0,0 +1,156 @@
+-- المخطط الأساسي (Postgres): المستخدمون والكتالوج والطلبات والملفات.
+-- يطابق المخطط الذي كان ينشئه CreateTablesSQL قبل نظام الترحيل، ويستخدم IF NOT EXISTS حتى
+-- يُطبق بأمان على تلك القواعد؛ الأعمدة والجداول الأحدث تضيفها ترحيلات لاحقة بـ ALTER TABLE.
+
+-- جدول المستخدمين
+CREATE TABLE IF NOT EXISTS users (
+	id TEXT PRIMARY KEY,
+	email TEXT UNIQUE NOT NULL,
+	username TEXT UNIQUE NOT NULL,
+	password_hash TEXT NOT NULL,
+	first_name TEXT NOT NULL,
+	last_name TEXT NOT NULL,
+	phone TEXT,
+	avatar TEXT,
+	role TEXT DEFAULT 'user',
+	status TEXT DEFAULT 'active',
+	email_verified BOOLEAN DEFAULT FALSE,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	last_login TIMESTAMP
+);
+
+-- جدول الفئات
+CREATE TABLE IF NOT EXISTS categories (
+	id TEXT PRIMARY KEY,
+	name TEXT NOT NULL,
+	slug TEXT UNIQUE NOT NULL,
+	image TEXT,
+	description TEXT,
+	parent_id TEXT,
+	is_active BOOLEAN DEFAULT TRUE,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (parent_id) REFERENCES categories(id) ON DELETE SET NULL
+);
+
+-- جدول الخدمات
+CREATE TABLE IF NOT EXISTS services (
+	id TEXT PRIMARY KEY,
+	title TEXT NOT NULL,
+	description TEXT NOT NULL,
+	price DOUBLE PRECISION NOT NULL,
+	duration BIGINT NOT NULL,
+	category_id TEXT NOT NULL,
+	provider_id TEXT NOT NULL,
+	images TEXT DEFAULT '[]',
+	tags TEXT DEFAULT '[]',
+	is_active BOOLEAN DEFAULT TRUE,
+	is_featured BOOLEAN DEFAULT FALSE,
+	rating DOUBLE PRECISION DEFAULT 0,
+	review_count BIGINT DEFAULT 0,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (category_id) REFERENCES categories(id) ON DELETE CASCADE,
+	FOREIGN KEY (provider_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- جدول الطلبات
+CREATE TABLE IF NOT EXISTS orders (
+	id TEXT PRIMARY KEY,
+	user_id TEXT NOT NULL,
+	service_id TEXT NOT NULL,
+	status TEXT DEFAULT 'pending',
+	amount DOUBLE PRECISION NOT NULL,
+	notes TEXT,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+	FOREIGN KEY (service_id) REFERENCES services(id) ON DELETE CASCADE
+);
+
+-- جدول المدفوعات
+CREATE TABLE IF NOT EXISTS payments (
+	id TEXT PRIMARY KEY,
+	order_id TEXT NOT NULL,
+	amount DOUBLE PRECISION NOT NULL,
+	currency TEXT DEFAULT 'USD',
+	status TEXT DEFAULT 'pending',
+	payment_method TEXT,
+	transaction_id TEXT UNIQUE,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE CASCADE
+);
+
+-- جدول الإشعارات
+CREATE TABLE IF NOT EXISTS notifications (
+	id TEXT PRIMARY KEY,
+	user_id TEXT NOT NULL,
+	title TEXT NOT NULL,
+	message TEXT NOT NULL,
+	type TEXT DEFAULT 'info',
+	is_read BOOLEAN DEFAULT FALSE,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- جدول الملفات
+CREATE TABLE IF NOT EXISTS files (
+	id TEXT PRIMARY KEY,
+	user_id TEXT NOT NULL,
+	name TEXT NOT NULL,
+	url TEXT NOT NULL,
+	size BIGINT,
+	type TEXT,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- جدول السجلات
+CREATE TABLE IF NOT EXISTS system_logs (
+	id TEXT PRIMARY KEY,
+	user_id TEXT,
+	level TEXT NOT NULL,
+	action TEXT NOT NULL,
+	resource TEXT,
+	details TEXT,
+	ip_address TEXT,
+	user_agent TEXT,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- جدول الجلسات
+CREATE TABLE IF NOT EXISTS sessions (
+	id TEXT PRIMARY KEY,
+	user_id TEXT NOT NULL,
+	token TEXT NOT NULL,
+	expires_at TIMESTAMP NOT NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- سجل التدقيق
+CREATE TABLE IF NOT EXISTS audit_logs (
+	id TEXT PRIMARY KEY,
+	user_id TEXT,
+	action TEXT NOT NULL,
+	resource TEXT,
+	details TEXT DEFAULT '{}',
+	ip_address TEXT,
+	user_agent TEXT,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL
+);
+
+-- الفهارس
+CREATE INDEX IF NOT EXISTS idx_users_status ON users(status);
+CREATE INDEX IF NOT EXISTS idx_services_category ON services(category_id);
+CREATE INDEX IF NOT EXISTS idx_services_provider ON services(provider_id);
+CREATE INDEX IF NOT EXISTS idx_orders_user ON orders(user_id);
+CREATE INDEX IF NOT EXISTS idx_notifications_user ON notifications(user_id);
+CREATE INDEX IF NOT EXISTS idx_files_user ON files(user_id);
+CREATE INDEX IF NOT EXISTS idx_sessions_token ON sessions(token);
+CREATE INDEX IF NOT EXISTS idx_sessions_user ON sessions(user_id);
+CREATE INDEX IF NOT EXISTS idx_sessions_expires ON sessions(expires_at);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_created ON audit_logs(created_at);
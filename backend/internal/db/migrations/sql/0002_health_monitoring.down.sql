@@ -0,0 +1,18 @@
+-- حذف جداول مراقبة الصحة
+DROP TABLE IF EXISTS business_metrics;
+DROP TABLE IF EXISTS application_errors;
+DROP TABLE IF EXISTS database_health;
+DROP TABLE IF EXISTS uptime_records;
+DROP TABLE IF EXISTS version_updates;
+DROP TABLE IF EXISTS security_checks;
+DROP TABLE IF EXISTS resource_usage;
+DROP TABLE IF EXISTS performance_reports;
+DROP TABLE IF EXISTS service_dependencies;
+DROP TABLE IF EXISTS scheduled_checks;
+DROP TABLE IF EXISTS realtime_metrics;
+DROP TABLE IF EXISTS system_alerts;
+DROP TABLE IF EXISTS service_availability;
+DROP TABLE IF EXISTS api_statistics;
+DROP TABLE IF EXISTS system_events;
+DROP TABLE IF EXISTS performance_metrics;
+DROP TABLE IF EXISTS health_logs;
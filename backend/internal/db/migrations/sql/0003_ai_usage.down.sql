@@ -0,0 +1,4 @@
+-- حذف جداول استخدام الذكاء الاصطناعي
+DROP TABLE IF EXISTS ai_budget_alerts;
+DROP TABLE IF EXISTS ai_budgets;
+DROP TABLE IF EXISTS ai_usage_records;
@@ -0,0 +1,61 @@
+-- سجلات استخدام الذكاء الاصطناعي والميزانيات وتنبيهاتها (Postgres).
+
+-- جدول سجلات الاستخدام: صف لكل استدعاء، والتكاليف تُجمع منه عند الطلب
+CREATE TABLE IF NOT EXISTS ai_usage_records (
+	id TEXT PRIMARY KEY,
+	user_id TEXT,
+	user_tier TEXT,
+	org_id TEXT,
+	provider TEXT NOT NULL,
+	request_type TEXT NOT NULL,
+	cost DOUBLE PRECISION NOT NULL DEFAULT 0,
+	quantity BIGINT NOT NULL DEFAULT 0,
+	latency_ms DOUBLE PRECISION NOT NULL DEFAULT 0,
+	success BOOLEAN DEFAULT TRUE,
+	cache_hit BOOLEAN DEFAULT FALSE,
+	saved_cost DOUBLE PRECISION NOT NULL DEFAULT 0,
+	error TEXT,
+	metadata TEXT DEFAULT '{}',
+	created_at TIMESTAMP NOT NULL
+);
+
+-- جدول الميزانيات: النطاق global أو user أو tier أو org، والفترة daily أو monthly.
+-- ميزانية الطبقة تحد كل مستخدم فيها ما لم تكن له ميزانية خاصة
+CREATE TABLE IF NOT EXISTS ai_budgets (
+	id TEXT PRIMARY KEY,
+	scope TEXT NOT NULL,
+	scope_id TEXT NOT NULL DEFAULT '',
+	period TEXT NOT NULL,
+	limit_amount DOUBLE PRECISION NOT NULL,
+	enforcement TEXT NOT NULL DEFAULT 'soft',
+	is_active BOOLEAN DEFAULT TRUE,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE(scope, scope_id, period)
+);
+
+-- جدول التنبيهات المرسلة: القيد الفريد يضمن تنبيهاً واحداً لكل عتبة في الفترة
+-- مهما تعددت نسخ الخادم
+CREATE TABLE IF NOT EXISTS ai_budget_alerts (
+	id TEXT PRIMARY KEY,
+	budget_id TEXT NOT NULL,
+	subject_id TEXT NOT NULL DEFAULT '',
+	period_key TEXT NOT NULL,
+	threshold BIGINT NOT NULL,
+	spent DOUBLE PRECISION NOT NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE(budget_id, subject_id, period_key, threshold)
+);
+
+-- الفهارس
+CREATE INDEX IF NOT EXISTS idx_ai_usage_created ON ai_usage_records(created_at);
+CREATE INDEX IF NOT EXISTS idx_ai_usage_user_date ON ai_usage_records(user_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_ai_usage_org_date ON ai_usage_records(org_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_ai_usage_provider_date ON ai_usage_records(provider, created_at);
+
+-- حدود الطبقات الشهرية الافتراضية لكل مستخدم
+INSERT INTO ai_budgets (id, scope, scope_id, period, limit_amount, enforcement) VALUES
+	('budget_tier_premium_monthly', 'tier', 'premium', 'monthly', 100, 'hard'),
+	('budget_tier_basic_monthly', 'tier', 'basic', 'monthly', 10, 'hard'),
+	('budget_tier_free_monthly', 'tier', 'free', 'monthly', 1, 'hard')
+ON CONFLICT DO NOTHING;
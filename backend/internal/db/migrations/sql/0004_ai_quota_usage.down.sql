@@ -0,0 +1,2 @@
+-- حذف دفتر حصص الذكاء الاصطناعي
+DROP TABLE IF EXISTS ai_quota_usage;
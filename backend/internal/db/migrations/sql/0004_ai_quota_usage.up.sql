@@ -0,0 +1,11 @@
+-- دفتر حصص الذكاء الاصطناعي: المستهلك والمحجوز لكل مستخدم ونوع وفترة.
+-- الحجز تحديث شرطي واحد فلا تتجاوز نسخ الخادم المتزامنة الحد
+CREATE TABLE IF NOT EXISTS ai_quota_usage (
+	user_id TEXT NOT NULL,
+	quota_type TEXT NOT NULL,
+	period_key TEXT NOT NULL,
+	used INTEGER NOT NULL DEFAULT 0,
+	reserved INTEGER NOT NULL DEFAULT 0,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (user_id, quota_type, period_key)
+);
@@ -0,0 +1,2 @@
+-- حذف تتبع ارتباط الملفات
+ALTER TABLE files DROP COLUMN referenced_at;
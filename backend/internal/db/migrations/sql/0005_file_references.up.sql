@@ -0,0 +1,3 @@
+-- تتبع ارتباط الملفات: referenced_at أول مرة وُجد فيها الملف مرتبطاً بسجل،
+-- فلا يعتبر يتيماً إلا ملف فقد ارتباطاً كان له (الملفات غير المرفقة أبداً تبقى للمستخدم)
+ALTER TABLE files ADD COLUMN referenced_at TIMESTAMP;
@@ -0,0 +1,12 @@
+-- حذف جداول الرفع وأعمدة دورة حياة الملفات
+DROP TABLE IF EXISTS image_derivatives;
+DROP TABLE IF EXISTS resumable_uploads;
+DROP TABLE IF EXISTS upload_tokens;
+DROP TABLE IF EXISTS storage_usage;
+ALTER TABLE files DROP COLUMN deleted_at;
+ALTER TABLE files DROP COLUMN orphaned_at;
+ALTER TABLE files DROP COLUMN scanned_at;
+ALTER TABLE files DROP COLUMN scan_reason;
+ALTER TABLE files DROP COLUMN scan_status;
+ALTER TABLE orders DROP COLUMN attachments;
+ALTER TABLE users DROP COLUMN tier;
@@ -0,0 +1,65 @@
+-- دورة حياة الملفات والرفع وطبقات المستخدمين على قواعد أُنشئت بالمخطط الأساسي.
+-- الملفات الموجودة تبدأ بحالة pending فيعيد الفاحص الدوري فحصها قبل نشرها.
+ALTER TABLE users ADD COLUMN tier TEXT DEFAULT 'free';
+ALTER TABLE orders ADD COLUMN attachments TEXT DEFAULT '[]';
+ALTER TABLE files ADD COLUMN scan_status TEXT DEFAULT 'pending';
+ALTER TABLE files ADD COLUMN scan_reason TEXT;
+ALTER TABLE files ADD COLUMN scanned_at TIMESTAMP;
+ALTER TABLE files ADD COLUMN orphaned_at TIMESTAMP;
+ALTER TABLE files ADD COLUMN deleted_at TIMESTAMP;
+
+-- جدول استهلاك التخزين لكل مستخدم
+CREATE TABLE IF NOT EXISTS storage_usage (
+	user_id TEXT PRIMARY KEY,
+	used_bytes BIGINT NOT NULL DEFAULT 0,
+	file_count BIGINT NOT NULL DEFAULT 0,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- جدول توكنات الرفع المباشر
+CREATE TABLE IF NOT EXISTS upload_tokens (
+	token TEXT PRIMARY KEY,
+	user_id TEXT NOT NULL,
+	file_id TEXT NOT NULL,
+	name TEXT NOT NULL,
+	type TEXT NOT NULL,
+	size BIGINT NOT NULL,
+	expires_at TIMESTAMP NOT NULL,
+	uploaded_at TIMESTAMP,
+	used_at TIMESTAMP,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- جدول الرفع المجزأ القابل للاستئناف
+CREATE TABLE IF NOT EXISTS resumable_uploads (
+	id TEXT PRIMARY KEY,
+	user_id TEXT NOT NULL,
+	name TEXT NOT NULL,
+	type TEXT NOT NULL,
+	length BIGINT NOT NULL,
+	upload_offset BIGINT NOT NULL DEFAULT 0,
+	chunks TEXT DEFAULT '[]',
+	status TEXT DEFAULT 'uploading',
+	file_id TEXT,
+	expires_at TIMESTAMP NOT NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- جدول مشتقات الصور المخزنة
+CREATE TABLE IF NOT EXISTS image_derivatives (
+	key TEXT PRIMARY KEY,
+	file_id TEXT NOT NULL,
+	content_type TEXT NOT NULL,
+	etag TEXT NOT NULL,
+	size BIGINT NOT NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (file_id) REFERENCES files(id) ON DELETE CASCADE
+);
+
+-- احتساب استهلاك الملفات الموجودة مسبقاً
+INSERT INTO storage_usage (user_id, used_bytes, file_count)
+SELECT user_id, COALESCE(SUM(size), 0), COUNT(*) FROM files GROUP BY user_id;
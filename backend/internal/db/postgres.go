@@ -0,0 +1,179 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"net/url"
+	"strings"
+
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/nawthtech/nawthtech/backend/internal/config"
+)
+
+// PostgresDriverName اسم مشغل Postgres المسجل في database/sql.
+// يغلف pgx ويمرر كل استعلام عبر Translate قبل تنفيذه.
+const PostgresDriverName = "nawthtech-postgres"
+
+func init() {
+	sql.Register(PostgresDriverName, &postgresDriver{base: stdlib.GetDefaultDriver()})
+}
+
+// PostgresDSN بناء رابط الاتصال من الإعدادات: DATABASE_URL إن كان رابط Postgres،
+// وإلا من DB_HOST و DB_PORT و DB_NAME و DB_USER و DB_PASSWORD و DB_SSL_MODE
+func PostgresDSN(cfg *config.Config) string {
+	dsn := cfg.Database.URL
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") || strings.Contains(dsn, "host=") {
+		return dsn
+	}
+
+	host := cfg.Database.Host
+	if host == "" {
+		host = "localhost"
+	}
+	port := cfg.Database.Port
+	if port == "" {
+		port = "5432"
+	}
+	u := &url.URL{
+		Scheme: "postgres",
+		Host:   host + ":" + port,
+		Path:   "/" + cfg.Database.Name,
+	}
+	if cfg.Database.User != "" {
+		u.User = url.UserPassword(cfg.Database.User, cfg.Database.Password)
+	}
+	if cfg.Database.SSLMode != "" {
+		u.RawQuery = url.Values{"sslmode": {cfg.Database.SSLMode}}.Encode()
+	}
+	return u.String()
+}
+
+// ================================
+// مغلف المشغل
+// ================================
+
+type postgresDriver struct {
+	base driver.Driver
+}
+
+func (d *postgresDriver) Open(name string) (driver.Conn, error) {
+	conn, err := d.base.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &postgresConn{Conn: conn}, nil
+}
+
+func (d *postgresDriver) OpenConnector(name string) (driver.Connector, error) {
+	base, ok := d.base.(driver.DriverContext)
+	if !ok {
+		return &postgresConnector{driver: d, name: name}, nil
+	}
+	connector, err := base.OpenConnector(name)
+	if err != nil {
+		return nil, err
+	}
+	return &postgresConnector{driver: d, base: connector}, nil
+}
+
+type postgresConnector struct {
+	driver *postgresDriver
+	base   driver.Connector
+	name   string
+}
+
+func (c *postgresConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	if c.base == nil {
+		return c.driver.Open(c.name)
+	}
+	conn, err := c.base.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &postgresConn{Conn: conn}, nil
+}
+
+func (c *postgresConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+// postgresConn يترجم الاستعلامات ويمرر الباقي إلى اتصال pgx
+type postgresConn struct {
+	driver.Conn
+}
+
+func translate(query string) string {
+	return Translate(Postgres, query)
+}
+
+func (c *postgresConn) Prepare(query string) (driver.Stmt, error) {
+	return c.Conn.Prepare(translate(query))
+}
+
+func (c *postgresConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if conn, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		return conn.PrepareContext(ctx, translate(query))
+	}
+	return c.Conn.Prepare(translate(query))
+}
+
+func (c *postgresConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	conn, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	return conn.ExecContext(ctx, translate(query), args)
+}
+
+func (c *postgresConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	conn, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	return conn.QueryContext(ctx, translate(query), args)
+}
+
+func (c *postgresConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if conn, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return conn.BeginTx(ctx, opts)
+	}
+	return c.Conn.Begin()
+}
+
+func (c *postgresConn) Ping(ctx context.Context) error {
+	if conn, ok := c.Conn.(driver.Pinger); ok {
+		return conn.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *postgresConn) CheckNamedValue(value *driver.NamedValue) error {
+	if conn, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return conn.CheckNamedValue(value)
+	}
+	return driver.ErrSkip
+}
+
+func (c *postgresConn) ResetSession(ctx context.Context) error {
+	if conn, ok := c.Conn.(driver.SessionResetter); ok {
+		return conn.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *postgresConn) IsValid() bool {
+	if conn, ok := c.Conn.(driver.Validator); ok {
+		return conn.IsValid()
+	}
+	return true
+}
+
+var (
+	_ driver.DriverContext      = (*postgresDriver)(nil)
+	_ driver.ExecerContext      = (*postgresConn)(nil)
+	_ driver.QueryerContext     = (*postgresConn)(nil)
+	_ driver.ConnPrepareContext = (*postgresConn)(nil)
+	_ driver.ConnBeginTx        = (*postgresConn)(nil)
+	_ driver.NamedValueChecker  = (*postgresConn)(nil)
+)
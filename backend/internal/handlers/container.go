@@ -1,17 +1,19 @@
 package handlers
 
 import (
-	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/nawthtech/nawthtech/backend/internal/ai"
-	"github.com/nawthtech/nawthtech/backend/internal/models"
+	"github.com/nawthtech/nawthtech/backend/internal/ai/types"
+	"github.com/nawthtech/nawthtech/backend/internal/cache"
+	"github.com/nawthtech/nawthtech/backend/internal/email"
 	"github.com/nawthtech/nawthtech/backend/internal/services"
 	"github.com/nawthtech/nawthtech/backend/internal/utils"
 )
@@ -55,6 +57,12 @@ type UploadHandler struct {
 	service services.UploadService
 }
 
+// ImageHandler معالجة طلبات الصور المشتقة
+type ImageHandler struct {
+	service services.ImageService
+	files   services.UploadService
+}
+
 // NotificationHandler معالجة طلبات الإشعارات
 type NotificationHandler struct {
 	service services.NotificationService
@@ -88,25 +96,28 @@ type HandlerContainer struct {
 	Order        *OrderHandler
 	Payment      *PaymentHandler
 	Upload       *UploadHandler
+	Image        *ImageHandler
 	Notification *NotificationHandler
 	Admin        *AdminHandler
 	Health       *HealthHandler
 	AI           *AIHandler
 	Email        *EmailHandler
+	// CatalogCache يستخدمه وسيط ذاكرة الاستجابات لمسارات الكتالوج (nil = بلا تخزين)
+	CatalogCache *cache.ReadThrough
 }
 
 // NewHandlerContainer إنشاء حاوية handlers جديدة
 func NewHandlerContainer(serviceContainer *services.ServiceContainer) *HandlerContainer {
 	container := &HandlerContainer{}
- aiClient, err := ai.NewClient()
+	aiClient, err := newAIClient(serviceContainer)
 	if err == nil {
 		container.AI = &AIHandler{aiClient: aiClient}
 		log.Println("✅ AI Client initialized")
 	} else {
 		log.Printf("⚠️ Failed to initialize AI Client: %v", err)
 	}
+
 	if serviceContainer != nil {
-	}
 		if serviceContainer.Auth != nil {
 			container.Auth = &AuthHandler{service: serviceContainer.Auth}
 		}
@@ -119,6 +130,7 @@ func NewHandlerContainer(serviceContainer *services.ServiceContainer) *HandlerCo
 		if serviceContainer.Category != nil {
 			container.Category = &CategoryHandler{service: serviceContainer.Category}
 		}
+		container.CatalogCache = serviceContainer.CatalogCache
 		if serviceContainer.Order != nil {
 			container.Order = &OrderHandler{service: serviceContainer.Order}
 		}
@@ -128,6 +140,9 @@ func NewHandlerContainer(serviceContainer *services.ServiceContainer) *HandlerCo
 		if serviceContainer.Upload != nil {
 			container.Upload = &UploadHandler{service: serviceContainer.Upload}
 		}
+		if serviceContainer.Image != nil {
+			container.Image = &ImageHandler{service: serviceContainer.Image, files: serviceContainer.Upload}
+		}
 		if serviceContainer.Notification != nil {
 			container.Notification = &NotificationHandler{service: serviceContainer.Notification}
 		}
@@ -137,33 +152,50 @@ func NewHandlerContainer(serviceContainer *services.ServiceContainer) *HandlerCo
 		if serviceContainer.Health != nil {
 			container.Health = &HealthHandler{service: serviceContainer.Health}
 		}
-		if serviceContainer.Email != nil {
-			emailWorker, err := email.NewCloudflareEmailWorker()
-		if err == nil {
-				container.Email = &EmailHandler{
-					service:     serviceContainer.Email,
-					emailWorker: emailWorker,
-				}
-			} else {
-				// Log error but continue without email worker
-				fmt.Printf("Warning: Failed to initialize email worker: %v\n", err)
-				container.Email = &EmailHandler{
-					service: serviceContainer.Email,
-				}
-  if serviceContainer != nil {
-		// ... rest of the handlers
-	}
-			}
-		}
+	}
+
+	// عامل البريد مستقل عن قاعدة البيانات؛ بدونه لا تسجل مسارات البريد
+	if emailWorker, err := email.NewCloudflareEmailWorker(); err == nil {
+		container.Email = NewEmailHandler(nil, emailWorker)
+	} else {
+		log.Printf("⚠️ Email worker not initialized: %v", err)
 	}
 
 	return container
 }
 
+// newAIClient عميل الذكاء الاصطناعي بمدير تكاليف قاعدة البيانات إن توفر، وإلا بالملف المحلي
+func newAIClient(serviceContainer *services.ServiceContainer) (*ai.Client, error) {
+	if serviceContainer != nil && serviceContainer.AICost != nil {
+		return ai.NewClientWithCostManager(serviceContainer.AICost)
+	}
+	return ai.NewClient()
+}
+
 // ================================
-// NewAIHandler (دالة منفصلة لإضافة AI handler)
+// الدوال المنشئة للـ handlers
 // ================================
 
+// NewAuthHandler إنشاء Auth handler جديد
+func NewAuthHandler(service services.AuthService) *AuthHandler {
+	return &AuthHandler{service: service}
+}
+
+// NewUserHandler إنشاء User handler جديد
+func NewUserHandler(service services.UserService) *UserHandler {
+	return &UserHandler{service: service}
+}
+
+// NewServiceHandler إنشاء Service handler جديد
+func NewServiceHandler(service services.ServiceService) *ServiceHandler {
+	return &ServiceHandler{service: service}
+}
+
+// NewCategoryHandler إنشاء Category handler جديد
+func NewCategoryHandler(service services.CategoryService) *CategoryHandler {
+	return &CategoryHandler{service: service}
+}
+
 // NewAIHandler إنشاء AI handler جديد
 func NewAIHandler(aiClient *ai.Client) *AIHandler {
 	return &AIHandler{aiClient: aiClient}
@@ -250,7 +282,7 @@ func (h *AuthHandler) RefreshToken(c *gin.Context) {
 
 // GetProfile الحصول على الملف الشخصي
 func (h *UserHandler) GetProfile(c *gin.Context) {
-	userID := c.GetString("user_id")
+	userID := getCurrentUserID(c)
 	if userID == "" {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
 		return
@@ -267,19 +299,19 @@ func (h *UserHandler) GetProfile(c *gin.Context) {
 
 // UpdateProfile تحديث الملف الشخصي
 func (h *UserHandler) UpdateProfile(c *gin.Context) {
-	userID := c.GetString("user_id")
+	userID := getCurrentUserID(c)
 	if userID == "" {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
 		return
 	}
 
-	var updateData map[string]interface{}
-	if err := c.ShouldBindJSON(&updateData); err != nil {
+	var req services.UserUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
 		return
 	}
 
-	user, err := h.service.UpdateProfile(c.Request.Context(), userID, updateData)
+	user, err := h.service.UpdateProfile(c.Request.Context(), userID, req)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -294,13 +326,13 @@ func (h *UserHandler) UpdateProfile(c *gin.Context) {
 
 // CreateService إنشاء خدمة جديدة
 func (h *ServiceHandler) CreateService(c *gin.Context) {
-	var service models.Service
-	if err := c.ShouldBindJSON(&service); err != nil {
+	var req services.ServiceCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
 		return
 	}
 
-	createdService, err := h.service.CreateService(c.Request.Context(), service)
+	createdService, err := h.service.CreateService(c.Request.Context(), req)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -311,13 +343,24 @@ func (h *ServiceHandler) CreateService(c *gin.Context) {
 
 // GetServices الحصول على قائمة الخدمات
 func (h *ServiceHandler) GetServices(c *gin.Context) {
-	services, err := h.service.GetServices(c.Request.Context())
+	params := services.ServiceQueryParams{
+		Search:     c.Query("search"),
+		CategoryID: c.Query("category_id"),
+		ProviderID: c.Query("provider_id"),
+		IsActive:   c.Query("is_active") == "true",
+		IsFeatured: c.Query("is_featured") == "true",
+	}
+	params.Page, params.Limit = utils.GetPaginationParams(c)
+	params.Cursor, params.WithTotal = utils.GetCursorParams(c)
+
+	page, err := h.service.ListServices(c.Request.Context(), params)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondListError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, services)
+	utils.SetPaginationLinks(c, page.NextCursor)
+	c.JSON(http.StatusOK, page)
 }
 
 // ================================
@@ -326,13 +369,13 @@ func (h *ServiceHandler) GetServices(c *gin.Context) {
 
 // CreateCategory إنشاء فئة جديدة
 func (h *CategoryHandler) CreateCategory(c *gin.Context) {
-	var category models.Category
-	if err := c.ShouldBindJSON(&category); err != nil {
+	var req services.CategoryCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
 		return
 	}
 
-	createdCategory, err := h.service.CreateCategory(c.Request.Context(), category)
+	createdCategory, err := h.service.CreateCategory(c.Request.Context(), req)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -343,7 +386,10 @@ func (h *CategoryHandler) CreateCategory(c *gin.Context) {
 
 // GetCategories الحصول على قائمة الفئات
 func (h *CategoryHandler) GetCategories(c *gin.Context) {
-	categories, err := h.service.GetCategories(c.Request.Context())
+	params := services.CategoryQueryParams{IsActive: c.Query("is_active") == "true"}
+	params.Page, params.Limit = utils.GetPaginationParams(c)
+
+	categories, err := h.service.GetCategories(c.Request.Context(), params)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -358,13 +404,20 @@ func (h *CategoryHandler) GetCategories(c *gin.Context) {
 
 // CreateOrder إنشاء طلب جديد
 func (h *OrderHandler) CreateOrder(c *gin.Context) {
-	var order models.Order
-	if err := c.ShouldBindJSON(&order); err != nil {
+	userID := getCurrentUserID(c)
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req services.OrderCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
 		return
 	}
+	req.UserID = userID
 
-	createdOrder, err := h.service.CreateOrder(c.Request.Context(), order)
+	createdOrder, err := h.service.CreateOrder(c.Request.Context(), req)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -373,6 +426,28 @@ func (h *OrderHandler) CreateOrder(c *gin.Context) {
 	c.JSON(http.StatusCreated, createdOrder)
 }
 
+// GetUserOrders الحصول على طلبات المستخدم
+func (h *OrderHandler) GetUserOrders(c *gin.Context) {
+	userID := getCurrentUserID(c)
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	params := services.OrderQueryParams{Status: c.Query("status")}
+	params.Page, params.Limit = utils.GetPaginationParams(c)
+	params.Cursor, params.WithTotal = utils.GetCursorParams(c)
+
+	page, err := h.service.ListUserOrders(c.Request.Context(), userID, params)
+	if err != nil {
+		respondListError(c, err)
+		return
+	}
+
+	utils.SetPaginationLinks(c, page.NextCursor)
+	c.JSON(http.StatusOK, page)
+}
+
 
 // ================================
 // PaymentHandler Methods
@@ -424,24 +499,296 @@ func (h *PaymentHandler) ConfirmPayment(c *gin.Context) {
 
 // UploadFile رفع ملف
 func (h *UploadHandler) UploadFile(c *gin.Context) {
-	file, err := c.FormFile("file")
+	userID := getCurrentUserID(c)
+	if userID == "" {
+		errorResponse(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	fileHeader, err := c.FormFile("file")
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "File is required"})
+		errorResponse(c, http.StatusBadRequest, "File is required")
 		return
 	}
 
-	uploadType := c.PostForm("type")
-	if uploadType == "" {
-		uploadType = "general"
+	file, err := fileHeader.Open()
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, "Failed to read file")
+		return
 	}
+	defer file.Close()
 
-	result, err := h.service.UploadFile(c.Request.Context(), file, uploadType)
+	data, err := io.ReadAll(file)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		errorResponse(c, http.StatusBadRequest, "Failed to read file")
 		return
 	}
 
-	c.JSON(http.StatusOK, result)
+	fileType := fileHeader.Header.Get("Content-Type")
+	if fileType == "" {
+		fileType = http.DetectContentType(data)
+	}
+
+	result, err := h.service.UploadFile(c.Request.Context(), services.UploadRequest{
+		UserID:   userID,
+		FileName: fileHeader.Filename,
+		FileType: fileType,
+		FileSize: int64(len(data)),
+	}, data)
+	if err != nil {
+		errorResponse(c, uploadErrorStatus(err), err.Error())
+		return
+	}
+
+	successResponse(c, result)
+}
+
+// GeneratePresignedURL إنشاء رابط رفع مباشر موقع
+func (h *UploadHandler) GeneratePresignedURL(c *gin.Context) {
+	userID := getCurrentUserID(c)
+	if userID == "" {
+		errorResponse(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	var req services.PresignedUploadRequest
+	if c.Request.Method == http.MethodGet {
+		// GET مهمل ويبقى للعملاء الحاليين: المعاملات في الاستعلام
+		c.Header("Deprecation", "true")
+		if err := c.ShouldBindQuery(&req); err != nil {
+			errorResponse(c, http.StatusBadRequest, fmt.Sprintf("Invalid request data: %v", err))
+			return
+		}
+	} else if !bindAndValidate(c, &req) {
+		return
+	}
+	req.UserID = userID
+
+	upload, err := h.service.GeneratePresignedURL(c.Request.Context(), req)
+	if err != nil {
+		errorResponse(c, uploadErrorStatus(err), err.Error())
+		return
+	}
+
+	successResponse(c, upload)
+}
+
+// DirectUpload رفع المحتوى عبر الخادم بتوكن الرفع (عند عدم دعم التخزين للروابط الموقعة)
+func (h *UploadHandler) DirectUpload(c *gin.Context) {
+	err := h.service.UploadWithToken(c.Request.Context(), c.Param("token"), c.Request.Body,
+		c.Request.ContentLength, c.GetHeader("Content-Type"))
+	if err != nil {
+		errorResponse(c, uploadErrorStatus(err), err.Error())
+		return
+	}
+
+	successResponse(c, gin.H{"uploaded": true})
+}
+
+// FinalizeUpload إتمام الرفع المباشر وتسجيل الملف
+func (h *UploadHandler) FinalizeUpload(c *gin.Context) {
+	userID := getCurrentUserID(c)
+	if userID == "" {
+		errorResponse(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	var req struct {
+		Token string `json:"token" validate:"required"`
+	}
+	if !bindAndValidate(c, &req) {
+		return
+	}
+
+	result, err := h.service.FinalizeUpload(c.Request.Context(), userID, req.Token)
+	if err != nil {
+		errorResponse(c, uploadErrorStatus(err), err.Error())
+		return
+	}
+
+	successResponse(c, result)
+}
+
+// DeleteFile حذف ملف يملكه المستخدم
+func (h *UploadHandler) DeleteFile(c *gin.Context) {
+	userID := getCurrentUserID(c)
+	if userID == "" {
+		errorResponse(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	file, err := h.service.GetFile(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		errorResponse(c, http.StatusNotFound, "File not found")
+		return
+	}
+
+	if file.UserID != userID {
+		errorResponse(c, http.StatusForbidden, "Access denied")
+		return
+	}
+
+	if err := h.service.DeleteFile(c.Request.Context(), file.ID); err != nil {
+		errorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	successResponse(c, gin.H{"deleted": true})
+}
+
+// ReplaceFile استبدال محتوى ملف يملكه المستخدم مع الإبقاء على معرفه
+func (h *UploadHandler) ReplaceFile(c *gin.Context) {
+	userID := getCurrentUserID(c)
+	if userID == "" {
+		errorResponse(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	file, err := h.service.GetFile(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		errorResponse(c, http.StatusNotFound, "File not found")
+		return
+	}
+	if file.UserID != userID {
+		errorResponse(c, http.StatusForbidden, "Access denied")
+		return
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, "File is required")
+		return
+	}
+
+	src, err := fileHeader.Open()
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, "Failed to read file")
+		return
+	}
+	defer src.Close()
+
+	data, err := io.ReadAll(src)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, "Failed to read file")
+		return
+	}
+
+	fileType := fileHeader.Header.Get("Content-Type")
+	if fileType == "" {
+		fileType = http.DetectContentType(data)
+	}
+
+	result, err := h.service.ReplaceFile(c.Request.Context(), file.ID, services.UploadRequest{
+		UserID:   userID,
+		FileName: fileHeader.Filename,
+		FileType: fileType,
+		FileSize: int64(len(data)),
+	}, data)
+	if err != nil {
+		errorResponse(c, uploadErrorStatus(err), err.Error())
+		return
+	}
+
+	successResponse(c, result)
+}
+
+// DownloadFile تنزيل محتوى ملف من التخزين
+func (h *UploadHandler) DownloadFile(c *gin.Context) {
+	rc, file, err := h.service.OpenFile(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		if errors.Is(err, services.ErrFileNotFound) || errors.Is(err, services.ErrFileQuarantined) {
+			errorResponse(c, http.StatusNotFound, "File not found")
+			return
+		}
+		errorResponse(c, uploadErrorStatus(err), err.Error())
+		return
+	}
+	defer rc.Close()
+
+	c.DataFromReader(http.StatusOK, file.Size, file.Type, rc, map[string]string{
+		"Content-Disposition": fmt.Sprintf("inline; filename=%q", file.Name),
+	})
+}
+
+// GetStorageUsage استهلاك المستخدم لحصة التخزين
+func (h *UploadHandler) GetStorageUsage(c *gin.Context) {
+	userID := getCurrentUserID(c)
+	if userID == "" {
+		errorResponse(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	usage, err := h.service.GetStorageUsage(c.Request.Context(), userID)
+	if err != nil {
+		errorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	successResponse(c, usage)
+}
+
+// GetDeletedFiles الملفات المحذوفة القابلة للاستعادة
+func (h *UploadHandler) GetDeletedFiles(c *gin.Context) {
+	userID := getCurrentUserID(c)
+	if userID == "" {
+		errorResponse(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	files, err := h.service.GetDeletedFiles(c.Request.Context(), userID)
+	if err != nil {
+		errorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	successResponse(c, files)
+}
+
+// RestoreFile استعادة ملف محذوف يملكه المستخدم
+func (h *UploadHandler) RestoreFile(c *gin.Context) {
+	userID := getCurrentUserID(c)
+	if userID == "" {
+		errorResponse(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	deleted, err := h.service.GetDeletedFiles(c.Request.Context(), userID)
+	if err != nil {
+		errorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	fileID := c.Param("id")
+	owned := false
+	for _, f := range deleted {
+		if f.ID == fileID {
+			owned = true
+			break
+		}
+	}
+	if !owned {
+		errorResponse(c, http.StatusNotFound, "File not found")
+		return
+	}
+
+	file, err := h.service.RestoreFile(c.Request.Context(), fileID)
+	if err != nil {
+		errorResponse(c, uploadErrorStatus(err), err.Error())
+		return
+	}
+
+	successResponse(c, file)
+}
+
+// CollectGarbage تشغيل دورة جمع الملفات اليتيمة والمحذوفة يدوياً (للمسؤولين)
+func (h *UploadHandler) CollectGarbage(c *gin.Context) {
+	report, err := h.service.CollectGarbage(c.Request.Context())
+	if err != nil {
+		errorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	successResponse(c, report)
 }
 
 // ================================
@@ -450,19 +797,37 @@ func (h *UploadHandler) UploadFile(c *gin.Context) {
 
 // GetNotifications الحصول على الإشعارات
 func (h *NotificationHandler) GetNotifications(c *gin.Context) {
-	userID := c.GetString("user_id")
+	userID := getCurrentUserID(c)
 	if userID == "" {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
 		return
 	}
 
-	notifications, err := h.service.GetUserNotifications(c.Request.Context(), userID)
+	params := services.NotificationQueryParams{Type: c.Query("type")}
+	if read := c.Query("read"); read != "" {
+		isRead := read == "true"
+		params.Read = &isRead
+	}
+	params.Page, params.Limit = utils.GetPaginationParams(c)
+	params.Cursor, params.WithTotal = utils.GetCursorParams(c)
+
+	page, err := h.service.ListUserNotifications(c.Request.Context(), userID, params)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondListError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, notifications)
+	utils.SetPaginationLinks(c, page.NextCursor)
+	c.JSON(http.StatusOK, page)
+}
+
+// respondListError المؤشر غير الصالح خطأ من العميل، وغيره خطأ داخلي
+func respondListError(c *gin.Context, err error) {
+	if errors.Is(err, services.ErrInvalidCursor) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 }
 
 // MarkAsRead تحديد الإشعار كمقروء
@@ -488,7 +853,7 @@ func (h *NotificationHandler) MarkAsRead(c *gin.Context) {
 
 // GetStatistics الحصول على إحصائيات النظام
 func (h *AdminHandler) GetStatistics(c *gin.Context) {
-	stats, err := h.service.GetSystemStatistics(c.Request.Context())
+	stats, err := h.service.GetDashboardStats(c.Request.Context())
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -499,7 +864,10 @@ func (h *AdminHandler) GetStatistics(c *gin.Context) {
 
 // GetAllUsers الحصول على جميع المستخدمين
 func (h *AdminHandler) GetAllUsers(c *gin.Context) {
-	users, err := h.service.GetAllUsers(c.Request.Context())
+	params := services.UserQueryParams{Role: c.Query("role"), Email: c.Query("email")}
+	params.Page, params.Limit = utils.GetPaginationParams(c)
+
+	users, err := h.service.GetUsers(c.Request.Context(), params)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -508,13 +876,42 @@ func (h *AdminHandler) GetAllUsers(c *gin.Context) {
 	c.JSON(http.StatusOK, users)
 }
 
+// SetUserTier تغيير طبقة المستخدم (تحدد حصة التخزين)
+func (h *AdminHandler) SetUserTier(c *gin.Context) {
+	var req struct {
+		Tier string `json:"tier" validate:"required,oneof=free basic premium enterprise"`
+	}
+	if !bindAndValidate(c, &req) {
+		return
+	}
+
+	err := h.service.SetUserTier(c.Request.Context(), c.Param("id"), req.Tier)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrUserNotFound):
+			errorResponse(c, http.StatusNotFound, "User not found")
+		case errors.Is(err, services.ErrInvalidRequest):
+			errorResponse(c, http.StatusBadRequest, "Invalid tier")
+		default:
+			errorResponse(c, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	successResponse(c, gin.H{"user_id": c.Param("id"), "tier": req.Tier})
+}
+
 // ================================
 // HealthHandler Methods
 // ================================
 
 // CheckHealth فحص صحة النظام
 func (h *HealthHandler) CheckHealth(c *gin.Context) {
-	healthStatus, err := h.service.CheckHealth(c.Request.Context())
+	healthStatus, err := h.service.CheckHealth(c.Request.Context(), &services.HealthRequest{
+		CheckDatabase: true,
+		CheckCache:    true,
+		CheckStorage:  true,
+	})
 	if err != nil {
 		c.JSON(http.StatusServiceUnavailable, gin.H{
 			"status":    "unhealthy",
@@ -585,9 +982,9 @@ func (h *AIHandler) GenerateContentHandler(c *gin.Context) {
 	prompt := h.buildEnhancedPrompt(req.Prompt, req.ContentType, req.Tone, req.Length)
 
 	// توليد المحتوى باستخدام الواجهة الصحيحة
-	content, err := h.aiClient.GenerateText(prompt, req.Provider)
+	content, err := h.aiClient.GenerateTextContext(c.Request.Context(), prompt, req.Provider)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
+		c.JSON(aiErrorStatus(err), gin.H{
 			"success": false,
 			"error":   "Failed to generate content",
 			"details": err.Error(),
@@ -611,6 +1008,105 @@ func (h *AIHandler) GenerateContentHandler(c *gin.Context) {
 	})
 }
 
+// GenerateContentStreamHandler توليد المحتوى متدفقًا عبر SSE: حدث delta لكل جزء من
+// النص ثم done بالاستخدام والتكلفة، أو error عند فشل المزود أثناء البث. انقطاع العميل
+// يلغي سياق الطلب فيتوقف المزود، وتُحتسب تكلفة ما وُلّد حتى لحظتها
+func (h *AIHandler) GenerateContentStreamHandler(c *gin.Context) {
+	var req struct {
+		Prompt      string  `json:"prompt" binding:"required"`
+		ContentType string  `json:"content_type"`
+		Tone        string  `json:"tone"`
+		Length      string  `json:"length"`
+		MaxTokens   int     `json:"max_tokens"`
+		Temperature float64 `json:"temperature"`
+		Provider    string  `json:"provider"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Invalid request format",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if len(req.Prompt) > 5000 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Prompt is too long (max 5000 characters)",
+		})
+		return
+	}
+
+	prompt := h.buildEnhancedPrompt(req.Prompt, req.ContentType, req.Tone, req.Length)
+	stream, err := h.aiClient.GenerateTextStream(c.Request.Context(), types.TextRequest{
+		Prompt:      prompt,
+		MaxTokens:   req.MaxTokens,
+		Temperature: req.Temperature,
+		Stream:      true,
+		UserID:      c.GetString("userID"),
+		UserTier:    c.GetString("userTier"),
+	}, req.Provider)
+	if err != nil {
+		c.JSON(aiErrorStatus(err), gin.H{
+			"success": false,
+			"error":   "Failed to start content stream",
+			"details": err.Error(),
+		})
+		return
+	}
+	// البث يُقرأ حتى إغلاقه ولو انقطع العميل، ليصل الجزء الأخير إلى تسجيل التكلفة
+	defer func() {
+		for range stream {
+		}
+	}()
+
+	c.Header("Content-Type", "text/event-stream; charset=UTF-8")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	c.Stream(func(w io.Writer) bool {
+		chunk, ok := <-stream
+		if !ok {
+			return false
+		}
+		if !chunk.Done {
+			c.SSEvent("delta", gin.H{"text": chunk.Delta})
+			return true
+		}
+
+		resp := chunk.Response
+		usage := gin.H{
+			"prompt_tokens":     resp.PromptTokens,
+			"completion_tokens": resp.CompletionTokens,
+			"total_tokens":      resp.Tokens,
+			"tokens_estimated":  resp.TokensEstimated,
+			"cost":              resp.Cost,
+		}
+		if chunk.Err != nil {
+			c.SSEvent("error", gin.H{
+				"error":         "Content stream interrupted",
+				"details":       chunk.Err.Error(),
+				"status":        aiErrorStatus(chunk.Err),
+				"finish_reason": resp.FinishReason,
+				"usage":         usage,
+			})
+			return false
+		}
+		c.SSEvent("done", gin.H{
+			"model_used":    resp.ModelUsed,
+			"finish_reason": resp.FinishReason,
+			"usage":         usage,
+			"metadata":      resp.Metadata,
+			"created_at":    resp.CreatedAt.UTC().Format(time.RFC3339),
+		})
+		return false
+	})
+}
+
 // AnalyzeImageHandler معالج تحليل الصور
 func (h *AIHandler) AnalyzeImageHandler(c *gin.Context) {
 	file, err := c.FormFile("image")
@@ -694,10 +1190,10 @@ func (h *AIHandler) AnalyzeImageHandler(c *gin.Context) {
 	}
 
 
-	‎// تحليل الصورة باستخدام العميل الحالي
-	analysis, err := h.aiClient.AnalyzeImage(imageData, prompt, provider)
+	// تحليل الصورة باستخدام العميل الحالي
+	analysis, err := h.aiClient.AnalyzeImageContext(c.Request.Context(), imageData, prompt, provider)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
+		c.JSON(aiErrorStatus(err), gin.H{
 			"success": false,
 			"error":   "Failed to analyze image",
 			"details": err.Error(),
@@ -708,7 +1204,7 @@ func (h *AIHandler) AnalyzeImageHandler(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"data": gin.H{
-			"analysis":      analysis.Text,
+			"analysis":      analysis.Result,
 			"confidence":    0.85,
 			"filename":      file.Filename,
 			"size":          file.Size,
@@ -720,10 +1216,27 @@ func (h *AIHandler) AnalyzeImageHandler(c *gin.Context) {
 	})
 }
 
-‎// توليد الفيديو
-	videoURL, err := h.aiClient.GenerateVideo(req.Prompt, req.Provider)
+// GenerateVideoHandler معالج توليد الفيديو
+func (h *AIHandler) GenerateVideoHandler(c *gin.Context) {
+	var req struct {
+		Prompt   string `json:"prompt" binding:"required"`
+		Duration int    `json:"duration"`
+		Provider string `json:"provider" default:"auto"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Invalid request format",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	// توليد الفيديو
+	videoURL, err := h.aiClient.GenerateVideoContext(c.Request.Context(), req.Prompt, req.Provider)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
+		c.JSON(aiErrorStatus(err), gin.H{
 			"success": false,
 			"error":   "Failed to generate video",
 			"details": err.Error(),
@@ -739,6 +1252,7 @@ func (h *AIHandler) AnalyzeImageHandler(c *gin.Context) {
 			"duration": req.Duration,
 			"provider": req.Provider,
 			"status":   "processing",
+			"url":      videoURL,
 			"note":     "Use the check_video endpoint to check status",
 		},
 	})
@@ -755,10 +1269,10 @@ func (h *AIHandler) CheckVideoStatusHandler(c *gin.Context) {
 		return
 	}
 
-‎	// الحصول على حالة الفيديو
-	status, err := h.aiClient.GetVideoStatus(operationID)
+	// الحصول على حالة الفيديو
+	status, err := h.aiClient.GetVideoStatusContext(c.Request.Context(), operationID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
+		c.JSON(aiErrorStatus(err), gin.H{
 			"success": false,
 			"error":   "Failed to get video status",
 			"details": err.Error(),
@@ -797,10 +1311,10 @@ func (h *AIHandler) AnalyzeTextHandler(c *gin.Context) {
 		return
 	}
 
-‎	// تحليل النص
-	analysis, err := h.aiClient.AnalyzeText(req.Text, req.Provider)
+	// تحليل النص
+	analysis, err := h.aiClient.AnalyzeTextContext(c.Request.Context(), req.Text, req.Provider)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
+		c.JSON(aiErrorStatus(err), gin.H{
 			"success": false,
 			"error":   "Failed to analyze text",
 			"details": err.Error(),
@@ -857,10 +1371,10 @@ func (h *AIHandler) TranslateTextHandler(c *gin.Context) {
 		return
 	}
 
-	‎// ترجمة النص باستخدام العميل الحالي
-	translatedText, err := h.aiClient.TranslateText(req.Text, req.SourceLang, req.TargetLang, req.Provider)
+	// ترجمة النص باستخدام العميل الحالي
+	translatedText, err := h.aiClient.TranslateTextContext(c.Request.Context(), req.Text, req.SourceLang, req.TargetLang, req.Provider)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
+		c.JSON(aiErrorStatus(err), gin.H{
 			"success": false,
 			"error":   "Failed to translate text",
 			"details": err.Error(),
@@ -904,7 +1418,7 @@ func (h *AIHandler) SummarizeTextHandler(c *gin.Context) {
 		return
 	}
 
-‎	// التحقق من طول النص
+	// التحقق من طول النص
 	if len(req.Text) > 10000 {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"success": false,
@@ -913,13 +1427,13 @@ func (h *AIHandler) SummarizeTextHandler(c *gin.Context) {
 		return
 	}
 
-‎	// بناء prompt للتلخيص
+	// بناء prompt للتلخيص
 	prompt := h.buildSummaryPrompt(req.Text, req.SummaryType, req.MaxLength)
 
-‎	// توليد التلخيص باستخدام العميل الحالي
-	summary, err := h.aiClient.GenerateText(prompt, req.Provider)
+	// توليد التلخيص باستخدام العميل الحالي
+	summary, err := h.aiClient.GenerateTextContext(c.Request.Context(), prompt, req.Provider)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
+		c.JSON(aiErrorStatus(err), gin.H{
 			"success": false,
 			"error":   "Failed to generate summary",
 			"details": err.Error(),
@@ -946,10 +1460,12 @@ func (h *AIHandler) SummarizeTextHandler(c *gin.Context) {
 func (h *AIHandler) GetAICapabilitiesHandler(c *gin.Context) {
 	var providers map[string][]string
 	var usageStats map[string]interface{}
+	var providerHealth []ai.BreakerSnapshot
 	
 	if h.aiClient != nil {
 		providers = h.aiClient.GetAvailableProviders()
 		usageStats = h.aiClient.GetUsageStatistics()
+		providerHealth = h.aiClient.GetProviderHealth()
 	}
 
 	capabilities := gin.H{
@@ -986,6 +1502,7 @@ func (h *AIHandler) GetAICapabilitiesHandler(c *gin.Context) {
 		},
 
 		"providers": providers,
+		"provider_health": providerHealth,
 		"usage_stats": usageStats,
 		
 		"content_types": []string{
@@ -1067,6 +1584,23 @@ func (h *AIHandler) buildSummaryPrompt(text, summaryType string, maxLength int)
 	return prompt.String()
 }
 
+// statusClientClosedRequest العميل أغلق الاتصال قبل الرد (اصطلاح nginx)
+const statusClientClosedRequest = 499
+
+// aiErrorStatus حالة HTTP لأخطاء المزود: انتهاء المهلة 504 وانقطاع العميل 499
+func aiErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, types.ErrRequestTimeout):
+		return http.StatusGatewayTimeout
+	case errors.Is(err, types.ErrRequestCanceled):
+		return statusClientClosedRequest
+	case errors.Is(err, types.ErrStreamNotSupported):
+		return http.StatusNotImplemented
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
 func (h *AIHandler) isValidLanguage(lang string) bool {
 	supportedLangs := h.getSupportedLanguagesSimple()
 	for _, supportedLang := range supportedLangs {
@@ -1090,21 +1624,37 @@ func (h *AIHandler) getSupportedLanguagesSimple() []string {
 // دوال مساعدة عامة
 // ================================
 
-// getCurrentUserID الحصول على معرف المستخدم الحالي
+// getCurrentUserID معرف المستخدم الذي وضعه AuthMiddleware في السياق
 func getCurrentUserID(c *gin.Context) string {
-	// يمكن تعديل هذا بناءً على طريقة المصادقة المستخدمة
-	if userID, exists := c.Get("user_id"); exists {
-		if id, ok := userID.(string); ok {
-			return id
-		}
-	}
-	
-	// أو من التوكن إذا كان مخزناً في السياق
-	if userID := c.GetString("user_id"); userID != "" {
-		return userID
+	return c.GetString("userID")
+}
+
+// uploadErrorStatus تحويل أخطاء الرفع إلى رموز HTTP
+func uploadErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrUploadTokenInvalid), errors.Is(err, services.ErrUploadTokenExpired):
+		return http.StatusForbidden
+	case errors.Is(err, services.ErrUploadTokenUsed):
+		return http.StatusConflict
+	case errors.Is(err, services.ErrUploadTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, services.ErrUploadTypeNotAllowed):
+		return http.StatusUnsupportedMediaType
+	case errors.Is(err, services.ErrUploadIncomplete), errors.Is(err, services.ErrInvalidRequest):
+		return http.StatusBadRequest
+	case errors.Is(err, services.ErrUnauthorized):
+		return http.StatusForbidden
+	case errors.Is(err, services.ErrFileNotFound), errors.Is(err, services.ErrFileQuarantined):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrFileNotScanned):
+		return http.StatusConflict
+	case errors.Is(err, services.ErrStorageQuotaExceeded):
+		return http.StatusInsufficientStorage
+	case errors.Is(err, services.ErrRestoreWindowExpired):
+		return http.StatusGone
+	default:
+		return http.StatusInternalServerError
 	}
-	
-	return ""
 }
 
 // successResponse إرسال استجابة ناجحة
@@ -1,23 +1,28 @@
 package handlers
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/nawthtech/nawthtech/backend/internal/email"
-	"github.com/nawthtech/nawthtech/backend/internal/services"
 )
 
+// EmailSender خدمة إرسال البريد (اختيارية؛ بدونها يُقبل الطلب دون إرسال)
+type EmailSender interface {
+	SendEmail(ctx context.Context, to, subject, body string, cc, bcc []string, isHTML bool) error
+}
+
 // EmailHandler معالجة طلبات البريد الإلكتروني
 type EmailHandler struct {
-	service      services.EmailService
+	service      EmailSender
 	emailWorker  *email.CloudflareEmailWorker
 }
 
 // NewEmailHandler إنشاء Email handler جديد
-func NewEmailHandler(service services.EmailService, emailWorker *email.CloudflareEmailWorker) *EmailHandler {
+func NewEmailHandler(service EmailSender, emailWorker *email.CloudflareEmailWorker) *EmailHandler {
 	return &EmailHandler{
 		service:     service,
 		emailWorker: emailWorker,
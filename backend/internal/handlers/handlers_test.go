@@ -2,6 +2,8 @@ package handlers
 
 import (
 	"testing"
+
+	"github.com/nawthtech/nawthtech/backend/internal/services"
 )
 
 func TestHandlerCreation(t *testing.T) {
@@ -16,9 +18,9 @@ func TestHandlerCreation(t *testing.T) {
 }
 
 func TestHandlerInterfaces(t *testing.T) {
-	// Test that handlers implement their interfaces
-	var _ AuthHandler = &authHandler{}
-	var _ UserHandler = &userHandler{}
-	var _ ServiceHandler = &serviceHandler{}
-	var _ CategoryHandler = &categoryHandler{}
+	// Test that handlers are built on their service interfaces
+	var _ *AuthHandler = NewAuthHandler(services.AuthService(nil))
+	var _ *UserHandler = NewUserHandler(services.UserService(nil))
+	var _ *ServiceHandler = NewServiceHandler(services.ServiceService(nil))
+	var _ *CategoryHandler = NewCategoryHandler(services.CategoryService(nil))
 }
@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nawthtech/nawthtech/backend/internal/services"
+)
+
+// ================================
+// تحويل الصور عند الطلب
+// ================================
+
+// imageCacheControl المشتقات قابلة للتخزين في CDN، وتمسح بالوسوم عند تغير الأصل
+const imageCacheControl = "public, max-age=86400, stale-while-revalidate=604800"
+
+// ServeImage إرسال صورة مشتقة حسب معاملات الرابط الموقع
+// GET /images/:id?width=&height=&fit=&format=&quality=&expires=&sig=
+func (h *ImageHandler) ServeImage(c *gin.Context) {
+	fileID := c.Param("id")
+
+	transform, err := parseImageTransform(c)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if !h.service.VerifyTransform(fileID, transform, c.Query("sig")) {
+		errorResponse(c, http.StatusForbidden, services.ErrImageSignatureInvalid.Error())
+		return
+	}
+
+	derivative, err := h.service.GetDerivative(c.Request.Context(), fileID, transform)
+	if err != nil {
+		errorResponse(c, imageErrorStatus(err), err.Error())
+		return
+	}
+	defer derivative.Body.Close()
+
+	etag := `"` + derivative.ETag + `"`
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", imageCacheControl)
+	c.Header("Cache-Tag", services.ImageCacheTag(fileID))
+	c.Header("Last-Modified", derivative.CreatedAt.UTC().Format(http.TimeFormat))
+
+	if etagMatches(c.GetHeader("If-None-Match"), etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	c.DataFromReader(http.StatusOK, derivative.Size, derivative.ContentType, derivative.Body, nil)
+}
+
+// SignImageURL إنشاء رابط مشتق موقع لصورة يملكها المستخدم
+func (h *ImageHandler) SignImageURL(c *gin.Context) {
+	userID := getCurrentUserID(c)
+	if userID == "" {
+		errorResponse(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	var req struct {
+		FileID string `json:"file_id" validate:"required"`
+		services.ImageTransform
+	}
+	if !bindAndValidate(c, &req) {
+		return
+	}
+
+	file, err := h.files.GetFile(c.Request.Context(), req.FileID)
+	if err != nil {
+		errorResponse(c, http.StatusNotFound, "File not found")
+		return
+	}
+	if file.UserID != userID {
+		errorResponse(c, http.StatusForbidden, "Access denied")
+		return
+	}
+
+	successResponse(c, gin.H{"url": h.service.TransformURL(file.ID, req.ImageTransform)})
+}
+
+// parseImageTransform قراءة معاملات التحويل من الاستعلام
+func parseImageTransform(c *gin.Context) (services.ImageTransform, error) {
+	var t services.ImageTransform
+	var err error
+
+	if t.Width, err = queryInt(c, "width"); err != nil {
+		return t, err
+	}
+	if t.Height, err = queryInt(c, "height"); err != nil {
+		return t, err
+	}
+	if t.Quality, err = queryInt(c, "quality"); err != nil {
+		return t, err
+	}
+	if t.Expires, err = queryInt64(c, "expires"); err != nil {
+		return t, err
+	}
+	t.Fit = c.Query("fit")
+	t.Format = c.Query("format")
+	return t, nil
+}
+
+// queryInt قراءة معامل رقمي اختياري
+func queryInt(c *gin.Context, name string) (int, error) {
+	value := c.Query(name)
+	if value == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, errors.New("invalid " + name + " parameter")
+	}
+	return n, nil
+}
+
+// queryInt64 قراءة معامل رقمي اختياري بعرض 64 بت (مثل الطوابع الزمنية)
+func queryInt64(c *gin.Context, name string) (int64, error) {
+	value := c.Query(name)
+	if value == "" {
+		return 0, nil
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, errors.New("invalid " + name + " parameter")
+	}
+	return n, nil
+}
+
+// etagMatches مقارنة If-None-Match مع ETag (يدعم القوائم و * والوسوم الضعيفة)
+func etagMatches(header, etag string) bool {
+	if header == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// imageErrorStatus تحويل أخطاء خدمة الصور إلى رموز HTTP
+func imageErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrInvalidTransform):
+		return http.StatusBadRequest
+	case errors.Is(err, services.ErrNotAnImage):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, services.ErrFileNotFound):
+		return http.StatusNotFound
+	default:
+		return uploadErrorStatus(err)
+	}
+}
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nawthtech/nawthtech/backend/internal/services"
+)
+
+// fakeImageService خدمة صور بتوقيع حقيقي ومشتق ثابت
+type fakeImageService struct {
+	services.ImageService
+	generated int
+}
+
+func (f *fakeImageService) GetDerivative(ctx context.Context, fileID string, t services.ImageTransform) (*services.ImageDerivative, error) {
+	if fileID == "missing" {
+		return nil, services.ErrFileNotFound
+	}
+	f.generated++
+	return &services.ImageDerivative{
+		FileID: fileID, ContentType: "image/webp", ETag: "abc123", Size: 4,
+		CreatedAt: time.Unix(1700000000, 0), Body: io.NopCloser(strings.NewReader("webp")),
+	}, nil
+}
+
+func TestServeImage(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	images := &fakeImageService{ImageService: services.NewImageService(nil, nil, services.ImageOptions{
+		SigningKey: []byte("secret"), BaseURL: "/images",
+	})}
+	r := gin.New()
+	r.GET("/images/:id", (&ImageHandler{service: images}).ServeImage)
+
+	get := func(path string, header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	link := images.TransformURL("file_1", services.ImageTransform{Width: 320, Format: "webp"})
+	w := get(link, nil)
+	if w.Code != http.StatusOK || w.Body.String() != "webp" {
+		t.Fatalf("signed URL: status %d: %s", w.Code, w.Body)
+	}
+	if w.Header().Get("ETag") != `"abc123"` || w.Header().Get("Cache-Tag") != services.ImageCacheTag("file_1") ||
+		w.Header().Get("Cache-Control") != imageCacheControl {
+		t.Errorf("headers %v", w.Header())
+	}
+
+	if w = get(link, http.Header{"If-None-Match": {`W/"abc123"`}}); w.Code != http.StatusNotModified {
+		t.Errorf("If-None-Match: status %d, want 304", w.Code)
+	}
+
+	for name, path := range map[string]string{
+		"tampered width": strings.Replace(link, "width=320", "width=640", 1),
+		"other file":     strings.Replace(link, "/file_1", "/file_2", 1),
+		"missing sig":    "/images/file_1?width=320&format=webp",
+		"expired": images.TransformURL("file_1", services.ImageTransform{
+			Width: 320, Expires: time.Now().Add(-time.Minute).Unix(),
+		}),
+	} {
+		if w = get(path, nil); w.Code != http.StatusForbidden {
+			t.Errorf("%s: status %d, want 403", name, w.Code)
+		}
+	}
+
+	if w = get("/images/file_1?width=abc", nil); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid width: status %d, want 400", w.Code)
+	}
+	if w = get(images.TransformURL("missing", services.ImageTransform{Width: 320}), nil); w.Code != http.StatusNotFound {
+		t.Errorf("missing source: status %d, want 404", w.Code)
+	}
+	if images.generated != 2 {
+		t.Errorf("derivative requested %d times, want 2", images.generated)
+	}
+}
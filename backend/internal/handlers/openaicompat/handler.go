@@ -0,0 +1,446 @@
+package openaicompat
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nawthtech/nawthtech/backend/internal/ai/types"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// ================================
+// واجهة متوافقة مع OpenAI
+// ================================
+
+// autoModel اسم النموذج الذي يترك اختيار المزود لإستراتيجية التوجيه
+const autoModel = "auto"
+
+// statusClientClosedRequest انقطع العميل قبل اكتمال الرد
+const statusClientClosedRequest = 499
+
+// Backend ما تحتاجه الواجهة من المزود المتعدد؛ ai.MultiProvider يحققه
+type Backend interface {
+	GenerateText(ctx context.Context, req types.TextRequest) (*types.TextResponse, error)
+	GenerateTextStream(ctx context.Context, req types.TextRequest) (<-chan types.StreamChunk, error)
+	Embed(ctx context.Context, req types.EmbeddingRequest) (*types.EmbeddingResponse, error)
+	GetAvailableProviders() map[string][]string
+}
+
+// Handler نقاط chat/completions و embeddings و models بصيغة OpenAI، ليعمل بها أي
+// عميل OpenAI SDK مع التوجيه والحصص وتتبع التكلفة. النموذج auto يترك الاختيار
+// للتوجيه، واسم مزود (ollama) أو مزود/نموذج (ollama/llama3) يثبّت المزود
+type Handler struct {
+	backend Backend
+}
+
+// NewHandler إنشاء معالج الواجهة المتوافقة
+func NewHandler(backend Backend) *Handler {
+	return &Handler{backend: backend}
+}
+
+// Register تسجيل المسارات على المجموعة (عادةً /v1)
+func (h *Handler) Register(r gin.IRoutes) {
+	r.POST("/chat/completions", h.ChatCompletions)
+	r.POST("/embeddings", h.Embeddings)
+	r.GET("/models", h.ListModels)
+}
+
+// ChatCompletions توليد رد المحادثة، أو بثه بأجزاء chat.completion.chunk عند stream
+func (h *Handler) ChatCompletions(c *gin.Context) {
+	var req openai.ChatCompletionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeError(c, http.StatusBadRequest, "invalid_request_error", fmt.Sprintf("invalid request body: %v", err))
+		return
+	}
+	if len(req.Messages) == 0 {
+		writeError(c, http.StatusBadRequest, "invalid_request_error", "messages must not be empty")
+		return
+	}
+	if req.N > 1 {
+		writeError(c, http.StatusBadRequest, "invalid_request_error", "n greater than 1 is not supported")
+		return
+	}
+	if len(req.Tools) > 0 || len(req.Functions) > 0 {
+		writeError(c, http.StatusBadRequest, "invalid_request_error", "tools and functions are not supported")
+		return
+	}
+
+	textReq := h.textRequest(c, req)
+	if req.Stream {
+		h.streamChat(c, req, textReq)
+		return
+	}
+
+	resp, err := h.backend.GenerateText(c.Request.Context(), textReq)
+	if err != nil {
+		writeProviderError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, openai.ChatCompletionResponse{
+		ID:      completionID(),
+		Object:  "chat.completion",
+		Created: resp.CreatedAt.Unix(),
+		Model:   responseModel(req.Model, resp.ModelUsed),
+		Choices: []openai.ChatCompletionChoice{{
+			Message: openai.ChatCompletionMessage{
+				Role:    openai.ChatMessageRoleAssistant,
+				Content: resp.Text,
+			},
+			FinishReason: finishReason(resp.FinishReason),
+		}},
+		Usage: usage(resp),
+	})
+}
+
+// streamChat بث الرد: الجزء الأول يحمل الدور، ثم جزء لكل نص جديد، ثم جزء بسبب
+// الانتهاء (والاستخدام إن طُلب) وأخيرًا [DONE]
+func (h *Handler) streamChat(c *gin.Context, req openai.ChatCompletionRequest, textReq types.TextRequest) {
+	stream, err := h.backend.GenerateTextStream(c.Request.Context(), textReq)
+	if err != nil {
+		writeProviderError(c, err)
+		return
+	}
+	// البث يُقرأ حتى إغلاقه ولو انقطع العميل، ليصل الجزء الأخير إلى تسجيل التكلفة
+	defer func() {
+		for range stream {
+		}
+	}()
+
+	id := completionID()
+	created := time.Now().Unix()
+	chunk := func(delta openai.ChatCompletionStreamChoiceDelta, reason openai.FinishReason) openai.ChatCompletionStreamResponse {
+		return openai.ChatCompletionStreamResponse{
+			ID:      id,
+			Object:  "chat.completion.chunk",
+			Created: created,
+			Model:   responseModel(req.Model, ""),
+			Choices: []openai.ChatCompletionStreamChoice{{Delta: delta, FinishReason: reason}},
+		}
+	}
+
+	c.Header("Content-Type", "text/event-stream; charset=UTF-8")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	writeData(c.Writer, chunk(openai.ChatCompletionStreamChoiceDelta{Role: openai.ChatMessageRoleAssistant}, ""))
+
+	c.Stream(func(w io.Writer) bool {
+		part, ok := <-stream
+		if !ok {
+			return false
+		}
+		if !part.Done {
+			writeData(w, chunk(openai.ChatCompletionStreamChoiceDelta{Content: part.Delta}, ""))
+			return true
+		}
+
+		if part.Err != nil {
+			status, errType := errorStatus(part.Err)
+			writeData(w, openai.ErrorResponse{Error: &openai.APIError{
+				Code:    status,
+				Message: part.Err.Error(),
+				Type:    errType,
+			}})
+			return false
+		}
+
+		resp := part.Response
+		final := chunk(openai.ChatCompletionStreamChoiceDelta{}, finishReason(resp.FinishReason))
+		final.Model = responseModel(req.Model, resp.ModelUsed)
+		writeData(w, final)
+		if req.StreamOptions != nil && req.StreamOptions.IncludeUsage {
+			u := usage(resp)
+			final.Choices = []openai.ChatCompletionStreamChoice{}
+			final.Usage = &u
+			writeData(w, final)
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+		return false
+	})
+}
+
+// Embeddings تضمين نص أو قائمة نصوص، بمتجهات float أو base64
+func (h *Handler) Embeddings(c *gin.Context) {
+	var req struct {
+		Input          json.RawMessage `json:"input"`
+		Model          string          `json:"model"`
+		EncodingFormat string          `json:"encoding_format"`
+		User           string          `json:"user"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeError(c, http.StatusBadRequest, "invalid_request_error", fmt.Sprintf("invalid request body: %v", err))
+		return
+	}
+	input, err := embeddingInput(req.Input)
+	if err != nil {
+		writeError(c, http.StatusBadRequest, "invalid_request_error", err.Error())
+		return
+	}
+	if req.EncodingFormat != "" && req.EncodingFormat != "float" && req.EncodingFormat != "base64" {
+		writeError(c, http.StatusBadRequest, "invalid_request_error", "encoding_format must be float or base64")
+		return
+	}
+
+	provider, model := h.splitModel(req.Model)
+	embedReq := types.EmbeddingRequest{
+		Input:    input,
+		Model:    model,
+		UserID:   c.GetString("userID"),
+		UserTier: c.GetString("userTier"),
+		Metadata: map[string]interface{}{"source": "openai_compat"},
+	}
+	if provider != "" {
+		embedReq.Metadata["provider"] = provider
+	}
+
+	resp, err := h.backend.Embed(c.Request.Context(), embedReq)
+	if err != nil {
+		writeProviderError(c, err)
+		return
+	}
+
+	data := make([]gin.H, len(resp.Embeddings))
+	for i, vector := range resp.Embeddings {
+		var embedding interface{} = vector
+		if req.EncodingFormat == "base64" {
+			embedding = encodeBase64(vector)
+		}
+		data[i] = gin.H{"object": "embedding", "index": i, "embedding": embedding}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"object": "list",
+		"data":   data,
+		"model":  responseModel(req.Model, resp.ModelUsed),
+		"usage": openai.Usage{
+			PromptTokens: resp.Tokens,
+			TotalTokens:  resp.Tokens,
+		},
+	})
+}
+
+// ListModels النموذج auto ثم مزودو النص المتاحون، كلٌّ باسمه
+func (h *Handler) ListModels(c *gin.Context) {
+	now := time.Now().Unix()
+	models := []openai.Model{{ID: autoModel, Object: "model", OwnedBy: "nawthtech", CreatedAt: now}}
+	for _, name := range h.backend.GetAvailableProviders()["text"] {
+		models = append(models, openai.Model{ID: name, Object: "model", OwnedBy: name, CreatedAt: now})
+	}
+	c.JSON(http.StatusOK, gin.H{"object": "list", "data": models})
+}
+
+// textRequest تحويل طلب المحادثة إلى طلب نص للمزود المتعدد
+func (h *Handler) textRequest(c *gin.Context, req openai.ChatCompletionRequest) types.TextRequest {
+	provider, model := h.splitModel(req.Model)
+	maxTokens := req.MaxTokens
+	if req.MaxCompletionTokens > 0 {
+		maxTokens = req.MaxCompletionTokens
+	}
+
+	textReq := types.TextRequest{
+		Prompt:           chatPrompt(req.Messages),
+		Model:            model,
+		Temperature:      float64(req.Temperature),
+		MaxTokens:        maxTokens,
+		TopP:             float64(req.TopP),
+		FrequencyPenalty: float64(req.FrequencyPenalty),
+		PresencePenalty:  float64(req.PresencePenalty),
+		Stop:             req.Stop,
+		Stream:           req.Stream,
+		User:             req.User,
+		UserID:           c.GetString("userID"),
+		UserTier:         c.GetString("userTier"),
+		Metadata:         map[string]interface{}{"source": "openai_compat"},
+	}
+	if provider != "" {
+		textReq.Metadata["provider"] = provider
+	}
+	return textReq
+}
+
+// splitModel فصل المزود المثبّت عن النموذج: auto، أو مزود، أو مزود/نموذج، أو نموذج
+// يُمرر كما هو للمزود الذي يختاره التوجيه
+func (h *Handler) splitModel(model string) (provider, name string) {
+	model = strings.TrimSpace(model)
+	if model == "" || model == autoModel {
+		return "", ""
+	}
+	providers := h.backend.GetAvailableProviders()["text"]
+	if p, m, ok := strings.Cut(model, "/"); ok && slices.Contains(providers, p) {
+		return p, m
+	}
+	if slices.Contains(providers, model) {
+		return model, ""
+	}
+	return "", model
+}
+
+// chatPrompt تسطيح الرسائل في نص واحد: رسالة مستخدم وحيدة تُرسل كما هي، وغير ذلك
+// يُكتب بأدوار System/User/Assistant وينتهي بـ Assistant: ليكمله النموذج
+func chatPrompt(messages []openai.ChatCompletionMessage) string {
+	if len(messages) == 1 && messages[0].Role == openai.ChatMessageRoleUser {
+		return messageText(messages[0])
+	}
+
+	var b strings.Builder
+	for _, msg := range messages {
+		role := "User"
+		switch msg.Role {
+		case openai.ChatMessageRoleSystem, "developer":
+			role = "System"
+		case openai.ChatMessageRoleAssistant:
+			role = "Assistant"
+		}
+		fmt.Fprintf(&b, "%s: %s\n\n", role, messageText(msg))
+	}
+	b.WriteString("Assistant:")
+	return b.String()
+}
+
+// messageText نص الرسالة، بما فيه الأجزاء النصية من المحتوى المتعدد
+func messageText(msg openai.ChatCompletionMessage) string {
+	if len(msg.MultiContent) == 0 {
+		return msg.Content
+	}
+	parts := make([]string, 0, len(msg.MultiContent))
+	for _, part := range msg.MultiContent {
+		if part.Type == openai.ChatMessagePartTypeText {
+			parts = append(parts, part.Text)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// embeddingInput قبول input نصًا أو قائمة نصوص؛ مصفوفات الرموز غير مدعومة
+func embeddingInput(raw json.RawMessage) ([]string, error) {
+	var single string
+	if err := json.Unmarshal(raw, &single); err == nil {
+		if single == "" {
+			return nil, errors.New("input must not be empty")
+		}
+		return []string{single}, nil
+	}
+	var list []string
+	if err := json.Unmarshal(raw, &list); err != nil {
+		return nil, errors.New("input must be a string or an array of strings")
+	}
+	if len(list) == 0 {
+		return nil, errors.New("input must not be empty")
+	}
+	return list, nil
+}
+
+// encodeBase64 المتجه بصيغة OpenAI: float32 بترتيب little-endian ثم base64
+func encodeBase64(vector []float64) string {
+	buf := make([]byte, 4*len(vector))
+	for i, v := range vector {
+		binary.LittleEndian.PutUint32(buf[4*i:], math.Float32bits(float32(v)))
+	}
+	return base64.StdEncoding.EncodeToString(buf)
+}
+
+// finishReason تحويل سبب الانتهاء من صيغ المزودين إلى قيم OpenAI
+func finishReason(reason string) openai.FinishReason {
+	switch strings.ToLower(reason) {
+	case "max_tokens", "length":
+		return openai.FinishReasonLength
+	case "safety", "content_filter", "recitation":
+		return openai.FinishReasonContentFilter
+	default:
+		return openai.FinishReasonStop
+	}
+}
+
+// usage رموز الرد بصيغة OpenAI
+func usage(resp *types.TextResponse) openai.Usage {
+	total := resp.Tokens
+	if total == 0 {
+		total = resp.PromptTokens + resp.CompletionTokens
+	}
+	return openai.Usage{
+		PromptTokens:     resp.PromptTokens,
+		CompletionTokens: resp.CompletionTokens,
+		TotalTokens:      total,
+	}
+}
+
+// responseModel النموذج الذي أجاب، وإلا المطلوب
+func responseModel(requested, used string) string {
+	if used != "" {
+		return used
+	}
+	if requested == "" {
+		return autoModel
+	}
+	return requested
+}
+
+// completionID معرّف بصيغة chatcmpl-...
+func completionID() string {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("chatcmpl-%d", time.Now().UnixNano())
+	}
+	return "chatcmpl-" + hex.EncodeToString(b)
+}
+
+// writeData كتابة حدث SSE بسطر data: واحد كما يتوقعه عملاء OpenAI
+func writeData(w io.Writer, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(w, "data: %s\n\n", data)
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// writeError رد خطأ بصيغة OpenAI
+func writeError(c *gin.Context, status int, errType, message string) {
+	c.AbortWithStatusJSON(status, openai.ErrorResponse{Error: &openai.APIError{
+		Code:    status,
+		Message: message,
+		Type:    errType,
+	}})
+}
+
+// writeProviderError رد خطأ المزود المتعدد بحالته المقابلة
+func writeProviderError(c *gin.Context, err error) {
+	status, errType := errorStatus(err)
+	writeError(c, status, errType, err.Error())
+}
+
+// errorStatus حالة HTTP ونوع خطأ OpenAI لأخطاء المزودين
+func errorStatus(err error) (int, string) {
+	switch {
+	case errors.Is(err, types.ErrRequestTimeout):
+		return http.StatusGatewayTimeout, "timeout_error"
+	case errors.Is(err, types.ErrRequestCanceled):
+		return statusClientClosedRequest, "request_canceled"
+	case errors.Is(err, types.ErrRateLimitExceeded):
+		return http.StatusTooManyRequests, "rate_limit_error"
+	case errors.Is(err, types.ErrInvalidRequest), errors.Is(err, types.ErrContentFiltered),
+		errors.Is(err, types.ErrModelNotSupported):
+		return http.StatusBadRequest, "invalid_request_error"
+	case errors.Is(err, types.ErrNoProviderAvailable), errors.Is(err, types.ErrProviderUnavailable),
+		errors.Is(err, types.ErrServiceUnavailable), errors.Is(err, types.ErrStreamNotSupported):
+		return http.StatusServiceUnavailable, "service_unavailable"
+	default:
+		return http.StatusInternalServerError, "server_error"
+	}
+}
@@ -0,0 +1,135 @@
+package openaicompat
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nawthtech/nawthtech/backend/internal/ai/types"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+type fakeBackend struct {
+	lastText types.TextRequest
+}
+
+func (f *fakeBackend) GenerateText(ctx context.Context, req types.TextRequest) (*types.TextResponse, error) {
+	f.lastText = req
+	return &types.TextResponse{
+		Text: "hello", PromptTokens: 5, CompletionTokens: 1, Tokens: 6,
+		ModelUsed: "llama3", FinishReason: "stop", CreatedAt: time.Now(),
+	}, nil
+}
+
+func (f *fakeBackend) GenerateTextStream(ctx context.Context, req types.TextRequest) (<-chan types.StreamChunk, error) {
+	f.lastText = req
+	out := make(chan types.StreamChunk, 3)
+	out <- types.StreamChunk{Delta: "hel"}
+	out <- types.StreamChunk{Delta: "lo"}
+	out <- types.StreamChunk{Done: true, Response: &types.TextResponse{
+		Text: "hello", PromptTokens: 5, CompletionTokens: 1, Tokens: 6, ModelUsed: "llama3", FinishReason: "MAX_TOKENS",
+	}}
+	close(out)
+	return out, nil
+}
+
+func (f *fakeBackend) Embed(ctx context.Context, req types.EmbeddingRequest) (*types.EmbeddingResponse, error) {
+	resp := &types.EmbeddingResponse{Tokens: 4, ModelUsed: "nomic-embed-text"}
+	for range req.Input {
+		resp.Embeddings = append(resp.Embeddings, []float64{0.5, -1})
+	}
+	return resp, nil
+}
+
+func (f *fakeBackend) GetAvailableProviders() map[string][]string {
+	return map[string][]string{"text": {"ollama"}}
+}
+
+func TestOpenAICompatibleEndpoints(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	backend := &fakeBackend{}
+	r := gin.New()
+	NewHandler(backend).Register(r.Group("/v1"))
+
+	// خادم حقيقي لأن c.Stream يحتاج CloseNotify الذي لا يدعمه ResponseRecorder
+	server := httptest.NewServer(r)
+	defer server.Close()
+	post := func(path, body string) *httptest.ResponseRecorder {
+		resp, err := http.Post(server.URL+path, "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		w := httptest.NewRecorder()
+		w.Code = resp.StatusCode
+		io.Copy(w.Body, resp.Body)
+		return w
+	}
+
+	w := post("/v1/chat/completions", `{"model":"ollama/llama3","messages":[{"role":"system","content":"be brief"},{"role":"user","content":"hi"}]}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("chat: status %d: %s", w.Code, w.Body)
+	}
+	var chat openai.ChatCompletionResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &chat); err != nil {
+		t.Fatal(err)
+	}
+	if chat.Object != "chat.completion" || chat.Choices[0].Message.Content != "hello" ||
+		chat.Choices[0].FinishReason != openai.FinishReasonStop || chat.Usage.TotalTokens != 6 {
+		t.Errorf("chat response %+v", chat)
+	}
+	if backend.lastText.Metadata["provider"] != "ollama" || backend.lastText.Model != "llama3" {
+		t.Errorf("provider pin not passed: %+v", backend.lastText)
+	}
+	if want := "System: be brief\n\nUser: hi\n\nAssistant:"; backend.lastText.Prompt != want {
+		t.Errorf("prompt %q, want %q", backend.lastText.Prompt, want)
+	}
+
+	w = post("/v1/chat/completions", `{"model":"auto","stream":true,"stream_options":{"include_usage":true},"messages":[{"role":"user","content":"hi"}]}`)
+	var text strings.Builder
+	var events []openai.ChatCompletionStreamResponse
+	for _, line := range strings.Split(w.Body.String(), "\n\n") {
+		data, ok := strings.CutPrefix(line, "data: ")
+		if !ok || data == "[DONE]" {
+			continue
+		}
+		var event openai.ChatCompletionStreamResponse
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			t.Fatalf("chunk %q: %v", data, err)
+		}
+		events = append(events, event)
+		if len(event.Choices) > 0 {
+			text.WriteString(event.Choices[0].Delta.Content)
+		}
+	}
+	if !strings.HasSuffix(w.Body.String(), "data: [DONE]\n\n") {
+		t.Errorf("stream does not end with [DONE]: %q", w.Body.String())
+	}
+	if text.String() != "hello" || len(events) != 5 {
+		t.Fatalf("streamed %q in %d chunks", text.String(), len(events))
+	}
+	if events[0].Choices[0].Delta.Role != openai.ChatMessageRoleAssistant ||
+		events[3].Choices[0].FinishReason != openai.FinishReasonLength ||
+		events[4].Usage == nil || events[4].Usage.TotalTokens != 6 {
+		t.Errorf("stream chunks %+v", events)
+	}
+
+	w = post("/v1/embeddings", `{"model":"nomic-embed-text","input":["a","b"]}`)
+	var embeddings openai.EmbeddingResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &embeddings); err != nil {
+		t.Fatal(err)
+	}
+	if len(embeddings.Data) != 2 || embeddings.Data[1].Index != 1 || embeddings.Data[1].Embedding[1] != -1 {
+		t.Errorf("embeddings %+v", embeddings)
+	}
+
+	if w = post("/v1/embeddings", `{"input":[[1,2,3]]}`); w.Code != http.StatusBadRequest {
+		t.Errorf("token input: status %d, want 400", w.Code)
+	}
+}
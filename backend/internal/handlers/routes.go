@@ -3,11 +3,34 @@ package handlers
 import (
 	"github.com/gin-gonic/gin"
 	"github.com/nawthtech/nawthtech/backend/internal/config"
+	"github.com/nawthtech/nawthtech/backend/internal/handlers/openaicompat"
 	"github.com/nawthtech/nawthtech/backend/internal/middleware"
- "github.com/nawthtech/nawthtech/backend/internal/handlers/monitoring"
+	"github.com/nawthtech/nawthtech/backend/internal/services"
 )
 
 
+// aiQuotaTypes نوع الحصة المحجوزة لكل مسار AI مكلف
+var aiQuotaTypes = map[string]string{
+	"/api/v1/ai/generate":        "text",
+	"/api/v1/ai/generate/stream": "text",
+	"/api/v1/ai/translate":       "translation",
+	"/api/v1/ai/summarize":       "text",
+	"/api/v1/ai/analyze-image":   "image_analysis",
+	"/api/v1/ai/analyze-text":    "analysis",
+	"/api/v1/ai/generate-video":  "video",
+	"/v1/chat/completions":       "text",
+	"/v1/embeddings":             "embedding",
+}
+
+// aiQuotaOptions إعدادات حصص AI؛ الطبقة تُقرأ من سجل المستخدم المسجل
+func aiQuotaOptions(hc *HandlerContainer) middleware.AIQuotaOptions {
+	opts := middleware.AIQuotaOptions{Types: aiQuotaTypes}
+	if hc.User != nil {
+		opts.Tier = hc.User.service.GetUserTier
+	}
+	return opts
+}
+
 // RegisterAllRoutes تسجيل جميع المسارات
 func RegisterAllRoutes(app *gin.Engine, cfg *config.Config, hc *HandlerContainer) {
 	// ==================== Public Routes ====================
@@ -36,21 +59,39 @@ func RegisterAllRoutes(app *gin.Engine, cfg *config.Config, hc *HandlerContainer
 			health.GET("/ready", func(c *gin.Context) { c.JSON(200, gin.H{"status": "ready"}) })
 		}
 	}
-  // monitoringGroup
-	monitoringGroup := api.Group("/monitoring")
-{
-    monitoringGroup.GET("/ai/metrics", monitoring.GetAIMetrics)
-    monitoringGroup.GET("/ai/health", monitoring.GetAIHealth)
-    monitoringGroup.GET("/ai/alerts", monitoring.GetAIAlerts)
-    monitoringGroup.GET("/ai/stats", monitoring.GetAIStats)
-  }
- }
+	
+	// Direct uploads (authorized by the upload token itself)
+	if hc.Upload != nil {
+		api.PUT("/upload/direct/:token", hc.Upload.DirectUpload)
+		api.OPTIONS("/upload/resumable", hc.Upload.ResumableOptions)
+	}
+	
+	// Stored files (public download)
+	files := app.Group("/files")
+	{
+		if hc.Upload != nil {
+			files.GET("/:id/:name", hc.Upload.DownloadFile)
+		}
+	}
+	
+	// Image derivatives (public, authorized by the URL signature)
+	images := app.Group("/images")
+	{
+		if hc.Image != nil {
+			images.GET("/:id", hc.Image.ServeImage)
+		}
+	}
 	// AI endpoints
- ai := api.Group("/ai")
+ // الحساب اختياري: المستخدم المسجل يُحتسب بطبقته، والزائر بعنوانه
+ ai := api.Group("/ai", middleware.OptionalAuthMiddleware(cfg))
  {
 	if hc.AI != nil {
+		if hc.AI.aiClient != nil && hc.AI.aiClient.CostManager() != nil {
+			ai.Use(middleware.AIQuota(hc.AI.aiClient.CostManager(), aiQuotaOptions(hc)))
+		}
 		ai.GET("/capabilities", hc.AI.GetAICapabilitiesHandler)
 		ai.POST("/generate", hc.AI.GenerateContentHandler)
+		ai.POST("/generate/stream", hc.AI.GenerateContentStreamHandler)
 		ai.POST("/translate", hc.AI.TranslateTextHandler)
 		ai.POST("/summarize", hc.AI.SummarizeTextHandler)
 		ai.POST("/analyze-image", hc.AI.AnalyzeImageHandler)
@@ -62,7 +103,7 @@ func RegisterAllRoutes(app *gin.Engine, cfg *config.Config, hc *HandlerContainer
 				providers := hc.AI.aiClient.GetAvailableProviders()
 				c.JSON(200, gin.H{"providers": providers})
 			} else {
-				c.JSON(200, gin.H{"providers": {}})
+				c.JSON(200, gin.H{"providers": []string{}})
 			}
 		})
 		ai.GET("/usage-stats", func(c *gin.Context) {
@@ -70,7 +111,7 @@ func RegisterAllRoutes(app *gin.Engine, cfg *config.Config, hc *HandlerContainer
 				stats := hc.AI.aiClient.GetUsageStatistics()
 				c.JSON(200, gin.H{"stats": stats})
 			} else {
-				c.JSON(200, gin.H{"stats": {}})
+				c.JSON(200, gin.H{"stats": gin.H{}})
 			}
 		})
 	} else {
@@ -80,6 +121,15 @@ func RegisterAllRoutes(app *gin.Engine, cfg *config.Config, hc *HandlerContainer
 	}
 }
 	
+	// OpenAI-compatible API: SDK clients send their JWT as the API key
+	if hc.AI != nil && hc.AI.aiClient != nil && hc.AI.aiClient.MultiProvider() != nil {
+		openAI := app.Group("/v1", middleware.AuthMiddleware(cfg))
+		if hc.AI.aiClient.CostManager() != nil {
+			openAI.Use(middleware.AIQuota(hc.AI.aiClient.CostManager(), aiQuotaOptions(hc)))
+		}
+		openaicompat.NewHandler(hc.AI.aiClient.MultiProvider()).Register(openAI)
+	}
+
 	// Email endpoints (public for setup)
 	email := api.Group("/email")
 	{
@@ -131,7 +181,10 @@ func RegisterAllRoutes(app *gin.Engine, cfg *config.Config, hc *HandlerContainer
 	service := protected.Group("/services")
 	{
 		if hc.Service != nil {
-			service.GET("", hc.Service.GetServices)
+			service.GET("", middleware.ResponseCache(hc.CatalogCache, middleware.ResponseCacheOptions{
+				TTL:  cfg.Cache.TTL,
+				Tags: []string{services.CacheTagServices},
+			}), hc.Service.GetServices)
 			service.POST("", hc.Service.CreateService)
 		}
 	}
@@ -140,7 +193,10 @@ func RegisterAllRoutes(app *gin.Engine, cfg *config.Config, hc *HandlerContainer
 	category := protected.Group("/categories")
 	{
 		if hc.Category != nil {
-			category.GET("", hc.Category.GetCategories)
+			category.GET("", middleware.ResponseCache(hc.CatalogCache, middleware.ResponseCacheOptions{
+				TTL:  cfg.Cache.TTL,
+				Tags: []string{services.CacheTagCategories, services.CacheTagServices},
+			}), hc.Category.GetCategories)
 			category.POST("", hc.Category.CreateCategory)
 		}
 	}
@@ -168,6 +224,21 @@ func RegisterAllRoutes(app *gin.Engine, cfg *config.Config, hc *HandlerContainer
 	{
 		if hc.Upload != nil {
 			upload.POST("", hc.Upload.UploadFile)
+			upload.PUT("/:id", hc.Upload.ReplaceFile)
+			upload.DELETE("/:id", hc.Upload.DeleteFile)
+			upload.GET("/usage", hc.Upload.GetStorageUsage)
+			upload.GET("/trash", hc.Upload.GetDeletedFiles)
+			upload.POST("/:id/restore", hc.Upload.RestoreFile)
+			upload.GET("/presigned-url", hc.Upload.GeneratePresignedURL) // مهمل: يبقى للعملاء الحاليين
+			upload.POST("/presigned-url", hc.Upload.GeneratePresignedURL)
+			upload.POST("/finalize", hc.Upload.FinalizeUpload)
+			upload.POST("/resumable", hc.Upload.CreateResumableUpload)
+			upload.HEAD("/resumable/:id", hc.Upload.ResumableUploadStatus)
+			upload.PATCH("/resumable/:id", hc.Upload.UploadChunk)
+			upload.DELETE("/resumable/:id", hc.Upload.AbortResumableUpload)
+		}
+		if hc.Image != nil {
+			upload.POST("/images/sign", hc.Image.SignImageURL)
 		}
 	}
 	
@@ -187,6 +258,10 @@ func RegisterAllRoutes(app *gin.Engine, cfg *config.Config, hc *HandlerContainer
 		if hc.Admin != nil {
 			admin.GET("/stats", hc.Admin.GetStatistics)
 			admin.GET("/users", hc.Admin.GetAllUsers)
+			admin.PUT("/users/:id/tier", hc.Admin.SetUserTier)
+		}
+		if hc.Upload != nil {
+			admin.POST("/storage/gc", hc.Upload.CollectGarbage)
 		}
 		if hc.Email != nil {
 			admin.GET("/email/reports", func(c *gin.Context) {
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nawthtech/nawthtech/backend/internal/services"
+)
+
+// fakeUploadService خدمة رفع تسجل آخر طلب رابط موقع
+type fakeUploadService struct {
+	services.UploadService
+	presigned []services.PresignedUploadRequest
+}
+
+func (f *fakeUploadService) GeneratePresignedURL(ctx context.Context, req services.PresignedUploadRequest) (*services.PresignedUpload, error) {
+	f.presigned = append(f.presigned, req)
+	return &services.PresignedUpload{Token: "upl_test", FileID: "file_1", Method: http.MethodPut}, nil
+}
+
+func TestGeneratePresignedURLMethods(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	uploads := &fakeUploadService{}
+	handler := &UploadHandler{service: uploads}
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("userID", "user_1")
+		c.Next()
+	})
+	r.GET("/upload/presigned-url", handler.GeneratePresignedURL)
+	r.POST("/upload/presigned-url", handler.GeneratePresignedURL)
+
+	post := httptest.NewRequest(http.MethodPost, "/upload/presigned-url",
+		strings.NewReader(`{"file_name":"a.png","file_type":"image/png","file_size":42}`))
+	post.Header.Set("Content-Type", "application/json")
+	get := httptest.NewRequest(http.MethodGet, "/upload/presigned-url?file_name=a.png&file_type=image/png&file_size=42", nil)
+
+	for _, req := range []*http.Request{post, get} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: status %d: %s", req.Method, w.Code, w.Body)
+		}
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/upload/presigned-url?file_size=abc", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("invalid query: status %d, want 400", w.Code)
+	}
+
+	want := services.PresignedUploadRequest{UserID: "user_1", FileName: "a.png", FileType: "image/png", FileSize: 42}
+	if len(uploads.presigned) != 2 || uploads.presigned[0] != want || uploads.presigned[1] != want {
+		t.Errorf("presigned requests %+v, want both %+v", uploads.presigned, want)
+	}
+}
@@ -0,0 +1,216 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nawthtech/nawthtech/backend/internal/services"
+)
+
+// ================================
+// الرفع المجزأ القابل للاستئناف (بروتوكول tus 1.0.0)
+// ================================
+
+const (
+	tusVersion    = "1.0.0"
+	tusExtensions = "creation,checksum,expiration,termination"
+
+	// tusStatusChecksumMismatch رمز الحالة المعرف في امتداد checksum لبروتوكول tus
+	tusStatusChecksumMismatch = 460
+)
+
+// ResumableOptions إعلان قدرات الخادم (OPTIONS)
+func (h *UploadHandler) ResumableOptions(c *gin.Context) {
+	c.Header("Tus-Resumable", tusVersion)
+	c.Header("Tus-Version", tusVersion)
+	c.Header("Tus-Extension", tusExtensions)
+	c.Header("Tus-Checksum-Algorithm", strings.Join(services.ResumableChecksumAlgorithms, ","))
+	c.Status(http.StatusNoContent)
+}
+
+// CreateResumableUpload إنشاء رفع مجزأ (POST) من ترويستي Upload-Length و Upload-Metadata
+func (h *UploadHandler) CreateResumableUpload(c *gin.Context) {
+	c.Header("Tus-Resumable", tusVersion)
+
+	userID := getCurrentUserID(c)
+	if userID == "" {
+		errorResponse(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	length, err := strconv.ParseInt(c.GetHeader("Upload-Length"), 10, 64)
+	if err != nil || length <= 0 {
+		errorResponse(c, http.StatusBadRequest, "Invalid Upload-Length header")
+		return
+	}
+
+	metadata := parseTusMetadata(c.GetHeader("Upload-Metadata"))
+	fileName := metadata["filename"]
+	if fileName == "" {
+		fileName = metadata["name"]
+	}
+	fileType := metadata["filetype"]
+	if fileType == "" {
+		fileType = metadata["type"]
+	}
+
+	upload, err := h.service.CreateResumableUpload(c.Request.Context(), services.ResumableUploadRequest{
+		UserID:   userID,
+		FileName: fileName,
+		FileType: fileType,
+		Length:   length,
+	})
+	if err != nil {
+		errorResponse(c, resumableErrorStatus(err), err.Error())
+		return
+	}
+
+	c.Header("Location", strings.TrimRight(c.Request.URL.Path, "/")+"/"+upload.ID)
+	c.Header("Upload-Offset", "0")
+	c.Header("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    upload,
+		"error":   nil,
+	})
+}
+
+// ResumableUploadStatus معرفة الإزاحة الحالية للاستئناف (HEAD)
+func (h *UploadHandler) ResumableUploadStatus(c *gin.Context) {
+	c.Header("Tus-Resumable", tusVersion)
+	c.Header("Cache-Control", "no-store")
+
+	upload, ok := h.ownedResumableUpload(c)
+	if !ok {
+		return
+	}
+
+	setResumableHeaders(c, upload)
+	c.Status(http.StatusOK)
+}
+
+// UploadChunk كتابة جزء عند الإزاحة المحددة (PATCH)
+func (h *UploadHandler) UploadChunk(c *gin.Context) {
+	c.Header("Tus-Resumable", tusVersion)
+
+	if c.ContentType() != "application/offset+octet-stream" {
+		c.AbortWithStatus(http.StatusUnsupportedMediaType)
+		return
+	}
+
+	offset, err := strconv.ParseInt(c.GetHeader("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	if c.Request.ContentLength < 0 {
+		c.AbortWithStatus(http.StatusLengthRequired)
+		return
+	}
+
+	if _, ok := h.ownedResumableUpload(c); !ok {
+		return
+	}
+
+	upload, err := h.service.WriteChunk(c.Request.Context(), c.Param("id"), offset,
+		c.Request.Body, c.Request.ContentLength, c.GetHeader("Upload-Checksum"))
+	if err != nil {
+		c.AbortWithStatus(resumableErrorStatus(err))
+		return
+	}
+
+	setResumableHeaders(c, upload)
+	if upload.FileID != "" {
+		c.Header("X-File-ID", upload.FileID)
+		c.Header("X-File-URL", upload.URL)
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// AbortResumableUpload إلغاء الرفع وحذف أجزائه (DELETE)
+func (h *UploadHandler) AbortResumableUpload(c *gin.Context) {
+	c.Header("Tus-Resumable", tusVersion)
+
+	if _, ok := h.ownedResumableUpload(c); !ok {
+		return
+	}
+
+	if err := h.service.AbortResumableUpload(c.Request.Context(), c.Param("id")); err != nil {
+		c.AbortWithStatus(resumableErrorStatus(err))
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ownedResumableUpload تحميل الرفع والتأكد أنه يخص المستخدم الحالي
+func (h *UploadHandler) ownedResumableUpload(c *gin.Context) (*services.ResumableUpload, bool) {
+	userID := getCurrentUserID(c)
+	if userID == "" {
+		c.AbortWithStatus(http.StatusUnauthorized)
+		return nil, false
+	}
+
+	upload, err := h.service.GetResumableUpload(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.AbortWithStatus(resumableErrorStatus(err))
+		return nil, false
+	}
+
+	// إخفاء وجود الرفع عن غير مالكه
+	if upload.UserID != userID {
+		c.AbortWithStatus(http.StatusNotFound)
+		return nil, false
+	}
+
+	return upload, true
+}
+
+// setResumableHeaders ترويسات الحالة المشتركة
+func setResumableHeaders(c *gin.Context, upload *services.ResumableUpload) {
+	c.Header("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	c.Header("Upload-Length", strconv.FormatInt(upload.Length, 10))
+	c.Header("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+}
+
+// parseTusMetadata تحليل Upload-Metadata: أزواج "key base64value" مفصولة بفواصل
+func parseTusMetadata(header string) map[string]string {
+	metadata := map[string]string{}
+	for _, pair := range strings.Split(header, ",") {
+		parts := strings.SplitN(strings.TrimSpace(pair), " ", 2)
+		if parts[0] == "" {
+			continue
+		}
+		if len(parts) == 1 {
+			metadata[parts[0]] = ""
+			continue
+		}
+		if value, err := base64.StdEncoding.DecodeString(parts[1]); err == nil {
+			metadata[parts[0]] = string(value)
+		}
+	}
+	return metadata
+}
+
+// resumableErrorStatus تحويل أخطاء الرفع المجزأ إلى رموز HTTP حسب بروتوكول tus
+func resumableErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrUploadNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrUploadExpired):
+		return http.StatusGone
+	case errors.Is(err, services.ErrUploadOffsetMismatch), errors.Is(err, services.ErrUploadAlreadyComplete):
+		return http.StatusConflict
+	case errors.Is(err, services.ErrChecksumMismatch):
+		return tusStatusChecksumMismatch
+	case errors.Is(err, services.ErrUnsupportedChecksum):
+		return http.StatusBadRequest
+	default:
+		return uploadErrorStatus(err)
+	}
+}
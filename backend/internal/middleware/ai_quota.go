@@ -0,0 +1,117 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nawthtech/nawthtech/backend/internal/ai"
+)
+
+// ================================
+// حصص نقاط AI
+// ================================
+
+// quotaUsageKey مفتاح السياق الذي يضع فيه المعالج الكمية المستهلكة فعلاً
+const quotaUsageKey = "aiQuotaUsage"
+
+// QuotaEnforcer حجز الحصة قبل الاستدعاء واعتمادها أو إلغاؤها بعده؛ ai.CostManager يحققه
+type QuotaEnforcer interface {
+	ReserveQuota(ctx context.Context, userID, tier, requestType string, amount int64) (*ai.QuotaReservation, error)
+	CommitQuota(ctx context.Context, r *ai.QuotaReservation, actual int64) error
+	ReleaseQuota(ctx context.Context, r *ai.QuotaReservation) error
+}
+
+// AIQuotaOptions إعدادات فرض الحصص
+type AIQuotaOptions struct {
+	// Types نوع الحصة لكل مسار (c.FullPath())؛ المسارات غير المذكورة تمر دون حجز
+	Types map[string]string
+	// Estimate الكمية المحجوزة قبل الاستدعاء (الافتراضي 1)
+	Estimate func(c *gin.Context, quotaType string) int64
+	// Tier طبقة المستخدم المسجل من سجله؛ عند غيابها أو فشلها تطبق الطبقة المجانية
+	Tier func(ctx context.Context, userID string) (string, error)
+}
+
+// SetQuotaUsage تسجيل الكمية المستهلكة فعلاً ليُعتمد بها الحجز بدل التقدير
+func SetQuotaUsage(c *gin.Context, amount int64) {
+	c.Set(quotaUsageKey, amount)
+}
+
+// AIQuota فرض حصص المستخدم على نقاط AI والفيديو: حجز قبل المعالج، ثم اعتماد عند
+// النجاح أو إلغاء عند الفشل. الرفض 429 مع X-Quota-Remaining و Retry-After
+func AIQuota(enforcer QuotaEnforcer, opts AIQuotaOptions) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		quotaType, ok := opts.Types[c.FullPath()]
+		if enforcer == nil || !ok {
+			c.Next()
+			return
+		}
+
+		// الزائر بلا حساب يُحتسب بعنوانه على الطبقة المجانية
+		ctx := c.Request.Context()
+		userID := c.GetString("userID")
+		tier := c.GetString("userTier")
+		if userID == "" {
+			userID = "ip:" + c.ClientIP()
+		} else if tier == "" && opts.Tier != nil {
+			var err error
+			if tier, err = opts.Tier(ctx, userID); err != nil {
+				log.Printf("Warning: failed to look up user tier: %v", err)
+			}
+			c.Set("userTier", tier)
+		}
+
+		amount := int64(1)
+		if opts.Estimate != nil {
+			amount = opts.Estimate(c, quotaType)
+		}
+
+		reservation, err := enforcer.ReserveQuota(ctx, userID, tier, quotaType, amount)
+		if err != nil {
+			var quotaErr *ai.QuotaError
+			if !errors.As(err, &quotaErr) {
+				// عطل مخزن الحصص لا يوقف الخدمة
+				log.Printf("Warning: failed to reserve AI quota: %v", err)
+				c.Next()
+				return
+			}
+			c.Header("X-Quota-Remaining", strconv.FormatInt(quotaErr.Remaining, 10))
+			if !quotaErr.ResetAt.IsZero() {
+				retry := max(int(time.Until(quotaErr.ResetAt).Seconds())+1, 1)
+				c.Header("Retry-After", strconv.Itoa(retry))
+			}
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+				"success": false,
+				"error":   "QUOTA_EXCEEDED",
+				"message": quotaErr.Reason,
+			})
+			return
+		}
+
+		if reservation.Limit > 0 {
+			c.Header("X-Quota-Limit", strconv.FormatInt(reservation.Limit, 10))
+			c.Header("X-Quota-Remaining", strconv.FormatInt(reservation.Remaining, 10))
+		}
+
+		c.Next()
+
+		// الاعتماد بعد انتهاء الطلب، حتى لو أُلغي سياقه
+		settleCtx := context.WithoutCancel(ctx)
+		if c.Writer.Status() >= http.StatusBadRequest {
+			err = enforcer.ReleaseQuota(settleCtx, reservation)
+		} else {
+			var used int64
+			if v, ok := c.Get(quotaUsageKey); ok {
+				used, _ = v.(int64)
+			}
+			err = enforcer.CommitQuota(settleCtx, reservation, used)
+		}
+		if err != nil {
+			log.Printf("Warning: failed to settle AI quota: %v", err)
+		}
+	}
+}
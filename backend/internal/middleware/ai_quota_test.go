@@ -0,0 +1,145 @@
+package middleware
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nawthtech/nawthtech/backend/internal/ai"
+	"github.com/nawthtech/nawthtech/backend/internal/db/migrations"
+	"github.com/nawthtech/nawthtech/backend/internal/repository"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func openQuotaDB(t *testing.T) *sql.DB {
+	t.Helper()
+	database, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "quota.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { database.Close() })
+	migrator, err := migrations.New(database, migrations.SQLite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Up(context.Background()); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return database
+}
+
+func TestAIQuotaReservesAndRejects(t *testing.T) {
+	database := openQuotaDB(t)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("userID", "user_1")
+		c.Next()
+	})
+	r.Use(AIQuota(ai.NewCostManagerWithStore(repository.NewStore(database)), AIQuotaOptions{
+		Types: map[string]string{"/video": "video"},
+	}))
+	r.POST("/video", func(c *gin.Context) {
+		if c.Query("fail") != "" {
+			c.Status(http.StatusBadGateway)
+			return
+		}
+		c.Status(http.StatusAccepted)
+	})
+
+	post := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, nil))
+		return w
+	}
+
+	// الطلب الفاشل يلغي حجزه فلا يُنقص الحصة المجانية (10 فيديو شهريًا)
+	if w := post("/video?fail=1"); w.Code != http.StatusBadGateway {
+		t.Fatalf("failed call: status %d", w.Code)
+	}
+	for i := 1; i <= 10; i++ {
+		w := post("/video")
+		if w.Code != http.StatusAccepted {
+			t.Fatalf("request %d: status %d", i, w.Code)
+		}
+		if got := w.Header().Get("X-Quota-Remaining"); got != strconv.Itoa(10-i) {
+			t.Errorf("request %d: X-Quota-Remaining %q", i, got)
+		}
+	}
+
+	w := post("/video")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("over quota: status %d, want 429", w.Code)
+	}
+	if w.Header().Get("X-Quota-Remaining") != "0" {
+		t.Errorf("X-Quota-Remaining %q, want 0", w.Header().Get("X-Quota-Remaining"))
+	}
+	if retry, err := strconv.Atoi(w.Header().Get("Retry-After")); err != nil || retry <= 0 {
+		t.Errorf("Retry-After %q", w.Header().Get("Retry-After"))
+	}
+
+	var used, reserved int64
+	database.QueryRow("SELECT used, reserved FROM ai_quota_usage WHERE user_id = 'user_1'").Scan(&used, &reserved)
+	if used != 10 || reserved != 0 {
+		t.Errorf("ledger used=%d reserved=%d, want 10 and 0", used, reserved)
+	}
+}
+
+func TestAIQuotaUsesUserTier(t *testing.T) {
+	database := openQuotaDB(t)
+	if _, err := database.Exec(`INSERT INTO users (id, email, username, password_hash, first_name, last_name, tier)
+		VALUES ('user_premium', 'p@example.com', 'premium', 'x', 'P', 'U', 'premium')`); err != nil {
+		t.Fatal(err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		if id := c.GetHeader("X-Test-User"); id != "" {
+			c.Set("userID", id)
+		}
+		c.Next()
+	})
+	r.Use(AIQuota(ai.NewCostManagerWithStore(repository.NewStore(database)), AIQuotaOptions{
+		Types: map[string]string{"/video": "video"},
+		Tier:  repository.NewStore(database).Users.GetTier,
+	}))
+	r.POST("/video", func(c *gin.Context) {
+		c.String(http.StatusAccepted, c.GetString("userTier"))
+	})
+
+	post := func(userID string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/video", nil)
+		if userID != "" {
+			req.Header.Set("X-Test-User", userID)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// الطبقة المميزة من سجل المستخدم: 100 فيديو بدل 10 المجانية
+	for i := 1; i <= 11; i++ {
+		if w := post("user_premium"); w.Code != http.StatusAccepted {
+			t.Fatalf("premium request %d: status %d", i, w.Code)
+		}
+	}
+	w := post("user_premium")
+	if w.Header().Get("X-Quota-Limit") != "100" || w.Body.String() != "premium" {
+		t.Errorf("premium: X-Quota-Limit %q, tier %q", w.Header().Get("X-Quota-Limit"), w.Body.String())
+	}
+
+	// الزائر والمستخدم غير الموجود يبقيان على الطبقة المجانية
+	for _, userID := range []string{"", "user_missing"} {
+		if w := post(userID); w.Header().Get("X-Quota-Limit") != "10" {
+			t.Errorf("user %q: X-Quota-Limit %q, want 10", userID, w.Header().Get("X-Quota-Limit"))
+		}
+	}
+}
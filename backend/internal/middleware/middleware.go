@@ -65,6 +65,18 @@ func AuthMiddleware(cfg *config.Config) gin.HandlerFunc {
 	}
 }
 
+// OptionalAuthMiddleware يضع المستخدم في السياق عند وجود توكن، ويمرر الزائر بلا توكن؛
+// التوكن غير الصالح يُرفض حتى لا يُعامل صاحبه كزائر
+func OptionalAuthMiddleware(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") == "" {
+			c.Next()
+			return
+		}
+		AuthMiddleware(cfg)(c)
+	}
+}
+
 // AdminMiddleware يتحقق من أن المستخدم مشرف
 func AdminMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -0,0 +1,307 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nawthtech/nawthtech/backend/internal/cache"
+)
+
+// ================================
+// ذاكرة استجابات HTTP
+// ================================
+
+// defaultResponseCacheTTL مدة الصلاحية عند عدم تحديدها
+const defaultResponseCacheTTL = time.Minute
+
+// ResponseCacheOptions إعدادات تخزين الاستجابات
+type ResponseCacheOptions struct {
+	// TTL مدة الصلاحية عند عدم تحديد max-age في استجابة المعالج
+	TTL time.Duration
+	// Tags وسوم الإبطال؛ إبطالها من طبقة الخدمات يُنهي الاستجابات المخزنة
+	Tags []string
+	// VaryByUser فصل الاستجابات لكل مستخدم (للمسارات التي يختلف محتواها حسب المستخدم)
+	VaryByUser bool
+}
+
+// cachedResponse استجابة مخزنة
+type cachedResponse struct {
+	Status   int         `json:"status"`
+	Header   http.Header `json:"header"`
+	Body     []byte      `json:"body"`
+	ETag     string      `json:"etag"`
+	StoredAt time.Time   `json:"stored_at"`
+}
+
+// uncachedHeaders رؤوس لا تُخزن مع الاستجابة: رؤوس الاتصال (hop-by-hop)، ورؤوس خاصة بالعميل،
+// والرؤوس التي يحسبها الوسيط عند كل رد
+var uncachedHeaders = map[string]bool{
+	"Connection":          true,
+	"Keep-Alive":          true,
+	"Proxy-Authenticate":  true,
+	"Proxy-Authorization": true,
+	"Te":                  true,
+	"Trailer":             true,
+	"Transfer-Encoding":   true,
+	"Upgrade":             true,
+	"Set-Cookie":          true,
+	"Content-Length":      true,
+	"Date":                true,
+	"Age":                 true,
+	"Etag":                true,
+	"Last-Modified":       true,
+	"X-Cache":             true,
+}
+
+// ResponseCache تخزين استجابات GET الناجحة مع احترام Cache-Control في الطلب
+// والاستجابة، والرد بـ 304 على الطلبات الشرطية (If-None-Match / If-Modified-Since)
+func ResponseCache(rt *cache.ReadThrough, opts ResponseCacheOptions) gin.HandlerFunc {
+	if opts.TTL <= 0 {
+		opts.TTL = defaultResponseCacheTTL
+	}
+	return func(c *gin.Context) {
+		method := c.Request.Method
+		if rt == nil || (method != http.MethodGet && method != http.MethodHead) {
+			c.Next()
+			return
+		}
+
+		reqCC := parseCacheControl(c.GetHeader("Cache-Control"))
+		if _, ok := reqCC["no-store"]; ok {
+			c.Next()
+			return
+		}
+
+		key := "http:" + method + ":" + c.Request.URL.RequestURI()
+		if opts.VaryByUser {
+			key += ":" + c.GetString("userID")
+		}
+
+		// الاستجابة لطلب موثق لا تُعلن عامة للوسطاء حتى لو خزنّاها هنا
+		visibility := "public"
+		if c.GetHeader("Authorization") != "" {
+			visibility = "private"
+		}
+
+		_, noCache := reqCC["no-cache"]
+		if !noCache {
+			var stored cachedResponse
+			if ok, _ := rt.Lookup(key, &stored); ok && withinMaxAge(reqCC, stored.StoredAt) {
+				writeCachedResponse(c, &stored, "HIT", visibility, opts.TTL)
+				c.Abort()
+				return
+			}
+		}
+
+		versions := rt.TagVersions(opts.Tags...)
+		writer := &bufferedWriter{ResponseWriter: c.Writer, status: http.StatusOK}
+		c.Writer = writer
+		c.Next()
+		c.Writer = writer.ResponseWriter
+
+		if writer.status != http.StatusOK {
+			writer.flush()
+			return
+		}
+
+		header := c.Writer.Header()
+		stored := cachedResponse{
+			Status:   writer.status,
+			Header:   cacheableHeaders(header),
+			Body:     writer.body.Bytes(),
+			ETag:     header.Get("ETag"),
+			StoredAt: time.Now().UTC().Truncate(time.Second),
+		}
+		if stored.ETag == "" {
+			stored.ETag = bodyETag(stored.Body)
+		}
+
+		respCC := parseCacheControl(header.Get("Cache-Control"))
+		_, private := respCC["private"]
+		_, noStore := respCC["no-store"]
+		ttl := responseTTL(respCC, opts.TTL)
+		if !private && !noStore && ttl > 0 {
+			_ = rt.Store(key, versions, stored, ttl)
+		}
+
+		writeCachedResponse(c, &stored, "MISS", visibility, ttl)
+	}
+}
+
+// writeCachedResponse كتابة الاستجابة مع رؤوس التحقق، أو 304 إذا كانت نسخة العميل صالحة
+func writeCachedResponse(c *gin.Context, resp *cachedResponse, status, visibility string, ttl time.Duration) {
+	header := c.Writer.Header()
+	for name, values := range resp.Header {
+		header[name] = values
+	}
+	if header.Get("Cache-Control") == "" {
+		header.Set("Cache-Control", fmt.Sprintf("%s, max-age=%d", visibility, int(ttl.Seconds())))
+	}
+	header.Set("ETag", resp.ETag)
+	header.Set("Last-Modified", resp.StoredAt.Format(http.TimeFormat))
+	header.Set("X-Cache", status)
+	if status == "HIT" {
+		header.Set("Age", strconv.Itoa(int(time.Since(resp.StoredAt).Seconds())))
+	}
+
+	if notModified(c.Request, resp) {
+		c.Status(http.StatusNotModified)
+		c.Writer.WriteHeaderNow()
+		return
+	}
+
+	c.Status(resp.Status)
+	c.Writer.WriteHeaderNow()
+	if c.Request.Method != http.MethodHead {
+		_, _ = c.Writer.Write(resp.Body)
+	}
+}
+
+// cacheableHeaders نسخة من رؤوس المعالج دون رؤوس الاتصال وما يذكره رأس Connection
+func cacheableHeaders(header http.Header) http.Header {
+	skip := make(map[string]bool)
+	for _, value := range header.Values("Connection") {
+		for _, name := range strings.Split(value, ",") {
+			skip[http.CanonicalHeaderKey(strings.TrimSpace(name))] = true
+		}
+	}
+
+	stored := make(http.Header, len(header))
+	for name, values := range header {
+		if uncachedHeaders[name] || skip[name] {
+			continue
+		}
+		stored[name] = append([]string(nil), values...)
+	}
+	return stored
+}
+
+// notModified هل نسخة العميل مطابقة (If-None-Match له الأولوية على If-Modified-Since)
+func notModified(r *http.Request, resp *cachedResponse) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		return etagMatches(inm, resp.ETag)
+	}
+	if ims := r.Header.Get("If-Modified-Since"); ims != "" {
+		since, err := http.ParseTime(ims)
+		return err == nil && !resp.StoredAt.After(since)
+	}
+	return false
+}
+
+// etagMatches مقارنة ضعيفة لقائمة If-None-Match
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// bodyETag وسم ضعيف مشتق من محتوى الاستجابة
+func bodyETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `W/"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// parseCacheControl تحليل توجيهات Cache-Control إلى خريطة (القيم بدون علامات تنصيص)
+func parseCacheControl(header string) map[string]string {
+	directives := make(map[string]string)
+	for _, part := range strings.Split(header, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, value, _ := strings.Cut(part, "=")
+		directives[strings.ToLower(strings.TrimSpace(name))] = strings.Trim(strings.TrimSpace(value), `"`)
+	}
+	return directives
+}
+
+// withinMaxAge هل عمر النسخة المخزنة ضمن max-age الذي يقبله العميل
+func withinMaxAge(reqCC map[string]string, storedAt time.Time) bool {
+	value, ok := reqCC["max-age"]
+	if !ok {
+		return true
+	}
+	maxAge, err := strconv.Atoi(value)
+	if err != nil {
+		return true
+	}
+	if maxAge <= 0 {
+		return false
+	}
+	return time.Since(storedAt) <= time.Duration(maxAge)*time.Second
+}
+
+// responseTTL مدة التخزين: s-maxage ثم max-age من استجابة المعالج، وإلا الافتراضي
+func responseTTL(respCC map[string]string, fallback time.Duration) time.Duration {
+	for _, directive := range []string{"s-maxage", "max-age"} {
+		if value, ok := respCC[directive]; ok {
+			if seconds, err := strconv.Atoi(value); err == nil {
+				return time.Duration(seconds) * time.Second
+			}
+		}
+	}
+	return fallback
+}
+
+// bufferedWriter يحجز الاستجابة في الذاكرة حتى يقرر الوسيط تخزينها
+type bufferedWriter struct {
+	gin.ResponseWriter
+	status  int
+	written bool
+	body    bytes.Buffer
+}
+
+func (w *bufferedWriter) WriteHeader(code int) {
+	if code > 0 && !w.written {
+		w.status = code
+	}
+}
+
+func (w *bufferedWriter) WriteHeaderNow() {
+	w.written = true
+}
+
+func (w *bufferedWriter) Write(data []byte) (int, error) {
+	w.written = true
+	return w.body.Write(data)
+}
+
+func (w *bufferedWriter) WriteString(s string) (int, error) {
+	w.written = true
+	return w.body.WriteString(s)
+}
+
+func (w *bufferedWriter) Status() int {
+	return w.status
+}
+
+func (w *bufferedWriter) Size() int {
+	if !w.written {
+		return -1
+	}
+	return w.body.Len()
+}
+
+func (w *bufferedWriter) Written() bool {
+	return w.written
+}
+
+// flush تمرير الاستجابة كما هي دون تخزين
+func (w *bufferedWriter) flush() {
+	w.ResponseWriter.WriteHeader(w.status)
+	w.ResponseWriter.WriteHeaderNow()
+	if w.body.Len() > 0 {
+		_, _ = w.ResponseWriter.Write(w.body.Bytes())
+	}
+}
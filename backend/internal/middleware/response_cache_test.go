@@ -0,0 +1,149 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nawthtech/nawthtech/backend/internal/cache"
+)
+
+func newCachedRouter(rt *cache.ReadThrough, calls *int) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/services", ResponseCache(rt, ResponseCacheOptions{TTL: time.Minute, Tags: []string{"services"}}), func(c *gin.Context) {
+		*calls++
+		c.JSON(http.StatusOK, gin.H{"calls": *calls})
+	})
+	r.GET("/private", ResponseCache(rt, ResponseCacheOptions{TTL: time.Minute}), func(c *gin.Context) {
+		*calls++
+		c.Header("Cache-Control", "private, max-age=60")
+		c.String(http.StatusOK, "secret")
+	})
+	return r
+}
+
+func serve(r http.Handler, path string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestResponseCacheHitAndInvalidation(t *testing.T) {
+	rt := cache.NewReadThrough(cache.NewMemory(0, 0), time.Minute)
+	calls := 0
+	r := newCachedRouter(rt, &calls)
+
+	first := serve(r, "/services", nil)
+	if first.Code != http.StatusOK || first.Header().Get("X-Cache") != "MISS" || first.Header().Get("ETag") == "" {
+		t.Fatalf("unexpected first response: %d %v", first.Code, first.Header())
+	}
+
+	second := serve(r, "/services", nil)
+	if second.Header().Get("X-Cache") != "HIT" || second.Body.String() != first.Body.String() || calls != 1 {
+		t.Fatalf("expected cached response, calls=%d headers=%v", calls, second.Header())
+	}
+
+	// no-cache في الطلب يتجاوز النسخة المخزنة
+	serve(r, "/services", map[string]string{"Cache-Control": "no-cache"})
+	if calls != 2 {
+		t.Errorf("expected no-cache to reach the handler, calls=%d", calls)
+	}
+
+	rt.Invalidate("services")
+	if w := serve(r, "/services", nil); w.Header().Get("X-Cache") != "MISS" || calls != 3 {
+		t.Errorf("expected miss after invalidation, calls=%d", calls)
+	}
+}
+
+func TestResponseCacheConditionalRequests(t *testing.T) {
+	rt := cache.NewReadThrough(cache.NewMemory(0, 0), time.Minute)
+	calls := 0
+	r := newCachedRouter(rt, &calls)
+
+	first := serve(r, "/services", nil)
+	etag := first.Header().Get("ETag")
+
+	if w := serve(r, "/services", map[string]string{"If-None-Match": etag}); w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+		t.Errorf("expected 304 for matching ETag, got %d", w.Code)
+	}
+	if w := serve(r, "/services", map[string]string{"If-None-Match": `W/"other"`}); w.Code != http.StatusOK {
+		t.Errorf("expected 200 for stale ETag, got %d", w.Code)
+	}
+
+	since := time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)
+	if w := serve(r, "/services", map[string]string{"If-Modified-Since": since}); w.Code != http.StatusNotModified {
+		t.Errorf("expected 304 for If-Modified-Since, got %d", w.Code)
+	}
+}
+
+func TestResponseCacheSkipsPrivateResponses(t *testing.T) {
+	rt := cache.NewReadThrough(cache.NewMemory(0, 0), time.Minute)
+	calls := 0
+	r := newCachedRouter(rt, &calls)
+
+	serve(r, "/private", nil)
+	w := serve(r, "/private", nil)
+	if calls != 2 || w.Header().Get("X-Cache") != "MISS" || w.Body.String() != "secret" {
+		t.Errorf("private responses must not be stored, calls=%d", calls)
+	}
+}
+
+func TestResponseCacheReplaysHandlerHeaders(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	rt := cache.NewReadThrough(cache.NewMemory(0, 0), time.Minute)
+	r := gin.New()
+	r.GET("/services", ResponseCache(rt, ResponseCacheOptions{TTL: time.Minute}), func(c *gin.Context) {
+		c.Header("Link", `</services?cursor=abc>; rel="next"`)
+		c.Header("X-Total-Count", "42")
+		c.Header("Set-Cookie", "session=1")
+		c.Header("Connection", "X-Hop")
+		c.Header("X-Hop", "1")
+		c.JSON(http.StatusOK, gin.H{"data": []string{}})
+	})
+
+	serve(r, "/services", nil)
+	hit := serve(r, "/services", nil)
+	if hit.Header().Get("X-Cache") != "HIT" {
+		t.Fatalf("expected cache hit, got %v", hit.Header())
+	}
+	if hit.Header().Get("Link") != `</services?cursor=abc>; rel="next"` || hit.Header().Get("X-Total-Count") != "42" ||
+		hit.Header().Get("Content-Type") != "application/json; charset=utf-8" {
+		t.Errorf("handler headers not replayed: %v", hit.Header())
+	}
+	if hit.Header().Get("Set-Cookie") != "" || hit.Header().Get("X-Hop") != "" || hit.Header().Get("Connection") != "" {
+		t.Errorf("per-client or hop-by-hop headers replayed: %v", hit.Header())
+	}
+}
+
+func TestResponseCacheVaryByUser(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	rt := cache.NewReadThrough(cache.NewMemory(0, 0), time.Minute)
+	r := gin.New()
+	r.GET("/orders", func(c *gin.Context) {
+		// بديل AuthMiddleware: نفس مفتاح السياق
+		c.Set("userID", c.GetHeader("X-Test-User"))
+	}, ResponseCache(rt, ResponseCacheOptions{TTL: time.Minute, VaryByUser: true}), func(c *gin.Context) {
+		c.String(http.StatusOK, "orders of "+c.GetString("userID"))
+	})
+
+	alice := serve(r, "/orders", map[string]string{"X-Test-User": "alice"})
+	bob := serve(r, "/orders", map[string]string{"X-Test-User": "bob"})
+	if alice.Body.String() != "orders of alice" || bob.Body.String() != "orders of bob" {
+		t.Fatalf("responses leaked between users: %q / %q", alice.Body.String(), bob.Body.String())
+	}
+	if bob.Header().Get("X-Cache") != "MISS" {
+		t.Errorf("expected a separate cache entry for the second user, got %q", bob.Header().Get("X-Cache"))
+	}
+
+	again := serve(r, "/orders", map[string]string{"X-Test-User": "alice"})
+	if again.Header().Get("X-Cache") != "HIT" || again.Body.String() != "orders of alice" {
+		t.Errorf("expected cached response for the same user, got %q %q", again.Header().Get("X-Cache"), again.Body.String())
+	}
+}
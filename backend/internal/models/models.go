@@ -67,14 +67,15 @@ type Category struct {
 // ================================
 
 type Order struct {
-	ID        string    `json:"id"`
-	UserID    string    `json:"user_id"`
-	ServiceID string    `json:"service_id"`
-	Status    string    `json:"status"` // pending, completed, cancelled
-	Amount    float64   `json:"amount"`
-	Notes     string    `json:"notes,omitempty"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID          string    `json:"id"`
+	UserID      string    `json:"user_id"`
+	ServiceID   string    `json:"service_id"`
+	Status      string    `json:"status"` // pending, completed, cancelled
+	Amount      float64   `json:"amount"`
+	Notes       string    `json:"notes,omitempty"`
+	Attachments []string  `json:"attachments,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
 }
 
 // ================================
@@ -98,13 +99,16 @@ type Payment struct {
 // ================================
 
 type File struct {
-	ID        string    `json:"id"`
-	UserID    string    `json:"user_id"`
-	Name      string    `json:"name"`
-	URL       string    `json:"url"`
-	Size      int64     `json:"size,omitempty"`
-	Type      string    `json:"type,omitempty"`
-	CreatedAt time.Time `json:"created_at"`
+	ID         string     `json:"id"`
+	UserID     string     `json:"user_id"`
+	Name       string     `json:"name"`
+	URL        string     `json:"url"`
+	Size       int64      `json:"size,omitempty"`
+	Type       string     `json:"type,omitempty"`
+	ScanStatus string     `json:"scan_status,omitempty"` // pending, clean, quarantined
+	ScanReason string     `json:"scan_reason,omitempty"`
+	DeletedAt  *time.Time `json:"deleted_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
 }
 
 // ================================
@@ -137,6 +141,50 @@ type Notification struct {
 	CreatedAt time.Time `json:"created_at"`
 }
 
+// ================================
+// AI Usage
+// ================================
+
+type AIUsageRecord struct {
+	ID          string                 `json:"id"`
+	UserID      string                 `json:"user_id,omitempty"`
+	UserTier    string                 `json:"user_tier,omitempty"`
+	OrgID       string                 `json:"org_id,omitempty"`
+	Provider    string                 `json:"provider"`
+	RequestType string                 `json:"request_type"` // text, image, video, ...
+	Cost        float64                `json:"cost"`
+	Quantity    int64                  `json:"quantity"`
+	LatencyMs   float64                `json:"latency_ms"`
+	Success     bool                   `json:"success"`
+	CacheHit    bool                   `json:"cache_hit"`
+	SavedCost   float64                `json:"saved_cost"`
+	Error       string                 `json:"error,omitempty"`
+	Metadata    map[string]interface{} `json:"metadata,omitempty"`
+	CreatedAt   time.Time              `json:"created_at"`
+}
+
+type AIBudget struct {
+	ID          string    `json:"id"`
+	Scope       string    `json:"scope"`              // global, user, tier, org
+	ScopeID     string    `json:"scope_id,omitempty"` // معرف المستخدم أو اسم الطبقة أو المؤسسة
+	Period      string    `json:"period"`             // daily, monthly
+	Limit       float64   `json:"limit"`
+	Enforcement string    `json:"enforcement"` // soft, hard
+	IsActive    bool      `json:"is_active"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type AIBudgetAlert struct {
+	ID        string    `json:"id"`
+	BudgetID  string    `json:"budget_id"`
+	SubjectID string    `json:"subject_id,omitempty"`
+	PeriodKey string    `json:"period_key"`
+	Threshold int       `json:"threshold"`
+	Spent     float64   `json:"spent"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
 // ================================
 // Cart
 // ================================
@@ -0,0 +1,372 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/nawthtech/nawthtech/backend/internal/models"
+)
+
+// AIUsageRecords جدول سجلات استخدام الذكاء الاصطناعي
+var AIUsageRecords = NewTable("ai_usage_records",
+	[]string{"id", "user_id", "user_tier", "org_id", "provider", "request_type", "cost", "quantity",
+		"latency_ms", "success", "cache_hit", "saved_cost", "error", "metadata", "created_at"},
+	scanAIUsageRecord,
+).Keyset(func(v models.AIUsageRecord) Cursor { return Cursor{Value: v.CreatedAt, ID: v.ID} })
+
+func scanAIUsageRecord(row RowScanner) (models.AIUsageRecord, error) {
+	var record models.AIUsageRecord
+	var userID, tier, orgID, errText sql.NullString
+	var success, cacheHit sql.NullBool
+	var metadata JSON[map[string]interface{}]
+	err := row.Scan(
+		&record.ID, &userID, &tier, &orgID, &record.Provider, &record.RequestType, &record.Cost, &record.Quantity,
+		&record.LatencyMs, &success, &cacheHit, &record.SavedCost, &errText, &metadata, &record.CreatedAt,
+	)
+	record.UserID = userID.String
+	record.UserTier = tier.String
+	record.OrgID = orgID.String
+	record.Success = success.Bool
+	record.CacheHit = cacheHit.Bool
+	record.Error = errText.String
+	record.Metadata = metadata.V
+	return record, err
+}
+
+// AIBudgets جدول ميزانيات الذكاء الاصطناعي
+var AIBudgets = NewTable("ai_budgets",
+	[]string{"id", "scope", "scope_id", "period", "limit_amount", "enforcement", "is_active", "created_at", "updated_at"},
+	scanAIBudget,
+)
+
+func scanAIBudget(row RowScanner) (models.AIBudget, error) {
+	var budget models.AIBudget
+	var isActive sql.NullBool
+	err := row.Scan(
+		&budget.ID, &budget.Scope, &budget.ScopeID, &budget.Period, &budget.Limit,
+		&budget.Enforcement, &isActive, &budget.CreatedAt, &budget.UpdatedAt,
+	)
+	budget.IsActive = isActive.Bool
+	return budget, err
+}
+
+// AIUsageFilter من يُحتسب عليه الإنفاق؛ الحقول الفارغة لا تقيد
+type AIUsageFilter struct {
+	UserID string
+	Tier   string
+	OrgID  string
+}
+
+// AIUsageSummary مجاميع الاستخدام منذ وقت محدد
+type AIUsageSummary struct {
+	Requests  int64
+	Cost      float64
+	CacheHits int64
+	SavedCost float64
+	Users     int64
+}
+
+// AIProviderUsage مجاميع استخدام مزود واحد (إصابات التخزين المؤقت لا تدخل في الطلبات)
+type AIProviderUsage struct {
+	Provider    string
+	Requests    int64
+	Cost        float64
+	AvgLatency  float64
+	SuccessRate float64
+	CacheHits   int64
+	SavedCost   float64
+}
+
+// AIUsageRepository الوصول إلى سجلات الاستخدام والميزانيات وتنبيهاتها
+type AIUsageRepository struct {
+	db DBTX
+}
+
+// NewAIUsageRepository إنشاء مستودع استخدام الذكاء الاصطناعي
+func NewAIUsageRepository(db DBTX) *AIUsageRepository {
+	return &AIUsageRepository{db: db}
+}
+
+// Query بدء استعلام على سجلات الاستخدام
+func (r *AIUsageRepository) Query() *Query[models.AIUsageRecord] {
+	return AIUsageRecords.Select()
+}
+
+// Paginate جلب صفحة سجلات مرتبة بـ created_at
+func (r *AIUsageRepository) Paginate(ctx context.Context, q *Query[models.AIUsageRecord], limit int, withTotal bool) (*Paged[models.AIUsageRecord], error) {
+	return q.Paginate(ctx, r.db, limit, withTotal)
+}
+
+// Record إضافة سجل استخدام
+func (r *AIUsageRepository) Record(ctx context.Context, record *models.AIUsageRecord) error {
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO ai_usage_records (id, user_id, user_tier, org_id, provider, request_type, cost, quantity,
+		 latency_ms, success, cache_hit, saved_cost, error, metadata, created_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		record.ID, nullString(record.UserID), nullString(record.UserTier), nullString(record.OrgID),
+		record.Provider, record.RequestType, record.Cost, record.Quantity,
+		record.LatencyMs, record.Success, record.CacheHit, record.SavedCost, nullString(record.Error),
+		JSON[map[string]interface{}]{V: record.Metadata}, record.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert ai usage record: %w", err)
+	}
+	return nil
+}
+
+// Spend إجمالي التكلفة المطابقة للفلتر منذ وقت محدد
+func (r *AIUsageRepository) Spend(ctx context.Context, filter AIUsageFilter, since time.Time) (float64, error) {
+	q := AIUsageRecords.Select().Gte("created_at", since)
+	if filter.UserID != "" {
+		q.Eq("user_id", filter.UserID)
+	}
+	if filter.Tier != "" {
+		q.Eq("user_tier", filter.Tier)
+	}
+	if filter.OrgID != "" {
+		q.Eq("org_id", filter.OrgID)
+	}
+	query, args, err := q.build("COALESCE(SUM(ai_usage_records.cost), 0)", false)
+	if err != nil {
+		return 0, err
+	}
+
+	var spent float64
+	if err := r.db.QueryRowContext(ctx, query, args...).Scan(&spent); err != nil {
+		return 0, fmt.Errorf("failed to sum ai spend: %w", err)
+	}
+	return spent, nil
+}
+
+// Summary مجاميع الاستخدام منذ وقت محدد (الصفري = الكل)
+func (r *AIUsageRepository) Summary(ctx context.Context, since time.Time) (*AIUsageSummary, error) {
+	q := AIUsageRecords.Select()
+	if !since.IsZero() {
+		q.Gte("created_at", since)
+	}
+	query, args, err := q.build(
+		`COALESCE(SUM(CASE WHEN ai_usage_records.cache_hit THEN 0 ELSE 1 END), 0),
+		 COALESCE(SUM(ai_usage_records.cost), 0),
+		 COALESCE(SUM(CASE WHEN ai_usage_records.cache_hit THEN 1 ELSE 0 END), 0),
+		 COALESCE(SUM(ai_usage_records.saved_cost), 0),
+		 COUNT(DISTINCT ai_usage_records.user_id)`,
+		false,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var summary AIUsageSummary
+	err = r.db.QueryRowContext(ctx, query, args...).Scan(
+		&summary.Requests, &summary.Cost, &summary.CacheHits, &summary.SavedCost, &summary.Users,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ai usage summary: %w", err)
+	}
+	return &summary, nil
+}
+
+// ByProvider مجاميع الاستخدام لكل مزود منذ وقت محدد (الصفري = الكل)
+func (r *AIUsageRepository) ByProvider(ctx context.Context, since time.Time) ([]AIProviderUsage, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT provider,
+		 COALESCE(SUM(CASE WHEN cache_hit THEN 0 ELSE 1 END), 0),
+		 COALESCE(SUM(cost), 0),
+		 COALESCE(AVG(CASE WHEN cache_hit THEN NULL ELSE latency_ms END), 0),
+		 COALESCE(AVG(CASE WHEN cache_hit THEN NULL WHEN success THEN 1.0 ELSE 0.0 END), 0),
+		 COALESCE(SUM(CASE WHEN cache_hit THEN 1 ELSE 0 END), 0),
+		 COALESCE(SUM(saved_cost), 0)
+		 FROM ai_usage_records WHERE created_at >= ?
+		 GROUP BY provider ORDER BY provider`,
+		since,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ai usage by provider: %w", err)
+	}
+	defer rows.Close()
+
+	var usage []AIProviderUsage
+	for rows.Next() {
+		var u AIProviderUsage
+		if err := rows.Scan(&u.Provider, &u.Requests, &u.Cost, &u.AvgLatency, &u.SuccessRate,
+			&u.CacheHits, &u.SavedCost); err != nil {
+			return nil, fmt.Errorf("failed to scan ai provider usage: %w", err)
+		}
+		usage = append(usage, u)
+	}
+	return usage, rows.Err()
+}
+
+// LatestSubject الطبقة والمؤسسة في آخر سجل للمستخدم (ErrNotFound إن لم يستخدم الخدمة بعد)
+func (r *AIUsageRepository) LatestSubject(ctx context.Context, userID string) (tier, orgID string, err error) {
+	record, err := AIUsageRecords.Select().Eq("user_id", userID).OrderBy("created_at", true).One(ctx, r.db)
+	if err != nil {
+		return "", "", err
+	}
+	return record.UserTier, record.OrgID, nil
+}
+
+// Purge حذف السجلات والتنبيهات ودفاتر الحصص الأقدم من وقت محدد
+func (r *AIUsageRepository) Purge(ctx context.Context, before time.Time) error {
+	if _, err := r.db.ExecContext(ctx, "DELETE FROM ai_usage_records WHERE created_at < ?", before); err != nil {
+		return fmt.Errorf("failed to purge ai usage records: %w", err)
+	}
+	if _, err := r.db.ExecContext(ctx, "DELETE FROM ai_budget_alerts WHERE created_at < ?", before); err != nil {
+		return fmt.Errorf("failed to purge ai budget alerts: %w", err)
+	}
+	if _, err := r.db.ExecContext(ctx, "DELETE FROM ai_quota_usage WHERE updated_at < ?", before); err != nil {
+		return fmt.Errorf("failed to purge ai quota usage: %w", err)
+	}
+	return nil
+}
+
+// ================================
+// الميزانيات والتنبيهات
+// ================================
+
+// BudgetID المعرف الثابت لميزانية النطاق والفترة، فحفظها من أي نسخة خادم يحدّث الصف نفسه
+func BudgetID(scope, scopeID, period string) string {
+	parts := []string{"budget", scope}
+	if scopeID != "" {
+		parts = append(parts, scopeID)
+	}
+	return strings.Join(append(parts, period), "_")
+}
+
+// Budgets كل الميزانيات
+func (r *AIUsageRepository) Budgets(ctx context.Context) ([]models.AIBudget, error) {
+	return AIBudgets.Select().OrderBy("scope", false).All(ctx, r.db)
+}
+
+// ApplicableBudgets الميزانيات المفعلة التي تشمل المستخدم: العامة وميزانيته وميزانية
+// طبقته ومؤسسته
+func (r *AIUsageRepository) ApplicableBudgets(ctx context.Context, userID, tier, orgID string) ([]models.AIBudget, error) {
+	conditions := []string{"ai_budgets.scope = 'global'"}
+	var args []interface{}
+	for scope, id := range map[string]string{"user": userID, "tier": tier, "org": orgID} {
+		if id != "" {
+			conditions = append(conditions, "(ai_budgets.scope = ? AND ai_budgets.scope_id = ?)")
+			args = append(args, scope, id)
+		}
+	}
+	return AIBudgets.Select().
+		Eq("is_active", true).
+		Where(strings.Join(conditions, " OR "), args...).
+		OrderBy("scope", false).
+		All(ctx, r.db)
+}
+
+// SaveBudget إنشاء ميزانية النطاق والفترة أو تحديثها
+func (r *AIUsageRepository) SaveBudget(ctx context.Context, budget *models.AIBudget) error {
+	budget.ID = BudgetID(budget.Scope, budget.ScopeID, budget.Period)
+	now := time.Now()
+	if budget.CreatedAt.IsZero() {
+		budget.CreatedAt = now
+	}
+	budget.UpdatedAt = now
+
+	_, err := r.db.ExecContext(ctx,
+		`INSERT OR REPLACE INTO ai_budgets (id, scope, scope_id, period, limit_amount, enforcement, is_active, created_at, updated_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		budget.ID, budget.Scope, budget.ScopeID, budget.Period, budget.Limit,
+		budget.Enforcement, budget.IsActive, budget.CreatedAt, budget.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save ai budget: %w", err)
+	}
+	return nil
+}
+
+// DeleteBudget حذف ميزانية
+func (r *AIUsageRepository) DeleteBudget(ctx context.Context, id string) error {
+	if _, err := r.db.ExecContext(ctx, "DELETE FROM ai_budgets WHERE id = ?", id); err != nil {
+		return fmt.Errorf("failed to delete ai budget: %w", err)
+	}
+	return nil
+}
+
+// ClaimAlert حجز تنبيه عتبة لفترة: يعيد true لأول نسخة خادم تسجله فقط، فتُرسل
+// كل عتبة مرة واحدة مهما تزامنت النسخ
+func (r *AIUsageRepository) ClaimAlert(ctx context.Context, alert *models.AIBudgetAlert) (bool, error) {
+	result, err := r.db.ExecContext(ctx,
+		`INSERT OR IGNORE INTO ai_budget_alerts (id, budget_id, subject_id, period_key, threshold, spent, created_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		alert.ID, alert.BudgetID, alert.SubjectID, alert.PeriodKey, alert.Threshold, alert.Spent, alert.CreatedAt,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim ai budget alert: %w", err)
+	}
+	claimed, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to claim ai budget alert: %w", err)
+	}
+	return claimed > 0, nil
+}
+
+// ================================
+// دفتر الحصص
+// ================================
+
+// ReserveQuota حجز كمية من حصة المستخدم في الفترة إن بقي لها متسع تحت الحد. الشرط
+// والزيادة في تحديث واحد، فلا يتجاوز الحجوزات المتزامنة من نسخ مختلفة الحد. يعيد
+// المستهلك والمحجوز بعد المحاولة
+func (r *AIUsageRepository) ReserveQuota(ctx context.Context, userID, quotaType, periodKey string, amount, limit int64) (ok bool, used, reserved int64, err error) {
+	now := time.Now()
+	_, err = r.db.ExecContext(ctx,
+		`INSERT OR IGNORE INTO ai_quota_usage (user_id, quota_type, period_key, used, reserved, updated_at)
+		 VALUES (?, ?, ?, 0, 0, ?)`,
+		userID, quotaType, periodKey, now,
+	)
+	if err != nil {
+		return false, 0, 0, fmt.Errorf("failed to init ai quota: %w", err)
+	}
+
+	result, err := r.db.ExecContext(ctx,
+		`UPDATE ai_quota_usage SET reserved = reserved + ?, updated_at = ?
+		 WHERE user_id = ? AND quota_type = ? AND period_key = ? AND used + reserved + ? <= ?`,
+		amount, now, userID, quotaType, periodKey, amount, limit,
+	)
+	if err != nil {
+		return false, 0, 0, fmt.Errorf("failed to reserve ai quota: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, 0, 0, fmt.Errorf("failed to reserve ai quota: %w", err)
+	}
+
+	used, reserved, err = r.QuotaUsage(ctx, userID, quotaType, periodKey)
+	return affected > 0, used, reserved, err
+}
+
+// SettleQuota تسوية حجز: يُرفع المحجوز ويُضاف المستهلك الفعلي (صفر عند الإلغاء)
+func (r *AIUsageRepository) SettleQuota(ctx context.Context, userID, quotaType, periodKey string, reserved, used int64) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE ai_quota_usage
+		 SET reserved = CASE WHEN reserved >= ? THEN reserved - ? ELSE 0 END, used = used + ?, updated_at = ?
+		 WHERE user_id = ? AND quota_type = ? AND period_key = ?`,
+		reserved, reserved, used, time.Now(), userID, quotaType, periodKey,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to settle ai quota: %w", err)
+	}
+	return nil
+}
+
+// QuotaUsage المستهلك والمحجوز من حصة المستخدم في الفترة
+func (r *AIUsageRepository) QuotaUsage(ctx context.Context, userID, quotaType, periodKey string) (used, reserved int64, err error) {
+	err = r.db.QueryRowContext(ctx,
+		"SELECT used, reserved FROM ai_quota_usage WHERE user_id = ? AND quota_type = ? AND period_key = ?",
+		userID, quotaType, periodKey,
+	).Scan(&used, &reserved)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, 0, nil
+	}
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get ai quota usage: %w", err)
+	}
+	return used, reserved, nil
+}
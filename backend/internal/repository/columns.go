@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// ================================
+// أعمدة JSON والقيم الفارغة
+// ================================
+
+// StringList قائمة نصوص تُخزن كمصفوفة JSON في عمود نصي ("[]" عند الفراغ)
+type StringList []string
+
+// Value تحويل القائمة إلى JSON للتخزين
+func (l StringList) Value() (driver.Value, error) {
+	if len(l) == 0 {
+		return "[]", nil
+	}
+	data, err := json.Marshal([]string(l))
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan قراءة القائمة من عمود JSON؛ NULL والنص الفارغ قائمة فارغة
+func (l *StringList) Scan(src interface{}) error {
+	*l = StringList{}
+	return scanJSON(src, (*[]string)(l))
+}
+
+// JSON عمود يحمل قيمة Go مرمزة بـ JSON
+type JSON[T any] struct {
+	V T
+}
+
+// Value ترميز القيمة للتخزين
+func (j JSON[T]) Value() (driver.Value, error) {
+	data, err := json.Marshal(j.V)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan فك ترميز القيمة؛ NULL يعطي القيمة الصفرية
+func (j *JSON[T]) Scan(src interface{}) error {
+	var zero T
+	j.V = zero
+	return scanJSON(src, &j.V)
+}
+
+func scanJSON(src interface{}, dest interface{}) error {
+	var data []byte
+	switch v := src.(type) {
+	case nil:
+		return nil
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	default:
+		return fmt.Errorf("cannot scan %T into JSON column", src)
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, dest)
+}
+
+// nullString نص أو NULL عند الفراغ
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+// timePtr مؤشر على الوقت أو nil لـ NULL
+func timePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	v := t.Time
+	return &v
+}
@@ -0,0 +1,156 @@
+package repository
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ================================
+// صفحات المفتاح والمؤشرات الموقعة
+// ================================
+
+// ErrInvalidCursor مؤشر تالف أو بتوقيع خاطئ أو لقائمة أخرى
+var ErrInvalidCursor = errors.New("invalid pagination cursor")
+
+// Paged صفحة نتائج مع مؤشر ما بعد آخر صف (nil في الصفحة الأخيرة)
+type Paged[T any] struct {
+	Items []T
+	Next  *Cursor
+	// Total إجمالي الصفوف المطابقة للشروط، أو -1 إن لم يُطلب
+	Total int64
+}
+
+// Keyset ضبط دالة استخراج المؤشر من الصف؛ يجب أن تطابق أول عمود ترتيب في الاستعلامات
+func (t *Table[T]) Keyset(cursor func(T) Cursor) *Table[T] {
+	t.cursor = cursor
+	return t
+}
+
+// Paginate جلب صفحة بحجم limit: يُجلب صف إضافي لمعرفة وجود صفحة تالية،
+// ويُحسب الإجمالي (بالشروط فقط دون المؤشر) عند withTotal
+func (q *Query[T]) Paginate(ctx context.Context, db DBTX, limit int, withTotal bool) (*Paged[T], error) {
+	if q.table.cursor == nil {
+		return nil, fmt.Errorf("table %s has no keyset", q.table.name)
+	}
+
+	saved := q.limit
+	q.limit = limit + 1
+	items, err := q.All(ctx, db)
+	q.limit = saved
+	if err != nil {
+		return nil, err
+	}
+
+	page := &Paged[T]{Items: items, Total: -1}
+	if len(items) > limit {
+		page.Items = items[:limit]
+		next := q.table.cursor(page.Items[limit-1])
+		page.Next = &next
+	}
+
+	if withTotal {
+		total, err := q.Count(ctx, db)
+		if err != nil {
+			return nil, err
+		}
+		page.Total = int64(total)
+	}
+	return page, nil
+}
+
+// CursorCodec ترميز المؤشرات كنصوص معتمة موقعة بـ HMAC-SHA256.
+// المؤشر مقيد بنطاق (اسم القائمة) حتى لا يُستخدم مؤشر قائمة في أخرى.
+type CursorCodec struct {
+	key []byte
+}
+
+// NewCursorCodec إنشاء مرمز بمفتاح سري؛ المفتاح الفارغ يُستبدل بمفتاح عشوائي
+// صالح لعمر العملية فقط
+func NewCursorCodec(secret []byte) *CursorCodec {
+	if len(secret) == 0 {
+		secret = make([]byte, 32)
+		rand.Read(secret)
+	}
+	sum := sha256.Sum256(append([]byte("pagination-cursor:"), secret...))
+	return &CursorCodec{key: sum[:]}
+}
+
+// cursorPayload الشكل المرمز للمؤشر؛ القيمة تحمل نوعها حتى تُستعاد كما هي
+type cursorPayload struct {
+	Scope string  `json:"s"`
+	Kind  string  `json:"k,omitempty"`
+	Value string  `json:"v,omitempty"`
+	Num   float64 `json:"n,omitempty"`
+	ID    string  `json:"id"`
+}
+
+// Encode ترميز المؤشر وتوقيعه
+func (c *CursorCodec) Encode(scope string, cursor Cursor) string {
+	payload := cursorPayload{Scope: scope, ID: cursor.ID}
+	switch v := cursor.Value.(type) {
+	case time.Time:
+		payload.Kind, payload.Value = "t", v.Format(time.RFC3339Nano)
+	case string:
+		payload.Kind, payload.Value = "s", v
+	case int:
+		payload.Kind, payload.Num = "n", float64(v)
+	case int64:
+		payload.Kind, payload.Num = "n", float64(v)
+	case float64:
+		payload.Kind, payload.Num = "n", v
+	}
+
+	data, _ := json.Marshal(payload)
+	body := base64.RawURLEncoding.EncodeToString(data)
+	return body + "." + base64.RawURLEncoding.EncodeToString(c.sign(body))
+}
+
+// Decode التحقق من التوقيع والنطاق واستعادة المؤشر
+func (c *CursorCodec) Decode(scope, token string) (Cursor, error) {
+	body, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return Cursor{}, ErrInvalidCursor
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(mac, c.sign(body)) {
+		return Cursor{}, ErrInvalidCursor
+	}
+	data, err := base64.RawURLEncoding.DecodeString(body)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	var payload cursorPayload
+	if err := json.Unmarshal(data, &payload); err != nil || payload.Scope != scope || payload.ID == "" {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	cursor := Cursor{ID: payload.ID}
+	switch payload.Kind {
+	case "t":
+		t, err := time.Parse(time.RFC3339Nano, payload.Value)
+		if err != nil {
+			return Cursor{}, ErrInvalidCursor
+		}
+		cursor.Value = t
+	case "s":
+		cursor.Value = payload.Value
+	case "n":
+		cursor.Value = payload.Num
+	}
+	return cursor, nil
+}
+
+func (c *CursorCodec) sign(body string) []byte {
+	mac := hmac.New(sha256.New, c.key)
+	mac.Write([]byte(body))
+	return mac.Sum(nil)
+}
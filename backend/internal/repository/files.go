@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/nawthtech/nawthtech/backend/internal/models"
+)
+
+// Files جدول الملفات. المسح يعيد حالة الفحص كما هي (فارغة إن لم تُضبط)؛
+// تطبيق سياسة إظهار الرابط مسؤولية خدمة الرفع.
+var Files = NewTable("files",
+	[]string{"id", "user_id", "name", "url", "size", "type", "scan_status", "scan_reason", "deleted_at", "created_at"},
+	scanFile,
+)
+
+func scanFile(row RowScanner) (models.File, error) {
+	var file models.File
+	var size sql.NullInt64
+	var fileType, scanStatus, scanReason sql.NullString
+	var deletedAt sql.NullTime
+	err := row.Scan(
+		&file.ID, &file.UserID, &file.Name, &file.URL, &size,
+		&fileType, &scanStatus, &scanReason, &deletedAt, &file.CreatedAt,
+	)
+	file.Size = size.Int64
+	file.Type = fileType.String
+	file.ScanStatus = scanStatus.String
+	file.ScanReason = scanReason.String
+	file.DeletedAt = timePtr(deletedAt)
+	return file, err
+}
+
+// FileRepository الوصول إلى جدول الملفات
+type FileRepository struct {
+	db DBTX
+}
+
+// NewFileRepository إنشاء مستودع الملفات
+func NewFileRepository(db DBTX) *FileRepository {
+	return &FileRepository{db: db}
+}
+
+// Query بدء استعلام على الملفات
+func (r *FileRepository) Query() *Query[models.File] {
+	return Files.Select()
+}
+
+// List تنفيذ استعلام ملفات
+func (r *FileRepository) List(ctx context.Context, q *Query[models.File]) ([]models.File, error) {
+	return q.All(ctx, r.db)
+}
+
+// Get ملف بالمعرف بما في ذلك المحذوف
+func (r *FileRepository) Get(ctx context.Context, id string) (*models.File, error) {
+	file, err := Files.Select().Eq("id", id).One(ctx, r.db)
+	if err != nil {
+		return nil, err
+	}
+	return &file, nil
+}
+
+// Create إضافة سجل ملف
+func (r *FileRepository) Create(ctx context.Context, file *models.File) error {
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO files (id, user_id, name, url, size, type, scan_status, created_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		file.ID, file.UserID, file.Name, file.URL, file.Size, file.Type, file.ScanStatus, file.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert file: %w", err)
+	}
+	return nil
+}
+
+// ReplaceContent تحديث بيانات المحتوى وإعادة الفحص إلى الحالة المحددة
+func (r *FileRepository) ReplaceContent(ctx context.Context, file *models.File) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE files SET name = ?, url = ?, size = ?, type = ?, scan_status = ?, scan_reason = NULL, scanned_at = NULL
+		 WHERE id = ?`,
+		file.Name, file.URL, file.Size, file.Type, file.ScanStatus, file.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update file: %w", err)
+	}
+	return nil
+}
+
+// DeletedSince ملفات المستخدم المحذوفة بعد وقت محدد، الأحدث أولاً
+func (r *FileRepository) DeletedSince(userID string, since time.Time) *Query[models.File] {
+	return Files.Select().
+		Eq("user_id", userID).
+		NotNull("deleted_at").
+		Gte("deleted_at", since).
+		OrderBy("deleted_at", true)
+}
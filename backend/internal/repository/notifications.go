@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/nawthtech/nawthtech/backend/internal/models"
+)
+
+// Notifications جدول الإشعارات
+var Notifications = NewTable("notifications",
+	[]string{"id", "user_id", "title", "message", "type", "is_read", "created_at"},
+	scanNotification,
+).Keyset(func(v models.Notification) Cursor { return Cursor{Value: v.CreatedAt, ID: v.ID} })
+
+func scanNotification(row RowScanner) (models.Notification, error) {
+	var notification models.Notification
+	var kind sql.NullString
+	var isRead sql.NullBool
+	err := row.Scan(
+		&notification.ID, &notification.UserID, &notification.Title, &notification.Message,
+		&kind, &isRead, &notification.CreatedAt,
+	)
+	notification.Type = kind.String
+	notification.IsRead = isRead.Bool
+	return notification, err
+}
+
+// NotificationRepository الوصول إلى جدول الإشعارات
+type NotificationRepository struct {
+	db DBTX
+}
+
+// NewNotificationRepository إنشاء مستودع الإشعارات
+func NewNotificationRepository(db DBTX) *NotificationRepository {
+	return &NotificationRepository{db: db}
+}
+
+// Query بدء استعلام على الإشعارات
+func (r *NotificationRepository) Query() *Query[models.Notification] {
+	return Notifications.Select()
+}
+
+// Paginate جلب صفحة إشعارات مرتبة بـ created_at
+func (r *NotificationRepository) Paginate(ctx context.Context, q *Query[models.Notification], limit int, withTotal bool) (*Paged[models.Notification], error) {
+	return q.Paginate(ctx, r.db, limit, withTotal)
+}
+
+// Create إضافة إشعار
+func (r *NotificationRepository) Create(ctx context.Context, notification *models.Notification) error {
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO notifications (id, user_id, title, message, type, is_read, created_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		notification.ID, notification.UserID, notification.Title, notification.Message,
+		notification.Type, notification.IsRead, notification.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert notification: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/nawthtech/nawthtech/backend/internal/models"
+)
+
+// Orders جدول الطلبات؛ attachments مصفوفة JSON
+var Orders = NewTable("orders",
+	[]string{"id", "user_id", "service_id", "status", "amount", "notes", "attachments", "created_at", "updated_at"},
+	scanOrder,
+).Keyset(func(v models.Order) Cursor { return Cursor{Value: v.CreatedAt, ID: v.ID} })
+
+func scanOrder(row RowScanner) (models.Order, error) {
+	var order models.Order
+	var status, notes sql.NullString
+	var attachments StringList
+	err := row.Scan(
+		&order.ID, &order.UserID, &order.ServiceID, &status, &order.Amount,
+		&notes, &attachments, &order.CreatedAt, &order.UpdatedAt,
+	)
+	order.Status = status.String
+	order.Notes = notes.String
+	order.Attachments = attachments
+	return order, err
+}
+
+// OrderStats إحصائيات الطلبات محسوبة في استعلام واحد
+type OrderStats struct {
+	Total        int
+	Pending      int
+	Completed    int
+	Cancelled    int
+	TotalRevenue float64
+}
+
+// OrderRepository الوصول إلى جدول الطلبات
+type OrderRepository struct {
+	db DBTX
+}
+
+// NewOrderRepository إنشاء مستودع الطلبات
+func NewOrderRepository(db DBTX) *OrderRepository {
+	return &OrderRepository{db: db}
+}
+
+// Query بدء استعلام على الطلبات
+func (r *OrderRepository) Query() *Query[models.Order] {
+	return Orders.Select()
+}
+
+// List تنفيذ استعلام طلبات
+func (r *OrderRepository) List(ctx context.Context, q *Query[models.Order]) ([]models.Order, error) {
+	return q.All(ctx, r.db)
+}
+
+// Get طلب بالمعرف
+func (r *OrderRepository) Get(ctx context.Context, id string) (*models.Order, error) {
+	order, err := Orders.Select().Eq("id", id).One(ctx, r.db)
+	if err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+// Create إضافة طلب
+func (r *OrderRepository) Create(ctx context.Context, order *models.Order) error {
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO orders (id, user_id, service_id, status, amount, notes, attachments, created_at, updated_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		order.ID, order.UserID, order.ServiceID, order.Status, order.Amount, order.Notes,
+		StringList(order.Attachments), order.CreatedAt, order.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert order: %w", err)
+	}
+	return nil
+}
+
+// UpdateStatus تغيير حالة الطلب؛ الملاحظات الفارغة تُبقي القديمة
+func (r *OrderRepository) UpdateStatus(ctx context.Context, id, status, notes string) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE orders SET status = ?, notes = COALESCE(?, notes), updated_at = ? WHERE id = ?`,
+		status, nullString(notes), time.Now(), id,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update order status: %w", err)
+	}
+	return nil
+}
+
+// Stats عدد الطلبات حسب الحالة والإيراد منذ وقت محدد (الصفري = الكل)
+func (r *OrderRepository) Stats(ctx context.Context, since time.Time) (*OrderStats, error) {
+	q := Orders.Select()
+	if !since.IsZero() {
+		q.Gte("created_at", since)
+	}
+	query, args, err := q.build(
+		`COUNT(*),
+		 COALESCE(SUM(CASE WHEN orders.status = 'pending' THEN 1 ELSE 0 END), 0),
+		 COALESCE(SUM(CASE WHEN orders.status = 'completed' THEN 1 ELSE 0 END), 0),
+		 COALESCE(SUM(CASE WHEN orders.status = 'cancelled' THEN 1 ELSE 0 END), 0),
+		 COALESCE(SUM(CASE WHEN orders.status = 'completed' THEN orders.amount ELSE 0 END), 0)`,
+		false,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var stats OrderStats
+	err = r.db.QueryRowContext(ctx, query, args...).Scan(
+		&stats.Total, &stats.Pending, &stats.Completed, &stats.Cancelled, &stats.TotalRevenue,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get order stats: %w", err)
+	}
+	return &stats, nil
+}
+
+// Paginate جلب صفحة طلبات مرتبة بـ created_at
+func (r *OrderRepository) Paginate(ctx context.Context, q *Query[models.Order], limit int, withTotal bool) (*Paged[models.Order], error) {
+	return q.Paginate(ctx, r.db, limit, withTotal)
+}
@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/nawthtech/nawthtech/backend/internal/models"
+)
+
+// Payments جدول المدفوعات
+var Payments = NewTable("payments",
+	[]string{"id", "order_id", "amount", "currency", "status", "payment_method", "transaction_id", "created_at", "updated_at"},
+	scanPayment,
+).Keyset(func(v models.Payment) Cursor { return Cursor{Value: v.CreatedAt, ID: v.ID} })
+
+func scanPayment(row RowScanner) (models.Payment, error) {
+	var payment models.Payment
+	var currency, status, method, transactionID sql.NullString
+	err := row.Scan(
+		&payment.ID, &payment.OrderID, &payment.Amount, &currency, &status,
+		&method, &transactionID, &payment.CreatedAt, &payment.UpdatedAt,
+	)
+	payment.Currency = currency.String
+	payment.Status = status.String
+	payment.PaymentMethod = method.String
+	payment.TransactionID = transactionID.String
+	return payment, err
+}
+
+// PaymentRepository الوصول إلى جدول المدفوعات
+type PaymentRepository struct {
+	db DBTX
+}
+
+// NewPaymentRepository إنشاء مستودع المدفوعات
+func NewPaymentRepository(db DBTX) *PaymentRepository {
+	return &PaymentRepository{db: db}
+}
+
+// Query بدء استعلام على المدفوعات
+func (r *PaymentRepository) Query() *Query[models.Payment] {
+	return Payments.Select()
+}
+
+// ForUser استعلام مدفوعات طلبات مستخدم (بضم جدول الطلبات)
+func (r *PaymentRepository) ForUser(userID string) *Query[models.Payment] {
+	return Payments.Select().
+		Join("INNER JOIN orders ON orders.id = payments.order_id").
+		Eq("orders.user_id", userID)
+}
+
+// List تنفيذ استعلام مدفوعات
+func (r *PaymentRepository) List(ctx context.Context, q *Query[models.Payment]) ([]models.Payment, error) {
+	return q.All(ctx, r.db)
+}
+
+// Get دفعة بالمعرف
+func (r *PaymentRepository) Get(ctx context.Context, id string) (*models.Payment, error) {
+	payment, err := Payments.Select().Eq("id", id).One(ctx, r.db)
+	if err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
+
+// UpdateStatus تغيير حالة الدفعة
+func (r *PaymentRepository) UpdateStatus(ctx context.Context, id, status string) error {
+	_, err := r.db.ExecContext(ctx,
+		"UPDATE payments SET status = ?, updated_at = ? WHERE id = ?",
+		status, time.Now(), id,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update payment status: %w", err)
+	}
+	return nil
+}
+
+// Paginate جلب صفحة مدفوعات مرتبة بـ created_at
+func (r *PaymentRepository) Paginate(ctx context.Context, q *Query[models.Payment], limit int, withTotal bool) (*Paged[models.Payment], error) {
+	return q.Paginate(ctx, r.db, limit, withTotal)
+}
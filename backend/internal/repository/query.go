@@ -0,0 +1,382 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ================================
+// منشئ الاستعلامات
+// ================================
+
+var identifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// RowScanner ما يشترك فيه *sql.Row و *sql.Rows
+type RowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// Table وصف جدول: اسمه وأعمدته بترتيب المسح ودالة مسح الصف إلى الكيان
+type Table[T any] struct {
+	name    string
+	key     string
+	columns []string
+	known   map[string]bool
+	scan    func(RowScanner) (T, error)
+	cursor  func(T) Cursor
+}
+
+// NewTable تعريف جدول؛ العمود الأول هو المفتاح الأساسي
+func NewTable[T any](name string, columns []string, scan func(RowScanner) (T, error)) *Table[T] {
+	known := make(map[string]bool, len(columns))
+	for _, column := range columns {
+		known[column] = true
+	}
+	return &Table[T]{name: name, key: columns[0], columns: columns, known: known, scan: scan}
+}
+
+// Name اسم الجدول
+func (t *Table[T]) Name() string {
+	return t.name
+}
+
+// Columns قائمة الأعمدة مؤهلة باسم الجدول بترتيب المسح
+func (t *Table[T]) Columns() string {
+	qualified := make([]string, len(t.columns))
+	for i, column := range t.columns {
+		qualified[i] = t.name + "." + column
+	}
+	return strings.Join(qualified, ", ")
+}
+
+// Select بدء استعلام على الجدول
+func (t *Table[T]) Select() *Query[T] {
+	return &Query[T]{table: t}
+}
+
+// column تأهيل اسم عمود بعد التحقق منه: الأسماء المجردة يجب أن تكون من أعمدة
+// الجدول، والمؤهلة (جدول.عمود) تأتي من جداول مضمومة
+func (t *Table[T]) column(name string) (string, error) {
+	if t.known[name] {
+		return t.name + "." + name, nil
+	}
+	if strings.Contains(name, ".") && identifierPattern.MatchString(name) {
+		return name, nil
+	}
+	return "", fmt.Errorf("%w: %s.%s", ErrInvalidColumn, t.name, name)
+}
+
+// Query استعلام SELECT مُنمّط. الدوال تعدل الاستعلام وتعيده للتسلسل،
+// وأول خطأ (مثل عمود غير معروف) يُحفظ ويُعاد عند البناء.
+type Query[T any] struct {
+	table  *Table[T]
+	joins  []string
+	where  []string
+	args   []interface{}
+	order  []orderTerm
+	after  *Cursor
+	limit  int
+	offset int
+	err    error
+}
+
+type orderTerm struct {
+	column string
+	desc   bool
+}
+
+// Cursor موضع في ترتيب الاستعلام: قيمة عمود الترتيب الأول ومعرف الصف
+type Cursor struct {
+	Value interface{}
+	ID    string
+}
+
+// Join ضم جدول آخر (نص JOIN كامل)
+func (q *Query[T]) Join(clause string) *Query[T] {
+	q.joins = append(q.joins, clause)
+	return q
+}
+
+// Where إضافة شرط خام بعناصر ? (يُستخدم داخل المستودعات فقط)
+func (q *Query[T]) Where(condition string, args ...interface{}) *Query[T] {
+	q.where = append(q.where, "("+condition+")")
+	q.args = append(q.args, args...)
+	return q
+}
+
+// Eq شرط مساواة على عمود
+func (q *Query[T]) Eq(column string, value interface{}) *Query[T] {
+	return q.compare(column, "=", value)
+}
+
+// NotEq شرط عدم مساواة على عمود
+func (q *Query[T]) NotEq(column string, value interface{}) *Query[T] {
+	return q.compare(column, "!=", value)
+}
+
+// Gte شرط أكبر من أو يساوي
+func (q *Query[T]) Gte(column string, value interface{}) *Query[T] {
+	return q.compare(column, ">=", value)
+}
+
+// Lte شرط أصغر من أو يساوي
+func (q *Query[T]) Lte(column string, value interface{}) *Query[T] {
+	return q.compare(column, "<=", value)
+}
+
+// IsNull شرط قيمة فارغة
+func (q *Query[T]) IsNull(column string) *Query[T] {
+	if c, ok := q.resolve(column); ok {
+		q.where = append(q.where, c+" IS NULL")
+	}
+	return q
+}
+
+// NotNull شرط قيمة غير فارغة
+func (q *Query[T]) NotNull(column string) *Query[T] {
+	if c, ok := q.resolve(column); ok {
+		q.where = append(q.where, c+" IS NOT NULL")
+	}
+	return q
+}
+
+// In شرط انتماء لقائمة؛ القائمة الفارغة لا تطابق شيئاً
+func (q *Query[T]) In(column string, values ...interface{}) *Query[T] {
+	c, ok := q.resolve(column)
+	if !ok {
+		return q
+	}
+	if len(values) == 0 {
+		q.where = append(q.where, "1 = 0")
+		return q
+	}
+	q.where = append(q.where, c+" IN (?"+strings.Repeat(", ?", len(values)-1)+")")
+	q.args = append(q.args, values...)
+	return q
+}
+
+// Search بحث نصي: كل كلمة يجب أن تظهر في أحد الأعمدة (LIKE غير حساس لحالة الأحرف)
+func (q *Query[T]) Search(text string, columns ...string) *Query[T] {
+	terms := strings.Fields(text)
+	if len(terms) == 0 || len(columns) == 0 {
+		return q
+	}
+	resolved := make([]string, 0, len(columns))
+	for _, column := range columns {
+		c, ok := q.resolve(column)
+		if !ok {
+			return q
+		}
+		resolved = append(resolved, c+" LIKE ?")
+	}
+	alternatives := "(" + strings.Join(resolved, " OR ") + ")"
+	for _, term := range terms {
+		q.where = append(q.where, alternatives)
+		for range resolved {
+			q.args = append(q.args, "%"+term+"%")
+		}
+	}
+	return q
+}
+
+// OrderBy ترتيب حسب عمود؛ المفتاح الأساسي يُضاف تلقائياً لكسر التعادل
+func (q *Query[T]) OrderBy(column string, desc bool) *Query[T] {
+	if c, ok := q.resolve(column); ok {
+		q.order = append(q.order, orderTerm{column: c, desc: desc})
+	}
+	return q
+}
+
+// After صفحة المفتاح (keyset): الصفوف التي تلي المؤشر في ترتيب أول عمود
+func (q *Query[T]) After(cursor Cursor) *Query[T] {
+	q.after = &cursor
+	return q
+}
+
+// Limit الحد الأقصى للصفوف
+func (q *Query[T]) Limit(limit int) *Query[T] {
+	q.limit = limit
+	return q
+}
+
+// Offset تخطي عدد من الصفوف
+func (q *Query[T]) Offset(offset int) *Query[T] {
+	q.offset = offset
+	return q
+}
+
+// Page صفحة بالرقم والحجم (الصفحة الأولى = 1)
+func (q *Query[T]) Page(page, limit int) *Query[T] {
+	if page < 1 {
+		page = 1
+	}
+	q.limit = limit
+	q.offset = (page - 1) * limit
+	return q
+}
+
+// Err أول خطأ في بناء الاستعلام
+func (q *Query[T]) Err() error {
+	return q.err
+}
+
+func (q *Query[T]) compare(column, op string, value interface{}) *Query[T] {
+	if c, ok := q.resolve(column); ok {
+		q.where = append(q.where, c+" "+op+" ?")
+		q.args = append(q.args, value)
+	}
+	return q
+}
+
+func (q *Query[T]) resolve(column string) (string, bool) {
+	if q.err != nil {
+		return "", false
+	}
+	c, err := q.table.column(column)
+	if err != nil {
+		q.err = err
+		return "", false
+	}
+	return c, true
+}
+
+// Build نص الاستعلام وقيمه
+func (q *Query[T]) Build() (string, []interface{}, error) {
+	return q.build(q.table.Columns(), true)
+}
+
+// build بناء الاستعلام بقائمة أعمدة محددة، مع الترتيب والحدود أو بدونهما
+func (q *Query[T]) build(selection string, paged bool) (string, []interface{}, error) {
+	if q.err != nil {
+		return "", nil, q.err
+	}
+
+	var b strings.Builder
+	b.WriteString("SELECT " + selection + " FROM " + q.table.name)
+	for _, join := range q.joins {
+		b.WriteString(" " + join)
+	}
+
+	where := append([]string{}, q.where...)
+	args := append([]interface{}{}, q.args...)
+	key := q.table.name + "." + q.table.key
+
+	order := q.order
+	if paged && len(order) > 0 && order[len(order)-1].column != key {
+		order = append(append([]orderTerm{}, order...), orderTerm{column: key, desc: order[0].desc})
+	}
+
+	if paged && q.after != nil {
+		if len(q.order) == 0 {
+			return "", nil, errors.New("cursor pagination requires an order")
+		}
+		first := order[0]
+		op := ">"
+		if first.desc {
+			op = "<"
+		}
+		if first.column == key {
+			where = append(where, fmt.Sprintf("%s %s ?", key, op))
+			args = append(args, q.after.ID)
+		} else {
+			where = append(where, fmt.Sprintf("(%s %s ? OR (%s = ? AND %s %s ?))", first.column, op, first.column, key, op))
+			args = append(args, q.after.Value, q.after.Value, q.after.ID)
+		}
+	}
+
+	if len(where) > 0 {
+		b.WriteString(" WHERE " + strings.Join(where, " AND "))
+	}
+
+	if paged {
+		if len(order) > 0 {
+			terms := make([]string, len(order))
+			for i, term := range order {
+				terms[i] = term.column
+				if term.desc {
+					terms[i] += " DESC"
+				}
+			}
+			b.WriteString(" ORDER BY " + strings.Join(terms, ", "))
+		}
+		if q.limit > 0 {
+			b.WriteString(" LIMIT ?")
+			args = append(args, q.limit)
+			if q.offset > 0 {
+				b.WriteString(" OFFSET ?")
+				args = append(args, q.offset)
+			}
+		}
+	}
+
+	return b.String(), args, nil
+}
+
+// All تنفيذ الاستعلام ومسح كل الصفوف
+func (q *Query[T]) All(ctx context.Context, db DBTX) ([]T, error) {
+	query, args, err := q.Build()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query %s: %w", q.table.name, err)
+	}
+	return scanAll(rows, q.table)
+}
+
+// One تنفيذ الاستعلام وإعادة أول صف، أو ErrNotFound
+func (q *Query[T]) One(ctx context.Context, db DBTX) (T, error) {
+	var zero T
+	saved := q.limit
+	q.limit = 1
+	query, args, err := q.Build()
+	q.limit = saved
+	if err != nil {
+		return zero, err
+	}
+
+	item, err := q.table.scan(db.QueryRowContext(ctx, query, args...))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return zero, ErrNotFound
+		}
+		return zero, fmt.Errorf("failed to get %s: %w", q.table.name, err)
+	}
+	return item, nil
+}
+
+// Count عدد الصفوف المطابقة للشروط (دون الترتيب والحدود)
+func (q *Query[T]) Count(ctx context.Context, db DBTX) (int, error) {
+	query, args, err := q.build("COUNT(*)", false)
+	if err != nil {
+		return 0, err
+	}
+	var count int
+	if err := db.QueryRowContext(ctx, query, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count %s: %w", q.table.name, err)
+	}
+	return count, nil
+}
+
+// scanAll مسح الصفوف بدالة الجدول وإغلاقها
+func scanAll[T any](rows *sql.Rows, table *Table[T]) ([]T, error) {
+	defer rows.Close()
+
+	items := []T{}
+	for rows.Next() {
+		item, err := table.scan(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan %s: %w", table.name, err)
+		}
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", table.name, err)
+	}
+	return items, nil
+}
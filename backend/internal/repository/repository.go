@@ -0,0 +1,86 @@
+// Package repository طبقة الوصول إلى البيانات: مستودع لكل كيان فوق منشئ
+// استعلامات مُنمّط، ومساحات صفوف مشتركة، ووحدات عمل بمعاملات.
+//
+// الاستعلامات تُكتب بصيغة SQLite (عناصر ?)، وطبقة اللهجة في internal/db
+// تحولها عند التنفيذ، لذلك تعمل المستودعات نفسها على SQLite و Postgres و D1.
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrNotFound لا يوجد صف مطابق
+	ErrNotFound = errors.New("record not found")
+	// ErrInvalidColumn عمود غير معروف في الفلترة أو الترتيب
+	ErrInvalidColumn = errors.New("invalid column")
+)
+
+// DBTX ما تحتاجه المستودعات من *sql.DB أو *sql.Tx
+type DBTX interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// Store مجموعة المستودعات فوق اتصال واحد (قاعدة البيانات أو معاملة)
+type Store struct {
+	db   *sql.DB
+	conn DBTX
+
+	Users         *UserRepository
+	Services      *ServiceRepository
+	Orders        *OrderRepository
+	Payments      *PaymentRepository
+	Files         *FileRepository
+	Notifications *NotificationRepository
+	SystemLogs    *SystemLogRepository
+	AIUsage       *AIUsageRepository
+}
+
+// NewStore إنشاء المستودعات فوق قاعدة البيانات
+func NewStore(db *sql.DB) *Store {
+	s := newStore(db)
+	s.db = db
+	return s
+}
+
+func newStore(conn DBTX) *Store {
+	return &Store{
+		conn:          conn,
+		Users:         NewUserRepository(conn),
+		Services:      NewServiceRepository(conn),
+		Orders:        NewOrderRepository(conn),
+		Payments:      NewPaymentRepository(conn),
+		Files:         NewFileRepository(conn),
+		Notifications: NewNotificationRepository(conn),
+		SystemLogs:    NewSystemLogRepository(conn),
+		AIUsage:       NewAIUsageRepository(conn),
+	}
+}
+
+// Transaction تنفيذ وحدة عمل في معاملة واحدة: تُعتمد إن أعادت fn nil وتُلغى غير ذلك.
+// المستودعات داخل fn مرتبطة بالمعاملة. في D1 تُجمع الكتابات وتُرسل دفعة واحدة
+// عند الاعتماد، لذلك القراءات داخل المعاملة لا ترى كتاباتها؛ اقرأ أولاً ثم اكتب.
+func (s *Store) Transaction(ctx context.Context, fn func(tx *Store) error) error {
+	if s.db == nil {
+		// مستودعات مرتبطة بمعاملة قائمة: المعاملات المتداخلة تشارك المعاملة الخارجية
+		return fn(s)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	if err := fn(newStore(tx)); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,265 @@
+package repository_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/nawthtech/nawthtech/backend/internal/db/d1"
+	"github.com/nawthtech/nawthtech/backend/internal/db/d1/d1test"
+	"github.com/nawthtech/nawthtech/backend/internal/db/migrations"
+	"github.com/nawthtech/nawthtech/backend/internal/models"
+	"github.com/nawthtech/nawthtech/backend/internal/repository"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestQueryBuild(t *testing.T) {
+	query, args, err := repository.Services.Select().
+		Eq("category_id", "cat_1").
+		Eq("is_active", true).
+		Search("logo brand", "title", "tags").
+		OrderBy("created_at", true).
+		Page(2, 10).
+		Build()
+	if err != nil {
+		t.Fatalf("build: %v", err)
+	}
+
+	want := "SELECT " + repository.Services.Columns() + " FROM services" +
+		" WHERE services.category_id = ? AND services.is_active = ?" +
+		" AND (services.title LIKE ? OR services.tags LIKE ?) AND (services.title LIKE ? OR services.tags LIKE ?)" +
+		" ORDER BY services.created_at DESC, services.id DESC LIMIT ? OFFSET ?"
+	if query != want {
+		t.Errorf("unexpected query:\n got: %s\nwant: %s", query, want)
+	}
+	wantArgs := []interface{}{"cat_1", true, "%logo%", "%logo%", "%brand%", "%brand%", 10, 10}
+	if !reflect.DeepEqual(args, wantArgs) {
+		t.Errorf("unexpected args: %v", args)
+	}
+
+	// الأعمدة غير المعروفة ترفض بدل إدخالها في النص
+	_, _, err = repository.Services.Select().OrderBy("price; DROP TABLE services", false).Build()
+	if !errors.Is(err, repository.ErrInvalidColumn) {
+		t.Errorf("expected invalid column error, got %v", err)
+	}
+
+	query, _, _ = repository.Orders.Select().In("id").Build()
+	if want := "SELECT " + repository.Orders.Columns() + " FROM orders WHERE 1 = 0"; query != want {
+		t.Errorf("empty IN must match nothing, got %s", query)
+	}
+}
+
+func TestStringList(t *testing.T) {
+	value, _ := repository.StringList(nil).Value()
+	if value != "[]" {
+		t.Errorf("empty list must be stored as [], got %v", value)
+	}
+
+	var list repository.StringList
+	for _, src := range []interface{}{nil, "", []byte(`["a","b"]`)} {
+		if err := list.Scan(src); err != nil {
+			t.Fatalf("scan %v: %v", src, err)
+		}
+	}
+	if !reflect.DeepEqual(list, repository.StringList{"a", "b"}) {
+		t.Errorf("unexpected list: %v", list)
+	}
+
+	var meta repository.JSON[map[string]int]
+	if err := meta.Scan(`{"views":3}`); err != nil || meta.V["views"] != 3 {
+		t.Errorf("unexpected JSON value: %v, %v", meta.V, err)
+	}
+}
+
+func TestCursorCodec(t *testing.T) {
+	codec := repository.NewCursorCodec([]byte("secret"))
+	created := time.Date(2024, 5, 1, 10, 30, 0, 123, time.UTC)
+	token := codec.Encode("orders", repository.Cursor{Value: created, ID: "order_1"})
+
+	cursor, err := codec.Decode("orders", token)
+	if err != nil || cursor.ID != "order_1" || !cursor.Value.(time.Time).Equal(created) {
+		t.Fatalf("unexpected cursor %+v: %v", cursor, err)
+	}
+
+	tampered := []byte(token)
+	tampered[0] ^= 1
+	for name, decode := range map[string]func() error{
+		"wrong scope": func() error { _, err := codec.Decode("payments", token); return err },
+		"tampered":    func() error { _, err := codec.Decode("orders", string(tampered)); return err },
+		"other key": func() error {
+			_, err := repository.NewCursorCodec([]byte("other")).Decode("orders", token)
+			return err
+		},
+		"garbage": func() error { _, err := codec.Decode("orders", "not-a-cursor"); return err },
+	} {
+		if err := decode(); !errors.Is(err, repository.ErrInvalidCursor) {
+			t.Errorf("%s: expected ErrInvalidCursor, got %v", name, err)
+		}
+	}
+}
+
+// openStores قاعدة SQLite وقاعدة D1 (عبر البديل المحلي) بعد تطبيق الترحيلات
+func openStores(t *testing.T) map[string]*sql.DB {
+	sqlite, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := d1test.NewServer(t)
+	remote, err := sql.Open(d1.DriverName, server.DSN())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	databases := map[string]*sql.DB{"sqlite3": sqlite, "d1": remote}
+	for name, database := range databases {
+		t.Cleanup(func() { database.Close() })
+		migrator, err := migrations.New(database, migrations.SQLite)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := migrator.Up(context.Background()); err != nil {
+			t.Fatalf("%s: migrate: %v", name, err)
+		}
+	}
+	return databases
+}
+
+func TestRepositories(t *testing.T) {
+	for name, database := range openStores(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			store := repository.NewStore(database)
+
+			now := time.Now().UTC().Truncate(time.Second)
+			user := &models.User{ID: "user_1", Email: "a@example.com", Username: "a", FirstName: "A", LastName: "B",
+				Role: "user", Status: "active", CreatedAt: now, UpdatedAt: now}
+			if err := store.Users.Create(ctx, user); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := database.ExecContext(ctx, "INSERT INTO categories (id, name, slug) VALUES ('cat_1', 'Design', 'design')"); err != nil {
+				t.Fatal(err)
+			}
+
+			// خمس خدمات بنفس وقت الإنشاء لاختبار كسر التعادل في صفحات المفتاح
+			for _, id := range []string{"svc_1", "svc_2", "svc_3", "svc_4", "svc_5"} {
+				err := store.Services.Create(ctx, &models.Service{ID: id, Title: "Logo " + id, Description: "d", Price: 10,
+					Duration: 1, CategoryID: "cat_1", ProviderID: user.ID, Tags: []string{"logo"}, IsActive: true,
+					CreatedAt: now, UpdatedAt: now})
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got, err := store.Users.Get(ctx, user.ID)
+			if err != nil || got.Phone != "" || !got.LastLogin.IsZero() {
+				t.Fatalf("unexpected user %+v: %v", got, err)
+			}
+			if _, err := store.Users.Get(ctx, "missing"); !errors.Is(err, repository.ErrNotFound) {
+				t.Errorf("expected ErrNotFound, got %v", err)
+			}
+
+			var seen []string
+			var cursor *repository.Cursor
+			for {
+				q := store.Services.Query().Eq("provider_id", user.ID).OrderBy("created_at", true).Limit(2)
+				if cursor != nil {
+					q.After(*cursor)
+				}
+				page, err := store.Services.List(ctx, q)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(page) == 0 {
+					break
+				}
+				last := page[len(page)-1]
+				cursor = &repository.Cursor{Value: last.CreatedAt, ID: last.ID}
+				for _, service := range page {
+					seen = append(seen, service.ID)
+				}
+			}
+			if want := []string{"svc_5", "svc_4", "svc_3", "svc_2", "svc_1"}; !reflect.DeepEqual(seen, want) {
+				t.Errorf("keyset pages = %v, want %v", seen, want)
+			}
+
+			// الصفحات عبر مؤشرات مرمزة، مع الإجمالي المطابق للشروط لا للمؤشر
+			codec := repository.NewCursorCodec([]byte("secret"))
+			seen, token := nil, ""
+			for {
+				q := store.Services.Query().Eq("provider_id", user.ID).OrderBy("created_at", true)
+				if token != "" {
+					after, err := codec.Decode("services", token)
+					if err != nil {
+						t.Fatal(err)
+					}
+					q.After(after)
+				}
+				page, err := store.Services.Paginate(ctx, q, 2, true)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if page.Total != 5 {
+					t.Errorf("total = %d, want 5", page.Total)
+				}
+				for _, service := range page.Items {
+					seen = append(seen, service.ID)
+				}
+				if page.Next == nil {
+					break
+				}
+				token = codec.Encode("services", *page.Next)
+			}
+			if want := []string{"svc_5", "svc_4", "svc_3", "svc_2", "svc_1"}; !reflect.DeepEqual(seen, want) {
+				t.Errorf("cursor pages = %v, want %v", seen, want)
+			}
+
+			byID, err := store.Services.GetMany(ctx, []string{"svc_1", "svc_3", "missing"})
+			if err != nil || len(byID) != 2 || byID["svc_3"].Tags[0] != "logo" {
+				t.Errorf("unexpected batch load: %v, %v", byID, err)
+			}
+
+			count, err := store.Services.Query().Search("LOGO svc_2", "title").Count(ctx, database)
+			if err != nil || count != 1 {
+				t.Errorf("expected one search match, got %d, %v", count, err)
+			}
+
+			// فشل وحدة العمل يلغي كل كتاباتها
+			errRollback := errors.New("rollback")
+			err = store.Transaction(ctx, func(tx *repository.Store) error {
+				tx.Orders.Create(ctx, &models.Order{ID: "order_1", UserID: user.ID, ServiceID: "svc_1", Status: "completed",
+					Amount: 10, CreatedAt: now, UpdatedAt: now})
+				return errRollback
+			})
+			if !errors.Is(err, errRollback) {
+				t.Fatalf("expected rollback error, got %v", err)
+			}
+			err = store.Transaction(ctx, func(tx *repository.Store) error {
+				for i, status := range []string{"completed", "completed", "pending"} {
+					err := tx.Orders.Create(ctx, &models.Order{ID: "order_" + string(rune('a'+i)), UserID: user.ID,
+						ServiceID: "svc_1", Status: status, Amount: 20, CreatedAt: now, UpdatedAt: now})
+					if err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			stats, err := store.Orders.Stats(ctx, time.Time{})
+			if err != nil || stats.Total != 3 || stats.Completed != 2 || stats.Pending != 1 || stats.TotalRevenue != 40 {
+				t.Errorf("unexpected order stats %+v: %v", stats, err)
+			}
+			userStats, err := store.Users.Stats(ctx, user.ID)
+			if err != nil || userStats.TotalOrders != 3 || userStats.TotalSpent != 40 || userStats.ServicesCount != 5 {
+				t.Errorf("unexpected user stats %+v: %v", userStats, err)
+			}
+		})
+	}
+}
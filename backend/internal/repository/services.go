@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/nawthtech/nawthtech/backend/internal/models"
+)
+
+// Services جدول الخدمات؛ images و tags مصفوفات JSON
+var Services = NewTable("services",
+	[]string{"id", "title", "description", "price", "duration", "category_id", "provider_id", "images", "tags", "is_active", "is_featured", "rating", "review_count", "created_at", "updated_at"},
+	scanService,
+).Keyset(func(v models.Service) Cursor { return Cursor{Value: v.CreatedAt, ID: v.ID} })
+
+func scanService(row RowScanner) (models.Service, error) {
+	var service models.Service
+	var images, tags StringList
+	var isActive, isFeatured sql.NullBool
+	var rating sql.NullFloat64
+	var reviewCount sql.NullInt64
+	err := row.Scan(
+		&service.ID, &service.Title, &service.Description, &service.Price, &service.Duration,
+		&service.CategoryID, &service.ProviderID, &images, &tags,
+		&isActive, &isFeatured, &rating, &reviewCount,
+		&service.CreatedAt, &service.UpdatedAt,
+	)
+	service.Images = images
+	service.Tags = tags
+	service.IsActive = isActive.Bool
+	service.IsFeatured = isFeatured.Bool
+	service.Rating = rating.Float64
+	service.ReviewCount = int(reviewCount.Int64)
+	return service, err
+}
+
+// ServiceRepository الوصول إلى جدول الخدمات
+type ServiceRepository struct {
+	db DBTX
+}
+
+// NewServiceRepository إنشاء مستودع الخدمات
+func NewServiceRepository(db DBTX) *ServiceRepository {
+	return &ServiceRepository{db: db}
+}
+
+// Query بدء استعلام على الخدمات
+func (r *ServiceRepository) Query() *Query[models.Service] {
+	return Services.Select()
+}
+
+// List تنفيذ استعلام خدمات
+func (r *ServiceRepository) List(ctx context.Context, q *Query[models.Service]) ([]models.Service, error) {
+	return q.All(ctx, r.db)
+}
+
+// Get خدمة بالمعرف
+func (r *ServiceRepository) Get(ctx context.Context, id string) (*models.Service, error) {
+	service, err := Services.Select().Eq("id", id).One(ctx, r.db)
+	if err != nil {
+		return nil, err
+	}
+	return &service, nil
+}
+
+// GetMany تحميل عدة خدمات باستعلام واحد، مفهرسة بالمعرف
+func (r *ServiceRepository) GetMany(ctx context.Context, ids []string) (map[string]models.Service, error) {
+	values := make([]interface{}, len(ids))
+	for i, id := range ids {
+		values[i] = id
+	}
+	services, err := Services.Select().In("id", values...).All(ctx, r.db)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]models.Service, len(services))
+	for _, service := range services {
+		byID[service.ID] = service
+	}
+	return byID, nil
+}
+
+// Create إضافة خدمة
+func (r *ServiceRepository) Create(ctx context.Context, service *models.Service) error {
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO services (id, title, description, price, duration, category_id, provider_id, images, tags, is_active, is_featured, created_at, updated_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		service.ID, service.Title, service.Description, service.Price, service.Duration, service.CategoryID, service.ProviderID,
+		StringList(service.Images), StringList(service.Tags), service.IsActive, service.IsFeatured, service.CreatedAt, service.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert service: %w", err)
+	}
+	return nil
+}
+
+// Update تحديث الحقول القابلة للتعديل في الخدمة
+func (r *ServiceRepository) Update(ctx context.Context, service *models.Service) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE services SET title = ?, description = ?, price = ?, duration = ?, category_id = ?, images = ?, tags = ?, is_active = ?, is_featured = ?, updated_at = ?
+		 WHERE id = ?`,
+		service.Title, service.Description, service.Price, service.Duration, service.CategoryID,
+		StringList(service.Images), StringList(service.Tags), service.IsActive, service.IsFeatured, time.Now(), service.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update service: %w", err)
+	}
+	return nil
+}
+
+// Delete حذف خدمة
+func (r *ServiceRepository) Delete(ctx context.Context, id string) error {
+	if _, err := r.db.ExecContext(ctx, "DELETE FROM services WHERE id = ?", id); err != nil {
+		return fmt.Errorf("failed to delete service: %w", err)
+	}
+	return nil
+}
+
+// Paginate جلب صفحة خدمات مرتبة بـ created_at
+func (r *ServiceRepository) Paginate(ctx context.Context, q *Query[models.Service], limit int, withTotal bool) (*Paged[models.Service], error) {
+	return q.Paginate(ctx, r.db, limit, withTotal)
+}
@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/nawthtech/nawthtech/backend/internal/models"
+)
+
+// SystemLogs جدول سجلات النظام
+var SystemLogs = NewTable("system_logs",
+	[]string{"id", "user_id", "level", "action", "resource", "details", "ip_address", "user_agent", "created_at"},
+	scanSystemLog,
+).Keyset(func(v models.SystemLog) Cursor { return Cursor{Value: v.CreatedAt, ID: v.ID} })
+
+func scanSystemLog(row RowScanner) (models.SystemLog, error) {
+	var log models.SystemLog
+	var userID, resource, details, ipAddress, userAgent sql.NullString
+	err := row.Scan(
+		&log.ID, &userID, &log.Level, &log.Action, &resource,
+		&details, &ipAddress, &userAgent, &log.CreatedAt,
+	)
+	log.UserID = userID.String
+	log.Resource = resource.String
+	log.Details = details.String
+	log.IPAddress = ipAddress.String
+	log.UserAgent = userAgent.String
+	return log, err
+}
+
+// SystemLogRepository الوصول إلى سجلات النظام
+type SystemLogRepository struct {
+	db DBTX
+}
+
+// NewSystemLogRepository إنشاء مستودع سجلات النظام
+func NewSystemLogRepository(db DBTX) *SystemLogRepository {
+	return &SystemLogRepository{db: db}
+}
+
+// Query بدء استعلام على السجلات
+func (r *SystemLogRepository) Query() *Query[models.SystemLog] {
+	return SystemLogs.Select()
+}
+
+// Paginate جلب صفحة سجلات مرتبة بـ created_at
+func (r *SystemLogRepository) Paginate(ctx context.Context, q *Query[models.SystemLog], limit int, withTotal bool) (*Paged[models.SystemLog], error) {
+	return q.Paginate(ctx, r.db, limit, withTotal)
+}
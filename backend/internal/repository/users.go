@@ -0,0 +1,175 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/nawthtech/nawthtech/backend/internal/models"
+)
+
+// Users جدول المستخدمين (password_hash لا يُقرأ في المسح العام)
+var Users = NewTable("users",
+	[]string{"id", "email", "username", "first_name", "last_name", "phone", "avatar", "role", "status", "email_verified", "created_at", "updated_at", "last_login"},
+	scanUser,
+)
+
+func scanUser(row RowScanner) (models.User, error) {
+	var user models.User
+	var phone, avatar, role, status sql.NullString
+	var emailVerified sql.NullBool
+	var lastLogin sql.NullTime
+	err := row.Scan(
+		&user.ID, &user.Email, &user.Username, &user.FirstName, &user.LastName,
+		&phone, &avatar, &role, &status, &emailVerified,
+		&user.CreatedAt, &user.UpdatedAt, &lastLogin,
+	)
+	user.Phone = phone.String
+	user.Avatar = avatar.String
+	user.Role = role.String
+	user.Status = status.String
+	user.EmailVerified = emailVerified.Bool
+	user.LastLogin = lastLogin.Time
+	return user, err
+}
+
+// UserStats إحصائيات مستخدم محسوبة في استعلام واحد
+type UserStats struct {
+	TotalOrders   int
+	TotalSpent    float64
+	ServicesCount int
+	CreatedAt     time.Time
+}
+
+// UserRepository الوصول إلى جدول المستخدمين
+type UserRepository struct {
+	db DBTX
+}
+
+// NewUserRepository إنشاء مستودع المستخدمين
+func NewUserRepository(db DBTX) *UserRepository {
+	return &UserRepository{db: db}
+}
+
+// Query بدء استعلام على المستخدمين
+func (r *UserRepository) Query() *Query[models.User] {
+	return Users.Select()
+}
+
+// List تنفيذ استعلام مستخدمين
+func (r *UserRepository) List(ctx context.Context, q *Query[models.User]) ([]models.User, error) {
+	return q.All(ctx, r.db)
+}
+
+// Get مستخدم بالمعرف
+func (r *UserRepository) Get(ctx context.Context, id string) (*models.User, error) {
+	user, err := Users.Select().Eq("id", id).One(ctx, r.db)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// GetActiveByEmail مستخدم نشط بالبريد
+func (r *UserRepository) GetActiveByEmail(ctx context.Context, email string) (*models.User, error) {
+	user, err := Users.Select().Eq("email", email).Eq("status", "active").One(ctx, r.db)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// Create إضافة مستخدم
+func (r *UserRepository) Create(ctx context.Context, user *models.User) error {
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO users (id, email, username, password_hash, first_name, last_name, phone, role, status, created_at, updated_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		user.ID, user.Email, user.Username, user.PasswordHash, user.FirstName, user.LastName, user.Phone,
+		user.Role, user.Status, user.CreatedAt, user.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert user: %w", err)
+	}
+	return nil
+}
+
+// GetTier طبقة المستخدم (تحدد حصص التخزين و AI)
+func (r *UserRepository) GetTier(ctx context.Context, id string) (string, error) {
+	var tier sql.NullString
+	err := r.db.QueryRowContext(ctx, "SELECT tier FROM users WHERE id = ?", id).Scan(&tier)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get user tier: %w", err)
+	}
+	return tier.String, nil
+}
+
+// UpdateProfile تحديث بيانات الملف الشخصي
+func (r *UserRepository) UpdateProfile(ctx context.Context, id, firstName, lastName, phone, avatar string) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE users SET first_name = ?, last_name = ?, phone = ?, avatar = ?, updated_at = ?
+		 WHERE id = ?`,
+		firstName, lastName, phone, avatar, time.Now(), id,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+	return nil
+}
+
+// UpdateAvatar تحديث الصورة الشخصية
+func (r *UserRepository) UpdateAvatar(ctx context.Context, id, avatar string) error {
+	_, err := r.db.ExecContext(ctx,
+		"UPDATE users SET avatar = ?, updated_at = ? WHERE id = ?",
+		avatar, time.Now(), id,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update avatar: %w", err)
+	}
+	return nil
+}
+
+// SetStatus تغيير حالة المستخدم
+func (r *UserRepository) SetStatus(ctx context.Context, id, status string) error {
+	_, err := r.db.ExecContext(ctx,
+		"UPDATE users SET status = ?, updated_at = ? WHERE id = ?",
+		status, time.Now(), id,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update user status: %w", err)
+	}
+	return nil
+}
+
+// TouchLogin تسجيل وقت آخر دخول
+func (r *UserRepository) TouchLogin(ctx context.Context, id string, at time.Time) error {
+	_, err := r.db.ExecContext(ctx, "UPDATE users SET last_login = ? WHERE id = ?", at, id)
+	if err != nil {
+		return fmt.Errorf("failed to update last login: %w", err)
+	}
+	return nil
+}
+
+// Stats عدد الطلبات والمبلغ المنفق وعدد الخدمات في استعلام واحد
+func (r *UserRepository) Stats(ctx context.Context, id string) (*UserStats, error) {
+	var stats UserStats
+	err := r.db.QueryRowContext(ctx,
+		`SELECT users.created_at,
+		        (SELECT COUNT(*) FROM orders WHERE orders.user_id = users.id),
+		        (SELECT COALESCE(SUM(orders.amount), 0) FROM orders WHERE orders.user_id = users.id AND orders.status = 'completed'),
+		        (SELECT COUNT(*) FROM services WHERE services.provider_id = users.id)
+		 FROM users WHERE users.id = ?`,
+		id,
+	).Scan(&stats.CreatedAt, &stats.TotalOrders, &stats.TotalSpent, &stats.ServicesCount)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get user stats: %w", err)
+	}
+	return &stats, nil
+}
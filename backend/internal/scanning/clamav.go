@@ -0,0 +1,172 @@
+package scanning
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+// ================================
+// عميل ClamAV (بروتوكول clamd)
+// ================================
+
+// clamChunkSize حجم الأجزاء المرسلة في أمر INSTREAM
+const clamChunkSize = 64 * 1024
+
+// VirusResult نتيجة فحص الفيروسات
+type VirusResult struct {
+	Infected  bool   `json:"infected"`
+	Signature string `json:"signature,omitempty"`
+}
+
+// ClamAV عميل clamd عبر TCP أو Unix socket
+type ClamAV struct {
+	network string
+	address string
+	timeout time.Duration
+}
+
+// NewClamAV إنشاء عميل clamd. العنوان بصيغة "tcp://host:3310" أو "host:3310"
+// أو "unix:///var/run/clamav/clamd.ctl" أو مسار socket مباشرة
+func NewClamAV(address string, timeout time.Duration) *ClamAV {
+	if timeout <= 0 {
+		timeout = 2 * time.Minute
+	}
+
+	network := "tcp"
+	switch {
+	case strings.HasPrefix(address, "unix://"):
+		network, address = "unix", strings.TrimPrefix(address, "unix://")
+	case strings.HasPrefix(address, "tcp://"):
+		address = strings.TrimPrefix(address, "tcp://")
+	case strings.HasPrefix(address, "/"):
+		network = "unix"
+	}
+
+	return &ClamAV{network: network, address: address, timeout: timeout}
+}
+
+// Ping التحقق من أن clamd يستجيب
+func (c *ClamAV) Ping(ctx context.Context) error {
+	conn, err := c.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte("zPING\x00")); err != nil {
+		return fmt.Errorf("%w: %v", ErrScanUnavailable, err)
+	}
+	reply, err := readClamReply(conn)
+	if err != nil {
+		return err
+	}
+	if reply != "PONG" {
+		return fmt.Errorf("%w: unexpected reply %q", ErrScanUnavailable, reply)
+	}
+	return nil
+}
+
+// ScanStream فحص التدفق بأمر INSTREAM: أجزاء مسبوقة بطولها (4 بايت big-endian) وتنتهي بجزء صفري
+func (c *ClamAV) ScanStream(ctx context.Context, r io.Reader) (*VirusResult, error) {
+	conn, err := c.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	w := bufio.NewWriterSize(conn, clamChunkSize+4)
+	if _, err := w.WriteString("zINSTREAM\x00"); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrScanUnavailable, err)
+	}
+
+	buf := make([]byte, clamChunkSize)
+	var size [4]byte
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		n, readErr := r.Read(buf)
+		if n > 0 {
+			binary.BigEndian.PutUint32(size[:], uint32(n))
+			if _, err := w.Write(size[:]); err != nil {
+				return nil, c.streamError(conn, err)
+			}
+			if _, err := w.Write(buf[:n]); err != nil {
+				return nil, c.streamError(conn, err)
+			}
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return nil, fmt.Errorf("failed to read file for scanning: %w", readErr)
+		}
+	}
+
+	binary.BigEndian.PutUint32(size[:], 0)
+	if _, err := w.Write(size[:]); err != nil {
+		return nil, c.streamError(conn, err)
+	}
+	if err := w.Flush(); err != nil {
+		return nil, c.streamError(conn, err)
+	}
+
+	reply, err := readClamReply(conn)
+	if err != nil {
+		return nil, err
+	}
+	return parseClamReply(reply)
+}
+
+// dial فتح اتصال بمهلة مستمدة من السياق أو الإعدادات
+func (c *ClamAV) dial(ctx context.Context) (net.Conn, error) {
+	dialer := net.Dialer{Timeout: 10 * time.Second}
+	conn, err := dialer.DialContext(ctx, c.network, c.address)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrScanUnavailable, err)
+	}
+
+	deadline := time.Now().Add(c.timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	_ = conn.SetDeadline(deadline)
+	return conn, nil
+}
+
+// streamError عند رفض clamd للتدفق (مثل تجاوز StreamMaxLength) يغلق الاتصال بعد إرسال سبب الخطأ
+func (c *ClamAV) streamError(conn net.Conn, err error) error {
+	if reply, readErr := readClamReply(conn); readErr == nil && reply != "" {
+		return fmt.Errorf("%w: %s", ErrScanUnavailable, reply)
+	}
+	return fmt.Errorf("%w: %v", ErrScanUnavailable, err)
+}
+
+// readClamReply قراءة الرد حتى البايت الصفري (أوامر z) أو نهاية الاتصال
+func readClamReply(conn net.Conn) (string, error) {
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if err != nil && err != io.EOF {
+		return "", fmt.Errorf("%w: %v", ErrScanUnavailable, err)
+	}
+	return strings.TrimSpace(strings.TrimRight(reply, "\x00")), nil
+}
+
+// parseClamReply تحليل الرد: "stream: OK" أو "stream: <signature> FOUND" أو "<message> ERROR"
+func parseClamReply(reply string) (*VirusResult, error) {
+	reply = strings.TrimPrefix(reply, "stream: ")
+	switch {
+	case reply == "OK":
+		return &VirusResult{}, nil
+	case strings.HasSuffix(reply, " FOUND"):
+		return &VirusResult{Infected: true, Signature: strings.TrimSuffix(reply, " FOUND")}, nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrScanUnavailable, reply)
+	}
+}
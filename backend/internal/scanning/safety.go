@@ -0,0 +1,88 @@
+package scanning
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/nawthtech/nawthtech/backend/internal/ai/types"
+)
+
+// ================================
+// فحص سلامة الصور
+// ================================
+
+// ImageAnalyzer محلل صور (يطابق MediaService.AnalyzeImage)
+type ImageAnalyzer interface {
+	AnalyzeImage(ctx context.Context, imageData []byte, prompt string) (*types.AnalysisResponse, error)
+}
+
+// safetyPrompt يطلب من النموذج ردا بصيغة JSON قابلة للتحليل
+const safetyPrompt = `Classify this image for content safety on a marketplace. ` +
+	`Respond with JSON only: {"safe": true|false, "categories": [..], "confidence": 0.0-1.0}. ` +
+	`Use categories from: nudity, sexual, violence, gore, hate, drugs, weapons.`
+
+// unsafeCategories الفئات التي تجعل الصورة غير آمنة
+var unsafeCategories = map[string]bool{
+	"nsfw":     true,
+	"adult":    true,
+	"nudity":   true,
+	"sexual":   true,
+	"violence": true,
+	"gore":     true,
+	"hate":     true,
+}
+
+// SafetyResult نتيجة فحص السلامة
+type SafetyResult struct {
+	Safe       bool     `json:"safe"`
+	Categories []string `json:"categories,omitempty"`
+	Confidence float64  `json:"confidence"`
+}
+
+// checkImageSafety تحليل الصورة وتفسير رد النموذج
+func checkImageSafety(ctx context.Context, analyzer ImageAnalyzer, image []byte, threshold float64) (*SafetyResult, error) {
+	resp, err := analyzer.AnalyzeImage(ctx, image, safetyPrompt)
+	if err != nil {
+		return nil, fmt.Errorf("%w: image analysis failed: %v", ErrScanUnavailable, err)
+	}
+	return interpretSafety(resp, threshold), nil
+}
+
+// interpretSafety قراءة JSON من نص الرد، وإلا الاعتماد على فئات الاستجابة
+func interpretSafety(resp *types.AnalysisResponse, threshold float64) *SafetyResult {
+	var parsed struct {
+		Safe       *bool    `json:"safe"`
+		Categories []string `json:"categories"`
+		Confidence float64  `json:"confidence"`
+	}
+
+	result := &SafetyResult{Safe: true}
+
+	if start, end := strings.Index(resp.Result, "{"), strings.LastIndex(resp.Result, "}"); start >= 0 && end > start {
+		if err := json.Unmarshal([]byte(resp.Result[start:end+1]), &parsed); err == nil && parsed.Safe != nil {
+			result.Categories = parsed.Categories
+			result.Confidence = parsed.Confidence
+			if result.Confidence == 0 {
+				result.Confidence = 1
+			}
+			result.Safe = *parsed.Safe || result.Confidence < threshold
+			return result
+		}
+	}
+
+	for _, category := range resp.Categories {
+		if unsafeCategories[strings.ToLower(category)] {
+			result.Categories = append(result.Categories, category)
+		}
+	}
+	result.Confidence = resp.Confidence
+	if result.Confidence == 0 {
+		result.Confidence = 1
+	}
+	if len(result.Categories) > 0 && result.Confidence >= threshold {
+		result.Safe = false
+	}
+	return result
+}
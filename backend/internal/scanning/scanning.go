@@ -0,0 +1,174 @@
+// Package scanning فحص سلامة الملفات المرفوعة قبل نشرها:
+// مطابقة النوع الفعلي (magic bytes) مع النوع المعلن، فحص الفيروسات عبر بروتوكول ClamAV،
+// وفحص سلامة محتوى الصور عبر نموذج تحليل الصور.
+package scanning
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// ================================
+// النتائج
+// ================================
+
+// Verdict نتيجة الفحص
+type Verdict string
+
+const (
+	VerdictClean        Verdict = "clean"
+	VerdictTypeMismatch Verdict = "type_mismatch"
+	VerdictInfected     Verdict = "infected"
+	VerdictUnsafe       Verdict = "unsafe"
+	// VerdictUnscanned تعذر إجراء أحد الفحوص المطلوبة (مثل صورة أكبر من حد فحص السلامة)
+	VerdictUnscanned Verdict = "unscanned"
+)
+
+// Report تقرير فحص ملف واحد
+type Report struct {
+	Verdict      Verdict   `json:"verdict"`
+	DeclaredType string    `json:"declared_type"`
+	DetectedType string    `json:"detected_type"`
+	Signature    string    `json:"signature,omitempty"`
+	Reason       string    `json:"reason,omitempty"`
+	Checks       []string  `json:"checks"`
+	ScannedAt    time.Time `json:"scanned_at"`
+}
+
+// Clean هل الملف آمن للنشر
+func (r *Report) Clean() bool {
+	return r.Verdict == VerdictClean
+}
+
+// ErrScanUnavailable فشل مؤقت في أحد الفاحصين (يعاد الفحص لاحقاً)
+var ErrScanUnavailable = errors.New("scanner unavailable")
+
+// ================================
+// الفاحص
+// ================================
+
+// VirusScanner فاحص فيروسات يقرأ المحتوى كتدفق
+type VirusScanner interface {
+	ScanStream(ctx context.Context, r io.Reader) (*VirusResult, error)
+}
+
+// Options إعدادات خط الفحص؛ الفاحصات غير المضبوطة تُتخطى
+type Options struct {
+	Virus    VirusScanner
+	Analyzer ImageAnalyzer
+	// MaxImageSize أكبر صورة ترسل لفحص السلامة؛ الأكبر منها لا تعتبر نظيفة (VerdictUnscanned)
+	MaxImageSize int64
+	// SafetyThreshold أدنى ثقة لاعتبار الصورة غير آمنة (0-1)
+	SafetyThreshold float64
+}
+
+// Scanner خط فحص الملفات
+type Scanner struct {
+	options Options
+}
+
+// New إنشاء فاحص جديد
+func New(opts Options) *Scanner {
+	if opts.MaxImageSize <= 0 {
+		opts.MaxImageSize = 20 * 1024 * 1024
+	}
+	if opts.SafetyThreshold <= 0 || opts.SafetyThreshold > 1 {
+		opts.SafetyThreshold = 0.7
+	}
+	return &Scanner{options: opts}
+}
+
+// Scan فحص المحتوى مقابل النوع المعلن. يقرأ التدفق مرة واحدة فقط.
+// يعيد خطأ (ErrScanUnavailable) عندما يتعذر إكمال الفحص، ويبقى الملف معلقاً.
+func (s *Scanner) Scan(ctx context.Context, r io.Reader, declaredType string) (*Report, error) {
+	report := &Report{
+		Verdict:      VerdictClean,
+		DeclaredType: declaredType,
+	}
+
+	br := bufio.NewReaderSize(r, sniffLen)
+	header, err := br.Peek(sniffLen)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return nil, fmt.Errorf("failed to read file header: %w", err)
+	}
+
+	report.DetectedType = DetectContentType(header)
+	report.Checks = append(report.Checks, "mime")
+	if !TypeMatches(report.DetectedType, declaredType) {
+		report.Verdict = VerdictTypeMismatch
+		report.Reason = fmt.Sprintf("content is %s but declared as %s", report.DetectedType, declaredType)
+		report.ScannedAt = time.Now()
+		return report, nil
+	}
+
+	// الاحتفاظ بنسخة من الصور لفحص السلامة أثناء تمرير التدفق لفاحص الفيروسات
+	var image *limitedBuffer
+	var body io.Reader = br
+	if s.options.Analyzer != nil && isAnalyzableImage(report.DetectedType) {
+		image = &limitedBuffer{limit: s.options.MaxImageSize}
+		body = io.TeeReader(br, image)
+	}
+
+	if s.options.Virus != nil {
+		result, err := s.options.Virus.ScanStream(ctx, body)
+		if err != nil {
+			return nil, err
+		}
+		report.Checks = append(report.Checks, "virus")
+		if result.Infected {
+			report.Verdict = VerdictInfected
+			report.Signature = result.Signature
+			report.Reason = "malware detected: " + result.Signature
+			report.ScannedAt = time.Now()
+			return report, nil
+		}
+	} else if image != nil {
+		if _, err := io.Copy(io.Discard, body); err != nil {
+			return nil, fmt.Errorf("failed to read file: %w", err)
+		}
+	}
+
+	// لا تنشر صورة لم تمر بفحص السلامة
+	if image != nil && image.truncated {
+		report.Verdict = VerdictUnscanned
+		report.Reason = fmt.Sprintf("image exceeds the %d byte safety check limit", s.options.MaxImageSize)
+	} else if image != nil {
+		safety, err := checkImageSafety(ctx, s.options.Analyzer, image.Bytes(), s.options.SafetyThreshold)
+		if err != nil {
+			return nil, err
+		}
+		report.Checks = append(report.Checks, "safety")
+		if !safety.Safe {
+			report.Verdict = VerdictUnsafe
+			report.Reason = "unsafe image content: " + strings.Join(safety.Categories, ", ")
+		}
+	}
+
+	report.ScannedAt = time.Now()
+	return report, nil
+}
+
+// limitedBuffer يخزن حتى حد معين ثم يتوقف دون إرجاع خطأ للكاتب
+type limitedBuffer struct {
+	bytes.Buffer
+	limit     int64
+	truncated bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if b.truncated {
+		return len(p), nil
+	}
+	if int64(b.Len()+len(p)) > b.limit {
+		b.truncated = true
+		b.Reset()
+		return len(p), nil
+	}
+	return b.Buffer.Write(p)
+}
@@ -0,0 +1,250 @@
+package scanning
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/png"
+	"io"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/nawthtech/nawthtech/backend/internal/ai/types"
+)
+
+// eicar ملف الاختبار القياسي الذي تكشفه كل برامج مكافحة الفيروسات
+const eicar = `X5O!P%@AP[4\PZX54(P^)7CC)7}$EICAR-STANDARD-ANTIVIRUS-TEST-FILE!$H+H*`
+
+// fakeClamd خادم clamd مبسط يطبق أوامر zPING و zINSTREAM
+type fakeClamd struct {
+	listener  net.Listener
+	maxStream int
+}
+
+func newFakeClamd(t *testing.T) *fakeClamd {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	f := &fakeClamd{listener: l, maxStream: 1 << 20}
+	go f.serve()
+	t.Cleanup(func() { l.Close() })
+	return f
+}
+
+func (f *fakeClamd) Addr() string {
+	return "tcp://" + f.listener.Addr().String()
+}
+
+func (f *fakeClamd) serve() {
+	for {
+		conn, err := f.listener.Accept()
+		if err != nil {
+			return
+		}
+		go f.handle(conn)
+	}
+}
+
+func (f *fakeClamd) handle(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+
+	command, err := r.ReadString(0)
+	if err != nil {
+		return
+	}
+
+	switch strings.TrimRight(command, "\x00") {
+	case "zPING":
+		conn.Write([]byte("PONG\x00"))
+	case "zINSTREAM":
+		var data bytes.Buffer
+		for {
+			var size [4]byte
+			if _, err := io.ReadFull(r, size[:]); err != nil {
+				return
+			}
+			n := binary.BigEndian.Uint32(size[:])
+			if n == 0 {
+				break
+			}
+			if data.Len()+int(n) > f.maxStream {
+				conn.Write([]byte("INSTREAM size limit exceeded. ERROR\x00"))
+				return
+			}
+			if _, err := io.CopyN(&data, r, int64(n)); err != nil {
+				return
+			}
+		}
+		if bytes.Contains(data.Bytes(), []byte("EICAR-STANDARD-ANTIVIRUS-TEST-FILE")) {
+			conn.Write([]byte("stream: Eicar-Test-Signature FOUND\x00"))
+			return
+		}
+		conn.Write([]byte("stream: OK\x00"))
+	default:
+		conn.Write([]byte("UNKNOWN COMMAND\x00"))
+	}
+}
+
+// fakeAnalyzer محلل صور يعيد ردا ثابتا
+type fakeAnalyzer struct {
+	result string
+	err    error
+	calls  int
+}
+
+func (a *fakeAnalyzer) AnalyzeImage(ctx context.Context, imageData []byte, prompt string) (*types.AnalysisResponse, error) {
+	a.calls++
+	if a.err != nil {
+		return nil, a.err
+	}
+	return &types.AnalysisResponse{Result: a.result}, nil
+}
+
+func pngBytes(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 4, 4))); err != nil {
+		t.Fatalf("png: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestTypeMatches(t *testing.T) {
+	cases := []struct {
+		content  []byte
+		declared string
+		want     bool
+	}{
+		{pngBytes(t), "image/png", true},
+		{pngBytes(t), "image/jpeg", false},
+		{[]byte("%PDF-1.7\n"), "application/pdf", true},
+		{[]byte("<html><script>alert(1)</script></html>"), "image/png", false},
+		{[]byte("name,price\nlogo,10\n"), "text/csv", true},
+		{[]byte(`{"a":1}`), "application/json", true},
+		{[]byte("MZ\x90\x00\x03\x00\x00\x00\x04\x00"), "application/pdf", false},
+		{[]byte("\x00\x00\x00\x14ftypqt  \x00\x00\x00\x00qt  "), "video/quicktime", true},
+		{[]byte("\x00\x00\x00\x18ftypisom\x00\x00\x02\x00isomavc1"), "video/mp4", true},
+		{[]byte("PK\x03\x04\x14\x00\x06\x00"), "application/vnd.openxmlformats-officedocument.wordprocessingml.document", true},
+	}
+
+	for i, tc := range cases {
+		detected := DetectContentType(tc.content)
+		if got := TypeMatches(detected, tc.declared); got != tc.want {
+			t.Errorf("case %d: detected %s declared %s: got %v", i, detected, tc.declared, got)
+		}
+	}
+}
+
+func TestClamAVAgainstStandIn(t *testing.T) {
+	clamd := newFakeClamd(t)
+	client := NewClamAV(clamd.Addr(), 0)
+	ctx := context.Background()
+
+	if err := client.Ping(ctx); err != nil {
+		t.Fatalf("Ping: %v", err)
+	}
+
+	result, err := client.ScanStream(ctx, strings.NewReader("just some text"))
+	if err != nil || result.Infected {
+		t.Fatalf("expected clean result, got %+v, %v", result, err)
+	}
+
+	// المحتوى أكبر من جزء واحد ويحمل التوقيع في النهاية
+	payload := strings.Repeat("a", clamChunkSize*2+10) + eicar
+	result, err = client.ScanStream(ctx, strings.NewReader(payload))
+	if err != nil {
+		t.Fatalf("ScanStream: %v", err)
+	}
+	if !result.Infected || result.Signature != "Eicar-Test-Signature" {
+		t.Errorf("expected EICAR detection, got %+v", result)
+	}
+
+	clamd.maxStream = 10
+	if _, err := client.ScanStream(ctx, strings.NewReader(strings.Repeat("b", 100))); !errors.Is(err, ErrScanUnavailable) {
+		t.Errorf("expected size limit error, got %v", err)
+	}
+
+	down := NewClamAV("127.0.0.1:1", 0)
+	if _, err := down.ScanStream(ctx, strings.NewReader("x")); !errors.Is(err, ErrScanUnavailable) {
+		t.Errorf("expected unavailable error, got %v", err)
+	}
+}
+
+func TestScannerPipeline(t *testing.T) {
+	clamd := newFakeClamd(t)
+	ctx := context.Background()
+
+	analyzer := &fakeAnalyzer{result: `{"safe": true, "categories": [], "confidence": 0.9}`}
+	scanner := New(Options{Virus: NewClamAV(clamd.Addr(), 0), Analyzer: analyzer})
+
+	report, err := scanner.Scan(ctx, bytes.NewReader(pngBytes(t)), "image/png")
+	if err != nil {
+		t.Fatalf("Scan: %v", err)
+	}
+	if !report.Clean() || len(report.Checks) != 3 || analyzer.calls != 1 {
+		t.Errorf("expected clean report with 3 checks, got %+v (calls=%d)", report, analyzer.calls)
+	}
+
+	report, err = scanner.Scan(ctx, strings.NewReader(eicar), "text/plain")
+	if err != nil {
+		t.Fatalf("Scan: %v", err)
+	}
+	if report.Verdict != VerdictInfected {
+		t.Errorf("expected infected verdict, got %+v", report)
+	}
+
+	report, err = scanner.Scan(ctx, strings.NewReader("<html></html>"), "image/png")
+	if err != nil {
+		t.Fatalf("Scan: %v", err)
+	}
+	if report.Verdict != VerdictTypeMismatch {
+		t.Errorf("expected type mismatch, got %+v", report)
+	}
+
+	analyzer.result = "Here you go: {\"safe\": false, \"categories\": [\"nudity\"], \"confidence\": 0.95}"
+	report, err = scanner.Scan(ctx, bytes.NewReader(pngBytes(t)), "image/png")
+	if err != nil {
+		t.Fatalf("Scan: %v", err)
+	}
+	if report.Verdict != VerdictUnsafe {
+		t.Errorf("expected unsafe verdict, got %+v", report)
+	}
+
+	// فشل التحليل يبقي الملف معلقاً بدل تمريره
+	analyzer.err = errors.New("provider down")
+	if _, err := scanner.Scan(ctx, bytes.NewReader(pngBytes(t)), "image/png"); !errors.Is(err, ErrScanUnavailable) {
+		t.Errorf("expected ErrScanUnavailable, got %v", err)
+	}
+}
+
+func TestScannerOversizedImageIsNotClean(t *testing.T) {
+	analyzer := &fakeAnalyzer{result: `{"safe": true, "categories": [], "confidence": 0.9}`}
+	scanner := New(Options{Analyzer: analyzer, MaxImageSize: 16})
+
+	report, err := scanner.Scan(context.Background(), bytes.NewReader(pngBytes(t)), "image/png")
+	if err != nil {
+		t.Fatalf("Scan: %v", err)
+	}
+	if report.Clean() || report.Verdict != VerdictUnscanned || analyzer.calls != 0 {
+		t.Errorf("expected unscanned verdict without analysis, got %+v (calls=%d)", report, analyzer.calls)
+	}
+}
+
+func TestInterpretSafetyFallsBackToCategories(t *testing.T) {
+	resp := &types.AnalysisResponse{Result: "The image shows violence.", Categories: []string{"Violence"}, Confidence: 0.8}
+	if interpretSafety(resp, 0.7).Safe {
+		t.Error("expected unsafe from categories")
+	}
+
+	resp.Confidence = 0.5
+	if !interpretSafety(resp, 0.7).Safe {
+		t.Error("expected low-confidence category to pass")
+	}
+}
@@ -0,0 +1,120 @@
+package scanning
+
+import (
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// ================================
+// كشف النوع من البايتات الأولى
+// ================================
+
+// sniffLen عدد البايتات التي يعتمد عليها الكشف (كما في http.DetectContentType)
+const sniffLen = 512
+
+// DetectContentType كشف نوع المحتوى من البايتات الأولى، مع إضافة حاويات ISO-BMFF
+// (QuickTime و HEIC وبقية علامات MP4) التي لا يميزها http.DetectContentType
+func DetectContentType(header []byte) string {
+	if len(header) >= 12 && string(header[4:8]) == "ftyp" {
+		switch string(header[8:12]) {
+		case "qt  ":
+			return "video/quicktime"
+		case "heic", "heix", "mif1", "msf1":
+			return "image/heic"
+		case "avif":
+			return "image/avif"
+		}
+	}
+
+	detected := http.DetectContentType(header)
+	if detected == "application/octet-stream" && len(header) >= 8 && string(header[4:8]) == "ftyp" {
+		return "video/mp4"
+	}
+	return detected
+}
+
+// strictPrefixes و strictTypes أنواع لها توقيع ثابت، فيجب أن يطابق المحتوى الفعلي إعلانها
+var strictPrefixes = []string{"image/", "video/", "audio/"}
+
+var strictTypes = map[string]bool{
+	"application/pdf":  true,
+	"application/zip":  true,
+	"application/gzip": true,
+}
+
+// zipContainers صيغ مبنية على ZIP (مستندات Office و EPUB)
+var zipContainers = map[string]bool{
+	"application/epub+zip": true,
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.document":   true,
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet":         true,
+	"application/vnd.openxmlformats-officedocument.presentationml.presentation": true,
+}
+
+// typeAliases أسماء بديلة شائعة لنفس النوع
+var typeAliases = map[string]string{
+	"image/jpg":         "image/jpeg",
+	"image/pjpeg":       "image/jpeg",
+	"image/x-png":       "image/png",
+	"image/x-ms-bmp":    "image/bmp",
+	"video/x-m4v":       "video/mp4",
+	"audio/mp3":         "audio/mpeg",
+	"application/x-pdf": "application/pdf",
+}
+
+// TypeMatches هل النوع المكتشف متوافق مع النوع المعلن
+func TypeMatches(detected, declared string) bool {
+	d := normalizeType(detected)
+	want := normalizeType(declared)
+	if want == "" {
+		return false
+	}
+	if d == want {
+		return true
+	}
+
+	switch {
+	case d == "text/plain":
+		// المحتوى النصي: أي نوع نصي معلن مقبول، لكن ليس نوعاً ثنائياً بتوقيع
+		return strings.HasPrefix(want, "text/") || want == "application/json" ||
+			want == "application/xml" || want == "application/x-yaml"
+	case d == "application/zip":
+		return zipContainers[want]
+	case d == "application/octet-stream":
+		// محتوى بلا توقيع معروف لا يمكن أن يعلن كنوع له توقيع
+		return !isStrictType(want)
+	}
+	return false
+}
+
+// isAnalyzableImage الصور التي يمكن إرسالها لفحص السلامة
+func isAnalyzableImage(contentType string) bool {
+	switch normalizeType(contentType) {
+	case "image/jpeg", "image/png", "image/gif", "image/webp", "image/bmp":
+		return true
+	}
+	return false
+}
+
+func isStrictType(contentType string) bool {
+	if strictTypes[contentType] {
+		return true
+	}
+	for _, prefix := range strictPrefixes {
+		if strings.HasPrefix(contentType, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func normalizeType(contentType string) string {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = strings.ToLower(strings.TrimSpace(contentType))
+	}
+	if alias, ok := typeAliases[mediaType]; ok {
+		return alias
+	}
+	return mediaType
+}
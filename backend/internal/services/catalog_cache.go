@@ -0,0 +1,241 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/nawthtech/nawthtech/backend/internal/cache"
+	"github.com/nawthtech/nawthtech/backend/internal/config"
+	"github.com/nawthtech/nawthtech/backend/internal/models"
+	"go.uber.org/zap"
+)
+
+// ================================
+// التخزين المؤقت لقراءات الكتالوج
+// ================================
+
+// وسوم إبطال الكتالوج: كل تعديل على خدمة أو فئة يرفع إصدار الوسوم المتأثرة
+const (
+	CacheTagServices   = "services"
+	CacheTagCategories = "categories"
+)
+
+// defaultCatalogCacheTTL مدة صلاحية قراءات الكتالوج عند عدم تحديدها في الإعدادات
+const defaultCatalogCacheTTL = 5 * time.Minute
+
+// ServiceCacheTag وسم خدمة واحدة
+func ServiceCacheTag(serviceID string) string {
+	return "service:" + serviceID
+}
+
+// cachedServiceService يغلف ServiceService بقراءة عبر التخزين المؤقت
+type cachedServiceService struct {
+	ServiceService
+	cache  *cache.ReadThrough
+	logger *zap.Logger
+}
+
+// cachedCategoryService يغلف CategoryService بقراءة عبر التخزين المؤقت
+type cachedCategoryService struct {
+	CategoryService
+	cache  *cache.ReadThrough
+	logger *zap.Logger
+}
+
+// NewCachedServiceService تغليف خدمة الخدمات بطبقة تخزين مؤقت مع إبطال بالوسوم
+func NewCachedServiceService(next ServiceService, rt *cache.ReadThrough, logger *zap.Logger) ServiceService {
+	return &cachedServiceService{ServiceService: next, cache: rt, logger: logger}
+}
+
+// NewCachedCategoryService تغليف خدمة الفئات بطبقة تخزين مؤقت مع إبطال بالوسوم
+func NewCachedCategoryService(next CategoryService, rt *cache.ReadThrough, logger *zap.Logger) CategoryService {
+	return &cachedCategoryService{CategoryService: next, cache: rt, logger: logger}
+}
+
+// newCatalogServices إنشاء خدمتي الخدمات والفئات، مغلفتين بالتخزين المؤقت إن كان مفعلاً
+func newCatalogServices(db *sql.DB, cfg *config.Config, store CacheService, logger *zap.Logger) (ServiceService, CategoryService, *cache.ReadThrough) {
+	services, categories := newServiceService(db, newCursorCodec(cfg)), NewCategoryService(db)
+	if store == nil || (cfg != nil && !cfg.Cache.Enabled) {
+		return services, categories, nil
+	}
+
+	ttl := defaultCatalogCacheTTL
+	if cfg != nil && cfg.Cache.TTL > 0 {
+		ttl = cfg.Cache.TTL
+	}
+	rt := cache.NewReadThrough(store, ttl)
+	return NewCachedServiceService(services, rt, logger), NewCachedCategoryService(categories, rt, logger), rt
+}
+
+// GetServiceByID قراءة خدمة عبر التخزين المؤقت؛ موسومة بوسم الخدمات أيضاً لأن حذف فئتها
+// يحذفها دون المرور بـ DeleteService
+func (s *cachedServiceService) GetServiceByID(ctx context.Context, serviceID string) (*models.Service, error) {
+	var service models.Service
+	err := s.cache.Fetch(ctx, "services:id:"+serviceID, []string{ServiceCacheTag(serviceID), CacheTagServices}, &service,
+		func(ctx context.Context) (interface{}, error) {
+			return s.ServiceService.GetServiceByID(ctx, serviceID)
+		})
+	if err != nil {
+		return nil, err
+	}
+	return &service, nil
+}
+
+// GetServices قائمة الخدمات عبر التخزين المؤقت (المفتاح مشتق من معاملات الاستعلام)
+func (s *cachedServiceService) GetServices(ctx context.Context, params ServiceQueryParams) ([]models.Service, error) {
+	var services []models.Service
+	err := s.cache.Fetch(ctx, "services:list:"+paramsCacheKey(params), []string{CacheTagServices}, &services,
+		func(ctx context.Context) (interface{}, error) {
+			return s.ServiceService.GetServices(ctx, params)
+		})
+	if err != nil {
+		return nil, err
+	}
+	return services, nil
+}
+
+// ListServices صفحة خدمات عبر التخزين المؤقت؛ المؤشر جزء من المعاملات فكل صفحة بمفتاحها
+func (s *cachedServiceService) ListServices(ctx context.Context, params ServiceQueryParams) (*Page[models.Service], error) {
+	var page Page[models.Service]
+	err := s.cache.Fetch(ctx, "services:page:"+paramsCacheKey(params), []string{CacheTagServices}, &page,
+		func(ctx context.Context) (interface{}, error) {
+			return s.ServiceService.ListServices(ctx, params)
+		})
+	if err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// GetFeaturedServices الخدمات المميزة عبر التخزين المؤقت
+func (s *cachedServiceService) GetFeaturedServices(ctx context.Context) ([]models.Service, error) {
+	var services []models.Service
+	err := s.cache.Fetch(ctx, "services:featured", []string{CacheTagServices}, &services,
+		func(ctx context.Context) (interface{}, error) {
+			return s.ServiceService.GetFeaturedServices(ctx)
+		})
+	if err != nil {
+		return nil, err
+	}
+	return services, nil
+}
+
+// CreateService إنشاء خدمة وإبطال القوائم وشجرة الفئات (عدد الخدمات)
+func (s *cachedServiceService) CreateService(ctx context.Context, req ServiceCreateRequest) (*models.Service, error) {
+	service, err := s.ServiceService.CreateService(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	s.invalidate(CacheTagServices, CacheTagCategories)
+	return service, nil
+}
+
+// UpdateService تحديث خدمة وإبطالها مع القوائم
+func (s *cachedServiceService) UpdateService(ctx context.Context, serviceID string, req ServiceUpdateRequest) (*models.Service, error) {
+	service, err := s.ServiceService.UpdateService(ctx, serviceID, req)
+	if err != nil {
+		return nil, err
+	}
+	s.invalidate(ServiceCacheTag(serviceID), CacheTagServices, CacheTagCategories)
+	return service, nil
+}
+
+// DeleteService حذف خدمة وإبطالها مع القوائم
+func (s *cachedServiceService) DeleteService(ctx context.Context, serviceID string) error {
+	if err := s.ServiceService.DeleteService(ctx, serviceID); err != nil {
+		return err
+	}
+	s.invalidate(ServiceCacheTag(serviceID), CacheTagServices, CacheTagCategories)
+	return nil
+}
+
+func (s *cachedServiceService) invalidate(tags ...string) {
+	if err := s.cache.Invalidate(tags...); err != nil && s.logger != nil {
+		s.logger.Warn("Failed to invalidate catalogue cache", zap.Strings("tags", tags), zap.Error(err))
+	}
+}
+
+// GetCategories قائمة الفئات عبر التخزين المؤقت
+func (s *cachedCategoryService) GetCategories(ctx context.Context, params CategoryQueryParams) ([]models.Category, error) {
+	var categories []models.Category
+	err := s.cache.Fetch(ctx, "categories:list:"+paramsCacheKey(params), []string{CacheTagCategories}, &categories,
+		func(ctx context.Context) (interface{}, error) {
+			return s.CategoryService.GetCategories(ctx, params)
+		})
+	if err != nil {
+		return nil, err
+	}
+	return categories, nil
+}
+
+// GetCategoryByID قراءة فئة عبر التخزين المؤقت
+func (s *cachedCategoryService) GetCategoryByID(ctx context.Context, categoryID string) (*models.Category, error) {
+	var category models.Category
+	err := s.cache.Fetch(ctx, "categories:id:"+categoryID, []string{CacheTagCategories}, &category,
+		func(ctx context.Context) (interface{}, error) {
+			return s.CategoryService.GetCategoryByID(ctx, categoryID)
+		})
+	if err != nil {
+		return nil, err
+	}
+	return &category, nil
+}
+
+// GetCategoryTree شجرة الفئات عبر التخزين المؤقت (تتأثر بالفئات وعدد الخدمات)
+func (s *cachedCategoryService) GetCategoryTree(ctx context.Context) ([]CategoryNode, error) {
+	var tree []CategoryNode
+	err := s.cache.Fetch(ctx, "categories:tree", []string{CacheTagCategories, CacheTagServices}, &tree,
+		func(ctx context.Context) (interface{}, error) {
+			return s.CategoryService.GetCategoryTree(ctx)
+		})
+	if err != nil {
+		return nil, err
+	}
+	return tree, nil
+}
+
+// CreateCategory إنشاء فئة وإبطال الشجرة
+func (s *cachedCategoryService) CreateCategory(ctx context.Context, req CategoryCreateRequest) (*models.Category, error) {
+	category, err := s.CategoryService.CreateCategory(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	s.invalidate(CacheTagCategories)
+	return category, nil
+}
+
+// UpdateCategory تحديث فئة وإبطال الشجرة
+func (s *cachedCategoryService) UpdateCategory(ctx context.Context, categoryID string, req CategoryUpdateRequest) (*models.Category, error) {
+	category, err := s.CategoryService.UpdateCategory(ctx, categoryID, req)
+	if err != nil {
+		return nil, err
+	}
+	s.invalidate(CacheTagCategories)
+	return category, nil
+}
+
+// DeleteCategory حذف فئة؛ حذفها يحذف خدماتها (ON DELETE CASCADE) فتبطل الخدمات أيضاً
+func (s *cachedCategoryService) DeleteCategory(ctx context.Context, categoryID string) error {
+	if err := s.CategoryService.DeleteCategory(ctx, categoryID); err != nil {
+		return err
+	}
+	s.invalidate(CacheTagCategories, CacheTagServices)
+	return nil
+}
+
+func (s *cachedCategoryService) invalidate(tags ...string) {
+	if err := s.cache.Invalidate(tags...); err != nil && s.logger != nil {
+		s.logger.Warn("Failed to invalidate catalogue cache", zap.Strings("tags", tags), zap.Error(err))
+	}
+}
+
+// paramsCacheKey مفتاح ثابت مشتق من معاملات الاستعلام
+func paramsCacheKey(params interface{}) string {
+	data, _ := json.Marshal(params)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,48 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/nawthtech/nawthtech/backend/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCatalogCacheDeleteCategory حذف الفئة يبطل الخدمات المخزنة التي حُذفت معها
+func TestCatalogCacheDeleteCategory(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T, container *ServiceContainer, database *sql.DB) {
+		ctx := context.Background()
+		cfg := &config.Config{}
+		cfg.Cache.Enabled = true
+		services, categories, rt := newCatalogServices(database, cfg, NewCacheService(), nil)
+		require.NotNil(t, rt)
+
+		auth, err := container.Auth.Register(ctx, AuthRegisterRequest{
+			FirstName: "Sara", LastName: "Ahmed", Email: "sara@example.com", Username: "sara", Password: "secret-password",
+		})
+		require.NoError(t, err)
+		category, err := categories.CreateCategory(ctx, CategoryCreateRequest{Name: "Design", Slug: "design"})
+		require.NoError(t, err)
+		service, err := services.CreateService(ctx, ServiceCreateRequest{
+			Title: "Logo Design", Description: "A professional logo for your brand",
+			Price: 49.5, Duration: 3, CategoryID: category.ID, ProviderID: auth.User.ID,
+		})
+		require.NoError(t, err)
+
+		_, err = services.GetServiceByID(ctx, service.ID)
+		require.NoError(t, err)
+
+		// ON DELETE CASCADE يحذف الخدمة دون المرور بطبقة الخدمات
+		_, err = database.ExecContext(ctx, "DELETE FROM services WHERE category_id = ?", category.ID)
+		require.NoError(t, err)
+		cached, err := services.GetServiceByID(ctx, service.ID)
+		require.NoError(t, err, "entry should still be served from cache before the category is deleted")
+		assert.Equal(t, service.ID, cached.ID)
+
+		require.NoError(t, categories.DeleteCategory(ctx, category.ID))
+		_, err = services.GetServiceByID(ctx, service.ID)
+		assert.Error(t, err)
+	})
+}
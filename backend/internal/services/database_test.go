@@ -0,0 +1,165 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nawthtech/nawthtech/backend/internal/config"
+	"github.com/nawthtech/nawthtech/backend/internal/db"
+	"github.com/nawthtech/nawthtech/backend/internal/db/d1"
+	"github.com/nawthtech/nawthtech/backend/internal/db/d1/d1test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// testDatabase قاعدة بيانات مهاجرة لتشغيل مجموعة اختبارات الخدمات عليها
+type testDatabase struct {
+	driver string
+	open   func(t *testing.T) *sql.DB
+}
+
+// testDatabases قواعد البيانات المتاحة للاختبار: SQLite و D1 (عبر بديل HTTP محلي)
+// دائماً، و Postgres عند ضبط TEST_POSTGRES_URL (كل اختبار في schema مستقل يُحذف بعده)
+func testDatabases() []testDatabase {
+	databases := []testDatabase{
+		{driver: "sqlite3", open: openSQLiteTestDB},
+		{driver: "d1", open: openD1TestDB},
+	}
+	if url := os.Getenv("TEST_POSTGRES_URL"); url != "" {
+		databases = append(databases, testDatabase{driver: "postgres", open: func(t *testing.T) *sql.DB {
+			return openPostgresTestDB(t, url)
+		}})
+	}
+	return databases
+}
+
+func openSQLiteTestDB(t *testing.T) *sql.DB {
+	database, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { database.Close() })
+	return database
+}
+
+func openD1TestDB(t *testing.T) *sql.DB {
+	server := d1test.NewServer(t)
+	database, err := sql.Open(d1.DriverName, server.DSN())
+	require.NoError(t, err)
+	t.Cleanup(func() { database.Close() })
+	return database
+}
+
+func openPostgresTestDB(t *testing.T, url string) *sql.DB {
+	admin, err := sql.Open(db.PostgresDriverName, url)
+	require.NoError(t, err)
+	defer admin.Close()
+
+	schema := fmt.Sprintf("test_%d", time.Now().UnixNano())
+	_, err = admin.Exec("CREATE SCHEMA " + schema)
+	require.NoError(t, err)
+
+	separator := "?"
+	if strings.Contains(url, "?") {
+		separator = "&"
+	}
+	database, err := sql.Open(db.PostgresDriverName, url+separator+"search_path="+schema)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		database.Close()
+		if admin, err := sql.Open(db.PostgresDriverName, url); err == nil {
+			admin.Exec("DROP SCHEMA " + schema + " CASCADE")
+			admin.Close()
+		}
+	})
+	return database
+}
+
+// forEachDatabase تشغيل الاختبار على كل قاعدة بيانات بعد تطبيق الترحيلات
+func forEachDatabase(t *testing.T, fn func(t *testing.T, container *ServiceContainer, database *sql.DB)) {
+	for _, tdb := range testDatabases() {
+		t.Run(tdb.driver, func(t *testing.T) {
+			database := tdb.open(t)
+
+			cfg := &config.Config{}
+			cfg.Database.Driver = tdb.driver
+			container, err := NewServiceContainerWithConfig(database, cfg, nil)
+			require.NoError(t, err)
+			require.NoError(t, container.InitializeDatabase(context.Background()))
+
+			fn(t, container, database)
+		})
+	}
+}
+
+// TestServicesAcrossDatabases نفس مسارات الخدمات على كل اللهجات المدعومة
+func TestServicesAcrossDatabases(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T, container *ServiceContainer, database *sql.DB) {
+		ctx := context.Background()
+
+		auth, err := container.Auth.Register(ctx, AuthRegisterRequest{
+			FirstName: "Sara",
+			LastName:  "Ahmed",
+			Email:     "sara@example.com",
+			Username:  "sara",
+			Password:  "secret-password",
+		})
+		require.NoError(t, err)
+
+		profile, err := container.User.GetProfile(ctx, auth.User.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "sara@example.com", profile.Email)
+
+		// LIKE غير حساس لحالة الأحرف في كل القواعد
+		users, err := container.User.SearchUsers(ctx, "SARA", UserQueryParams{})
+		require.NoError(t, err)
+		assert.Len(t, users, 1)
+
+		category, err := container.Category.CreateCategory(ctx, CategoryCreateRequest{Name: "Design", Slug: "design"})
+		require.NoError(t, err)
+
+		service, err := container.Service.CreateService(ctx, ServiceCreateRequest{
+			Title:       "Logo Design",
+			Description: "A professional logo for your brand",
+			Price:       49.5,
+			Duration:    3,
+			CategoryID:  category.ID,
+			ProviderID:  auth.User.ID,
+			Tags:        []string{"logo", "brand"},
+		})
+		require.NoError(t, err)
+
+		fetched, err := container.Service.GetServiceByID(ctx, service.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 49.5, fetched.Price)
+		assert.Equal(t, []string{"logo", "brand"}, fetched.Tags)
+		assert.True(t, fetched.IsActive)
+
+		found, err := container.Service.GetServices(ctx, ServiceQueryParams{Search: "LOGO", IsActive: true})
+		require.NoError(t, err)
+		assert.Len(t, found, 1)
+
+		// INSERT OR REPLACE يُترجم إلى ON CONFLICT في Postgres
+		for _, size := range []int64{10, 20} {
+			_, err := database.ExecContext(ctx,
+				`INSERT OR REPLACE INTO image_derivatives (key, file_id, content_type, etag, size, created_at)
+				 VALUES (?, ?, ?, ?, ?, ?)`,
+				"derived/key", "file_1", "image/webp", "etag", size, time.Now())
+			require.NoError(t, err)
+		}
+		var size int64
+		require.NoError(t, database.QueryRowContext(ctx, "SELECT size FROM image_derivatives WHERE key = ?", "derived/key").Scan(&size))
+		assert.Equal(t, int64(20), size)
+
+		check, err := container.Health.CheckDatabase(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, "healthy", check.Status)
+	})
+}
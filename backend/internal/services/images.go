@@ -0,0 +1,429 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	aiservices "github.com/nawthtech/nawthtech/backend/internal/ai/services"
+	"github.com/nawthtech/nawthtech/backend/internal/cloudflare"
+	"github.com/nawthtech/nawthtech/backend/internal/config"
+	"github.com/nawthtech/nawthtech/backend/internal/storage"
+)
+
+// ================================
+// تحويل الصور ومشتقاتها
+// ================================
+
+// ImageService توليد مشتقات الصور (أحجام وتنسيقات) وتخزينها مؤقتاً
+type ImageService interface {
+	SignTransform(fileID string, t ImageTransform) string
+	VerifyTransform(fileID string, t ImageTransform, signature string) bool
+	TransformURL(fileID string, t ImageTransform) string
+	GetDerivative(ctx context.Context, fileID string, t ImageTransform) (*ImageDerivative, error)
+	InvalidateImage(ctx context.Context, fileID string) error
+}
+
+// ImageTransform معاملات التحويل المطلوبة في الرابط
+type ImageTransform struct {
+	Width   int    `json:"width,omitempty" form:"width"`
+	Height  int    `json:"height,omitempty" form:"height"`
+	Fit     string `json:"fit,omitempty" form:"fit"`
+	Format  string `json:"format,omitempty" form:"format"`
+	Quality int    `json:"quality,omitempty" form:"quality"`
+	// Expires وقت انتهاء صلاحية الرابط (Unix)، و0 يعني رابطاً دائماً
+	Expires int64 `json:"expires,omitempty" form:"expires"`
+}
+
+// ImageDerivative صورة مشتقة جاهزة للإرسال
+type ImageDerivative struct {
+	FileID      string
+	Key         string
+	ContentType string
+	ETag        string
+	Size        int64
+	CreatedAt   time.Time
+	Body        io.ReadCloser
+}
+
+// ImageOptions إعدادات خدمة الصور
+type ImageOptions struct {
+	SigningKey    []byte
+	BaseURL       string
+	MaxDimension  int
+	MaxSourceSize int64
+	// URLTTL مدة صلاحية الروابط الموقعة الجديدة (0 = دائمة، مناسبة للروابط المحفوظة في القوائم)
+	URLTTL time.Duration
+	// Purge مسح نسخ CDN بالوسوم عند تغير الصورة الأصلية
+	Purge func(tags []string) error
+}
+
+type imageServiceImpl struct {
+	db      *sql.DB
+	storage storage.Storage
+	media   *aiservices.MediaService
+	options ImageOptions
+}
+
+const (
+	defaultMaxImageDimension = 4096
+	derivativeKeyPrefix      = "derivatives/"
+)
+
+var imageTransformFits = map[string]bool{
+	aiservices.FitContain: true,
+	aiservices.FitCover:   true,
+	aiservices.FitFill:    true,
+	aiservices.FitInside:  true,
+}
+
+var imageTransformFormats = map[string]bool{
+	"jpeg": true,
+	"png":  true,
+	"webp": true,
+	"gif":  true,
+}
+
+// NewImageService إنشاء خدمة تحويل الصور
+func NewImageService(db *sql.DB, store storage.Storage, opts ImageOptions) ImageService {
+	if opts.MaxDimension <= 0 {
+		opts.MaxDimension = defaultMaxImageDimension
+	}
+	if opts.MaxSourceSize <= 0 {
+		opts.MaxSourceSize = 25 * 1024 * 1024
+	}
+	if opts.BaseURL == "" {
+		opts.BaseURL = "/images"
+	}
+	return &imageServiceImpl{
+		db:      db,
+		storage: store,
+		media:   aiservices.NewMediaService(nil, nil),
+		options: opts,
+	}
+}
+
+// imageOptionsFromConfig بناء إعدادات الصور من الإعدادات العامة
+func imageOptionsFromConfig(cfg *config.Config) ImageOptions {
+	opts := ImageOptions{}
+	if cfg == nil {
+		return opts
+	}
+
+	key := cfg.Upload.ImageSigningKey
+	if key == "" {
+		key = cfg.EncryptionKey
+	}
+	opts.SigningKey = []byte(key)
+	opts.URLTTL = cfg.Upload.ImageURLTTL
+	if cfg.APIURL != "" {
+		opts.BaseURL = strings.TrimRight(cfg.APIURL, "/") + "/images"
+	}
+
+	// المسح بالوسوم متاح فقط عند إعداد منطقة Cloudflare
+	if cloudflare.NewCloudflareConfig().ZoneID != "" {
+		opts.Purge = cloudflare.PurgeByTags
+	}
+	return opts
+}
+
+// ImageCacheTag وسم CDN لكل مشتقات صورة (يرسل في ترويسة Cache-Tag)
+func ImageCacheTag(fileID string) string {
+	return "image-" + fileID
+}
+
+// canonical الصيغة الموحدة للمعاملات المستخدمة في التوقيع والمفتاح (دون الصلاحية، فالمشتق واحد)
+func (t ImageTransform) canonical() string {
+	return fmt.Sprintf("w%d-h%d-%s-q%d.%s", t.Width, t.Height, t.Fit, t.Quality, t.Format)
+}
+
+// normalize ملء القيم الافتراضية حتى تتطابق الطلبات المتكافئة في التوقيع والتخزين
+func (t ImageTransform) normalize() ImageTransform {
+	t.Fit = strings.ToLower(t.Fit)
+	if t.Fit == "" {
+		t.Fit = aiservices.FitContain
+	}
+	t.Format = strings.ToLower(t.Format)
+	if t.Format == "jpg" {
+		t.Format = "jpeg"
+	}
+	if t.Quality == 0 {
+		t.Quality = aiservices.DefaultImageQuality
+	}
+	return t
+}
+
+// validate التحقق من حدود المعاملات
+func (t ImageTransform) validate(maxDimension int) error {
+	if t.Width < 0 || t.Height < 0 || t.Width > maxDimension || t.Height > maxDimension {
+		return ErrInvalidTransform
+	}
+	if t.Quality < 1 || t.Quality > 100 {
+		return ErrInvalidTransform
+	}
+	if !imageTransformFits[t.Fit] {
+		return ErrInvalidTransform
+	}
+	if t.Format != "" && !imageTransformFormats[t.Format] {
+		return ErrInvalidTransform
+	}
+	return nil
+}
+
+// SignTransform توقيع HMAC لمعرف الصورة ومعاملات التحويل
+func (s *imageServiceImpl) SignTransform(fileID string, t ImageTransform) string {
+	message := fileID + ":" + t.normalize().canonical()
+	if t.Expires != 0 {
+		message += ":" + strconv.FormatInt(t.Expires, 10)
+	}
+
+	mac := hmac.New(sha256.New, s.options.SigningKey)
+	mac.Write([]byte(message))
+	return hex.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// VerifyTransform التحقق من توقيع الرابط بمقارنة ثابتة الزمن ومن عدم انتهاء صلاحيته
+func (s *imageServiceImpl) VerifyTransform(fileID string, t ImageTransform, signature string) bool {
+	if len(s.options.SigningKey) == 0 || signature == "" {
+		return false
+	}
+	if t.Expires != 0 && time.Now().Unix() > t.Expires {
+		return false
+	}
+	return hmac.Equal([]byte(signature), []byte(s.SignTransform(fileID, t)))
+}
+
+// TransformURL بناء رابط موقع للمشتق
+func (s *imageServiceImpl) TransformURL(fileID string, t ImageTransform) string {
+	if t.Expires == 0 && s.options.URLTTL > 0 {
+		t.Expires = time.Now().Add(s.options.URLTTL).Unix()
+	}
+
+	query := url.Values{}
+	if t.Width > 0 {
+		query.Set("width", strconv.Itoa(t.Width))
+	}
+	if t.Height > 0 {
+		query.Set("height", strconv.Itoa(t.Height))
+	}
+	if t.Fit != "" {
+		query.Set("fit", t.Fit)
+	}
+	if t.Format != "" {
+		query.Set("format", t.Format)
+	}
+	if t.Quality > 0 {
+		query.Set("quality", strconv.Itoa(t.Quality))
+	}
+	if t.Expires != 0 {
+		query.Set("expires", strconv.FormatInt(t.Expires, 10))
+	}
+	query.Set("sig", s.SignTransform(fileID, t))
+
+	return s.options.BaseURL + "/" + url.PathEscape(fileID) + "?" + query.Encode()
+}
+
+// GetDerivative إرجاع المشتق من التخزين أو توليده وتخزينه عند أول طلب
+func (s *imageServiceImpl) GetDerivative(ctx context.Context, fileID string, t ImageTransform) (*ImageDerivative, error) {
+	if s.storage == nil {
+		return nil, ErrStorageUnhealthy
+	}
+
+	t = t.normalize()
+	if err := t.validate(s.options.MaxDimension); err != nil {
+		return nil, err
+	}
+
+	source, err := s.getSourceImage(ctx, fileID)
+	if err != nil {
+		return nil, err
+	}
+	if t.Format == "" {
+		t.Format = imageFormatFromType(source.Type)
+	}
+
+	key := derivativeKeyPrefix + fileID + "/" + t.canonical()
+
+	if derivative, err := s.loadDerivative(ctx, fileID, key); err == nil {
+		return derivative, nil
+	} else if !errors.Is(err, storage.ErrNotFound) {
+		return nil, err
+	}
+
+	return s.generateDerivative(ctx, source, key, t)
+}
+
+// InvalidateImage حذف كل مشتقات الصورة ومسح نسخ CDN
+func (s *imageServiceImpl) InvalidateImage(ctx context.Context, fileID string) error {
+	rows, err := s.db.QueryContext(ctx, "SELECT key FROM image_derivatives WHERE file_id = ?", fileID)
+	if err != nil {
+		return fmt.Errorf("failed to list image derivatives: %w", err)
+	}
+
+	var keys []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan image derivative: %w", err)
+		}
+		keys = append(keys, key)
+	}
+	rows.Close()
+
+	if s.storage != nil {
+		for _, key := range keys {
+			if err := s.storage.Delete(ctx, key); err != nil && !errors.Is(err, storage.ErrNotFound) {
+				return fmt.Errorf("failed to delete image derivative: %w", err)
+			}
+		}
+	}
+
+	if _, err := s.db.ExecContext(ctx, "DELETE FROM image_derivatives WHERE file_id = ?", fileID); err != nil {
+		return fmt.Errorf("failed to delete image derivatives: %w", err)
+	}
+
+	if s.options.Purge != nil {
+		if err := s.options.Purge([]string{ImageCacheTag(fileID)}); err != nil {
+			return fmt.Errorf("failed to purge image cache: %w", err)
+		}
+	}
+	return nil
+}
+
+// sourceImage بيانات الصورة الأصلية من جدول الملفات
+type sourceImage struct {
+	ID   string
+	Name string
+	Type string
+	Size int64
+}
+
+func (s *imageServiceImpl) getSourceImage(ctx context.Context, fileID string) (*sourceImage, error) {
+	var src sourceImage
+	var scanStatus sql.NullString
+	err := s.db.QueryRowContext(ctx,
+		"SELECT id, name, type, size, scan_status FROM files WHERE id = ? AND deleted_at IS NULL",
+		fileID,
+	).Scan(&src.ID, &src.Name, &src.Type, &src.Size, &scanStatus)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrFileNotFound
+		}
+		return nil, fmt.Errorf("failed to get source image: %w", err)
+	}
+
+	// لا تولد مشتقات من ملف لم يجتز الفحص
+	switch scanStatus.String {
+	case FileScanClean:
+	case FileScanQuarantined:
+		return nil, ErrFileQuarantined
+	default:
+		return nil, ErrFileNotScanned
+	}
+
+	if !strings.HasPrefix(src.Type, "image/") {
+		return nil, ErrNotAnImage
+	}
+	if src.Size > s.options.MaxSourceSize {
+		return nil, ErrUploadTooLarge
+	}
+	return &src, nil
+}
+
+// loadDerivative فتح مشتق مخزن سابقاً
+func (s *imageServiceImpl) loadDerivative(ctx context.Context, fileID, key string) (*ImageDerivative, error) {
+	derivative := &ImageDerivative{FileID: fileID, Key: key}
+	err := s.db.QueryRowContext(ctx,
+		"SELECT content_type, etag, size, created_at FROM image_derivatives WHERE key = ?",
+		key,
+	).Scan(&derivative.ContentType, &derivative.ETag, &derivative.Size, &derivative.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, storage.ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get image derivative: %w", err)
+	}
+
+	body, _, err := s.storage.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	derivative.Body = body
+	return derivative, nil
+}
+
+// generateDerivative توليد المشتق من الأصل وتخزينه وتسجيله
+func (s *imageServiceImpl) generateDerivative(ctx context.Context, source *sourceImage, key string, t ImageTransform) (*ImageDerivative, error) {
+	rc, _, err := s.storage.Get(ctx, fileStorageKey(source.ID, source.Name))
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, ErrFileNotFound
+		}
+		return nil, fmt.Errorf("failed to open source image: %w", err)
+	}
+	data, err := io.ReadAll(io.LimitReader(rc, s.options.MaxSourceSize+1))
+	rc.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read source image: %w", err)
+	}
+
+	result, err := s.media.ProcessImage(ctx, data, aiservices.ImageProcessOptions{
+		Width:   t.Width,
+		Height:  t.Height,
+		Fit:     t.Fit,
+		Format:  t.Format,
+		Quality: t.Quality,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNotAnImage, err)
+	}
+
+	sum := sha256.Sum256(result.Data)
+	derivative := &ImageDerivative{
+		FileID:      source.ID,
+		Key:         key,
+		ContentType: result.ContentType(),
+		ETag:        hex.EncodeToString(sum[:16]),
+		Size:        int64(len(result.Data)),
+		CreatedAt:   time.Now(),
+		Body:        io.NopCloser(bytes.NewReader(result.Data)),
+	}
+
+	if _, err := s.storage.Put(ctx, key, bytes.NewReader(result.Data), derivative.Size, derivative.ContentType); err != nil {
+		return nil, fmt.Errorf("failed to store image derivative: %w", err)
+	}
+
+	_, err = s.db.ExecContext(ctx,
+		`INSERT OR REPLACE INTO image_derivatives (key, file_id, content_type, etag, size, created_at)
+		 VALUES (?, ?, ?, ?, ?, ?)`,
+		key, source.ID, derivative.ContentType, derivative.ETag, derivative.Size, derivative.CreatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save image derivative: %w", err)
+	}
+
+	return derivative, nil
+}
+
+// imageFormatFromType تنسيق الإخراج الافتراضي المطابق لنوع الأصل
+func imageFormatFromType(contentType string) string {
+	format := strings.TrimPrefix(strings.ToLower(contentType), "image/")
+	if format == "jpg" {
+		format = "jpeg"
+	}
+	if !imageTransformFormats[format] {
+		return "jpeg"
+	}
+	return format
+}
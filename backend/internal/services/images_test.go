@@ -0,0 +1,171 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/nawthtech/nawthtech/backend/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testPNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		img.Set(x, 0, color.NRGBA{R: 200, A: 255})
+	}
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+func TestImageTransformSignature(t *testing.T) {
+	images := NewImageService(nil, nil, ImageOptions{SigningKey: []byte("secret"), BaseURL: "https://api.example.com/images"})
+	transform := ImageTransform{Width: 320, Format: "webp"}
+
+	signature := images.SignTransform("file_1", transform)
+	assert.True(t, images.VerifyTransform("file_1", transform, signature))
+
+	// أي تعديل على المعاملات أو المعرف أو التوقيع يبطل الرابط
+	assert.False(t, images.VerifyTransform("file_1", ImageTransform{Width: 3200, Format: "webp"}, signature))
+	assert.False(t, images.VerifyTransform("file_2", transform, signature))
+	assert.False(t, images.VerifyTransform("file_1", transform, signature[:len(signature)-1]+"0"))
+	assert.False(t, images.VerifyTransform("file_1", transform, ""))
+
+	other := NewImageService(nil, nil, ImageOptions{SigningKey: []byte("other")})
+	assert.False(t, other.VerifyTransform("file_1", transform, signature))
+	unsigned := NewImageService(nil, nil, ImageOptions{})
+	assert.False(t, unsigned.VerifyTransform("file_1", transform, unsigned.SignTransform("file_1", transform)))
+}
+
+func TestImageTransformURLExpiry(t *testing.T) {
+	images := NewImageService(nil, nil, ImageOptions{SigningKey: []byte("secret"), URLTTL: time.Hour})
+
+	link, err := url.Parse(images.TransformURL("file_1", ImageTransform{Width: 320}))
+	require.NoError(t, err)
+	query := link.Query()
+	require.NotEmpty(t, query.Get("expires"))
+
+	expires, err := strconv.ParseInt(query.Get("expires"), 10, 64)
+	require.NoError(t, err)
+	transform := ImageTransform{Width: 320, Expires: expires}
+	assert.True(t, images.VerifyTransform("file_1", transform, query.Get("sig")))
+
+	// تمديد الصلاحية يغير التوقيع
+	extended := transform
+	extended.Expires += 3600
+	assert.False(t, images.VerifyTransform("file_1", extended, query.Get("sig")))
+
+	expired := ImageTransform{Width: 320, Expires: time.Now().Add(-time.Minute).Unix()}
+	assert.False(t, images.VerifyTransform("file_1", expired, images.SignTransform("file_1", expired)))
+}
+
+func TestImageTransformCanonical(t *testing.T) {
+	images := NewImageService(nil, nil, ImageOptions{SigningKey: []byte("secret")})
+
+	// القيم الافتراضية والحالة والاسم البديل لا تغير التوقيع ولا مفتاح المشتق
+	explicit := ImageTransform{Width: 100, Fit: "contain", Format: "jpeg", Quality: 85}
+	for _, equivalent := range []ImageTransform{
+		{Width: 100, Format: "jpeg"},
+		{Width: 100, Fit: "CONTAIN", Format: "JPG", Quality: 85},
+	} {
+		assert.Equal(t, explicit.normalize().canonical(), equivalent.normalize().canonical())
+		assert.Equal(t, images.SignTransform("file_1", explicit), images.SignTransform("file_1", equivalent))
+	}
+
+	assert.NotEqual(t, images.SignTransform("file_1", explicit), images.SignTransform("file_1", ImageTransform{Width: 100, Format: "png"}))
+	assert.Equal(t, "w100-h0-contain-q85.jpeg", explicit.normalize().canonical())
+
+	// الصلاحية جزء من التوقيع لكنها ليست جزءاً من مفتاح المشتق
+	expiring := explicit
+	expiring.Expires = time.Now().Add(time.Hour).Unix()
+	assert.Equal(t, explicit.canonical(), expiring.canonical())
+	assert.NotEqual(t, images.SignTransform("file_1", explicit), images.SignTransform("file_1", expiring))
+
+	assert.ErrorIs(t, ImageTransform{Width: 5000}.normalize().validate(4096), ErrInvalidTransform)
+	assert.ErrorIs(t, ImageTransform{Fit: "stretch"}.normalize().validate(4096), ErrInvalidTransform)
+	assert.ErrorIs(t, ImageTransform{Format: "bmp"}.normalize().validate(4096), ErrInvalidTransform)
+}
+
+// TestImageDerivatives توليد المشتق عند أول طلب، وإعادته من التخزين بعدها، وإبطاله عند حذف الأصل
+func TestImageDerivatives(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T, container *ServiceContainer, database *sql.DB) {
+		ctx := context.Background()
+		var images ImageService
+		uploads, store, userID := newTestUploadService(t, container, database, UploadOptions{
+			OnFileChange: func(ctx context.Context, fileID string) {
+				require.NoError(t, images.InvalidateImage(ctx, fileID))
+			},
+		})
+
+		var purged []string
+		images = NewImageService(database, store, ImageOptions{
+			SigningKey: []byte("secret"),
+			Purge: func(tags []string) error {
+				purged = append(purged, tags...)
+				return nil
+			},
+		})
+
+		data := testPNG(t, 40, 20)
+		file, err := uploads.UploadFile(ctx, UploadRequest{UserID: userID, FileName: "photo.png", FileType: "image/png"}, data)
+		require.NoError(t, err)
+		_, err = database.ExecContext(ctx, "UPDATE files SET scan_status = ? WHERE id = ?", FileScanClean, file.ID)
+		require.NoError(t, err)
+
+		transform := ImageTransform{Width: 20, Format: "jpeg"}
+		first, err := images.GetDerivative(ctx, file.ID, transform)
+		require.NoError(t, err)
+		first.Body.Close()
+		assert.Equal(t, "image/jpeg", first.ContentType)
+
+		decoded, _, err := image.DecodeConfig(bytesOf(t, store, first.Key))
+		require.NoError(t, err)
+		assert.Equal(t, 20, decoded.Width)
+		assert.Equal(t, 10, decoded.Height)
+
+		// الطلب المكافئ يعاد من التخزين دون توليد جديد (الأصل لم يعد متاحاً للتوليد)
+		require.NoError(t, store.Delete(ctx, fileStorageKey(file.ID, "photo.png")))
+		second, err := images.GetDerivative(ctx, file.ID, ImageTransform{Width: 20, Format: "JPG", Fit: "contain"})
+		require.NoError(t, err)
+		second.Body.Close()
+		assert.Equal(t, first.Key, second.Key)
+		assert.Equal(t, first.ETag, second.ETag)
+		assert.Equal(t, first.CreatedAt.Unix(), second.CreatedAt.Unix())
+
+		var count int
+		require.NoError(t, database.QueryRowContext(ctx, "SELECT COUNT(*) FROM image_derivatives WHERE file_id = ?", file.ID).Scan(&count))
+		assert.Equal(t, 1, count)
+
+		require.NoError(t, uploads.DeleteFile(ctx, file.ID))
+
+		require.NoError(t, database.QueryRowContext(ctx, "SELECT COUNT(*) FROM image_derivatives WHERE file_id = ?", file.ID).Scan(&count))
+		assert.Equal(t, 0, count)
+		_, err = store.Stat(ctx, first.Key)
+		assert.ErrorIs(t, err, storage.ErrNotFound)
+		assert.Equal(t, []string{ImageCacheTag(file.ID)}, purged)
+
+		_, err = images.GetDerivative(ctx, file.ID, transform)
+		assert.ErrorIs(t, err, ErrFileNotFound)
+	})
+}
+
+func bytesOf(t *testing.T, store storage.Storage, key string) io.Reader {
+	t.Helper()
+	rc, _, err := store.Get(context.Background(), key)
+	require.NoError(t, err)
+	defer rc.Close()
+	data, err := io.ReadAll(rc)
+	require.NoError(t, err)
+	return bytes.NewReader(data)
+}
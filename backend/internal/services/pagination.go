@@ -0,0 +1,92 @@
+package services
+
+import (
+	"context"
+
+	"github.com/nawthtech/nawthtech/backend/internal/config"
+	"github.com/nawthtech/nawthtech/backend/internal/repository"
+)
+
+// ================================
+// صفحات القوائم
+// ================================
+
+// القوائم ترتب بـ created_at تنازلياً مع المعرف لكسر التعادل. الصفحة الأولى
+// تُطلب برقم الصفحة أو بدونه، وما بعدها بـ Cursor المأخوذ من NextCursor؛
+// المؤشر معتم وموقع ومقيد بقائمته، ولا يتأثر بالإضافات أثناء التصفح.
+
+// نطاقات المؤشرات: مؤشر قائمة لا يُقبل في أخرى
+const (
+	cursorScopeServices      = "services"
+	cursorScopeOrders        = "orders"
+	cursorScopePayments      = "payments"
+	cursorScopeNotifications = "notifications"
+	cursorScopeSystemLogs    = "system_logs"
+)
+
+// Page صفحة من قائمة مع مؤشر الصفحة التالية
+type Page[T any] struct {
+	Items      []T    `json:"items"`
+	Limit      int    `json:"limit"`
+	HasMore    bool   `json:"has_more"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	// Total الإجمالي المطابق للفلاتر، عند WithTotal فقط (استعلام COUNT إضافي)
+	Total *int64 `json:"total,omitempty"`
+}
+
+// defaultCursors مرمز المؤشرات للخدمات المنشأة بلا إعدادات (مفتاح لعمر العملية)
+var defaultCursors = repository.NewCursorCodec(nil)
+
+// newCursorCodec مرمز المؤشرات من الإعدادات: ENCRYPTION_KEY ثم JWT_SECRET،
+// حتى تبقى المؤشرات صالحة بين النسخ وبعد إعادة التشغيل
+func newCursorCodec(cfg *config.Config) *repository.CursorCodec {
+	if cfg == nil {
+		return defaultCursors
+	}
+	secret := cfg.EncryptionKey
+	if secret == "" {
+		secret = cfg.Auth.JWTSecret
+	}
+	if secret == "" {
+		return defaultCursors
+	}
+	return repository.NewCursorCodec([]byte(secret))
+}
+
+// paginate تطبيق الصفحة المطلوبة (بالمؤشر أو برقم الصفحة) على الاستعلام وجلبها
+func paginate[T any](
+	ctx context.Context,
+	cursors *repository.CursorCodec,
+	scope string,
+	q *repository.Query[T],
+	page, limit int,
+	cursor string,
+	withTotal bool,
+	fetch func(context.Context, *repository.Query[T], int, bool) (*repository.Paged[T], error),
+) (*Page[T], error) {
+	page, limit = validatePaginationParams(page, limit)
+	if cursor != "" {
+		after, err := cursors.Decode(scope, cursor)
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+		q.After(after)
+	} else {
+		q.Offset(calculateOffset(page, limit))
+	}
+
+	paged, err := fetch(ctx, q, limit, withTotal)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Page[T]{Items: paged.Items, Limit: limit, HasMore: paged.Next != nil}
+	if paged.Next != nil {
+		result.NextCursor = cursors.Encode(scope, *paged.Next)
+	}
+	if paged.Total >= 0 {
+		total := paged.Total
+		result.Total = &total
+	}
+	return result, nil
+}
@@ -1,18 +1,26 @@
 package services
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"runtime"
 	"strings"
-	"sync"
 	"time"
 
+	"github.com/nawthtech/nawthtech/backend/internal/ai"
+	"github.com/nawthtech/nawthtech/backend/internal/cache"
 	"github.com/nawthtech/nawthtech/backend/internal/config"
+	"github.com/nawthtech/nawthtech/backend/internal/db"
+	"github.com/nawthtech/nawthtech/backend/internal/db/migrations"
+	"github.com/nawthtech/nawthtech/backend/internal/logger"
 	"github.com/nawthtech/nawthtech/backend/internal/models"
+	"github.com/nawthtech/nawthtech/backend/internal/repository"
+	"github.com/nawthtech/nawthtech/backend/internal/storage"
 	"go.uber.org/zap"
 )
 
@@ -107,6 +115,8 @@ type ServiceQueryParams struct {
 	IsActive   bool    `json:"is_active"`
 	IsFeatured bool    `json:"is_featured"`
 	Search     string  `json:"search"`
+	Cursor     string  `json:"cursor,omitempty"`
+	WithTotal  bool    `json:"with_total,omitempty"`
 }
 
 type CategoryCreateRequest struct {
@@ -135,17 +145,20 @@ type CategoryNode struct {
 }
 
 type OrderCreateRequest struct {
-	UserID    string  `json:"user_id" validate:"required"`
-	ServiceID string  `json:"service_id" validate:"required"`
-	Amount    float64 `json:"amount" validate:"required,min=0"`
-	Notes     string  `json:"notes" validate:"max=500"`
+	UserID      string   `json:"user_id" validate:"required"`
+	ServiceID   string   `json:"service_id" validate:"required"`
+	Amount      float64  `json:"amount" validate:"required,min=0"`
+	Notes       string   `json:"notes" validate:"max=500"`
+	Attachments []string `json:"attachments" validate:"max=10"`
 }
 
 type OrderQueryParams struct {
-	Page   int    `json:"page" validate:"min=1"`
-	Limit  int    `json:"limit" validate:"min=1,max=100"`
-	Status string `json:"status"`
-	UserID string `json:"user_id"`
+	Page      int    `json:"page" validate:"min=1"`
+	Limit     int    `json:"limit" validate:"min=1,max=100"`
+	Status    string `json:"status"`
+	UserID    string `json:"user_id"`
+	Cursor    string `json:"cursor,omitempty"`
+	WithTotal bool   `json:"with_total,omitempty"`
 }
 
 type OrderStats struct {
@@ -188,13 +201,15 @@ type PaymentResult struct {
 }
 
 type PaymentQueryParams struct {
-	Page     int       `json:"page" validate:"min=1"`
-	Limit    int       `json:"limit" validate:"min=1,max=100"`
-	Status   string    `json:"status"`
-	UserID   string    `json:"user_id"`
-	OrderID  string    `json:"order_id"`
-	FromDate time.Time `json:"from_date,omitempty"`
-	ToDate   time.Time `json:"to_date,omitempty"`
+	Page      int       `json:"page" validate:"min=1"`
+	Limit     int       `json:"limit" validate:"min=1,max=100"`
+	Status    string    `json:"status"`
+	UserID    string    `json:"user_id"`
+	OrderID   string    `json:"order_id"`
+	FromDate  time.Time `json:"from_date,omitempty"`
+	ToDate    time.Time `json:"to_date,omitempty"`
+	Cursor    string    `json:"cursor,omitempty"`
+	WithTotal bool      `json:"with_total,omitempty"`
 }
 
 type PaymentValidation struct {
@@ -205,17 +220,22 @@ type PaymentValidation struct {
 }
 
 type UploadRequest struct {
+	UserID   string `json:"user_id" validate:"required"`
 	FileName string `json:"file_name" validate:"required"`
 	FileType string `json:"file_type" validate:"required"`
 	FileSize int64  `json:"file_size" validate:"required,min=1"`
 }
 
 type UploadResult struct {
+	ID       string    `json:"id"`
 	URL      string    `json:"url"`
 	FileName string    `json:"file_name"`
 	FileType string    `json:"file_type"`
 	FileSize int64     `json:"file_size"`
 	Uploaded time.Time `json:"uploaded"`
+	// ScanStatus حالة الفحص؛ الرابط لا يعاد إلا بعد أن يصبح الملف نظيفاً
+	ScanStatus string `json:"scan_status,omitempty"`
+	ScanReason string `json:"scan_reason,omitempty"`
 }
 
 type NotificationCreateRequest struct {
@@ -226,11 +246,13 @@ type NotificationCreateRequest struct {
 }
 
 type NotificationQueryParams struct {
-	Page   int    `json:"page" validate:"min=1"`
-	Limit  int    `json:"limit" validate:"min=1,max=100"`
-	UserID string `json:"user_id"`
-	Type   string `json:"type"`
-	Read   *bool  `json:"read"`
+	Page      int    `json:"page" validate:"min=1"`
+	Limit     int    `json:"limit" validate:"min=1,max=100"`
+	UserID    string `json:"user_id"`
+	Type      string `json:"type"`
+	Read      *bool  `json:"read"`
+	Cursor    string `json:"cursor,omitempty"`
+	WithTotal bool   `json:"with_total,omitempty"`
 }
 
 // ================================
@@ -248,12 +270,14 @@ type DashboardStats struct {
 }
 
 type SystemLogQuery struct {
-	Page     int       `json:"page" validate:"min=1"`
-	Limit    int       `json:"limit" validate:"min=1,max=100"`
-	Level    string    `json:"level"`
-	UserID   string    `json:"user_id"`
-	FromDate time.Time `json:"from_date,omitempty"`
-	ToDate   time.Time `json:"to_date,omitempty"`
+	Page      int       `json:"page" validate:"min=1"`
+	Limit     int       `json:"limit" validate:"min=1,max=100"`
+	Level     string    `json:"level"`
+	UserID    string    `json:"user_id"`
+	FromDate  time.Time `json:"from_date,omitempty"`
+	ToDate    time.Time `json:"to_date,omitempty"`
+	Cursor    string    `json:"cursor,omitempty"`
+	WithTotal bool      `json:"with_total,omitempty"`
 }
 
 // ================================
@@ -370,6 +394,7 @@ type UserService interface {
 	DeleteAccount(ctx context.Context, userID string) error
 	SearchUsers(ctx context.Context, query string, params UserQueryParams) ([]models.User, error)
 	GetUserStats(ctx context.Context, userID string) (*UserStats, error)
+	GetUserTier(ctx context.Context, userID string) (string, error)
 }
 
 type ServiceService interface {
@@ -378,6 +403,7 @@ type ServiceService interface {
 	UpdateService(ctx context.Context, serviceID string, req ServiceUpdateRequest) (*models.Service, error)
 	DeleteService(ctx context.Context, serviceID string) error
 	GetServices(ctx context.Context, params ServiceQueryParams) ([]models.Service, error)
+	ListServices(ctx context.Context, params ServiceQueryParams) (*Page[models.Service], error)
 	SearchServices(ctx context.Context, query string, params ServiceQueryParams) ([]models.Service, error)
 	GetFeaturedServices(ctx context.Context) ([]models.Service, error)
 	GetSimilarServices(ctx context.Context, serviceID string) ([]models.Service, error)
@@ -396,6 +422,7 @@ type OrderService interface {
 	CreateOrder(ctx context.Context, req OrderCreateRequest) (*models.Order, error)
 	GetOrderByID(ctx context.Context, orderID string) (*models.Order, error)
 	GetUserOrders(ctx context.Context, userID string, params OrderQueryParams) ([]models.Order, error)
+	ListUserOrders(ctx context.Context, userID string, params OrderQueryParams) (*Page[models.Order], error)
 	UpdateOrderStatus(ctx context.Context, orderID string, status string, notes string) (*models.Order, error)
 	CancelOrder(ctx context.Context, orderID string, reason string) (*models.Order, error)
 	GetOrderStats(ctx context.Context, timeframe string) (*OrderStats, error)
@@ -405,20 +432,38 @@ type PaymentService interface {
 	CreatePaymentIntent(ctx context.Context, req PaymentIntentRequest) (*PaymentIntent, error)
 	ConfirmPayment(ctx context.Context, paymentID string, confirmationData map[string]interface{}) (*PaymentResult, error)
 	GetPaymentHistory(ctx context.Context, userID string, params PaymentQueryParams) ([]models.Payment, error)
+	ListPaymentHistory(ctx context.Context, userID string, params PaymentQueryParams) (*Page[models.Payment], error)
 	ValidatePayment(ctx context.Context, paymentData map[string]interface{}) (*PaymentValidation, error)
 }
 
 type UploadService interface {
 	UploadFile(ctx context.Context, req UploadRequest, fileData []byte) (*UploadResult, error)
+	ReplaceFile(ctx context.Context, fileID string, req UploadRequest, fileData []byte) (*UploadResult, error)
 	DeleteFile(ctx context.Context, fileID string) error
+	ScanFile(ctx context.Context, fileID string) (*models.File, error)
+	ScanPendingFiles(ctx context.Context) (int, error)
 	GetFile(ctx context.Context, fileID string) (*models.File, error)
 	GetUserFiles(ctx context.Context, userID string) ([]models.File, error)
-	GeneratePresignedURL(ctx context.Context, fileName, fileType string) (string, error)
+	GetDeletedFiles(ctx context.Context, userID string) ([]models.File, error)
+	RestoreFile(ctx context.Context, fileID string) (*models.File, error)
+	GetStorageUsage(ctx context.Context, userID string) (*StorageUsage, error)
+	DetectOrphanedFiles(ctx context.Context) (int, error)
+	CollectGarbage(ctx context.Context) (*StorageGCReport, error)
+	OpenFile(ctx context.Context, fileID string) (io.ReadCloser, *models.File, error)
+	GeneratePresignedURL(ctx context.Context, req PresignedUploadRequest) (*PresignedUpload, error)
+	UploadWithToken(ctx context.Context, token string, r io.Reader, size int64, contentType string) error
+	FinalizeUpload(ctx context.Context, userID, token string) (*UploadResult, error)
+	CreateResumableUpload(ctx context.Context, req ResumableUploadRequest) (*ResumableUpload, error)
+	GetResumableUpload(ctx context.Context, uploadID string) (*ResumableUpload, error)
+	WriteChunk(ctx context.Context, uploadID string, offset int64, r io.Reader, size int64, checksum string) (*ResumableUpload, error)
+	AbortResumableUpload(ctx context.Context, uploadID string) error
+	CleanupAbandonedUploads(ctx context.Context) (int, error)
 }
 
 type NotificationService interface {
 	CreateNotification(ctx context.Context, req NotificationCreateRequest) (*models.Notification, error)
 	GetUserNotifications(ctx context.Context, userID string, params NotificationQueryParams) ([]models.Notification, error)
+	ListUserNotifications(ctx context.Context, userID string, params NotificationQueryParams) (*Page[models.Notification], error)
 	MarkAsRead(ctx context.Context, notificationID string) error
 	MarkAllAsRead(ctx context.Context, userID string) error
 	DeleteNotification(ctx context.Context, notificationID string) error
@@ -429,9 +474,11 @@ type AdminService interface {
 	GetDashboardStats(ctx context.Context) (*DashboardStats, error)
 	GetUsers(ctx context.Context, params UserQueryParams) ([]models.User, error)
 	GetSystemLogs(ctx context.Context, params SystemLogQuery) ([]models.SystemLog, error)
+	ListSystemLogs(ctx context.Context, params SystemLogQuery) (*Page[models.SystemLog], error)
 	UpdateSystemSettings(ctx context.Context, settings map[string]string) error
 	BanUser(ctx context.Context, userID string, reason string) error
 	UnbanUser(ctx context.Context, userID string) error
+	SetUserTier(ctx context.Context, userID string, tier string) error
 }
 
 type CacheService interface {
@@ -440,6 +487,7 @@ type CacheService interface {
 	Delete(key string) error
 	Exists(key string) (bool, error)
 	Flush() error
+	Stats() cache.Stats
 }
  
 type HealthService interface {
@@ -468,15 +516,19 @@ type HealthService interface {
 // ================================
 
 type authServiceImpl struct {
-	db *sql.DB
+	db    *sql.DB
+	store *repository.Store
 }
 
 type userServiceImpl struct {
-	db *sql.DB
+	db    *sql.DB
+	store *repository.Store
 }
 
 type serviceServiceImpl struct {
-	db *sql.DB
+	db      *sql.DB
+	store   *repository.Store
+	cursors *repository.CursorCodec
 }
 
 type categoryServiceImpl struct {
@@ -484,28 +536,34 @@ type categoryServiceImpl struct {
 }
 
 type orderServiceImpl struct {
-	db *sql.DB
+	db      *sql.DB
+	store   *repository.Store
+	cursors *repository.CursorCodec
 }
 
 type paymentServiceImpl struct {
-	db *sql.DB
+	db      *sql.DB
+	store   *repository.Store
+	cursors *repository.CursorCodec
 }
 
 type uploadServiceImpl struct {
-	db *sql.DB
+	db      *sql.DB
+	files   *repository.FileRepository
+	storage storage.Storage
+	options UploadOptions
 }
 
 type notificationServiceImpl struct {
-	db *sql.DB
+	db      *sql.DB
+	store   *repository.Store
+	cursors *repository.CursorCodec
 }
 
 type adminServiceImpl struct {
-	db *sql.DB
-}
-
-type cacheServiceImpl struct {
-	store map[string]interface{}
-	mu    sync.RWMutex
+	db      *sql.DB
+	store   *repository.Store
+	cursors *repository.CursorCodec
 }
 
 // تطبيق HealthService
@@ -513,6 +571,7 @@ type healthServiceImpl struct {
 	db     *sql.DB
 	config *config.Config
 	logger *zap.Logger
+	cache  CacheService
 	startTime time.Time
 }
 
@@ -528,331 +587,21 @@ type ServiceContainer struct {
 	Order        OrderService
 	Payment      PaymentService
 	Upload       UploadService
+	Image        ImageService
 	Notification NotificationService
 	Admin        AdminService
 	Cache        CacheService
 	Health       HealthService
+	// CatalogCache طبقة القراءة المشتركة بين خدمات الكتالوج وذاكرة استجابات HTTP (nil إن كان التخزين المؤقت معطلاً)
+	CatalogCache *cache.ReadThrough
+	// AICost مدير تكاليف الذكاء الاصطناعي وميزانياته في قاعدة البيانات (nil بلا قاعدة بيانات)
+	AICost *ai.CostManager
 
 	db     *sql.DB
 	config *config.Config
 	logger *zap.Logger
 }
 
-// أضف هذه الدوال لـ ServiceContainer:
-func (sc *ServiceContainer) InitializeHealthTables(ctx context.Context) error {
-	// إنشاء جداول الصحة فقط
-	healthTables := []string{
-		// جدول سجلات الصحة
-		`CREATE TABLE IF NOT EXISTS health_logs (
-			id TEXT PRIMARY KEY,
-			service_name TEXT NOT NULL,
-			status TEXT NOT NULL,
-			check_type TEXT NOT NULL,
-			duration_ms INTEGER NOT NULL,
-			message TEXT,
-			metadata TEXT DEFAULT '{}',
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-		)`,
-
-		// جدول مقاييس الأداء
-		`CREATE TABLE IF NOT EXISTS performance_metrics (
-			id TEXT PRIMARY KEY,
-			metric_name TEXT NOT NULL,
-			metric_value REAL NOT NULL,
-			metric_unit TEXT,
-			metadata TEXT DEFAULT '{}',
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-		)`,
-
-		// جدول أحداث النظام
-		`CREATE TABLE IF NOT EXISTS system_events (
-			id TEXT PRIMARY KEY,
-			event_type TEXT NOT NULL,
-			event_source TEXT NOT NULL,
-			severity TEXT NOT NULL,
-			description TEXT NOT NULL,
-			details TEXT,
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-		)`,
-
-		// جدول إحصائيات الـ API
-		`CREATE TABLE IF NOT EXISTS api_statistics (
-			id TEXT PRIMARY KEY,
-			endpoint TEXT NOT NULL,
-			method TEXT NOT NULL,
-			status_code INTEGER NOT NULL,
-			response_time_ms INTEGER NOT NULL,
-			user_id TEXT,
-			ip_address TEXT,
-			user_agent TEXT,
-			metadata TEXT DEFAULT '{}',
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-		)`,
-
-		// جدول توفر الخدمة
-		`CREATE TABLE IF NOT EXISTS service_availability (
-			id TEXT PRIMARY KEY,
-			service_name TEXT NOT NULL,
-			status TEXT NOT NULL,
-			uptime_percentage REAL,
-			response_time_avg REAL,
-			error_rate REAL,
-			last_check TIMESTAMP,
-			next_check TIMESTAMP,
-			metadata TEXT DEFAULT '{}',
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-			UNIQUE(service_name)
-		)`,
-
-		// جدول تنبيهات النظام
-		`CREATE TABLE IF NOT EXISTS system_alerts (
-			id TEXT PRIMARY KEY,
-			alert_type TEXT NOT NULL,
-			alert_level TEXT NOT NULL,
-			alert_message TEXT NOT NULL,
-			is_resolved BOOLEAN DEFAULT FALSE,
-			resolved_at TIMESTAMP,
-			resolved_by TEXT,
-			resolution_note TEXT,
-			metadata TEXT DEFAULT '{}',
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-		)`,
-
-		// جدول المراقبة في الوقت الحقيقي
-		`CREATE TABLE IF NOT EXISTS realtime_metrics (
-			id TEXT PRIMARY KEY,
-			metric_type TEXT NOT NULL,
-			metric_value REAL NOT NULL,
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-		)`,
-
-		// جدول فحوصات الصحة المجدولة
-		`CREATE TABLE IF NOT EXISTS scheduled_checks (
-			id TEXT PRIMARY KEY,
-			check_name TEXT NOT NULL,
-			check_type TEXT NOT NULL,
-			schedule TEXT NOT NULL,
-			last_run TIMESTAMP,
-			last_status TEXT,
-			next_run TIMESTAMP,
-			is_active BOOLEAN DEFAULT TRUE,
-			metadata TEXT DEFAULT '{}',
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-			UNIQUE(check_name)
-		)`,
-
-		// جدول اعتمادية الخدمات
-		`CREATE TABLE IF NOT EXISTS service_dependencies (
-			id TEXT PRIMARY KEY,
-			service_name TEXT NOT NULL,
-			dependency_name TEXT NOT NULL,
-			dependency_type TEXT NOT NULL,
-			dependency_url TEXT,
-			is_required BOOLEAN DEFAULT TRUE,
-			last_check TIMESTAMP,
-			last_status TEXT,
-			metadata TEXT DEFAULT '{}',
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-			UNIQUE(service_name, dependency_name)
-		)`,
-
-		// جدول تقارير الأداء
-		`CREATE TABLE IF NOT EXISTS performance_reports (
-			id TEXT PRIMARY KEY,
-			report_type TEXT NOT NULL,
-			report_period TEXT NOT NULL,
-			metrics_data TEXT NOT NULL,
-			generated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-		)`,
-
-		// جدول استخدام الموارد
-		`CREATE TABLE IF NOT EXISTS resource_usage (
-			id TEXT PRIMARY KEY,
-			resource_type TEXT NOT NULL,
-			usage_percentage REAL NOT NULL,
-			total_amount REAL,
-			used_amount REAL,
-			unit TEXT,
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-		)`,
-
-		// جدول فحوصات الأمان
-		`CREATE TABLE IF NOT EXISTS security_checks (
-			id TEXT PRIMARY KEY,
-			check_name TEXT NOT NULL,
-			check_category TEXT NOT NULL,
-			status TEXT NOT NULL,
-			details TEXT,
-			last_run TIMESTAMP,
-			next_run TIMESTAMP,
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-		)`,
-
-		// جدول تحديثات الإصدارات
-		`CREATE TABLE IF NOT EXISTS version_updates (
-			id TEXT PRIMARY KEY,
-			component_name TEXT NOT NULL,
-			current_version TEXT NOT NULL,
-			latest_version TEXT,
-			update_available BOOLEAN DEFAULT FALSE,
-			release_notes TEXT,
-			last_checked TIMESTAMP,
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-			UNIQUE(component_name)
-		)`,
-
-		// جدول وقت التشغيل
-		`CREATE TABLE IF NOT EXISTS uptime_records (
-			id TEXT PRIMARY KEY,
-			service_name TEXT NOT NULL,
-			start_time TIMESTAMP NOT NULL,
-			end_time TIMESTAMP,
-			duration_seconds INTEGER,
-			status TEXT NOT NULL,
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-		)`,
-
-		// جدول فحوصات قاعدة البيانات
-		`CREATE TABLE IF NOT EXISTS database_health (
-			id TEXT PRIMARY KEY,
-			database_name TEXT NOT NULL,
-			connection_status TEXT NOT NULL,
-			connection_time_ms INTEGER,
-			query_count INTEGER,
-			active_connections INTEGER,
-			max_connections INTEGER,
-			disk_usage_mb REAL,
-			last_vacuum TIMESTAMP,
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-		)`,
-
-		// جدول أخطاء التطبيق
-		`CREATE TABLE IF NOT EXISTS application_errors (
-			id TEXT PRIMARY KEY,
-			error_type TEXT NOT NULL,
-			error_message TEXT NOT NULL,
-			stack_trace TEXT,
-			endpoint TEXT,
-			user_id TEXT,
-			ip_address TEXT,
-			resolved BOOLEAN DEFAULT FALSE,
-			resolved_at TIMESTAMP,
-			resolved_by TEXT,
-			metadata TEXT DEFAULT '{}',
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-		)`,
-
-		// جدول مقاييس الأعمال
-		`CREATE TABLE IF NOT EXISTS business_metrics (
-			id TEXT PRIMARY KEY,
-			metric_name TEXT NOT NULL,
-			metric_value REAL NOT NULL,
-			metric_date DATE NOT NULL,
-			metadata TEXT DEFAULT '{}',
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-			UNIQUE(metric_name, metric_date)
-		)`,
-	}
-
-	// إنشاء الجداول
-	for _, query := range healthTables {
-		_, err := sc.db.ExecContext(ctx, query)
-		if err != nil {
-			return fmt.Errorf("failed to create health table: %s, error: %w", query, err)
-		}
-	}
-
-	// إنشاء الفهارس
-	indexes := []string{
-		// فهارس لـ health_logs
-		`CREATE INDEX IF NOT EXISTS idx_health_logs_service ON health_logs(service_name)`,
-		`CREATE INDEX IF NOT EXISTS idx_health_logs_status ON health_logs(status)`,
-		`CREATE INDEX IF NOT EXISTS idx_health_logs_created ON health_logs(created_at)`,
-
-		// فهارس لـ performance_metrics
-		`CREATE INDEX IF NOT EXISTS idx_perf_metrics_name_date ON performance_metrics(metric_name, created_at)`,
-
-		// فهارس لـ system_events
-		`CREATE INDEX IF NOT EXISTS idx_system_events_date_severity ON system_events(created_at, severity)`,
-
-		// فهارس لـ api_statistics
-		`CREATE INDEX IF NOT EXISTS idx_api_stats_endpoint_date ON api_statistics(endpoint, created_at)`,
-		`CREATE INDEX IF NOT EXISTS idx_api_stats_user_date ON api_statistics(user_id, created_at)`,
-
-		// فهارس لـ system_alerts
-		`CREATE INDEX IF NOT EXISTS idx_alerts_type_date ON system_alerts(alert_type, created_at)`,
-
-		// فهارس لـ realtime_metrics
-		`CREATE INDEX IF NOT EXISTS idx_realtime_type_date ON realtime_metrics(metric_type, created_at)`,
-
-		// فهارس لـ application_errors
-		`CREATE INDEX IF NOT EXISTS idx_errors_type_date ON application_errors(error_type, created_at)`,
-
-		// فهارس لـ business_metrics
-		`CREATE INDEX IF NOT EXISTS idx_business_name_date ON business_metrics(metric_name, metric_date)`,
-	}
-
-	for _, index := range indexes {
-		_, err := sc.db.ExecContext(ctx, index)
-		if err != nil {
-			sc.logger.Warn("Failed to create health index", zap.String("index", index), zap.Error(err))
-		}
-	}
-
-	// إدراج بيانات افتراضية
-	defaultData := []string{
-		// فحوصات مجدولة افتراضية
-		`INSERT OR IGNORE INTO scheduled_checks (id, check_name, check_type, schedule, is_active) VALUES
-			('check_db_001', 'Database Health Check', 'database', '*/5 * * * *', TRUE),
-			('check_api_001', 'API Endpoint Check', 'api', '*/10 * * * *', TRUE),
-			('check_cache_001', 'Cache Service Check', 'external_service', '*/15 * * * *', TRUE),
-			('check_storage_001', 'Storage Service Check', 'external_service', '*/30 * * * *', TRUE)`,
-
-		// اعتمادية الخدمات
-		`INSERT OR IGNORE INTO service_dependencies (id, service_name, dependency_name, dependency_type, is_required) VALUES
-			('dep_001', 'nawthtech-api', 'SQLite Database', 'database', TRUE),
-			('dep_002', 'nawthtech-api', 'In-memory Cache', 'cache', FALSE),
-			('dep_003', 'nawthtech-api', 'Cloudinary Storage', 'storage', FALSE),
-			('dep_004', 'nawthtech-api', 'Payment Gateway', 'api', TRUE)`,
-
-		// فحوصات الأمان الافتراضية
-		`INSERT OR IGNORE INTO security_checks (id, check_name, check_category, status) VALUES
-			('sec_001', 'JWT Token Validation', 'authentication', 'passed'),
-			('sec_002', 'Password Hashing', 'encryption', 'passed'),
-			('sec_003', 'CORS Configuration', 'network', 'passed'),
-			('sec_004', 'SQL Injection Protection', 'database', 'passed')`,
-
-		// معلومات الإصدارات
-		`INSERT OR IGNORE INTO version_updates (id, component_name, current_version) VALUES
-			('ver_001', 'backend', '1.0.0'),
-			('ver_002', 'api', '1.0.0'),
-			('ver_003', 'database', 'sqlite-3.0')`,
-
-		// سجلات وقت التشغيل الأولية
-		`INSERT OR IGNORE INTO uptime_records (id, service_name, start_time, status) VALUES
-			('uptime_001', 'nawthtech-api', CURRENT_TIMESTAMP, 'up')`,
-	}
-
-	for _, query := range defaultData {
-		_, err := sc.db.ExecContext(ctx, query)
-		if err != nil {
-			sc.logger.Warn("Failed to insert default health data", zap.String("query", query), zap.Error(err))
-		}
-	}
-
-	if sc.logger != nil {
-		sc.logger.Info("Health tables initialized successfully")
-	}
-	
-	return nil
-}
-
 func (sc *ServiceContainer) LogHealthCheck(ctx context.Context, serviceName, status, checkType string, durationMs int64, message string, metadata map[string]interface{}) error {
 	query := `INSERT INTO health_logs 
 		(id, service_name, status, check_type, duration_ms, message, metadata, created_at)
@@ -883,15 +632,19 @@ func (sc *ServiceContainer) LogHealthCheck(ctx context.Context, serviceName, sta
 // ================================
 
 func NewAuthService(db *sql.DB) AuthService {
-	return &authServiceImpl{db: db}
+	return &authServiceImpl{db: db, store: repository.NewStore(db)}
 }
 
 func NewUserService(db *sql.DB) UserService {
-	return &userServiceImpl{db: db}
+	return &userServiceImpl{db: db, store: repository.NewStore(db)}
 }
 
 func NewServiceService(db *sql.DB) ServiceService {
-	return &serviceServiceImpl{db: db}
+	return newServiceService(db, defaultCursors)
+}
+
+func newServiceService(db *sql.DB, cursors *repository.CursorCodec) ServiceService {
+	return &serviceServiceImpl{db: db, store: repository.NewStore(db), cursors: cursors}
 }
 
 func NewCategoryService(db *sql.DB) CategoryService {
@@ -899,281 +652,196 @@ func NewCategoryService(db *sql.DB) CategoryService {
 }
 
 func NewOrderService(db *sql.DB) OrderService {
-	return &orderServiceImpl{db: db}
+	return newOrderService(db, defaultCursors)
+}
+
+func newOrderService(db *sql.DB, cursors *repository.CursorCodec) OrderService {
+	return &orderServiceImpl{db: db, store: repository.NewStore(db), cursors: cursors}
 }
 
 func NewPaymentService(db *sql.DB) PaymentService {
-	return &paymentServiceImpl{db: db}
+	return newPaymentService(db, defaultCursors)
 }
 
+func newPaymentService(db *sql.DB, cursors *repository.CursorCodec) PaymentService {
+	return &paymentServiceImpl{db: db, store: repository.NewStore(db), cursors: cursors}
+}
+
+// NewUploadService إنشاء خدمة رفع بتخزين محلي افتراضي
 func NewUploadService(db *sql.DB) UploadService {
-	return &uploadServiceImpl{db: db}
+	s := &uploadServiceImpl{db: db, files: repository.NewFileRepository(db), options: DefaultUploadOptions()}
+	// مؤشر *LocalStorage الفارغ داخل الواجهة ليس nil، فلا يُسند إلا عند النجاح
+	store, err := storage.NewLocalStorage("./uploads", "")
+	if err != nil {
+		logger.Warn(context.Background(), "failed to initialize local storage", logger.ErrAttr(err))
+		return s
+	}
+	s.storage = store
+	return s
+}
+
+// NewUploadServiceWithStorage إنشاء خدمة رفع بـ backend تخزين محدد
+func NewUploadServiceWithStorage(db *sql.DB, store storage.Storage) UploadService {
+	return &uploadServiceImpl{db: db, files: repository.NewFileRepository(db), storage: store, options: DefaultUploadOptions()}
+}
+
+// NewUploadServiceWithOptions إنشاء خدمة رفع بتخزين وحدود محددة
+func NewUploadServiceWithOptions(db *sql.DB, store storage.Storage, opts UploadOptions) UploadService {
+	defaults := DefaultUploadOptions()
+	if opts.MaxSize <= 0 {
+		opts.MaxSize = defaults.MaxSize
+	}
+	if opts.MaxResumableSize <= 0 {
+		opts.MaxResumableSize = defaults.MaxResumableSize
+	}
+	if opts.TokenTTL <= 0 {
+		opts.TokenTTL = defaults.TokenTTL
+	}
+	if opts.ResumableTTL <= 0 {
+		opts.ResumableTTL = defaults.ResumableTTL
+	}
+	if opts.Scanner == nil {
+		opts.Scanner = defaults.Scanner
+	}
+	if opts.RestoreWindow <= 0 {
+		opts.RestoreWindow = defaults.RestoreWindow
+	}
+	if opts.OrphanGrace <= 0 {
+		opts.OrphanGrace = defaults.OrphanGrace
+	}
+	return &uploadServiceImpl{db: db, files: repository.NewFileRepository(db), storage: store, options: opts}
 }
 
 func NewNotificationService(db *sql.DB) NotificationService {
-	return &notificationServiceImpl{db: db}
+	return newNotificationService(db, defaultCursors)
+}
+
+func newNotificationService(db *sql.DB, cursors *repository.CursorCodec) NotificationService {
+	return &notificationServiceImpl{db: db, store: repository.NewStore(db), cursors: cursors}
 }
 
 func NewAdminService(db *sql.DB) AdminService {
-	return &adminServiceImpl{db: db}
+	return newAdminService(db, defaultCursors)
+}
+
+func newAdminService(db *sql.DB, cursors *repository.CursorCodec) AdminService {
+	return &adminServiceImpl{db: db, store: repository.NewStore(db), cursors: cursors}
 }
 
+// NewCacheService تخزين مؤقت في الذاكرة بالحدود الافتراضية
 func NewCacheService() CacheService {
-	return &cacheServiceImpl{
-		store: make(map[string]interface{}),
-		mu:    sync.RWMutex{},
+	return cache.NewMemory(cache.DefaultMaxEntries, 0)
+}
+
+// newCacheService إنشاء التخزين المؤقت حسب الإعدادات، مع الرجوع للذاكرة عند تعذر Redis
+func newCacheService(cfg *config.Config, logger *zap.Logger) CacheService {
+	c, err := cache.New(cfg)
+	if err != nil {
+		if logger != nil {
+			logger.Warn("Falling back to in-memory cache", zap.Error(err))
+		}
+		return NewCacheService()
 	}
+	return c
 }
 
 // NewHealthService إنشاء خدمة صحة جديدة
 func NewHealthService(db *sql.DB, cfg *config.Config, logger *zap.Logger) HealthService {
+	return NewHealthServiceWithCache(db, cfg, logger, nil)
+}
+
+// NewHealthServiceWithCache خدمة صحة تضمّن إحصائيات التخزين المؤقت في فحوصها
+func NewHealthServiceWithCache(db *sql.DB, cfg *config.Config, logger *zap.Logger, cacheService CacheService) HealthService {
 	return &healthServiceImpl{
 		db:        db,
 		config:    cfg,
 		logger:    logger,
+		cache:     cacheService,
 		startTime: time.Now(),
 	}
 }
 
-func NewServiceContainer(db *sql.DB, cfg *config.Config) *ServiceContainer {
+func NewServiceContainer(db *sql.DB, cfg *config.Config) (*ServiceContainer, error) {
+	upload, images, err := newFileServices(db, cfg, nil)
+	if err != nil {
+		return nil, err
+	}
+	cacheService := newCacheService(cfg, nil)
+	catalog, categories, catalogCache := newCatalogServices(db, cfg, cacheService, nil)
 	return &ServiceContainer{
 		Auth:         NewAuthService(db),
 		User:         NewUserService(db),
-		Service:      NewServiceService(db),
-		Category:     NewCategoryService(db),
+		Service:      catalog,
+		Category:     categories,
 		Order:        NewOrderService(db),
 		Payment:      NewPaymentService(db),
-		Upload:       NewUploadService(db),
+		Upload:       upload,
+		Image:        images,
 		Notification: NewNotificationService(db),
 		Admin:        NewAdminService(db),
-		Cache:        NewCacheService(),
-		Health:       NewHealthService(db, cfg, &zap.Logger{}),
+		Cache:        cacheService,
+		Health:       NewHealthServiceWithCache(db, cfg, &zap.Logger{}, cacheService),
+		CatalogCache: catalogCache,
+		AICost:       newAICostManager(db),
 		db:           db,
-	}
+	}, nil
 }
 
-func NewServiceContainerWithConfig(db *sql.DB, cfg *config.Config, logger *zap.Logger) *ServiceContainer {
+func NewServiceContainerWithConfig(db *sql.DB, cfg *config.Config, logger *zap.Logger) (*ServiceContainer, error) {
+	upload, images, err := newFileServices(db, cfg, logger)
+	if err != nil {
+		return nil, err
+	}
+	cacheService := newCacheService(cfg, logger)
+	catalog, categories, catalogCache := newCatalogServices(db, cfg, cacheService, logger)
+	cursors := newCursorCodec(cfg)
 	return &ServiceContainer{
 		Auth:         NewAuthService(db),
 		User:         NewUserService(db),
-		Service:      NewServiceService(db),
-		Category:     NewCategoryService(db),
-		Order:        NewOrderService(db),
-		Payment:      NewPaymentService(db),
-		Upload:       NewUploadService(db),
-		Notification: NewNotificationService(db),
-		Admin:        NewAdminService(db),
-		Cache:        NewCacheService(),
-		Health:       NewHealthService(db, cfg, logger),
+		Service:      catalog,
+		Category:     categories,
+		Order:        newOrderService(db, cursors),
+		Payment:      newPaymentService(db, cursors),
+		Upload:       upload,
+		Image:        images,
+		Notification: newNotificationService(db, cursors),
+		Admin:        newAdminService(db, cursors),
+		Cache:        cacheService,
+		Health:       NewHealthServiceWithCache(db, cfg, logger, cacheService),
+		CatalogCache: catalogCache,
+		AICost:       newAICostManager(db),
 		db:           db,
 		config:       cfg,
 		logger:       logger,
+	}, nil
+}
+
+// newAICostManager مدير تكاليف مشترك بين نسخ الخادم عبر قاعدة البيانات
+func newAICostManager(db *sql.DB) *ai.CostManager {
+	if db == nil {
+		return nil
 	}
+	return ai.NewCostManagerWithStore(repository.NewStore(db))
 }
 
-// ================================
-// دوال SQL للإنشاء
-// ================================
+// newFileServices إنشاء خدمتي الرفع والصور على نفس التخزين، مع إبطال المشتقات عند تغير الملف
+// فشل تهيئة التخزين المضبوط يوقف الإنشاء بدل الرجوع بصمت إلى التخزين المحلي
+func newFileServices(db *sql.DB, cfg *config.Config, logger *zap.Logger) (UploadService, ImageService, error) {
+	store, err := storage.New(cfg)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to initialize storage backend: %w", err)
+	}
+	images := NewImageService(db, store, imageOptionsFromConfig(cfg))
 
-func CreateTablesSQL() []string {
-	return []string{
-		// جدول المستخدمين
-		`CREATE TABLE IF NOT EXISTS users (
-			id TEXT PRIMARY KEY,
-			email TEXT UNIQUE NOT NULL,
-			username TEXT UNIQUE NOT NULL,
-			password_hash TEXT NOT NULL,
-			first_name TEXT NOT NULL,
-			last_name TEXT NOT NULL,
-			phone TEXT,
-			avatar TEXT,
-			role TEXT DEFAULT 'user',
-			status TEXT DEFAULT 'active',
-			email_verified BOOLEAN DEFAULT FALSE,
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-			last_login TIMESTAMP
-		)`,
-
-		// جدول الفئات
-		`CREATE TABLE IF NOT EXISTS categories (
-			id TEXT PRIMARY KEY,
-			name TEXT NOT NULL,
-			slug TEXT UNIQUE NOT NULL,
-			image TEXT,
-			description TEXT,
-			parent_id TEXT,
-			is_active BOOLEAN DEFAULT TRUE,
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-			FOREIGN KEY (parent_id) REFERENCES categories(id) ON DELETE SET NULL
-		)`,
-
-		// جدول الخدمات
-		`CREATE TABLE IF NOT EXISTS services (
-			id TEXT PRIMARY KEY,
-			title TEXT NOT NULL,
-			description TEXT NOT NULL,
-			price REAL NOT NULL,
-			duration INTEGER NOT NULL,
-			category_id TEXT NOT NULL,
-			provider_id TEXT NOT NULL,
-			images TEXT DEFAULT '[]',
-			tags TEXT DEFAULT '[]',
-			is_active BOOLEAN DEFAULT TRUE,
-			is_featured BOOLEAN DEFAULT FALSE,
-			rating REAL DEFAULT 0,
-			review_count INTEGER DEFAULT 0,
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-			FOREIGN KEY (category_id) REFERENCES categories(id) ON DELETE CASCADE,
-			FOREIGN KEY (provider_id) REFERENCES users(id) ON DELETE CASCADE
-		)`,
-
-		// جدول الطلبات
-		`CREATE TABLE IF NOT EXISTS orders (
-			id TEXT PRIMARY KEY,
-			user_id TEXT NOT NULL,
-			service_id TEXT NOT NULL,
-			status TEXT DEFAULT 'pending',
-			amount REAL NOT NULL,
-			notes TEXT,
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
-			FOREIGN KEY (service_id) REFERENCES services(id) ON DELETE CASCADE
-		)`,
-
-		// جدول المدفوعات
-		`CREATE TABLE IF NOT EXISTS payments (
-			id TEXT PRIMARY KEY,
-			order_id TEXT NOT NULL,
-			amount REAL NOT NULL,
-			currency TEXT DEFAULT 'USD',
-			status TEXT DEFAULT 'pending',
-			payment_method TEXT,
-			transaction_id TEXT UNIQUE,
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-			FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE CASCADE
-		)`,
-
-		// جدول الإشعارات
-		`CREATE TABLE IF NOT EXISTS notifications (
-			id TEXT PRIMARY KEY,
-			user_id TEXT NOT NULL,
-			title TEXT NOT NULL,
-			message TEXT NOT NULL,
-			type TEXT DEFAULT 'info',
-			is_read BOOLEAN DEFAULT FALSE,
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
-		)`,
-
-		// جدول الملفات
-		`CREATE TABLE IF NOT EXISTS files (
-			id TEXT PRIMARY KEY,
-			user_id TEXT NOT NULL,
-			name TEXT NOT NULL,
-			url TEXT NOT NULL,
-			size INTEGER,
-			type TEXT,
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
-		)`,
-
-		// جدول السجلات
-		`CREATE TABLE IF NOT EXISTS system_logs (
-			id TEXT PRIMARY KEY,
-			user_id TEXT,
-			level TEXT NOT NULL,
-			action TEXT NOT NULL,
-			resource TEXT,
-			details TEXT,
-			ip_address TEXT,
-			user_agent TEXT,
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-		)`,
-	}
-
-}
-
-// CreateHealthDefaultData إنشاء بيانات افتراضية لمراقبة الصحة
-func CreateHealthDefaultData() []string {
-	return []string{
-		// إدراج فحوصات مجدولة افتراضية
-		`INSERT OR IGNORE INTO scheduled_checks (id, check_name, check_type, schedule, is_active) VALUES
-			('check_db_001', 'Database Health Check', 'database', '*/5 * * * *', TRUE),
-			('check_api_001', 'API Endpoint Check', 'api', '*/10 * * * *', TRUE),
-			('check_cache_001', 'Cache Service Check', 'external_service', '*/15 * * * *', TRUE),
-			('check_storage_001', 'Storage Service Check', 'external_service', '*/30 * * * *', TRUE)`,
-		
-		// إدراج اعتمادية الخدمات
-		`INSERT OR IGNORE INTO service_dependencies (id, service_name, dependency_name, dependency_type, is_required) VALUES
-			('dep_001', 'nawthtech-api', 'SQLite Database', 'database', TRUE),
-			('dep_002', 'nawthtech-api', 'In-memory Cache', 'cache', FALSE),
-			('dep_003', 'nawthtech-api', 'Cloudinary Storage', 'storage', FALSE),
-			('dep_004', 'nawthtech-api', 'Payment Gateway', 'api', TRUE)`,
-		
-		// إدراج فحوصات الأمان الافتراضية
-		`INSERT OR IGNORE INTO security_checks (id, check_name, check_category, status) VALUES
-			('sec_001', 'JWT Token Validation', 'authentication', 'passed'),
-			('sec_002', 'Password Hashing', 'encryption', 'passed'),
-			('sec_003', 'CORS Configuration', 'network', 'passed'),
-			('sec_004', 'SQL Injection Protection', 'database', 'passed')`,
-		
-		// إدراج معلومات الإصدارات
-		`INSERT OR IGNORE INTO version_updates (id, component_name, current_version) VALUES
-			('ver_001', 'backend', '1.0.0'),
-			('ver_002', 'api', '1.0.0'),
-			('ver_003', 'database', 'sqlite-3.0')`,
-		
-		// إدراج سجلات وقت التشغيل الأولية
-		`INSERT OR IGNORE INTO uptime_records (id, service_name, start_time, status) VALUES
-			('uptime_001', 'nawthtech-api', CURRENT_TIMESTAMP, 'up')`,
-	}
-}
-
-// CreateHealthIndexes إنشاء فهارس لتحسين أداء استعلامات الصحة
-func CreateHealthIndexes() []string {
-	return []string{
-		// فهارس لجدول health_logs
-		`CREATE INDEX IF NOT EXISTS idx_health_logs_service ON health_logs(service_name)`,
-		`CREATE INDEX IF NOT EXISTS idx_health_logs_status ON health_logs(status)`,
-		`CREATE INDEX IF NOT EXISTS idx_health_logs_created ON health_logs(created_at)`,
-		
-		// فهارس لجدول performance_metrics
-		`CREATE INDEX IF NOT EXISTS idx_perf_metrics_name_date ON performance_metrics(metric_name, created_at)`,
-		
-		// فهارس لجدول system_events
-		`CREATE INDEX IF NOT EXISTS idx_system_events_date_severity ON system_events(created_at, severity)`,
-		
-		// فهارس لجدول api_statistics
-		`CREATE INDEX IF NOT EXISTS idx_api_stats_endpoint_date ON api_statistics(endpoint, created_at)`,
-		`CREATE INDEX IF NOT EXISTS idx_api_stats_user_date ON api_statistics(user_id, created_at)`,
-		
-		// فهارس لجدول system_alerts
-		`CREATE INDEX IF NOT EXISTS idx_alerts_type_date ON system_alerts(alert_type, created_at)`,
-		
-		// فهارس لجدول realtime_metrics
-		`CREATE INDEX IF NOT EXISTS idx_realtime_type_date ON realtime_metrics(metric_type, created_at)`,
-		
-		// فهارس لجدول application_errors
-		`CREATE INDEX IF NOT EXISTS idx_errors_type_date ON application_errors(error_type, created_at)`,
-		
-		// فهارس لجدول business_metrics
-		`CREATE INDEX IF NOT EXISTS idx_business_name_date ON business_metrics(metric_name, metric_date)`,
-	}
-}
-
-// GetHealthTablesSQL الحصول على SQL لإنشاء جداول الصحة فقط
-func GetHealthTablesSQL() []string {
-	return []string{
-		// جميع جداول الصحة من CreateTablesSQL()
-		`CREATE TABLE IF NOT EXISTS health_logs (...)`,
-		`CREATE TABLE IF NOT EXISTS performance_metrics (...)`,
-		// ... بقية الجداول
+	opts := uploadOptionsFromConfig(cfg)
+	opts.Scanner = newUploadScanner(cfg, logger)
+	opts.OnFileChange = func(ctx context.Context, fileID string) {
+		if err := images.InvalidateImage(ctx, fileID); err != nil && logger != nil {
+			logger.Warn("Failed to invalidate image derivatives", zap.String("file_id", fileID), zap.Error(err))
+		}
 	}
+
+	return NewUploadServiceWithOptions(db, store, opts), images, nil
 }
 
 // ================================
@@ -1201,25 +869,9 @@ func generateID(prefix string) string {
 	return fmt.Sprintf("%s_%d", prefix, time.Now().UnixNano())
 }
 
-func buildSearchQuery(baseQuery string, search string, args []interface{}) (string, []interface{}) {
-	if search == "" {
-		return baseQuery, args
-	}
-	
-	search = strings.TrimSpace(search)
-	searchTerms := strings.Fields(search)
-	
-	var conditions []string
-	for _, term := range searchTerms {
-		conditions = append(conditions, "(title LIKE ? OR description LIKE ? OR tags LIKE ?)")
-		args = append(args, "%"+term+"%", "%"+term+"%", "%"+term+"%")
-	}
-	
-	whereClause := strings.Join(conditions, " AND ")
-	if strings.Contains(baseQuery, "WHERE") {
-		return baseQuery + " AND (" + whereClause + ")", args
-	}
-	return baseQuery + " WHERE " + whereClause, args
+// fileStorageKey مفتاح الملف في التخزين، مشتق من المعرف والاسم
+func fileStorageKey(fileID, fileName string) string {
+	return "files/" + fileID + "/" + storage.SanitizeFileName(fileName)
 }
 
 func validatePaginationParams(page, limit int) (int, int) {
@@ -1245,32 +897,24 @@ func calculateOffset(page, limit int) int {
 
 // AuthService Implementation
 func (s *authServiceImpl) Register(ctx context.Context, req AuthRegisterRequest) (*AuthResponse, error) {
-	userID := generateID("user")
-	
-	// تشفير كلمة المرور (يجب استخدام bcrypt في الواقع)
-	passwordHash := fmt.Sprintf("hash_%s", req.Password)
-	
-	_, err := s.db.ExecContext(ctx,
-		`INSERT INTO users (id, email, username, password_hash, first_name, last_name, phone, role, status, created_at, updated_at)
-		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
-		userID, req.Email, req.Username, passwordHash, req.FirstName, req.LastName, req.Phone,
-		"user", "active", time.Now(), time.Now(),
-	)
-	if err != nil {
-		return nil, fmt.Errorf("failed to register user: %w", err)
-	}
-	
+	now := time.Now()
 	user := &models.User{
-		ID:        userID,
-		Email:     req.Email,
-		Username:  req.Username,
-		FirstName: req.FirstName,
-		LastName:  req.LastName,
-		Phone:     req.Phone,
-		Role:      "user",
-		Status:    "active",
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+		ID:       generateID("user"),
+		Email:    req.Email,
+		Username: req.Username,
+		// تشفير كلمة المرور (يجب استخدام bcrypt في الواقع)
+		PasswordHash: fmt.Sprintf("hash_%s", req.Password),
+		FirstName:    req.FirstName,
+		LastName:     req.LastName,
+		Phone:        req.Phone,
+		Role:         "user",
+		Status:       "active",
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+	
+	if err := s.store.Users.Create(ctx, user); err != nil {
+		return nil, fmt.Errorf("failed to register user: %w", err)
 	}
 	
 	return &AuthResponse{
@@ -1282,34 +926,22 @@ func (s *authServiceImpl) Register(ctx context.Context, req AuthRegisterRequest)
 }
 
 func (s *authServiceImpl) Login(ctx context.Context, req AuthLoginRequest) (*AuthResponse, error) {
-	var user models.User
-	row := s.db.QueryRowContext(ctx,
-		`SELECT id, email, username, first_name, last_name, phone, avatar, role, status, created_at, updated_at
-		 FROM users WHERE email = ? AND status = 'active'`,
-		req.Email)
-	
-	err := row.Scan(
-		&user.ID, &user.Email, &user.Username, &user.FirstName, &user.LastName,
-		&user.Phone, &user.Avatar, &user.Role, &user.Status, &user.CreatedAt, &user.UpdatedAt,
-	)
+	user, err := s.store.Users.GetActiveByEmail(ctx, req.Email)
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if errors.Is(err, repository.ErrNotFound) {
 			return nil, errors.New("invalid credentials")
 		}
 		return nil, fmt.Errorf("failed to login: %w", err)
 	}
 	
 	// تحديث آخر تسجيل دخول
-	_, err = s.db.ExecContext(ctx,
-		"UPDATE users SET last_login = ? WHERE id = ?",
-		time.Now(), user.ID,
-	)
-	if err != nil {
-		return nil, fmt.Errorf("failed to update last login: %w", err)
+	user.LastLogin = time.Now()
+	if err := s.store.Users.TouchLogin(ctx, user.ID, user.LastLogin); err != nil {
+		return nil, err
 	}
 	
 	return &AuthResponse{
-		User:         &user,
+		User:         user,
 		AccessToken:  generateID("access"),
 		RefreshToken: generateID("refresh"),
 		ExpiresAt:    time.Now().Add(24 * time.Hour),
@@ -1343,34 +975,34 @@ func (s *authServiceImpl) ChangePassword(ctx context.Context, userID string, req
 
 // UserService Implementation
 func (s *userServiceImpl) GetProfile(ctx context.Context, userID string) (*models.User, error) {
-	var user models.User
-	row := s.db.QueryRowContext(ctx,
-		`SELECT id, email, username, first_name, last_name, phone, avatar, role, status, email_verified, created_at, updated_at, last_login
-		 FROM users WHERE id = ?`,
-		userID)
-	
-	err := row.Scan(
-		&user.ID, &user.Email, &user.Username, &user.FirstName, &user.LastName,
-		&user.Phone, &user.Avatar, &user.Role, &user.Status, &user.EmailVerified,
-		&user.CreatedAt, &user.UpdatedAt, &user.LastLogin,
-	)
+	user, err := s.store.Users.Get(ctx, userID)
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if errors.Is(err, repository.ErrNotFound) {
 			return nil, errors.New("user not found")
 		}
 		return nil, fmt.Errorf("failed to get profile: %w", err)
 	}
 	
-	return &user, nil
+	return user, nil
 }
 
-func (s *userServiceImpl) UpdateProfile(ctx context.Context, userID string, req UserUpdateRequest) (*models.User, error) {
-	_, err := s.db.ExecContext(ctx,
-		`UPDATE users SET first_name = ?, last_name = ?, phone = ?, avatar = ?, updated_at = ?
-		 WHERE id = ?`,
-		req.FirstName, req.LastName, req.Phone, req.Avatar, time.Now(), userID,
-	)
+// GetUserTier طبقة المستخدم من سجله (free عند غيابها)
+func (s *userServiceImpl) GetUserTier(ctx context.Context, userID string) (string, error) {
+	tier, err := s.store.Users.GetTier(ctx, userID)
 	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return "", ErrUserNotFound
+		}
+		return "", err
+	}
+	if tier == "" {
+		return DefaultStorageTier, nil
+	}
+	return tier, nil
+}
+
+func (s *userServiceImpl) UpdateProfile(ctx context.Context, userID string, req UserUpdateRequest) (*models.User, error) {
+	if err := s.store.Users.UpdateProfile(ctx, userID, req.FirstName, req.LastName, req.Phone, req.Avatar); err != nil {
 		return nil, fmt.Errorf("failed to update profile: %w", err)
 	}
 	
@@ -1378,126 +1010,60 @@ func (s *userServiceImpl) UpdateProfile(ctx context.Context, userID string, req
 }
 
 func (s *userServiceImpl) UpdateAvatar(ctx context.Context, userID string, avatarURL string) error {
-	_, err := s.db.ExecContext(ctx,
-		"UPDATE users SET avatar = ?, updated_at = ? WHERE id = ?",
-		avatarURL, time.Now(), userID,
-	)
-	return err
+	return s.store.Users.UpdateAvatar(ctx, userID, avatarURL)
 }
 
 func (s *userServiceImpl) DeleteAccount(ctx context.Context, userID string) error {
-	_, err := s.db.ExecContext(ctx,
-		"UPDATE users SET status = 'deleted', updated_at = ? WHERE id = ?",
-		time.Now(), userID,
-	)
-	return err
+	return s.store.Users.SetStatus(ctx, userID, "deleted")
 }
 
 func (s *userServiceImpl) SearchUsers(ctx context.Context, query string, params UserQueryParams) ([]models.User, error) {
 	page, limit := validatePaginationParams(params.Page, params.Limit)
-	offset := calculateOffset(page, limit)
-	
-	sqlQuery := `SELECT id, email, username, first_name, last_name, phone, avatar, role, status, created_at
-				 FROM users WHERE status != 'deleted'`
-	args := []interface{}{}
 	
-	if query != "" {
-		sqlQuery += " AND (email LIKE ? OR username LIKE ? OR first_name LIKE ? OR last_name LIKE ?)"
-		args = append(args, "%"+query+"%", "%"+query+"%", "%"+query+"%", "%"+query+"%")
-	}
+	q := s.store.Users.Query().
+		NotEq("status", "deleted").
+		Search(query, "email", "username", "first_name", "last_name").
+		OrderBy("created_at", true).
+		Page(page, limit)
 	
 	if params.Role != "" {
-		sqlQuery += " AND role = ?"
-		args = append(args, params.Role)
+		q.Eq("role", params.Role)
 	}
 	
 	if params.Email != "" {
-		sqlQuery += " AND email = ?"
-		args = append(args, params.Email)
+		q.Eq("email", params.Email)
 	}
 	
-	sqlQuery += " LIMIT ? OFFSET ?"
-	args = append(args, limit, offset)
-	
-	rows, err := s.db.QueryContext(ctx, sqlQuery, args...)
+	users, err := s.store.Users.List(ctx, q)
 	if err != nil {
 		return nil, fmt.Errorf("failed to search users: %w", err)
 	}
-	defer rows.Close()
-	
-	var users []models.User
-	for rows.Next() {
-		var user models.User
-		err := rows.Scan(
-			&user.ID, &user.Email, &user.Username, &user.FirstName, &user.LastName,
-			&user.Phone, &user.Avatar, &user.Role, &user.Status, &user.CreatedAt,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan user: %w", err)
-		}
-		users = append(users, user)
-	}
 	
 	return users, nil
 }
 
 func (s *userServiceImpl) GetUserStats(ctx context.Context, userID string) (*UserStats, error) {
-	// الحصول على عدد الطلبات
-	var totalOrders int
-	err := s.db.QueryRowContext(ctx,
-		"SELECT COUNT(*) FROM orders WHERE user_id = ?",
-		userID,
-	).Scan(&totalOrders)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get total orders: %w", err)
-	}
-	
-	// الحصول على المبلغ الإجمالي
-	var totalSpent float64
-	err = s.db.QueryRowContext(ctx,
-		"SELECT COALESCE(SUM(amount), 0) FROM orders WHERE user_id = ? AND status = 'completed'",
-		userID,
-	).Scan(&totalSpent)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get total spent: %w", err)
-	}
-	
-	// الحصول على تاريخ الإنشاء
-	var activeSince string
-	err = s.db.QueryRowContext(ctx,
-		"SELECT DATE(created_at) FROM users WHERE id = ?",
-		userID,
-	).Scan(&activeSince)
-	if err != nil {
-		activeSince = time.Now().Format("2006-01-02")
-	}
-	
-	// الحصول على عدد الخدمات
-	var servicesCount int
-	err = s.db.QueryRowContext(ctx,
-		"SELECT COUNT(*) FROM services WHERE provider_id = ?",
-		userID,
-	).Scan(&servicesCount)
+	stats, err := s.store.Users.Stats(ctx, userID)
 	if err != nil {
-		servicesCount = 0
+		if errors.Is(err, repository.ErrNotFound) {
+			return &UserStats{ActiveSince: time.Now().Format("2006-01-02")}, nil
+		}
+		return nil, err
 	}
 	
 	return &UserStats{
-		TotalOrders:   totalOrders,
-		TotalSpent:    totalSpent,
-		ActiveSince:   activeSince,
-		ServicesCount: servicesCount,
+		TotalOrders:   stats.TotalOrders,
+		TotalSpent:    stats.TotalSpent,
+		ActiveSince:   stats.CreatedAt.Format("2006-01-02"),
+		ServicesCount: stats.ServicesCount,
 	}, nil
 }
 
 // ServiceService Implementation
 func (s *serviceServiceImpl) CreateService(ctx context.Context, req ServiceCreateRequest) (*models.Service, error) {
-	serviceID := generateID("service")
-	imagesJSON := serializeStrings(req.Images)
-	tagsJSON := serializeStrings(req.Tags)
-	
+	now := time.Now()
 	service := &models.Service{
-		ID:          serviceID,
+		ID:          generateID("service"),
 		Title:       req.Title,
 		Description: req.Description,
 		Price:       req.Price,
@@ -1510,17 +1076,11 @@ func (s *serviceServiceImpl) CreateService(ctx context.Context, req ServiceCreat
 		IsFeatured:  false,
 		Rating:      0,
 		ReviewCount: 0,
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
+		CreatedAt:   now,
+		UpdatedAt:   now,
 	}
 	
-	_, err := s.db.ExecContext(ctx,
-		`INSERT INTO services (id, title, description, price, duration, category_id, provider_id, images, tags, is_active, is_featured, created_at, updated_at)
-		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
-		 serviceID, req.Title, req.Description, req.Price, req.Duration, req.CategoryID, req.ProviderID,
-		 imagesJSON, tagsJSON, true, false, time.Now(), time.Now(),
-	)
-	if err != nil {
+	if err := s.store.Services.Create(ctx, service); err != nil {
 		return nil, fmt.Errorf("failed to create service: %w", err)
 	}
 	
@@ -1528,125 +1088,88 @@ func (s *serviceServiceImpl) CreateService(ctx context.Context, req ServiceCreat
 }
 
 func (s *serviceServiceImpl) GetServiceByID(ctx context.Context, serviceID string) (*models.Service, error) {
-	var service models.Service
-	var imagesJSON, tagsJSON string
-	
-	row := s.db.QueryRowContext(ctx,
-		`SELECT id, title, description, price, duration, category_id, provider_id, images, tags, is_active, is_featured, rating, review_count, created_at, updated_at
-		 FROM services WHERE id = ?`,
-		serviceID)
-	
-	err := row.Scan(
-		&service.ID, &service.Title, &service.Description, &service.Price, &service.Duration,
-		&service.CategoryID, &service.ProviderID, &imagesJSON, &tagsJSON,
-		&service.IsActive, &service.IsFeatured, &service.Rating, &service.ReviewCount,
-		&service.CreatedAt, &service.UpdatedAt,
-	)
+	service, err := s.store.Services.Get(ctx, serviceID)
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if errors.Is(err, repository.ErrNotFound) {
 			return nil, errors.New("service not found")
 		}
 		return nil, fmt.Errorf("failed to get service: %w", err)
 	}
 	
-	service.Images, _ = deserializeStrings(imagesJSON)
-	service.Tags, _ = deserializeStrings(tagsJSON)
-	
-	return &service, nil
+	return service, nil
 }
 
 func (s *serviceServiceImpl) UpdateService(ctx context.Context, serviceID string, req ServiceUpdateRequest) (*models.Service, error) {
-	imagesJSON := serializeStrings(req.Images)
-	tagsJSON := serializeStrings(req.Tags)
-	
-	_, err := s.db.ExecContext(ctx,
-		`UPDATE services SET title = ?, description = ?, price = ?, duration = ?, category_id = ?, images = ?, tags = ?, is_active = ?, is_featured = ?, updated_at = ?
-		 WHERE id = ?`,
-		req.Title, req.Description, req.Price, req.Duration, req.CategoryID, imagesJSON, tagsJSON,
-		req.IsActive, req.IsFeatured, time.Now(), serviceID,
-	)
+	err := s.store.Services.Update(ctx, &models.Service{
+		ID:          serviceID,
+		Title:       req.Title,
+		Description: req.Description,
+		Price:       req.Price,
+		Duration:    req.Duration,
+		CategoryID:  req.CategoryID,
+		Images:      req.Images,
+		Tags:        req.Tags,
+		IsActive:    req.IsActive,
+		IsFeatured:  req.IsFeatured,
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to update service: %w", err)
+		return nil, err
 	}
 	
 	return s.GetServiceByID(ctx, serviceID)
 }
 
 func (s *serviceServiceImpl) DeleteService(ctx context.Context, serviceID string) error {
-	_, err := s.db.ExecContext(ctx,
-		"DELETE FROM services WHERE id = ?",
-		serviceID,
-	)
-	return err
+	return s.store.Services.Delete(ctx, serviceID)
 }
 
 func (s *serviceServiceImpl) GetServices(ctx context.Context, params ServiceQueryParams) ([]models.Service, error) {
-	page, limit := validatePaginationParams(params.Page, params.Limit)
-	offset := calculateOffset(page, limit)
-	
-	sqlQuery := `SELECT id, title, description, price, duration, category_id, provider_id, images, tags, is_active, is_featured, rating, review_count, created_at
-				 FROM services WHERE 1=1`
-	args := []interface{}{}
+	page, err := s.ListServices(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	return page.Items, nil
+}
+
+// ListServices صفحة خدمات مع مؤشر الصفحة التالية والإجمالي عند طلبه
+func (s *serviceServiceImpl) ListServices(ctx context.Context, params ServiceQueryParams) (*Page[models.Service], error) {
+	q := s.store.Services.Query().
+		Search(params.Search, "title", "description", "tags").
+		OrderBy("created_at", true)
 	
 	if params.CategoryID != "" {
-		sqlQuery += " AND category_id = ?"
-		args = append(args, params.CategoryID)
+		q.Eq("category_id", params.CategoryID)
 	}
 	
 	if params.ProviderID != "" {
-		sqlQuery += " AND provider_id = ?"
-		args = append(args, params.ProviderID)
+		q.Eq("provider_id", params.ProviderID)
 	}
 	
 	if params.IsActive {
-		sqlQuery += " AND is_active = TRUE"
+		q.Eq("is_active", true)
 	}
 	
 	if params.IsFeatured {
-		sqlQuery += " AND is_featured = TRUE"
+		q.Eq("is_featured", true)
 	}
 	
 	if params.MinPrice > 0 {
-		sqlQuery += " AND price >= ?"
-		args = append(args, params.MinPrice)
+		q.Gte("price", params.MinPrice)
 	}
 	
 	if params.MaxPrice > 0 {
-		sqlQuery += " AND price <= ?"
-		args = append(args, params.MaxPrice)
+		q.Lte("price", params.MaxPrice)
 	}
 	
-	sqlQuery, args = buildSearchQuery(sqlQuery, params.Search, args)
-	sqlQuery += " ORDER BY created_at DESC LIMIT ? OFFSET ?"
-	args = append(args, limit, offset)
-	
-	rows, err := s.db.QueryContext(ctx, sqlQuery, args...)
+	page, err := paginate(ctx, s.cursors, cursorScopeServices, q, params.Page, params.Limit, params.Cursor, params.WithTotal, s.store.Services.Paginate)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get services: %w", err)
-	}
-	defer rows.Close()
-	
-	var services []models.Service
-	for rows.Next() {
-		var service models.Service
-		var imagesJSON, tagsJSON string
-		
-		err := rows.Scan(
-			&service.ID, &service.Title, &service.Description, &service.Price, &service.Duration,
-			&service.CategoryID, &service.ProviderID, &imagesJSON, &tagsJSON,
-			&service.IsActive, &service.IsFeatured, &service.Rating, &service.ReviewCount,
-			&service.CreatedAt,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan service: %w", err)
+		if errors.Is(err, ErrInvalidCursor) {
+			return nil, err
 		}
-		
-		service.Images, _ = deserializeStrings(imagesJSON)
-		service.Tags, _ = deserializeStrings(tagsJSON)
-		services = append(services, service)
+		return nil, fmt.Errorf("failed to get services: %w", err)
 	}
 	
-	return services, nil
+	return page, nil
 }
 
 func (s *serviceServiceImpl) SearchServices(ctx context.Context, query string, params ServiceQueryParams) ([]models.Service, error) {
@@ -1858,25 +1381,20 @@ func (s *categoryServiceImpl) addChildrenToNode(node *CategoryNode, childrenMap
 
 // OrderService Implementation
 func (s *orderServiceImpl) CreateOrder(ctx context.Context, req OrderCreateRequest) (*models.Order, error) {
-	orderID := generateID("order")
-	
+	now := time.Now()
 	order := &models.Order{
-		ID:        orderID,
-		UserID:    req.UserID,
-		ServiceID: req.ServiceID,
-		Status:    "pending",
-		Amount:    req.Amount,
-		Notes:     req.Notes,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
-	}
-	
-	_, err := s.db.ExecContext(ctx,
-		`INSERT INTO orders (id, user_id, service_id, status, amount, notes, created_at, updated_at)
-		 VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
-		orderID, req.UserID, req.ServiceID, "pending", req.Amount, req.Notes, time.Now(), time.Now(),
-	)
-	if err != nil {
+		ID:          generateID("order"),
+		UserID:      req.UserID,
+		ServiceID:   req.ServiceID,
+		Status:      "pending",
+		Amount:      req.Amount,
+		Notes:       req.Notes,
+		Attachments: req.Attachments,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	
+	if err := s.store.Orders.Create(ctx, order); err != nil {
 		return nil, fmt.Errorf("failed to create order: %w", err)
 	}
 	
@@ -1884,71 +1402,49 @@ func (s *orderServiceImpl) CreateOrder(ctx context.Context, req OrderCreateReque
 }
 
 func (s *orderServiceImpl) GetOrderByID(ctx context.Context, orderID string) (*models.Order, error) {
-	var order models.Order
-	row := s.db.QueryRowContext(ctx,
-		`SELECT id, user_id, service_id, status, amount, notes, created_at, updated_at
-		 FROM orders WHERE id = ?`,
-		orderID)
-	
-	err := row.Scan(
-		&order.ID, &order.UserID, &order.ServiceID, &order.Status, &order.Amount,
-		&order.Notes, &order.CreatedAt, &order.UpdatedAt,
-	)
+	order, err := s.store.Orders.Get(ctx, orderID)
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if errors.Is(err, repository.ErrNotFound) {
 			return nil, errors.New("order not found")
 		}
 		return nil, fmt.Errorf("failed to get order: %w", err)
 	}
 	
-	return &order, nil
+	return order, nil
 }
 
 func (s *orderServiceImpl) GetUserOrders(ctx context.Context, userID string, params OrderQueryParams) ([]models.Order, error) {
-	page, limit := validatePaginationParams(params.Page, params.Limit)
-	offset := calculateOffset(page, limit)
-	
-	sqlQuery := `SELECT id, user_id, service_id, status, amount, notes, created_at, updated_at
-				 FROM orders WHERE user_id = ?`
-	args := []interface{}{userID}
+	page, err := s.ListUserOrders(ctx, userID, params)
+	if err != nil {
+		return nil, err
+	}
+	return page.Items, nil
+}
+
+// ListUserOrders صفحة طلبات المستخدم، الأحدث أولاً
+func (s *orderServiceImpl) ListUserOrders(ctx context.Context, userID string, params OrderQueryParams) (*Page[models.Order], error) {
+	q := s.store.Orders.Query().
+		Eq("user_id", userID).
+		OrderBy("created_at", true)
 	
 	if params.Status != "" {
-		sqlQuery += " AND status = ?"
-		args = append(args, params.Status)
+		q.Eq("status", params.Status)
 	}
 	
-	sqlQuery += " ORDER BY created_at DESC LIMIT ? OFFSET ?"
-	args = append(args, limit, offset)
-	
-	rows, err := s.db.QueryContext(ctx, sqlQuery, args...)
+	page, err := paginate(ctx, s.cursors, cursorScopeOrders, q, params.Page, params.Limit, params.Cursor, params.WithTotal, s.store.Orders.Paginate)
 	if err != nil {
+		if errors.Is(err, ErrInvalidCursor) {
+			return nil, err
+		}
 		return nil, fmt.Errorf("failed to get user orders: %w", err)
 	}
-	defer rows.Close()
 	
-	var orders []models.Order
-	for rows.Next() {
-		var order models.Order
-		err := rows.Scan(
-			&order.ID, &order.UserID, &order.ServiceID, &order.Status, &order.Amount,
-			&order.Notes, &order.CreatedAt, &order.UpdatedAt,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan order: %w", err)
-		}
-		orders = append(orders, order)
-	}
-	
-	return orders, nil
+	return page, nil
 }
 
 func (s *orderServiceImpl) UpdateOrderStatus(ctx context.Context, orderID string, status string, notes string) (*models.Order, error) {
-	_, err := s.db.ExecContext(ctx,
-		`UPDATE orders SET status = ?, notes = COALESCE(?, notes), updated_at = ? WHERE id = ?`,
-		status, notes, time.Now(), orderID,
-	)
-	if err != nil {
-		return nil, fmt.Errorf("failed to update order status: %w", err)
+	if err := s.store.Orders.UpdateStatus(ctx, orderID, status, notes); err != nil {
+		return nil, err
 	}
 	
 	return s.GetOrderByID(ctx, orderID)
@@ -1959,68 +1455,36 @@ func (s *orderServiceImpl) CancelOrder(ctx context.Context, orderID string, reas
 }
 
 func (s *orderServiceImpl) GetOrderStats(ctx context.Context, timeframe string) (*OrderStats, error) {
-	// حساب الإحصائيات بناءً على timeframe
-	var whereClause string
+	// حساب بداية الفترة بناءً على timeframe (الوقت الصفري = كل الطلبات)
+	var since time.Time
+	now := time.Now()
 	switch timeframe {
 	case "today":
-		whereClause = "DATE(created_at) = DATE('now')"
+		since = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
 	case "week":
-		whereClause = "created_at >= DATE('now', '-7 days')"
+		since = now.AddDate(0, 0, -7)
 	case "month":
-		whereClause = "created_at >= DATE('now', '-1 month')"
+		since = now.AddDate(0, -1, 0)
 	case "year":
-		whereClause = "created_at >= DATE('now', '-1 year')"
-	default:
-		whereClause = "1=1"
-	}
-	
-	stats := &OrderStats{}
-	
-	// إجمالي الطلبات
-	err := s.db.QueryRowContext(ctx,
-		fmt.Sprintf("SELECT COUNT(*) FROM orders WHERE %s", whereClause),
-	).Scan(&stats.TotalOrders)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get total orders: %w", err)
+		since = now.AddDate(-1, 0, 0)
 	}
 	
-	// الطلبات المعلقة
-	err = s.db.QueryRowContext(ctx,
-		fmt.Sprintf("SELECT COUNT(*) FROM orders WHERE status = 'pending' AND %s", whereClause),
-	).Scan(&stats.PendingOrders)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get pending orders: %w", err)
-	}
-	
-	// الطلبات المكتملة
-	err = s.db.QueryRowContext(ctx,
-		fmt.Sprintf("SELECT COUNT(*) FROM orders WHERE status = 'completed' AND %s", whereClause),
-	).Scan(&stats.Completed)
+	counts, err := s.store.Orders.Stats(ctx, since)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get completed orders: %w", err)
-	}
-	
-	// الطلبات الملغاة
-	err = s.db.QueryRowContext(ctx,
-		fmt.Sprintf("SELECT COUNT(*) FROM orders WHERE status = 'cancelled' AND %s", whereClause),
-	).Scan(&stats.Cancelled)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get cancelled orders: %w", err)
+		return nil, err
 	}
 	
-	// إجمالي الإيرادات
-	err = s.db.QueryRowContext(ctx,
-		fmt.Sprintf("SELECT COALESCE(SUM(amount), 0) FROM orders WHERE status = 'completed' AND %s", whereClause),
-	).Scan(&stats.TotalRevenue)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get total revenue: %w", err)
+	stats := &OrderStats{
+		TotalOrders:   counts.Total,
+		PendingOrders: counts.Pending,
+		Completed:     counts.Completed,
+		Cancelled:     counts.Cancelled,
+		TotalRevenue:  counts.TotalRevenue,
 	}
 	
 	// متوسط قيمة الطلب
 	if stats.Completed > 0 {
 		stats.AvgOrderValue = stats.TotalRevenue / float64(stats.Completed)
-	} else {
-		stats.AvgOrderValue = 0
 	}
 	
 	return stats, nil
@@ -2044,90 +1508,73 @@ func (s *paymentServiceImpl) CreatePaymentIntent(ctx context.Context, req Paymen
      return paymentIntent, nil
 }
 func (s *paymentServiceImpl) ConfirmPayment(ctx context.Context, paymentID string, confirmationData map[string]interface{}) (*PaymentResult, error) {
-	_, err := s.db.ExecContext(ctx,
-		"UPDATE payments SET status = 'completed', updated_at = ? WHERE id = ?",
-		time.Now(), paymentID,
-	)
-	if err != nil {
-		return nil, fmt.Errorf("failed to confirm payment: %w", err)
-	}
-	
-	// الحصول على تفاصيل الدفع
-	var orderID, currency string
-	var amount float64
-	err = s.db.QueryRowContext(ctx,
-		"SELECT order_id, amount, currency FROM payments WHERE id = ?",
-		paymentID,
-	).Scan(&orderID, &amount, &currency)
+	// قراءة الدفعة ثم تحديثها في وحدة عمل واحدة
+	var payment *models.Payment
+	err := s.store.Transaction(ctx, func(tx *repository.Store) error {
+		var err error
+		payment, err = tx.Payments.Get(ctx, paymentID)
+		if err != nil {
+			return fmt.Errorf("failed to get payment details: %w", err)
+		}
+		if err := tx.Payments.UpdateStatus(ctx, paymentID, "completed"); err != nil {
+			return fmt.Errorf("failed to confirm payment: %w", err)
+		}
+		return nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to get payment details: %w", err)
+		return nil, err
 	}
 	
 	return &PaymentResult{
 		Success:   true,
 		Message:   "Payment confirmed successfully",
 		PaymentID: paymentID,
-		OrderID:   orderID,
-		Amount:    amount,
-		Currency:  currency,
+		OrderID:   payment.OrderID,
+		Amount:    payment.Amount,
+		Currency:  payment.Currency,
 		Status:    "completed",
 		Timestamp: time.Now(),
 	}, nil
 }
 
 func (s *paymentServiceImpl) GetPaymentHistory(ctx context.Context, userID string, params PaymentQueryParams) ([]models.Payment, error) {
-	page, limit := validatePaginationParams(params.Page, params.Limit)
-	offset := calculateOffset(page, limit)
-	
-	sqlQuery := `SELECT p.id, p.order_id, p.amount, p.currency, p.status, p.payment_method, p.transaction_id, p.created_at, p.updated_at
-				 FROM payments p
-				 INNER JOIN orders o ON p.order_id = o.id
-				 WHERE o.user_id = ?`
-	args := []interface{}{userID}
+	page, err := s.ListPaymentHistory(ctx, userID, params)
+	if err != nil {
+		return nil, err
+	}
+	return page.Items, nil
+}
+
+// ListPaymentHistory صفحة مدفوعات طلبات المستخدم، الأحدث أولاً
+func (s *paymentServiceImpl) ListPaymentHistory(ctx context.Context, userID string, params PaymentQueryParams) (*Page[models.Payment], error) {
+	q := s.store.Payments.ForUser(userID).
+		OrderBy("created_at", true)
 	
 	if params.Status != "" {
-		sqlQuery += " AND p.status = ?"
-		args = append(args, params.Status)
+		q.Eq("status", params.Status)
 	}
 	
 	if params.OrderID != "" {
-		sqlQuery += " AND p.order_id = ?"
-		args = append(args, params.OrderID)
+		q.Eq("order_id", params.OrderID)
 	}
 	
 	if !params.FromDate.IsZero() {
-		sqlQuery += " AND p.created_at >= ?"
-		args = append(args, params.FromDate)
+		q.Gte("created_at", params.FromDate)
 	}
 	
 	if !params.ToDate.IsZero() {
-		sqlQuery += " AND p.created_at <= ?"
-		args = append(args, params.ToDate)
+		q.Lte("created_at", params.ToDate)
 	}
 	
-	sqlQuery += " ORDER BY p.created_at DESC LIMIT ? OFFSET ?"
-	args = append(args, limit, offset)
-	
-	rows, err := s.db.QueryContext(ctx, sqlQuery, args...)
+	page, err := paginate(ctx, s.cursors, cursorScopePayments, q, params.Page, params.Limit, params.Cursor, params.WithTotal, s.store.Payments.Paginate)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get payment history: %w", err)
-	}
-	defer rows.Close()
-	
-	var payments []models.Payment
-	for rows.Next() {
-		var payment models.Payment
-		err := rows.Scan(
-			&payment.ID, &payment.OrderID, &payment.Amount, &payment.Currency, &payment.Status,
-			&payment.PaymentMethod, &payment.TransactionID, &payment.CreatedAt, &payment.UpdatedAt,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan payment: %w", err)
+		if errors.Is(err, ErrInvalidCursor) {
+			return nil, err
 		}
-		payments = append(payments, payment)
+		return nil, fmt.Errorf("failed to get payment history: %w", err)
 	}
 	
-	return payments, nil
+	return page, nil
 }
 
 func (s *paymentServiceImpl) ValidatePayment(ctx context.Context, paymentData map[string]interface{}) (*PaymentValidation, error) {
@@ -2160,96 +1607,177 @@ func (s *paymentServiceImpl) ValidatePayment(ctx context.Context, paymentData ma
 
 // UploadService Implementation
 func (s *uploadServiceImpl) UploadFile(ctx context.Context, req UploadRequest, fileData []byte) (*UploadResult, error) {
+	if s.storage == nil {
+		return nil, ErrStorageUnhealthy
+	}
+	if err := s.checkUploadLimits(req.FileType, int64(len(fileData))); err != nil {
+		return nil, err
+	}
+	if err := s.checkQuota(ctx, req.UserID, int64(len(fileData))); err != nil {
+		return nil, err
+	}
+
 	fileID := generateID("file")
-	
-	// هنا يمكنك رفع الملف إلى S3 أو Cloudflare R2 أو تخزين محلي
-	fileURL := fmt.Sprintf("https://storage.nawthtech.com/files/%s/%s", fileID, req.FileName)
-	
-	_, err := s.db.ExecContext(ctx,
-		`INSERT INTO files (id, name, url, size, type, created_at)
-		 VALUES (?, ?, ?, ?, ?, ?)`,
-		fileID, req.FileName, fileURL, req.FileSize, req.FileType, time.Now(),
-	)
+	key := fileStorageKey(fileID, req.FileName)
+
+	obj, err := s.storage.Put(ctx, key, bytes.NewReader(fileData), int64(len(fileData)), req.FileType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store file: %w", err)
+	}
+
+	// الملف يبقى معلقاً (غير منشور) حتى ينجح الفحص
+	now := time.Now()
+	err = s.files.Create(ctx, &models.File{
+		ID: fileID, UserID: req.UserID, Name: req.FileName, URL: obj.URL,
+		Size: obj.Size, Type: req.FileType, ScanStatus: FileScanPending, CreatedAt: now,
+	})
 	if err != nil {
+		// عدم ترك كائنات يتيمة في التخزين
+		_ = s.storage.Delete(ctx, key)
 		return nil, fmt.Errorf("failed to save file metadata: %w", err)
 	}
-	
-	return &UploadResult{
-		URL:      fileURL,
-		FileName: req.FileName,
-		FileType: req.FileType,
-		FileSize: req.FileSize,
-		Uploaded: time.Now(),
-	}, nil
+	s.adjustUsage(ctx, req.UserID, obj.Size, 1)
+
+	return s.scanUploadResult(ctx, fileID, now)
+}
+
+// ReplaceFile استبدال محتوى ملف قائم مع الإبقاء على معرفه (وإبطال مشتقاته)
+func (s *uploadServiceImpl) ReplaceFile(ctx context.Context, fileID string, req UploadRequest, fileData []byte) (*UploadResult, error) {
+	if s.storage == nil {
+		return nil, ErrStorageUnhealthy
+	}
+	if err := s.checkUploadLimits(req.FileType, int64(len(fileData))); err != nil {
+		return nil, err
+	}
+
+	file, err := s.GetFile(ctx, fileID)
+	if err != nil {
+		return nil, err
+	}
+	if growth := int64(len(fileData)) - file.Size; growth > 0 {
+		if err := s.checkQuota(ctx, file.UserID, growth); err != nil {
+			return nil, err
+		}
+	}
+
+	key := fileStorageKey(fileID, req.FileName)
+	obj, err := s.storage.Put(ctx, key, bytes.NewReader(fileData), int64(len(fileData)), req.FileType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store file: %w", err)
+	}
+
+	err = s.files.ReplaceContent(ctx, &models.File{
+		ID: fileID, Name: req.FileName, URL: obj.URL,
+		Size: obj.Size, Type: req.FileType, ScanStatus: FileScanPending,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to update file metadata: %w", err)
+	}
+	s.adjustUsage(ctx, file.UserID, obj.Size-file.Size, 0)
+
+	// حذف الكائن القديم إذا تغير مكانه
+	if oldKey := fileObjectKey(file); oldKey != key {
+		_ = s.storage.Delete(ctx, oldKey)
+	}
+	s.fileChanged(ctx, fileID)
+
+	return s.scanUploadResult(ctx, fileID, time.Now())
 }
 
+// DeleteFile نقل الملف إلى المحذوفات؛ يبقى قابلاً للاستعادة حتى انتهاء RestoreWindow
 func (s *uploadServiceImpl) DeleteFile(ctx context.Context, fileID string) error {
-	_, err := s.db.ExecContext(ctx,
-		"DELETE FROM files WHERE id = ?",
-		fileID,
-	)
-	return err
+	file, err := s.GetFile(ctx, fileID)
+	if err != nil {
+		return err
+	}
+	return s.trashFile(ctx, file)
+}
+
+// fileChanged إبلاغ المستمع (مثل خدمة الصور) بتغير محتوى الملف
+func (s *uploadServiceImpl) fileChanged(ctx context.Context, fileID string) {
+	if s.options.OnFileChange != nil {
+		s.options.OnFileChange(ctx, fileID)
+	}
+}
+
+// OpenFile فتح محتوى الملف من التخزين للتنزيل (الملفات النظيفة فقط)
+func (s *uploadServiceImpl) OpenFile(ctx context.Context, fileID string) (io.ReadCloser, *models.File, error) {
+	if s.storage == nil {
+		return nil, nil, ErrStorageUnhealthy
+	}
+
+	file, err := s.GetFile(ctx, fileID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := checkFileServable(file); err != nil {
+		return nil, nil, err
+	}
+
+	rc, _, err := s.storage.Get(ctx, fileObjectKey(file))
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, nil, ErrFileNotFound
+		}
+		return nil, nil, fmt.Errorf("failed to open stored file: %w", err)
+	}
+
+	return rc, file, nil
 }
 
+// GetFile الحصول على ملف قائم (الملفات المحذوفة لا تظهر)
 func (s *uploadServiceImpl) GetFile(ctx context.Context, fileID string) (*models.File, error) {
-	var file models.File
-	row := s.db.QueryRowContext(ctx,
-		`SELECT id, user_id, name, url, size, type, created_at
-		 FROM files WHERE id = ?`,
-		fileID)
-	
-	err := row.Scan(
-		&file.ID, &file.UserID, &file.Name, &file.URL, &file.Size,
-		&file.Type, &file.CreatedAt,
-	)
+	file, err := s.getFileRecord(ctx, fileID)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, errors.New("file not found")
+		return nil, err
+	}
+	if file.DeletedAt != nil {
+		return nil, ErrFileNotFound
+	}
+	return file, nil
+}
+
+// getFileRecord تحميل سجل الملف بما في ذلك المحذوف
+func (s *uploadServiceImpl) getFileRecord(ctx context.Context, fileID string) (*models.File, error) {
+	file, err := s.files.Get(ctx, fileID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrFileNotFound
 		}
 		return nil, fmt.Errorf("failed to get file: %w", err)
 	}
+	applyScanState(file)
+	applyDeletedState(file)
 	
-	return &file, nil
+	return file, nil
 }
 
 func (s *uploadServiceImpl) GetUserFiles(ctx context.Context, userID string) ([]models.File, error) {
-	rows, err := s.db.QueryContext(ctx,
-		`SELECT id, user_id, name, url, size, type, created_at
-		 FROM files WHERE user_id = ? ORDER BY created_at DESC`,
-		userID,
-	)
+	return s.listFiles(ctx, s.files.Query().
+		Eq("user_id", userID).
+		IsNull("deleted_at").
+		OrderBy("created_at", true))
+}
+
+// listFiles تنفيذ استعلام ملفات وتطبيق حالة الفحص والحذف على كل ملف
+func (s *uploadServiceImpl) listFiles(ctx context.Context, q *repository.Query[models.File]) ([]models.File, error) {
+	files, err := s.files.List(ctx, q)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user files: %w", err)
 	}
-	defer rows.Close()
 	
-	var files []models.File
-	for rows.Next() {
-		var file models.File
-		err := rows.Scan(
-			&file.ID, &file.UserID, &file.Name, &file.URL, &file.Size,
-			&file.Type, &file.CreatedAt,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan file: %w", err)
-		}
-		files = append(files, file)
+	for i := range files {
+		applyScanState(&files[i])
+		applyDeletedState(&files[i])
 	}
 	
 	return files, nil
 }
 
-func (s *uploadServiceImpl) GeneratePresignedURL(ctx context.Context, fileName, fileType string) (string, error) {
-	fileID := generateID("file")
-	return fmt.Sprintf("https://storage.nawthtech.com/upload/%s?filename=%s&type=%s", fileID, fileName, fileType), nil
-}
-
 // NotificationService Implementation
 func (s *notificationServiceImpl) CreateNotification(ctx context.Context, req NotificationCreateRequest) (*models.Notification, error) {
-	notificationID := generateID("notif")
-	
 	notification := &models.Notification{
-		ID:        notificationID,
+		ID:        generateID("notif"),
 		UserID:    req.UserID,
 		Title:     req.Title,
 		Message:   req.Message,
@@ -2258,12 +1786,7 @@ func (s *notificationServiceImpl) CreateNotification(ctx context.Context, req No
 		CreatedAt: time.Now(),
 	}
 	
-	_, err := s.db.ExecContext(ctx,
-		`INSERT INTO notifications (id, user_id, title, message, type, is_read, created_at)
-		 VALUES (?, ?, ?, ?, ?, ?, ?)`,
-		notificationID, req.UserID, req.Title, req.Message, req.Type, false, time.Now(),
-	)
-	if err != nil {
+	if err := s.store.Notifications.Create(ctx, notification); err != nil {
 		return nil, fmt.Errorf("failed to create notification: %w", err)
 	}
 	
@@ -2271,46 +1794,36 @@ func (s *notificationServiceImpl) CreateNotification(ctx context.Context, req No
 }
 
 func (s *notificationServiceImpl) GetUserNotifications(ctx context.Context, userID string, params NotificationQueryParams) ([]models.Notification, error) {
-	page, limit := validatePaginationParams(params.Page, params.Limit)
-	offset := calculateOffset(page, limit)
-	
-	sqlQuery := `SELECT id, user_id, title, message, type, is_read, created_at
-				 FROM notifications WHERE user_id = ?`
-	args := []interface{}{userID}
+	page, err := s.ListUserNotifications(ctx, userID, params)
+	if err != nil {
+		return nil, err
+	}
+	return page.Items, nil
+}
+
+// ListUserNotifications صفحة إشعارات المستخدم، الأحدث أولاً
+func (s *notificationServiceImpl) ListUserNotifications(ctx context.Context, userID string, params NotificationQueryParams) (*Page[models.Notification], error) {
+	q := s.store.Notifications.Query().
+		Eq("user_id", userID).
+		OrderBy("created_at", true)
 	
 	if params.Type != "" {
-		sqlQuery += " AND type = ?"
-		args = append(args, params.Type)
+		q.Eq("type", params.Type)
 	}
 	
 	if params.Read != nil {
-		sqlQuery += " AND is_read = ?"
-		args = append(args, *params.Read)
+		q.Eq("is_read", *params.Read)
 	}
 	
-	sqlQuery += " ORDER BY created_at DESC LIMIT ? OFFSET ?"
-	args = append(args, limit, offset)
-	
-	rows, err := s.db.QueryContext(ctx, sqlQuery, args...)
+	page, err := paginate(ctx, s.cursors, cursorScopeNotifications, q, params.Page, params.Limit, params.Cursor, params.WithTotal, s.store.Notifications.Paginate)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get notifications: %w", err)
-	}
-	defer rows.Close()
-	
-	var notifications []models.Notification
-	for rows.Next() {
-		var notification models.Notification
-		err := rows.Scan(
-			&notification.ID, &notification.UserID, &notification.Title, &notification.Message,
-			&notification.Type, &notification.IsRead, &notification.CreatedAt,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan notification: %w", err)
+		if errors.Is(err, ErrInvalidCursor) {
+			return nil, err
 		}
-		notifications = append(notifications, notification)
+		return nil, fmt.Errorf("failed to get notifications: %w", err)
 	}
 	
-	return notifications, nil
+	return page, nil
 }
 
 func (s *notificationServiceImpl) MarkAsRead(ctx context.Context, notificationID string) error {
@@ -2415,56 +1928,43 @@ func (s *adminServiceImpl) GetUsers(ctx context.Context, params UserQueryParams)
 }
 
 func (s *adminServiceImpl) GetSystemLogs(ctx context.Context, params SystemLogQuery) ([]models.SystemLog, error) {
-	page, limit := validatePaginationParams(params.Page, params.Limit)
-	offset := calculateOffset(page, limit)
-	
-	sqlQuery := `SELECT id, user_id, level, action, resource, details, ip_address, user_agent, created_at
-				 FROM system_logs WHERE 1=1`
-	args := []interface{}{}
+	page, err := s.ListSystemLogs(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	return page.Items, nil
+}
+
+// ListSystemLogs صفحة سجلات النظام، الأحدث أولاً
+func (s *adminServiceImpl) ListSystemLogs(ctx context.Context, params SystemLogQuery) (*Page[models.SystemLog], error) {
+	q := s.store.SystemLogs.Query().
+		OrderBy("created_at", true)
 	
 	if params.Level != "" {
-		sqlQuery += " AND level = ?"
-		args = append(args, params.Level)
+		q.Eq("level", params.Level)
 	}
 	
 	if params.UserID != "" {
-		sqlQuery += " AND user_id = ?"
-		args = append(args, params.UserID)
+		q.Eq("user_id", params.UserID)
 	}
 	
 	if !params.FromDate.IsZero() {
-		sqlQuery += " AND created_at >= ?"
-		args = append(args, params.FromDate)
+		q.Gte("created_at", params.FromDate)
 	}
 	
 	if !params.ToDate.IsZero() {
-		sqlQuery += " AND created_at <= ?"
-		args = append(args, params.ToDate)
+		q.Lte("created_at", params.ToDate)
 	}
 	
-	sqlQuery += " ORDER BY created_at DESC LIMIT ? OFFSET ?"
-	args = append(args, limit, offset)
-	
-	rows, err := s.db.QueryContext(ctx, sqlQuery, args...)
+	page, err := paginate(ctx, s.cursors, cursorScopeSystemLogs, q, params.Page, params.Limit, params.Cursor, params.WithTotal, s.store.SystemLogs.Paginate)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get system logs: %w", err)
-	}
-	defer rows.Close()
-	
-	var logs []models.SystemLog
-	for rows.Next() {
-		var log models.SystemLog
-		err := rows.Scan(
-			&log.ID, &log.UserID, &log.Level, &log.Action, &log.Resource,
-			&log.Details, &log.IPAddress, &log.UserAgent, &log.CreatedAt,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan system log: %w", err)
+		if errors.Is(err, ErrInvalidCursor) {
+			return nil, err
 		}
-		logs = append(logs, log)
+		return nil, fmt.Errorf("failed to get system logs: %w", err)
 	}
 	
-	return logs, nil
+	return page, nil
 }
 
 func (s *adminServiceImpl) UpdateSystemSettings(ctx context.Context, settings map[string]string) error {
@@ -2488,47 +1988,24 @@ func (s *adminServiceImpl) UnbanUser(ctx context.Context, userID string) error {
 	return err
 }
 
-// CacheService Implementation
-func (c *cacheServiceImpl) Get(key string) (interface{}, error) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-	
-	val, ok := c.store[key]
-	if !ok {
-		return nil, errors.New("key not found")
+// SetUserTier تغيير طبقة المستخدم (تحدد حصة التخزين)
+func (s *adminServiceImpl) SetUserTier(ctx context.Context, userID string, tier string) error {
+	switch tier {
+	case "free", "basic", "premium", "enterprise":
+	default:
+		return ErrInvalidRequest
 	}
-	return val, nil
-}
-
-func (c *cacheServiceImpl) Set(key string, value interface{}, expiration time.Duration) error {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	
-	c.store[key] = value
-	return nil
-}
 
-func (c *cacheServiceImpl) Delete(key string) error {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	
-	delete(c.store, key)
-	return nil
-}
-
-func (c *cacheServiceImpl) Exists(key string) (bool, error) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-	
-	_, ok := c.store[key]
-	return ok, nil
-}
-
-func (c *cacheServiceImpl) Flush() error {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	
-	c.store = make(map[string]interface{})
+	result, err := s.db.ExecContext(ctx,
+		"UPDATE users SET tier = ?, updated_at = ? WHERE id = ?",
+		tier, time.Now(), userID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update user tier: %w", err)
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return ErrUserNotFound
+	}
 	return nil
 }
 
@@ -2635,8 +2112,9 @@ func (s *healthServiceImpl) CheckDatabase(ctx context.Context) (*Check, error) {
 	}
 	
 	// اختبار استعلام بسيط
+	dialect := migrations.DetectDialect(s.db)
 	var version string
-	err = s.db.QueryRowContext(ctx, "SELECT sqlite_version()").Scan(&version)
+	err = s.db.QueryRowContext(ctx, db.VersionQuery(dialect)).Scan(&version)
 	if err != nil {
 		return &Check{
 			Name:      "database",
@@ -2650,7 +2128,7 @@ func (s *healthServiceImpl) CheckDatabase(ctx context.Context) (*Check, error) {
 	return &Check{
 		Name:      "database",
 		Status:    "healthy",
-		Message:   fmt.Sprintf("Database connected (SQLite %s)", version),
+		Message:   fmt.Sprintf("Database connected (%s %s)", dialect, version),
 		Duration:  time.Since(startTime),
 		Timestamp: time.Now(),
 	}, nil
@@ -2660,6 +2138,10 @@ func (s *healthServiceImpl) CheckDatabase(ctx context.Context) (*Check, error) {
 func (s *healthServiceImpl) CheckCache(ctx context.Context) (*Check, error) {
 	startTime := time.Now()
 	
+	if s.cache != nil {
+		return s.checkCacheStats(ctx, startTime), nil
+	}
+	
 	if s.config.Cache.Enabled && s.config.Cache.Type == "memory" {
 		return &Check{
 			Name:      "cache",
@@ -2689,6 +2171,31 @@ func (s *healthServiceImpl) CheckCache(ctx context.Context) (*Check, error) {
 	}, nil
 }
 
+// checkCacheStats فحص التخزين المؤقت الفعلي: الاتصال (لـ Redis) والإحصائيات
+func (s *healthServiceImpl) checkCacheStats(ctx context.Context, startTime time.Time) *Check {
+	if pinger, ok := s.cache.(interface{ Ping(context.Context) error }); ok {
+		if err := pinger.Ping(ctx); err != nil {
+			return &Check{
+				Name:      "cache",
+				Status:    "unhealthy",
+				Message:   fmt.Sprintf("Cache unreachable: %v", err),
+				Duration:  time.Since(startTime),
+				Timestamp: time.Now(),
+			}
+		}
+	}
+	
+	stats := s.cache.Stats()
+	return &Check{
+		Name:   "cache",
+		Status: "healthy",
+		Message: fmt.Sprintf("%s cache: %d keys, hit rate %.1f%% (%d hits, %d misses), %d evictions, %d expirations",
+			stats.Backend, stats.Keys, stats.HitRate*100, stats.Hits, stats.Misses, stats.Evictions, stats.Expirations),
+		Duration:  time.Since(startTime),
+		Timestamp: time.Now(),
+	}
+}
+
 // CheckStorage فحص التخزين
 func (s *healthServiceImpl) CheckStorage(ctx context.Context) (*Check, error) {
 	startTime := time.Now()
@@ -2917,6 +2424,10 @@ func (s *healthServiceImpl) GetMetrics(ctx context.Context) (map[string]interfac
 		}
 	}
 	
+	if s.cache != nil {
+		metrics["cache"] = s.cache.Stats()
+	}
+	
 	metrics["start_time"] = s.startTime.Format(time.RFC3339)
 	
 	metrics["config"] = map[string]interface{}{
@@ -2969,28 +2480,101 @@ func (s *healthServiceImpl) GetDashboardStats(ctx context.Context) (*DashboardSt
 // دوال ServiceContainer
 // ================================
 
+// InitializeDatabase تطبيق ترحيلات المخطط المعلقة (انظر internal/db/migrations)
 func (sc *ServiceContainer) InitializeDatabase(ctx context.Context) error {
-	// إنشاء جميع الجداول الأساسية
-	for _, query := range CreateTablesSQL() {
-		_, err := sc.db.ExecContext(ctx, query)
-		if err != nil {
-			return fmt.Errorf("failed to execute query: %s, error: %w", query, err)
-		}
+	if sc.db == nil {
+		return fmt.Errorf("database not initialized")
 	}
-	
-	// تهيئة جداول الصحة (اختياري، يمكن تجاهل الأخطاء)
-	if err := sc.InitializeHealthTables(ctx); err != nil {
-		if sc.logger != nil {
-			sc.logger.Warn("Failed to initialize health tables", zap.Error(err))
-		} else {
-			sc.logger.Info("⚠️ Failed to initialize health tables: %v", zap.Error(err))
+	if ctx == nil {
+		return fmt.Errorf("context is required")
+	}
+
+	dialect := migrations.DetectDialect(sc.db)
+	if sc.config != nil {
+		if configured, err := migrations.ParseDialect(sc.config.Database.Driver); err == nil {
+			dialect = configured
 		}
 	}
-	
+
+	migrator, err := migrations.New(sc.db, dialect)
+	if err != nil {
+		return fmt.Errorf("failed to load migrations: %w", err)
+	}
+	applied, err := migrator.Up(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to run migrations: %w", err)
+	}
+	if sc.logger != nil && len(applied) > 0 {
+		sc.logger.Info("Applied database migrations", zap.Int("count", len(applied)))
+	}
 	return nil
 }
 
 
+// StartBackgroundJobs تشغيل المهام الدورية للخدمات حتى إلغاء السياق
+func (sc *ServiceContainer) StartBackgroundJobs(ctx context.Context) {
+	if sc.Upload != nil {
+		go runPeriodic(ctx, time.Hour, func(ctx context.Context) {
+			cleaned, err := sc.Upload.CleanupAbandonedUploads(ctx)
+			if sc.logger == nil {
+				return
+			}
+			if err != nil {
+				sc.logger.Warn("Failed to clean up abandoned uploads", zap.Error(err))
+			} else if cleaned > 0 {
+				sc.logger.Info("Cleaned up abandoned uploads", zap.Int("count", cleaned))
+			}
+		})
+
+		// إعادة فحص الملفات المعلقة (الرفع المجزأ أو تعذر الوصول للفاحص)
+		go runPeriodic(ctx, time.Minute, func(ctx context.Context) {
+			scanned, err := sc.Upload.ScanPendingFiles(ctx)
+			if sc.logger == nil {
+				return
+			}
+			if err != nil {
+				sc.logger.Warn("Failed to scan pending files", zap.Error(err))
+			} else if scanned > 0 {
+				sc.logger.Info("Scanned pending files", zap.Int("count", scanned))
+			}
+		})
+
+		// كشف الملفات اليتيمة والحذف النهائي للمحذوفات بعد مهلة الاستعادة
+		go runPeriodic(ctx, storageGCInterval, func(ctx context.Context) {
+			report, err := sc.Upload.CollectGarbage(ctx)
+			if sc.logger == nil {
+				return
+			}
+			if err != nil {
+				sc.logger.Warn("Failed to collect storage garbage", zap.Error(err))
+			} else if report.Trashed > 0 || report.Purged > 0 || report.Failed > 0 {
+				sc.logger.Info("Collected storage garbage",
+					zap.Int("orphaned", report.Orphaned),
+					zap.Int("trashed", report.Trashed),
+					zap.Int("purged", report.Purged),
+					zap.Int64("freed_bytes", report.FreedBytes),
+					zap.Int("failed", report.Failed),
+				)
+			}
+		})
+	}
+}
+
+// runPeriodic تنفيذ دالة بشكل دوري حتى إلغاء السياق
+func runPeriodic(ctx context.Context, interval time.Duration, fn func(context.Context)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			fn(ctx)
+		}
+	}
+}
+
 func (sc *ServiceContainer) Close() error {
 	var errors []string
 	
@@ -3000,6 +2584,12 @@ func (sc *ServiceContainer) Close() error {
 		}
 	}
 	
+	if closer, ok := sc.Cache.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			errors = append(errors, fmt.Sprintf("cache: %v", err))
+		}
+	}
+	
 	if sc.logger != nil {
 		if err := sc.logger.Sync(); err != nil {
 			errors = append(errors, fmt.Sprintf("logger: %v", err))
@@ -3033,6 +2623,32 @@ var (
 	ErrValidation        = errors.New("validation error")
 	ErrNotImplemented    = errors.New("not implemented") 
 	
+	// Upload Errors
+	ErrUploadTokenInvalid    = errors.New("invalid upload token")
+	ErrUploadTokenExpired    = errors.New("upload token expired")
+	ErrUploadTokenUsed       = errors.New("upload token already used")
+	ErrUploadTooLarge        = errors.New("file exceeds maximum upload size")
+	ErrUploadTypeNotAllowed  = errors.New("file type not allowed")
+	ErrUploadIncomplete      = errors.New("uploaded object missing or does not match")
+	ErrUploadNotFound        = errors.New("upload not found")
+	ErrUploadExpired         = errors.New("upload expired")
+	ErrUploadAlreadyComplete = errors.New("upload already complete")
+	ErrUploadOffsetMismatch  = errors.New("upload offset mismatch")
+	ErrChecksumMismatch      = errors.New("chunk checksum mismatch")
+	ErrUnsupportedChecksum   = errors.New("unsupported checksum algorithm")
+	ErrStorageQuotaExceeded  = errors.New("storage quota exceeded")
+	ErrRestoreWindowExpired  = errors.New("file restore window has expired")
+	
+	// Image Errors
+	ErrInvalidTransform      = errors.New("invalid image transform")
+	ErrImageSignatureInvalid = errors.New("invalid image signature")
+	ErrNotAnImage            = errors.New("file is not a processable image")
+	ErrFileNotScanned        = errors.New("file is pending security scan")
+	ErrFileQuarantined       = errors.New("file is quarantined")
+	
+	// Pagination Errors
+	ErrInvalidCursor = repository.ErrInvalidCursor
+	
 	// Health Errors
 	ErrHealthCheckFailed  = errors.New("health check failed")
 	ErrDatabaseUnhealthy  = errors.New("database is unhealthy")
@@ -3058,8 +2674,11 @@ var (
 // دوال Init و Initialization
 // ================================
 
-func InitServiceContainer(db *sql.DB, cfg *config.Config, logger *zap.Logger) *ServiceContainer {
-	container := NewServiceContainerWithConfig(db,cfg, logger)
+func InitServiceContainer(db *sql.DB, cfg *config.Config, logger *zap.Logger) (*ServiceContainer, error) {
+	container, err := NewServiceContainerWithConfig(db,cfg, logger)
+	if err != nil {
+		return nil, err
+	}
 	
 	// تهيئة قاعدة البيانات
 	ctx := context.Background()
@@ -3067,11 +2686,14 @@ func InitServiceContainer(db *sql.DB, cfg *config.Config, logger *zap.Logger) *S
 		fmt.Printf("Warning: failed to initialize database: %v\n", err)
 	}
 	
-	return container
+	return container, nil
 }
 
-func InitServiceContainerWithConfig(db *sql.DB, cfg *config.Config, logger *zap.Logger) *ServiceContainer {
-	container := NewServiceContainerWithConfig(db, cfg, logger)
+func InitServiceContainerWithConfig(db *sql.DB, cfg *config.Config, logger *zap.Logger) (*ServiceContainer, error) {
+	container, err := NewServiceContainerWithConfig(db, cfg, logger)
+	if err != nil {
+		return nil, err
+	}
 	
 	// تهيئة قاعدة البيانات
 	ctx := context.Background()
@@ -3079,5 +2701,5 @@ func InitServiceContainerWithConfig(db *sql.DB, cfg *config.Config, logger *zap.
 		logger.Warn("Failed to initialize database", zap.Error(err))
 	}
 	
-	return container
+	return container, nil
 }
\ No newline at end of file
@@ -5,13 +5,19 @@ import (
 	"testing"
 	"time"
 
+	"github.com/nawthtech/nawthtech/backend/internal/config"
+	"github.com/nawthtech/nawthtech/backend/internal/db/migrations"
+	"github.com/nawthtech/nawthtech/backend/internal/storage"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
 )
 
 // TestNewServiceContainer اختبار إنشاء حاوية الخدمات
 func TestNewServiceContainer(t *testing.T) {
 	// Test with nil database (for unit tests)
-	container := NewServiceContainer(nil)
+	container, err := NewServiceContainer(nil, nil)
+	require.NoError(t, err)
 	assert.NotNil(t, container, "Service container should be created")
 
 	// Test that all services are initialized
@@ -29,32 +35,42 @@ func TestNewServiceContainer(t *testing.T) {
 
 // TestNewServiceContainerWithConfig اختبار إنشاء حاوية الخدمات مع الإعدادات
 func TestNewServiceContainerWithConfig(t *testing.T) {
-	// Create a mock config
-	config := struct {
-		Environment string
-		Version     string
-	}{
+	// Create a test config
+	cfg := &config.Config{
 		Environment: "test",
 		Version:     "1.0.0",
 	}
 
-	// Create a mock logger
-	logger := struct {
-		Info  func(msg string, fields ...interface{})
-		Error func(msg string, fields ...interface{})
-	}{
-		Info:  func(msg string, fields ...interface{}) {},
-		Error: func(msg string, fields ...interface{}) {},
-	}
+	// Use a no-op logger
+	logger := zap.NewNop()
 
 	// Test with nil database
-	container := NewServiceContainerWithConfig(nil, config, logger)
+	container, err := NewServiceContainerWithConfig(nil, cfg, logger)
+	require.NoError(t, err)
 	assert.NotNil(t, container, "Service container should be created with config")
 
 	// Test db field
 	assert.Nil(t, container.db, "Database should be nil when passing nil")
 }
 
+// TestNewServiceContainerStorageFailure فشل التخزين المضبوط يوقف الإنشاء بدل الرجوع للتخزين المحلي
+func TestNewServiceContainerStorageFailure(t *testing.T) {
+	for _, driver := range []string{"s3", "cloudinary", "ftp"} {
+		t.Run(driver, func(t *testing.T) {
+			cfg := &config.Config{Environment: "test"}
+			cfg.Upload.Storage = driver
+			cfg.Upload.Path = t.TempDir()
+
+			container, err := NewServiceContainerWithConfig(nil, cfg, zap.NewNop())
+			require.Error(t, err)
+			assert.Nil(t, container)
+			if driver != "ftp" {
+				assert.ErrorIs(t, err, storage.ErrNotConfigured)
+			}
+		})
+	}
+}
+
 // TestCacheServiceMethods اختبار طرق خدمة التخزين المؤقت
 func TestCacheServiceMethods(t *testing.T) {
 	// Create cache service
@@ -160,7 +176,8 @@ func TestServiceFactoryFunctions(t *testing.T) {
 
 // TestServiceInterfaces اختبار أن الخدمات تنفذ الواجهات المطلوبة
 func TestServiceInterfaces(t *testing.T) {
-	container := NewServiceContainer(nil)
+	container, err := NewServiceContainer(nil, nil)
+	require.NoError(t, err)
 
 	// Test AuthService interface
 	var authService AuthService = container.Auth
@@ -205,14 +222,16 @@ func TestServiceInterfaces(t *testing.T) {
 
 // TestServiceContainerMethods اختبار طرق حاوية الخدمات
 func TestServiceContainerMethods(t *testing.T) {
-	// Create a mock database connection
-	db := &sql.DB{}
+	// Create an in-memory database connection
+	db, err := sql.Open("sqlite3", ":memory:")
+	assert.NoError(t, err)
 
-	container := NewServiceContainer(db)
+	container, err := NewServiceContainer(db, nil)
+	require.NoError(t, err)
 	assert.NotNil(t, container, "Service container should be created")
 
 	// Test InitializeDatabase method
-	err := container.InitializeDatabase(nil)
+	err = container.InitializeDatabase(nil)
 	assert.Error(t, err, "InitializeDatabase should return error with nil context")
 
 	// Test Close method
@@ -314,7 +333,8 @@ func TestServiceErrors(t *testing.T) {
 // TestServiceContainerIntegration اختبار تكامل حاوية الخدمات
 func TestServiceContainerIntegration(t *testing.T) {
 	// Create service container
-	container := NewServiceContainer(nil)
+	container, err := NewServiceContainer(nil, nil)
+	require.NoError(t, err)
 
 	// Verify all services are properly linked
 	assert.NotNil(t, container.Auth, "Auth service should be available")
@@ -329,39 +349,33 @@ func TestServiceContainerIntegration(t *testing.T) {
 	assert.NotNil(t, container.Cache, "Cache service should be available")
 }
 
-// TestCreateTablesSQL اختبار إنشاء جداول SQL
-func TestCreateTablesSQL(t *testing.T) {
-	// Get SQL statements for creating tables
-	sqlStatements := CreateTablesSQL()
-
-	// Should return multiple SQL statements
-	assert.Greater(t, len(sqlStatements), 0, "Should return at least one SQL statement")
-
-	// Each statement should be a non-empty string
-	for i, stmt := range sqlStatements {
-		assert.NotEmpty(t, stmt, "SQL statement %d should not be empty", i)
-		assert.Contains(t, stmt, "CREATE TABLE", "SQL statement %d should be a CREATE TABLE statement", i)
-	}
-
-	// Should include all expected tables
-	expectedTables := []string{
-		"users",
-		"categories",
-		"services",
-		"orders",
-		"payments",
-		"notifications",
-		"files",
-		"system_logs",
-	}
-
-	allStatements := ""
-	for _, stmt := range sqlStatements {
-		allStatements += stmt + " "
-	}
-
-	for _, table := range expectedTables {
-		assert.Contains(t, allStatements, table, "Should include CREATE TABLE for %s", table)
+// TestSchemaMigrations اختبار أن ترحيلات المخطط تنشئ الجداول المطلوبة
+func TestSchemaMigrations(t *testing.T) {
+	for _, dialect := range []migrations.Dialect{migrations.SQLite, migrations.Postgres} {
+		loaded, err := migrations.Load(migrations.Files(), dialect)
+		assert.NoError(t, err, "migrations should load for %s", dialect)
+		assert.Greater(t, len(loaded), 0, "Should return at least one migration")
+
+		allStatements := ""
+		for _, migration := range loaded {
+			assert.NotEmpty(t, migration.Down, "migration %d should be reversible", migration.Version)
+			allStatements += migration.Up + " "
+		}
+
+		// Should include all expected tables
+		expectedTables := []string{
+			"users",
+			"categories",
+			"services",
+			"orders",
+			"payments",
+			"notifications",
+			"files",
+			"system_logs",
+		}
+		for _, table := range expectedTables {
+			assert.Contains(t, allStatements, "CREATE TABLE IF NOT EXISTS "+table+" (", "Should include CREATE TABLE for %s", table)
+		}
 	}
 }
 
@@ -0,0 +1,473 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/nawthtech/nawthtech/backend/internal/models"
+	"github.com/nawthtech/nawthtech/backend/internal/storage"
+)
+
+// ================================
+// حصص التخزين ودورة حياة الملفات
+// ================================
+
+// DefaultStorageTier الطبقة المستخدمة عند غياب طبقة المستخدم أو عدم وجود حصة لها
+const DefaultStorageTier = "free"
+
+// storageGCInterval الفاصل بين دورات جمع الملفات اليتيمة والمحذوفة
+const storageGCInterval = 6 * time.Hour
+
+// fileIDPattern معرفات الملفات كما يولدها generateID("file") داخل الروابط المخزنة
+var fileIDPattern = regexp.MustCompile(`file_[0-9]+`)
+
+// fileReferenceQueries الأعمدة التي تشير إلى الملفات: صور الخدمات والفئات، صور المستخدمين، ومرفقات الطلبات
+var fileReferenceQueries = []string{
+	"SELECT images FROM services WHERE images IS NOT NULL",
+	"SELECT image FROM categories WHERE image IS NOT NULL",
+	"SELECT avatar FROM users WHERE avatar IS NOT NULL",
+	"SELECT attachments FROM orders WHERE attachments IS NOT NULL",
+}
+
+// StorageUsage استهلاك المستخدم لحصة التخزين
+type StorageUsage struct {
+	UserID         string `json:"user_id"`
+	Tier           string `json:"tier"`
+	QuotaBytes     int64  `json:"quota_bytes"` // 0 = بلا حد
+	UsedBytes      int64  `json:"used_bytes"`
+	ReservedBytes  int64  `json:"reserved_bytes"` // روابط الرفع والرفع المجزأ الجاري
+	AvailableBytes int64  `json:"available_bytes,omitempty"`
+	FileCount      int64  `json:"file_count"`
+	TrashBytes     int64  `json:"trash_bytes"`
+	TrashCount     int64  `json:"trash_count"`
+	Unlimited      bool   `json:"unlimited"`
+}
+
+// StorageGCReport نتيجة دورة جمع المهملات
+type StorageGCReport struct {
+	Orphaned        int       `json:"orphaned"`
+	Trashed         int       `json:"trashed"`
+	Purged          int       `json:"purged"`
+	FreedBytes      int64     `json:"freed_bytes"`
+	Failed          int       `json:"failed"`
+	UsageReconciled int       `json:"usage_reconciled"`
+	StartedAt       time.Time `json:"started_at"`
+	Duration        string    `json:"duration"`
+}
+
+// GetStorageUsage حساب الاستهلاك والحصة المتبقية للمستخدم
+func (s *uploadServiceImpl) GetStorageUsage(ctx context.Context, userID string) (*StorageUsage, error) {
+	usage := &StorageUsage{UserID: userID, Tier: s.userTier(ctx, userID)}
+	usage.QuotaBytes = s.quotaFor(usage.Tier)
+	usage.Unlimited = usage.QuotaBytes <= 0
+
+	err := s.db.QueryRowContext(ctx,
+		"SELECT used_bytes, file_count FROM storage_usage WHERE user_id = ?",
+		userID,
+	).Scan(&usage.UsedBytes, &usage.FileCount)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to get storage usage: %w", err)
+	}
+
+	now := time.Now()
+	var tokens, resumable int64
+	if err := s.db.QueryRowContext(ctx,
+		"SELECT COALESCE(SUM(size), 0) FROM upload_tokens WHERE user_id = ? AND used_at IS NULL AND expires_at > ?",
+		userID, now,
+	).Scan(&tokens); err != nil {
+		return nil, fmt.Errorf("failed to get reserved storage: %w", err)
+	}
+	if err := s.db.QueryRowContext(ctx,
+		"SELECT COALESCE(SUM(length), 0) FROM resumable_uploads WHERE user_id = ? AND status = ? AND expires_at > ?",
+		userID, ResumableStatusUploading, now,
+	).Scan(&resumable); err != nil {
+		return nil, fmt.Errorf("failed to get reserved storage: %w", err)
+	}
+	usage.ReservedBytes = tokens + resumable
+
+	if err := s.db.QueryRowContext(ctx,
+		"SELECT COALESCE(SUM(size), 0), COUNT(*) FROM files WHERE user_id = ? AND deleted_at IS NOT NULL",
+		userID,
+	).Scan(&usage.TrashBytes, &usage.TrashCount); err != nil {
+		return nil, fmt.Errorf("failed to get trash usage: %w", err)
+	}
+
+	if !usage.Unlimited {
+		usage.AvailableBytes = usage.QuotaBytes - usage.UsedBytes - usage.ReservedBytes
+		if usage.AvailableBytes < 0 {
+			usage.AvailableBytes = 0
+		}
+	}
+	return usage, nil
+}
+
+// checkQuota التحقق من أن إضافة size بايت لا تتجاوز حصة المستخدم
+func (s *uploadServiceImpl) checkQuota(ctx context.Context, userID string, size int64) error {
+	if len(s.options.Quotas) == 0 || userID == "" {
+		return nil
+	}
+
+	usage, err := s.GetStorageUsage(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !usage.Unlimited && usage.UsedBytes+usage.ReservedBytes+size > usage.QuotaBytes {
+		return ErrStorageQuotaExceeded
+	}
+	return nil
+}
+
+// userTier طبقة المستخدم من جدول المستخدمين
+func (s *uploadServiceImpl) userTier(ctx context.Context, userID string) string {
+	var tier sql.NullString
+	err := s.db.QueryRowContext(ctx, "SELECT tier FROM users WHERE id = ?", userID).Scan(&tier)
+	if err != nil || tier.String == "" {
+		return DefaultStorageTier
+	}
+	return tier.String
+}
+
+// quotaFor حصة الطبقة، والطبقات غير المعرفة تأخذ حصة الطبقة الافتراضية
+func (s *uploadServiceImpl) quotaFor(tier string) int64 {
+	if quota, ok := s.options.Quotas[tier]; ok {
+		return quota
+	}
+	return s.options.Quotas[DefaultStorageTier]
+}
+
+// adjustUsage تعديل عداد الاستهلاك؛ الأخطاء تُصحح عند المطابقة في CollectGarbage
+func (s *uploadServiceImpl) adjustUsage(ctx context.Context, userID string, bytes, files int64) {
+	if userID == "" || (bytes == 0 && files == 0) {
+		return
+	}
+
+	_, _ = s.db.ExecContext(ctx,
+		`INSERT INTO storage_usage (user_id, used_bytes, file_count, updated_at)
+		 VALUES (?, ?, ?, ?)
+		 ON CONFLICT(user_id) DO UPDATE SET
+			used_bytes = storage_usage.used_bytes + excluded.used_bytes,
+			file_count = storage_usage.file_count + excluded.file_count,
+			updated_at = excluded.updated_at`,
+		userID, bytes, files, time.Now(),
+	)
+}
+
+// reconcileUsage إعادة حساب الاستهلاك من جدول الملفات
+func (s *uploadServiceImpl) reconcileUsage(ctx context.Context) (int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin usage reconciliation: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM storage_usage"); err != nil {
+		return 0, fmt.Errorf("failed to reset storage usage: %w", err)
+	}
+	result, err := tx.ExecContext(ctx,
+		`INSERT INTO storage_usage (user_id, used_bytes, file_count, updated_at)
+		 SELECT user_id, COALESCE(SUM(size), 0), COUNT(*), ?
+		 FROM files WHERE deleted_at IS NULL GROUP BY user_id`,
+		time.Now(),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to recalculate storage usage: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit usage reconciliation: %w", err)
+	}
+
+	affected, _ := result.RowsAffected()
+	return int(affected), nil
+}
+
+// ================================
+// الحذف المؤقت والاستعادة
+// ================================
+
+// trashFile نقل الكائن خارج المسار العام وتعليم الملف كمحذوف
+func (s *uploadServiceImpl) trashFile(ctx context.Context, file *models.File) error {
+	key := fileObjectKey(file)
+	moved := false
+	if s.storage != nil && file.ScanStatus != FileScanQuarantined {
+		err := s.moveObject(ctx, key, trashStorageKey(file.ID, file.Name), file.Type)
+		if err != nil && !errors.Is(err, storage.ErrNotFound) {
+			return fmt.Errorf("failed to move file to trash: %w", err)
+		}
+		moved = err == nil
+	}
+
+	result, err := s.db.ExecContext(ctx,
+		"UPDATE files SET deleted_at = ? WHERE id = ? AND deleted_at IS NULL",
+		time.Now(), file.ID,
+	)
+	if err == nil {
+		if affected, _ := result.RowsAffected(); affected != 1 {
+			err = ErrFileNotFound
+		}
+	}
+	if err != nil {
+		if moved {
+			_ = s.moveObject(ctx, trashStorageKey(file.ID, file.Name), key, file.Type)
+		}
+		if errors.Is(err, ErrFileNotFound) {
+			return err
+		}
+		return fmt.Errorf("failed to delete file: %w", err)
+	}
+
+	s.adjustUsage(ctx, file.UserID, -file.Size, -1)
+	s.fileChanged(ctx, file.ID)
+	return nil
+}
+
+// RestoreFile استعادة ملف محذوف خلال مهلة الاستعادة (مع التحقق من الحصة)
+func (s *uploadServiceImpl) RestoreFile(ctx context.Context, fileID string) (*models.File, error) {
+	file, err := s.getFileRecord(ctx, fileID)
+	if err != nil {
+		return nil, err
+	}
+	if file.DeletedAt == nil {
+		return file, nil
+	}
+	if time.Since(*file.DeletedAt) > s.options.RestoreWindow {
+		return nil, ErrRestoreWindowExpired
+	}
+	if err := s.checkQuota(ctx, file.UserID, file.Size); err != nil {
+		return nil, err
+	}
+
+	if s.storage != nil && file.ScanStatus != FileScanQuarantined {
+		err := s.moveObject(ctx, trashStorageKey(file.ID, file.Name), fileStorageKey(file.ID, file.Name), file.Type)
+		if err != nil && !errors.Is(err, storage.ErrNotFound) {
+			return nil, fmt.Errorf("failed to restore file: %w", err)
+		}
+	}
+
+	result, err := s.db.ExecContext(ctx,
+		"UPDATE files SET deleted_at = NULL, orphaned_at = NULL WHERE id = ? AND deleted_at IS NOT NULL",
+		file.ID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to restore file: %w", err)
+	}
+	if affected, _ := result.RowsAffected(); affected == 1 {
+		s.adjustUsage(ctx, file.UserID, file.Size, 1)
+	}
+
+	return s.GetFile(ctx, file.ID)
+}
+
+// GetDeletedFiles الملفات المحذوفة التي ما زالت قابلة للاستعادة
+func (s *uploadServiceImpl) GetDeletedFiles(ctx context.Context, userID string) ([]models.File, error) {
+	return s.listFiles(ctx, s.files.DeletedSince(userID, time.Now().Add(-s.options.RestoreWindow)))
+}
+
+// purgeFile الحذف النهائي للكائن ومشتقاته وسجله
+func (s *uploadServiceImpl) purgeFile(ctx context.Context, file *models.File) error {
+	if s.storage != nil {
+		if err := s.storage.Delete(ctx, fileObjectKey(file)); err != nil {
+			return fmt.Errorf("failed to delete stored file: %w", err)
+		}
+	}
+	s.fileChanged(ctx, file.ID)
+
+	if _, err := s.db.ExecContext(ctx,
+		"DELETE FROM files WHERE id = ? AND deleted_at IS NOT NULL",
+		file.ID,
+	); err != nil {
+		return fmt.Errorf("failed to purge file: %w", err)
+	}
+	return nil
+}
+
+// moveObject نقل كائن بين مفتاحين (نسخ ثم حذف)
+func (s *uploadServiceImpl) moveObject(ctx context.Context, from, to, contentType string) error {
+	rc, obj, err := s.storage.Get(ctx, from)
+	if err != nil {
+		return err
+	}
+	_, err = s.storage.Put(ctx, to, rc, obj.Size, contentType)
+	rc.Close()
+	if err != nil {
+		return err
+	}
+	return s.storage.Delete(ctx, from)
+}
+
+// applyDeletedState إخفاء رابط الملف المحذوف
+func applyDeletedState(file *models.File) {
+	if file.DeletedAt != nil {
+		file.URL = ""
+	}
+}
+
+// trashStorageKey مفتاح المحذوفات خارج مسار files/ العام
+func trashStorageKey(fileID, fileName string) string {
+	return "trash/" + fileID + "/" + storage.SanitizeFileName(fileName)
+}
+
+// ================================
+// الملفات اليتيمة وجمع المهملات
+// ================================
+
+// DetectOrphanedFiles تعليم الملفات التي فقدت ارتباطها بخدمة أو صورة مستخدم أو طلب،
+// وإلغاء التعليم عن الملفات التي عادت مرتبطة. الملف الذي لم يُرفق بأي سجل قط لا يعتبر يتيماً
+// (يبقى ضمن ملفات المستخدم حتى يحذفه). يعيد عدد الملفات اليتيمة حالياً
+func (s *uploadServiceImpl) DetectOrphanedFiles(ctx context.Context) (int, error) {
+	referenced, err := s.referencedFileIDs(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT id, referenced_at, orphaned_at FROM files WHERE deleted_at IS NULL",
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list files: %w", err)
+	}
+
+	var attach, mark []string
+	orphaned := 0
+	for rows.Next() {
+		var id string
+		var referencedAt, orphanedAt sql.NullTime
+		if err := rows.Scan(&id, &referencedAt, &orphanedAt); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan file: %w", err)
+		}
+		switch {
+		case referenced[id]:
+			if !referencedAt.Valid || orphanedAt.Valid {
+				attach = append(attach, id)
+			}
+		case referencedAt.Valid:
+			orphaned++
+			if !orphanedAt.Valid {
+				mark = append(mark, id)
+			}
+		}
+	}
+	rows.Close()
+
+	now := time.Now()
+	for _, id := range attach {
+		if _, err := s.db.ExecContext(ctx,
+			"UPDATE files SET referenced_at = COALESCE(referenced_at, ?), orphaned_at = NULL WHERE id = ?",
+			now, id,
+		); err != nil {
+			return 0, fmt.Errorf("failed to mark referenced file: %w", err)
+		}
+	}
+	for _, id := range mark {
+		if _, err := s.db.ExecContext(ctx, "UPDATE files SET orphaned_at = ? WHERE id = ?", now, id); err != nil {
+			return 0, fmt.Errorf("failed to mark orphaned file: %w", err)
+		}
+	}
+	return orphaned, nil
+}
+
+// referencedFileIDs جمع معرفات الملفات من كل الأعمدة التي تشير إليها
+func (s *uploadServiceImpl) referencedFileIDs(ctx context.Context) (map[string]bool, error) {
+	referenced := make(map[string]bool)
+	for _, query := range fileReferenceQueries {
+		rows, err := s.db.QueryContext(ctx, query)
+		if err != nil {
+			return nil, fmt.Errorf("failed to collect file references: %w", err)
+		}
+		for rows.Next() {
+			var value sql.NullString
+			if err := rows.Scan(&value); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to scan file reference: %w", err)
+			}
+			for _, id := range fileIDPattern.FindAllString(value.String, -1) {
+				referenced[id] = true
+			}
+		}
+		rows.Close()
+	}
+	return referenced, nil
+}
+
+// CollectGarbage دورة كاملة: كشف اليتيمة، نقل اليتيمة القديمة للمحذوفات،
+// الحذف النهائي بعد انتهاء مهلة الاستعادة، ثم مطابقة عدادات الاستهلاك
+func (s *uploadServiceImpl) CollectGarbage(ctx context.Context) (*StorageGCReport, error) {
+	report := &StorageGCReport{StartedAt: time.Now()}
+	defer func() {
+		report.Duration = time.Since(report.StartedAt).String()
+	}()
+
+	orphaned, err := s.DetectOrphanedFiles(ctx)
+	if err != nil {
+		return nil, err
+	}
+	report.Orphaned = orphaned
+
+	// اليتيمة منذ أكثر من مهلة السماح تنقل للمحذوفات (وتبقى قابلة للاستعادة)
+	expired, err := s.fileIDs(ctx,
+		"SELECT id FROM files WHERE deleted_at IS NULL AND orphaned_at IS NOT NULL AND orphaned_at < ?",
+		report.StartedAt.Add(-s.options.OrphanGrace),
+	)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range expired {
+		file, err := s.GetFile(ctx, id)
+		if err == nil {
+			err = s.trashFile(ctx, file)
+		}
+		if err != nil {
+			report.Failed++
+			continue
+		}
+		report.Trashed++
+	}
+
+	purgeable, err := s.fileIDs(ctx,
+		"SELECT id FROM files WHERE deleted_at IS NOT NULL AND deleted_at < ?",
+		report.StartedAt.Add(-s.options.RestoreWindow),
+	)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range purgeable {
+		file, err := s.getFileRecord(ctx, id)
+		if err == nil {
+			err = s.purgeFile(ctx, file)
+		}
+		if err != nil {
+			report.Failed++
+			continue
+		}
+		report.Purged++
+		report.FreedBytes += file.Size
+	}
+
+	if report.UsageReconciled, err = s.reconcileUsage(ctx); err != nil {
+		return report, err
+	}
+	return report, nil
+}
+
+// fileIDs تنفيذ استعلام يعيد عمود معرفات فقط
+func (s *uploadServiceImpl) fileIDs(ctx context.Context, query string, args ...interface{}) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan file id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
@@ -0,0 +1,122 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/nawthtech/nawthtech/backend/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestStorageGarbageCollection الملف المرفق ثم المفصول يصبح يتيماً، ينقل للمحذوفات، يستعاد، ثم يحذف نهائياً؛
+// والملف الذي لم يرفق قط يبقى للمستخدم
+func TestStorageGarbageCollection(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T, container *ServiceContainer, database *sql.DB) {
+		ctx := context.Background()
+		uploads, store, userID := newTestUploadService(t, container, database, UploadOptions{
+			OrphanGrace:   time.Hour,
+			RestoreWindow: 24 * time.Hour,
+		})
+
+		upload := func(name string) *UploadResult {
+			result, err := uploads.UploadFile(ctx, UploadRequest{UserID: userID, FileName: name, FileType: "text/plain"}, []byte("content of "+name))
+			require.NoError(t, err)
+			return result
+		}
+		draft := upload("draft.txt")
+		avatar := upload("avatar.txt")
+		backdate := func(column, fileID string, age time.Duration) {
+			_, err := database.ExecContext(ctx, "UPDATE files SET "+column+" = ? WHERE id = ?", time.Now().Add(-age), fileID)
+			require.NoError(t, err)
+		}
+		backdate("created_at", draft.ID, 30*24*time.Hour)
+
+		_, err := database.ExecContext(ctx, "UPDATE users SET avatar = ? WHERE id = ?", "/files/"+avatar.ID, userID)
+		require.NoError(t, err)
+		report, err := uploads.CollectGarbage(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 0, report.Orphaned)
+
+		_, err = database.ExecContext(ctx, "UPDATE users SET avatar = NULL WHERE id = ?", userID)
+		require.NoError(t, err)
+		report, err = uploads.CollectGarbage(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 1, report.Orphaned)
+		assert.Equal(t, 0, report.Trashed)
+
+		backdate("orphaned_at", avatar.ID, 2*time.Hour)
+		report, err = uploads.CollectGarbage(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 1, report.Trashed)
+
+		files, err := uploads.GetUserFiles(ctx, userID)
+		require.NoError(t, err)
+		require.Len(t, files, 1)
+		assert.Equal(t, draft.ID, files[0].ID)
+		deleted, err := uploads.GetDeletedFiles(ctx, userID)
+		require.NoError(t, err)
+		require.Len(t, deleted, 1)
+		assert.Equal(t, avatar.ID, deleted[0].ID)
+
+		restored, err := uploads.RestoreFile(ctx, avatar.ID)
+		require.NoError(t, err)
+		assert.Nil(t, restored.DeletedAt)
+		_, err = store.Stat(ctx, fileStorageKey(avatar.ID, "avatar.txt"))
+		assert.NoError(t, err)
+
+		require.NoError(t, uploads.DeleteFile(ctx, avatar.ID))
+		backdate("deleted_at", avatar.ID, 48*time.Hour)
+		_, err = uploads.RestoreFile(ctx, avatar.ID)
+		assert.ErrorIs(t, err, ErrRestoreWindowExpired)
+
+		report, err = uploads.CollectGarbage(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 1, report.Purged)
+		assert.Equal(t, avatar.FileSize, report.FreedBytes)
+		_, err = store.Stat(ctx, trashStorageKey(avatar.ID, "avatar.txt"))
+		assert.ErrorIs(t, err, storage.ErrNotFound)
+		_, err = uploads.RestoreFile(ctx, avatar.ID)
+		assert.ErrorIs(t, err, ErrFileNotFound)
+
+		_, err = uploads.GetFile(ctx, draft.ID)
+		assert.NoError(t, err)
+	})
+}
+
+// TestStorageUsageReconcile العدادات المنحرفة تصحح من جدول الملفات في دورة الجمع
+func TestStorageUsageReconcile(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T, container *ServiceContainer, database *sql.DB) {
+		ctx := context.Background()
+		uploads, _, userID := newTestUploadService(t, container, database, UploadOptions{
+			Quotas: map[string]int64{DefaultStorageTier: 40},
+		})
+
+		kept, err := uploads.UploadFile(ctx, UploadRequest{UserID: userID, FileName: "a.txt", FileType: "text/plain"}, []byte("0123456789"))
+		require.NoError(t, err)
+		trashed, err := uploads.UploadFile(ctx, UploadRequest{UserID: userID, FileName: "b.txt", FileType: "text/plain"}, []byte("01234"))
+		require.NoError(t, err)
+		require.NoError(t, uploads.DeleteFile(ctx, trashed.ID))
+
+		_, err = database.ExecContext(ctx, "UPDATE storage_usage SET used_bytes = 39, file_count = 7 WHERE user_id = ?", userID)
+		require.NoError(t, err)
+		_, err = uploads.UploadFile(ctx, UploadRequest{UserID: userID, FileName: "c.txt", FileType: "text/plain"}, []byte("0123"))
+		assert.ErrorIs(t, err, ErrStorageQuotaExceeded)
+
+		report, err := uploads.CollectGarbage(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 1, report.UsageReconciled)
+
+		usage, err := uploads.GetStorageUsage(ctx, userID)
+		require.NoError(t, err)
+		assert.Equal(t, kept.FileSize, usage.UsedBytes)
+		assert.Equal(t, int64(1), usage.FileCount)
+		assert.Equal(t, trashed.FileSize, usage.TrashBytes)
+		assert.Equal(t, int64(40)-kept.FileSize, usage.AvailableBytes)
+
+		_, err = uploads.UploadFile(ctx, UploadRequest{UserID: userID, FileName: "c.txt", FileType: "text/plain"}, []byte("0123"))
+		assert.NoError(t, err)
+	})
+}
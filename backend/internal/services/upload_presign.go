@@ -0,0 +1,431 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/nawthtech/nawthtech/backend/internal/config"
+	"github.com/nawthtech/nawthtech/backend/internal/models"
+	"github.com/nawthtech/nawthtech/backend/internal/repository"
+	"github.com/nawthtech/nawthtech/backend/internal/scanning"
+	"github.com/nawthtech/nawthtech/backend/internal/storage"
+	"github.com/nawthtech/nawthtech/backend/internal/utils"
+)
+
+// ================================
+// الرفع المباشر بروابط موقعة
+// ================================
+
+// UploadOptions حدود وإعدادات خدمة الرفع
+type UploadOptions struct {
+	MaxSize          int64
+	MaxResumableSize int64
+	AllowedTypes     []string
+	TokenTTL         time.Duration
+	// ResumableTTL مدة بقاء الرفع المجزأ دون نشاط قبل اعتباره مهجوراً
+	ResumableTTL time.Duration
+	// DirectUploadURL رابط الرفع عبر الخادم عندما لا يدعم التخزين الروابط الموقعة
+	DirectUploadURL string
+	// OnFileChange يستدعى بعد استبدال محتوى ملف أو حذفه
+	OnFileChange func(ctx context.Context, fileID string)
+	// Scanner خط فحص الملفات قبل نشرها
+	Scanner *scanning.Scanner
+	// Quotas حصة التخزين لكل طبقة (الطبقة غير المعروفة تأخذ حصة free، و0 يعني بلا حد)
+	Quotas map[string]int64
+	// RestoreWindow مدة بقاء الملف المحذوف قابلاً للاستعادة قبل حذفه نهائياً
+	RestoreWindow time.Duration
+	// OrphanGrace مهلة بقاء الملف الذي فقد ارتباطه قبل نقله إلى المحذوفات
+	OrphanGrace time.Duration
+}
+
+// PresignedUploadRequest طلب رابط رفع موقع
+type PresignedUploadRequest struct {
+	UserID   string `json:"user_id" form:"-"`
+	FileName string `json:"file_name" form:"file_name" validate:"required"`
+	FileType string `json:"file_type" form:"file_type" validate:"required"`
+	FileSize int64  `json:"file_size" form:"file_size" validate:"required,min=1"`
+}
+
+// PresignedUpload رابط الرفع الموقع والتوكن اللازم لإتمام الرفع
+type PresignedUpload struct {
+	Token     string            `json:"token"`
+	FileID    string            `json:"file_id"`
+	Method    string            `json:"method"`
+	UploadURL string            `json:"upload_url"`
+	Headers   map[string]string `json:"headers,omitempty"`
+	MaxSize   int64             `json:"max_size"`
+	ExpiresAt time.Time         `json:"expires_at"`
+}
+
+// pendingUpload سجل توكن رفع من جدول upload_tokens
+type pendingUpload struct {
+	Token      string
+	UserID     string
+	FileID     string
+	Name       string
+	Type       string
+	Size       int64
+	ExpiresAt  time.Time
+	UploadedAt sql.NullTime
+	UsedAt     sql.NullTime
+}
+
+// DefaultUploadOptions الحدود الافتراضية للرفع
+func DefaultUploadOptions() UploadOptions {
+	return UploadOptions{
+		MaxSize:          10 * 1024 * 1024,
+		MaxResumableSize: 2 * 1024 * 1024 * 1024,
+		TokenTTL:         15 * time.Minute,
+		ResumableTTL:     24 * time.Hour,
+		DirectUploadURL:  "/api/v1/upload/direct",
+		Scanner:          scanning.New(scanning.Options{}),
+		RestoreWindow:    30 * 24 * time.Hour,
+		OrphanGrace:      7 * 24 * time.Hour,
+	}
+}
+
+// uploadOptionsFromConfig بناء حدود الرفع من الإعدادات
+func uploadOptionsFromConfig(cfg *config.Config) UploadOptions {
+	opts := DefaultUploadOptions()
+	if cfg == nil {
+		return opts
+	}
+
+	if cfg.Upload.MaxSize > 0 {
+		opts.MaxSize = cfg.Upload.MaxSize
+	}
+	if cfg.Upload.MaxResumableSize > 0 {
+		opts.MaxResumableSize = cfg.Upload.MaxResumableSize
+	}
+	opts.AllowedTypes = cfg.Upload.AllowedTypes
+	opts.Quotas = cfg.Upload.Quotas
+	if cfg.Upload.RestoreWindow > 0 {
+		opts.RestoreWindow = cfg.Upload.RestoreWindow
+	}
+	if cfg.Upload.OrphanGrace > 0 {
+		opts.OrphanGrace = cfg.Upload.OrphanGrace
+	}
+	if cfg.APIURL != "" {
+		opts.DirectUploadURL = strings.TrimRight(cfg.APIURL, "/") + "/api/v1/upload/direct"
+	}
+	return opts
+}
+
+// GeneratePresignedURL إنشاء رابط رفع موقع مقيد بالحجم والنوع
+func (s *uploadServiceImpl) GeneratePresignedURL(ctx context.Context, req PresignedUploadRequest) (*PresignedUpload, error) {
+	if s.storage == nil {
+		return nil, ErrStorageUnhealthy
+	}
+	if req.UserID == "" || req.FileName == "" || req.FileType == "" {
+		return nil, ErrInvalidRequest
+	}
+	if err := s.checkUploadLimits(req.FileType, req.FileSize); err != nil {
+		return nil, err
+	}
+	if err := s.checkQuota(ctx, req.UserID, req.FileSize); err != nil {
+		return nil, err
+	}
+
+	token, err := utils.GenerateUploadToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate upload token: %w", err)
+	}
+
+	fileID := generateID("file")
+	expiresAt := time.Now().Add(s.options.TokenTTL)
+
+	upload := &PresignedUpload{
+		Token:     token,
+		FileID:    fileID,
+		Method:    http.MethodPut,
+		UploadURL: s.options.DirectUploadURL + "/" + token,
+		Headers:   map[string]string{"Content-Type": req.FileType},
+		MaxSize:   req.FileSize,
+		ExpiresAt: expiresAt,
+	}
+
+	// الرفع مباشرة إلى التخزين إن كان يدعم الروابط الموقعة؛ الرابط يبقى صالحاً حتى انتهاء
+	// التوكن، لذا يوقع لمفتاح مرحلي لا يُقرأ منه بعد الإتمام
+	if presigner, ok := s.storage.(storage.Presigner); ok {
+		presigned, err := presigner.PresignPut(ctx, uploadStagingKey(token), req.FileType, req.FileSize, s.options.TokenTTL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to presign upload: %w", err)
+		}
+		upload.Method = presigned.Method
+		upload.UploadURL = presigned.URL
+		upload.Headers = presigned.Headers
+	}
+
+	_, err = s.db.ExecContext(ctx,
+		`INSERT INTO upload_tokens (token, user_id, file_id, name, type, size, expires_at, created_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		token, req.UserID, fileID, req.FileName, req.FileType, req.FileSize, expiresAt, time.Now(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save upload token: %w", err)
+	}
+
+	return upload, nil
+}
+
+// UploadWithToken رفع المحتوى عبر الخادم باستخدام توكن الرفع
+func (s *uploadServiceImpl) UploadWithToken(ctx context.Context, token string, r io.Reader, size int64, contentType string) error {
+	if s.storage == nil {
+		return ErrStorageUnhealthy
+	}
+
+	pending, err := s.getPendingUpload(ctx, token)
+	if err != nil {
+		return err
+	}
+	if pending.UsedAt.Valid || pending.UploadedAt.Valid {
+		return ErrUploadTokenUsed
+	}
+	if !sameMediaType(contentType, pending.Type) {
+		return ErrUploadTypeNotAllowed
+	}
+	if size >= 0 && size != pending.Size {
+		if size > pending.Size {
+			return ErrUploadTooLarge
+		}
+		return ErrUploadIncomplete
+	}
+
+	// حجز التوكن قبل الكتابة لمنع الرفع المتزامن بنفس التوكن
+	claimed, err := s.claimUploadToken(ctx, token, "uploaded_at")
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		return ErrUploadTokenUsed
+	}
+
+	key := uploadStagingKey(token)
+	if _, err := s.storage.Put(ctx, key, io.LimitReader(r, pending.Size+1), pending.Size, pending.Type); err != nil {
+		_, _ = s.db.ExecContext(ctx, "UPDATE upload_tokens SET uploaded_at = NULL WHERE token = ?", token)
+		return fmt.Errorf("failed to store file: %w", err)
+	}
+
+	return nil
+}
+
+// FinalizeUpload التحقق من الكائن المرفوع وتسجيله في جدول الملفات
+func (s *uploadServiceImpl) FinalizeUpload(ctx context.Context, userID, token string) (*UploadResult, error) {
+	if s.storage == nil {
+		return nil, ErrStorageUnhealthy
+	}
+
+	pending, err := s.getPendingUpload(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	if pending.UserID != userID {
+		return nil, ErrUnauthorized
+	}
+	if pending.UsedAt.Valid {
+		return nil, ErrUploadTokenUsed
+	}
+
+	// لا يُحجز التوكن قبل التأكد من اكتمال الكائن، ليبقى الإتمام قابلاً لإعادة المحاولة
+	staging := uploadStagingKey(token)
+	obj, err := s.storage.Stat(ctx, staging)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, ErrUploadIncomplete
+		}
+		return nil, fmt.Errorf("failed to verify uploaded file: %w", err)
+	}
+
+	// الحجم يجب أن يطابق ما تم توقيعه، وإلا يحذف الكائن ويُعاد فتح التوكن للرفع من جديد
+	// (نوع المحتوى مفروض عند الرفع عبر الترويسة الموقعة أو UploadWithToken)
+	if obj.Size != pending.Size {
+		_ = s.storage.Delete(ctx, staging)
+		_, _ = s.db.ExecContext(ctx, "UPDATE upload_tokens SET uploaded_at = NULL WHERE token = ?", token)
+		return nil, ErrUploadIncomplete
+	}
+
+	now := time.Now()
+	key := fileStorageKey(pending.FileID, pending.Name)
+	if err := s.commitUpload(ctx, pending, &models.File{
+		ID: pending.FileID, UserID: pending.UserID, Name: pending.Name, URL: s.storage.URL(key),
+		Size: obj.Size, Type: pending.Type, ScanStatus: FileScanPending, CreatedAt: now,
+	}); err != nil {
+		return nil, err
+	}
+
+	// النقل إلى المفتاح النهائي بعد حجز التوكن فقط، فلا يصل رفع لاحق عبر الرابط الموقع
+	// إلى الملف المفحوص؛ فشل النقل يعيد التوكن والملف إلى ما قبل الإتمام
+	if err := s.promoteUpload(ctx, staging, key, pending); err != nil {
+		s.revertUpload(ctx, pending, key)
+		return nil, err
+	}
+	_ = s.storage.Delete(ctx, staging)
+	s.adjustUsage(ctx, pending.UserID, obj.Size, 1)
+
+	return s.scanUploadResult(ctx, pending.FileID, now)
+}
+
+// commitUpload حجز التوكن وإدراج سجل الملف في معاملة واحدة، فلا يُستهلك التوكن إن فشل الإدراج
+func (s *uploadServiceImpl) commitUpload(ctx context.Context, pending *pendingUpload, file *models.File) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	claim, err := tx.ExecContext(ctx,
+		"UPDATE upload_tokens SET used_at = ? WHERE token = ? AND used_at IS NULL",
+		file.CreatedAt, pending.Token,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to claim upload token: %w", err)
+	}
+	// معرف الملف مفتاح أساسي، فالإتمام المتزامن بنفس التوكن يفشل هنا
+	if err := repository.NewFileRepository(tx).Create(ctx, file); err != nil {
+		return s.finalizeError(ctx, pending.Token, fmt.Errorf("failed to save file metadata: %w", err))
+	}
+	if err := tx.Commit(); err != nil {
+		return s.finalizeError(ctx, pending.Token, fmt.Errorf("failed to save file metadata: %w", err))
+	}
+
+	// في D1 لا تتوفر نتيجة التعليمة إلا بعد Commit
+	if affected, err := claim.RowsAffected(); err == nil && affected == 0 {
+		return ErrUploadTokenUsed
+	}
+	return nil
+}
+
+// promoteUpload نسخ الكائن المرحلي إلى مفتاحه النهائي
+func (s *uploadServiceImpl) promoteUpload(ctx context.Context, staging, key string, pending *pendingUpload) error {
+	rc, _, err := s.storage.Get(ctx, staging)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return ErrUploadIncomplete
+		}
+		return fmt.Errorf("failed to read uploaded file: %w", err)
+	}
+	defer rc.Close()
+
+	obj, err := s.storage.Put(ctx, key, io.LimitReader(rc, pending.Size+1), pending.Size, pending.Type)
+	if err != nil {
+		return fmt.Errorf("failed to store file: %w", err)
+	}
+	if obj.Size != pending.Size {
+		return ErrUploadIncomplete
+	}
+	return nil
+}
+
+// revertUpload إلغاء إتمام لم يكتمل نقله: حذف سجل الملف وكائنه وإعادة فتح التوكن
+func (s *uploadServiceImpl) revertUpload(ctx context.Context, pending *pendingUpload, key string) {
+	_ = s.storage.Delete(ctx, key)
+	_, _ = s.db.ExecContext(ctx, "DELETE FROM files WHERE id = ?", pending.FileID)
+	_, _ = s.db.ExecContext(ctx, "UPDATE upload_tokens SET used_at = NULL WHERE token = ?", pending.Token)
+}
+
+// uploadStagingKey المفتاح المرحلي للرفع المباشر بتوكن
+func uploadStagingKey(token string) string {
+	return "pending/" + token
+}
+
+// finalizeError تحويل فشل الإتمام إلى ErrUploadTokenUsed إذا سبق إتمام التوكن من طلب آخر
+func (s *uploadServiceImpl) finalizeError(ctx context.Context, token string, err error) error {
+	var usedAt sql.NullTime
+	if s.db.QueryRowContext(ctx, "SELECT used_at FROM upload_tokens WHERE token = ?", token).Scan(&usedAt) == nil && usedAt.Valid {
+		return ErrUploadTokenUsed
+	}
+	return err
+}
+
+// getPendingUpload تحميل توكن الرفع والتحقق من صلاحيته الزمنية
+func (s *uploadServiceImpl) getPendingUpload(ctx context.Context, token string) (*pendingUpload, error) {
+	if !strings.HasPrefix(token, "upl_") {
+		return nil, ErrUploadTokenInvalid
+	}
+
+	var p pendingUpload
+	err := s.db.QueryRowContext(ctx,
+		`SELECT token, user_id, file_id, name, type, size, expires_at, uploaded_at, used_at
+		 FROM upload_tokens WHERE token = ?`,
+		token,
+	).Scan(&p.Token, &p.UserID, &p.FileID, &p.Name, &p.Type, &p.Size, &p.ExpiresAt, &p.UploadedAt, &p.UsedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrUploadTokenInvalid
+		}
+		return nil, fmt.Errorf("failed to get upload token: %w", err)
+	}
+
+	if !utils.ValidateUploadToken(token, s.options.TokenTTL) || time.Now().After(p.ExpiresAt) {
+		return nil, ErrUploadTokenExpired
+	}
+
+	return &p, nil
+}
+
+// claimUploadToken تعليم التوكن بشكل ذري، ويعيد false إذا سبق استخدامه
+func (s *uploadServiceImpl) claimUploadToken(ctx context.Context, token, column string) (bool, error) {
+	result, err := s.db.ExecContext(ctx,
+		fmt.Sprintf("UPDATE upload_tokens SET %s = ? WHERE token = ? AND %s IS NULL", column, column),
+		time.Now(), token,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim upload token: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to claim upload token: %w", err)
+	}
+	return affected == 1, nil
+}
+
+// checkUploadLimits التحقق من الحجم والنوع المسموحين
+func (s *uploadServiceImpl) checkUploadLimits(fileType string, size int64) error {
+	if size <= 0 {
+		return ErrInvalidRequest
+	}
+	if s.options.MaxSize > 0 && size > s.options.MaxSize {
+		return ErrUploadTooLarge
+	}
+	return s.checkUploadType(fileType)
+}
+
+// checkUploadType التحقق من أن نوع المحتوى ضمن القائمة المسموحة
+func (s *uploadServiceImpl) checkUploadType(fileType string) error {
+	if len(s.options.AllowedTypes) == 0 {
+		return nil
+	}
+
+	for _, allowed := range s.options.AllowedTypes {
+		allowed = strings.TrimSpace(allowed)
+		if strings.HasSuffix(allowed, "/*") {
+			mediaType, _, _ := mime.ParseMediaType(fileType)
+			if strings.HasPrefix(mediaType, strings.TrimSuffix(allowed, "*")) {
+				return nil
+			}
+			continue
+		}
+		if sameMediaType(fileType, allowed) {
+			return nil
+		}
+	}
+	return ErrUploadTypeNotAllowed
+}
+
+// sameMediaType مقارنة نوعي محتوى مع تجاهل المعاملات مثل charset
+func sameMediaType(a, b string) bool {
+	ma, _, errA := mime.ParseMediaType(a)
+	mb, _, errB := mime.ParseMediaType(b)
+	if errA != nil || errB != nil {
+		return strings.EqualFold(strings.TrimSpace(a), strings.TrimSpace(b))
+	}
+	return ma == mb
+}
@@ -0,0 +1,144 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nawthtech/nawthtech/backend/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestUploadService خدمة رفع على تخزين محلي مؤقت ومستخدم مسجل
+func newTestUploadService(t *testing.T, container *ServiceContainer, database *sql.DB, opts UploadOptions) (*uploadServiceImpl, storage.Storage, string) {
+	t.Helper()
+
+	store, err := storage.NewLocalStorage(t.TempDir(), "")
+	require.NoError(t, err)
+
+	auth, err := container.Auth.Register(context.Background(), AuthRegisterRequest{
+		FirstName: "Sara",
+		LastName:  "Ahmed",
+		Email:     "sara@example.com",
+		Username:  "sara",
+		Password:  "secret-password",
+	})
+	require.NoError(t, err)
+
+	return NewUploadServiceWithOptions(database, store, opts).(*uploadServiceImpl), store, auth.User.ID
+}
+
+// TestFinalizeUploadRetry الإتمام قبل وصول الكائن لا يستهلك التوكن
+func TestFinalizeUploadRetry(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T, container *ServiceContainer, database *sql.DB) {
+		ctx := context.Background()
+		uploads, _, userID := newTestUploadService(t, container, database, UploadOptions{})
+
+		content := []byte("hello world")
+		upload, err := uploads.GeneratePresignedURL(ctx, PresignedUploadRequest{
+			UserID: userID, FileName: "hello.txt", FileType: "text/plain", FileSize: int64(len(content)),
+		})
+		require.NoError(t, err)
+
+		_, err = uploads.FinalizeUpload(ctx, userID, upload.Token)
+		assert.ErrorIs(t, err, ErrUploadIncomplete)
+
+		require.NoError(t, uploads.UploadWithToken(ctx, upload.Token, bytes.NewReader(content), int64(len(content)), "text/plain"))
+
+		result, err := uploads.FinalizeUpload(ctx, userID, upload.Token)
+		require.NoError(t, err)
+		assert.Equal(t, upload.FileID, result.ID)
+		assert.Equal(t, int64(len(content)), result.FileSize)
+
+		_, err = uploads.FinalizeUpload(ctx, userID, upload.Token)
+		assert.ErrorIs(t, err, ErrUploadTokenUsed)
+
+		usage, err := uploads.GetStorageUsage(ctx, userID)
+		require.NoError(t, err)
+		assert.Equal(t, int64(len(content)), usage.UsedBytes)
+		assert.Equal(t, int64(1), usage.FileCount)
+	})
+}
+
+// TestFinalizeUploadSizeMismatch الكائن بحجم مختلف عن الموقع يحذف ويمكن رفعه من جديد
+func TestFinalizeUploadSizeMismatch(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T, container *ServiceContainer, database *sql.DB) {
+		ctx := context.Background()
+		uploads, store, userID := newTestUploadService(t, container, database, UploadOptions{})
+
+		content := []byte("hello world")
+		upload, err := uploads.GeneratePresignedURL(ctx, PresignedUploadRequest{
+			UserID: userID, FileName: "hello.txt", FileType: "text/plain", FileSize: int64(len(content)),
+		})
+		require.NoError(t, err)
+
+		// رفع مباشر إلى التخزين يتجاوز قيود الرابط الموقع
+		key := uploadStagingKey(upload.Token)
+		_, err = store.Put(ctx, key, strings.NewReader("hello"), 5, "text/plain")
+		require.NoError(t, err)
+
+		_, err = uploads.FinalizeUpload(ctx, userID, upload.Token)
+		assert.ErrorIs(t, err, ErrUploadIncomplete)
+
+		_, err = store.Stat(ctx, key)
+		assert.ErrorIs(t, err, storage.ErrNotFound)
+		_, err = uploads.GetFile(ctx, upload.FileID)
+		assert.Error(t, err)
+
+		require.NoError(t, uploads.UploadWithToken(ctx, upload.Token, bytes.NewReader(content), int64(len(content)), "text/plain"))
+		_, err = uploads.FinalizeUpload(ctx, userID, upload.Token)
+		require.NoError(t, err)
+	})
+}
+
+// TestFinalizeUploadIgnoresLaterWrites الرابط الموقع يبقى صالحاً بعد الإتمام، لكن الرفع عبره
+// لا يصل إلى الملف المفحوص المنشور
+func TestFinalizeUploadIgnoresLaterWrites(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T, container *ServiceContainer, database *sql.DB) {
+		ctx := context.Background()
+		uploads, store, userID := newTestUploadService(t, container, database, UploadOptions{})
+
+		content := []byte("hello world")
+		upload, err := uploads.GeneratePresignedURL(ctx, PresignedUploadRequest{
+			UserID: userID, FileName: "hello.txt", FileType: "text/plain", FileSize: int64(len(content)),
+		})
+		require.NoError(t, err)
+		require.NoError(t, uploads.UploadWithToken(ctx, upload.Token, bytes.NewReader(content), int64(len(content)), "text/plain"))
+
+		result, err := uploads.FinalizeUpload(ctx, userID, upload.Token)
+		require.NoError(t, err)
+		assert.Equal(t, FileScanClean, result.ScanStatus)
+
+		// PUT ثانٍ إلى الرابط الموقع بنفس الحجم والنوع يكتب في المفتاح المرحلي
+		staging := uploadStagingKey(upload.Token)
+		_, err = store.Put(ctx, staging, strings.NewReader("evil world!"), int64(len(content)), "text/plain")
+		require.NoError(t, err)
+		_, err = uploads.FinalizeUpload(ctx, userID, upload.Token)
+		assert.ErrorIs(t, err, ErrUploadTokenUsed)
+
+		rc, file, err := uploads.OpenFile(ctx, upload.FileID)
+		require.NoError(t, err)
+		served, err := io.ReadAll(rc)
+		rc.Close()
+		require.NoError(t, err)
+		assert.Equal(t, content, served)
+		assert.Equal(t, FileScanClean, file.ScanStatus)
+
+		// تنظيف التوكن المنتهي يحذف الكائن المرحلي دون المساس بالملف
+		_, err = database.ExecContext(ctx, "UPDATE upload_tokens SET expires_at = ? WHERE token = ?",
+			time.Now().Add(-time.Minute), upload.Token)
+		require.NoError(t, err)
+		cleaned, err := uploads.CleanupAbandonedUploads(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 0, cleaned)
+		_, err = store.Stat(ctx, staging)
+		assert.ErrorIs(t, err, storage.ErrNotFound)
+		_, err = store.Stat(ctx, fileStorageKey(upload.FileID, "hello.txt"))
+		assert.NoError(t, err)
+	})
+}
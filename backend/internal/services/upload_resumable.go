@@ -0,0 +1,481 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/nawthtech/nawthtech/backend/internal/models"
+	"github.com/nawthtech/nawthtech/backend/internal/repository"
+)
+
+// ================================
+// الرفع المجزأ القابل للاستئناف (tus)
+// ================================
+
+const (
+	ResumableStatusUploading = "uploading"
+	ResumableStatusComplete  = "complete"
+)
+
+// ResumableChecksumAlgorithms خوارزميات التحقق المدعومة لكل جزء
+var ResumableChecksumAlgorithms = []string{"sha1", "sha256", "md5"}
+
+// ResumableUploadRequest طلب إنشاء رفع مجزأ
+type ResumableUploadRequest struct {
+	UserID   string `json:"user_id"`
+	FileName string `json:"file_name"`
+	FileType string `json:"file_type"`
+	Length   int64  `json:"length"`
+}
+
+// ResumableUpload حالة رفع مجزأ
+type ResumableUpload struct {
+	ID        string    `json:"id"`
+	UserID    string    `json:"user_id"`
+	FileName  string    `json:"file_name"`
+	FileType  string    `json:"file_type"`
+	Length    int64     `json:"length"`
+	Offset    int64     `json:"offset"`
+	Status    string    `json:"status"`
+	FileID    string    `json:"file_id,omitempty"`
+	URL       string    `json:"url,omitempty"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	chunks []resumableChunk
+}
+
+// resumableChunk جزء مخزن من الرفع
+type resumableChunk struct {
+	Key    string `json:"key"`
+	Offset int64  `json:"offset"`
+	Size   int64  `json:"size"`
+}
+
+// CreateResumableUpload إنشاء رفع مجزأ جديد
+func (s *uploadServiceImpl) CreateResumableUpload(ctx context.Context, req ResumableUploadRequest) (*ResumableUpload, error) {
+	if s.storage == nil {
+		return nil, ErrStorageUnhealthy
+	}
+	if req.UserID == "" || req.FileName == "" || req.Length <= 0 {
+		return nil, ErrInvalidRequest
+	}
+	if req.FileType == "" {
+		req.FileType = "application/octet-stream"
+	}
+	if req.Length > s.options.MaxResumableSize {
+		return nil, ErrUploadTooLarge
+	}
+	// الحجم يُتحقق منه أعلاه، والنوع حسب القائمة المسموحة
+	if err := s.checkUploadType(req.FileType); err != nil {
+		return nil, err
+	}
+	if err := s.checkQuota(ctx, req.UserID, req.Length); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	upload := &ResumableUpload{
+		ID:        generateID("rupl"),
+		UserID:    req.UserID,
+		FileName:  req.FileName,
+		FileType:  req.FileType,
+		Length:    req.Length,
+		Status:    ResumableStatusUploading,
+		ExpiresAt: now.Add(s.options.ResumableTTL),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO resumable_uploads (id, user_id, name, type, length, upload_offset, chunks, status, expires_at, created_at, updated_at)
+		 VALUES (?, ?, ?, ?, ?, 0, '[]', ?, ?, ?, ?)`,
+		upload.ID, upload.UserID, upload.FileName, upload.FileType, upload.Length,
+		upload.Status, upload.ExpiresAt, upload.CreatedAt, upload.UpdatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create resumable upload: %w", err)
+	}
+
+	return upload, nil
+}
+
+// GetResumableUpload الحصول على حالة رفع مجزأ
+func (s *uploadServiceImpl) GetResumableUpload(ctx context.Context, uploadID string) (*ResumableUpload, error) {
+	var upload ResumableUpload
+	var chunks string
+	var fileID sql.NullString
+
+	err := s.db.QueryRowContext(ctx,
+		`SELECT id, user_id, name, type, length, upload_offset, chunks, status, file_id, expires_at, created_at, updated_at
+		 FROM resumable_uploads WHERE id = ?`,
+		uploadID,
+	).Scan(
+		&upload.ID, &upload.UserID, &upload.FileName, &upload.FileType, &upload.Length,
+		&upload.Offset, &chunks, &upload.Status, &fileID, &upload.ExpiresAt,
+		&upload.CreatedAt, &upload.UpdatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrUploadNotFound
+		}
+		return nil, fmt.Errorf("failed to get resumable upload: %w", err)
+	}
+
+	if err := json.Unmarshal([]byte(chunks), &upload.chunks); err != nil {
+		return nil, fmt.Errorf("failed to decode upload chunks: %w", err)
+	}
+
+	upload.FileID = fileID.String
+	if upload.FileID != "" {
+		// الرابط يظهر فقط بعد اجتياز الملف للفحص
+		if file, err := s.GetFile(ctx, upload.FileID); err == nil {
+			upload.URL = file.URL
+		}
+	}
+
+	return &upload, nil
+}
+
+// WriteChunk كتابة جزء عند الإزاحة المحددة مع التحقق الاختياري من المجموع (Upload-Checksum)
+func (s *uploadServiceImpl) WriteChunk(ctx context.Context, uploadID string, offset int64, r io.Reader, size int64, checksum string) (*ResumableUpload, error) {
+	if s.storage == nil {
+		return nil, ErrStorageUnhealthy
+	}
+
+	upload, err := s.GetResumableUpload(ctx, uploadID)
+	if err != nil {
+		return nil, err
+	}
+	if upload.Status != ResumableStatusUploading {
+		return nil, ErrUploadAlreadyComplete
+	}
+	if time.Now().After(upload.ExpiresAt) {
+		return nil, ErrUploadExpired
+	}
+	if offset != upload.Offset {
+		return nil, ErrUploadOffsetMismatch
+	}
+	if size < 0 {
+		return nil, ErrInvalidRequest
+	}
+	if offset+size > upload.Length {
+		return nil, ErrUploadTooLarge
+	}
+
+	// إعادة محاولة الإتمام لرفع اكتملت أجزاؤه
+	if size == 0 {
+		if upload.Offset == upload.Length {
+			return s.completeResumableUpload(ctx, upload)
+		}
+		return upload, nil
+	}
+
+	algorithm, expected, err := parseUploadChecksum(checksum)
+	if err != nil {
+		return nil, err
+	}
+
+	var hasher hash.Hash
+	body := io.LimitReader(r, size+1)
+	if algorithm != "" {
+		hasher = newChecksumHash(algorithm)
+		body = io.TeeReader(body, hasher)
+	}
+
+	// مفتاح فريد لكل محاولة حتى لا تتداخل الطلبات المتزامنة على نفس الإزاحة
+	chunk := resumableChunk{
+		Key:    fmt.Sprintf("uploads/%s/%016d-%d", upload.ID, offset, time.Now().UnixNano()),
+		Offset: offset,
+		Size:   size,
+	}
+
+	if _, err := s.storage.Put(ctx, chunk.Key, body, size, "application/octet-stream"); err != nil {
+		return nil, fmt.Errorf("failed to store chunk: %w", err)
+	}
+
+	if hasher != nil && !bytes.Equal(hasher.Sum(nil), expected) {
+		_ = s.storage.Delete(ctx, chunk.Key)
+		return nil, ErrChecksumMismatch
+	}
+
+	chunks, err := json.Marshal(append(upload.chunks, chunk))
+	if err != nil {
+		_ = s.storage.Delete(ctx, chunk.Key)
+		return nil, fmt.Errorf("failed to encode upload chunks: %w", err)
+	}
+
+	// تقدم الإزاحة مشروط بعدم تغيرها منذ القراءة (آمن مع عدة نسخ من الخادم)
+	now := time.Now()
+	result, err := s.db.ExecContext(ctx,
+		`UPDATE resumable_uploads SET upload_offset = ?, chunks = ?, expires_at = ?, updated_at = ?
+		 WHERE id = ? AND upload_offset = ? AND status = ?`,
+		offset+size, string(chunks), now.Add(s.options.ResumableTTL), now,
+		upload.ID, offset, ResumableStatusUploading,
+	)
+	if err != nil {
+		_ = s.storage.Delete(ctx, chunk.Key)
+		return nil, fmt.Errorf("failed to update upload offset: %w", err)
+	}
+	if affected, _ := result.RowsAffected(); affected != 1 {
+		_ = s.storage.Delete(ctx, chunk.Key)
+		return nil, ErrUploadOffsetMismatch
+	}
+
+	upload.Offset = offset + size
+	upload.chunks = append(upload.chunks, chunk)
+	upload.ExpiresAt = now.Add(s.options.ResumableTTL)
+	upload.UpdatedAt = now
+
+	if upload.Offset == upload.Length {
+		return s.completeResumableUpload(ctx, upload)
+	}
+
+	return upload, nil
+}
+
+// AbortResumableUpload إلغاء رفع مجزأ وحذف أجزائه
+func (s *uploadServiceImpl) AbortResumableUpload(ctx context.Context, uploadID string) error {
+	upload, err := s.GetResumableUpload(ctx, uploadID)
+	if err != nil {
+		return err
+	}
+	if upload.Status == ResumableStatusComplete {
+		return ErrUploadAlreadyComplete
+	}
+
+	s.deleteChunks(ctx, upload.chunks)
+
+	_, err = s.db.ExecContext(ctx, "DELETE FROM resumable_uploads WHERE id = ?", uploadID)
+	if err != nil {
+		return fmt.Errorf("failed to delete resumable upload: %w", err)
+	}
+	return nil
+}
+
+// CleanupAbandonedUploads حذف الرفع المجزأ والمباشر المنتهي دون إتمام، ويعيد عدد ما تم تنظيفه
+func (s *uploadServiceImpl) CleanupAbandonedUploads(ctx context.Context) (int, error) {
+	now := time.Now()
+	cleaned := 0
+
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT id, chunks FROM resumable_uploads WHERE status = ? AND expires_at < ?",
+		ResumableStatusUploading, now,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to find abandoned uploads: %w", err)
+	}
+
+	abandoned := map[string][]resumableChunk{}
+	for rows.Next() {
+		var id, raw string
+		if err := rows.Scan(&id, &raw); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan abandoned upload: %w", err)
+		}
+		var chunks []resumableChunk
+		_ = json.Unmarshal([]byte(raw), &chunks)
+		abandoned[id] = chunks
+	}
+	rows.Close()
+
+	for id, chunks := range abandoned {
+		s.deleteChunks(ctx, chunks)
+		result, err := s.db.ExecContext(ctx,
+			"DELETE FROM resumable_uploads WHERE id = ? AND status = ?",
+			id, ResumableStatusUploading,
+		)
+		if err != nil {
+			return cleaned, fmt.Errorf("failed to delete abandoned upload: %w", err)
+		}
+		if affected, _ := result.RowsAffected(); affected == 1 {
+			cleaned++
+		}
+	}
+
+	// سجلات الرفع المكتمل لم تعد لازمة بعد انتهاء صلاحيتها
+	if _, err := s.db.ExecContext(ctx,
+		"DELETE FROM resumable_uploads WHERE status = ? AND expires_at < ?",
+		ResumableStatusComplete, now,
+	); err != nil {
+		return cleaned, fmt.Errorf("failed to delete completed uploads: %w", err)
+	}
+
+	// توكنات الرفع المنتهية: الكائن المرحلي يحذف سواء لم يتم الرفع أو أعيد الرفع بعد إتمامه
+	tokens, err := s.db.QueryContext(ctx,
+		"SELECT token, used_at FROM upload_tokens WHERE expires_at < ?",
+		now,
+	)
+	if err != nil {
+		return cleaned, fmt.Errorf("failed to find expired upload tokens: %w", err)
+	}
+
+	type expiredToken struct {
+		token  string
+		usedAt sql.NullTime
+	}
+	var expired []expiredToken
+	for tokens.Next() {
+		var t expiredToken
+		if err := tokens.Scan(&t.token, &t.usedAt); err != nil {
+			tokens.Close()
+			return cleaned, fmt.Errorf("failed to scan upload token: %w", err)
+		}
+		expired = append(expired, t)
+	}
+	tokens.Close()
+
+	for _, t := range expired {
+		if s.storage != nil {
+			_ = s.storage.Delete(ctx, uploadStagingKey(t.token))
+		}
+		if _, err := s.db.ExecContext(ctx, "DELETE FROM upload_tokens WHERE token = ?", t.token); err != nil {
+			return cleaned, fmt.Errorf("failed to delete upload token: %w", err)
+		}
+		if !t.usedAt.Valid {
+			cleaned++
+		}
+	}
+
+	return cleaned, nil
+}
+
+// completeResumableUpload تجميع الأجزاء في الكائن النهائي وتسجيل الملف
+func (s *uploadServiceImpl) completeResumableUpload(ctx context.Context, upload *ResumableUpload) (*ResumableUpload, error) {
+	fileID := generateID("file")
+	key := fileStorageKey(fileID, upload.FileName)
+
+	readers := make([]io.Reader, 0, len(upload.chunks))
+	closers := make([]io.Closer, 0, len(upload.chunks))
+	defer func() {
+		for _, c := range closers {
+			c.Close()
+		}
+	}()
+
+	for _, chunk := range upload.chunks {
+		rc, _, err := s.storage.Get(ctx, chunk.Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read chunk at offset %d: %w", chunk.Offset, err)
+		}
+		readers = append(readers, rc)
+		closers = append(closers, rc)
+	}
+
+	obj, err := s.storage.Put(ctx, key, io.MultiReader(readers...), upload.Length, upload.FileType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to assemble upload: %w", err)
+	}
+
+	// الحالة وسجل الملف يكتبان معاً؛ عند الفشل يبقى الرفع قابلاً لإعادة محاولة الإتمام
+	now := time.Now()
+	completed, err := s.commitResumableUpload(ctx, upload, &models.File{
+		ID: fileID, UserID: upload.UserID, Name: upload.FileName, URL: obj.URL,
+		Size: upload.Length, Type: upload.FileType, ScanStatus: FileScanPending, CreatedAt: now,
+	})
+	if err != nil {
+		_ = s.storage.Delete(ctx, key)
+		return nil, err
+	}
+	if !completed {
+		_, _ = s.db.ExecContext(ctx, "DELETE FROM files WHERE id = ?", fileID)
+		_ = s.storage.Delete(ctx, key)
+		return nil, ErrUploadAlreadyComplete
+	}
+	s.adjustUsage(ctx, upload.UserID, upload.Length, 1)
+
+	s.deleteChunks(ctx, upload.chunks)
+
+	upload.Status = ResumableStatusComplete
+	upload.FileID = fileID
+	upload.UpdatedAt = now
+	return upload, nil
+}
+
+// commitResumableUpload تعليم الرفع مكتملاً وإدراج الملف في معاملة واحدة، ويعيد false إن أتمه طلب آخر
+func (s *uploadServiceImpl) commitResumableUpload(ctx context.Context, upload *ResumableUpload, file *models.File) (bool, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx,
+		`UPDATE resumable_uploads SET status = ?, file_id = ?, updated_at = ?
+		 WHERE id = ? AND status = ?`,
+		ResumableStatusComplete, file.ID, file.CreatedAt, upload.ID, ResumableStatusUploading,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to complete upload: %w", err)
+	}
+	// الملفات الكبيرة تفحص في المهمة الدورية، وتبقى غير منشورة حتى ذلك
+	if err := repository.NewFileRepository(tx).Create(ctx, file); err != nil {
+		return false, fmt.Errorf("failed to save file metadata: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to complete upload: %w", err)
+	}
+
+	// في D1 لا تتوفر نتيجة التعليمة إلا بعد Commit
+	affected, err := result.RowsAffected()
+	return err != nil || affected == 1, nil
+}
+
+// deleteChunks حذف أجزاء الرفع من التخزين (الأخطاء تُتجاهل، ويعيد التنظيف الدوري المحاولة)
+func (s *uploadServiceImpl) deleteChunks(ctx context.Context, chunks []resumableChunk) {
+	if s.storage == nil {
+		return
+	}
+	for _, chunk := range chunks {
+		_ = s.storage.Delete(ctx, chunk.Key)
+	}
+}
+
+// parseUploadChecksum تحليل ترويسة Upload-Checksum بصيغة "<algorithm> <base64>"
+func parseUploadChecksum(header string) (string, []byte, error) {
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return "", nil, nil
+	}
+
+	parts := strings.SplitN(header, " ", 2)
+	if len(parts) != 2 {
+		return "", nil, ErrInvalidRequest
+	}
+
+	algorithm := strings.ToLower(parts[0])
+	if newChecksumHash(algorithm) == nil {
+		return "", nil, ErrUnsupportedChecksum
+	}
+
+	sum, err := base64.StdEncoding.DecodeString(strings.TrimSpace(parts[1]))
+	if err != nil {
+		return "", nil, ErrInvalidRequest
+	}
+	return algorithm, sum, nil
+}
+
+func newChecksumHash(algorithm string) hash.Hash {
+	switch algorithm {
+	case "sha1":
+		return sha1.New()
+	case "sha256":
+		return sha256.New()
+	case "md5":
+		return md5.New()
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,135 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func sha256Checksum(data string) string {
+	sum := sha256.Sum256([]byte(data))
+	return "sha256 " + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// TestResumableUploadChunks التحقق من الإزاحة والمجموع والاستئناف بعد الانقطاع
+func TestResumableUploadChunks(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T, container *ServiceContainer, database *sql.DB) {
+		ctx := context.Background()
+		uploads, store, userID := newTestUploadService(t, container, database, UploadOptions{})
+
+		upload, err := uploads.CreateResumableUpload(ctx, ResumableUploadRequest{
+			UserID: userID, FileName: "notes.txt", FileType: "text/plain", Length: 10,
+		})
+		require.NoError(t, err)
+
+		_, err = uploads.WriteChunk(ctx, upload.ID, 3, strings.NewReader("defg"), 4, "")
+		assert.ErrorIs(t, err, ErrUploadOffsetMismatch)
+
+		_, err = uploads.WriteChunk(ctx, upload.ID, 0, strings.NewReader("abcd"), 4, sha256Checksum("wxyz"))
+		assert.ErrorIs(t, err, ErrChecksumMismatch)
+
+		upload, err = uploads.WriteChunk(ctx, upload.ID, 0, strings.NewReader("abcd"), 4, sha256Checksum("abcd"))
+		require.NoError(t, err)
+		assert.Equal(t, int64(4), upload.Offset)
+
+		// انقطاع الاتصال أثناء الجزء لا يقدم الإزاحة
+		interrupted := io.MultiReader(strings.NewReader("ef"), iotest.ErrReader(errors.New("connection reset")))
+		_, err = uploads.WriteChunk(ctx, upload.ID, 4, interrupted, 6, "")
+		assert.Error(t, err)
+
+		upload, err = uploads.GetResumableUpload(ctx, upload.ID)
+		require.NoError(t, err)
+		assert.Equal(t, int64(4), upload.Offset)
+		assert.Len(t, upload.chunks, 1)
+
+		upload, err = uploads.WriteChunk(ctx, upload.ID, 4, strings.NewReader("efghij"), 6, "")
+		require.NoError(t, err)
+		assert.Equal(t, ResumableStatusComplete, upload.Status)
+
+		// الملف لم يفحص بعد، فيقرأ من التخزين مباشرة
+		rc, _, err := store.Get(ctx, fileStorageKey(upload.FileID, "notes.txt"))
+		require.NoError(t, err)
+		defer rc.Close()
+		content, err := io.ReadAll(rc)
+		require.NoError(t, err)
+		assert.Equal(t, "abcdefghij", string(content))
+
+		_, err = uploads.WriteChunk(ctx, upload.ID, 10, strings.NewReader(""), 0, "")
+		assert.ErrorIs(t, err, ErrUploadAlreadyComplete)
+	})
+}
+
+// TestResumableUploadCompleteRetry فشل تسجيل الملف يبقي الرفع قابلاً لإعادة الإتمام
+func TestResumableUploadCompleteRetry(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T, container *ServiceContainer, database *sql.DB) {
+		ctx := context.Background()
+		uploads, _, userID := newTestUploadService(t, container, database, UploadOptions{})
+
+		upload, err := uploads.CreateResumableUpload(ctx, ResumableUploadRequest{
+			UserID: userID, FileName: "notes.txt", FileType: "text/plain", Length: 5,
+		})
+		require.NoError(t, err)
+
+		_, err = database.ExecContext(ctx, "ALTER TABLE files RENAME TO files_unavailable")
+		require.NoError(t, err)
+		_, err = uploads.WriteChunk(ctx, upload.ID, 0, strings.NewReader("hello"), 5, "")
+		assert.Error(t, err)
+		_, err = database.ExecContext(ctx, "ALTER TABLE files_unavailable RENAME TO files")
+		require.NoError(t, err)
+
+		upload, err = uploads.GetResumableUpload(ctx, upload.ID)
+		require.NoError(t, err)
+		assert.Equal(t, ResumableStatusUploading, upload.Status)
+		assert.Equal(t, int64(5), upload.Offset)
+
+		upload, err = uploads.WriteChunk(ctx, upload.ID, 5, strings.NewReader(""), 0, "")
+		require.NoError(t, err)
+		assert.Equal(t, ResumableStatusComplete, upload.Status)
+
+		files, err := uploads.GetUserFiles(ctx, userID)
+		require.NoError(t, err)
+		assert.Len(t, files, 1)
+	})
+}
+
+// TestCleanupAbandonedUploads حذف الرفع المنتهي وأجزائه دون المساس بالرفع النشط
+func TestCleanupAbandonedUploads(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T, container *ServiceContainer, database *sql.DB) {
+		ctx := context.Background()
+		uploads, store, userID := newTestUploadService(t, container, database, UploadOptions{})
+
+		request := ResumableUploadRequest{UserID: userID, FileName: "notes.txt", FileType: "text/plain", Length: 10}
+		abandoned, err := uploads.CreateResumableUpload(ctx, request)
+		require.NoError(t, err)
+		abandoned, err = uploads.WriteChunk(ctx, abandoned.ID, 0, strings.NewReader("abcd"), 4, "")
+		require.NoError(t, err)
+		active, err := uploads.CreateResumableUpload(ctx, request)
+		require.NoError(t, err)
+
+		_, err = database.ExecContext(ctx, "UPDATE resumable_uploads SET expires_at = ? WHERE id = ?",
+			time.Now().Add(-time.Minute), abandoned.ID)
+		require.NoError(t, err)
+
+		cleaned, err := uploads.CleanupAbandonedUploads(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 1, cleaned)
+
+		_, err = uploads.GetResumableUpload(ctx, abandoned.ID)
+		assert.ErrorIs(t, err, ErrUploadNotFound)
+		_, err = store.Stat(ctx, abandoned.chunks[0].Key)
+		assert.Error(t, err)
+
+		_, err = uploads.GetResumableUpload(ctx, active.ID)
+		assert.NoError(t, err)
+	})
+}
@@ -0,0 +1,214 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/nawthtech/nawthtech/backend/internal/ai"
+	aiservices "github.com/nawthtech/nawthtech/backend/internal/ai/services"
+	"github.com/nawthtech/nawthtech/backend/internal/config"
+	"github.com/nawthtech/nawthtech/backend/internal/models"
+	"github.com/nawthtech/nawthtech/backend/internal/scanning"
+	"github.com/nawthtech/nawthtech/backend/internal/storage"
+	"go.uber.org/zap"
+)
+
+// ================================
+// فحص الملفات المرفوعة والحجر
+// ================================
+
+// حالات فحص الملف
+const (
+	FileScanPending     = "pending"
+	FileScanClean       = "clean"
+	FileScanQuarantined = "quarantined"
+)
+
+// pendingScanBatch عدد الملفات المعلقة التي تفحص في كل دورة
+const pendingScanBatch = 100
+
+// newUploadScanner بناء خط الفحص من الإعدادات (ClamAV وفحص سلامة الصور اختياريان)
+func newUploadScanner(cfg *config.Config, logger *zap.Logger) *scanning.Scanner {
+	opts := scanning.Options{}
+	if cfg == nil {
+		return scanning.New(opts)
+	}
+
+	if cfg.Upload.ClamAVAddress != "" {
+		opts.Virus = scanning.NewClamAV(cfg.Upload.ClamAVAddress, 0)
+	}
+
+	if cfg.Upload.SafetyCheck {
+		provider, err := ai.NewMultiProvider()
+		if err != nil {
+			if logger != nil {
+				logger.Warn("Image safety check disabled: no AI provider", zap.Error(err))
+			}
+		} else {
+			opts.Analyzer = aiservices.NewMediaService(provider, nil)
+		}
+	}
+
+	return scanning.New(opts)
+}
+
+// ScanFile فحص ملف معلق وتحديث حالته: نظيف يُنشر، وغير ذلك يُنقل إلى الحجر
+func (s *uploadServiceImpl) ScanFile(ctx context.Context, fileID string) (*models.File, error) {
+	if s.storage == nil {
+		return nil, ErrStorageUnhealthy
+	}
+
+	file, err := s.GetFile(ctx, fileID)
+	if err != nil {
+		return nil, err
+	}
+	if file.ScanStatus != FileScanPending {
+		return file, nil
+	}
+
+	key := fileStorageKey(file.ID, file.Name)
+	rc, _, err := s.storage.Get(ctx, key)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, ErrFileNotFound
+		}
+		return nil, fmt.Errorf("failed to open file for scanning: %w", err)
+	}
+	report, err := s.options.Scanner.Scan(ctx, rc, file.Type)
+	rc.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan file: %w", err)
+	}
+
+	if report.Clean() {
+		_, err = s.db.ExecContext(ctx,
+			"UPDATE files SET scan_status = ?, scan_reason = NULL, scanned_at = ? WHERE id = ? AND scan_status = ?",
+			FileScanClean, report.ScannedAt, file.ID, FileScanPending,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to update scan status: %w", err)
+		}
+		return s.GetFile(ctx, file.ID)
+	}
+
+	if err := s.quarantineFile(ctx, file, report); err != nil {
+		return nil, err
+	}
+	return s.GetFile(ctx, file.ID)
+}
+
+// ScanPendingFiles فحص الملفات المعلقة (المرفوعة بأجزاء أو التي تعذر فحصها سابقاً)
+func (s *uploadServiceImpl) ScanPendingFiles(ctx context.Context) (int, error) {
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT id FROM files WHERE scan_status = ? AND deleted_at IS NULL ORDER BY created_at LIMIT ?",
+		FileScanPending, pendingScanBatch,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list pending files: %w", err)
+	}
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan pending file: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+
+	scanned := 0
+	for _, id := range ids {
+		if _, err := s.ScanFile(ctx, id); err != nil {
+			// الفاحص غير متاح: التوقف وإعادة المحاولة في الدورة التالية
+			if errors.Is(err, scanning.ErrScanUnavailable) {
+				return scanned, err
+			}
+			continue
+		}
+		scanned++
+	}
+	return scanned, nil
+}
+
+// quarantineFile نقل الكائن خارج المسار العام وتسجيل سبب الحجر
+func (s *uploadServiceImpl) quarantineFile(ctx context.Context, file *models.File, report *scanning.Report) error {
+	key := fileStorageKey(file.ID, file.Name)
+	if err := s.moveObject(ctx, key, quarantineStorageKey(file.ID, file.Name), file.Type); err != nil {
+		return fmt.Errorf("failed to quarantine file: %w", err)
+	}
+
+	_, err := s.db.ExecContext(ctx,
+		"UPDATE files SET scan_status = ?, scan_reason = ?, scanned_at = ? WHERE id = ?",
+		FileScanQuarantined, report.Reason, report.ScannedAt, file.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update scan status: %w", err)
+	}
+	return nil
+}
+
+// scanUploadResult فحص الملف فور رفعه؛ تعذر الفحص يبقيه معلقاً للمهمة الدورية
+func (s *uploadServiceImpl) scanUploadResult(ctx context.Context, fileID string, uploaded time.Time) (*UploadResult, error) {
+	file, err := s.ScanFile(ctx, fileID)
+	if err != nil {
+		if !errors.Is(err, scanning.ErrScanUnavailable) {
+			return nil, err
+		}
+		if file, err = s.GetFile(ctx, fileID); err != nil {
+			return nil, err
+		}
+	}
+
+	return &UploadResult{
+		ID:         file.ID,
+		URL:        file.URL,
+		FileName:   file.Name,
+		FileType:   file.Type,
+		FileSize:   file.Size,
+		Uploaded:   uploaded,
+		ScanStatus: file.ScanStatus,
+		ScanReason: file.ScanReason,
+	}, nil
+}
+
+// applyScanState ضبط حالة الفحص وإخفاء رابط الملف غير النظيف
+func applyScanState(file *models.File) {
+	if file.ScanStatus == "" {
+		file.ScanStatus = FileScanPending
+	}
+	if file.ScanStatus != FileScanClean {
+		file.URL = ""
+	}
+}
+
+// checkFileServable الملفات المعلقة أو المحجورة لا تُخدم أبداً
+func checkFileServable(file *models.File) error {
+	switch file.ScanStatus {
+	case FileScanClean:
+		return nil
+	case FileScanQuarantined:
+		return ErrFileQuarantined
+	default:
+		return ErrFileNotScanned
+	}
+}
+
+// fileObjectKey مفتاح الكائن الحالي للملف حسب حالته (المحجور يبقى في الحجر حتى بعد حذفه)
+func fileObjectKey(file *models.File) string {
+	switch {
+	case file.ScanStatus == FileScanQuarantined:
+		return quarantineStorageKey(file.ID, file.Name)
+	case file.DeletedAt != nil:
+		return trashStorageKey(file.ID, file.Name)
+	}
+	return fileStorageKey(file.ID, file.Name)
+}
+
+// quarantineStorageKey مفتاح الحجر خارج مسار files/ العام
+func quarantineStorageKey(fileID, fileName string) string {
+	return "quarantine/" + fileID + "/" + storage.SanitizeFileName(fileName)
+}
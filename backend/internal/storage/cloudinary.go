@@ -0,0 +1,203 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cloudinary/cloudinary-go/v2"
+	"github.com/cloudinary/cloudinary-go/v2/api/uploader"
+)
+
+// CloudinaryStorage تخزين الكائنات على Cloudinary
+type CloudinaryStorage struct {
+	cld       *cloudinary.Cloudinary
+	cloudName string
+	client    *http.Client
+}
+
+// NewCloudinaryStorage إنشاء تخزين Cloudinary من رابط الاتصال
+// cloudinary://<api_key>:<api_secret>@<cloud_name>
+func NewCloudinaryStorage(cloudinaryURL string) (*CloudinaryStorage, error) {
+	if cloudinaryURL == "" {
+		return nil, fmt.Errorf("%w: cloudinary url is required", ErrNotConfigured)
+	}
+
+	cld, err := cloudinary.NewFromURL(cloudinaryURL)
+	if err != nil {
+		return nil, fmt.Errorf("storage: failed to init cloudinary: %w", err)
+	}
+	cld.Config.URL.Secure = true
+
+	return &CloudinaryStorage{
+		cld:       cld,
+		cloudName: cld.Config.Cloud.CloudName,
+		client:    &http.Client{Timeout: 5 * time.Minute},
+	}, nil
+}
+
+// Name اسم الـ backend
+func (s *CloudinaryStorage) Name() string {
+	return DriverCloudinary
+}
+
+// Put يرفع الكائن إلى Cloudinary بمعرف عام مشتق من المفتاح
+func (s *CloudinaryStorage) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) (*Object, error) {
+	key, err := CleanKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	resourceType, publicID := cloudinaryAsset(key)
+	overwrite := true
+
+	result, err := s.cld.Upload.Upload(ctx, r, uploader.UploadParams{
+		PublicID:     publicID,
+		ResourceType: resourceType,
+		Overwrite:    &overwrite,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("storage: cloudinary upload failed: %w", err)
+	}
+	if result.Error.Message != "" {
+		return nil, fmt.Errorf("storage: cloudinary upload failed: %s", result.Error.Message)
+	}
+
+	return &Object{
+		Key:          key,
+		URL:          s.URL(key),
+		Size:         int64(result.Bytes),
+		ContentType:  contentType,
+		ETag:         result.Etag,
+		LastModified: result.CreatedAt,
+	}, nil
+}
+
+// Get يحمل الكائن من رابط التسليم
+func (s *CloudinaryStorage) Get(ctx context.Context, key string) (io.ReadCloser, *Object, error) {
+	key, err := CleanKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	resp, err := s.fetch(ctx, http.MethodGet, key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return resp.Body, s.objectFromHeader(key, resp.Header), nil
+}
+
+// Stat معلومات الكائن عبر طلب HEAD لرابط التسليم
+func (s *CloudinaryStorage) Stat(ctx context.Context, key string) (*Object, error) {
+	key, err := CleanKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.fetch(ctx, http.MethodHead, key)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+
+	return s.objectFromHeader(key, resp.Header), nil
+}
+
+// Delete يحذف الكائن من Cloudinary
+func (s *CloudinaryStorage) Delete(ctx context.Context, key string) error {
+	key, err := CleanKey(key)
+	if err != nil {
+		return err
+	}
+
+	resourceType, publicID := cloudinaryAsset(key)
+	invalidate := true
+
+	result, err := s.cld.Upload.Destroy(ctx, uploader.DestroyParams{
+		PublicID:     publicID,
+		ResourceType: resourceType,
+		Invalidate:   &invalidate,
+	})
+	if err != nil {
+		return fmt.Errorf("storage: cloudinary delete failed: %w", err)
+	}
+	if result.Error.Message != "" {
+		return fmt.Errorf("storage: cloudinary delete failed: %s", result.Error.Message)
+	}
+
+	// "not found" ليس خطأ في الحذف
+	return nil
+}
+
+// URL رابط التسليم العام للكائن
+func (s *CloudinaryStorage) URL(key string) string {
+	resourceType, publicID := cloudinaryAsset(key)
+	if resourceType != "raw" {
+		publicID += path.Ext(key)
+	}
+	return fmt.Sprintf("https://res.cloudinary.com/%s/%s/upload/%s", s.cloudName, resourceType, escapePath(publicID))
+}
+
+// fetch طلب لرابط التسليم مع تحويل 404 إلى ErrNotFound
+func (s *CloudinaryStorage) fetch(ctx context.Context, method, key string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, s.URL(key), nil)
+	if err != nil {
+		return nil, fmt.Errorf("storage: failed to build cloudinary request: %w", err)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("storage: cloudinary %s %s failed: %w", method, key, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusNotFound {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("storage: cloudinary %s %s failed with status %d", method, key, resp.StatusCode)
+	}
+
+	return resp, nil
+}
+
+// objectFromHeader بناء معلومات الكائن من ترويسات الاستجابة
+func (s *CloudinaryStorage) objectFromHeader(key string, h http.Header) *Object {
+	obj := &Object{
+		Key:         key,
+		URL:         s.URL(key),
+		ContentType: h.Get("Content-Type"),
+		ETag:        strings.Trim(h.Get("ETag"), `"`),
+	}
+	if size, err := strconv.ParseInt(h.Get("Content-Length"), 10, 64); err == nil {
+		obj.Size = size
+	}
+	if t, err := http.ParseTime(h.Get("Last-Modified")); err == nil {
+		obj.LastModified = t
+	}
+	return obj
+}
+
+// cloudinaryAsset يحدد نوع المورد والمعرف العام من المفتاح.
+// Cloudinary يضيف الامتداد تلقائياً للصور والفيديو، لذا يُحذف منها ويُبقى للملفات الخام
+func cloudinaryAsset(key string) (string, string) {
+	ext := strings.ToLower(path.Ext(key))
+
+	var resourceType string
+	switch ext {
+	case ".jpg", ".jpeg", ".png", ".gif", ".webp", ".bmp", ".svg", ".avif":
+		resourceType = "image"
+	case ".mp4", ".mov", ".avi", ".webm", ".mkv", ".mp3", ".wav", ".ogg":
+		resourceType = "video"
+	default:
+		return "raw", key
+	}
+
+	return resourceType, strings.TrimSuffix(key, path.Ext(key))
+}
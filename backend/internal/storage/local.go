@@ -0,0 +1,190 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// LocalStorage تخزين الملفات على القرص المحلي
+type LocalStorage struct {
+	root    string
+	baseURL string
+}
+
+// NewLocalStorage إنشاء تخزين محلي جديد في المجلد المحدد
+func NewLocalStorage(root, baseURL string) (*LocalStorage, error) {
+	if root == "" {
+		root = "./uploads"
+	}
+
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return nil, fmt.Errorf("storage: failed to resolve local root: %w", err)
+	}
+
+	if err := os.MkdirAll(absRoot, 0o755); err != nil {
+		return nil, fmt.Errorf("storage: failed to create local root: %w", err)
+	}
+
+	return &LocalStorage{
+		root:    absRoot,
+		baseURL: strings.TrimRight(baseURL, "/"),
+	}, nil
+}
+
+// Name اسم الـ backend
+func (s *LocalStorage) Name() string {
+	return DriverLocal
+}
+
+// Root المجلد الجذري للتخزين
+func (s *LocalStorage) Root() string {
+	return s.root
+}
+
+// Put يكتب الملف بشكل ذري (ملف مؤقت ثم إعادة تسمية)
+func (s *LocalStorage) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) (*Object, error) {
+	fullPath, key, err := s.resolve(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0o755); err != nil {
+		return nil, fmt.Errorf("storage: failed to create directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(fullPath), ".upload-*")
+	if err != nil {
+		return nil, fmt.Errorf("storage: failed to create temp file: %w", err)
+	}
+	tmpName := tmp.Name()
+	defer os.Remove(tmpName)
+
+	written, err := io.Copy(tmp, &contextReader{ctx: ctx, r: r})
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("storage: failed to write object: %w", err)
+	}
+
+	if size >= 0 && written != size {
+		return nil, fmt.Errorf("storage: size mismatch for %s: expected %d, wrote %d", key, size, written)
+	}
+
+	if err := os.Rename(tmpName, fullPath); err != nil {
+		return nil, fmt.Errorf("storage: failed to commit object: %w", err)
+	}
+
+	return s.Stat(ctx, key)
+}
+
+// Get يفتح الملف للقراءة
+func (s *LocalStorage) Get(ctx context.Context, key string) (io.ReadCloser, *Object, error) {
+	obj, err := s.Stat(ctx, key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	fullPath, _, _ := s.resolve(key)
+	f, err := os.Open(fullPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil, ErrNotFound
+		}
+		return nil, nil, fmt.Errorf("storage: failed to open object: %w", err)
+	}
+
+	return f, obj, nil
+}
+
+// Stat معلومات الملف
+func (s *LocalStorage) Stat(ctx context.Context, key string) (*Object, error) {
+	fullPath, key, err := s.resolve(key)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(fullPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("storage: failed to stat object: %w", err)
+	}
+	if info.IsDir() {
+		return nil, ErrNotFound
+	}
+
+	return &Object{
+		Key:          key,
+		URL:          s.URL(key),
+		Size:         info.Size(),
+		ContentType:  mime.TypeByExtension(path.Ext(key)),
+		ETag:         fmt.Sprintf("%x-%x", info.ModTime().UnixNano(), info.Size()),
+		LastModified: info.ModTime(),
+	}, nil
+}
+
+// Delete يحذف الملف والمجلدات الفارغة التي تحتويه
+func (s *LocalStorage) Delete(ctx context.Context, key string) error {
+	fullPath, _, err := s.resolve(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(fullPath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("storage: failed to delete object: %w", err)
+	}
+
+	// إزالة المجلدات الفارغة حتى الجذر
+	for dir := filepath.Dir(fullPath); dir != s.root && strings.HasPrefix(dir, s.root); dir = filepath.Dir(dir) {
+		if err := os.Remove(dir); err != nil {
+			break
+		}
+	}
+
+	return nil
+}
+
+// URL الرابط العام للملف
+func (s *LocalStorage) URL(key string) string {
+	if s.baseURL == "" {
+		return "/" + key
+	}
+	return s.baseURL + "/" + key
+}
+
+// resolve يحول المفتاح إلى مسار داخل الجذر
+func (s *LocalStorage) resolve(key string) (string, string, error) {
+	key, err := CleanKey(key)
+	if err != nil {
+		return "", "", err
+	}
+
+	fullPath := filepath.Join(s.root, filepath.FromSlash(key))
+	if !strings.HasPrefix(fullPath, s.root+string(filepath.Separator)) {
+		return "", "", ErrInvalidKey
+	}
+
+	return fullPath, key, nil
+}
+
+// contextReader قارئ يتوقف عند إلغاء السياق
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (cr *contextReader) Read(p []byte) (int, error) {
+	if err := cr.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return cr.r.Read(p)
+}
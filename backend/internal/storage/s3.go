@@ -0,0 +1,484 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	s3Algorithm     = "AWS4-HMAC-SHA256"
+	s3Service       = "s3"
+	s3UnsignedBody  = "UNSIGNED-PAYLOAD"
+	s3TimeFormat    = "20060102T150405Z"
+	s3ShortDate     = "20060102"
+	s3DefaultRegion = "us-east-1"
+)
+
+// S3Options إعدادات التخزين المتوافق مع S3
+type S3Options struct {
+	Endpoint   string // فارغ لـ AWS، أو رابط MinIO / R2
+	Bucket     string
+	Region     string
+	AccessKey  string
+	SecretKey  string
+	PathStyle  bool
+	PublicURL  string // رابط عام بديل (CDN) للكائنات
+	HTTPClient *http.Client
+}
+
+// S3Storage تخزين متوافق مع S3 موقع بـ AWS Signature V4
+type S3Storage struct {
+	endpoint  *url.URL
+	bucket    string
+	region    string
+	accessKey string
+	secretKey string
+	pathStyle bool
+	publicURL string
+	client    *http.Client
+	now       func() time.Time
+}
+
+// s3Error استجابة خطأ S3
+type s3Error struct {
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+// NewS3Storage إنشاء تخزين S3 جديد
+func NewS3Storage(opts S3Options) (*S3Storage, error) {
+	if opts.Bucket == "" || opts.AccessKey == "" || opts.SecretKey == "" {
+		return nil, fmt.Errorf("%w: s3 bucket and credentials are required", ErrNotConfigured)
+	}
+
+	if opts.Region == "" {
+		opts.Region = s3DefaultRegion
+	}
+
+	endpoint := opts.Endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", opts.Region)
+	}
+
+	u, err := url.Parse(strings.TrimRight(endpoint, "/"))
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("storage: invalid s3 endpoint %q", endpoint)
+	}
+
+	client := opts.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 5 * time.Minute}
+	}
+
+	return &S3Storage{
+		endpoint:  u,
+		bucket:    opts.Bucket,
+		region:    opts.Region,
+		accessKey: opts.AccessKey,
+		secretKey: opts.SecretKey,
+		pathStyle: opts.PathStyle,
+		publicURL: strings.TrimRight(opts.PublicURL, "/"),
+		client:    client,
+		now:       time.Now,
+	}, nil
+}
+
+// Name اسم الـ backend
+func (s *S3Storage) Name() string {
+	return DriverS3
+}
+
+// Put يرفع الكائن بطلب PUT واحد
+func (s *S3Storage) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) (*Object, error) {
+	key, err := CleanKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	header := http.Header{}
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+
+	resp, err := s.do(ctx, http.MethodPut, key, r, size, header)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, s.responseError("put", key, resp)
+	}
+
+	return &Object{
+		Key:          key,
+		URL:          s.URL(key),
+		Size:         size,
+		ContentType:  contentType,
+		ETag:         strings.Trim(resp.Header.Get("ETag"), `"`),
+		LastModified: s.now().UTC(),
+	}, nil
+}
+
+// Get يحمل الكائن
+func (s *S3Storage) Get(ctx context.Context, key string) (io.ReadCloser, *Object, error) {
+	key, err := CleanKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	resp, err := s.do(ctx, http.MethodGet, key, nil, 0, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, nil, s.responseError("get", key, resp)
+	}
+
+	return resp.Body, s.objectFromHeader(key, resp.Header), nil
+}
+
+// Stat معلومات الكائن عبر طلب HEAD
+func (s *S3Storage) Stat(ctx context.Context, key string) (*Object, error) {
+	key, err := CleanKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.do(ctx, http.MethodHead, key, nil, 0, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, s.responseError("stat", key, resp)
+	}
+
+	return s.objectFromHeader(key, resp.Header), nil
+}
+
+// Delete يحذف الكائن
+func (s *S3Storage) Delete(ctx context.Context, key string) error {
+	key, err := CleanKey(key)
+	if err != nil {
+		return err
+	}
+
+	resp, err := s.do(ctx, http.MethodDelete, key, nil, 0, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusNoContent, http.StatusNotFound:
+		return nil
+	default:
+		return s.responseError("delete", key, resp)
+	}
+}
+
+// PresignPut يولد رابط PUT موقعاً (SigV4 في الاستعلام).
+// الحجم ونوع المحتوى من الترويسات الموقعة، فأي اختلاف فيهما يرفضه S3
+func (s *S3Storage) PresignPut(ctx context.Context, key, contentType string, size int64, expires time.Duration) (*PresignedRequest, error) {
+	key, err := CleanKey(key)
+	if err != nil {
+		return nil, err
+	}
+	if size <= 0 {
+		return nil, fmt.Errorf("storage: presigned upload requires a positive size")
+	}
+	if expires <= 0 || expires > 7*24*time.Hour {
+		return nil, fmt.Errorf("storage: invalid presign expiry %s", expires)
+	}
+
+	t := s.now().UTC()
+	u := s.objectURL(key)
+	scope := s.scope(t)
+
+	headers := map[string]string{
+		"content-length": strconv.FormatInt(size, 10),
+		"host":           u.Host,
+	}
+	if contentType != "" {
+		headers["content-type"] = contentType
+	}
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	query := url.Values{}
+	query.Set("X-Amz-Algorithm", s3Algorithm)
+	query.Set("X-Amz-Credential", s.accessKey+"/"+scope)
+	query.Set("X-Amz-Date", t.Format(s3TimeFormat))
+	query.Set("X-Amz-Expires", strconv.Itoa(int(expires.Seconds())))
+	query.Set("X-Amz-SignedHeaders", signedHeaders)
+
+	canonicalRequest := strings.Join([]string{
+		http.MethodPut,
+		canonicalURI(u),
+		canonicalQuery(query),
+		canonicalHeaders.String(),
+		signedHeaders,
+		s3UnsignedBody,
+	}, "\n")
+
+	query.Set("X-Amz-Signature", s.signature(t, stringToSign(t.Format(s3TimeFormat), scope, canonicalRequest)))
+	u.RawQuery = canonicalQuery(query)
+
+	reqHeaders := map[string]string{}
+	if contentType != "" {
+		reqHeaders["Content-Type"] = contentType
+	}
+
+	return &PresignedRequest{
+		Method:    http.MethodPut,
+		URL:       u.String(),
+		Headers:   reqHeaders,
+		ExpiresAt: t.Add(expires),
+	}, nil
+}
+
+// URL الرابط العام للكائن
+func (s *S3Storage) URL(key string) string {
+	if s.publicURL != "" {
+		return s.publicURL + "/" + escapePath(key)
+	}
+	return s.objectURL(key).String()
+}
+
+// ================================
+// دوال داخلية
+// ================================
+
+// objectURL رابط الكائن حسب نمط العنونة (path-style أو virtual-hosted)
+func (s *S3Storage) objectURL(key string) *url.URL {
+	u := *s.endpoint
+	if s.pathStyle {
+		u.Path = s.endpoint.Path + "/" + s.bucket + "/" + key
+	} else {
+		u.Host = s.bucket + "." + s.endpoint.Host
+		u.Path = s.endpoint.Path + "/" + key
+	}
+	u.RawPath = escapePath(u.Path)
+	return &u
+}
+
+// do ينفذ طلباً موقعاً
+func (s *S3Storage) do(ctx context.Context, method, key string, body io.Reader, size int64, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, s.objectURL(key).String(), body)
+	if err != nil {
+		return nil, fmt.Errorf("storage: failed to build s3 request: %w", err)
+	}
+
+	for k, values := range header {
+		for _, v := range values {
+			req.Header.Add(k, v)
+		}
+	}
+
+	if body != nil && size >= 0 {
+		req.ContentLength = size
+	}
+
+	s.signRequest(req, s.now().UTC())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("storage: s3 %s %s failed: %w", method, key, err)
+	}
+	return resp, nil
+}
+
+// signRequest يوقع الطلب في الترويسة (Authorization) بـ SigV4
+func (s *S3Storage) signRequest(req *http.Request, t time.Time) {
+	amzDate := t.Format(s3TimeFormat)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", s3UnsignedBody)
+	req.Host = req.URL.Host
+
+	signedHeaders, canonicalHeaders := canonicalizeHeaders(req)
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		canonicalURI(req.URL),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders,
+		signedHeaders,
+		s3UnsignedBody,
+	}, "\n")
+
+	scope := s.scope(t)
+	signature := s.signature(t, stringToSign(amzDate, scope, canonicalRequest))
+
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s3Algorithm, s.accessKey, scope, signedHeaders, signature))
+}
+
+// scope نطاق بيانات الاعتماد
+func (s *S3Storage) scope(t time.Time) string {
+	return strings.Join([]string{t.Format(s3ShortDate), s.region, s3Service, "aws4_request"}, "/")
+}
+
+// signature توقيع النص بمفتاح مشتق من التاريخ والمنطقة
+func (s *S3Storage) signature(t time.Time, toSign string) string {
+	key := deriveSigningKey(s.secretKey, t.Format(s3ShortDate), s.region, s3Service)
+	return hex.EncodeToString(hmacSHA256(key, toSign))
+}
+
+// objectFromHeader بناء معلومات الكائن من ترويسات الاستجابة
+func (s *S3Storage) objectFromHeader(key string, h http.Header) *Object {
+	obj := &Object{
+		Key:         key,
+		URL:         s.URL(key),
+		ContentType: h.Get("Content-Type"),
+		ETag:        strings.Trim(h.Get("ETag"), `"`),
+	}
+	if size, err := strconv.ParseInt(h.Get("Content-Length"), 10, 64); err == nil {
+		obj.Size = size
+	}
+	if t, err := http.ParseTime(h.Get("Last-Modified")); err == nil {
+		obj.LastModified = t
+	}
+	return obj
+}
+
+// responseError تحويل استجابة الخطأ إلى error
+func (s *S3Storage) responseError(op, key string, resp *http.Response) error {
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+
+	var apiErr s3Error
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if len(body) > 0 && xml.Unmarshal(body, &apiErr) == nil && apiErr.Code != "" {
+		if apiErr.Code == "NoSuchKey" {
+			return ErrNotFound
+		}
+		return fmt.Errorf("storage: s3 %s %s failed: %s: %s", op, key, apiErr.Code, apiErr.Message)
+	}
+
+	return fmt.Errorf("storage: s3 %s %s failed with status %d", op, key, resp.StatusCode)
+}
+
+// ================================
+// دوال SigV4 المساعدة
+// ================================
+
+func deriveSigningKey(secret, date, region, service string) []byte {
+	kDate := hmacSHA256([]byte("AWS4"+secret), date)
+	kRegion := hmacSHA256(kDate, region)
+	kService := hmacSHA256(kRegion, service)
+	return hmacSHA256(kService, "aws4_request")
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func stringToSign(amzDate, scope, canonicalRequest string) string {
+	hash := sha256.Sum256([]byte(canonicalRequest))
+	return strings.Join([]string{s3Algorithm, amzDate, scope, hex.EncodeToString(hash[:])}, "\n")
+}
+
+func canonicalizeHeaders(req *http.Request) (string, string) {
+	headers := map[string]string{"host": req.Host}
+	for k, values := range req.Header {
+		lk := strings.ToLower(k)
+		if lk == "authorization" || lk == "user-agent" {
+			continue
+		}
+		trimmed := make([]string, len(values))
+		for i, v := range values {
+			trimmed[i] = strings.Join(strings.Fields(v), " ")
+		}
+		headers[lk] = strings.Join(trimmed, ",")
+	}
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		b.WriteString(name)
+		b.WriteByte(':')
+		b.WriteString(headers[name])
+		b.WriteByte('\n')
+	}
+
+	return strings.Join(names, ";"), b.String()
+}
+
+func canonicalURI(u *url.URL) string {
+	if u.Path == "" {
+		return "/"
+	}
+	return escapePath(u.Path)
+}
+
+func canonicalQuery(values url.Values) string {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var parts []string
+	for _, k := range keys {
+		vs := append([]string(nil), values[k]...)
+		sort.Strings(vs)
+		for _, v := range vs {
+			parts = append(parts, uriEncode(k, true)+"="+uriEncode(v, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// escapePath ترميز المسار حسب قواعد AWS مع الإبقاء على "/"
+func escapePath(p string) string {
+	return uriEncode(p, false)
+}
+
+func uriEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c >= 'A' && c <= 'Z', c >= 'a' && c <= 'z', c >= '0' && c <= '9',
+			c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
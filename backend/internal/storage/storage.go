@@ -0,0 +1,156 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/nawthtech/nawthtech/backend/internal/config"
+)
+
+// ================================
+// أنواع التخزين
+// ================================
+
+const (
+	DriverLocal      = "local"
+	DriverS3         = "s3"
+	DriverCloudinary = "cloudinary"
+)
+
+// ================================
+// الأخطاء
+// ================================
+
+var (
+	ErrNotFound      = errors.New("storage: object not found")
+	ErrInvalidKey    = errors.New("storage: invalid object key")
+	ErrNotConfigured = errors.New("storage: backend not configured")
+)
+
+// ================================
+// هياكل البيانات
+// ================================
+
+// Object معلومات كائن مخزن
+type Object struct {
+	Key          string    `json:"key"`
+	URL          string    `json:"url"`
+	Size         int64     `json:"size"`
+	ContentType  string    `json:"content_type,omitempty"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified time.Time `json:"last_modified"`
+}
+
+// ================================
+// الواجهة
+// ================================
+
+// Storage واجهة تخزين الكائنات (قرص محلي، S3، Cloudinary)
+type Storage interface {
+	// Put يخزن محتوى القارئ تحت المفتاح المحدد
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) (*Object, error)
+	// Get يفتح الكائن للقراءة، ويجب على المستدعي إغلاق القارئ
+	Get(ctx context.Context, key string) (io.ReadCloser, *Object, error)
+	// Stat يعيد معلومات الكائن دون قراءة محتواه
+	Stat(ctx context.Context, key string) (*Object, error)
+	// Delete يحذف الكائن، ولا يعتبر غياب الكائن خطأ
+	Delete(ctx context.Context, key string) error
+	// URL يعيد الرابط العام للكائن
+	URL(key string) string
+	// Name اسم الـ backend
+	Name() string
+}
+
+// PresignedRequest طلب موقع يرسله العميل مباشرة إلى التخزين
+type PresignedRequest struct {
+	Method    string            `json:"method"`
+	URL       string            `json:"url"`
+	Headers   map[string]string `json:"headers,omitempty"`
+	ExpiresAt time.Time         `json:"expires_at"`
+}
+
+// Presigner واجهة اختيارية للـ backends التي تدعم الرفع المباشر بروابط موقعة
+type Presigner interface {
+	// PresignPut يولد رابط PUT موقعاً مقيداً بالحجم ونوع المحتوى
+	PresignPut(ctx context.Context, key, contentType string, size int64, expires time.Duration) (*PresignedRequest, error)
+}
+
+// ================================
+// دوال الإنشاء
+// ================================
+
+// New ينشئ backend التخزين حسب الإعدادات
+func New(cfg *config.Config) (Storage, error) {
+	if cfg == nil {
+		return NewLocalStorage("./uploads", "")
+	}
+
+	switch driver := cfg.GetStorageDriver(); driver {
+	case DriverLocal:
+		return NewLocalStorage(cfg.Upload.Path, cfg.Upload.PublicURL)
+	case DriverS3:
+		return NewS3Storage(S3Options{
+			Endpoint:  cfg.Upload.S3Endpoint,
+			Bucket:    cfg.Upload.S3Bucket,
+			Region:    cfg.Upload.S3Region,
+			AccessKey: cfg.Upload.S3AccessKey,
+			SecretKey: cfg.Upload.S3SecretKey,
+			PathStyle: cfg.Upload.S3PathStyle,
+			PublicURL: cfg.Upload.S3PublicURL,
+		})
+	case DriverCloudinary:
+		return NewCloudinaryStorage(cfg.Upload.CloudinaryURL)
+	default:
+		return nil, fmt.Errorf("storage: unknown driver %q", driver)
+	}
+}
+
+// ================================
+// دوال مساعدة
+// ================================
+
+// CleanKey ينظف مفتاح الكائن ويرفض المفاتيح التي تخرج عن الجذر
+func CleanKey(key string) (string, error) {
+	key = strings.ReplaceAll(key, "\\", "/")
+	if key == "" || strings.HasPrefix(key, "/") {
+		return "", ErrInvalidKey
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == ".." {
+			return "", ErrInvalidKey
+		}
+	}
+	cleaned := path.Clean(key)
+	if cleaned == "." {
+		return "", ErrInvalidKey
+	}
+	return cleaned, nil
+}
+
+// SanitizeFileName يحول اسم الملف إلى صيغة آمنة للاستخدام في المفاتيح والروابط
+func SanitizeFileName(name string) string {
+	name = path.Base(strings.ReplaceAll(name, "\\", "/"))
+
+	var b strings.Builder
+	for _, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case r == '.', r == '-', r == '_':
+			b.WriteRune(r)
+		default:
+			b.WriteRune('_')
+		}
+	}
+
+	cleaned := strings.Trim(b.String(), "._")
+	if cleaned == "" {
+		return "file"
+	}
+	return cleaned
+}
@@ -0,0 +1,314 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nawthtech/nawthtech/backend/internal/config"
+)
+
+// fakeS3 خادم S3 مبسط في الذاكرة (بديل MinIO للاختبارات)
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	types   map[string]string
+}
+
+func newFakeS3() *fakeS3 {
+	return &fakeS3{objects: map[string][]byte{}, types: map[string]string{}}
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("X-Amz-Signature") != "" {
+		if !verifyPresigned(r, "secret") {
+			w.WriteHeader(http.StatusForbidden)
+			io.WriteString(w, `<Error><Code>SignatureDoesNotMatch</Code></Error>`)
+			return
+		}
+	} else if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/") {
+		w.WriteHeader(http.StatusForbidden)
+		io.WriteString(w, `<Error><Code>AccessDenied</Code><Message>missing signature</Message></Error>`)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	key := r.URL.Path
+	switch r.Method {
+	case http.MethodPut:
+		body, _ := io.ReadAll(r.Body)
+		f.objects[key] = body
+		f.types[key] = r.Header.Get("Content-Type")
+		w.Header().Set("ETag", `"etag-1"`)
+	case http.MethodGet, http.MethodHead:
+		body, ok := f.objects[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			io.WriteString(w, `<Error><Code>NoSuchKey</Code></Error>`)
+			return
+		}
+		w.Header().Set("Content-Type", f.types[key])
+		w.Header().Set("ETag", `"etag-1"`)
+		w.Write(body)
+	case http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// verifyPresigned يعيد حساب توقيع الرابط من الطلب كما وصل إلى الخادم
+func verifyPresigned(r *http.Request, secret string) bool {
+	query := r.URL.Query()
+	signature := query.Get("X-Amz-Signature")
+	query.Del("X-Amz-Signature")
+
+	t, err := time.Parse(s3TimeFormat, query.Get("X-Amz-Date"))
+	if err != nil {
+		return false
+	}
+	expires, _ := strconv.Atoi(query.Get("X-Amz-Expires"))
+	if time.Now().After(t.Add(time.Duration(expires) * time.Second)) {
+		return false
+	}
+
+	signed := strings.Split(query.Get("X-Amz-SignedHeaders"), ";")
+	var canonical strings.Builder
+	for _, name := range signed {
+		value := r.Header.Get(name)
+		switch name {
+		case "host":
+			value = r.Host
+		case "content-length":
+			value = strconv.FormatInt(r.ContentLength, 10)
+		}
+		canonical.WriteString(name + ":" + value + "\n")
+	}
+
+	credential := strings.SplitN(query.Get("X-Amz-Credential"), "/", 2)
+	scope := credential[1]
+	parts := strings.Split(scope, "/")
+
+	canonicalRequest := strings.Join([]string{
+		r.Method,
+		escapePath(r.URL.Path),
+		canonicalQuery(query),
+		canonical.String(),
+		query.Get("X-Amz-SignedHeaders"),
+		s3UnsignedBody,
+	}, "\n")
+
+	key := deriveSigningKey(secret, parts[0], parts[1], parts[2])
+	want := hex.EncodeToString(hmacSHA256(key, stringToSign(query.Get("X-Amz-Date"), scope, canonicalRequest)))
+	return want == signature
+}
+
+func TestLocalStorageRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewLocalStorage(t.TempDir(), "https://api.example.com")
+	if err != nil {
+		t.Fatalf("NewLocalStorage: %v", err)
+	}
+
+	obj, err := store.Put(ctx, "files/f1/hello.txt", strings.NewReader("hello"), 5, "text/plain")
+	if err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if obj.Size != 5 || obj.URL != "https://api.example.com/files/f1/hello.txt" {
+		t.Errorf("unexpected object: %+v", obj)
+	}
+
+	rc, _, err := store.Get(ctx, "files/f1/hello.txt")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	data, _ := io.ReadAll(rc)
+	rc.Close()
+	if string(data) != "hello" {
+		t.Errorf("expected hello, got %q", data)
+	}
+
+	if err := store.Delete(ctx, "files/f1/hello.txt"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := store.Stat(ctx, "files/f1/hello.txt"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound after delete, got %v", err)
+	}
+}
+
+func TestLocalStorageRejectsTraversal(t *testing.T) {
+	store, err := NewLocalStorage(t.TempDir(), "")
+	if err != nil {
+		t.Fatalf("NewLocalStorage: %v", err)
+	}
+
+	for _, key := range []string{"../escape.txt", "/etc/passwd", "a/../../b", ""} {
+		if _, err := store.Put(context.Background(), key, strings.NewReader("x"), 1, ""); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("key %q: expected ErrInvalidKey, got %v", key, err)
+		}
+	}
+}
+
+func TestS3StorageAgainstFakeServer(t *testing.T) {
+	server := httptest.NewServer(newFakeS3())
+	defer server.Close()
+
+	store, err := NewS3Storage(S3Options{
+		Endpoint:  server.URL,
+		Bucket:    "uploads",
+		AccessKey: "AKID",
+		SecretKey: "secret",
+		PathStyle: true,
+	})
+	if err != nil {
+		t.Fatalf("NewS3Storage: %v", err)
+	}
+
+	ctx := context.Background()
+	payload := []byte("image-bytes")
+
+	obj, err := store.Put(ctx, "files/f1/photo one.png", bytes.NewReader(payload), int64(len(payload)), "image/png")
+	if err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if obj.ETag != "etag-1" {
+		t.Errorf("expected etag-1, got %q", obj.ETag)
+	}
+	if want := server.URL + "/uploads/files/f1/photo%20one.png"; obj.URL != want {
+		t.Errorf("expected url %q, got %q", want, obj.URL)
+	}
+
+	stat, err := store.Stat(ctx, "files/f1/photo one.png")
+	if err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+	if stat.Size != int64(len(payload)) || stat.ContentType != "image/png" {
+		t.Errorf("unexpected stat: %+v", stat)
+	}
+
+	rc, _, err := store.Get(ctx, "files/f1/photo one.png")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	data, _ := io.ReadAll(rc)
+	rc.Close()
+	if !bytes.Equal(data, payload) {
+		t.Errorf("payload mismatch: %q", data)
+	}
+
+	if err := store.Delete(ctx, "files/f1/photo one.png"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, _, err := store.Get(ctx, "files/f1/photo one.png"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestNewS3UsesPublicURL(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Upload.Storage = DriverS3
+	cfg.Upload.S3Endpoint = "http://localhost:9000"
+	cfg.Upload.S3Bucket = "uploads"
+	cfg.Upload.S3AccessKey = "AKID"
+	cfg.Upload.S3SecretKey = "secret"
+	cfg.Upload.S3PathStyle = true
+	cfg.Upload.PublicURL = "https://api.example.com"
+	cfg.Upload.S3PublicURL = "https://cdn.example.com/"
+
+	store, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if got, want := store.URL("files/f1/a b.png"), "https://cdn.example.com/files/f1/a%20b.png"; got != want {
+		t.Errorf("expected url %q, got %q", want, got)
+	}
+
+	cfg.Upload.S3PublicURL = ""
+	store, err = New(cfg)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if got, want := store.URL("files/f1/a.png"), "http://localhost:9000/uploads/files/f1/a.png"; got != want {
+		t.Errorf("expected bucket url %q, got %q", want, got)
+	}
+}
+
+func TestS3PresignPut(t *testing.T) {
+	server := httptest.NewServer(newFakeS3())
+	defer server.Close()
+
+	store, err := NewS3Storage(S3Options{
+		Endpoint:  server.URL,
+		Bucket:    "uploads",
+		AccessKey: "AKID",
+		SecretKey: "secret",
+		PathStyle: true,
+	})
+	if err != nil {
+		t.Fatalf("NewS3Storage: %v", err)
+	}
+
+	presigned, err := store.PresignPut(context.Background(), "files/f2/a.png", "image/png", 4, time.Minute)
+	if err != nil {
+		t.Fatalf("PresignPut: %v", err)
+	}
+
+	send := func(body, contentType string) int {
+		req, _ := http.NewRequest(presigned.Method, presigned.URL, strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("presigned request: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if code := send("toolarge", "image/png"); code != http.StatusForbidden {
+		t.Errorf("expected size mismatch to be rejected, got %d", code)
+	}
+	if code := send("abcd", "text/html"); code != http.StatusForbidden {
+		t.Errorf("expected type mismatch to be rejected, got %d", code)
+	}
+	if code := send("abcd", "image/png"); code != http.StatusOK {
+		t.Fatalf("expected presigned upload to succeed, got %d", code)
+	}
+
+	obj, err := store.Stat(context.Background(), "files/f2/a.png")
+	if err != nil || obj.Size != 4 {
+		t.Errorf("unexpected stat after presigned upload: %+v, %v", obj, err)
+	}
+}
+
+func TestS3SigningKey(t *testing.T) {
+	// المثال الرسمي من توثيق AWS SigV4
+	key := deriveSigningKey("wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY", "20150830", "us-east-1", "iam")
+	want := "c4afb1cc5771d871763a393e44b703571b55cc28424d1a5e86da6ed3c154a4b9"
+	if got := hex.EncodeToString(key); got != want {
+		t.Errorf("signing key mismatch: got %s", got)
+	}
+}
+
+func TestCloudinaryAsset(t *testing.T) {
+	cases := map[string][2]string{
+		"files/f1/photo.JPG": {"image", "files/f1/photo"},
+		"files/f1/clip.mp4":  {"video", "files/f1/clip"},
+		"files/f1/doc.pdf":   {"raw", "files/f1/doc.pdf"},
+	}
+	for key, want := range cases {
+		rt, id := cloudinaryAsset(key)
+		if rt != want[0] || id != want[1] {
+			t.Errorf("%s: got (%s, %s)", key, rt, id)
+		}
+	}
+}
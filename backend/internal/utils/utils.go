@@ -11,6 +11,7 @@ import (
 	"math/big"
 	"net"
 	"net/http"
+	"net/url"
 	"reflect"
 	"regexp"
 	"runtime"
@@ -407,6 +408,34 @@ func GetPaginationParams(c *gin.Context) (page, limit int) {
 	return page, limit
 }
 
+// GetCursorParams معاملات صفحات المؤشر: cursor من NextCursor السابق، و with_total
+// لطلب الإجمالي (استعلام COUNT إضافي)
+func GetCursorParams(c *gin.Context) (cursor string, withTotal bool) {
+	withTotal, _ = strconv.ParseBool(c.Query("with_total"))
+	return c.Query("cursor"), withTotal
+}
+
+// SetPaginationLinks ترويسة Link (RFC 8288) للصفحة الأولى والتالية، بنفس
+// معاملات الطلب الحالي مع استبدال المؤشر
+func SetPaginationLinks(c *gin.Context, nextCursor string) {
+	link := func(cursor, rel string) string {
+		query := c.Request.URL.Query()
+		query.Del("page")
+		query.Del("cursor")
+		if cursor != "" {
+			query.Set("cursor", cursor)
+		}
+		u := url.URL{Path: c.Request.URL.Path, RawQuery: query.Encode()}
+		return fmt.Sprintf("<%s>; rel=\"%s\"", u.String(), rel)
+	}
+
+	links := []string{link("", "first")}
+	if nextCursor != "" {
+		links = append(links, link(nextCursor, "next"))
+	}
+	c.Header("Link", strings.Join(links, ", "))
+}
+
 // ApplyPagination تطبيق الترقيم على الاستعلام
 func ApplyPagination(query interface{}, page, limit int) (interface{}, *Pagination) {
 	// هذه دالة عامة - يمكن تخصيصها حسب ORM المستخدم
@@ -1199,8 +1228,17 @@ func ValidateUploadToken(token string, maxAge time.Duration) bool {
 		return false
 	}
 
-	uploadTime := time.Unix(timestamp, 0)
-	return time.Since(uploadTime) <= maxAge
+	// الجزء العشوائي 32 بايت بصيغة hex
+	if len(parts[2]) != 64 {
+		return false
+	}
+	if _, err := hex.DecodeString(parts[2]); err != nil {
+		return false
+	}
+
+	// رفض التوكنات ذات التاريخ المستقبلي (مع هامش بسيط لفرق الساعات)
+	age := time.Since(time.Unix(timestamp, 0))
+	return age >= -time.Minute && age <= maxAge
 }
 
 // ========== دوال مساعدة للـ Context ==========
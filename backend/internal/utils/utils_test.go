@@ -1,7 +1,10 @@
 package utils
 
 import (
+	"fmt"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestGetMemoryUsageMB(t *testing.T) {
@@ -48,3 +51,28 @@ func TestFormatBytes(t *testing.T) {
 		}
 	}
 }
+
+func TestValidateUploadToken(t *testing.T) {
+	token, err := GenerateUploadToken()
+	if err != nil {
+		t.Fatalf("GenerateUploadToken failed: %v", err)
+	}
+
+	if !ValidateUploadToken(token, time.Minute) {
+		t.Errorf("Expected fresh token to be valid: %s", token)
+	}
+
+	old := fmt.Sprintf("upl_%d_%s", time.Now().Add(-time.Hour).Unix(), strings.Repeat("a", 64))
+	if ValidateUploadToken(old, time.Minute) {
+		t.Errorf("Expected expired token to be rejected")
+	}
+
+	future := fmt.Sprintf("upl_%d_%s", time.Now().Add(time.Hour).Unix(), strings.Repeat("a", 64))
+	if ValidateUploadToken(future, time.Minute) {
+		t.Errorf("Expected future token to be rejected")
+	}
+
+	if ValidateUploadToken("upl_123_short", time.Hour) {
+		t.Errorf("Expected malformed token to be rejected")
+	}
+}
@@ -180,6 +180,8 @@ ENCRYPTION_KEY=""
 API_KEY=""
 
 # ==================== قاعدة البيانات (Cloudflare D1) ====================
+# DB_DRIVER: sqlite3 أو postgres (مع DATABASE_URL=postgres://... أو DB_HOST/DB_PORT/DB_USER/DB_PASSWORD)
+# أو d1 (مع CLOUDFLARE_ACCOUNT_ID و CLOUDFLARE_D1_DATABASE و CLOUDFLARE_API_TOKEN)
 DB_DRIVER=sqlite3
 DATABASE_URL=database.nawthtech.com
 